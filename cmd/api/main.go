@@ -1,20 +1,44 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
 	"net/http"
 	"os"
+	"strings"
+	"time"
+	// Embedded so analytics timezones work on images without system tzdata.
+	_ "time/tzdata"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
 	_ "github.com/joho/godotenv/autoload" // Import godotenv/autoload
+	"go.mongodb.org/mongo-driver/bson"
+
+	"markly/internal/database"
+	"markly/internal/models"
+	"markly/internal/preflight"
+	"markly/internal/repositories"
 	"markly/internal/server"
 )
 
 func main() {
+	validate := flag.Bool("validate", false, "check configuration and dependencies, print a report and exit")
+	grantAdmin := flag.String("grant-admin", "", "give the admin role to the existing account with this email and exit")
+	flag.Parse()
+
 	// Configure zerolog for better output
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 
+	if *validate {
+		os.Exit(runValidation())
+	}
+	if *grantAdmin != "" {
+		os.Exit(runGrantAdmin(*grantAdmin))
+	}
+
 	s := server.NewServer()
 
 	done := make(chan bool, 1)
@@ -29,3 +53,42 @@ func main() {
 	<-done
 	log.Info().Msg("Graceful shutdown complete.")
 }
+
+// runValidation prints the preflight report as JSON and returns the process exit code.
+func runValidation() int {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	report := preflight.Run(ctx)
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		log.Error().Err(err).Msg("Failed to write validation report")
+		return 1
+	}
+	if !report.OK {
+		return 1
+	}
+	return 0
+}
+
+// runGrantAdmin sets the admin role of the account with email, for bootstrapping
+// the first admins from a shell on the server. It returns the process exit code.
+func runGrantAdmin(email string) int {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	users := repositories.NewUserRepository(database.New())
+	user, err := users.FindByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		log.Error().Err(err).Str("email", email).Msg("Failed to find account to grant admin")
+		return 1
+	}
+	if _, err := users.Update(ctx, user.ID, bson.M{"role": models.RoleAdmin}); err != nil {
+		log.Error().Err(err).Str("userID", user.ID.Hex()).Msg("Failed to grant admin")
+		return 1
+	}
+	log.Info().Str("userID", user.ID.Hex()).Str("email", user.Email).Msg("Admin role granted")
+	return 0
+}
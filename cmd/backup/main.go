@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	_ "github.com/joho/godotenv/autoload"
+	"markly/internal/backup"
+	"markly/internal/database"
+	"markly/internal/scheduler"
+	"markly/internal/utils"
+)
+
+func main() {
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+
+	outDir := flag.String("out", "backups", "directory the backup files are written to")
+	userHex := flag.String("user", "", "only back up the data of this user ID")
+	s3Bucket := flag.String("s3-bucket", "", "also upload each backup to this S3 bucket")
+	s3Prefix := flag.String("s3-prefix", "markly/", "key prefix for uploads to S3")
+	interval := flag.Duration("interval", 0, "run repeatedly on this interval instead of once (e.g. 24h)")
+	metricsAddr := flag.String("metrics-addr", "", "serve Prometheus metrics on this address while running on an interval")
+	flag.Parse()
+
+	var userID *primitive.ObjectID
+	if *userHex != "" {
+		id, err := primitive.ObjectIDFromHex(*userHex)
+		if err != nil {
+			log.Fatal().Err(err).Str("user", *userHex).Msg("Invalid user ID")
+		}
+		userID = &id
+	}
+
+	db := database.New()
+	run := func(ctx context.Context) error {
+		return runBackup(ctx, db, *outDir, userID, *s3Bucket, *s3Prefix)
+	}
+
+	if *interval <= 0 {
+		if err := run(context.Background()); err != nil {
+			log.Fatal().Err(err).Msg("Backup failed")
+		}
+		return
+	}
+
+	if *metricsAddr != "" {
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", promhttp.Handler())
+			if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+				log.Error().Err(err).Msg("Metrics server stopped")
+			}
+		}()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	s := scheduler.New()
+	s.Register("backup", *interval, run)
+	s.RunNow(ctx, "backup")
+	s.Start(ctx)
+	s.Wait()
+	log.Info().Msg("Backup scheduler stopped")
+}
+
+func runBackup(ctx context.Context, db database.Service, outDir string, userID *primitive.ObjectID, s3Bucket, s3Prefix string) error {
+	if err := os.MkdirAll(outDir, 0o750); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	name := "markly-" + time.Now().UTC().Format("20060102T150405Z")
+	if userID != nil {
+		name += "-" + userID.Hex()
+	}
+	name += ".jsonl.gz"
+	path := filepath.Join(outDir, name)
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+
+	manifest, err := backup.Dump(ctx, db.Client(), f, backup.Options{UserID: userID})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err == nil {
+		utils.BackupSizeBytes.Set(float64(info.Size()))
+	}
+
+	var total int64
+	for _, c := range manifest.Collections {
+		total += c
+	}
+	log.Info().Str("file", path).Int64("documents", total).Interface("collections", manifest.Collections).Msg("Backup written")
+
+	if s3Bucket == "" {
+		return nil
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read backup for upload: %w", err)
+	}
+	key := strings.TrimSuffix(s3Prefix, "/") + "/" + name
+	if err := backup.S3ConfigFromEnv(s3Bucket).PutObject(ctx, key, body); err != nil {
+		return err
+	}
+	log.Info().Str("bucket", s3Bucket).Str("key", key).Msg("Backup uploaded to S3")
+	return nil
+}
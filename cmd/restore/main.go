@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	_ "github.com/joho/godotenv/autoload"
+	"markly/internal/backup"
+	"markly/internal/database"
+)
+
+func main() {
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+
+	in := flag.String("in", "", "backup to restore: a local file or s3://bucket/key")
+	dryRun := flag.Bool("dry-run", false, "report what would be restored without writing anything")
+	userHex := flag.String("user", "", "only restore the data of this user ID")
+	flag.Parse()
+
+	if *in == "" {
+		log.Fatal().Msg("-in is required")
+	}
+
+	opts := backup.RestoreOptions{DryRun: *dryRun}
+	if *userHex != "" {
+		id, err := primitive.ObjectIDFromHex(*userHex)
+		if err != nil {
+			log.Fatal().Err(err).Str("user", *userHex).Msg("Invalid user ID")
+		}
+		opts.UserID = &id
+	}
+
+	ctx := context.Background()
+	var src io.Reader
+	if strings.HasPrefix(*in, "s3://") {
+		bucket, key, _ := strings.Cut(strings.TrimPrefix(*in, "s3://"), "/")
+		body, err := backup.S3ConfigFromEnv(bucket).GetObject(ctx, key)
+		if err != nil {
+			log.Fatal().Err(err).Str("in", *in).Msg("Failed to download backup")
+		}
+		src = bytes.NewReader(body)
+	} else {
+		f, err := os.Open(*in)
+		if err != nil {
+			log.Fatal().Err(err).Str("in", *in).Msg("Failed to open backup")
+		}
+		defer f.Close()
+		src = f
+	}
+
+	db := database.New()
+	report, err := backup.Restore(ctx, db.Client(), src, opts)
+	if report != nil {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msg("Restore failed")
+	}
+	if *dryRun {
+		log.Info().Msg("Dry run complete, nothing was written")
+	} else {
+		log.Info().Msg("Restore complete")
+	}
+}
@@ -0,0 +1,40 @@
+// Command sdk writes the client SDK descriptors of this build, the same files
+// the server serves under /api/meta/sdk, so releases can publish them.
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/server"
+)
+
+func main() {
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+
+	outDir := flag.String("out", "sdk", "directory the descriptors are written to")
+	flag.Parse()
+
+	bundle, err := server.BuildSDK()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to generate sdk descriptors")
+	}
+	dir := filepath.Join(*outDir, bundle.APIVersion)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		log.Fatal().Err(err).Str("dir", dir).Msg("Failed to create output directory")
+	}
+	for _, a := range []struct {
+		name    string
+		content []byte
+	}{{bundle.OpenAPI.Name, bundle.OpenAPI.Content}, {bundle.TypeScript.Name, bundle.TypeScript.Content}} {
+		path := filepath.Join(dir, a.name)
+		if err := os.WriteFile(path, a.content, 0o644); err != nil {
+			log.Fatal().Err(err).Str("path", path).Msg("Failed to write sdk descriptor")
+		}
+		log.Info().Str("path", path).Str("version", bundle.ServerVersion).Msg("SDK descriptor written")
+	}
+}
@@ -0,0 +1,320 @@
+// Package backup produces and restores compressed dumps of the Markly database.
+//
+// A dump is a gzip-compressed stream of JSON lines. The first line is a header,
+// every following line holds one document in canonical Extended JSON together
+// with the name of the collection it came from.
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"markly/internal/utils"
+)
+
+const (
+	DatabaseName  = "markly"
+	formatVersion = 1
+)
+
+// globalCollections hold data that does not belong to a single user and are skipped in per-user dumps.
+var globalCollections = map[string]bool{
+	"trending_items":    true,
+	"instance_settings": true,
+}
+
+type Options struct {
+	// UserID limits the dump to documents owned by a single user.
+	UserID *primitive.ObjectID
+}
+
+type RestoreOptions struct {
+	DryRun bool
+	// UserID restores only the documents owned by a single user.
+	UserID *primitive.ObjectID
+}
+
+type header struct {
+	Format    int       `json:"markly_backup"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    string    `json:"user_id,omitempty"`
+}
+
+type line struct {
+	Collection string          `json:"c"`
+	Document   json.RawMessage `json:"d"`
+}
+
+// Manifest summarises what a dump contains.
+type Manifest struct {
+	CreatedAt   time.Time        `json:"created_at"`
+	UserID      string           `json:"user_id,omitempty"`
+	Collections map[string]int64 `json:"collections"`
+}
+
+// RestoreReport summarises what a restore did, or would do in dry-run mode.
+type RestoreReport struct {
+	DryRun      bool                         `json:"dry_run"`
+	BackupTaken time.Time                    `json:"backup_taken"`
+	Collections map[string]*CollectionReport `json:"collections"`
+}
+
+type CollectionReport struct {
+	Documents int64 `json:"documents"`
+	Inserted  int64 `json:"inserted"`
+	Replaced  int64 `json:"replaced"`
+	Skipped   int64 `json:"skipped"`
+	// Erased counts the documents skipped because their owner has been erased.
+	Erased int64 `json:"erased"`
+}
+
+// userFilter returns the filter selecting a user's documents in a collection,
+// or false if the collection is not user scoped.
+func userFilter(collection string, userID primitive.ObjectID) (bson.M, bool) {
+	if globalCollections[collection] {
+		return nil, false
+	}
+	if collection == "users" {
+		return bson.M{"_id": userID}, true
+	}
+	return bson.M{"user_id": userID}, true
+}
+
+// Dump writes a compressed dump of the database to w. When the deployment supports it,
+// all collections are read from a single snapshot so the dump is consistent.
+func Dump(ctx context.Context, client *mongo.Client, w io.Writer, opts Options) (*Manifest, error) {
+	db := client.Database(DatabaseName)
+
+	names, err := db.ListCollectionNames(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+	sort.Strings(names)
+
+	manifest := &Manifest{CreatedAt: time.Now().UTC(), Collections: make(map[string]int64)}
+	if opts.UserID != nil {
+		manifest.UserID = opts.UserID.Hex()
+	}
+
+	gz := gzip.NewWriter(w)
+	enc := json.NewEncoder(gz)
+	if err := enc.Encode(header{Format: formatVersion, CreatedAt: manifest.CreatedAt, UserID: manifest.UserID}); err != nil {
+		return nil, fmt.Errorf("failed to write backup header: %w", err)
+	}
+
+	dumpAll := func(sc context.Context) error {
+		for _, name := range names {
+			filter := bson.M{}
+			if opts.UserID != nil {
+				f, ok := userFilter(name, *opts.UserID)
+				if !ok {
+					continue
+				}
+				filter = f
+			}
+			count, err := dumpCollection(sc, db.Collection(name), filter, enc)
+			if err != nil {
+				return fmt.Errorf("failed to dump collection %s: %w", name, err)
+			}
+			manifest.Collections[name] = count
+			utils.BackupDocumentsTotal.WithLabelValues("dump", name).Add(float64(count))
+		}
+		return nil
+	}
+
+	session, err := client.StartSession(options.Session().SetSnapshot(true))
+	if err == nil {
+		defer session.EndSession(ctx)
+		err = mongo.WithSession(ctx, session, func(sc mongo.SessionContext) error { return dumpAll(sc) })
+	}
+	if err != nil && isSnapshotUnsupported(err) {
+		log.Warn().Err(err).Msg("Snapshot reads unavailable, dumping without a consistent snapshot")
+		manifest.Collections = make(map[string]int64)
+		err = dumpAll(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish backup stream: %w", err)
+	}
+	return manifest, nil
+}
+
+func isSnapshotUnsupported(err error) bool {
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) {
+		// IllegalOperation / InvalidOptions are returned by standalone servers.
+		return cmdErr.Code == 20 || cmdErr.Code == 72
+	}
+	return false
+}
+
+func dumpCollection(ctx context.Context, collection *mongo.Collection, filter bson.M, enc *json.Encoder) (int64, error) {
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var count int64
+	for cursor.Next(ctx) {
+		doc, err := bson.MarshalExtJSON(cursor.Current, true, false)
+		if err != nil {
+			return count, err
+		}
+		if err := enc.Encode(line{Collection: collection.Name(), Document: doc}); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, cursor.Err()
+}
+
+// Restore reads a dump produced by Dump and upserts every document by _id.
+func Restore(ctx context.Context, client *mongo.Client, r io.Reader, opts RestoreOptions) (*RestoreReport, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup stream: %w", err)
+	}
+	defer gz.Close()
+
+	reader := bufio.NewReader(gz)
+	first, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup header: %w", err)
+	}
+	var h header
+	if err := json.Unmarshal(first, &h); err != nil || h.Format != formatVersion {
+		return nil, fmt.Errorf("not a markly backup or unsupported format version")
+	}
+
+	report := &RestoreReport{DryRun: opts.DryRun, BackupTaken: h.CreatedAt, Collections: make(map[string]*CollectionReport)}
+	db := client.Database(DatabaseName)
+	erased, err := erasedUsers(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		raw, err := reader.ReadBytes('\n')
+		if len(raw) > 0 {
+			if err := restoreLine(ctx, db, raw, opts, erased, report); err != nil {
+				return report, err
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return report, fmt.Errorf("failed to read backup: %w", err)
+		}
+	}
+	return report, nil
+}
+
+// erasedUsers returns the users whose data was erased. Backups taken before an
+// erasure still hold their documents, which must never be restored.
+func erasedUsers(ctx context.Context, db *mongo.Database) (map[primitive.ObjectID]bool, error) {
+	ids, err := db.Collection("erasures").Distinct(ctx, "user_id", bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read erasure reports: %w", err)
+	}
+	erased := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		if oid, ok := id.(primitive.ObjectID); ok {
+			erased[oid] = true
+		}
+	}
+	return erased, nil
+}
+
+func restoreLine(ctx context.Context, db *mongo.Database, raw []byte, opts RestoreOptions, erased map[primitive.ObjectID]bool, report *RestoreReport) error {
+	var l line
+	if err := json.Unmarshal(raw, &l); err != nil {
+		return fmt.Errorf("corrupt backup line: %w", err)
+	}
+	var doc bson.D
+	if err := bson.UnmarshalExtJSON(l.Document, true, &doc); err != nil {
+		return fmt.Errorf("corrupt document in collection %s: %w", l.Collection, err)
+	}
+
+	cr, ok := report.Collections[l.Collection]
+	if !ok {
+		cr = &CollectionReport{}
+		report.Collections[l.Collection] = cr
+	}
+	cr.Documents++
+
+	id, owner := documentKeys(l.Collection, doc)
+	if opts.UserID != nil && owner != *opts.UserID {
+		cr.Skipped++
+		return nil
+	}
+	if l.Collection != "erasures" && erased[owner] {
+		cr.Erased++
+		return nil
+	}
+
+	collection := db.Collection(l.Collection)
+	if opts.DryRun {
+		count, err := collection.CountDocuments(ctx, bson.M{"_id": id})
+		if err != nil {
+			return fmt.Errorf("failed to inspect collection %s: %w", l.Collection, err)
+		}
+		if count > 0 {
+			cr.Replaced++
+		} else {
+			cr.Inserted++
+		}
+		return nil
+	}
+
+	result, err := collection.ReplaceOne(ctx, bson.M{"_id": id}, doc, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to restore document into %s: %w", l.Collection, err)
+	}
+	if result.UpsertedCount > 0 {
+		cr.Inserted++
+	} else {
+		cr.Replaced++
+	}
+	utils.BackupDocumentsTotal.WithLabelValues("restore", l.Collection).Inc()
+	return nil
+}
+
+// documentKeys extracts the _id of a document and the user that owns it.
+func documentKeys(collection string, doc bson.D) (interface{}, primitive.ObjectID) {
+	var id interface{}
+	var owner primitive.ObjectID
+	for _, e := range doc {
+		switch e.Key {
+		case "_id":
+			id = e.Value
+			if collection == "users" {
+				if oid, ok := e.Value.(primitive.ObjectID); ok {
+					owner = oid
+				}
+			}
+		case "user_id":
+			if oid, ok := e.Value.(primitive.ObjectID); ok && collection != "users" {
+				owner = oid
+			}
+		}
+	}
+	return id, owner
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -11,6 +13,8 @@ import (
 	_ "github.com/joho/godotenv/autoload"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"markly/internal/utils"
 )
 
 type Service interface {
@@ -18,8 +22,14 @@ type Service interface {
 	Client() *mongo.Client
 }
 
+// service hands out the client of the active target. With fallback targets
+// configured, a monitor checks every target and fails over between them.
 type service struct {
-	db *mongo.Client
+	cfg failoverConfig
+
+	mu      sync.RWMutex
+	targets []*target
+	active  int
 }
 
 var (
@@ -28,35 +38,115 @@ var (
 	//database = os.Getenv("BLUEPRINT_DB_DATABASE")
 )
 
+// Target is a MongoDB deployment the service can use.
+type Target struct {
+	Name string
+	URI  string
+}
+
+// Targets returns the deployments configured in the environment in order of
+// preference: the primary, MONGO_URI or else BLUEPRINT_DB_HOST and
+// BLUEPRINT_DB_PORT, then the whitespace-separated MONGO_FALLBACK_URIS.
+func Targets() []Target {
+	primary := os.Getenv("MONGO_URI")
+	if primary == "" {
+		primary = fmt.Sprintf("mongodb://%s:%s", host, port)
+	}
+	targets := []Target{{Name: "primary", URI: primary}}
+	for i, uri := range strings.Fields(os.Getenv("MONGO_FALLBACK_URIS")) {
+		targets = append(targets, Target{Name: fmt.Sprintf("fallback-%d", i+1), URI: uri})
+	}
+	return targets
+}
+
 func New() Service {
-	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(fmt.Sprintf("mongodb://%s:%s", host, port)))
+	s := &service{cfg: failoverConfigFromEnv()}
+	for _, t := range Targets() {
+		tg := &target{Target: t}
+		if err := tg.connect(); err != nil {
+			log.Error().Err(err).Str("target", t.Name).Msg("Failed to connect to MongoDB target")
+		}
+		s.targets = append(s.targets, tg)
+	}
 
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to connect to MongoDB")
+	if len(s.targets) == 1 {
+		if s.targets[0].client == nil {
+			log.Fatal().Msg("Failed to connect to MongoDB")
+		}
+		utils.DBActiveTarget.WithLabelValues(s.targets[0].Name).Set(1)
+		return s
 	}
-	return &service{
-		db: client,
+
+	s.active = -1
+	for i, t := range s.targets {
+		if t.client != nil {
+			s.active = i
+			break
+		}
+	}
+	if s.active < 0 {
+		log.Fatal().Msg("Failed to connect to any MongoDB target")
 	}
+	// A target that fails its first check is left immediately rather than after
+	// the threshold, so that the server starts on a reachable deployment.
+	s.checkTargets(time.Now())
+	s.failover(1)
+	log.Info().Str("target", s.targets[s.active].Name).Int("targets", len(s.targets)).Msg("MongoDB failover enabled")
+	go s.monitor()
+	return s
 }
 
 func (s *service) Health() map[string]string {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
 
-	err := s.db.Ping(ctx, nil)
+	s.mu.RLock()
+	active := s.targets[s.active]
+	client := active.client
+	summary := s.targetSummary()
+	s.mu.RUnlock()
+
+	start := time.Now()
+	err := client.Ping(ctx, nil)
 	if err != nil {
-		log.Error().Err(err).Msg("Database health check failed")
-		return map[string]string{
+		log.Error().Err(err).Str("target", active.Name).Msg("Database health check failed")
+		stats := map[string]string{
 			"message": "db down",
 			"error":   err.Error(),
+			"target":  active.Name,
+		}
+		if summary != "" {
+			stats["targets"] = summary
 		}
+		return stats
 	}
 
-	return map[string]string{
-		"message": "It's healthy",
+	stats := map[string]string{
+		"message":    "It's healthy",
+		"target":     active.Name,
+		"latency_ms": fmt.Sprint(time.Since(start).Milliseconds()),
+	}
+	if summary != "" {
+		stats["targets"] = summary
 	}
+	return stats
 }
 
 func (s *service) Client() *mongo.Client {
-	return s.db
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.targets[s.active].client
+}
+
+// connect creates the client of the target. The driver connects lazily, so this
+// only fails for invalid URIs and unresolvable mongodb+srv records.
+func (t *target) connect() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(t.URI))
+	if err != nil {
+		return err
+	}
+	t.client = client
+	return nil
 }
@@ -0,0 +1,247 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/utils"
+)
+
+// maxCheckBackoff bounds how long a failing standby target waits between checks.
+const maxCheckBackoff = 5 * time.Minute
+
+// failoverConfig tunes the health checks of the targets.
+type failoverConfig struct {
+	// interval separates the checks of the active target and healthy standbys.
+	interval time.Duration
+	// timeout bounds a ping.
+	timeout time.Duration
+	// threshold is the number of checks in a row that fail a target over, and
+	// that the primary must pass before it is used again.
+	threshold int
+	// maxLatency makes slower pings count as failures. Zero disables it.
+	maxLatency time.Duration
+}
+
+func failoverConfigFromEnv() failoverConfig {
+	cfg := failoverConfig{interval: 10 * time.Second, timeout: 2 * time.Second, threshold: 3}
+	if d, err := time.ParseDuration(os.Getenv("MONGO_HEALTH_CHECK_INTERVAL")); err == nil && d > 0 {
+		cfg.interval = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("MONGO_HEALTH_CHECK_TIMEOUT")); err == nil && d > 0 {
+		cfg.timeout = d
+	}
+	if n, err := strconv.Atoi(os.Getenv("MONGO_FAILOVER_THRESHOLD")); err == nil && n > 0 {
+		cfg.threshold = n
+	}
+	if d, err := time.ParseDuration(os.Getenv("MONGO_MAX_LATENCY")); err == nil && d > 0 {
+		cfg.maxLatency = d
+	}
+	return cfg
+}
+
+// target is a Target with its client and the results of its recent checks.
+// A nil client is reconnected at the next check.
+type target struct {
+	Target
+	client *mongo.Client
+	health targetHealth
+	// nextCheck delays the checks of a failing standby.
+	nextCheck time.Time
+}
+
+// targetHealth counts the checks a target passed or failed in a row.
+type targetHealth struct {
+	failures  int
+	successes int
+	latency   time.Duration
+}
+
+func (h targetHealth) healthy() bool {
+	return h.successes > 0
+}
+
+func (s *service) monitor() {
+	ticker := time.NewTicker(s.cfg.interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		s.checkTargets(now)
+		s.failover(s.cfg.threshold)
+	}
+}
+
+// checkTargets pings the active target and every standby that is due. A standby
+// that keeps failing is checked less and less often, and is reconnected each
+// time it fails threshold more checks.
+func (s *service) checkTargets(now time.Time) {
+	for i, t := range s.targets {
+		s.mu.RLock()
+		due := i == s.active || !now.Before(t.nextCheck)
+		s.mu.RUnlock()
+		if !due {
+			continue
+		}
+
+		latency, err := s.ping(t)
+		s.mu.Lock()
+		if err != nil {
+			t.health.failures++
+			t.health.successes = 0
+			if i != s.active {
+				t.nextCheck = now.Add(checkBackoff(s.cfg.interval, t.health.failures))
+				if t.client != nil && t.health.failures%s.cfg.threshold == 0 {
+					go disconnect(t.client)
+					t.client = nil
+				}
+			}
+			log.Warn().Err(err).Str("target", t.Name).Int("failures", t.health.failures).Msg("MongoDB target check failed")
+		} else {
+			t.health.failures = 0
+			t.health.successes++
+			t.health.latency = latency
+			t.nextCheck = time.Time{}
+		}
+		s.mu.Unlock()
+
+		utils.DBTargetUp.WithLabelValues(t.Name).Set(boolGauge(err == nil))
+		if err == nil {
+			utils.DBTargetPingSeconds.WithLabelValues(t.Name).Set(latency.Seconds())
+		}
+	}
+}
+
+// ping checks one target, connecting it first if it has no client.
+func (s *service) ping(t *target) (time.Duration, error) {
+	s.mu.RLock()
+	client := t.client
+	s.mu.RUnlock()
+	if client == nil {
+		reconnected := &target{Target: t.Target}
+		if err := reconnected.connect(); err != nil {
+			return 0, fmt.Errorf("failed to reconnect: %w", err)
+		}
+		s.mu.Lock()
+		t.client = reconnected.client
+		s.mu.Unlock()
+		client = reconnected.client
+		log.Info().Str("target", t.Name).Msg("MongoDB target reconnected")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.timeout)
+	defer cancel()
+	start := time.Now()
+	if err := client.Ping(ctx, nil); err != nil {
+		return 0, err
+	}
+	latency := time.Since(start)
+	if s.cfg.maxLatency > 0 && latency > s.cfg.maxLatency {
+		return latency, fmt.Errorf("ping took %s, more than %s", latency.Round(time.Millisecond), s.cfg.maxLatency)
+	}
+	return latency, nil
+}
+
+// failover switches to the target chooseTarget picks.
+func (s *service) failover(threshold int) {
+	s.mu.Lock()
+	health := make([]targetHealth, len(s.targets))
+	for i, t := range s.targets {
+		health[i] = t.health
+	}
+	from := s.active
+	to := chooseTarget(health, from, threshold)
+	s.active = to
+	s.mu.Unlock()
+
+	for i, t := range s.targets {
+		utils.DBActiveTarget.WithLabelValues(t.Name).Set(boolGauge(i == to))
+	}
+	if to != from {
+		utils.DBFailoversTotal.WithLabelValues(s.targets[from].Name, s.targets[to].Name).Inc()
+		log.Warn().Str("from", s.targets[from].Name).Str("to", s.targets[to].Name).Dur("latency", health[to].latency).Msg("MongoDB failover")
+	}
+}
+
+// chooseTarget returns the target to use, given targets in order of preference
+// with the primary first. The active target is kept until it fails threshold
+// checks in a row; then the primary is used if it is healthy, or else the
+// healthy fallback with the lowest latency. A fallback hands back to the
+// primary once it has passed threshold checks in a row, so that a flapping
+// primary is not used. Without a healthy alternative the active target stays.
+func chooseTarget(health []targetHealth, active, threshold int) int {
+	if active != 0 && health[0].successes >= threshold {
+		return 0
+	}
+	if health[active].failures < threshold {
+		return active
+	}
+	best := -1
+	for i, h := range health {
+		if i == active || !h.healthy() {
+			continue
+		}
+		if i == 0 {
+			return 0
+		}
+		if best < 0 || h.latency < health[best].latency {
+			best = i
+		}
+	}
+	if best < 0 {
+		return active
+	}
+	return best
+}
+
+// checkBackoff returns the wait before the next check of a standby that failed
+// the last failures checks: the interval, doubled for each failure after the
+// first, up to maxCheckBackoff.
+func checkBackoff(interval time.Duration, failures int) time.Duration {
+	wait := interval
+	for i := 1; i < failures && wait < maxCheckBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxCheckBackoff {
+		return maxCheckBackoff
+	}
+	return wait
+}
+
+// targetSummary lists the state of every target, such as
+// "primary:down,fallback-1:up", or is empty without fallbacks. The caller
+// holds s.mu.
+func (s *service) targetSummary() string {
+	if len(s.targets) < 2 {
+		return ""
+	}
+	states := make([]string, len(s.targets))
+	for i, t := range s.targets {
+		state := "down"
+		if t.health.healthy() {
+			state = "up"
+		}
+		states[i] = t.Name + ":" + state
+	}
+	return strings.Join(states, ",")
+}
+
+func disconnect(client *mongo.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := client.Disconnect(ctx); err != nil {
+		log.Debug().Err(err).Msg("Failed to disconnect MongoDB client")
+	}
+}
+
+func boolGauge(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
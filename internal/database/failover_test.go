@@ -0,0 +1,57 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestChooseTarget(t *testing.T) {
+	up := func(successes int, latency time.Duration) targetHealth {
+		return targetHealth{successes: successes, latency: latency}
+	}
+	down := func(failures int) targetHealth { return targetHealth{failures: failures} }
+
+	cases := []struct {
+		name   string
+		health []targetHealth
+		active int
+		want   int
+	}{
+		{"healthy primary stays", []targetHealth{up(5, 0), up(5, 0)}, 0, 0},
+		{"primary below threshold stays", []targetHealth{down(2), up(5, 0)}, 0, 0},
+		{"failing primary moves to fastest fallback", []targetHealth{down(3), up(1, 80*time.Millisecond), up(1, 20*time.Millisecond)}, 0, 2},
+		{"failing primary without alternative stays", []targetHealth{down(3), down(4)}, 0, 0},
+		{"fallback waits for a stable primary", []targetHealth{up(2, 0), up(9, 0)}, 1, 1},
+		{"fallback hands back to a stable primary", []targetHealth{up(3, 0), up(9, 0)}, 1, 0},
+		{"failing fallback prefers a healthy primary", []targetHealth{up(1, time.Second), down(3), up(4, time.Millisecond)}, 1, 0},
+		{"failing fallback moves to the other fallback", []targetHealth{down(9), down(3), up(4, 0)}, 1, 2},
+	}
+	for _, c := range cases {
+		if got := chooseTarget(c.health, c.active, 3); got != c.want {
+			t.Errorf("%s: chooseTarget = %d, want %d", c.name, got, c.want)
+		}
+	}
+}
+
+func TestCheckBackoff(t *testing.T) {
+	interval := 10 * time.Second
+	for failures, want := range map[int]time.Duration{1: interval, 2: 2 * interval, 4: 8 * interval, 50: maxCheckBackoff} {
+		if got := checkBackoff(interval, failures); got != want {
+			t.Errorf("checkBackoff(%d) = %s, want %s", failures, got, want)
+		}
+	}
+}
+
+func TestTargets(t *testing.T) {
+	t.Setenv("MONGO_URI", "mongodb://eu-1,eu-2/?replicaSet=rs0")
+	t.Setenv("MONGO_FALLBACK_URIS", " mongodb+srv://us.example.net \n mongodb://ap:27017 ")
+	want := []Target{
+		{Name: "primary", URI: "mongodb://eu-1,eu-2/?replicaSet=rs0"},
+		{Name: "fallback-1", URI: "mongodb+srv://us.example.net"},
+		{Name: "fallback-2", URI: "mongodb://ap:27017"},
+	}
+	if got := Targets(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Targets = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// IndexSpec describes an index the application relies on.
+type IndexSpec struct {
+	Collection string
+	Name       string
+	Keys       bson.D
+	Unique     bool
+	// Partial limits the index to the documents matching the filter.
+	Partial bson.M
+}
+
+// RequiredIndexes lists every index the services expect to exist. Several services
+// translate duplicate key errors into "already exists" responses, which only works
+// when the matching unique index is present.
+var RequiredIndexes = []IndexSpec{
+	{Collection: "users", Name: "email_unique", Keys: bson.D{{Key: "email", Value: 1}}, Unique: true},
+	{Collection: "users", Name: "referred_by_created_at", Keys: bson.D{{Key: "referred_by", Value: 1}, {Key: "created_at", Value: 1}}},
+	{Collection: "invites", Name: "code_unique", Keys: bson.D{{Key: "code", Value: 1}}, Unique: true},
+	{Collection: "invites", Name: "user_created_at", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	{Collection: "bookmarks", Name: "user_created_at", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	{Collection: "bookmarks", Name: "user_collection_created_at", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "collectionsid", Value: 1}, {Key: "created_at", Value: 1}}},
+	{Collection: "bookmarks", Name: "created_at", Keys: bson.D{{Key: "created_at", Value: -1}}},
+	{Collection: "bookmarks", Name: "user_canonical_url", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "canonical_url", Value: 1}}},
+	{Collection: "bookmarks", Name: "user_language", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "language", Value: 1}}},
+	{Collection: "bookmarks", Name: "metadata_at", Keys: bson.D{{Key: "metadata_at", Value: 1}}},
+	// search_grams leads so the index also serves the backfill query for null grams.
+	{Collection: "bookmarks", Name: "search_grams_user", Keys: bson.D{{Key: "search_grams", Value: 1}, {Key: "user_id", Value: 1}}},
+	// note_grams leads so the index also serves the backfill query for missing grams.
+	{Collection: "bookmarks", Name: "note_grams_user", Keys: bson.D{{Key: "note_grams", Value: 1}, {Key: "user_id", Value: 1}}},
+	{Collection: "bookmarks", Name: "text_search", Keys: bson.D{{Key: "title", Value: "text"}, {Key: "summary", Value: "text"}, {Key: "url", Value: "text"}}},
+	{Collection: "bookmark_contents", Name: "bookmark_unique", Keys: bson.D{{Key: "bookmark_id", Value: 1}, {Key: "user_id", Value: 1}}, Unique: true},
+	{Collection: "bookmark_shares", Name: "token_hash_unique", Keys: bson.D{{Key: "token_hash", Value: 1}}, Unique: true},
+	{Collection: "bookmark_shares", Name: "user_bookmark_created_at", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "bookmark_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	{Collection: "highlights", Name: "user_bookmark", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "bookmark_id", Value: 1}, {Key: "created_at", Value: 1}}},
+	{Collection: "tags", Name: "user_name_unique", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "name", Value: 1}}, Unique: true},
+	{Collection: "tag_subscriptions", Name: "user_tag_unique", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "tag", Value: 1}}, Unique: true},
+	{Collection: "tag_subscriptions", Name: "trending", Keys: bson.D{{Key: "trending", Value: 1}}},
+	{Collection: "tag_notifications", Name: "user_tag_source_item_unique", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "tag", Value: 1}, {Key: "source", Value: 1}, {Key: "item", Value: 1}}, Unique: true},
+	{Collection: "collections", Name: "auto_archive", Keys: bson.D{{Key: "settings.auto_archive.after_days", Value: 1}}},
+	{Collection: "collections", Name: "user_name_unique", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "name", Value: 1}}, Unique: true},
+	// Resources created before slugs existed have none until they are migrated.
+	{Collection: "collections", Name: "user_slug_unique", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "slug", Value: 1}}, Unique: true, Partial: bson.M{"slug": bson.M{"$gt": ""}}},
+	{Collection: "collections", Name: "user_previous_slugs", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "previous_slugs", Value: 1}}},
+	{Collection: "collection_feeds", Name: "user_collection_url_unique", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "collection_id", Value: 1}, {Key: "url", Value: 1}}, Unique: true},
+	{Collection: "collection_feeds", Name: "next_fetch_at", Keys: bson.D{{Key: "next_fetch_at", Value: 1}}},
+	{Collection: "categories", Name: "user_name_unique", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "name", Value: 1}}, Unique: true},
+	{Collection: "categories", Name: "user_slug_unique", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "slug", Value: 1}}, Unique: true, Partial: bson.M{"slug": bson.M{"$gt": ""}}},
+	{Collection: "categories", Name: "user_previous_slugs", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "previous_slugs", Value: 1}}},
+	{Collection: "category_icons", Name: "user_id", Keys: bson.D{{Key: "user_id", Value: 1}}},
+	{Collection: "bookmark_thumbnails", Name: "user_id", Keys: bson.D{{Key: "user_id", Value: 1}}},
+	{Collection: "collection_templates", Name: "user_name", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "name", Value: 1}}},
+	{Collection: "ai_events", Name: "created_at", Keys: bson.D{{Key: "created_at", Value: 1}}},
+	{Collection: "github_connections", Name: "user_unique", Keys: bson.D{{Key: "user_id", Value: 1}}, Unique: true},
+	{Collection: "github_connections", Name: "state_hash", Keys: bson.D{{Key: "state_hash", Value: 1}}},
+	{Collection: "import_jobs", Name: "user_created_at", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	{Collection: "import_jobs", Name: "status_updated_at", Keys: bson.D{{Key: "status", Value: 1}, {Key: "updated_at", Value: 1}}},
+	{Collection: "import_jobs", Name: "finished_at", Keys: bson.D{{Key: "finished_at", Value: 1}}},
+	{Collection: "import_jobs", Name: "user_active_unique", Keys: bson.D{{Key: "user_id", Value: 1}}, Unique: true, Partial: bson.M{"active": true}},
+	{Collection: "api_keys", Name: "key_hash_unique", Keys: bson.D{{Key: "key_hash", Value: 1}}, Unique: true},
+	{Collection: "audit_events", Name: "user_created_at", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	{Collection: "audit_events", Name: "user_action_created_at", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "action", Value: 1}, {Key: "created_at", Value: -1}}},
+	{Collection: "audit_events", Name: "created_at", Keys: bson.D{{Key: "created_at", Value: -1}}},
+	{Collection: "impersonations", Name: "approval_hash", Keys: bson.D{{Key: "approval_hash", Value: 1}}},
+	{Collection: "impersonations", Name: "user_created_at", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	{Collection: "newsletter_subscribers", Name: "collection_email_unique", Keys: bson.D{{Key: "collection_id", Value: 1}, {Key: "email", Value: 1}}, Unique: true},
+	{Collection: "newsletter_subscribers", Name: "unsubscribe_token_unique", Keys: bson.D{{Key: "unsubscribe_token", Value: 1}}, Unique: true},
+	// Only one newsletter of a collection can be sending at a time.
+	{Collection: "newsletter_sends", Name: "collection_sending_unique", Keys: bson.D{{Key: "collection_id", Value: 1}}, Unique: true, Partial: bson.M{"status": "sending"}},
+	{Collection: "newsletter_sends", Name: "collection_created_at", Keys: bson.D{{Key: "collection_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	{Collection: "newsletter_sends", Name: "finished_at", Keys: bson.D{{Key: "finished_at", Value: 1}}},
+	{Collection: "erasures", Name: "user_started_at", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "started_at", Value: -1}}},
+	{Collection: "data_keys", Name: "user_unique", Keys: bson.D{{Key: "user_id", Value: 1}}, Unique: true},
+}
+
+// ObsoleteIndexes lists indexes of earlier versions that a required index replaces
+// with the same keys. They are dropped before the required indexes are created.
+var ObsoleteIndexes = []IndexSpec{
+	{Collection: "collections", Name: "user_slug"},
+	{Collection: "categories", Name: "user_slug"},
+}
+
+// EnsureIndexes drops the obsolete indexes and creates any missing required index.
+// Failures are returned together so one bad collection does not prevent the others
+// from being indexed.
+func EnsureIndexes(ctx context.Context, client *mongo.Client) error {
+	db := client.Database("markly")
+	var failed []string
+	for _, spec := range ObsoleteIndexes {
+		_, err := db.Collection(spec.Collection).Indexes().DropOne(ctx, spec.Name)
+		var cmdErr mongo.CommandError
+		// Codes 26 and 27 are a missing collection and a missing index.
+		if err != nil && !(errors.As(err, &cmdErr) && (cmdErr.Code == 26 || cmdErr.Code == 27)) {
+			log.Error().Err(err).Str("collection", spec.Collection).Str("index", spec.Name).Msg("Failed to drop obsolete index")
+			failed = append(failed, spec.Collection+"."+spec.Name)
+		}
+	}
+	for _, spec := range RequiredIndexes {
+		model := mongo.IndexModel{
+			Keys:    spec.Keys,
+			Options: options.Index().SetName(spec.Name).SetUnique(spec.Unique),
+		}
+		if spec.Partial != nil {
+			model.Options.SetPartialFilterExpression(spec.Partial)
+		}
+		if _, err := db.Collection(spec.Collection).Indexes().CreateOne(ctx, model); err != nil {
+			log.Error().Err(err).Str("collection", spec.Collection).Str("index", spec.Name).Msg("Failed to create index")
+			failed = append(failed, spec.Collection+"."+spec.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to update indexes: %v", failed)
+	}
+	return nil
+}
+
+// MissingIndexes returns the required indexes that do not exist, as "collection.name".
+func MissingIndexes(ctx context.Context, client *mongo.Client) ([]string, error) {
+	db := client.Database("markly")
+	var missing []string
+	for _, spec := range RequiredIndexes {
+		cursor, err := db.Collection(spec.Collection).Indexes().List(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list indexes of %s: %w", spec.Collection, err)
+		}
+		var existing []bson.M
+		if err := cursor.All(ctx, &existing); err != nil {
+			return nil, fmt.Errorf("failed to decode indexes of %s: %w", spec.Collection, err)
+		}
+		found := false
+		for _, idx := range existing {
+			if idx["name"] == spec.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, spec.Collection+"."+spec.Name)
+		}
+	}
+	return missing, nil
+}
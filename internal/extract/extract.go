@@ -0,0 +1,343 @@
+// Package extract fetches web pages and reduces them to their readable article
+// content, for offline reading.
+package extract
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Article is the readable content of a page.
+type Article struct {
+	Title    string
+	SiteName string
+	// FaviconURL is the page's declared icon. Parse returns it as written in the
+	// document; Fetch resolves it against the page URL and falls back to /favicon.ico.
+	FaviconURL string
+	// ImageURL is the page's preview image from og:image or twitter:image, resolved
+	// by Fetch like FaviconURL but without a fallback.
+	ImageURL  string
+	Text      string
+	HTML      string
+	WordCount int
+	// Language is the page's declared language tag as written, such as "en-US".
+	Language string
+}
+
+// Elements that never hold article content.
+var skippedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Iframe: true,
+	atom.Nav: true, atom.Header: true, atom.Footer: true, atom.Aside: true,
+	atom.Form: true, atom.Button: true, atom.Svg: true, atom.Template: true,
+	atom.Object: true, atom.Embed: true, atom.Select: true, atom.Input: true,
+}
+
+// Elements kept in the sanitized HTML; everything else is unwrapped to its children.
+var allowedElements = map[atom.Atom]bool{
+	atom.P: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Blockquote: true, atom.Pre: true, atom.Code: true,
+	atom.Em: true, atom.Strong: true, atom.B: true, atom.I: true, atom.A: true, atom.Img: true, atom.Br: true,
+	atom.Figure: true, atom.Figcaption: true, atom.Table: true, atom.Thead: true, atom.Tbody: true,
+	atom.Tr: true, atom.Th: true, atom.Td: true, atom.Hr: true,
+}
+
+// Attributes kept per element. URLs are only kept when they are http(s) or relative.
+var allowedAttributes = map[atom.Atom][]string{
+	atom.A:   {"href"},
+	atom.Img: {"src", "alt"},
+}
+
+// Elements whose content is never visible text. Unlike skippedElements, this keeps
+// navigation and other page furniture, which a user may well have selected.
+var hiddenElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Iframe: true, atom.Svg: true, atom.Object: true, atom.Embed: true,
+}
+
+// Elements that end a line of text in the plain-text rendering.
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Li: true, atom.Blockquote: true, atom.Pre: true, atom.Br: true, atom.Tr: true, atom.Figcaption: true,
+	atom.Section: true, atom.Article: true,
+}
+
+// Parse extracts the article from an HTML document. The article body is the <article>
+// element when there is one, else <main>, else the element whose direct paragraphs
+// hold the most text.
+func Parse(document []byte) (*Article, error) {
+	root, err := html.Parse(bytes.NewReader(document))
+	if err != nil {
+		return nil, err
+	}
+
+	article := &Article{}
+	article.Title, article.SiteName, article.FaviconURL, article.ImageURL = metadata(root)
+	article.Language = documentLanguage(root)
+
+	body := findFirst(root, atom.Article)
+	if body == nil {
+		body = findFirst(root, atom.Main)
+	}
+	if body == nil {
+		body = densestElement(root)
+	}
+	if body == nil {
+		body = findFirst(root, atom.Body)
+	}
+	if body == nil {
+		return article, nil
+	}
+
+	var htmlBuf bytes.Buffer
+	for c := body.FirstChild; c != nil; c = c.NextSibling {
+		renderSanitized(&htmlBuf, c)
+	}
+	article.HTML = strings.TrimSpace(htmlBuf.String())
+
+	var textBuf strings.Builder
+	renderText(&textBuf, body, skippedElements)
+	article.Text = normalizeText(textBuf.String())
+	article.WordCount = len(strings.Fields(article.Text))
+	return article, nil
+}
+
+// FragmentText returns the plain text of an HTML fragment, such as the selection
+// a browser copies from a page. Markup is dropped, and so are scripts, styles and
+// other elements without visible text. Plain text comes back with its whitespace
+// normalized.
+func FragmentText(fragment string) string {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), body)
+	if err != nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, n := range nodes {
+		renderText(&sb, n, hiddenElements)
+	}
+	return normalizeText(sb.String())
+}
+
+func metadata(root *html.Node) (title, siteName, icon, image string) {
+	var ogTitle, touchIcon, twitterImage string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Title:
+				if title == "" && n.FirstChild != nil {
+					title = strings.TrimSpace(n.FirstChild.Data)
+				}
+			case atom.Meta:
+				property := attr(n, "property")
+				content := strings.TrimSpace(attr(n, "content"))
+				switch property {
+				case "og:title":
+					ogTitle = content
+				case "og:site_name":
+					siteName = content
+				case "og:image", "og:image:url", "og:image:secure_url":
+					if image == "" && safeURL(content) {
+						image = content
+					}
+				}
+				if name := attr(n, "name"); (name == "twitter:image" || property == "twitter:image") && twitterImage == "" && safeURL(content) {
+					twitterImage = content
+				}
+			case atom.Link:
+				href := strings.TrimSpace(attr(n, "href"))
+				if href == "" || !safeURL(href) {
+					break
+				}
+				for _, rel := range strings.Fields(strings.ToLower(attr(n, "rel"))) {
+					if rel == "icon" && icon == "" {
+						icon = href
+					} else if rel == "apple-touch-icon" && touchIcon == "" {
+						touchIcon = href
+					}
+				}
+			case atom.Body:
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	if ogTitle != "" {
+		title = ogTitle
+	}
+	if icon == "" {
+		icon = touchIcon
+	}
+	if image == "" {
+		image = twitterImage
+	}
+	return title, siteName, icon, image
+}
+
+// documentLanguage returns the lang attribute of the <html> element, falling back
+// to the Content-Language and og:locale meta tags.
+func documentLanguage(root *html.Node) string {
+	if n := findFirst(root, atom.Html); n != nil {
+		if lang := strings.TrimSpace(attr(n, "lang")); lang != "" {
+			return lang
+		}
+	}
+	var contentLanguage, locale string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Meta:
+				content := strings.TrimSpace(attr(n, "content"))
+				if strings.EqualFold(attr(n, "http-equiv"), "content-language") && contentLanguage == "" {
+					// The header may list several languages; the first is the main one.
+					contentLanguage = strings.TrimSpace(strings.Split(content, ",")[0])
+				} else if attr(n, "property") == "og:locale" && locale == "" {
+					locale = content
+				}
+			case atom.Body:
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	if contentLanguage != "" {
+		return contentLanguage
+	}
+	return locale
+}
+
+func findFirst(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findFirst(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// densestElement returns the element whose direct <p> children contain the most text.
+func densestElement(root *html.Node) *html.Node {
+	var best *html.Node
+	bestScore := 0
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && skippedElements[n.DataAtom] {
+			return
+		}
+		score := 0
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && c.DataAtom == atom.P {
+				var sb strings.Builder
+				renderText(&sb, c, skippedElements)
+				score += len(strings.TrimSpace(sb.String()))
+			}
+		}
+		if score > bestScore {
+			best, bestScore = n, score
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	return best
+}
+
+func renderSanitized(buf *bytes.Buffer, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		buf.WriteString(html.EscapeString(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+	if skippedElements[n.DataAtom] {
+		return
+	}
+
+	allowed := allowedElements[n.DataAtom]
+	if allowed {
+		buf.WriteByte('<')
+		buf.WriteString(n.Data)
+		for _, name := range allowedAttributes[n.DataAtom] {
+			value := attr(n, name)
+			if value == "" || !safeURL(value) {
+				continue
+			}
+			buf.WriteString(" " + name + `="` + html.EscapeString(value) + `"`)
+		}
+		buf.WriteByte('>')
+		if n.DataAtom == atom.Img || n.DataAtom == atom.Br || n.DataAtom == atom.Hr {
+			return
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		renderSanitized(buf, c)
+	}
+	if allowed {
+		buf.WriteString("</" + n.Data + ">")
+	}
+}
+
+func renderText(sb *strings.Builder, n *html.Node, skipped map[atom.Atom]bool) {
+	switch n.Type {
+	case html.TextNode:
+		sb.WriteString(n.Data)
+		return
+	case html.ElementNode:
+		if skipped[n.DataAtom] {
+			return
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		renderText(sb, c, skipped)
+	}
+	if n.Type == html.ElementNode && blockElements[n.DataAtom] {
+		sb.WriteString("\n")
+	}
+}
+
+// normalizeText collapses whitespace within lines and drops empty lines.
+func normalizeText(s string) string {
+	lines := strings.Split(s, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		line = strings.Join(strings.FieldsFunc(line, unicode.IsSpace), " ")
+		if line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func safeURL(value string) bool {
+	lower := strings.ToLower(strings.TrimSpace(value))
+	if i := strings.Index(lower, ":"); i >= 0 && !strings.ContainsAny(lower[:i], "/?#") {
+		return strings.HasPrefix(lower, "http:") || strings.HasPrefix(lower, "https:")
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package extract
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const page = `<!doctype html>
+<html lang="en-GB"><head>
+<title>Fallback title</title>
+<meta property="og:title" content="The Real Title">
+<meta property="og:site_name" content="Example Blog">
+<meta name="twitter:image" content="/twitter.png">
+<meta property="og:image" content="https://cdn.example.com/cover.jpg">
+<link rel="apple-touch-icon" href="/touch.png">
+<link rel="shortcut icon" href="/static/icon.png">
+<script>var tracking = 1;</script>
+</head><body>
+<nav><a href="/">Home</a></nav>
+<article>
+  <h1>The Real Title</h1>
+  <p>First <strong>paragraph</strong> with a <a href="https://example.com/x" onclick="evil()">link</a>.</p>
+  <div class="ad"><script>ads()</script></div>
+  <p>Second   paragraph.</p>
+  <a href="javascript:alert(1)">bad</a>
+</article>
+<footer>Copyright</footer>
+</body></html>`
+
+func TestParse(t *testing.T) {
+	article, err := Parse([]byte(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if article.Title != "The Real Title" {
+		t.Errorf("Title = %q", article.Title)
+	}
+	if article.SiteName != "Example Blog" {
+		t.Errorf("SiteName = %q", article.SiteName)
+	}
+	if article.FaviconURL != "/static/icon.png" {
+		t.Errorf("FaviconURL = %q", article.FaviconURL)
+	}
+	if article.ImageURL != "https://cdn.example.com/cover.jpg" {
+		t.Errorf("ImageURL = %q", article.ImageURL)
+	}
+	if article.Language != "en-GB" {
+		t.Errorf("Language = %q", article.Language)
+	}
+
+	wantText := "The Real Title\nFirst paragraph with a link.\nSecond paragraph.\nbad"
+	if article.Text != wantText {
+		t.Errorf("Text = %q, want %q", article.Text, wantText)
+	}
+	if article.WordCount != 11 {
+		t.Errorf("WordCount = %d", article.WordCount)
+	}
+
+	for _, unwanted := range []string{"script", "onclick", "javascript:", "Home", "Copyright", "<div"} {
+		if strings.Contains(article.HTML, unwanted) {
+			t.Errorf("HTML contains %q: %s", unwanted, article.HTML)
+		}
+	}
+	if !strings.Contains(article.HTML, `<a href="https://example.com/x">link</a>`) {
+		t.Errorf("HTML lost the safe link: %s", article.HTML)
+	}
+}
+
+func TestParseWithoutArticleUsesDensestElement(t *testing.T) {
+	doc := `<html><body><div id="menu"><p>Menu</p></div><div id="content"><p>A long paragraph of real content.</p><p>And another one.</p></div></body></html>`
+	article, err := Parse([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(article.Text, "Menu") || !strings.Contains(article.Text, "real content") {
+		t.Errorf("Text = %q", article.Text)
+	}
+}
+
+func TestResolveFavicon(t *testing.T) {
+	page, _ := url.Parse("https://blog.example.com/posts/1")
+	cases := map[string]string{
+		"":                             "https://blog.example.com/favicon.ico",
+		"icon.png":                     "https://blog.example.com/posts/icon.png",
+		"//cdn.example.com/i.ico":      "https://cdn.example.com/i.ico",
+		"https://static.example.com/a": "https://static.example.com/a",
+		"data:image/png;base64,xyz":    "",
+	}
+	for href, want := range cases {
+		if got := resolveFavicon(page, href); got != want {
+			t.Errorf("resolveFavicon(%q) = %q, want %q", href, got, want)
+		}
+	}
+}
+
+func TestFragmentText(t *testing.T) {
+	cases := map[string]string{
+		"Plain   text\n\n\nover lines": "Plain text\nover lines",
+		`<p>First <b>bold</b></p><script>alert(1)</script><p>Second &amp; last</p>`: "First bold\nSecond & last",
+		`<nav>Menu</nav><style>p{}</style><img src=x onerror="alert(1)">`:           "Menu",
+		"": "",
+	}
+	for in, want := range cases {
+		if got := FragmentText(in); got != want {
+			t.Errorf("FragmentText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package extract
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+// ErrNotFeed is returned for documents that are neither RSS nor Atom.
+var ErrNotFeed = errors.New("document is not an RSS or Atom feed")
+
+// Feed is an RSS or Atom feed reduced to what bookmarks are made from.
+type Feed struct {
+	Title   string
+	Entries []FeedEntry
+}
+
+// FeedEntry is one item of a feed. ID is the entry's guid or id, else its link,
+// and identifies the entry across fetches. Published is zero when the entry
+// carries no readable date.
+type FeedEntry struct {
+	ID        string
+	URL       string
+	Title     string
+	Summary   string
+	Published time.Time
+}
+
+type feedLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Text string `xml:",chardata"`
+}
+
+type feedItem struct {
+	Title       string     `xml:"title"`
+	Links       []feedLink `xml:"link"`
+	GUID        string     `xml:"guid"`
+	ID          string     `xml:"id"`
+	Description string     `xml:"description"`
+	Summary     string     `xml:"summary"`
+	Content     string     `xml:"content"`
+	PubDate     string     `xml:"pubDate"`
+	Date        string     `xml:"date"`
+	Published   string     `xml:"published"`
+	Updated     string     `xml:"updated"`
+}
+
+// feedDocument covers RSS 2.0 (items in the channel), RSS 1.0 (items beside it)
+// and Atom (entries in the feed). Element names match in any namespace.
+type feedDocument struct {
+	XMLName xml.Name
+	Channel struct {
+		Title string     `xml:"title"`
+		Items []feedItem `xml:"item"`
+	} `xml:"channel"`
+	Title   string     `xml:"title"`
+	Items   []feedItem `xml:"item"`
+	Entries []feedItem `xml:"entry"`
+}
+
+// Feed dates come in RFC 822 for RSS, with many variations in the wild, and
+// RFC 3339 for Atom and Dublin Core.
+var feedDateLayouts = []string{
+	time.RFC1123Z, time.RFC1123, "Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700", "2 Jan 2006 15:04:05 MST", time.RFC822Z, time.RFC822,
+	time.RFC3339, "2006-01-02T15:04:05", "2006-01-02",
+}
+
+// ParseFeed reads an RSS or Atom document. Relative entry links are resolved
+// against base, and entries without an http or https link are left out.
+func ParseFeed(document []byte, base *url.URL) (*Feed, error) {
+	dec := xml.NewDecoder(bytes.NewReader(document))
+	dec.CharsetReader = charset.NewReaderLabel
+	dec.Strict = false
+	var doc feedDocument
+	if err := dec.Decode(&doc); err != nil {
+		return nil, ErrNotFeed
+	}
+
+	feed := &Feed{}
+	var items []feedItem
+	switch strings.ToLower(doc.XMLName.Local) {
+	case "rss":
+		feed.Title = doc.Channel.Title
+		items = doc.Channel.Items
+	case "rdf":
+		feed.Title = doc.Channel.Title
+		items = doc.Items
+	case "feed":
+		feed.Title = doc.Title
+		items = doc.Entries
+	default:
+		return nil, ErrNotFeed
+	}
+	feed.Title = FragmentText(feed.Title)
+
+	for _, item := range items {
+		link := itemLink(item.Links)
+		if link == "" {
+			continue
+		}
+		if base != nil {
+			link = resolveLink(base, link)
+		} else if u, err := url.Parse(link); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			link = ""
+		}
+		if link == "" {
+			continue
+		}
+		entry := FeedEntry{URL: link, Title: FragmentText(item.Title)}
+		entry.ID = strings.TrimSpace(firstNonEmpty(item.GUID, item.ID))
+		if entry.ID == "" {
+			entry.ID = link
+		}
+		entry.Summary = FragmentText(firstNonEmpty(item.Description, item.Summary, item.Content))
+		entry.Published = parseFeedDate(firstNonEmpty(item.PubDate, item.Published, item.Date, item.Updated))
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed, nil
+}
+
+// itemLink returns the RSS link text or the Atom alternate link of an item.
+func itemLink(links []feedLink) string {
+	for _, l := range links {
+		if l.Href == "" && strings.TrimSpace(l.Text) != "" {
+			return strings.TrimSpace(l.Text)
+		}
+		if l.Href != "" && (l.Rel == "" || l.Rel == "alternate") {
+			return strings.TrimSpace(l.Href)
+		}
+	}
+	return ""
+}
+
+func parseFeedDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	for _, layout := range feedDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
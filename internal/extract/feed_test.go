@@ -0,0 +1,72 @@
+package extract
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseFeedRSS(t *testing.T) {
+	const rss = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<rss version="2.0"><channel>
+<title>Example &amp; Co</title>
+<item>
+  <title>First post</title>
+  <link>/posts/1</link>
+  <guid isPermaLink="false">post-1</guid>
+  <pubDate>Tue, 13 Oct 2026 09:30:00 +0000</pubDate>
+  <description>&lt;p&gt;Hello &lt;b&gt;world&lt;/b&gt;&lt;/p&gt;</description>
+</item>
+<item><title>No link</title></item>
+<item><title>Script</title><link>javascript:alert(1)</link></item>
+<item><title>Second</title><link>https://example.com/posts/2</link><pubDate>someday</pubDate></item>
+</channel></rss>`
+	base, _ := url.Parse("https://example.com/feed.xml")
+	feed, err := ParseFeed([]byte(rss), base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title != "Example & Co" {
+		t.Errorf("title = %q", feed.Title)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("entries = %+v, want 2", feed.Entries)
+	}
+	first := feed.Entries[0]
+	want := time.Date(2026, 10, 13, 9, 30, 0, 0, time.UTC)
+	if first.ID != "post-1" || first.URL != "https://example.com/posts/1" || first.Summary != "Hello world" || !first.Published.Equal(want) {
+		t.Errorf("first entry = %+v", first)
+	}
+	if second := feed.Entries[1]; second.ID != second.URL || !second.Published.IsZero() {
+		t.Errorf("second entry = %+v, want its link as ID and no date", second)
+	}
+}
+
+func TestParseFeedAtom(t *testing.T) {
+	const atom = `<feed xmlns="http://www.w3.org/2005/Atom">
+<title type="html">Atom &lt;em&gt;Feed&lt;/em&gt;</title>
+<entry>
+  <id>tag:example.com,2026:1</id>
+  <title>Entry</title>
+  <link rel="self" href="https://example.com/entries/1.atom"/>
+  <link rel="alternate" href="https://example.com/entries/1"/>
+  <updated>2026-10-12T08:00:00Z</updated>
+  <summary>Short</summary>
+</entry>
+</feed>`
+	feed, err := ParseFeed([]byte(atom), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title != "Atom Feed" || len(feed.Entries) != 1 {
+		t.Fatalf("feed = %+v", feed)
+	}
+	e := feed.Entries[0]
+	if e.ID != "tag:example.com,2026:1" || e.URL != "https://example.com/entries/1" || e.Summary != "Short" || e.Published.IsZero() {
+		t.Errorf("entry = %+v", e)
+	}
+
+	if _, err := ParseFeed([]byte("<html><body>not a feed</body></html>"), nil); err != ErrNotFeed {
+		t.Errorf("HTML page: err = %v, want ErrNotFeed", err)
+	}
+}
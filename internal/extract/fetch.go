@@ -0,0 +1,215 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxDocumentBytes caps how much of a page is downloaded, and maxImageBytes how
+// much of an image.
+const (
+	maxDocumentBytes = 5 << 20
+	maxImageBytes    = 5 << 20
+)
+
+var (
+	ErrNotHTML  = errors.New("page is not an HTML document")
+	ErrNotImage = errors.New("response is not an image")
+)
+
+// Fetcher downloads pages. Unless AllowPrivate is set it refuses to connect to
+// loopback, private and link-local addresses, since the URLs come from users.
+type Fetcher struct {
+	AllowPrivate bool
+	client       *http.Client
+}
+
+func NewFetcher(allowPrivate bool) *Fetcher {
+	return &Fetcher{AllowPrivate: allowPrivate, client: NewClient(15*time.Second, allowPrivate)}
+}
+
+// NewClient returns an HTTP client with the given timeout that, unless
+// allowPrivate is set, refuses to connect to loopback, private and link-local
+// addresses. Every request to a URL that came from a user goes through one.
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowPrivate {
+		dialer.Control = checkAddress
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, Proxy: http.ProxyFromEnvironment},
+	}
+}
+
+func checkAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("refusing to fetch from non-public address %s", host)
+	}
+	return nil
+}
+
+// Fetch downloads rawURL and extracts its article.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Article, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "MarklyBot/1.0 (+https://markly.app)")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("page returned status %d", resp.StatusCode)
+	}
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil &&
+		mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, ErrNotHTML
+	}
+
+	document, err := io.ReadAll(io.LimitReader(resp.Body, maxDocumentBytes))
+	if err != nil {
+		return nil, err
+	}
+	article, err := Parse(document)
+	if err != nil {
+		return nil, err
+	}
+	article.FaviconURL = resolveFavicon(resp.Request.URL, article.FaviconURL)
+	if article.ImageURL != "" {
+		article.ImageURL = resolveLink(resp.Request.URL, article.ImageURL)
+	}
+	return article, nil
+}
+
+// FeedResponse is the result of a feed download. NotModified is set, and Feed
+// is nil, when the server confirmed the copy named by the validators is current.
+type FeedResponse struct {
+	Feed         *Feed
+	NotModified  bool
+	ETag         string
+	LastModified string
+}
+
+// FetchFeed downloads and parses the RSS or Atom feed at rawURL. The etag and
+// lastModified validators of an earlier response, when given, make the request
+// conditional.
+func (f *Fetcher) FetchFeed(ctx context.Context, rawURL, etag, lastModified string) (*FeedResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "MarklyBot/1.0 (+https://markly.app)")
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, text/xml;q=0.9")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &FeedResponse{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+	if resp.StatusCode == http.StatusNotModified {
+		result.NotModified = true
+		if result.ETag == "" {
+			result.ETag = etag
+		}
+		if result.LastModified == "" {
+			result.LastModified = lastModified
+		}
+		return result, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("feed returned status %d", resp.StatusCode)
+	}
+
+	document, err := io.ReadAll(io.LimitReader(resp.Body, maxDocumentBytes))
+	if err != nil {
+		return nil, err
+	}
+	if result.Feed, err = ParseFeed(document, resp.Request.URL); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// FetchImage downloads the image at rawURL, up to maxImageBytes. It fails for
+// responses that are not images.
+func (f *Fetcher) FetchImage(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "MarklyBot/1.0 (+https://markly.app)")
+	req.Header.Set("Accept", "image/png,image/jpeg,image/gif")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("image returned status %d", resp.StatusCode)
+	}
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil && !strings.HasPrefix(mediaType, "image/") {
+		return nil, ErrNotImage
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageBytes {
+		return nil, fmt.Errorf("image is larger than %d bytes", maxImageBytes)
+	}
+	return data, nil
+}
+
+// resolveFavicon returns the absolute URL of the icon declared by a page, or of
+// /favicon.ico on its host when none is declared.
+func resolveFavicon(page *url.URL, href string) string {
+	if href == "" {
+		href = "/favicon.ico"
+	}
+	return resolveLink(page, href)
+}
+
+// resolveLink returns href resolved against page, or "" unless it is an http or
+// https URL.
+func resolveLink(page *url.URL, href string) string {
+	ref, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	icon := page.ResolveReference(ref)
+	if icon.Scheme != "http" && icon.Scheme != "https" {
+		return ""
+	}
+	return icon.String()
+}
@@ -2,11 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"markly/internal/models"
 	"markly/internal/services"
 	"markly/internal/utils"
 	"net/http"
+	"strings"
 
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -14,15 +16,32 @@ import (
 )
 
 type AgentHandler struct {
-	agentService *services.AgentService
+	agentService     *services.AgentService
+	tagSubscriptions services.TagSubscriptionService
 }
 
-func NewAgentHandler(agentService *services.AgentService) *AgentHandler {
+func NewAgentHandler(agentService *services.AgentService, tagSubscriptions services.TagSubscriptionService) *AgentHandler {
 	return &AgentHandler{
-		agentService: agentService,
+		agentService:     agentService,
+		tagSubscriptions: tagSubscriptions,
 	}
 }
 
+// allowExternalAI writes an error response and returns false when the user's data
+// must not be sent to the LLM provider.
+func (a *AgentHandler) allowExternalAI(w http.ResponseWriter, userID primitive.ObjectID) bool {
+	err := a.agentService.CheckExternalAI(userID)
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, services.ErrExternalAIDisabled) {
+		utils.SendJSONError(w, err.Error(), http.StatusForbidden)
+	} else {
+		utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
+	}
+	return false
+}
+
 func (a *AgentHandler) GenerateSummary(w http.ResponseWriter, r *http.Request) {
 	userID, err := utils.GetUserIDFromContext(w, r)
 	if err != nil {
@@ -34,6 +53,10 @@ func (a *AgentHandler) GenerateSummary(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !a.allowExternalAI(w, userID) {
+		return
+	}
+
 	bookmark, err := a.agentService.GetBookmarkForSummary(userID, bookmarkID)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
@@ -45,20 +68,85 @@ func (a *AgentHandler) GenerateSummary(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	summary, err := services.LLMSummarize(bookmark.URL, bookmark.Title)
+	// ?highlights=true summarizes with the user's highlights of the page.
+	var highlights []string
+	if r.URL.Query().Get("highlights") == "true" {
+		highlights, err = a.agentService.GetHighlightTexts(userID, bookmarkID)
+		if err != nil {
+			utils.SendJSONError(w, "Failed to retrieve highlights", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// A page whose stored fingerprint has not changed since it was last summarized
+	// is not summarized again unless ?force=true. The fingerprint is updated when
+	// the page is extracted, not here, so the request does not wait for the page.
+	// Summaries of highlights depend on more than the page, so they are not reused.
+	contentHash := ""
+	if r.URL.Query().Get("highlights") != "true" {
+		contentHash = bookmark.ContentHash
+		if bookmark.Summary != "" && contentHash != "" && contentHash == bookmark.SummaryContentHash && r.URL.Query().Get("force") != "true" {
+			w.Header().Set("X-Summary-Unchanged", "true")
+			utils.RespondWithJSON(w, http.StatusOK, bookmark)
+			return
+		}
+	}
+
+	regenerated := bookmark.Summary != ""
+	summary, err := a.agentService.Summarize(r.Context(), bookmark.URL, bookmark.Title, highlights...)
 	if err != nil {
 		log.Error().Err(err).Str("bookmark_id", bookmarkID.Hex()).Msg("Error generating summary for bookmark")
 		utils.SendJSONError(w, "Failed to generate summary", http.StatusInternalServerError)
 		return
 	}
+	promptVersion := services.SummaryPrompt(highlights)
+	a.agentService.RecordAIEvent(models.AIEventKindSummary, models.AIEventGenerated, promptVersion, 1)
+	if regenerated {
+		a.agentService.RecordAIEvent(models.AIEventKindSummary, models.AIEventRegenerated, promptVersion, 1)
+	}
 
-	if err := a.agentService.UpdateBookmarkSummary(bookmarkID, userID, summary); err != nil {
+	if err := a.agentService.UpdateBookmarkSummary(bookmarkID, userID, summary, contentHash); err != nil {
 		log.Error().Err(err).Str("bookmark_id", bookmarkID.Hex()).Msg("Failed to save summary for bookmark")
 		utils.SendJSONError(w, "Failed to save summary", http.StatusInternalServerError)
 		return
 	}
 
 	bookmark.Summary = summary
+	w.Header().Set("X-Prompt-Version", promptVersion)
+	utils.RespondWithJSON(w, http.StatusOK, bookmark)
+}
+
+// TranslateSummary translates a bookmark's summary into the language given by ?to=.
+// ?refresh=true translates again instead of returning the stored translation.
+func (a *AgentHandler) TranslateSummary(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	bookmarkID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	if !a.allowExternalAI(w, userID) {
+		return
+	}
+
+	bookmark, err := a.agentService.TranslateSummary(r.Context(), userID, bookmarkID, r.URL.Query().Get("to"), r.URL.Query().Get("refresh") == "true")
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch {
+		case strings.HasPrefix(err.Error(), "invalid"):
+			statusCode = http.StatusBadRequest
+		case strings.Contains(err.Error(), "not found"):
+			statusCode = http.StatusNotFound
+		case strings.Contains(err.Error(), "no summary"), strings.Contains(err.Error(), "changed"):
+			statusCode = http.StatusConflict
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
 	utils.RespondWithJSON(w, http.StatusOK, bookmark)
 }
 
@@ -68,6 +156,10 @@ func (a *AgentHandler) GenerateAISuggestions(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if !a.allowExternalAI(w, userID) {
+		return
+	}
+
 	var filter models.PromptBookmarkFilter
 
 	bookmarkParams := r.URL.Query().Get("bookmarks")
@@ -132,17 +224,28 @@ func (a *AgentHandler) GenerateAISuggestions(w http.ResponseWriter, r *http.Requ
 	}
 
 	// Generate suggestions using LLM
-	suggestions, err := services.LLMGenerateSuggestions(promptBookmarks)
+	suggestions, err := a.agentService.GenerateSuggestions(r.Context(), promptBookmarks)
 	if err != nil {
 		log.Error().Err(err).Msg("Error generating AI suggestions")
 		utils.SendJSONError(w, fmt.Sprintf("Failed to generate AI suggestions: %v", err), http.StatusInternalServerError)
 		return
 	}
+	a.agentService.RecordAIEvent(models.AIEventKindSuggestion, models.AIEventGenerated, services.SuggestionsPromptVersion, len(suggestions))
+	a.tagSubscriptions.NotifySuggestions(userID, suggestions)
 
 	utils.RespondWithJSON(w, http.StatusOK, suggestions)
 }
 
 func (a *AgentHandler) SummarizeURL(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	if !a.allowExternalAI(w, userID) {
+		return
+	}
+
 	var req models.SummarizeURLRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		log.Error().Err(err).Msg("Invalid request payload for SummarizeURL")
@@ -156,12 +259,33 @@ func (a *AgentHandler) SummarizeURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	summary, err := services.LLMSummarize(req.URL, req.Title)
+	summary, err := a.agentService.Summarize(r.Context(), req.URL, req.Title)
 	if err != nil {
 		log.Error().Err(err).Str("url", req.URL).Msg("Error generating summary for URL")
 		utils.SendJSONError(w, "Failed to generate summary", http.StatusInternalServerError)
 		return
 	}
 
-	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"summary": summary})
+	a.agentService.RecordAIEvent(models.AIEventKindSummary, models.AIEventGenerated, services.SummaryPromptVersion, 1)
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"summary": summary, "prompt_version": services.SummaryPromptVersion})
+}
+
+func (a *AgentHandler) RecordFeedback(w http.ResponseWriter, r *http.Request) {
+	if _, err := utils.GetUserIDFromContext(w, r); err != nil {
+		return
+	}
+
+	var req models.AIFeedbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.agentService.RecordAIFeedback(req); err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
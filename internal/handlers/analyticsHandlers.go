@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"markly/internal/services"
@@ -21,6 +23,11 @@ func NewAnalyticsHandlers(analyticsService *services.AnalyticsService) *Analytic
 }
 
 func (h *AnalyticsHandlers) GetUserGrowth(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
 	startDateStr := r.URL.Query().Get("startDate")
 	endDateStr := r.URL.Query().Get("endDate")
 
@@ -40,8 +47,13 @@ func (h *AnalyticsHandlers) GetUserGrowth(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	userGrowth, err := h.AnalyticsService.GetUserGrowth(r.Context(), startDate, endDate)
+	query := r.URL.Query()
+	userGrowth, err := h.AnalyticsService.GetUserGrowth(r.Context(), userID, startDate, endDate, query.Get("interval"), query.Get("tz"))
 	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid") {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve user growth data")
 		return
 	}
@@ -50,6 +62,11 @@ func (h *AnalyticsHandlers) GetUserGrowth(w http.ResponseWriter, r *http.Request
 }
 
 func (h *AnalyticsHandlers) GetBookmarkActivity(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
 	startDateStr := r.URL.Query().Get("startDate")
 	endDateStr := r.URL.Query().Get("endDate")
 
@@ -69,8 +86,13 @@ func (h *AnalyticsHandlers) GetBookmarkActivity(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	bookmarkActivity, err := h.AnalyticsService.GetBookmarkActivity(r.Context(), startDate, endDate)
+	query := r.URL.Query()
+	bookmarkActivity, err := h.AnalyticsService.GetBookmarkActivity(r.Context(), userID, startDate, endDate, query.Get("interval"), query.Get("tz"))
 	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid") {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve bookmark activity data")
 		return
 	}
@@ -94,8 +116,28 @@ func (h *AnalyticsHandlers) GetBookmarkEngagement(w http.ResponseWriter, r *http
 	utils.RespondWithJSON(w, http.StatusOK, bookmarkEngagement)
 }
 
+func (h *AnalyticsHandlers) GetBookmarkSources(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	sources, err := h.AnalyticsService.GetBookmarkSources(r.Context(), userID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve bookmark source data")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, sources)
+}
+
 func (h *AnalyticsHandlers) GetTagTrends(w http.ResponseWriter, r *http.Request) {
-	tagTrends, err := h.AnalyticsService.GetTagTrends(r.Context())
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	tagTrends, err := h.AnalyticsService.GetTagTrends(r.Context(), userID)
 	if err != nil {
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve tag trends data")
 		return
@@ -113,3 +155,34 @@ func (h *AnalyticsHandlers) GetTrendingItems(w http.ResponseWriter, r *http.Requ
 
 	utils.RespondWithJSON(w, http.StatusOK, trendingItems)
 }
+
+// GetAIStats reports AI suggestion and summary outcomes over the last `days` days (default 30).
+func (h *AnalyticsHandlers) GetAIStats(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		d, err := strconv.Atoi(daysStr)
+		if err != nil || d < 1 || d > 365 {
+			utils.RespondWithError(w, http.StatusBadRequest, "days must be an integer between 1 and 365")
+			return
+		}
+		days = d
+	}
+
+	stats, err := h.AnalyticsService.GetAIStats(r.Context(), time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve AI statistics")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]interface{}{"days": days, "prompts": stats})
+}
+
+func (h *AnalyticsHandlers) GetTrendingDomains(w http.ResponseWriter, r *http.Request) {
+	domains, err := h.AnalyticsService.GetTrendingDomains(r.Context())
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve trending domains")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, domains)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/models"
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+type APIKeyHandler struct {
+	service services.APIKeyService
+}
+
+func NewAPIKeyHandler(service services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error().Err(err).Msg("Invalid JSON for CreateAPIKey")
+		utils.SendJSONError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.service.CreateAPIKey(r.Context(), userID, req)
+	if err != nil {
+		log.Error().Err(err).Msg("Error creating api key via service")
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "required") || strings.Contains(err.Error(), "invalid") {
+			statusCode = http.StatusBadRequest
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, created)
+}
+
+func (h *APIKeyHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	keys, err := h.service.GetAPIKeys(r.Context(), userID)
+	if err != nil {
+		log.Error().Err(err).Msg("Error getting api keys from service")
+		utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, keys)
+}
+
+func (h *APIKeyHandler) UpdateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	keyID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	var updatePayload models.APIKeyUpdate
+	if err := json.NewDecoder(r.Body).Decode(&updatePayload); err != nil {
+		log.Error().Err(err).Msg("Invalid JSON payload for UpdateAPIKey")
+		utils.SendJSONError(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.service.UpdateAPIKey(r.Context(), userID, keyID, updatePayload)
+	if err != nil {
+		log.Error().Err(err).Str("api_key_id", keyID.Hex()).Msg("Error updating api key via service")
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "no fields to update") || strings.Contains(err.Error(), "invalid") || strings.Contains(err.Error(), "required") {
+			statusCode = http.StatusBadRequest
+		} else if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, updated)
+}
+
+func (h *APIKeyHandler) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	keyID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	deleted, err := h.service.DeleteAPIKey(r.Context(), userID, keyID)
+	if err != nil {
+		log.Error().Err(err).Str("api_key_id", keyID.Hex()).Msg("Error deleting api key via service")
+		if strings.Contains(err.Error(), "not found") {
+			utils.SendJSONError(w, err.Error(), http.StatusNotFound)
+		} else {
+			utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if deleted {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -74,7 +74,7 @@ func (a *AuthHandler) ProviderCallback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Info().Str("email", PUser.Email).Msg("User authenticated with provider, attempting to handle login")
-	token, err := a.authService.HandleLogin(r.Context(), PUser)
+	token, err := a.authService.HandleLogin(r.Context(), PUser, utils.ClientIP(r), r.UserAgent())
 
 	if err != nil {
 		log.Error().Err(err).Msg("Error handling login after provider authentication")
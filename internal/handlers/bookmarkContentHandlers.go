@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+type BookmarkContentHandler struct {
+	service services.BookmarkContentService
+}
+
+func NewBookmarkContentHandler(service services.BookmarkContentService) *BookmarkContentHandler {
+	return &BookmarkContentHandler{service: service}
+}
+
+// GetContent returns the readable-mode content of a bookmark. The optional format
+// query parameter ("text" or "html") omits the other representation, and refresh=true
+// extracts the page again.
+func (h *BookmarkContentHandler) GetContent(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	bookmarkID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "text" && format != "html" {
+		utils.SendJSONError(w, "invalid format. Must be 'text' or 'html'.", http.StatusBadRequest)
+		return
+	}
+
+	content, err := h.service.GetContent(r.Context(), userID, bookmarkID, r.URL.Query().Get("refresh") == "true")
+	if err != nil {
+		if sendForbidden(w, err) {
+			return
+		}
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		} else if strings.HasPrefix(err.Error(), "content extraction failed") {
+			statusCode = http.StatusBadGateway
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+
+	switch format {
+	case "text":
+		content.HTML = ""
+	case "html":
+		content.Text = ""
+	}
+	utils.RespondWithJSON(w, http.StatusOK, content)
+}
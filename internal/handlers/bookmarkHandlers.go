@@ -2,11 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 
 	_ "github.com/joho/godotenv/autoload"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"markly/internal/models"
 	"markly/internal/services"
@@ -27,17 +32,121 @@ func (h *BookmarkHandler) GetBookmarks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bookmarks, err := h.service.GetBookmarks(r.Context(), userID, r)
+	bookmarks, next, err := h.service.GetBookmarks(r.Context(), userID, r)
 	if err != nil {
+		if sendLimitExceeded(w, err) {
+			return
+		}
 		log.Error().Err(err).Msg("Error getting bookmarks from service")
-		utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		statusCode := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "invalid") {
+			statusCode = http.StatusBadRequest
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
 		return
 	}
+	if expands(r, "category") {
+		ptrs := make([]*models.Bookmark, len(bookmarks))
+		for i := range bookmarks {
+			ptrs[i] = &bookmarks[i]
+		}
+		h.service.ExpandCategories(r.Context(), userID, ptrs...)
+	}
 
+	utils.SetNextCursor(w, next)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(bookmarks)
 }
 
+// liteCacheMaxAge is how long clients may reuse a lite list without revalidating.
+const liteCacheMaxAge = 60 * time.Second
+
+// GetBookmarksLite serves the compact list for constrained clients. Unchanged pages
+// are answered with 304 Not Modified.
+func (h *BookmarkHandler) GetBookmarksLite(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	bookmarks, next, err := h.service.GetBookmarksLite(r.Context(), userID, r)
+	if err != nil {
+		if sendLimitExceeded(w, err) {
+			return
+		}
+		log.Error().Err(err).Msg("Error getting lite bookmarks from service")
+		statusCode := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "invalid") {
+			statusCode = http.StatusBadRequest
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+
+	utils.SetNextCursor(w, next)
+	utils.RespondWithCachedJSON(w, r, bookmarks, liteCacheMaxAge)
+}
+
+func (h *BookmarkHandler) SearchBookmarks(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	query := r.URL.Query()
+	var fuzzy *bool
+	if v := query.Get("fuzzy"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			utils.SendJSONError(w, "invalid fuzzy: must be true or false", http.StatusBadRequest)
+			return
+		}
+		fuzzy = &b
+	}
+	limit := 0
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			utils.SendJSONError(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	bookmarks, err := h.service.SearchBookmarks(r.Context(), userID, query.Get("q"), fuzzy, query.Get("in"), limit)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "invalid") || strings.Contains(err.Error(), "required") {
+			statusCode = http.StatusBadRequest
+		} else if errors.Is(err, services.ErrEncryptionNotConfigured) {
+			statusCode = http.StatusNotImplemented
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+	if expands(r, "category") {
+		ptrs := make([]*models.Bookmark, len(bookmarks))
+		for i := range bookmarks {
+			ptrs[i] = &bookmarks[i]
+		}
+		h.service.ExpandCategories(r.Context(), userID, ptrs...)
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, bookmarks)
+}
+
+// addBookmarkErrorStatus maps an error from adding a bookmark to a status code.
+func addBookmarkErrorStatus(err error) int {
+	if err.Error() == "URL and Title are required" ||
+		(err.Error() == "invalid tag ID format" || err.Error() == "invalid collection ID format" || err.Error() == "invalid category ID format") ||
+		(err.Error() == "invalid reference: "+err.Error()) || // This part needs to be more specific if possible
+		strings.HasPrefix(err.Error(), "invalid source client") ||
+		strings.HasPrefix(err.Error(), "invalid url") {
+		return http.StatusBadRequest
+	} else if errors.Is(err, services.ErrEncryptionNotConfigured) {
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
+
 func (h *BookmarkHandler) AddBookmark(w http.ResponseWriter, r *http.Request) {
 	userID, err := utils.GetUserIDFromContext(w, r)
 	if err != nil {
@@ -51,18 +160,15 @@ func (h *BookmarkHandler) AddBookmark(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Debug().Interface("request_body", reqBody).Msg("Received bookmark request")
+	log.Debug().Str("url", reqBody.URL).Msg("Received bookmark request")
 
 	bm, err := h.service.AddBookmark(r.Context(), userID, reqBody)
 	if err != nil {
-		log.Error().Err(err).Msg("Error adding bookmark via service")
-		statusCode := http.StatusInternalServerError
-		if err.Error() == "URL and Title are required" ||
-			(err.Error() == "invalid tag ID format" || err.Error() == "invalid collection ID format" || err.Error() == "invalid category ID format") ||
-			(err.Error() == "invalid reference: "+err.Error()) { // This part needs to be more specific if possible
-			statusCode = http.StatusBadRequest
+		if sendLimitExceeded(w, err) {
+			return
 		}
-		utils.SendJSONError(w, err.Error(), statusCode)
+		log.Error().Err(err).Msg("Error adding bookmark via service")
+		utils.SendJSONError(w, err.Error(), addBookmarkErrorStatus(err))
 		return
 	}
 
@@ -86,6 +192,9 @@ func (h *BookmarkHandler) GetBookmarkByID(w http.ResponseWriter, r *http.Request
 
 	bm, err := h.service.GetBookmarkByID(r.Context(), userID, bookmarkID)
 	if err != nil {
+		if sendForbidden(w, err) {
+			return
+		}
 		log.Error().Err(err).Str("bookmark_id", bookmarkID.Hex()).Msg("Error getting bookmark by ID from service")
 		if err.Error() == "bookmark not found" {
 			utils.SendJSONError(w, err.Error(), http.StatusNotFound)
@@ -94,10 +203,23 @@ func (h *BookmarkHandler) GetBookmarkByID(w http.ResponseWriter, r *http.Request
 		}
 		return
 	}
+	if expands(r, "category") {
+		h.service.ExpandCategories(r.Context(), userID, bm)
+	}
 
 	utils.RespondWithJSON(w, http.StatusOK, bm)
 }
 
+// expands reports whether the comma-separated expand query parameter names field.
+func expands(r *http.Request, field string) bool {
+	for _, f := range strings.Split(r.URL.Query().Get("expand"), ",") {
+		if strings.TrimSpace(f) == field {
+			return true
+		}
+	}
+	return false
+}
+
 func (h *BookmarkHandler) DeleteBookmark(w http.ResponseWriter, r *http.Request) {
 	userID, err := utils.GetUserIDFromContext(w, r)
 	if err != nil {
@@ -111,6 +233,9 @@ func (h *BookmarkHandler) DeleteBookmark(w http.ResponseWriter, r *http.Request)
 
 	deleted, err := h.service.DeleteBookmark(r.Context(), userID, bookmarkID)
 	if err != nil {
+		if sendForbidden(w, err) {
+			return
+		}
 		log.Error().Err(err).Str("bookmark_id", bookmarkID.Hex()).Msg("Error deleting bookmark via service")
 		if err.Error() == "bookmark not found or not authorized to delete" {
 			utils.SendJSONError(w, err.Error(), http.StatusNotFound)
@@ -146,14 +271,20 @@ func (h *BookmarkHandler) UpdateBookmark(w http.ResponseWriter, r *http.Request)
 
 	updatedBookmark, err := h.service.UpdateBookmark(r.Context(), userID, bookmarkID, updatePayload)
 	if err != nil {
+		if sendForbidden(w, err) || sendLimitExceeded(w, err) {
+			return
+		}
 		log.Error().Err(err).Str("bookmark_id", bookmarkID.Hex()).Msg("Error updating bookmark via service")
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "no valid fields provided for update" ||
 			(err.Error() == "invalid tag ID format" || err.Error() == "invalid collection ID format" || err.Error() == "invalid category ID format") ||
-			(err.Error() == "invalid tag reference" || err.Error() == "invalid collection reference" || err.Error() == "invalid category reference") {
+			(err.Error() == "invalid tag reference" || err.Error() == "invalid collection reference" || err.Error() == "invalid category reference") ||
+			strings.HasPrefix(err.Error(), "invalid url") {
 			statusCode = http.StatusBadRequest
 		} else if err.Error() == "bookmark not found or not authorized to update" {
 			statusCode = http.StatusNotFound
+		} else if errors.Is(err, services.ErrEncryptionNotConfigured) {
+			statusCode = http.StatusNotImplemented
 		}
 		utils.SendJSONError(w, err.Error(), statusCode)
 		return
@@ -162,3 +293,188 @@ func (h *BookmarkHandler) UpdateBookmark(w http.ResponseWriter, r *http.Request)
 	log.Info().Str("bookmark_id", bookmarkID.Hex()).Msg("Bookmark updated successfully")
 	utils.RespondWithJSON(w, http.StatusOK, updatedBookmark)
 }
+
+// FavoriteBookmark and UnfavoriteBookmark set the favorite status without a
+// request body, for keyboard shortcuts and the extension. Both are idempotent.
+func (h *BookmarkHandler) FavoriteBookmark(w http.ResponseWriter, r *http.Request) {
+	h.setFavorite(w, r, true)
+}
+
+func (h *BookmarkHandler) UnfavoriteBookmark(w http.ResponseWriter, r *http.Request) {
+	h.setFavorite(w, r, false)
+}
+
+func (h *BookmarkHandler) setFavorite(w http.ResponseWriter, r *http.Request, fav bool) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	bookmarkID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	bm, err := h.service.SetFavorite(r.Context(), userID, bookmarkID, fav)
+	if err != nil {
+		if sendForbidden(w, err) {
+			return
+		}
+		log.Error().Err(err).Str("bookmark_id", bookmarkID.Hex()).Msg("Error setting bookmark favorite status via service")
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, bm)
+}
+
+func (h *BookmarkHandler) GetDuplicateBookmarks(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	// ?by=content groups by page text instead of URL.
+	var groups []models.DuplicateGroup
+	switch r.URL.Query().Get("by") {
+	case "", "url":
+		groups, err = h.service.GetDuplicateBookmarks(r.Context(), userID)
+	case "content":
+		groups, err = h.service.GetContentDuplicates(r.Context(), userID)
+	default:
+		utils.SendJSONError(w, "Invalid by: must be url or content", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Error getting duplicate bookmarks from service")
+		utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
+
+func (h *BookmarkHandler) SuggestTags(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	tags, err := h.service.SuggestTags(r.Context(), userID, r.URL.Query().Get("url"))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "required") {
+			statusCode = http.StatusBadRequest
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string][]string{"tags": tags})
+}
+
+func (h *BookmarkHandler) MergeBookmark(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	targetID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	var req models.MergeBookmarkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	sourceID, err := primitive.ObjectIDFromHex(req.SourceID)
+	if err != nil {
+		utils.SendJSONError(w, "invalid source_id format", http.StatusBadRequest)
+		return
+	}
+
+	bm, err := h.service.MergeBookmarks(r.Context(), userID, targetID, sourceID)
+	if err != nil {
+		if sendForbidden(w, err) || sendLimitExceeded(w, err) {
+			return
+		}
+		log.Error().Err(err).Str("target_id", targetID.Hex()).Str("source_id", sourceID.Hex()).Msg("Error merging bookmarks via service")
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		} else if strings.HasPrefix(err.Error(), "invalid") {
+			statusCode = http.StatusBadRequest
+		} else if errors.Is(err, services.ErrEncryptionNotConfigured) {
+			statusCode = http.StatusNotImplemented
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, bm)
+}
+
+func (h *BookmarkHandler) BulkTagBookmarks(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var reqBody models.BulkTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		utils.SendJSONError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.BulkTag(r.Context(), userID, r, reqBody)
+	if err != nil {
+		if sendLimitExceeded(w, err) {
+			return
+		}
+		log.Error().Err(err).Msg("Error bulk tagging bookmarks via service")
+		statusCode := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "invalid") || strings.HasPrefix(err.Error(), "no tags provided") {
+			statusCode = http.StatusBadRequest
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, result)
+}
+
+func (h *BookmarkHandler) BatchCreateBookmarks(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var items []models.AddBookmarkRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+		utils.SendJSONError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.BatchCreate(r.Context(), userID, items)
+	if err != nil {
+		if sendLimitExceeded(w, err) {
+			return
+		}
+		log.Error().Err(err).Msg("Error batch creating bookmarks via service")
+		statusCode := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "invalid") || strings.HasPrefix(err.Error(), "no bookmarks provided") {
+			statusCode = http.StatusBadRequest
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, result)
+}
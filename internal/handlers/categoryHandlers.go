@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/gorilla/mux"
 	"github.com/rs/zerolog/log"
 
 	_ "github.com/joho/godotenv/autoload"
@@ -41,6 +44,8 @@ func (h *CategoryHandler) AddCategory(w http.ResponseWriter, r *http.Request) {
 		statusCode := http.StatusInternalServerError
 		if strings.Contains(err.Error(), "already exists") {
 			statusCode = http.StatusConflict
+		} else if strings.HasPrefix(err.Error(), "invalid") {
+			statusCode = http.StatusBadRequest
 		}
 		utils.SendJSONError(w, err.Error(), statusCode)
 		return
@@ -56,12 +61,26 @@ func (h *CategoryHandler) GetCategories(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	categories, err := h.service.GetCategories(r.Context(), userID)
+	page, err := utils.GetPageRequest(r)
+	if err != nil {
+		if sendLimitExceeded(w, err) {
+			return
+		}
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	categories, next, err := h.service.GetCategories(r.Context(), userID, page)
 	if err != nil {
 		log.Error().Err(err).Msg("Error getting categories from service")
-		utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		statusCode := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "invalid") {
+			statusCode = http.StatusBadRequest
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
 		return
 	}
+	utils.SetNextCursor(w, next)
 
 	log.Info().Int("count", len(categories)).Str("user_id", userID.Hex()).Msg("Categories retrieved successfully")
 	utils.RespondWithJSON(w, http.StatusOK, categories)
@@ -80,6 +99,9 @@ func (h *CategoryHandler) GetCategoryByID(w http.ResponseWriter, r *http.Request
 
 	category, err := h.service.GetCategoryByID(r.Context(), userID, categoryID)
 	if err != nil {
+		if sendForbidden(w, err) {
+			return
+		}
 		log.Error().Err(err).Str("category_id", categoryID.Hex()).Str("user_id", userID.Hex()).Msg("Error getting category by ID from service")
 		if strings.Contains(err.Error(), "not found") {
 			utils.SendJSONError(w, err.Error(), http.StatusNotFound)
@@ -106,6 +128,9 @@ func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request)
 
 	deleted, err := h.service.DeleteCategory(r.Context(), userID, categoryID)
 	if err != nil {
+		if sendForbidden(w, err) {
+			return
+		}
 		log.Error().Err(err).Str("category_id", categoryID.Hex()).Str("user_id", userID.Hex()).Msg("Error deleting category via service")
 		if strings.Contains(err.Error(), "not found") {
 			utils.SendJSONError(w, err.Error(), http.StatusNotFound)
@@ -141,6 +166,9 @@ func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request)
 
 	updatedCategory, err := h.service.UpdateCategory(r.Context(), userID, categoryID, updatePayload)
 	if err != nil {
+		if sendForbidden(w, err) {
+			return
+		}
 		log.Error().Err(err).Str("category_id", categoryID.Hex()).Str("user_id", userID.Hex()).Msg("Error updating category via service")
 		statusCode := http.StatusInternalServerError
 		if strings.Contains(err.Error(), "no fields to update") || strings.Contains(err.Error(), "invalid") {
@@ -157,3 +185,136 @@ func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request)
 	log.Info().Str("category_id", categoryID.Hex()).Str("user_id", userID.Hex()).Msg("Category updated successfully")
 	utils.RespondWithJSON(w, http.StatusOK, updatedCategory)
 }
+
+// GetCategoryBySlug resolves a slug for the web app's routes. A slug the category had
+// before a rename redirects to its current slug. The redirect is temporary because
+// the old slug may be taken back by renaming the category again.
+func (h *CategoryHandler) GetCategoryBySlug(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	slug := mux.Vars(r)["slug"]
+	result, err := h.service.GetCategoryBySlug(r.Context(), userID, slug)
+	if err != nil {
+		log.Error().Err(err).Str("slug", slug).Str("user_id", userID.Hex()).Msg("Error getting category by slug from service")
+		if strings.Contains(err.Error(), "not found") {
+			utils.SendJSONError(w, err.Error(), http.StatusNotFound)
+		} else {
+			utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if result.Slug != slug {
+		http.Redirect(w, r, "/api/categories/by-slug/"+result.Slug, http.StatusFound)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, result)
+}
+
+// GetCategoryColors lists the palette category colors are picked from.
+func (h *CategoryHandler) GetCategoryColors(w http.ResponseWriter, r *http.Request) {
+	utils.RespondWithJSON(w, http.StatusOK, models.CategoryColors)
+}
+
+// maxCategoryIconUpload bounds the size of uploaded icons before resizing.
+const maxCategoryIconUpload = 2 << 20
+
+// UploadCategoryIcon reads an image from the "icon" field of a multipart form.
+func (h *CategoryHandler) UploadCategoryIcon(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	categoryID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxCategoryIconUpload+1<<10)
+	file, _, err := r.FormFile("icon")
+	if err != nil {
+		utils.SendJSONError(w, "icon file is required, at most 2 MB", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxCategoryIconUpload+1))
+	if err != nil || len(data) > maxCategoryIconUpload {
+		utils.SendJSONError(w, "icon file is required, at most 2 MB", http.StatusBadRequest)
+		return
+	}
+
+	category, err := h.service.SetIcon(r.Context(), userID, categoryID, data)
+	if err != nil {
+		if sendForbidden(w, err) {
+			return
+		}
+		log.Error().Err(err).Str("category_id", categoryID.Hex()).Str("user_id", userID.Hex()).Msg("Error uploading category icon via service")
+		statusCode := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "invalid") {
+			statusCode = http.StatusBadRequest
+		} else if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, category)
+}
+
+func (h *CategoryHandler) GetCategoryIcon(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	categoryID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	icon, err := h.service.GetIcon(r.Context(), userID, categoryID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			utils.SendJSONError(w, err.Error(), http.StatusNotFound)
+		} else {
+			utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Icon URLs change with every upload, so a cached icon never goes stale.
+	w.Header().Set("Content-Type", icon.ContentType)
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	w.Header().Set("Content-Length", strconv.Itoa(len(icon.Data)))
+	w.Write(icon.Data)
+}
+
+func (h *CategoryHandler) DeleteCategoryIcon(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	categoryID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	if err := h.service.DeleteIcon(r.Context(), userID, categoryID); err != nil {
+		if sendForbidden(w, err) {
+			return
+		}
+		log.Error().Err(err).Str("category_id", categoryID.Hex()).Str("user_id", userID.Hex()).Msg("Error deleting category icon via service")
+		if strings.Contains(err.Error(), "not found") {
+			utils.SendJSONError(w, err.Error(), http.StatusNotFound)
+		} else {
+			utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
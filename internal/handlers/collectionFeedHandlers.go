@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/models"
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+type CollectionFeedHandler struct {
+	service services.CollectionFeedService
+}
+
+func NewCollectionFeedHandler(service services.CollectionFeedService) *CollectionFeedHandler {
+	return &CollectionFeedHandler{service: service}
+}
+
+// collectionFeedStatus maps collection feed service errors to status codes.
+func collectionFeedStatus(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "already"):
+		return http.StatusConflict
+	case strings.HasPrefix(err.Error(), "invalid") || strings.HasPrefix(err.Error(), "no "):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (h *CollectionFeedHandler) GetFeeds(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	collectionID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	feeds, err := h.service.ListFeeds(r.Context(), userID, collectionID)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), collectionFeedStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, feeds)
+}
+
+func (h *CollectionFeedHandler) AddFeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	collectionID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	var req models.AddCollectionFeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	feed, err := h.service.AddFeed(r.Context(), userID, collectionID, req)
+	if err != nil {
+		if sendLimitExceeded(w, err) {
+			return
+		}
+		log.Error().Err(err).Str("collection_id", collectionID.Hex()).Msg("Error adding collection feed via service")
+		utils.SendJSONError(w, err.Error(), collectionFeedStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, feed)
+}
+
+func (h *CollectionFeedHandler) UpdateFeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	collectionID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	feedID, err := utils.GetObjectIDFromVars(w, r, "feedId")
+	if err != nil {
+		return
+	}
+
+	var update models.CollectionFeedUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		utils.SendJSONError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	feed, err := h.service.UpdateFeed(r.Context(), userID, collectionID, feedID, update)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), collectionFeedStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, feed)
+}
+
+func (h *CollectionFeedHandler) RemoveFeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	collectionID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	feedID, err := utils.GetObjectIDFromVars(w, r, "feedId")
+	if err != nil {
+		return
+	}
+
+	removed, err := h.service.RemoveFeed(r.Context(), userID, collectionID, feedID)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), collectionFeedStatus(err))
+		return
+	}
+	if !removed {
+		utils.SendJSONError(w, "Feed not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
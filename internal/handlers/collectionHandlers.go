@@ -3,8 +3,10 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/gorilla/mux"
 	"github.com/rs/zerolog/log"
 
 	_ "github.com/joho/godotenv/autoload"
@@ -37,10 +39,15 @@ func (h *CollectionHandler) AddCollection(w http.ResponseWriter, r *http.Request
 
 	addedCollection, err := h.service.AddCollection(r.Context(), userID, col)
 	if err != nil {
+		if sendLimitExceeded(w, err) {
+			return
+		}
 		log.Error().Err(err).Msg("Error adding collection via service")
 		statusCode := http.StatusInternalServerError
 		if strings.Contains(err.Error(), "already exists") {
 			statusCode = http.StatusConflict
+		} else if strings.HasPrefix(err.Error(), "invalid") {
+			statusCode = http.StatusBadRequest
 		}
 		utils.SendJSONError(w, err.Error(), statusCode)
 		return
@@ -56,12 +63,26 @@ func (h *CollectionHandler) GetCollections(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	collections, err := h.service.GetCollections(r.Context(), userID)
+	page, err := utils.GetPageRequest(r)
+	if err != nil {
+		if sendLimitExceeded(w, err) {
+			return
+		}
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	collections, next, err := h.service.GetCollections(r.Context(), userID, page)
 	if err != nil {
 		log.Error().Err(err).Msg("Error getting collections from service")
-		utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		statusCode := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "invalid") {
+			statusCode = http.StatusBadRequest
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
 		return
 	}
+	utils.SetNextCursor(w, next)
 
 	log.Info().Int("count", len(collections)).Str("user_id", userID.Hex()).Msg("Collections retrieved successfully")
 	utils.RespondWithJSON(w, http.StatusOK, collections)
@@ -80,6 +101,9 @@ func (h *CollectionHandler) GetCollection(w http.ResponseWriter, r *http.Request
 
 	col, err := h.service.GetCollectionByID(r.Context(), userID, collectionID)
 	if err != nil {
+		if sendForbidden(w, err) {
+			return
+		}
 		log.Error().Err(err).Str("collection_id", collectionID.Hex()).Str("user_id", userID.Hex()).Msg("Error getting collection by ID from service")
 		if strings.Contains(err.Error(), "not found") {
 			utils.SendJSONError(w, err.Error(), http.StatusNotFound)
@@ -106,6 +130,9 @@ func (h *CollectionHandler) DeleteCollection(w http.ResponseWriter, r *http.Requ
 
 	deleted, err := h.service.DeleteCollection(r.Context(), userID, collectionID)
 	if err != nil {
+		if sendForbidden(w, err) {
+			return
+		}
 		log.Error().Err(err).Str("collection_id", collectionID.Hex()).Str("user_id", userID.Hex()).Msg("Error deleting collection via service")
 		if strings.Contains(err.Error(), "not found") {
 			utils.SendJSONError(w, err.Error(), http.StatusNotFound)
@@ -141,6 +168,9 @@ func (h *CollectionHandler) UpdateCollection(w http.ResponseWriter, r *http.Requ
 
 	updatedCollection, err := h.service.UpdateCollection(r.Context(), userID, collectionID, updatePayload)
 	if err != nil {
+		if sendForbidden(w, err) {
+			return
+		}
 		log.Error().Err(err).Str("collection_id", collectionID.Hex()).Str("user_id", userID.Hex()).Msg("Error updating collection via service")
 		statusCode := http.StatusInternalServerError
 		if strings.Contains(err.Error(), "no fields to update") || strings.Contains(err.Error(), "invalid") {
@@ -157,3 +187,104 @@ func (h *CollectionHandler) UpdateCollection(w http.ResponseWriter, r *http.Requ
 	log.Info().Str("collection_id", collectionID.Hex()).Str("user_id", userID.Hex()).Msg("Collection updated successfully")
 	utils.RespondWithJSON(w, http.StatusOK, updatedCollection)
 }
+
+func (h *CollectionHandler) PreviewAutoArchive(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	collectionID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	// after_days (and optionally unread_only) preview a policy that is not saved yet.
+	var policy *models.AutoArchivePolicy
+	if afterDays := r.URL.Query().Get("after_days"); afterDays != "" {
+		days, err := strconv.Atoi(afterDays)
+		if err != nil {
+			utils.SendJSONError(w, "invalid after_days: must be an integer", http.StatusBadRequest)
+			return
+		}
+		policy = &models.AutoArchivePolicy{AfterDays: days}
+		if unreadOnly := r.URL.Query().Get("unread_only"); unreadOnly != "" {
+			policy.UnreadOnly, err = strconv.ParseBool(unreadOnly)
+			if err != nil {
+				utils.SendJSONError(w, "invalid unread_only: must be 'true' or 'false'", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	preview, err := h.service.PreviewAutoArchive(r.Context(), userID, collectionID, policy)
+	if err != nil {
+		log.Error().Err(err).Str("collection_id", collectionID.Hex()).Str("user_id", userID.Hex()).Msg("Error previewing auto-archive via service")
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		} else if strings.Contains(err.Error(), "invalid") {
+			statusCode = http.StatusBadRequest
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, preview)
+}
+
+// GetCollectionBySlug resolves a slug for the web app's routes. A slug the collection had
+// before a rename redirects to its current slug. The redirect is temporary because
+// the old slug may be taken back by renaming the collection again.
+func (h *CollectionHandler) GetCollectionBySlug(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	slug := mux.Vars(r)["slug"]
+	result, err := h.service.GetCollectionBySlug(r.Context(), userID, slug)
+	if err != nil {
+		log.Error().Err(err).Str("slug", slug).Str("user_id", userID.Hex()).Msg("Error getting collection by slug from service")
+		if strings.Contains(err.Error(), "not found") {
+			utils.SendJSONError(w, err.Error(), http.StatusNotFound)
+		} else {
+			utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if result.Slug != slug {
+		http.Redirect(w, r, "/api/collections/by-slug/"+result.Slug, http.StatusFound)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, result)
+}
+
+func (h *CollectionHandler) GetCollectionStats(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	collectionID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	stats, err := h.service.GetCollectionStats(r.Context(), userID, collectionID)
+	if err != nil {
+		log.Error().Err(err).Str("collection_id", collectionID.Hex()).Str("user_id", userID.Hex()).Msg("Error computing collection stats via service")
+		if sendForbidden(w, err) {
+			return
+		}
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, stats)
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/models"
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+type CollectionTemplateHandler struct {
+	service services.CollectionTemplateService
+}
+
+func NewCollectionTemplateHandler(service services.CollectionTemplateService) *CollectionTemplateHandler {
+	return &CollectionTemplateHandler{service: service}
+}
+
+func templateErrorStatus(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "required"):
+		return http.StatusBadRequest
+	case strings.Contains(err.Error(), "already exists"):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func (h *CollectionTemplateHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	templates, err := h.service.ListTemplates(r.Context(), userID)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), templateErrorStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, templates)
+}
+
+func (h *CollectionTemplateHandler) CreateFromTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req models.CreateFromTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error().Err(err).Msg("Invalid JSON input for CreateFromTemplate")
+		utils.SendJSONError(w, "Invalid JSON input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.CreateFromTemplate(r.Context(), userID, req)
+	if err != nil {
+		if sendLimitExceeded(w, err) {
+			return
+		}
+		log.Error().Err(err).Msg("Error creating collection from template via service")
+		utils.SendJSONError(w, err.Error(), templateErrorStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, result)
+}
+
+func (h *CollectionTemplateHandler) SaveAsTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	collectionID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	var req models.SaveAsTemplateRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Error().Err(err).Msg("Invalid JSON input for SaveAsTemplate")
+			utils.SendJSONError(w, "Invalid JSON input: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	template, err := h.service.SaveAsTemplate(r.Context(), userID, collectionID, req)
+	if err != nil {
+		log.Error().Err(err).Str("collection_id", collectionID.Hex()).Msg("Error saving collection as template via service")
+		utils.SendJSONError(w, err.Error(), templateErrorStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, template)
+}
+
+func (h *CollectionTemplateHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	templateID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	if err := h.service.DeleteTemplate(r.Context(), userID, templateID); err != nil {
+		utils.SendJSONError(w, err.Error(), templateErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/models"
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+type ErasureHandler struct {
+	service services.ErasureService
+}
+
+func NewErasureHandler(service services.ErasureService) *ErasureHandler {
+	return &ErasureHandler{service: service}
+}
+
+// erasureStatus maps erasure service errors to status codes.
+func erasureStatus(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "not configured"):
+		return http.StatusServiceUnavailable
+	case strings.HasPrefix(err.Error(), "invalid") || strings.Contains(err.Error(), "required"):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (h *ErasureHandler) EraseUser(w http.ResponseWriter, r *http.Request) {
+	adminID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	userID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	var req models.EraseUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.EraseUser(r.Context(), adminID, userID, req, utils.ClientIP(r))
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.Hex()).Msg("Error erasing user via service")
+		utils.SendJSONError(w, err.Error(), erasureStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, report)
+}
+
+func (h *ErasureHandler) ListErasures(w http.ResponseWriter, r *http.Request) {
+	userID, ok := optionalUserID(w, r)
+	if !ok {
+		return
+	}
+
+	reports, err := h.service.List(r.Context(), userID)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, reports)
+}
+
+func (h *ErasureHandler) GetErasure(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	verification, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), erasureStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, verification)
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/models"
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+// maxImportBody bounds the size of an uploaded Markly export or CSV file.
+const maxImportBody = 64 << 20
+
+// maxCSVMappingSize bounds the mapping field of a CSV import.
+const maxCSVMappingSize = 16 << 10
+
+type ExportHandler struct {
+	service services.ExportService
+}
+
+func NewExportHandler(service services.ExportService) *ExportHandler {
+	return &ExportHandler{service: service}
+}
+
+func (h *ExportHandler) ExportMarkly(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	export, err := h.service.Export(r.Context(), userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Error exporting user data via service")
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+
+	filename := fmt.Sprintf("markly-export-%s.json", export.ExportedAt.Format("2006-01-02"))
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	utils.RespondWithJSON(w, http.StatusOK, export)
+}
+
+// ExportMarkdown downloads the user's bookmarks as a zip of Markdown files.
+func (h *ExportHandler) ExportMarkdown(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	files, err := h.service.ExportMarkdown(r.Context(), userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Error exporting Markdown via service")
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+
+	filename := fmt.Sprintf("markly-markdown-%s.zip", time.Now().UTC().Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.Name, Method: zip.Deflate, Modified: f.ModifiedAt})
+		if err == nil {
+			_, err = fw.Write(f.Content)
+		}
+		if err != nil {
+			log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to write Markdown export")
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to write Markdown export")
+	}
+}
+
+func (h *ExportHandler) ImportMarkly(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBody)
+	var export models.MarklyExport
+	if err := json.NewDecoder(r.Body).Decode(&export); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.SendJSONError(w, "export file is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		utils.SendJSONError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.Import(r.Context(), userID, &export)
+	if err != nil {
+		if sendLimitExceeded(w, err) {
+			return
+		}
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Error importing Markly export via service")
+		statusCode := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "invalid") {
+			statusCode = http.StatusBadRequest
+		} else if errors.Is(err, services.ErrEncryptionNotConfigured) {
+			statusCode = http.StatusNotImplemented
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, result)
+}
+
+// ImportCSV reads a multipart form with a "mapping" field holding the JSON column
+// mapping, followed by a "file" field with the CSV. The file is parsed as it is
+// uploaded, so the mapping has to come first.
+func (h *ExportHandler) ImportCSV(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBody)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		utils.SendJSONError(w, "Invalid request: expected multipart/form-data with mapping and file fields", http.StatusBadRequest)
+		return
+	}
+
+	var mapping *models.CSVColumnMapping
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			sendImportReadError(w, err, "Invalid multipart body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		switch part.FormName() {
+		case "mapping":
+			mapping = &models.CSVColumnMapping{}
+			if err := json.NewDecoder(io.LimitReader(part, maxCSVMappingSize)).Decode(mapping); err != nil {
+				utils.SendJSONError(w, "Invalid mapping JSON: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		case "file":
+			if mapping == nil {
+				utils.SendJSONError(w, "Invalid request: the mapping field must come before the file", http.StatusBadRequest)
+				return
+			}
+			result, err := h.service.ImportCSV(r.Context(), userID, part, *mapping)
+			if err != nil {
+				log.Error().Err(err).Str("userID", userID.Hex()).Msg("Error importing CSV via service")
+				if strings.HasPrefix(err.Error(), "invalid") {
+					utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				sendImportReadError(w, err, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			utils.RespondWithJSON(w, http.StatusOK, result)
+			return
+		}
+	}
+	utils.SendJSONError(w, "Invalid request: no file provided", http.StatusBadRequest)
+}
+
+// sendImportReadError reports a failed import upload with msg and statusCode,
+// unless the upload went over maxImportBody.
+func sendImportReadError(w http.ResponseWriter, err error, msg string, statusCode int) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		utils.SendJSONError(w, "import file is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	utils.SendJSONError(w, msg, statusCode)
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/models"
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+// githubStateCookie holds the state of the authorization the browser started. It
+// is only sent to the callback.
+const (
+	githubStateCookie     = "markly_github_state"
+	githubStateCookiePath = "/api/integrations/github/callback"
+)
+
+type GitHubHandler struct {
+	service services.GitHubService
+}
+
+func NewGitHubHandler(service services.GitHubService) *GitHubHandler {
+	return &GitHubHandler{service: service}
+}
+
+func githubErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrGitHubNotConfigured), errors.Is(err, services.ErrEncryptionNotConfigured):
+		return http.StatusNotImplemented
+	case errors.Is(err, utils.ErrLimitExceeded):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrGitHubPushNotAllowed):
+		return http.StatusForbidden
+	case strings.HasPrefix(err.Error(), "invalid"):
+		return http.StatusBadRequest
+	case strings.Contains(err.Error(), "not connected"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "revoked"), strings.Contains(err.Error(), "already running"), strings.Contains(err.Error(), "conflicted"):
+		return http.StatusConflict
+	case strings.HasPrefix(err.Error(), "failed to list github stars"), strings.HasPrefix(err.Error(), "failed to read github"), strings.HasPrefix(err.Error(), "failed to push to github"):
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+// Connect returns the GitHub page where the user approves the connection, and
+// gives the browser the state cookie the callback checks. ?push=true asks for
+// access to the account's repositories, to push Markdown exports.
+func (h *GitHubHandler) Connect(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	auth, err := h.service.Authorize(r.Context(), userID, r.URL.Query().Get("push") == "true")
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), githubErrorStatus(err))
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     githubStateCookie,
+		Value:    auth.State,
+		Path:     githubStateCookiePath,
+		Expires:  auth.ExpiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+	utils.RespondWithJSON(w, http.StatusOK, auth)
+}
+
+// Callback is where GitHub redirects the user's browser after the approval. The
+// state parameter identifies the user, so it needs no token, and must match the
+// state cookie of the browser that started the authorization.
+func (h *GitHubHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	browserState := ""
+	if cookie, err := r.Cookie(githubStateCookie); err == nil {
+		browserState = cookie.Value
+	}
+	http.SetCookie(w, &http.Cookie{Name: githubStateCookie, Path: githubStateCookiePath, MaxAge: -1, HttpOnly: true})
+
+	query := r.URL.Query()
+	if reason := query.Get("error"); reason != "" {
+		utils.SendJSONError(w, "github authorization was denied: "+reason, http.StatusBadRequest)
+		return
+	}
+
+	conn, err := h.service.Callback(r.Context(), query.Get("code"), query.Get("state"), browserState)
+	if err != nil {
+		log.Warn().Err(err).Msg("GitHub authorization callback failed")
+		utils.SendJSONError(w, err.Error(), githubErrorStatus(err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, conn)
+}
+
+func (h *GitHubHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	conn, err := h.service.Status(r.Context(), userID)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), githubErrorStatus(err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, conn)
+}
+
+func (h *GitHubHandler) Sync(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	result, err := h.service.Sync(r.Context(), userID)
+	if err != nil {
+		log.Warn().Err(err).Str("userID", userID.Hex()).Msg("GitHub stars sync failed")
+		utils.SendJSONError(w, err.Error(), githubErrorStatus(err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, result)
+}
+
+func (h *GitHubHandler) Disconnect(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	if err := h.service.Disconnect(r.Context(), userID); err != nil {
+		utils.SendJSONError(w, err.Error(), githubErrorStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PushMarkdown commits the user's Markdown export to a repository of the
+// connected GitHub account.
+func (h *GitHubHandler) PushMarkdown(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req models.MarkdownPushRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	result, err := h.service.PushMarkdown(r.Context(), userID, req)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), githubErrorStatus(err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/models"
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+type HighlightHandler struct {
+	service services.HighlightService
+}
+
+func NewHighlightHandler(service services.HighlightService) *HighlightHandler {
+	return &HighlightHandler{service: service}
+}
+
+func highlightErrorStatus(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "invalid") || strings.Contains(err.Error(), "required") || strings.Contains(err.Error(), "no valid fields"):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (h *HighlightHandler) GetHighlights(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+	bookmarkID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	highlights, err := h.service.GetHighlights(r.Context(), userID, bookmarkID)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), highlightErrorStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, highlights)
+}
+
+func (h *HighlightHandler) AddHighlight(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+	bookmarkID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	var req models.CreateHighlightRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error().Err(err).Msg("Invalid JSON input for AddHighlight")
+		utils.SendJSONError(w, "Invalid JSON input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	highlight, err := h.service.AddHighlight(r.Context(), userID, bookmarkID, req)
+	if err != nil {
+		if sendForbidden(w, err) {
+			return
+		}
+		utils.SendJSONError(w, err.Error(), highlightErrorStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, highlight)
+}
+
+func (h *HighlightHandler) UpdateHighlight(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+	bookmarkID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+	highlightID, err := utils.GetObjectIDFromVars(w, r, "highlightId")
+	if err != nil {
+		return
+	}
+
+	var updatePayload models.HighlightUpdate
+	if err := json.NewDecoder(r.Body).Decode(&updatePayload); err != nil {
+		log.Error().Err(err).Msg("Invalid JSON input for UpdateHighlight")
+		utils.SendJSONError(w, "Invalid JSON input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	highlight, err := h.service.UpdateHighlight(r.Context(), userID, bookmarkID, highlightID, updatePayload)
+	if err != nil {
+		if sendForbidden(w, err) {
+			return
+		}
+		utils.SendJSONError(w, err.Error(), highlightErrorStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, highlight)
+}
+
+func (h *HighlightHandler) DeleteHighlight(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+	bookmarkID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+	highlightID, err := utils.GetObjectIDFromVars(w, r, "highlightId")
+	if err != nil {
+		return
+	}
+
+	if err := h.service.DeleteHighlight(r.Context(), userID, bookmarkID, highlightID); err != nil {
+		if sendForbidden(w, err) {
+			return
+		}
+		utils.SendJSONError(w, err.Error(), highlightErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
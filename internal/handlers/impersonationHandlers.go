@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/models"
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+type ImpersonationHandler struct {
+	service      services.ImpersonationService
+	auditService services.AuditService
+}
+
+func NewImpersonationHandler(service services.ImpersonationService, auditService services.AuditService) *ImpersonationHandler {
+	return &ImpersonationHandler{service: service, auditService: auditService}
+}
+
+// impersonationStatus maps impersonation service errors to status codes.
+func impersonationStatus(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "not active"):
+		return http.StatusConflict
+	case strings.HasPrefix(err.Error(), "invalid") || strings.Contains(err.Error(), "required"):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (h *ImpersonationHandler) RequestImpersonation(w http.ResponseWriter, r *http.Request) {
+	adminID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req models.CreateImpersonationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	imp, err := h.service.Request(r.Context(), adminID, req, utils.ClientIP(r))
+	if err != nil {
+		log.Error().Err(err).Msg("Error requesting impersonation via service")
+		utils.SendJSONError(w, err.Error(), impersonationStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, imp)
+}
+
+func (h *ImpersonationHandler) ListImpersonations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := optionalUserID(w, r)
+	if !ok {
+		return
+	}
+
+	imps, err := h.service.List(r.Context(), userID)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, imps)
+}
+
+func (h *ImpersonationHandler) IssueToken(w http.ResponseWriter, r *http.Request) {
+	adminID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	token, err := h.service.IssueToken(r.Context(), adminID, id, utils.ClientIP(r))
+	if err != nil {
+		log.Error().Err(err).Str("impersonation_id", id.Hex()).Msg("Error issuing impersonation token via service")
+		utils.SendJSONError(w, err.Error(), impersonationStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, token)
+}
+
+func (h *ImpersonationHandler) ApproveImpersonation(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, h.service.Approve)
+}
+
+func (h *ImpersonationHandler) DenyImpersonation(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, h.service.Deny)
+}
+
+func (h *ImpersonationHandler) decide(w http.ResponseWriter, r *http.Request, decide func(ctx context.Context, token, ip string) (*models.Impersonation, error)) {
+	var req models.ImpersonationDecision
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	imp, err := decide(r.Context(), req.Token, utils.ClientIP(r))
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), impersonationStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, imp)
+}
+
+func (h *ImpersonationHandler) GetMyImpersonations(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	imps, err := h.service.ListForUser(r.Context(), userID)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, imps)
+}
+
+func (h *ImpersonationHandler) RevokeImpersonation(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	imp, err := h.service.Revoke(r.Context(), userID, id, utils.ClientIP(r))
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), impersonationStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, imp)
+}
+
+func (h *ImpersonationHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	userID, ok := optionalUserID(w, r)
+	if !ok {
+		return
+	}
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			utils.SendJSONError(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	events, err := h.auditService.List(r.Context(), userID, limit)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "invalid") {
+			statusCode = http.StatusBadRequest
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, events)
+}
+
+// optionalUserID parses the user_id query parameter, which filters admin listings.
+func optionalUserID(w http.ResponseWriter, r *http.Request) (*primitive.ObjectID, bool) {
+	v := r.URL.Query().Get("user_id")
+	if v == "" {
+		return nil, true
+	}
+	id, err := primitive.ObjectIDFromHex(v)
+	if err != nil {
+		utils.SendJSONError(w, "invalid user_id format", http.StatusBadRequest)
+		return nil, false
+	}
+	return &id, true
+}
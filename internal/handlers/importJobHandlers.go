@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/models"
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+type ImportJobHandler struct {
+	service services.ImportJobService
+}
+
+func NewImportJobHandler(service services.ImportJobService) *ImportJobHandler {
+	return &ImportJobHandler{service: service}
+}
+
+func importJobErrorStatus(err error) int {
+	switch {
+	case strings.HasPrefix(err.Error(), "invalid"):
+		return http.StatusBadRequest
+	case strings.Contains(err.Error(), "already running"):
+		return http.StatusConflict
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// ImportHackerNews queues an import of the favorites of a Hacker News account.
+func (h *ImportJobHandler) ImportHackerNews(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req models.HackerNewsImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	job, err := h.service.ImportHackerNews(r.Context(), userID, req.Username)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), importJobErrorStatus(err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusAccepted, job)
+}
+
+// ImportReddit reads a multipart form whose "file" field holds a Reddit export
+// and queues its import.
+func (h *ImportJobHandler) ImportReddit(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBody)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		utils.SendJSONError(w, "Invalid request: expected multipart/form-data with a file field", http.StatusBadRequest)
+		return
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			sendImportReadError(w, err, "Invalid multipart body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+		job, err := h.service.ImportReddit(r.Context(), userID, part)
+		if err != nil {
+			log.Warn().Err(err).Str("userID", userID.Hex()).Msg("Error starting Reddit import via service")
+			sendImportReadError(w, err, err.Error(), importJobErrorStatus(err))
+			return
+		}
+		utils.RespondWithJSON(w, http.StatusAccepted, job)
+		return
+	}
+	utils.SendJSONError(w, "Invalid request: no file provided", http.StatusBadRequest)
+}
+
+// GetImportJob reports the progress of an import job.
+func (h *ImportJobHandler) GetImportJob(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+	jobID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	job, err := h.service.GetJob(r.Context(), userID, jobID)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), importJobErrorStatus(err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, job)
+}
+
+// ListImports lists the user's newest background imports, running and finished.
+func (h *ImportJobHandler) ListImports(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			utils.SendJSONError(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	jobs, err := h.service.ListJobs(r.Context(), userID, limit)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), importJobErrorStatus(err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, jobs)
+}
+
+// GetImportErrors downloads the items an import job failed to save as CSV.
+func (h *ImportJobHandler) GetImportErrors(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+	jobID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	job, err := h.service.GetJob(r.Context(), userID, jobID)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), importJobErrorStatus(err))
+		return
+	}
+
+	filename := fmt.Sprintf("markly-import-%s-errors.csv", job.ID.Hex())
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"item", "url", "error"})
+	for _, e := range job.Errors {
+		cw.Write([]string{strconv.Itoa(e.Item), utils.CSVSafe(e.URL), utils.CSVSafe(e.Error)})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Error().Err(err).Str("jobID", job.ID.Hex()).Msg("Failed to write import errors CSV")
+	}
+}
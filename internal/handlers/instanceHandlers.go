@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/models"
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+type InstanceHandler struct {
+	service services.InstanceService
+}
+
+func NewInstanceHandler(service services.InstanceService) *InstanceHandler {
+	return &InstanceHandler{service: service}
+}
+
+func (h *InstanceHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.service.GetSettings(r.Context())
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, settings)
+}
+
+func (h *InstanceHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	adminID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var update models.InstanceSettingsUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		utils.SendJSONError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.service.UpdateSettings(r.Context(), adminID, update, utils.ClientIP(r))
+	if err != nil {
+		log.Error().Err(err).Str("admin_id", adminID.Hex()).Msg("Error updating instance settings via service")
+		statusCode := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "invalid") || strings.Contains(err.Error(), "no valid fields") {
+			statusCode = http.StatusBadRequest
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, settings)
+}
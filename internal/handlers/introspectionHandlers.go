@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/models"
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+type IntrospectionHandler struct {
+	service services.IntrospectionService
+}
+
+func NewIntrospectionHandler(service services.IntrospectionService) *IntrospectionHandler {
+	return &IntrospectionHandler{service: service}
+}
+
+func introspectionErrorStatus(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "disabled"):
+		return http.StatusNotFound
+	case strings.HasPrefix(err.Error(), "invalid"):
+		return http.StatusUnauthorized
+	}
+	return http.StatusInternalServerError
+}
+
+// Introspect is called by internal services, which authenticate with the
+// X-Introspection-Secret header instead of a user token.
+func (h *IntrospectionHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+	var req models.IntrospectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.Introspect(r.Context(), r.Header.Get("X-Introspection-Secret"), req.Token)
+	if err != nil {
+		log.Warn().Err(err).Str("ip", utils.ClientIP(r)).Msg("Token introspection rejected")
+		utils.SendJSONError(w, err.Error(), introspectionErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	utils.RespondWithJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/models"
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+type InviteHandler struct {
+	service services.InviteService
+}
+
+func NewInviteHandler(service services.InviteService) *InviteHandler {
+	return &InviteHandler{service: service}
+}
+
+func (h *InviteHandler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req models.CreateInviteRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendJSONError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	invite, err := h.service.CreateInvite(r.Context(), userID, req)
+	if err != nil {
+		log.Error().Err(err).Msg("Error creating invite via service")
+		if sendLimitExceeded(w, err) {
+			return
+		}
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "invalid") {
+			statusCode = http.StatusBadRequest
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, invite)
+}
+
+func (h *InviteHandler) GetInvites(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	overview, err := h.service.GetInvites(r.Context(), userID)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, overview)
+}
+
+func (h *InviteHandler) DeleteInvite(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	inviteID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	deleted, err := h.service.DeleteInvite(r.Context(), userID, inviteID)
+	if err != nil {
+		log.Error().Err(err).Str("invite_id", inviteID.Hex()).Msg("Error deleting invite via service")
+		if strings.Contains(err.Error(), "not found") {
+			utils.SendJSONError(w, err.Error(), http.StatusNotFound)
+		} else {
+			utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if deleted {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"markly/internal/utils"
+)
+
+// sendLimitExceeded answers 422 Unprocessable Entity when err is about a configured
+// limit, and reports whether it did.
+func sendLimitExceeded(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, utils.ErrLimitExceeded) {
+		return false
+	}
+	utils.SendJSONError(w, err.Error(), http.StatusUnprocessableEntity)
+	return true
+}
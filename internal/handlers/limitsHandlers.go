@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/models"
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+type LimitsHandler struct {
+	service services.LimitsService
+	// rateLimit reports the request rate limit that applies to a request.
+	rateLimit func(r *http.Request) models.RateLimitStatus
+}
+
+func NewLimitsHandler(service services.LimitsService, rateLimit func(r *http.Request) models.RateLimitStatus) *LimitsHandler {
+	return &LimitsHandler{service: service, rateLimit: rateLimit}
+}
+
+func (h *LimitsHandler) GetMyLimits(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	quotas, err := h.service.GetQuotas(r.Context(), userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Error retrieving quotas via service")
+		utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	utils.RespondWithJSON(w, http.StatusOK, models.LimitsStatus{
+		RateLimit: h.rateLimit(r),
+		Quotas:    quotas,
+		Limits:    utils.CurrentLimits(),
+	})
+}
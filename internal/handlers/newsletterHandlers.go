@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/models"
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+type NewsletterHandler struct {
+	service services.NewsletterService
+}
+
+func NewNewsletterHandler(service services.NewsletterService) *NewsletterHandler {
+	return &NewsletterHandler{service: service}
+}
+
+// newsletterStatus maps newsletter service errors to status codes.
+func newsletterStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrExternalAIDisabled):
+		return http.StatusForbidden
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "already"):
+		return http.StatusConflict
+	case strings.Contains(err.Error(), "not configured"):
+		return http.StatusServiceUnavailable
+	case strings.HasPrefix(err.Error(), "invalid") || strings.HasPrefix(err.Error(), "no ") || strings.Contains(err.Error(), "required"):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (h *NewsletterHandler) SendNewsletter(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	collectionID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	// The body is optional; an empty one sends with the defaults.
+	var req models.SendNewsletterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		utils.SendJSONError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	send, err := h.service.Send(r.Context(), userID, collectionID, req)
+	if err != nil {
+		log.Error().Err(err).Str("collection_id", collectionID.Hex()).Msg("Error sending newsletter via service")
+		utils.SendJSONError(w, err.Error(), newsletterStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusAccepted, send)
+}
+
+func (h *NewsletterHandler) GetSends(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	collectionID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	sends, err := h.service.ListSends(r.Context(), userID, collectionID)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), newsletterStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, sends)
+}
+
+func (h *NewsletterHandler) GetSubscribers(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	collectionID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	subs, err := h.service.ListSubscribers(r.Context(), userID, collectionID)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), newsletterStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, subs)
+}
+
+func (h *NewsletterHandler) AddSubscriber(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	collectionID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	var req models.AddNewsletterSubscriberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.service.AddSubscriber(r.Context(), userID, collectionID, req)
+	if sendLimitExceeded(w, err) {
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("collection_id", collectionID.Hex()).Msg("Error adding newsletter subscriber via service")
+		utils.SendJSONError(w, err.Error(), newsletterStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, sub)
+}
+
+func (h *NewsletterHandler) RemoveSubscriber(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	collectionID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	subscriberID, err := utils.GetObjectIDFromVars(w, r, "subscriberId")
+	if err != nil {
+		return
+	}
+
+	if err := h.service.RemoveSubscriber(r.Context(), userID, collectionID, subscriberID); err != nil {
+		utils.SendJSONError(w, err.Error(), newsletterStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *NewsletterHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	var req models.NewsletterConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Confirm(r.Context(), req.Token); err != nil {
+		utils.SendJSONError(w, err.Error(), newsletterStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Subscription confirmed"})
+}
+
+func (h *NewsletterHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	var req models.NewsletterUnsubscribeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Unsubscribe(r.Context(), req.Token); err != nil {
+		utils.SendJSONError(w, err.Error(), newsletterStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Unsubscribed"})
+}
@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+// sendForbidden answers 403 Forbidden when err is about another user's resource, and
+// reports whether it did. Services only return such errors when
+// REVEAL_RESOURCE_OWNERSHIP is on; otherwise those resources are not found.
+func sendForbidden(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, services.ErrForbidden) {
+		return false
+	}
+	utils.SendJSONError(w, err.Error(), http.StatusForbidden)
+	return true
+}
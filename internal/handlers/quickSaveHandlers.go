@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/models"
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+// maxQuickSaveBody bounds a quick save request, whose selection may be HTML many
+// times longer than its text.
+const maxQuickSaveBody = 1 << 20
+
+type QuickSaveHandler struct {
+	service services.QuickSaveService
+}
+
+func NewQuickSaveHandler(service services.QuickSaveService) *QuickSaveHandler {
+	return &QuickSaveHandler{service: service}
+}
+
+func (h *QuickSaveHandler) QuickSave(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxQuickSaveBody)
+	var req models.QuickSaveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.SendJSONError(w, "quick save request is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		utils.SendJSONError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.SummarizeSelection && !utils.HasScope(r.Context(), utils.ScopeAIInvoke) {
+		utils.SendJSONError(w, "insufficient scope: summarize_selection requires the ai:invoke scope", http.StatusForbidden)
+		return
+	}
+
+	bm, summaryStatus, err := h.service.QuickSave(r.Context(), userID, req)
+	if err != nil {
+		if sendLimitExceeded(w, err) {
+			return
+		}
+		log.Error().Err(err).Msg("Error quick saving bookmark via service")
+		utils.SendJSONError(w, err.Error(), addBookmarkErrorStatus(err))
+		return
+	}
+
+	if summaryStatus != "" {
+		w.Header().Set("X-Summary-Status", summaryStatus)
+	}
+	utils.RespondWithJSON(w, http.StatusCreated, bm)
+}
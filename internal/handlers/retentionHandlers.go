@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+type RetentionHandler struct {
+	service services.RetentionService
+}
+
+func NewRetentionHandler(service services.RetentionService) *RetentionHandler {
+	return &RetentionHandler{service: service}
+}
+
+func (h *RetentionHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := h.service.Status(r.Context())
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, status)
+}
+
+// Purge applies the retention policy now. It is a dry run unless dry_run=false
+// is passed, so an admin sees what a policy deletes before it does.
+func (h *RetentionHandler) Purge(w http.ResponseWriter, r *http.Request) {
+	adminID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	dryRun := true
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			utils.SendJSONError(w, "invalid dry_run: must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := h.service.Purge(r.Context(), adminID, dryRun, utils.ClientIP(r))
+	if err != nil {
+		log.Error().Err(err).Str("admin_id", adminID.Hex()).Bool("dry_run", dryRun).Msg("Error purging expired records via service")
+		utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, report)
+}
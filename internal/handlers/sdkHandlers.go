@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/models"
+	"markly/internal/sdk"
+	"markly/internal/utils"
+)
+
+// SDKHandler serves the client SDK descriptors. They are generated on first use
+// and kept for the life of the process, since they only change with a release.
+type SDKHandler struct {
+	build func() (*sdk.Bundle, error)
+
+	once   sync.Once
+	bundle *sdk.Bundle
+	err    error
+}
+
+func NewSDKHandler(build func() (*sdk.Bundle, error)) *SDKHandler {
+	return &SDKHandler{build: build}
+}
+
+func (h *SDKHandler) load() (*sdk.Bundle, error) {
+	h.once.Do(func() {
+		if h.bundle, h.err = h.build(); h.err != nil {
+			log.Error().Err(h.err).Msg("Failed to generate sdk descriptors")
+		}
+	})
+	return h.bundle, h.err
+}
+
+func (h *SDKHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
+	bundle, err := h.load()
+	if err != nil {
+		utils.SendJSONError(w, "failed to generate sdk descriptors", http.StatusInternalServerError)
+		return
+	}
+
+	version := models.SDKVersion{Version: bundle.APIVersion, Artifacts: []models.SDKArtifact{}}
+	for _, a := range []sdk.Artifact{bundle.OpenAPI, bundle.TypeScript} {
+		version.Artifacts = append(version.Artifacts, models.SDKArtifact{
+			Name:        a.Name,
+			URL:         fmt.Sprintf("/api/meta/sdk/%s/%s", bundle.APIVersion, a.Name),
+			ContentType: a.ContentType,
+			Size:        len(a.Content),
+			SHA256:      a.SHA256,
+		})
+	}
+	utils.RespondWithJSON(w, http.StatusOK, models.SDKIndex{ServerVersion: bundle.ServerVersion, Versions: []models.SDKVersion{version}})
+}
+
+// GetArtifact sends one descriptor. Its digest is the ETag, so clients checking
+// for a new release get 304 Not Modified until the server is upgraded.
+func (h *SDKHandler) GetArtifact(w http.ResponseWriter, r *http.Request) {
+	bundle, err := h.load()
+	if err != nil {
+		utils.SendJSONError(w, "failed to generate sdk descriptors", http.StatusInternalServerError)
+		return
+	}
+
+	vars := mux.Vars(r)
+	if vars["version"] != bundle.APIVersion {
+		utils.SendJSONError(w, fmt.Sprintf("sdk version %q not found", vars["version"]), http.StatusNotFound)
+		return
+	}
+	var artifact *sdk.Artifact
+	for _, a := range []*sdk.Artifact{&bundle.OpenAPI, &bundle.TypeScript} {
+		if a.Name == vars["file"] {
+			artifact = a
+		}
+	}
+	if artifact == nil {
+		utils.SendJSONError(w, fmt.Sprintf("sdk file %q not found", vars["file"]), http.StatusNotFound)
+		return
+	}
+
+	etag := `"` + artifact.SHA256 + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		if strings.TrimSpace(candidate) == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", artifact.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(artifact.Content)))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="markly-%s-%s"`, bundle.APIVersion, artifact.Name))
+	w.WriteHeader(http.StatusOK)
+	w.Write(artifact.Content)
+}
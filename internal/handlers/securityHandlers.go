@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+type SecurityHandler struct {
+	auditService services.AuditService
+}
+
+func NewSecurityHandler(auditService services.AuditService) *SecurityHandler {
+	return &SecurityHandler{auditService: auditService}
+}
+
+// GetAccessLog lists the user's recent authentications as JSON, or as a CSV
+// download with ?format=csv.
+func (h *SecurityHandler) GetAccessLog(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		utils.SendJSONError(w, "invalid format: use json or csv", http.StatusBadRequest)
+		return
+	}
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			utils.SendJSONError(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	entries, err := h.auditService.AccessLog(r.Context(), userID, limit)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "invalid") {
+			statusCode = http.StatusBadRequest
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+
+	if format != "csv" {
+		utils.RespondWithJSON(w, http.StatusOK, entries)
+		return
+	}
+
+	filename := fmt.Sprintf("markly-access-log-%s.csv", time.Now().UTC().Format("2006-01-02"))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "ip", "user_agent", "method", "details"})
+	for _, e := range entries {
+		cw.Write([]string{
+			e.Time.UTC().Format(time.RFC3339),
+			utils.CSVSafe(e.IP),
+			utils.CSVSafe(e.UserAgent),
+			e.Method,
+			utils.CSVSafe(e.Details),
+		})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to write access log CSV")
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/models"
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+type ShareHandler struct {
+	service services.ShareService
+}
+
+func NewShareHandler(service services.ShareService) *ShareHandler {
+	return &ShareHandler{service: service}
+}
+
+func shareErrorStatus(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.HasPrefix(err.Error(), "invalid"):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (h *ShareHandler) CreateShare(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+	bookmarkID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	var req models.CreateShareRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendJSONError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	share, err := h.service.CreateShare(r.Context(), userID, bookmarkID, req)
+	if err != nil {
+		log.Error().Err(err).Str("bookmark_id", bookmarkID.Hex()).Msg("Error creating share link via service")
+		if sendForbidden(w, err) || sendLimitExceeded(w, err) {
+			return
+		}
+		utils.SendJSONError(w, err.Error(), shareErrorStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, share)
+}
+
+func (h *ShareHandler) ListShares(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+	bookmarkID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	shares, err := h.service.ListShares(r.Context(), userID, bookmarkID)
+	if err != nil {
+		if sendForbidden(w, err) {
+			return
+		}
+		utils.SendJSONError(w, err.Error(), shareErrorStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, shares)
+}
+
+func (h *ShareHandler) RevokeShare(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+	bookmarkID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+	shareID, err := utils.GetObjectIDFromVars(w, r, "shareId")
+	if err != nil {
+		return
+	}
+
+	if err := h.service.RevokeShare(r.Context(), userID, bookmarkID, shareID); err != nil {
+		log.Error().Err(err).Str("share_id", shareID.Hex()).Msg("Error revoking share link via service")
+		utils.SendJSONError(w, err.Error(), shareErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ViewShare serves a shared bookmark to anyone holding the link. Responses are not
+// cached so every view is counted.
+func (h *ShareHandler) ViewShare(w http.ResponseWriter, r *http.Request) {
+	shared, err := h.service.ViewShare(r.Context(), mux.Vars(r)["token"])
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), shareErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	utils.RespondWithJSON(w, http.StatusOK, shared)
+}
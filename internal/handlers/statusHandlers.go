@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/models"
+	"markly/internal/scheduler"
+)
+
+// statusCacheTTL is how long a rendered status is reused. The endpoint is public, so
+// callers polling it share one snapshot instead of each building their own.
+const statusCacheTTL = 5 * time.Second
+
+// StatusHandler serves the public status page. Unlike /health it checks no
+// dependencies and exposes nothing that should stay private.
+type StatusHandler struct {
+	startedAt time.Time
+	version   string
+	jobs      func() []scheduler.JobStatus
+	queues    func() map[string]models.QueueDepth
+
+	mu       sync.Mutex
+	cached   []byte
+	cachedAt time.Time
+}
+
+func NewStatusHandler(startedAt time.Time, version string, jobs func() []scheduler.JobStatus, queues func() map[string]models.QueueDepth) *StatusHandler {
+	return &StatusHandler{startedAt: startedAt, version: version, jobs: jobs, queues: queues}
+}
+
+func (h *StatusHandler) snapshot(now time.Time) models.Status {
+	status := models.Status{
+		Status:        "ok",
+		Version:       h.version,
+		StartedAt:     h.startedAt,
+		UptimeSeconds: int64(now.Sub(h.startedAt).Seconds()),
+		Queues:        h.queues(),
+		Jobs:          []models.StatusJob{},
+	}
+	for _, j := range h.jobs() {
+		status.Jobs = append(status.Jobs, models.StatusJob{
+			Name:            j.Name,
+			IntervalSeconds: j.IntervalS,
+			LastRunAt:       j.LastRunAt,
+			LastStatus:      j.LastStatus,
+			Running:         j.Running,
+		})
+	}
+	return status
+}
+
+func (h *StatusHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	h.mu.Lock()
+	if h.cached == nil || now.Sub(h.cachedAt) >= statusCacheTTL {
+		body, err := json.Marshal(h.snapshot(now))
+		if err != nil {
+			h.mu.Unlock()
+			log.Error().Err(err).Msg("Error marshalling status response")
+			http.Error(w, "failed to build status", http.StatusInternalServerError)
+			return
+		}
+		h.cached, h.cachedAt = body, now
+	}
+	body := h.cached
+	h.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=5")
+	_, _ = w.Write(body)
+}
@@ -74,12 +74,26 @@ func (h *TagHandler) GetUserTags(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tags, err := h.service.GetUserTags(r.Context(), userID)
+	page, err := utils.GetPageRequest(r)
+	if err != nil {
+		if sendLimitExceeded(w, err) {
+			return
+		}
+		utils.SendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tags, next, err := h.service.GetUserTags(r.Context(), userID, page)
 	if err != nil {
 		log.Error().Err(err).Msg("Error getting user tags from service")
-		utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		statusCode := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "invalid") {
+			statusCode = http.StatusBadRequest
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
 		return
 	}
+	utils.SetNextCursor(w, next)
 
 	log.Info().Int("count", len(tags)).Str("user_id", userID.Hex()).Msg("User tags retrieved successfully")
 	utils.RespondWithJSON(w, http.StatusOK, tags)
@@ -98,6 +112,9 @@ func (h *TagHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
 
 	deleted, err := h.service.DeleteTag(r.Context(), userID, tagID)
 	if err != nil {
+		if sendForbidden(w, err) {
+			return
+		}
 		log.Error().Err(err).Str("tag_id", tagID.Hex()).Str("user_id", userID.Hex()).Msg("Error deleting tag via service")
 		if strings.Contains(err.Error(), "not found") {
 			utils.SendJSONError(w, err.Error(), http.StatusNotFound)
@@ -133,6 +150,9 @@ func (h *TagHandler) UpdateTag(w http.ResponseWriter, r *http.Request) {
 
 	updatedTag, err := h.service.UpdateTag(r.Context(), userID, tagID, updatePayload)
 	if err != nil {
+		if sendForbidden(w, err) {
+			return
+		}
 		log.Error().Err(err).Str("tag_id", tagID.Hex()).Str("user_id", userID.Hex()).Msg("Error updating tag via service")
 		statusCode := http.StatusInternalServerError
 		if strings.Contains(err.Error(), "no fields to update") || strings.Contains(err.Error(), "invalid") {
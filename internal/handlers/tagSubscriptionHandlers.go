@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/models"
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+type TagSubscriptionHandler struct {
+	service services.TagSubscriptionService
+}
+
+func NewTagSubscriptionHandler(service services.TagSubscriptionService) *TagSubscriptionHandler {
+	return &TagSubscriptionHandler{service: service}
+}
+
+func tagSubscriptionErrorStatus(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.HasPrefix(err.Error(), "invalid"), strings.Contains(err.Error(), "no valid fields"):
+		return http.StatusBadRequest
+	case strings.Contains(err.Error(), "already exists"):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func (h *TagSubscriptionHandler) GetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	subs, err := h.service.GetSubscriptions(r.Context(), userID)
+	if err != nil {
+		utils.SendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, subs)
+}
+
+func (h *TagSubscriptionHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req models.TagSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.service.CreateSubscription(r.Context(), userID, req)
+	if err != nil {
+		log.Error().Err(err).Msg("Error creating tag subscription via service")
+		if sendLimitExceeded(w, err) {
+			return
+		}
+		utils.SendJSONError(w, err.Error(), tagSubscriptionErrorStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, sub)
+}
+
+func (h *TagSubscriptionHandler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	subID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	var req models.TagSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.service.UpdateSubscription(r.Context(), userID, subID, req)
+	if err != nil {
+		log.Error().Err(err).Str("subscription_id", subID.Hex()).Msg("Error updating tag subscription via service")
+		utils.SendJSONError(w, err.Error(), tagSubscriptionErrorStatus(err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, sub)
+}
+
+func (h *TagSubscriptionHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	subID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	deleted, err := h.service.DeleteSubscription(r.Context(), userID, subID)
+	if err != nil {
+		log.Error().Err(err).Str("subscription_id", subID.Hex()).Msg("Error deleting tag subscription via service")
+		utils.SendJSONError(w, err.Error(), tagSubscriptionErrorStatus(err))
+		return
+	}
+
+	if deleted {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+type ThumbnailHandler struct {
+	service services.ThumbnailService
+}
+
+func NewThumbnailHandler(service services.ThumbnailService) *ThumbnailHandler {
+	return &ThumbnailHandler{service: service}
+}
+
+// GetThumbnail returns the PNG thumbnail of a bookmark's preview image, making it
+// first if needed.
+func (h *ThumbnailHandler) GetThumbnail(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	bookmarkID, err := utils.GetObjectIDFromVars(w, r, "id")
+	if err != nil {
+		return
+	}
+
+	thumb, err := h.service.GetThumbnail(r.Context(), userID, bookmarkID)
+	if err != nil {
+		if sendForbidden(w, err) || sendLimitExceeded(w, err) {
+			return
+		}
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		} else if strings.HasPrefix(err.Error(), "failed to fetch preview image") {
+			statusCode = http.StatusBadGateway
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+
+	// The thumbnail changes when the page's preview image does, so clients
+	// revalidate after a day.
+	w.Header().Set("Content-Type", thumb.ContentType)
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Header().Set("Content-Length", strconv.Itoa(len(thumb.Data)))
+	w.Write(thumb.Data)
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -24,18 +25,20 @@ func NewUserHandler(userService services.UserService) *UserHandler {
 }
 
 func (u *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
-	var user models.User
+	var req models.RegisterRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		log.Error().Err(err).Msg("Invalid user data input for Register")
 		utils.SendJSONError(w, "Invalid user data input: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	registeredUser, err := u.userService.RegisterUser(r.Context(), &user)
+	registeredUser, err := u.userService.RegisterUser(r.Context(), &req)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
-		if strings.Contains(err.Error(), "required") || strings.Contains(err.Error(), "invalid") {
+		if errors.Is(err, services.ErrSignupNotAllowed) {
+			statusCode = http.StatusForbidden
+		} else if strings.Contains(err.Error(), "required") || strings.Contains(err.Error(), "invalid") {
 			statusCode = http.StatusBadRequest
 		} else if strings.Contains(err.Error(), "already exists") {
 			statusCode = http.StatusConflict
@@ -56,11 +59,13 @@ func (u *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := u.userService.LoginUser(r.Context(), &creds)
+	token, err := u.userService.LoginUser(r.Context(), &creds, utils.ClientIP(r), r.UserAgent())
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if strings.Contains(err.Error(), "invalid credentials") {
 			statusCode = http.StatusUnauthorized
+		} else if strings.HasPrefix(err.Error(), "invalid client") || strings.HasPrefix(err.Error(), "invalid scope") {
+			statusCode = http.StatusBadRequest
 		}
 		utils.RespondWithError(w, statusCode, err.Error())
 		return
@@ -122,7 +127,9 @@ func (u *UserHandler) UpdateMyProfile(w http.ResponseWriter, r *http.Request) {
 	updatedUser, err := u.userService.UpdateUserProfile(r.Context(), userID, &updatePayload)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
-		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "not authorized") {
+		if errors.Is(err, services.ErrEmailDomainNotAllowed) {
+			statusCode = http.StatusForbidden
+		} else if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "not authorized") {
 			statusCode = http.StatusNotFound
 		} else if strings.Contains(err.Error(), "email already in use") {
 			statusCode = http.StatusConflict
@@ -163,3 +170,50 @@ func (u *UserHandler) DeleteMyProfile(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (u *UserHandler) GetMySettings(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	settings, err := u.userService.GetSettings(r.Context(), userID)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, settings)
+}
+
+func (u *UserHandler) UpdateMySettings(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var updatePayload models.UserSettingsUpdate
+	if err := json.NewDecoder(r.Body).Decode(&updatePayload); err != nil {
+		log.Error().Err(err).Msg("Invalid JSON payload for UpdateMySettings")
+		utils.SendJSONError(w, "Invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	settings, err := u.userService.UpdateSettings(r.Context(), userID, &updatePayload)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		} else if strings.Contains(err.Error(), "invalid") || strings.Contains(err.Error(), "no valid fields provided") {
+			statusCode = http.StatusBadRequest
+		}
+		utils.SendJSONError(w, err.Error(), statusCode)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, settings)
+}
@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/models"
+	"markly/internal/services"
+	"markly/internal/utils"
+)
+
+var userService services.UserService
+
+// SetUserService enables AdminMiddleware, which looks up the role of the caller.
+func SetUserService(s services.UserService) {
+	userService = s
+}
+
+// isAdmin reports whether user has the admin role. The role is only ever set in
+// the database, for example with `api --grant-admin`, never from the email of an
+// account, which is not verified.
+func isAdmin(user *models.User) bool {
+	return user.Role == models.RoleAdmin
+}
+
+// AdminMiddleware only lets admins through. It must be wrapped by AuthMiddleware.
+func AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if userService == nil {
+			utils.SendJSONError(w, "Server configuration error: user service missing", http.StatusInternalServerError)
+			return
+		}
+		userID, err := utils.GetUserIDFromContext(w, r)
+		if err != nil {
+			return
+		}
+		user, err := userService.GetUserProfile(r.Context(), userID)
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				utils.SendJSONError(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to load user for admin check")
+			utils.SendJSONError(w, "Failed to verify permissions", http.StatusInternalServerError)
+			return
+		}
+		if !isAdmin(user) {
+			log.Warn().Str("userID", userID.Hex()).Str("path", r.URL.Path).Msg("Non-admin denied access to admin endpoint")
+			utils.SendJSONError(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,88 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"markly/internal/utils"
+)
+
+// audienceRule matches the routes under pathPrefix, or only the route itself when
+// exact is set. With no methods it applies to every method.
+type audienceRule struct {
+	pathPrefix string
+	methods    []string
+	exact      bool
+}
+
+func (rule audienceRule) matches(r *http.Request) bool {
+	if r.URL.Path != rule.pathPrefix && (rule.exact || !strings.HasPrefix(r.URL.Path, rule.pathPrefix+"/")) {
+		return false
+	}
+	return len(rule.methods) == 0 || containsMethod(rule.methods, r.Method)
+}
+
+// audienceAllowed lists the only routes an audience may call, for clients that
+// need few of them. The browser extension saves and reads bookmarks: it can
+// create tags, categories and collections while saving, but not change or delete
+// them, and cannot touch the account, its settings, exports, imports, connected
+// services or admin endpoints. Routes added later stay closed to it.
+var audienceAllowed = map[string][]audienceRule{
+	utils.AudienceExtension: {
+		{pathPrefix: "/api/bookmarks"},
+		{pathPrefix: "/api/agent"},
+		{pathPrefix: "/api/tags", methods: []string{http.MethodGet}},
+		{pathPrefix: "/api/tags", methods: []string{http.MethodPost}, exact: true},
+		{pathPrefix: "/api/categories", methods: []string{http.MethodGet}},
+		{pathPrefix: "/api/categories", methods: []string{http.MethodPost}, exact: true},
+		{pathPrefix: "/api/collections", methods: []string{http.MethodGet}},
+		{pathPrefix: "/api/collections", methods: []string{http.MethodPost}, exact: true},
+		{pathPrefix: "/api/me", methods: []string{http.MethodGet}, exact: true},
+		{pathPrefix: "/api/me/settings", methods: []string{http.MethodGet}, exact: true},
+		{pathPrefix: "/api/me/limits", methods: []string{http.MethodGet}, exact: true},
+	},
+}
+
+// audienceDenied lists routes an audience may not call. Web tokens can call every
+// route; the mobile app is a full client apart from administration.
+var audienceDenied = map[string][]audienceRule{
+	utils.AudienceMobile: {
+		{pathPrefix: "/api/admin"},
+	},
+}
+
+// checkAudience returns an error when a token issued for audience may not call
+// the route of r. Prefixes match whole path segments, so "/api/me" does not
+// cover "/api/menu".
+func checkAudience(audience string, r *http.Request) error {
+	if r.Method == http.MethodOptions {
+		return nil
+	}
+	denied := fmt.Errorf("%s tokens cannot access this endpoint", audience)
+	if allowed, ok := audienceAllowed[audience]; ok && !matchesAny(allowed, r) {
+		return denied
+	}
+	if matchesAny(audienceDenied[audience], r) {
+		return denied
+	}
+	return nil
+}
+
+func matchesAny(rules []audienceRule, r *http.Request) bool {
+	for _, rule := range rules {
+		if rule.matches(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
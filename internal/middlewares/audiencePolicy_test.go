@@ -0,0 +1,48 @@
+package middlewares
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"markly/internal/utils"
+)
+
+func TestCheckAudience(t *testing.T) {
+	cases := []struct {
+		audience, method, path string
+		allowed                bool
+	}{
+		{utils.AudienceWeb, "DELETE", "/api/me", true},
+		{utils.AudienceWeb, "GET", "/api/admin/audit", true},
+		{utils.AudienceExtension, "POST", "/api/bookmarks", true},
+		{utils.AudienceExtension, "POST", "/api/bookmarks/quick-save", true},
+		{utils.AudienceExtension, "PUT", "/api/bookmarks/6543", true},
+		{utils.AudienceExtension, "GET", "/api/me", true},
+		{utils.AudienceExtension, "DELETE", "/api/me", false},
+		{utils.AudienceExtension, "PATCH", "/api/me", false},
+		{utils.AudienceExtension, "GET", "/api/me/settings", true},
+		{utils.AudienceExtension, "PATCH", "/api/me/settings", false},
+		{utils.AudienceExtension, "GET", "/api/me/api-keys", false},
+		{utils.AudienceExtension, "GET", "/api/tags/user", true},
+		{utils.AudienceExtension, "POST", "/api/tags", true},
+		{utils.AudienceExtension, "DELETE", "/api/tags/6543", false},
+		{utils.AudienceExtension, "POST", "/api/collections", true},
+		{utils.AudienceExtension, "DELETE", "/api/collections/6543", false},
+		{utils.AudienceExtension, "POST", "/api/collections/6543/newsletter/subscribers", false},
+		{utils.AudienceExtension, "GET", "/api/export/markly", false},
+		{utils.AudienceExtension, "POST", "/api/import/markly", false},
+		{utils.AudienceExtension, "GET", "/api/menu", false},
+		{utils.AudienceExtension, "GET", "/api/me/security/access-log", false},
+		{utils.AudienceExtension, "OPTIONS", "/api/me", true},
+		{utils.AudienceExtension, "POST", "/api/integrations/github/connect", false},
+		{utils.AudienceMobile, "DELETE", "/api/me", true},
+		{utils.AudienceMobile, "GET", "/api/admin/audit", false},
+		{utils.AudienceMobile, "GET", "/api/administrators", true},
+	}
+	for _, c := range cases {
+		err := checkAudience(c.audience, httptest.NewRequest(c.method, c.path, nil))
+		if (err == nil) != c.allowed {
+			t.Errorf("checkAudience(%s, %s %s) = %v, want allowed %v", c.audience, c.method, c.path, err, c.allowed)
+		}
+	}
+}
@@ -21,7 +21,8 @@ func CorsMiddleware(next http.Handler) http.Handler {
 			if strings.TrimSpace(allowed) == origin {
 				w.Header().Set("Access-Control-Allow-Origin", allowed)
 				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
-				w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type")
+				w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, If-None-Match, X-API-Key")
+				w.Header().Set("Access-Control-Expose-Headers", "X-Next-Cursor, ETag")
 				w.Header().Set("Access-Control-Allow-Credentials", "true")
 				break
 			}
@@ -2,17 +2,58 @@ package middlewares
 
 import (
 	"context"
-	"github.com/golang-jwt/jwt/v5"
+	"markly/internal/services"
 	"markly/internal/utils"
 	"net/http"
 	"os"
 	"strings"
 )
 
+var apiKeyService services.APIKeyService
+var impersonationService services.ImpersonationService
+
+// SetAPIKeyService enables X-API-Key authentication in AuthMiddleware.
+func SetAPIKeyService(s services.APIKeyService) {
+	apiKeyService = s
+}
+
+// SetImpersonationService enables impersonation tokens in AuthMiddleware. Without
+// it they are rejected.
+func SetImpersonationService(s services.ImpersonationService) {
+	impersonationService = s
+}
+
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		jwtKey := []byte(os.Getenv("JWT_SECRET"))
-		if len(jwtKey) == 0 {
+		if rawKey := r.Header.Get("X-API-Key"); rawKey != "" && apiKeyService != nil {
+			key, err := apiKeyService.Authenticate(r.Context(), rawKey, utils.ClientIP(r), r.UserAgent())
+			if err != nil {
+				statusCode := http.StatusUnauthorized
+				if strings.Contains(err.Error(), "not allowed") {
+					statusCode = http.StatusForbidden
+				} else if strings.Contains(err.Error(), "failed to verify") {
+					statusCode = http.StatusInternalServerError
+				}
+				http.Error(w, err.Error(), statusCode)
+				return
+			}
+			// Keys created before scopes existed hold all of them.
+			scopes := key.Scopes
+			if len(scopes) == 0 {
+				scopes = utils.AllScopes
+			}
+			if err := checkScopes(scopes, r.Method, routePath(r)); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			ctx := context.WithValue(r.Context(), "userID", key.UserID.Hex())
+			ctx = context.WithValue(ctx, "apiKeyID", key.ID.Hex())
+			ctx = context.WithValue(ctx, "scopes", scopes)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		if os.Getenv("JWT_SECRET") == "" {
 			http.Error(w, "Server configuration error: JWT secret missing", http.StatusInternalServerError)
 			return
 		}
@@ -30,17 +71,43 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		}
 		tokenString = tokenString[len("Bearer "):]
 
-		claims := &utils.Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := utils.ParseJWT(tokenString)
+		if err != nil {
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
 			return
 		}
+		if err := checkAudience(claims.Client(), r); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		scopes := claims.Scopes()
+		if err := checkScopes(scopes, r.Method, routePath(r)); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 
 		ctx := context.WithValue(r.Context(), "userID", claims.ID)
+		ctx = context.WithValue(ctx, "audience", claims.Client())
+		if scopes != nil {
+			ctx = context.WithValue(ctx, "scopes", scopes)
+		}
+		if claims.ImpersonationID != "" {
+			if impersonationService == nil {
+				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				return
+			}
+			if err := impersonationService.Authorize(r.Context(), claims, r); err != nil {
+				statusCode := http.StatusUnauthorized
+				if strings.Contains(err.Error(), "read-only") || strings.Contains(err.Error(), "cannot access") {
+					statusCode = http.StatusForbidden
+				} else if strings.Contains(err.Error(), "failed to verify") {
+					statusCode = http.StatusInternalServerError
+				}
+				http.Error(w, err.Error(), statusCode)
+				return
+			}
+			ctx = context.WithValue(ctx, "impersonatorID", claims.ImpersonatorID)
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
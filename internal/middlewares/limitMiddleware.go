@@ -1,12 +1,22 @@
 package middlewares
 
 import (
+	"math"
 	"net"
 	"net/http"
 	"sync"
 	"time"
 
 	"golang.org/x/time/rate"
+
+	"markly/internal/models"
+)
+
+// Every client gets a token bucket refilled with rateLimitPerSecond requests per
+// second and holding up to rateLimitBurst.
+const (
+	rateLimitPerSecond = 3
+	rateLimitBurst     = 5
 )
 
 type visitor struct {
@@ -32,7 +42,7 @@ func getLimiter(key string, isUser bool) *rate.Limiter {
 	}
 
 	if !exists {
-		limiter := rate.NewLimiter(3, 5)
+		limiter := rate.NewLimiter(rateLimitPerSecond, rateLimitBurst)
 		v = &visitor{limiter, time.Now()}
 		if isUser {
 			userVisitors[key] = v
@@ -65,6 +75,23 @@ func CleanupVisitors() {
 	}
 }
 
+// RateLimitStatus reports the state of the bucket RateLimit uses for r, without
+// taking a token. RateLimit runs before authentication, so buckets are per IP.
+func RateLimitStatus(r *http.Request) models.RateLimitStatus {
+	status := models.RateLimitStatus{Scope: "ip", PerSecond: rateLimitPerSecond, Burst: rateLimitBurst, Remaining: rateLimitBurst}
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		status.ResetAt = time.Now().UTC()
+		return status
+	}
+	now := time.Now()
+	tokens := getLimiter(ip, false).TokensAt(now)
+	status.Remaining = int(math.Max(0, math.Floor(tokens)))
+	refill := (rateLimitBurst - tokens) / rateLimitPerSecond
+	status.ResetAt = now.Add(time.Duration(refill * float64(time.Second))).UTC()
+	return status
+}
+
 func RateLimit(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var limiter *rate.Limiter
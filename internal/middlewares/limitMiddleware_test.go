@@ -0,0 +1,34 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimitStatusDoesNotTakeTokens(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/me/limits", nil)
+	r.RemoteAddr = "192.0.2.55:4321"
+	mu.Lock()
+	delete(ipVisitors, "192.0.2.55")
+	mu.Unlock()
+
+	for i := 0; i < 2; i++ {
+		if status := RateLimitStatus(r); status.Remaining != rateLimitBurst || status.Scope != "ip" {
+			t.Fatalf("status of a new client = %+v, want %d requests remaining", status, rateLimitBurst)
+		}
+	}
+
+	limited := RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for i := 0; i < 2; i++ {
+		limited.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	status := RateLimitStatus(r)
+	if status.Remaining != rateLimitBurst-2 {
+		t.Errorf("remaining = %d after 2 requests, want %d", status.Remaining, rateLimitBurst-2)
+	}
+	if wait := time.Until(status.ResetAt); wait <= 0 || wait > time.Second {
+		t.Errorf("bucket full again in %v, want within a second", wait)
+	}
+}
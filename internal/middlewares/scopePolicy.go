@@ -0,0 +1,87 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"markly/internal/utils"
+)
+
+// scopeRule names the scope the routes under pathPrefix need: read for GET
+// requests and write for the other methods. An empty scope keeps those requests
+// to full-access sessions. An exact rule only covers the route itself.
+type scopeRule struct {
+	pathPrefix  string
+	read, write string
+	exact       bool
+}
+
+// routeScopes maps route groups to scopes. The longest matching prefix wins and
+// routes that match none, such as the account, its credentials, connected
+// services and administration, need a full-access session.
+var routeScopes = []scopeRule{
+	{pathPrefix: "/api/bookmarks", read: utils.ScopeBookmarksRead, write: utils.ScopeBookmarksWrite},
+	{pathPrefix: "/api/bookmarks/{id}/summary", read: utils.ScopeAIInvoke, write: utils.ScopeAIInvoke},
+	{pathPrefix: "/api/categories", read: utils.ScopeBookmarksRead, write: utils.ScopeBookmarksWrite},
+	{pathPrefix: "/api/collections", read: utils.ScopeBookmarksRead, write: utils.ScopeBookmarksWrite},
+	{pathPrefix: "/api/tags", read: utils.ScopeBookmarksRead, write: utils.ScopeTagsWrite},
+	{pathPrefix: "/api/me/tag-subscriptions", read: utils.ScopeBookmarksRead, write: utils.ScopeTagsWrite},
+	{pathPrefix: "/api/agent", read: utils.ScopeAIInvoke, write: utils.ScopeAIInvoke},
+	{pathPrefix: "/api/analytics", read: utils.ScopeBookmarksRead},
+	{pathPrefix: "/api/trending", read: utils.ScopeBookmarksRead},
+	{pathPrefix: "/api/export", read: utils.ScopeExportRead},
+	{pathPrefix: "/api/import", read: utils.ScopeBookmarksRead, write: utils.ScopeBookmarksWrite},
+	{pathPrefix: "/api/imports", read: utils.ScopeBookmarksRead},
+	{pathPrefix: "/api/me", read: utils.ScopeBookmarksRead, exact: true},
+	// Settings hold the activity webhook, so changing them needs a full-access
+	// session like the rest of the account.
+	{pathPrefix: "/api/me/settings", read: utils.ScopeBookmarksRead},
+	{pathPrefix: "/api/me/limits", read: utils.ScopeBookmarksRead},
+}
+
+// routePath returns the template of the route r matched, so rules can name
+// path variables, or the request path outside a router.
+func routePath(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return r.URL.Path
+}
+
+// checkScopes returns an error when a credential holding scopes may not call
+// method on path. Nil scopes mean full access.
+func checkScopes(scopes []string, method, path string) error {
+	if scopes == nil || method == http.MethodOptions {
+		return nil
+	}
+	var match *scopeRule
+	for i, rule := range routeScopes {
+		if path != rule.pathPrefix && (rule.exact || !strings.HasPrefix(path, rule.pathPrefix+"/")) {
+			continue
+		}
+		if match == nil || len(rule.pathPrefix) > len(match.pathPrefix) {
+			match = &routeScopes[i]
+		}
+	}
+	required := ""
+	if match != nil {
+		required = match.write
+		if method == http.MethodGet || method == http.MethodHead {
+			required = match.read
+		}
+	}
+	if required == "" {
+		return fmt.Errorf("insufficient scope: this endpoint requires a full-access session")
+	}
+	for _, s := range scopes {
+		if s == required {
+			return nil
+		}
+	}
+	return fmt.Errorf("insufficient scope: this endpoint requires the %s scope", required)
+}
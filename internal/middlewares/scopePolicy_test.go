@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"testing"
+
+	"markly/internal/utils"
+)
+
+func TestCheckScopes(t *testing.T) {
+	readOnly := []string{utils.ScopeBookmarksRead}
+	writer := []string{utils.ScopeBookmarksRead, utils.ScopeBookmarksWrite}
+	cases := []struct {
+		name         string
+		scopes       []string
+		method, path string
+		allowed      bool
+	}{
+		{"full access", nil, "DELETE", "/api/me", true},
+		{"read bookmarks", readOnly, "GET", "/api/bookmarks/{id}", true},
+		{"write without scope", readOnly, "POST", "/api/bookmarks", false},
+		{"write bookmarks", writer, "PUT", "/api/bookmarks/{id}", true},
+		{"summary needs ai", writer, "POST", "/api/bookmarks/{id}/summary/translate", false},
+		{"summary with ai", []string{utils.ScopeAIInvoke}, "POST", "/api/bookmarks/{id}/summary/translate", true},
+		{"tags need tags:write", writer, "POST", "/api/tags", false},
+		{"tag subscriptions", []string{utils.ScopeTagsWrite}, "PATCH", "/api/me/tag-subscriptions/{id}", true},
+		{"export", readOnly, "GET", "/api/export/markdown", false},
+		{"export with scope", []string{utils.ScopeExportRead}, "GET", "/api/export/markly", true},
+		{"profile", readOnly, "GET", "/api/me", true},
+		{"profile update", utils.AllScopes, "PATCH", "/api/me", false},
+		{"settings", readOnly, "GET", "/api/me/settings", true},
+		{"settings update", utils.AllScopes, "PATCH", "/api/me/settings", false},
+		{"api keys", utils.AllScopes, "GET", "/api/me/api-keys", false},
+		{"admin", utils.AllScopes, "GET", "/api/admin/audit", false},
+		{"integrations", utils.AllScopes, "POST", "/api/integrations/github/sync", false},
+		{"segment boundary", utils.AllScopes, "GET", "/api/bookmarksx", false},
+		{"preflight", readOnly, "OPTIONS", "/api/admin/audit", true},
+	}
+	for _, c := range cases {
+		if err := checkScopes(c.scopes, c.method, c.path); (err == nil) != c.allowed {
+			t.Errorf("%s: checkScopes(%v, %s %s) = %v, want allowed %v", c.name, c.scopes, c.method, c.path, err, c.allowed)
+		}
+	}
+}
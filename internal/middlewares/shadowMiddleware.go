@@ -0,0 +1,221 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+// Shadowing mirrors a sample of successful GET /api/... requests to the v2 API and
+// compares the two responses in the background. The client only ever sees the
+// primary response. It is disabled unless SHADOW_TARGET_URL is set.
+var (
+	shadowTarget     string
+	shadowPrefix     = "/api/v2"
+	shadowSampleRate = 0.01
+	shadowClient     = &http.Client{Timeout: 5 * time.Second}
+	// shadowSlots bounds the number of comparisons in flight; requests beyond it are not mirrored.
+	shadowSlots = make(chan struct{}, 16)
+)
+
+// maxShadowBodyBytes caps the primary response kept for a comparison; larger
+// responses are not mirrored.
+const maxShadowBodyBytes = 1 << 20
+
+// ShadowQueueDepth reports the shadow comparisons in flight.
+func ShadowQueueDepth() models.QueueDepth {
+	return models.QueueDepth{InFlight: len(shadowSlots), Capacity: cap(shadowSlots)}
+}
+
+// Only these headers are forwarded so the v2 handler authenticates as the same user.
+var shadowForwardHeaders = []string{"Authorization", "X-API-Key", "Accept"}
+
+func init() {
+	shadowTarget = strings.TrimRight(os.Getenv("SHADOW_TARGET_URL"), "/")
+	if prefix := os.Getenv("SHADOW_PATH_PREFIX"); prefix != "" {
+		shadowPrefix = "/" + strings.Trim(prefix, "/")
+	}
+	if rate, err := strconv.ParseFloat(os.Getenv("SHADOW_SAMPLE_RATE"), 64); err == nil && rate >= 0 && rate <= 1 {
+		shadowSampleRate = rate
+	}
+}
+
+// bodyRecorder keeps a copy of a JSON response body while writing it through.
+// It stops copying, and sets skipped, for other content types and for bodies
+// larger than maxShadowBodyBytes.
+type bodyRecorder struct {
+	*responseWriterWrapper
+	body    bytes.Buffer
+	skipped bool
+}
+
+func (br *bodyRecorder) Write(b []byte) (int, error) {
+	if !br.skipped {
+		if !strings.HasPrefix(br.Header().Get("Content-Type"), "application/json") || br.body.Len()+len(b) > maxShadowBodyBytes {
+			br.skipped = true
+			br.body = bytes.Buffer{}
+		} else {
+			br.body.Write(b)
+		}
+	}
+	return br.responseWriterWrapper.Write(b)
+}
+
+func shouldShadow(r *http.Request) bool {
+	if shadowTarget == "" || r.Method != http.MethodGet {
+		return false
+	}
+	path := r.URL.Path
+	if !strings.HasPrefix(path, "/api/") || strings.HasPrefix(path, "/api/auth/") || strings.HasPrefix(path, shadowPrefix+"/") {
+		return false
+	}
+	return rand.Float64() < shadowSampleRate
+}
+
+func ShadowMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !shouldShadow(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := &bodyRecorder{responseWriterWrapper: newResponseWriterWrapper(w)}
+		next.ServeHTTP(recorder, r)
+
+		if recorder.statusCode != http.StatusOK {
+			return
+		}
+		if recorder.skipped {
+			utils.ShadowRequestsTotal.WithLabelValues("skipped").Inc()
+			return
+		}
+
+		select {
+		case shadowSlots <- struct{}{}:
+		default:
+			utils.ShadowRequestsTotal.WithLabelValues("dropped").Inc()
+			return
+		}
+
+		header := make(http.Header)
+		for _, name := range shadowForwardHeaders {
+			if value := r.Header.Get(name); value != "" {
+				header.Set(name, value)
+			}
+		}
+		target := shadowTarget + shadowPrefix + strings.TrimPrefix(r.URL.Path, "/api")
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		primary := recorder.body.Bytes()
+
+		go func() {
+			defer func() { <-shadowSlots }()
+			compareShadow(r.URL.Path, target, header, primary)
+		}()
+	})
+}
+
+func compareShadow(path, target string, header http.Header, primary []byte) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		utils.ShadowRequestsTotal.WithLabelValues("error").Inc()
+		return
+	}
+	req.Header = header
+
+	resp, err := shadowClient.Do(req)
+	if err != nil {
+		utils.ShadowRequestsTotal.WithLabelValues("error").Inc()
+		log.Warn().Err(err).Str("path", path).Msg("Shadow request failed")
+		return
+	}
+	defer resp.Body.Close()
+
+	shadow, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil || resp.StatusCode != http.StatusOK {
+		utils.ShadowRequestsTotal.WithLabelValues("error").Inc()
+		log.Warn().Err(err).Str("path", path).Int("status", resp.StatusCode).Msg("Shadow request returned an unusable response")
+		return
+	}
+
+	diff, err := diffResponses(primary, shadow)
+	if err != nil {
+		utils.ShadowRequestsTotal.WithLabelValues("error").Inc()
+		log.Warn().Err(err).Str("path", path).Msg("Failed to compare shadow response")
+		return
+	}
+	if diff != "" {
+		utils.ShadowRequestsTotal.WithLabelValues("mismatch").Inc()
+		log.Warn().Str("path", path).Str("diff", diff).Msg("Shadow response differs from primary")
+		return
+	}
+	utils.ShadowRequestsTotal.WithLabelValues("match").Inc()
+}
+
+// diffResponses compares the item count and IDs of a v1 response with a v2 response.
+// v2 list responses are wrapped in an envelope whose "data" field holds the items.
+// It returns an empty string when both responses contain the same items in the same order.
+func diffResponses(primary, shadow []byte) (string, error) {
+	var v1, v2 interface{}
+	if err := json.Unmarshal(primary, &v1); err != nil {
+		return "", fmt.Errorf("invalid primary response: %w", err)
+	}
+	if err := json.Unmarshal(shadow, &v2); err != nil {
+		return "", fmt.Errorf("invalid shadow response: %w", err)
+	}
+	if envelope, ok := v2.(map[string]interface{}); ok {
+		if data, ok := envelope["data"]; ok {
+			v2 = data
+		}
+	}
+
+	ids1, ids2 := responseIDs(v1), responseIDs(v2)
+	if len(ids1) != len(ids2) {
+		return fmt.Sprintf("count %d != %d", len(ids1), len(ids2)), nil
+	}
+	for i := range ids1 {
+		if ids1[i] != ids2[i] {
+			return fmt.Sprintf("id at %d: %s != %s", i, ids1[i], ids2[i]), nil
+		}
+	}
+	return "", nil
+}
+
+// responseIDs returns the "id" of every item in a list response, or of the single
+// object in a detail response.
+func responseIDs(v interface{}) []string {
+	var items []interface{}
+	switch value := v.(type) {
+	case []interface{}:
+		items = value
+	case map[string]interface{}:
+		items = []interface{}{value}
+	default:
+		return nil
+	}
+
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		obj, _ := item.(map[string]interface{})
+		id, _ := obj["id"].(string)
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBodyRecorderKeepsSmallJSONOnly(t *testing.T) {
+	record := func(contentType string, body []byte) *bodyRecorder {
+		w := httptest.NewRecorder()
+		br := &bodyRecorder{responseWriterWrapper: newResponseWriterWrapper(w)}
+		br.Header().Set("Content-Type", contentType)
+		br.Write(body[:len(body)/2])
+		br.Write(body[len(body)/2:])
+		if !bytes.Equal(w.Body.Bytes(), body) {
+			t.Errorf("%s: the client received %d of %d bytes", contentType, w.Body.Len(), len(body))
+		}
+		return br
+	}
+
+	if br := record("application/json; charset=utf-8", []byte(`[{"id":"a"}]`)); br.skipped || br.body.String() != `[{"id":"a"}]` {
+		t.Errorf("json response: skipped %v, recorded %q", br.skipped, br.body.String())
+	}
+	if br := record("text/html", []byte("<p>content</p>")); !br.skipped || br.body.Len() != 0 {
+		t.Errorf("html response: skipped %v, recorded %d bytes", br.skipped, br.body.Len())
+	}
+	if br := record("application/json", bytes.Repeat([]byte("x"), maxShadowBodyBytes+1)); !br.skipped || br.body.Len() != 0 {
+		t.Errorf("large response: skipped %v, recorded %d bytes", br.skipped, br.body.Len())
+	}
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of AI output tracked by AIEvent.
+const (
+	AIEventKindSuggestion = "suggestion"
+	AIEventKindSummary    = "summary"
+)
+
+// Actions recorded for AI output.
+const (
+	AIEventGenerated   = "generated"
+	AIEventAccepted    = "accepted"
+	AIEventDismissed   = "dismissed"
+	AIEventRegenerated = "regenerated"
+)
+
+// AIEvent records AI output being generated or acted on. Events are kept without a
+// user so that they can only be reported in aggregate.
+type AIEvent struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Kind          string             `json:"kind" bson:"kind"`
+	Action        string             `json:"action" bson:"action"`
+	PromptVersion string             `json:"prompt_version" bson:"prompt_version"`
+	Count         int                `json:"count" bson:"count"`
+	CreatedAt     time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// AIFeedbackRequest is sent by clients when the user accepts or dismisses AI output.
+type AIFeedbackRequest struct {
+	Kind          string `json:"kind"`
+	Action        string `json:"action"`
+	PromptVersion string `json:"prompt_version"`
+	// Count is the number of items acted on, defaulting to 1.
+	Count int `json:"count,omitempty"`
+}
+
+// AIEventCount is the total count of one action for a prompt version.
+type AIEventCount struct {
+	Kind          string `bson:"kind"`
+	PromptVersion string `bson:"prompt_version"`
+	Action        string `bson:"action"`
+	Count         int64  `bson:"count"`
+}
+
+// AIPromptStats summarizes how users responded to the output of one prompt version.
+// Rates are relative to Generated.
+type AIPromptStats struct {
+	Kind             string  `json:"kind"`
+	PromptVersion    string  `json:"prompt_version"`
+	Generated        int64   `json:"generated"`
+	Accepted         int64   `json:"accepted"`
+	Dismissed        int64   `json:"dismissed"`
+	Regenerated      int64   `json:"regenerated"`
+	AcceptanceRate   float64 `json:"acceptance_rate"`
+	DismissRate      float64 `json:"dismiss_rate"`
+	RegenerationRate float64 `json:"regeneration_rate"`
+}
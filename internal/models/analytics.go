@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Analytics bucket intervals accepted by the growth and activity endpoints.
+const (
+	IntervalDay  = "day"
+	IntervalWeek = "week"
+)
+
+// TimeBucket counts the documents created in one day or week. Start is local
+// midnight in the requested timezone; weeks start on Monday.
+type TimeBucket struct {
+	Start time.Time `json:"start" bson:"_id"`
+	Count int       `json:"count" bson:"count"`
+}
+
+// TimeSeries is the bucketed part of the growth and activity responses.
+type TimeSeries struct {
+	Interval string       `json:"interval"`
+	Timezone string       `json:"timezone"`
+	Buckets  []TimeBucket `json:"buckets"`
+}
+
+type UserGrowth struct {
+	NewUsers int `json:"new_users"`
+	TimeSeries
+}
+
+type BookmarkActivity struct {
+	NewBookmarks int `json:"new_bookmarks"`
+	TimeSeries
+}
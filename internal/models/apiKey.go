@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type APIKey struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID       primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Name         string             `json:"name" bson:"name"`
+	Prefix       string             `json:"prefix" bson:"prefix"`
+	KeyHash      string             `json:"-" bson:"key_hash"`
+	AllowedCIDRs []string           `json:"allowed_cidrs,omitempty" bson:"allowed_cidrs,omitempty"`
+	DeniedCIDRs  []string           `json:"denied_cidrs,omitempty" bson:"denied_cidrs,omitempty"`
+	// Scopes limits what the key may do. Keys created before scopes existed
+	// have none stored and hold all of them.
+	Scopes     []string   `json:"scopes" bson:"scopes,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty" bson:"last_used_ip,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name         string   `json:"name"`
+	AllowedCIDRs []string `json:"allowed_cidrs,omitempty"`
+	DeniedCIDRs  []string `json:"denied_cidrs,omitempty"`
+	// Scopes defaults to every scope when omitted.
+	Scopes    []string   `json:"scopes,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type APIKeyUpdate struct {
+	Name         *string    `json:"name,omitempty"`
+	AllowedCIDRs *[]string  `json:"allowed_cidrs,omitempty"`
+	DeniedCIDRs  *[]string  `json:"denied_cidrs,omitempty"`
+	Scopes       *[]string  `json:"scopes,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+}
+
+// CreatedAPIKey is returned once on creation and is the only time the raw key is exposed.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
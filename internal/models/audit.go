@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Audit actions.
+const (
+	AuditImpersonationRequested   = "impersonation.requested"
+	AuditImpersonationApproved    = "impersonation.approved"
+	AuditImpersonationDenied      = "impersonation.denied"
+	AuditImpersonationRevoked     = "impersonation.revoked"
+	AuditImpersonationTokenIssued = "impersonation.token_issued"
+	// AuditImpersonatedRequest is recorded for every request made with an
+	// impersonation token.
+	AuditImpersonatedRequest = "impersonation.request"
+	AuditUserErased          = "user.erased"
+	// AuditInstanceSettingsUpdated is recorded with the admin as both actor and user.
+	AuditInstanceSettingsUpdated = "instance.settings_updated"
+	// AuditRetentionPurged is recorded when an admin purges expired records, with
+	// the admin as both actor and user. Scheduled purges are only logged.
+	AuditRetentionPurged = "instance.retention_purged"
+	// AuditLogin is recorded for each successful authentication and listed in the
+	// user's access log. AuthMethod says how the user authenticated.
+	AuditLogin = "auth.login"
+)
+
+// Authentication methods of AuditLogin events. OAuth logins record the provider
+// name, such as "google".
+const (
+	AuthMethodPassword = "password"
+	AuthMethodAPIKey   = "api_key"
+)
+
+// AuditEvent records a security-relevant action. ActorID is who acted and UserID
+// whose account was affected; they differ when an admin acts on a user.
+type AuditEvent struct {
+	ID              primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	Action          string              `json:"action" bson:"action"`
+	ActorID         primitive.ObjectID  `json:"actor_id" bson:"actor_id"`
+	UserID          primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	ImpersonationID *primitive.ObjectID `json:"impersonation_id,omitempty" bson:"impersonation_id,omitempty"`
+	Method          string              `json:"method,omitempty" bson:"method,omitempty"`
+	Path            string              `json:"path,omitempty" bson:"path,omitempty"`
+	IP              string              `json:"ip,omitempty" bson:"ip,omitempty"`
+	UserAgent       string              `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	AuthMethod      string              `json:"auth_method,omitempty" bson:"auth_method,omitempty"`
+	Details         string              `json:"details,omitempty" bson:"details,omitempty"`
+	CreatedAt       time.Time           `json:"created_at" bson:"created_at"`
+}
+
+// AccessLogEntry is one authentication in a user's access log.
+type AccessLogEntry struct {
+	Time      time.Time `json:"time"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Method    string    `json:"method"`
+	// Details names the API key for api_key authentications.
+	Details string `json:"details,omitempty"`
+}
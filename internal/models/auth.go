@@ -1,6 +1,42 @@
 package models
 
+// RegisterRequest is the body of a registration. Everything else about the new
+// account comes from the instance settings.
+type RegisterRequest struct {
+	Username   string `json:"username"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	InviteCode string `json:"invite_code,omitempty"`
+}
+
 type Login struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// Client is the kind of client the token is for: web (the default),
+	// extension or mobile.
+	Client string `json:"client,omitempty"`
+	// Scopes restricts the token, for example one handed to a third-party
+	// integration. Without it the token holds what its client allows.
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// TokenIntrospection describes a JWT to internal services. Only Active is set for
+// tokens that are invalid, expired or revoked.
+type TokenIntrospection struct {
+	Active bool `json:"active"`
+	// Subject is the ID of the user the token acts as.
+	Subject  string `json:"sub,omitempty"`
+	Audience string `json:"aud,omitempty"`
+	// Scope lists the token's scopes, space-separated. It is empty for
+	// full-access sessions.
+	Scope     string `json:"scope,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	// ImpersonatedBy is the admin using an impersonation token.
+	ImpersonatedBy string `json:"impersonated_by,omitempty"`
+}
+
+// IntrospectionRequest is the body of a token introspection request.
+type IntrospectionRequest struct {
+	Token string `json:"token"`
 }
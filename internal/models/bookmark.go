@@ -5,16 +5,139 @@ import (
 )
 
 type Bookmark struct {
-	ID            primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
-	UserID        primitive.ObjectID   `json:"user_id" bson:"user_id"`
-	URL           string               `json:"url" bson:"url"`
-	Title         string               `json:"title" bson:"title"`
-	Summary       string               `json:"summary,omitempty" bson:"summary,omitempty"`
+	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID primitive.ObjectID `json:"user_id" bson:"user_id"`
+	// URL is the normalized URL, with a scheme, that clients link to.
+	URL string `json:"url" bson:"url"`
+	// OriginalURL is the URL as entered, kept for display when it differs from URL.
+	OriginalURL string `json:"original_url,omitempty" bson:"original_url,omitempty"`
+	// CanonicalURL is the normalized URL used to detect duplicates.
+	CanonicalURL string `json:"canonical_url,omitempty" bson:"canonical_url,omitempty"`
+	Title        string `json:"title" bson:"title"`
+	// SiteName and FaviconURL come from the page's metadata when its content is
+	// extracted. MetadataAt records the attempt, so pages without metadata are not
+	// fetched again by the backfill job.
+	SiteName   string `json:"site_name,omitempty" bson:"site_name,omitempty"`
+	FaviconURL string `json:"favicon_url,omitempty" bson:"favicon_url,omitempty"`
+	// ImageURL is the page's preview image, from which thumbnails are made.
+	ImageURL   string              `json:"image_url,omitempty" bson:"image_url,omitempty"`
+	MetadataAt *primitive.DateTime `json:"-" bson:"metadata_at,omitempty"`
+	// ContentHash and ContentSimhash fingerprint the page's extracted text. The
+	// hash only matches identical text; the simhash also finds near-duplicates,
+	// such as the same article under different URLs.
+	ContentHash    string `json:"content_hash,omitempty" bson:"content_hash,omitempty"`
+	ContentSimhash int64  `json:"-" bson:"content_simhash,omitempty"`
+	Summary        string `json:"summary,omitempty" bson:"summary,omitempty"`
+	// SummaryContentHash is the ContentHash the summary was generated from, so an
+	// unchanged page is not summarized again.
+	SummaryContentHash string `json:"-" bson:"summary_content_hash,omitempty"`
+	// SummaryTranslations holds machine translations of Summary by language code.
+	// They are dropped when the summary changes.
+	SummaryTranslations map[string]string `json:"summary_translations,omitempty" bson:"summary_translations,omitempty"`
+	// Language is the base language code of the page, such as "en", detected when
+	// its content is extracted.
+	Language      string               `json:"language,omitempty" bson:"language,omitempty"`
 	TagsID        []primitive.ObjectID `json:"tags,omitempty" bson:"tagsid,omitempty"`
 	CollectionsID []primitive.ObjectID `json:"collections,omitempty" bson:"collectionsid,omitempty"`
 	CategoryID    *primitive.ObjectID  `json:"category,omitempty" bson:"categoryid,omitempty"`
-	IsFav         bool                 `json:"is_fav" bson:"is_fav"`
-	CreatedAt     primitive.DateTime   `json:"created_at" bson:"created_at"`
+	// CategoryDetails is filled in when the category is expanded.
+	CategoryDetails *CategorySummary `json:"category_details,omitempty" bson:"-"`
+	IsFav           bool             `json:"is_fav" bson:"is_fav"`
+	// Pinned bookmarks are listed before all others.
+	Pinned bool `json:"pinned" bson:"pinned,omitempty"`
+	Read   bool `json:"read" bson:"read,omitempty"`
+	// ArchivedAt is set when the bookmark is archived, by the user or by a
+	// collection's auto-archive policy. Archived bookmarks are hidden from listings
+	// unless requested.
+	ArchivedAt *primitive.DateTime `json:"archived_at,omitempty" bson:"archived_at,omitempty"`
+	Source     *BookmarkSource     `json:"source,omitempty" bson:"source,omitempty"`
+	// MergedFrom lists the bookmarks merged into this one.
+	MergedFrom []primitive.ObjectID `json:"-" bson:"merged_from,omitempty"`
+	// Notes holds the decrypted private note; only EncryptedNotes is ever persisted.
+	Notes          string `json:"notes,omitempty" bson:"-"`
+	EncryptedNotes string `json:"-" bson:"notes_enc,omitempty"`
+	// SuggestedTags are domain-based tag names returned when a bookmark is saved
+	// without auto-applying them.
+	SuggestedTags []string           `json:"suggested_tags,omitempty" bson:"-"`
+	Highlights    []Highlight        `json:"highlights,omitempty" bson:"-"`
+	CreatedAt     primitive.DateTime `json:"created_at" bson:"created_at"`
+	// SearchGrams are the trigrams of the title, summary and URL used by fuzzy
+	// search. Missing or null means not indexed yet.
+	SearchGrams []string `json:"-" bson:"search_grams"`
+	// NoteGrams are the trigrams of the notes, hashed with the user's key so the
+	// notes stay unreadable in the database. Missing means not indexed yet.
+	NoteGrams []string `json:"-" bson:"note_grams,omitempty"`
+	// SearchMatch is set on search results.
+	SearchMatch *SearchMatch `json:"search_match,omitempty" bson:"-"`
+}
+
+// Search scopes: what a bookmark search looks at.
+const (
+	SearchScopeAll   = "all"
+	SearchScopeNotes = "notes"
+)
+
+// SearchMatch tells where a search result matched: its title, summary or URL, its
+// notes, or both. NoteTerms are the words of the notes that matched, as written,
+// and NoteExcerpt the part of the notes around the first of them.
+type SearchMatch struct {
+	Bookmark    bool     `json:"bookmark"`
+	Notes       bool     `json:"notes"`
+	NoteExcerpt string   `json:"note_excerpt,omitempty"`
+	NoteTerms   []string `json:"note_terms,omitempty"`
+}
+
+// BookmarkLite is the compact form of a bookmark returned by the lite list. Pinned
+// and CreatedAt are only read to page through the list.
+type BookmarkLite struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	Title     string             `json:"title" bson:"title"`
+	URL       string             `json:"url" bson:"url"`
+	Pinned    bool               `json:"-" bson:"pinned,omitempty"`
+	CreatedAt primitive.DateTime `json:"-" bson:"created_at"`
+}
+
+// Client types a bookmark can be captured from.
+const (
+	SourceClientExtension = "extension"
+	SourceClientWeb       = "web"
+	SourceClientMobile    = "mobile"
+	SourceClientAPI       = "api"
+	// SourceClientFeed marks bookmarks ingested from a collection feed. Clients
+	// cannot claim it.
+	SourceClientFeed = "feed"
+)
+
+// BookmarkSource records where a bookmark was captured from.
+type BookmarkSource struct {
+	Client   string `json:"client" bson:"client"`
+	Referrer string `json:"referrer,omitempty" bson:"referrer,omitempty"`
+	// FeedID is the collection feed a bookmark was ingested from.
+	FeedID *primitive.ObjectID `json:"feed_id,omitempty" bson:"feed_id,omitempty"`
+}
+
+// IsValidSourceClient reports whether client is one of the known client types.
+func IsValidSourceClient(client string) bool {
+	switch client {
+	case SourceClientExtension, SourceClientWeb, SourceClientMobile, SourceClientAPI:
+		return true
+	}
+	return false
+}
+
+type SourceCount struct {
+	Client string `json:"client" bson:"_id"`
+	Count  int    `json:"count" bson:"count"`
+}
+
+// DuplicateGroup is a set of bookmarks of one user that share a canonical URL or,
+// when grouped by content, the same article text.
+type DuplicateGroup struct {
+	CanonicalURL string `json:"canonical_url,omitempty" bson:"_id"`
+	// ContentHash is the content hash of the oldest bookmark of a content group.
+	ContentHash string     `json:"content_hash,omitempty" bson:"-"`
+	Count       int        `json:"count" bson:"count"`
+	Bookmarks   []Bookmark `json:"bookmarks" bson:"bookmarks"`
 }
 
 type BookmarkUpdate struct {
@@ -28,13 +151,25 @@ type BookmarkUpdate struct {
 }
 
 type AddBookmarkRequestBody struct {
-	URL         string   `json:"url"`
-	Title       string   `json:"title"`
-	Summary     string   `json:"summary,omitempty"`
-	Tags        []string `json:"tags,omitempty"`
-	Collections []string `json:"collections,omitempty"`
-	CategoryID  *string  `json:"category_id,omitempty"`
-	IsFav       bool     `json:"is_fav"`
+	URL         string          `json:"url"`
+	Title       string          `json:"title"`
+	Summary     string          `json:"summary,omitempty"`
+	Tags        []string        `json:"tags,omitempty"`
+	Collections []string        `json:"collections,omitempty"`
+	CategoryID  *string         `json:"category_id,omitempty"`
+	IsFav       bool            `json:"is_fav"`
+	Source      *BookmarkSource `json:"source,omitempty"`
+	Notes       string          `json:"notes,omitempty"`
+}
+
+// QuickSaveRequest is a bookmark saved from the browser extension together with
+// the text the user had selected on the page. Selection may be HTML; only its text
+// is kept.
+type QuickSaveRequest struct {
+	AddBookmarkRequestBody
+	Selection string `json:"selection,omitempty"`
+	// SummarizeSelection asks for a summary centered on the selection.
+	SummarizeSelection bool `json:"summarize_selection,omitempty"`
 }
 
 type UpdateBookmarkRequestBody struct {
@@ -45,4 +180,45 @@ type UpdateBookmarkRequestBody struct {
 	Collections *[]string `json:"collections,omitempty"`
 	CategoryID  *string   `json:"category_id,omitempty"`
 	IsFav       *bool     `json:"is_fav,omitempty"`
+	Notes       *string   `json:"notes,omitempty"`
+	Pinned      *bool     `json:"pinned,omitempty"`
+	Read        *bool     `json:"read,omitempty"`
+	Archived    *bool     `json:"archived,omitempty"`
+}
+
+// Search limits.
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+type MergeBookmarkRequest struct {
+	// SourceID is the bookmark merged into the target and then deleted.
+	SourceID string `json:"source_id"`
+}
+
+// BulkTagRequest lists the tag IDs to add to and remove from every bookmark
+// matching a filter.
+type BulkTagRequest struct {
+	Add    []string `json:"add,omitempty"`
+	Remove []string `json:"remove,omitempty"`
+}
+
+type BulkTagResult struct {
+	Matched  int64 `json:"matched"`
+	Modified int64 `json:"modified"`
+}
+
+// BatchCreateItemResult is the outcome of one item of a batch-create request.
+// Index is the item's position in the request.
+type BatchCreateItemResult struct {
+	Index    int       `json:"index"`
+	Bookmark *Bookmark `json:"bookmark,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type BatchCreateResult struct {
+	Created int                     `json:"created"`
+	Failed  int                     `json:"failed"`
+	Results []BatchCreateItemResult `json:"results"`
 }
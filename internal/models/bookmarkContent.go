@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Extraction states of a BookmarkContent.
+const (
+	ContentStatusOK     = "ok"
+	ContentStatusFailed = "failed"
+)
+
+// BookmarkContent is the readable-mode copy of a bookmarked page, stored so that
+// clients can offer offline reading.
+type BookmarkContent struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	BookmarkID  primitive.ObjectID `json:"bookmark_id" bson:"bookmark_id"`
+	UserID      primitive.ObjectID `json:"user_id" bson:"user_id"`
+	URL         string             `json:"url" bson:"url"`
+	Title       string             `json:"title,omitempty" bson:"title,omitempty"`
+	SiteName    string             `json:"site_name,omitempty" bson:"site_name,omitempty"`
+	FaviconURL  string             `json:"favicon_url,omitempty" bson:"favicon_url,omitempty"`
+	Text        string             `json:"text,omitempty" bson:"text,omitempty"`
+	HTML        string             `json:"html,omitempty" bson:"html,omitempty"`
+	WordCount   int                `json:"word_count" bson:"word_count"`
+	Status      string             `json:"status" bson:"status"`
+	Error       string             `json:"error,omitempty" bson:"error,omitempty"`
+	ExtractedAt time.Time          `json:"extracted_at" bson:"extracted_at"`
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -8,10 +10,49 @@ type Category struct {
 	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	UserID primitive.ObjectID `json:"user_id" bson:"user_id"`
 	Name   string             `json:"name" bson:"name"`
-	Emoji  string             `json:"emoji,omitempty" bson:"emoji,omitempty"`
+	// Slug addresses the category in URLs. Slugs it had before a rename keep
+	// resolving to it.
+	Slug          string   `json:"slug,omitempty" bson:"slug,omitempty"`
+	PreviousSlugs []string `json:"-" bson:"previous_slugs,omitempty"`
+	Emoji         string   `json:"emoji,omitempty" bson:"emoji,omitempty"`
+	// Color is one of CategoryColors.
+	Color string `json:"color,omitempty" bson:"color,omitempty"`
+	// IconUpdatedAt is set while the category has an uploaded icon. IconURL is
+	// derived from it and changes with every upload, so clients can cache icons.
+	IconUpdatedAt *time.Time `json:"-" bson:"icon_updated_at,omitempty"`
+	IconURL       string     `json:"icon_url,omitempty" bson:"-"`
 }
 
 type CategoryUpdate struct {
 	Name  *string `json:"name,omitempty" bson:"name,omitempty"`
 	Emoji *string `json:"emoji,omitempty" bson:"emoji,omitempty"`
+	Color *string `json:"color,omitempty" bson:"color,omitempty"`
+}
+
+// CategoryColors is the palette category colors are picked from, so that clients
+// can theme categories consistently.
+var CategoryColors = []string{
+	"#ef4444", "#f97316", "#f59e0b", "#eab308", "#84cc16", "#22c55e",
+	"#14b8a6", "#06b6d4", "#3b82f6", "#6366f1", "#8b5cf6", "#d946ef",
+	"#ec4899", "#64748b",
+}
+
+// CategoryIcon is the uploaded icon of a category, stored resized as PNG.
+type CategoryIcon struct {
+	CategoryID  primitive.ObjectID `json:"-" bson:"_id"`
+	UserID      primitive.ObjectID `json:"-" bson:"user_id"`
+	ContentType string             `json:"-" bson:"content_type"`
+	Data        []byte             `json:"-" bson:"data"`
+	UpdatedAt   time.Time          `json:"-" bson:"updated_at"`
+}
+
+// CategorySummary is the part of a category embedded in bookmarks requested with
+// expand=category, enough for clients to group bookmarks.
+type CategorySummary struct {
+	ID      primitive.ObjectID `json:"id"`
+	Name    string             `json:"name"`
+	Slug    string             `json:"slug,omitempty"`
+	Emoji   string             `json:"emoji,omitempty"`
+	Color   string             `json:"color,omitempty"`
+	IconURL string             `json:"icon_url,omitempty"`
 }
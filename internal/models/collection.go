@@ -8,8 +8,65 @@ type Collection struct {
 	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	UserID primitive.ObjectID `json:"user_id" bson:"user_id"`
 	Name   string             `json:"name" bson:"name"`
+	// Slug addresses the collection in URLs. Slugs it had before a rename keep
+	// resolving to it.
+	Slug          string              `json:"slug,omitempty" bson:"slug,omitempty"`
+	PreviousSlugs []string            `json:"-" bson:"previous_slugs,omitempty"`
+	Settings      *CollectionSettings `json:"settings,omitempty" bson:"settings,omitempty"`
 }
 
+type CollectionSettings struct {
+	AutoArchive *AutoArchivePolicy `json:"auto_archive,omitempty" bson:"auto_archive,omitempty"`
+}
+
+// AutoArchivePolicy archives the bookmarks of a collection once they are older than
+// AfterDays. Pinned bookmarks are never archived automatically.
+type AutoArchivePolicy struct {
+	AfterDays  int  `json:"after_days" bson:"after_days"`
+	UnreadOnly bool `json:"unread_only" bson:"unread_only"`
+}
+
+// MaxAutoArchiveDays bounds AutoArchivePolicy.AfterDays.
+const MaxAutoArchiveDays = 3650
+
 type CollectionUpdate struct {
-	Name *string `json:"name,omitempty" bson:"name,omitempty"`
+	Name     *string             `json:"name,omitempty" bson:"name,omitempty"`
+	Settings *CollectionSettings `json:"settings,omitempty" bson:"settings,omitempty"`
+}
+
+// CollectionStats summarises the unarchived bookmarks of a collection. WordCount
+// and ReadingMinutes only cover the Measured bookmarks, those whose page content
+// has been extracted.
+type CollectionStats struct {
+	Count          int64                `json:"count" bson:"count"`
+	Unread         int64                `json:"unread" bson:"unread"`
+	Archived       int64                `json:"archived" bson:"archived"`
+	Oldest         *CollectionStatsItem `json:"oldest" bson:"oldest"`
+	Newest         *CollectionStatsItem `json:"newest" bson:"newest"`
+	TopTags        []CollectionTagCount `json:"top_tags" bson:"top_tags"`
+	WordCount      int64                `json:"word_count" bson:"word_count"`
+	Measured       int64                `json:"measured" bson:"measured"`
+	ReadingMinutes int64                `json:"reading_minutes" bson:"-"`
+}
+
+// CollectionStatsItem identifies the oldest or newest bookmark of a collection.
+type CollectionStatsItem struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	Title     string             `json:"title" bson:"title"`
+	URL       string             `json:"url" bson:"url"`
+	CreatedAt primitive.DateTime `json:"created_at" bson:"created_at"`
+}
+
+// CollectionTagCount counts the bookmarks of a collection carrying a tag.
+type CollectionTagCount struct {
+	ID    primitive.ObjectID `json:"id" bson:"_id"`
+	Name  string             `json:"name" bson:"name"`
+	Count int64              `json:"count" bson:"count"`
+}
+
+// AutoArchivePreview lists the bookmarks an auto-archive policy would archive now.
+type AutoArchivePreview struct {
+	Policy    AutoArchivePolicy `json:"policy"`
+	Count     int64             `json:"count"`
+	Bookmarks []Bookmark        `json:"bookmarks"`
 }
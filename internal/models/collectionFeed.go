@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Bounds of CollectionFeed.IntervalMinutes.
+const (
+	DefaultFeedIntervalMinutes = 60
+	MinFeedIntervalMinutes     = 15
+	MaxFeedIntervalMinutes     = 24 * 60
+)
+
+// CollectionFeed is an RSS or Atom feed followed into a collection: its new
+// entries are saved as bookmarks of the collection. After a failed fetch the next
+// one waits twice as long as the previous wait, up to a day.
+type CollectionFeed struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id"`
+	UserID       primitive.ObjectID `json:"user_id" bson:"user_id"`
+	CollectionID primitive.ObjectID `json:"collection_id" bson:"collection_id"`
+	URL          string             `json:"url" bson:"url"`
+	Title        string             `json:"title,omitempty" bson:"title,omitempty"`
+	// IntervalMinutes is how often the feed is fetched while it works.
+	IntervalMinutes int        `json:"interval_minutes" bson:"interval_minutes"`
+	NextFetchAt     time.Time  `json:"next_fetch_at" bson:"next_fetch_at"`
+	LastFetchedAt   *time.Time `json:"last_fetched_at,omitempty" bson:"last_fetched_at,omitempty"`
+	// LastError and Failures describe the fetches that failed in a row since the
+	// last one that worked.
+	LastError string `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	Failures  int    `json:"failures" bson:"failures"`
+	// Ingested counts the bookmarks created from the feed.
+	Ingested int `json:"ingested" bson:"ingested"`
+	// ETag and LastModified make fetches conditional. Seen holds the IDs of the
+	// latest entries, so entries are saved once even after their bookmark is
+	// deleted.
+	ETag         string    `json:"-" bson:"etag,omitempty"`
+	LastModified string    `json:"-" bson:"last_modified,omitempty"`
+	Seen         []string  `json:"-" bson:"seen"`
+	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
+}
+
+// AddCollectionFeedRequest follows a feed into a collection. IntervalMinutes
+// defaults to DefaultFeedIntervalMinutes.
+type AddCollectionFeedRequest struct {
+	URL             string `json:"url"`
+	IntervalMinutes int    `json:"interval_minutes,omitempty"`
+}
+
+type CollectionFeedUpdate struct {
+	IntervalMinutes *int `json:"interval_minutes,omitempty"`
+}
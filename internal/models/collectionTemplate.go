@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CollectionTemplate describes a collection with starter tags, categories and
+// placeholder bookmarks. Built-in templates have a Key and no UserID; templates
+// saved by users are stored in the collection_templates collection.
+type CollectionTemplate struct {
+	ID          primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID      *primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	Key         string              `json:"key,omitempty" bson:"-"`
+	Name        string              `json:"name" bson:"name"`
+	Description string              `json:"description,omitempty" bson:"description,omitempty"`
+	Tags        []string            `json:"tags,omitempty" bson:"tags,omitempty"`
+	Categories  []TemplateCategory  `json:"categories,omitempty" bson:"categories,omitempty"`
+	Bookmarks   []TemplateBookmark  `json:"bookmarks,omitempty" bson:"bookmarks,omitempty"`
+	CreatedAt   *time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty"`
+}
+
+type TemplateCategory struct {
+	Name  string `json:"name" bson:"name"`
+	Emoji string `json:"emoji,omitempty" bson:"emoji,omitempty"`
+}
+
+// TemplateBookmark is a placeholder bookmark. Tags and Category refer to entries of
+// the template by name.
+type TemplateBookmark struct {
+	URL      string   `json:"url" bson:"url"`
+	Title    string   `json:"title" bson:"title"`
+	Summary  string   `json:"summary,omitempty" bson:"summary,omitempty"`
+	Tags     []string `json:"tags,omitempty" bson:"tags,omitempty"`
+	Category string   `json:"category,omitempty" bson:"category,omitempty"`
+}
+
+type CreateFromTemplateRequest struct {
+	// Template is the key of a built-in template or the ID of a saved one.
+	Template string `json:"template"`
+	// Name overrides the collection name; it defaults to the template name.
+	Name string `json:"name,omitempty"`
+}
+
+type SaveAsTemplateRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// FromTemplateResult lists everything created from a template. Tags and categories
+// the user already had are reused and included as well.
+type FromTemplateResult struct {
+	Collection *Collection `json:"collection"`
+	Tags       []Tag       `json:"tags"`
+	Categories []Category  `json:"categories"`
+	Bookmarks  []Bookmark  `json:"bookmarks"`
+}
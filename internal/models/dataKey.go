@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DataKey is a per-user encryption key, stored wrapped by the master key.
+type DataKey struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	UserID     primitive.ObjectID `bson:"user_id"`
+	WrappedKey []byte             `bson:"wrapped_key"`
+	CreatedAt  time.Time          `bson:"created_at"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErasureReport records the irreversible erasure of a user's data. Reports are
+// kept after the data is gone: they hold no personal data, and restores skip the
+// documents of every user that has one.
+type ErasureReport struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID      primitive.ObjectID `json:"user_id" bson:"user_id"`
+	RequestedBy primitive.ObjectID `json:"requested_by" bson:"requested_by"`
+	// Reference identifies the legal request, such as a ticket number.
+	Reference string `json:"reference" bson:"reference"`
+	// Collections counts the documents deleted from each collection.
+	Collections           map[string]int64 `json:"collections" bson:"collections"`
+	AuditEventsAnonymized int64            `json:"audit_events_anonymized" bson:"audit_events_anonymized"`
+	// SubscriptionsRemoved counts the subscriptions of the user's email address to
+	// newsletters of other users that were deleted.
+	SubscriptionsRemoved int64     `json:"subscriptions_removed,omitempty" bson:"subscriptions_removed,omitempty"`
+	StartedAt            time.Time `json:"started_at" bson:"started_at"`
+	// CompletedAt is unset while the erasure runs, or if it failed part way.
+	CompletedAt *time.Time `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+	// Signature is the hex HMAC-SHA256 of the report without it, keyed with
+	// ERASURE_SIGNING_KEY.
+	Signature string `json:"signature,omitempty" bson:"signature,omitempty"`
+}
+
+type EraseUserRequest struct {
+	// Confirm must repeat the ID of the user being erased.
+	Confirm   string `json:"confirm"`
+	Reference string `json:"reference"`
+}
+
+// ErasureVerification is an erasure report together with whether its signature
+// is valid for the current signing key.
+type ErasureVerification struct {
+	Report         ErasureReport `json:"report"`
+	SignatureValid bool          `json:"signature_valid"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// MarklyExportFormat is the version of the Markly JSON export format.
+const MarklyExportFormat = 1
+
+// MarklyExport is everything one user owns, in the form produced by
+// GET /api/export/markly and accepted by POST /api/import/markly. IDs are those of
+// the exporting instance; bookmarks reference tags, collections and categories by
+// them, and carry their decrypted notes and their highlights.
+type MarklyExport struct {
+	Format      int           `json:"markly_export"`
+	ExportedAt  time.Time     `json:"exported_at"`
+	Settings    *UserSettings `json:"settings,omitempty"`
+	Tags        []Tag         `json:"tags"`
+	Categories  []Category    `json:"categories"`
+	Collections []Collection  `json:"collections"`
+	Bookmarks   []Bookmark    `json:"bookmarks"`
+}
+
+// ImportCount counts the items of one kind that an import created, and those it
+// matched to what the user already had.
+type ImportCount struct {
+	Created  int `json:"created"`
+	Existing int `json:"existing"`
+}
+
+// MarklyImportResult summarises an import. Re-running the same import creates
+// nothing new.
+type MarklyImportResult struct {
+	Tags             ImportCount `json:"tags"`
+	Categories       ImportCount `json:"categories"`
+	Collections      ImportCount `json:"collections"`
+	Bookmarks        ImportCount `json:"bookmarks"`
+	Highlights       ImportCount `json:"highlights"`
+	SettingsRestored bool        `json:"settings_restored"`
+}
+
+// CSVColumnMapping names the header of the CSV column holding each bookmark field.
+// Only URL is required; columns that are not mapped are ignored.
+type CSVColumnMapping struct {
+	URL        string `json:"url"`
+	Title      string `json:"title,omitempty"`
+	Tags       string `json:"tags,omitempty"`
+	CreatedAt  string `json:"created_at,omitempty"`
+	Collection string `json:"collection,omitempty"`
+	// TagSeparator splits the tags column into tag names. It defaults to ",".
+	TagSeparator string `json:"tag_separator,omitempty"`
+}
+
+// CSVRowError reports a CSV record that was not imported. Row counts records from
+// 1, the header.
+type CSVRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// CSVImportResult summarises a CSV import. Rows counts the data records read.
+// Errors holds the first failed rows; Failed counts all of them.
+type CSVImportResult struct {
+	Rows            int           `json:"rows"`
+	Tags            ImportCount   `json:"tags"`
+	Collections     ImportCount   `json:"collections"`
+	Bookmarks       ImportCount   `json:"bookmarks"`
+	Failed          int           `json:"failed"`
+	Errors          []CSVRowError `json:"errors"`
+	ErrorsTruncated bool          `json:"errors_truncated,omitempty"`
+	// Truncated is set when the file had more records than one import reads.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// MarkdownFile is one note of a Markdown export: a bookmark written as Markdown
+// with YAML front matter.
+type MarkdownFile struct {
+	Name       string
+	ModifiedAt time.Time
+	Content    []byte
+}
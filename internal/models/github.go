@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GitHubStarsCollection is the collection starred repositories are imported into.
+const GitHubStarsCollection = "GitHub Stars"
+
+// GitHubPushScope is the OAuth scope that lets Markdown exports be pushed to the
+// account's repositories.
+const GitHubPushScope = "repo"
+
+// GitHubConnection links a user to the GitHub account whose starred repositories
+// are imported. The access token is encrypted with the user's data key. StateHash
+// and StateExpiresAt belong to an authorization the user has not completed yet.
+type GitHubConnection struct {
+	ID             primitive.ObjectID `json:"-" bson:"_id,omitempty"`
+	UserID         primitive.ObjectID `json:"-" bson:"user_id"`
+	Connected      bool               `json:"connected" bson:"-"`
+	Login          string             `json:"login,omitempty" bson:"login,omitempty"`
+	EncryptedToken string             `json:"-" bson:"token_enc,omitempty"`
+	StateHash      string             `json:"-" bson:"state_hash,omitempty"`
+	StateExpiresAt *time.Time         `json:"-" bson:"state_expires_at,omitempty"`
+	ConnectedAt    *time.Time         `json:"connected_at,omitempty" bson:"connected_at,omitempty"`
+	// Scopes are the OAuth scopes GitHub granted. Pushing Markdown exports needs
+	// GitHubPushScope.
+	Scopes       []string   `json:"scopes,omitempty" bson:"scopes,omitempty"`
+	LastSyncedAt *time.Time `json:"last_synced_at,omitempty" bson:"last_synced_at,omitempty"`
+	// LastSyncError is set when the last sync failed; LastSync then describes the
+	// last one that succeeded.
+	LastSyncError string            `json:"last_sync_error,omitempty" bson:"last_sync_error,omitempty"`
+	LastSync      *GitHubSyncResult `json:"last_sync,omitempty" bson:"last_sync,omitempty"`
+}
+
+// GitHubSyncResult summarises a sync of starred repositories. Truncated is set
+// when the account has more stars than one sync reads.
+type GitHubSyncResult struct {
+	Starred   int  `json:"starred" bson:"starred"`
+	Created   int  `json:"created" bson:"created"`
+	Existing  int  `json:"existing" bson:"existing"`
+	Truncated bool `json:"truncated,omitempty" bson:"truncated,omitempty"`
+}
+
+// GitHubAuthorization is where to send the user to connect their GitHub account.
+// State is also kept in a cookie of the browser, which the callback checks.
+type GitHubAuthorization struct {
+	AuthorizeURL string    `json:"authorize_url"`
+	State        string    `json:"-"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// MarkdownPushRequest says where to commit a Markdown export. Branch defaults to
+// the repository's default branch and Directory to "markly".
+type MarkdownPushRequest struct {
+	Repository string `json:"repository"`
+	Branch     string `json:"branch,omitempty"`
+	Directory  string `json:"directory,omitempty"`
+}
+
+// MarkdownPushResult is the commit a Markdown export was pushed as.
+type MarkdownPushResult struct {
+	Repository string `json:"repository"`
+	Branch     string `json:"branch"`
+	Commit     string `json:"commit"`
+	CommitURL  string `json:"commit_url"`
+	Files      int    `json:"files"`
+}
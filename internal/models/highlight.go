@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Highlight colors offered by the browser extension.
+var HighlightColors = []string{"yellow", "green", "blue", "pink", "purple"}
+
+const DefaultHighlightColor = "yellow"
+
+// Highlight is a passage of a bookmarked page selected by the user. Prefix and
+// Suffix hold the text around the selection so clients can anchor it again.
+type Highlight struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	BookmarkID primitive.ObjectID `json:"bookmark_id" bson:"bookmark_id"`
+	UserID     primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Text       string             `json:"text" bson:"text"`
+	Prefix     string             `json:"prefix,omitempty" bson:"prefix,omitempty"`
+	Suffix     string             `json:"suffix,omitempty" bson:"suffix,omitempty"`
+	Color      string             `json:"color" bson:"color"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type CreateHighlightRequest struct {
+	Text   string `json:"text"`
+	Prefix string `json:"prefix,omitempty"`
+	Suffix string `json:"suffix,omitempty"`
+	Color  string `json:"color,omitempty"`
+}
+
+type HighlightUpdate struct {
+	Color *string `json:"color,omitempty"`
+}
+
+// IsValidHighlightColor reports whether color is one of HighlightColors.
+func IsValidHighlightColor(color string) bool {
+	for _, c := range HighlightColors {
+		if c == color {
+			return true
+		}
+	}
+	return false
+}
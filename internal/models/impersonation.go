@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Impersonation statuses. A request starts pending and is approved or denied by
+// the user; an approved one can be revoked by the user until it expires.
+const (
+	ImpersonationPending  = "pending"
+	ImpersonationApproved = "approved"
+	ImpersonationDenied   = "denied"
+	ImpersonationRevoked  = "revoked"
+)
+
+// Impersonation is an admin's request to act as a user for a limited time.
+type Impersonation struct {
+	ID              primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	AdminID         primitive.ObjectID `json:"admin_id" bson:"admin_id"`
+	UserID          primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Reason          string             `json:"reason" bson:"reason"`
+	Status          string             `json:"status" bson:"status"`
+	DurationMinutes int                `json:"duration_minutes" bson:"duration_minutes"`
+	ApprovalHash    string             `json:"-" bson:"approval_hash"`
+	// ApproveBy is when a pending request lapses.
+	ApproveBy  time.Time  `json:"approve_by" bson:"approve_by"`
+	ApprovedAt *time.Time `json:"approved_at,omitempty" bson:"approved_at,omitempty"`
+	// ExpiresAt is when tokens for an approved request stop working.
+	ExpiresAt *time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at"`
+}
+
+// Active reports whether tokens for the impersonation are valid at now.
+func (i *Impersonation) Active(now time.Time) bool {
+	return i.Status == ImpersonationApproved && i.ExpiresAt != nil && now.Before(*i.ExpiresAt)
+}
+
+type CreateImpersonationRequest struct {
+	UserID          string `json:"user_id"`
+	Reason          string `json:"reason"`
+	DurationMinutes int    `json:"duration_minutes,omitempty"`
+}
+
+type ImpersonationDecision struct {
+	Token string `json:"token"`
+}
+
+// ImpersonationToken is a read-only JWT for the impersonated user.
+type ImpersonationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Sources of background imports.
+const (
+	ImportSourceHackerNews = "hackernews"
+	ImportSourceReddit     = "reddit"
+)
+
+// Import job statuses. A queued job waits for a free import slot.
+const (
+	ImportJobQueued    = "queued"
+	ImportJobRunning   = "running"
+	ImportJobCompleted = "completed"
+	ImportJobFailed    = "failed"
+)
+
+// ImportItemError reports an item of an import that was not saved. Item counts the
+// items of the source from 1.
+type ImportItemError struct {
+	Item  int    `json:"item" bson:"item"`
+	URL   string `json:"url,omitempty" bson:"url,omitempty"`
+	Error string `json:"error" bson:"error"`
+}
+
+// ImportJob is an import that runs in the background. Processed grows towards
+// Total while it runs; Errors holds the first failed items and Failed counts all
+// of them.
+type ImportJob struct {
+	ID     primitive.ObjectID `json:"id" bson:"_id"`
+	UserID primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Source string             `json:"source" bson:"source"`
+	Status string             `json:"status" bson:"status"`
+	// Total counts the items read from the source so far.
+	Total     int         `json:"total" bson:"total"`
+	Processed int         `json:"processed" bson:"processed"`
+	Tags      ImportCount `json:"tags" bson:"tags"`
+	Bookmarks ImportCount `json:"bookmarks" bson:"bookmarks"`
+	Failed    int         `json:"failed" bson:"failed"`
+	// Truncated is set when the source held more items than an import reads.
+	Truncated       bool              `json:"truncated,omitempty" bson:"truncated,omitempty"`
+	Errors          []ImportItemError `json:"errors" bson:"errors"`
+	ErrorsTruncated bool              `json:"errors_truncated,omitempty" bson:"errors_truncated,omitempty"`
+	// Error tells why a failed job stopped.
+	Error      string     `json:"error,omitempty" bson:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+	// DurationSeconds is how long the job has run, up to now while it runs.
+	DurationSeconds float64 `json:"duration_seconds,omitempty" bson:"-"`
+	// UpdatedAt is refreshed while the job is queued or running; jobs left behind
+	// by a stopped server are recognised by it.
+	UpdatedAt time.Time `json:"-" bson:"updated_at"`
+	// Active is set while the job is queued or running. A unique index on it lets a
+	// user run one import at a time.
+	Active bool `json:"-" bson:"active,omitempty"`
+}
+
+// HackerNewsImportRequest asks for the favorites of a Hacker News account, which
+// are public.
+type HackerNewsImportRequest struct {
+	Username string `json:"username"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Signup modes of an instance.
+const (
+	SignupOpen       = "open"
+	SignupInviteOnly = "invite_only"
+	SignupDisabled   = "disabled"
+)
+
+// InstanceSettings are the settings of the whole deployment, set by admins. An
+// instance without stored settings behaves like the zero value with SignupOpen.
+type InstanceSettings struct {
+	SignupMode string `json:"signup_mode" bson:"signup_mode"`
+	// AllowedEmailDomains restricts new accounts to email addresses of these
+	// domains and their subdomains. Empty allows every domain.
+	AllowedEmailDomains []string `json:"allowed_email_domains" bson:"allowed_email_domains"`
+	// DefaultPlan is recorded on every new account.
+	DefaultPlan string              `json:"default_plan,omitempty" bson:"default_plan,omitempty"`
+	Retention   RetentionPolicy     `json:"retention" bson:"retention"`
+	UpdatedAt   *time.Time          `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+	UpdatedBy   *primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+}
+
+type InstanceSettingsUpdate struct {
+	SignupMode          *string                `json:"signup_mode,omitempty"`
+	AllowedEmailDomains *[]string              `json:"allowed_email_domains,omitempty"`
+	DefaultPlan         *string                `json:"default_plan,omitempty"`
+	Retention           *RetentionPolicyUpdate `json:"retention,omitempty"`
+}
+
+// IsValidSignupMode reports whether mode is one of the signup modes.
+func IsValidSignupMode(mode string) bool {
+	switch mode {
+	case SignupOpen, SignupInviteOnly, SignupDisabled:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Invite is a code a user hands out to let others sign up. New accounts record
+// who invited them in User.ReferredBy.
+type Invite struct {
+	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Code   string             `json:"code" bson:"code"`
+	// MaxUses is the number of accounts the code can create. Zero is unlimited,
+	// which only admins can ask for.
+	MaxUses   int        `json:"max_uses" bson:"max_uses"`
+	Uses      int        `json:"uses" bson:"uses"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at"`
+}
+
+type CreateInviteRequest struct {
+	MaxUses   int        `json:"max_uses,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Referral is an account created with one of a user's invite codes.
+type Referral struct {
+	UserID     primitive.ObjectID `json:"user_id" bson:"_id"`
+	Username   string             `json:"username" bson:"username"`
+	InviteCode string             `json:"invite_code" bson:"invite_code"`
+	JoinedAt   time.Time          `json:"joined_at" bson:"created_at"`
+}
+
+type InviteOverview struct {
+	Invites   []Invite   `json:"invites"`
+	Referrals []Referral `json:"referrals"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// Limits are the sizes operators can tune without code changes. They are read from
+// the LIMIT_* environment variables at startup.
+type Limits struct {
+	// DefaultPageSize is the page size of cursor-paginated lists when no limit is
+	// given, and MaxPageSize the largest limit accepted.
+	DefaultPageSize int64 `json:"default_page_size"`
+	MaxPageSize     int64 `json:"max_page_size"`
+	// BookmarkPageSize is the page size of the page-numbered bookmark list.
+	BookmarkPageSize      int64 `json:"bookmark_page_size"`
+	MaxBatchSize          int   `json:"max_batch_size"`
+	MaxTagsPerBookmark    int   `json:"max_tags_per_bookmark"`
+	MaxCollectionsPerUser int   `json:"max_collections_per_user"`
+	// MaxThumbnailsPerMonth bounds the preview images generated for one user per
+	// calendar month (UTC).
+	MaxThumbnailsPerMonth int `json:"max_thumbnails_per_month"`
+	// MaxSelectionLength bounds, in characters, the page text a quick save keeps
+	// as the bookmark's note.
+	MaxSelectionLength int `json:"max_selection_length"`
+}
+
+// DefaultLimits are used for every limit that is not configured.
+func DefaultLimits() Limits {
+	return Limits{
+		DefaultPageSize:       50,
+		MaxPageSize:           200,
+		BookmarkPageSize:      5,
+		MaxBatchSize:          100,
+		MaxTagsPerBookmark:    100,
+		MaxCollectionsPerUser: 1000,
+		MaxThumbnailsPerMonth: 500,
+		MaxSelectionLength:    10000,
+	}
+}
+
+// RateLimitStatus is the state of the request rate limit bucket of a client.
+// Remaining is the number of requests that can be sent right away; the bucket is
+// full again at ResetAt.
+type RateLimitStatus struct {
+	Scope     string    `json:"scope"`
+	PerSecond float64   `json:"per_second"`
+	Burst     int       `json:"burst"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+// QuotaUsage is a user's consumption of one quota. ResetAt is set for quotas that
+// start over periodically.
+type QuotaUsage struct {
+	Name    string     `json:"name"`
+	Used    int64      `json:"used"`
+	Limit   int64      `json:"limit"`
+	ResetAt *time.Time `json:"reset_at,omitempty"`
+}
+
+// LimitsStatus is returned by GET /api/me/limits.
+type LimitsStatus struct {
+	RateLimit RateLimitStatus `json:"rate_limit"`
+	Quotas    []QuotaUsage    `json:"quotas"`
+	Limits    Limits          `json:"limits"`
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NewsletterSubscriber receives the newsletter of a collection once they have
+// confirmed the subscription. Unsubscribed subscribers are kept so that the owner
+// cannot add them back.
+type NewsletterSubscriber struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID       primitive.ObjectID `json:"-" bson:"user_id"`
+	CollectionID primitive.ObjectID `json:"collection_id" bson:"collection_id"`
+	Email        string             `json:"email" bson:"email"`
+	// UnsubscribeToken is the hash of the token in the unsubscribe link of every
+	// newsletter. The token itself is kept in EncryptedToken, encrypted with the
+	// data key of the owner.
+	UnsubscribeToken string `json:"-" bson:"unsubscribe_token"`
+	EncryptedToken   string `json:"-" bson:"encrypted_token,omitempty"`
+	// ConfirmToken is the hash of the token in the confirmation email.
+	ConfirmToken   string     `json:"-" bson:"confirm_token,omitempty"`
+	ConfirmedAt    *time.Time `json:"confirmed_at,omitempty" bson:"confirmed_at,omitempty"`
+	UnsubscribedAt *time.Time `json:"unsubscribed_at,omitempty" bson:"unsubscribed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" bson:"created_at"`
+}
+
+type AddNewsletterSubscriberRequest struct {
+	Email string `json:"email"`
+}
+
+type NewsletterUnsubscribeRequest struct {
+	Token string `json:"token"`
+}
+
+type NewsletterConfirmRequest struct {
+	Token string `json:"token"`
+}
+
+// Newsletter send statuses. A send is created as sending and finishes as sent when
+// at least one email went out, or failed otherwise.
+const (
+	NewsletterSending = "sending"
+	NewsletterSent    = "sent"
+	NewsletterFailed  = "failed"
+)
+
+// NewsletterSend records one newsletter sent for a collection.
+type NewsletterSend struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID       primitive.ObjectID `json:"-" bson:"user_id"`
+	CollectionID primitive.ObjectID `json:"collection_id" bson:"collection_id"`
+	Subject      string             `json:"subject" bson:"subject"`
+	// Since is the start of the period whose additions the newsletter covers.
+	Since       time.Time            `json:"since" bson:"since"`
+	BookmarkIDs []primitive.ObjectID `json:"bookmark_ids" bson:"bookmark_ids"`
+	AIIntro     bool                 `json:"ai_intro" bson:"ai_intro"`
+	Status      string               `json:"status" bson:"status"`
+	Recipients  int                  `json:"recipients" bson:"recipients"`
+	Delivered   int                  `json:"delivered" bson:"delivered"`
+	Failed      int                  `json:"failed" bson:"failed"`
+	CreatedAt   time.Time            `json:"created_at" bson:"created_at"`
+	FinishedAt  *time.Time           `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+}
+
+type SendNewsletterRequest struct {
+	// Subject defaults to the collection name.
+	Subject string `json:"subject,omitempty"`
+	// Since defaults to the previous successful send, or to a week ago for the
+	// first one.
+	Since *time.Time `json:"since,omitempty"`
+	// AIIntro opens the newsletter with an introduction written by the LLM.
+	AIIntro bool `json:"ai_intro,omitempty"`
+}
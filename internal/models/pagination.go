@@ -0,0 +1,13 @@
+package models
+
+// PageRequest asks for one page of a list. Cursor is the opaque value returned with
+// the previous page; it is only valid for the same list and filters.
+type PageRequest struct {
+	Limit  int64
+	Cursor string
+}
+
+// Paged reports whether a page was asked for at all.
+func (p PageRequest) Paged() bool {
+	return p.Limit > 0 || p.Cursor != ""
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// Retention targets, the kinds of records a retention policy purges. Each is
+// named after its collection.
+const (
+	RetentionAuditEvents     = "audit_events"
+	RetentionAIEvents        = "ai_events"
+	RetentionImportJobs      = "import_jobs"
+	RetentionNewsletterSends = "newsletter_sends"
+	RetentionImpersonations  = "impersonations"
+	RetentionThumbnailUsage  = "thumbnail_usage"
+)
+
+// RetentionTargets lists every retention target in the order they are reported.
+var RetentionTargets = []string{RetentionAuditEvents, RetentionAIEvents, RetentionImportJobs, RetentionNewsletterSends, RetentionImpersonations, RetentionThumbnailUsage}
+
+// RetentionPolicy says how many days records of each target are kept. Targets
+// without a number of days are kept forever.
+type RetentionPolicy struct {
+	Days map[string]int `json:"days" bson:"days,omitempty"`
+	// DryRun makes the purge job report what it would delete without deleting it.
+	DryRun bool `json:"dry_run" bson:"dry_run"`
+}
+
+type RetentionPolicyUpdate struct {
+	// Days sets the days of the targets it holds. 0 keeps a target forever again.
+	Days   map[string]int `json:"days,omitempty"`
+	DryRun *bool          `json:"dry_run,omitempty"`
+}
+
+// RetentionReport is the result of a purge. A dry run counts the expired records
+// without deleting them.
+type RetentionReport struct {
+	DryRun  bool                    `json:"dry_run"`
+	RanAt   time.Time               `json:"ran_at"`
+	Targets []RetentionTargetReport `json:"targets"`
+}
+
+// RetentionTargetReport is the result of a purge for one target. Expired counts
+// the records older than Cutoff when the purge started.
+type RetentionTargetReport struct {
+	Target  string    `json:"target"`
+	Days    int       `json:"days"`
+	Cutoff  time.Time `json:"cutoff"`
+	Expired int64     `json:"expired"`
+	Deleted int64     `json:"deleted"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// RetentionStatus is the retention policy with the last purge of this server.
+type RetentionStatus struct {
+	Policy  RetentionPolicy  `json:"policy"`
+	LastRun *RetentionReport `json:"last_run,omitempty"`
+}
+
+// IsRetentionTarget reports whether target is one of the retention targets.
+func IsRetentionTarget(target string) bool {
+	for _, t := range RetentionTargets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+package models
+
+// SDKIndex lists the client SDK descriptors the server offers, one set per API
+// version.
+type SDKIndex struct {
+	ServerVersion string       `json:"server_version"`
+	Versions      []SDKVersion `json:"versions"`
+}
+
+type SDKVersion struct {
+	Version   string        `json:"version"`
+	Artifacts []SDKArtifact `json:"artifacts"`
+}
+
+// SDKArtifact is a downloadable descriptor. SHA256 is also its ETag.
+type SDKArtifact struct {
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"`
+	SHA256      string `json:"sha256"`
+}
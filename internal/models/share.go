@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BookmarkShare is a public, expiring link to a read-only view of one bookmark.
+// Only a hash of the token is stored; the token is returned once on creation.
+type BookmarkShare struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID       primitive.ObjectID `json:"user_id" bson:"user_id"`
+	BookmarkID   primitive.ObjectID `json:"bookmark_id" bson:"bookmark_id"`
+	TokenHash    string             `json:"-" bson:"token_hash"`
+	ExpiresAt    time.Time          `json:"expires_at" bson:"expires_at"`
+	Views        int                `json:"views" bson:"views"`
+	LastViewedAt *time.Time         `json:"last_viewed_at,omitempty" bson:"last_viewed_at,omitempty"`
+	RevokedAt    *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type CreateShareRequest struct {
+	// ExpiresAt defaults to seven days from now.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// CreatedShare is returned once on creation and is the only time the token is exposed.
+type CreatedShare struct {
+	BookmarkShare
+	Token string `json:"token"`
+	// URL is the link to hand out, set when SHARE_LINK_URL is configured.
+	URL string `json:"url,omitempty"`
+}
+
+// SharedBookmark is what a share link shows. It never contains notes, tags or
+// anything else about the owner's library.
+type SharedBookmark struct {
+	Title     string         `json:"title"`
+	URL       string         `json:"url"`
+	Summary   string         `json:"summary,omitempty"`
+	Content   *SharedContent `json:"content,omitempty"`
+	ExpiresAt time.Time      `json:"expires_at"`
+}
+
+// SharedContent is the archived readable copy of a shared page.
+type SharedContent struct {
+	SiteName    string    `json:"site_name,omitempty"`
+	Text        string    `json:"text,omitempty"`
+	HTML        string    `json:"html,omitempty"`
+	WordCount   int       `json:"word_count"`
+	ExtractedAt time.Time `json:"extracted_at"`
+}
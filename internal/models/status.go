@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// QueueDepth describes a bounded background work queue.
+type QueueDepth struct {
+	InFlight int `json:"in_flight"`
+	Capacity int `json:"capacity"`
+}
+
+// StatusJob is the public view of a scheduled job; errors are left out.
+type StatusJob struct {
+	Name            string     `json:"name"`
+	IntervalSeconds int64      `json:"interval_seconds"`
+	LastRunAt       *time.Time `json:"last_run_at,omitempty"`
+	LastStatus      string     `json:"last_status,omitempty"`
+	Running         bool       `json:"running"`
+}
+
+// Status is served by the public status page endpoint. It must never contain
+// configuration values or error details.
+type Status struct {
+	Status        string                `json:"status"`
+	Version       string                `json:"version"`
+	StartedAt     time.Time             `json:"started_at"`
+	UptimeSeconds int64                 `json:"uptime_seconds"`
+	Queues        map[string]QueueDepth `json:"queues"`
+	Jobs          []StatusJob           `json:"jobs"`
+}
@@ -16,3 +16,13 @@ type TagUpdate struct {
 	WeeklyCount *int    `json:"weeklyCount,omitempty" bson:"weekly_count,omitempty"`
 	PrevCount   *int    `json:"prevCount,omitempty" bson:"prev_count,omitempty"`
 }
+
+// TagTrend compares how often a tag was used on bookmarks saved in the last 7 days
+// (WeeklyCount) with the 7 days before (PrevCount).
+type TagTrend struct {
+	TagID       primitive.ObjectID `json:"id" bson:"_id"`
+	Name        string             `json:"name" bson:"-"`
+	WeeklyCount int                `json:"weeklyCount" bson:"weekly_count"`
+	PrevCount   int                `json:"prevCount" bson:"prev_count"`
+	Delta       int                `json:"delta" bson:"-"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TagSubscription asks for a notification when trending domains or AI suggestions
+// match a tag. Tag is stored lowercased and matched case-insensitively; the user
+// does not need to have a tag of that name.
+type TagSubscription struct {
+	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Tag    string             `json:"tag" bson:"tag"`
+	// Trending enables notifications for trending domains mapped to the tag.
+	Trending bool `json:"trending" bson:"trending"`
+	// Suggestions enables notifications for AI suggestions carrying the tag.
+	Suggestions bool      `json:"suggestions" bson:"suggestions"`
+	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// TagSubscriptionRequest creates a subscription or changes its preferences. Both
+// sources default to enabled on creation.
+type TagSubscriptionRequest struct {
+	Tag         string `json:"tag,omitempty"`
+	Trending    *bool  `json:"trending,omitempty"`
+	Suggestions *bool  `json:"suggestions,omitempty"`
+}
+
+const (
+	TagNotificationTrending   = "trending"
+	TagNotificationSuggestion = "suggestion"
+)
+
+// TagNotification records an item a user was notified about for a tag, so the
+// same trending domain or suggested URL is only reported once.
+type TagNotification struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	Tag       string             `bson:"tag"`
+	Source    string             `bson:"source"`
+	Item      string             `bson:"item"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
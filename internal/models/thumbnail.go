@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BookmarkThumbnail is a small PNG of a bookmark's preview image, cached for
+// newsletters and clients. It is made again when the preview image changes.
+type BookmarkThumbnail struct {
+	BookmarkID  primitive.ObjectID `json:"-" bson:"_id"`
+	UserID      primitive.ObjectID `json:"-" bson:"user_id"`
+	SourceURL   string             `json:"-" bson:"source_url"`
+	ContentType string             `json:"-" bson:"content_type"`
+	Data        []byte             `json:"-" bson:"data"`
+	CreatedAt   time.Time          `json:"-" bson:"created_at"`
+}
@@ -1,6 +1,10 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type TrendingItem struct {
 	ID    primitive.ObjectID `json:"id" bson:"_id"`
@@ -15,4 +19,21 @@ type AISuggestion struct {
 	Category   string   `json:"category"`
 	Collection string   `json:"collection"`
 	Tags       []string `json:"tags"`
+	// PromptVersion identifies the prompt that produced the suggestion; clients send
+	// it back with feedback.
+	PromptVersion string `json:"prompt_version"`
+}
+
+// TrendingDomain is a domain bookmarked by many distinct users.
+type TrendingDomain struct {
+	Domain    string `json:"domain" bson:"_id"`
+	Bookmarks int    `json:"bookmarks" bson:"bookmarks"`
+}
+
+// TrendingDomains is a cached snapshot of the trending domains.
+type TrendingDomains struct {
+	Domains    []TrendingDomain `json:"domains" bson:"domains"`
+	MinUsers   int              `json:"min_users" bson:"min_users"`
+	Since      time.Time        `json:"since" bson:"since"`
+	ComputedAt time.Time        `json:"computed_at" bson:"computed_at"`
 }
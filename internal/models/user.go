@@ -7,12 +7,83 @@ import (
 )
 
 type User struct {
-	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
-	Username  string             `json:"username" bson:"username"`
-	Email     string             `json:"email" bson:"email"`
-	Password  string             `json:"password" bson:"password"`
-	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+	ID       primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	Username string             `json:"username" bson:"username"`
+	Email    string             `json:"email" bson:"email"`
+	Password string             `json:"password" bson:"password"`
+	// Role is empty for regular users. It can only be set in the database.
+	Role string `json:"role,omitempty" bson:"role,omitempty"`
+	Plan string `json:"plan,omitempty" bson:"plan,omitempty"`
+	// InviteCode is the code the account was registered with, if any, and
+	// ReferredBy the user who created it.
+	InviteCode string              `json:"invite_code,omitempty" bson:"invite_code,omitempty"`
+	ReferredBy *primitive.ObjectID `json:"referred_by,omitempty" bson:"referred_by,omitempty"`
+	Settings   *UserSettings       `json:"settings,omitempty" bson:"settings,omitempty"`
+	CreatedAt  time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+// RoleAdmin grants access to the /api/admin endpoints.
+const RoleAdmin = "admin"
+
+// UserSettings holds per-user preferences.
+type UserSettings struct {
+	// DomainTagRules extend or override the built-in domain to tag mapping.
+	// A rule with no tags disables the built-in mapping for that domain.
+	DomainTagRules []DomainTagRule `json:"domain_tag_rules,omitempty" bson:"domain_tag_rules,omitempty"`
+	// AutoApplyDomainTags attaches the suggested tags when a bookmark is saved.
+	AutoApplyDomainTags bool `json:"auto_apply_domain_tags" bson:"auto_apply_domain_tags"`
+	// ExcludeFromTrending keeps the user's bookmarks out of cross-user trending data.
+	ExcludeFromTrending bool `json:"exclude_from_trending" bson:"exclude_from_trending"`
+	// DisableExternalAI stops the user's bookmark titles and URLs from being sent to
+	// external LLM providers, which disables the AI features.
+	DisableExternalAI bool `json:"disable_external_ai" bson:"disable_external_ai"`
+	// Timezone is an IANA name such as "Europe/Berlin", used to group analytics by
+	// day and week. Empty means UTC.
+	Timezone string `json:"timezone,omitempty" bson:"timezone,omitempty"`
+	// ActivityWebhookURL receives a small JSON payload when the user adds, updates or
+	// deletes a bookmark, for tools such as n8n or Home Assistant.
+	ActivityWebhookURL     string `json:"activity_webhook_url,omitempty" bson:"activity_webhook_url,omitempty"`
+	ActivityWebhookEnabled bool   `json:"activity_webhook_enabled" bson:"activity_webhook_enabled"`
+	// ActivityWebhookSecret is sent with every delivery in the X-Markly-Secret header.
+	// It is generated the first time the webhook is enabled.
+	ActivityWebhookSecret string `json:"activity_webhook_secret,omitempty" bson:"activity_webhook_secret,omitempty"`
+}
+
+// DomainTagRule maps a domain and its subdomains to tag names.
+type DomainTagRule struct {
+	Domain string   `json:"domain" bson:"domain"`
+	Tags   []string `json:"tags" bson:"tags"`
+}
+
+type UserSettingsUpdate struct {
+	DomainTagRules         *[]DomainTagRule `json:"domain_tag_rules,omitempty"`
+	AutoApplyDomainTags    *bool            `json:"auto_apply_domain_tags,omitempty"`
+	ExcludeFromTrending    *bool            `json:"exclude_from_trending,omitempty"`
+	DisableExternalAI      *bool            `json:"disable_external_ai,omitempty"`
+	Timezone               *string          `json:"timezone,omitempty"`
+	ActivityWebhookURL     *string          `json:"activity_webhook_url,omitempty"`
+	ActivityWebhookEnabled *bool            `json:"activity_webhook_enabled,omitempty"`
+	// RotateActivityWebhookSecret replaces the secret with a new one.
+	RotateActivityWebhookSecret bool `json:"rotate_activity_webhook_secret,omitempty"`
+}
+
+// Activity webhook events.
+const (
+	ActivityBookmarkCreated = "bookmark.created"
+	ActivityBookmarkUpdated = "bookmark.updated"
+	ActivityBookmarkDeleted = "bookmark.deleted"
+	// Sent instead of bookmark.updated by the favorite endpoints.
+	ActivityBookmarkFavorited   = "bookmark.favorited"
+	ActivityBookmarkUnfavorited = "bookmark.unfavorited"
+)
+
+// ActivityWebhookPayload is the body posted to a user's activity webhook.
+type ActivityWebhookPayload struct {
+	Event string   `json:"event"`
+	URL   string   `json:"url"`
+	Title string   `json:"title"`
+	Tags  []string `json:"tags"`
 }
 
 type UserProfileUpdate struct {
@@ -0,0 +1,195 @@
+// Package preflight checks configuration and external dependencies before the API
+// starts serving traffic. It backs the --validate flag of cmd/api, which deploy
+// pipelines run against a new release before switching traffic to it.
+package preflight
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gopkg.in/gomail.v2"
+
+	"markly/internal/database"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+// Check is the outcome of a single validation step.
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report collects every check. OK is false when at least one check failed;
+// warnings do not fail validation.
+type Report struct {
+	OK     bool    `json:"ok"`
+	Checks []Check `json:"checks"`
+}
+
+func (r *Report) add(name, status, detail string) {
+	r.Checks = append(r.Checks, Check{Name: name, Status: status, Detail: detail})
+	if status == StatusFail {
+		r.OK = false
+	}
+}
+
+// Run executes all checks. It never stops early so the report lists every problem at once.
+func Run(ctx context.Context) Report {
+	report := Report{OK: true}
+
+	checkConfig(&report)
+	checkMongo(ctx, &report)
+	checkMongoFallbacks(ctx, &report)
+	checkSMTP(&report)
+	checkLLM(ctx, &report)
+
+	return report
+}
+
+func checkConfig(report *Report) {
+	before := len(report.Checks)
+	required := []string{"PORT", "JWT_SECRET", "SESSION_KEY"}
+	if os.Getenv("MONGO_URI") == "" {
+		required = append(required, "BLUEPRINT_DB_HOST", "BLUEPRINT_DB_PORT")
+	}
+	for _, name := range required {
+		if os.Getenv(name) == "" {
+			report.add("config."+strings.ToLower(name), StatusFail, name+" is not set")
+		}
+	}
+	if port := os.Getenv("PORT"); port != "" {
+		if _, err := strconv.Atoi(port); err != nil {
+			report.add("config.port", StatusFail, "PORT is not a number")
+		}
+	}
+	if key := os.Getenv("NOTES_MASTER_KEY"); key != "" {
+		decoded, err := base64.StdEncoding.DecodeString(key)
+		if err != nil || len(decoded) != 32 {
+			report.add("config.notes_master_key", StatusFail, "NOTES_MASTER_KEY must be 32 bytes encoded as base64")
+		}
+	} else {
+		report.add("config.notes_master_key", StatusWarn, "NOTES_MASTER_KEY is not set, private notes are disabled")
+	}
+	for _, check := range report.Checks[before:] {
+		if check.Status == StatusFail {
+			return
+		}
+	}
+	report.add("config", StatusOK, "")
+}
+
+func checkMongo(ctx context.Context, report *Report) {
+	connectCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client, check, err := connectMongo(connectCtx, database.Targets()[0].URI)
+	if err != nil {
+		report.add(check, StatusFail, err.Error())
+		return
+	}
+	defer client.Disconnect(context.Background())
+	report.add("mongo.ping", StatusOK, "")
+
+	missing, err := database.MissingIndexes(connectCtx, client)
+	switch {
+	case err != nil:
+		report.add("mongo.indexes", StatusFail, err.Error())
+	case len(missing) > 0:
+		report.add("mongo.indexes", StatusFail, "missing indexes: "+strings.Join(missing, ", "))
+	default:
+		report.add("mongo.indexes", StatusOK, fmt.Sprintf("%d indexes present", len(database.RequiredIndexes)))
+	}
+}
+
+// checkMongoFallbacks pings the fallback MongoDB targets. An unreachable
+// fallback is only a warning, since the server starts without it.
+func checkMongoFallbacks(ctx context.Context, report *Report) {
+	for _, target := range database.Targets()[1:] {
+		connectCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		client, check, err := connectMongo(connectCtx, target.URI)
+		cancel()
+		if err != nil {
+			report.add("mongo.ping."+target.Name, StatusWarn, check+": "+err.Error())
+			continue
+		}
+		client.Disconnect(context.Background())
+		report.add("mongo.ping."+target.Name, StatusOK, "")
+	}
+}
+
+// connectMongo connects to uri and pings it. On failure it also returns the
+// name of the step that failed, mongo.connect or mongo.ping.
+func connectMongo(ctx context.Context, uri string) (*mongo.Client, string, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetServerSelectionTimeout(5*time.Second))
+	if err != nil {
+		return nil, "mongo.connect", err
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return nil, "mongo.ping", err
+	}
+	return client, "", nil
+}
+
+func checkSMTP(report *Report) {
+	username, password := os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD")
+	if username == "" || password == "" {
+		report.add("smtp", StatusFail, "SMTP_USERNAME and SMTP_PASSWORD must be set")
+		return
+	}
+
+	// Dialing authenticates against the server without sending anything.
+	sender, err := gomail.NewDialer("smtp.gmail.com", 587, username, password).Dial()
+	if err != nil {
+		report.add("smtp", StatusFail, err.Error())
+		return
+	}
+	sender.Close()
+	report.add("smtp", StatusOK, "")
+}
+
+func checkLLM(ctx context.Context, report *Report) {
+	key := os.Getenv("API_KEY")
+	if key == "" {
+		report.add("llm", StatusFail, "API_KEY is not set")
+		return
+	}
+
+	// Listing models verifies the key without spending generation quota.
+	reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	endpoint := "https://generativelanguage.googleapis.com/v1beta/models?pageSize=1&key=" + url.QueryEscape(key)
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		report.add("llm", StatusFail, err.Error())
+		return
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// The error message contains the request URL, and therefore the key.
+		report.add("llm", StatusFail, "failed to reach the Gemini API")
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		report.add("llm", StatusFail, fmt.Sprintf("Gemini API rejected the key with status %d", resp.StatusCode))
+		return
+	}
+	report.add("llm", StatusOK, "")
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/database"
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+type AIEventRepository interface {
+	Create(ctx context.Context, event *models.AIEvent) error
+	CountSince(ctx context.Context, since time.Time) ([]models.AIEventCount, error)
+}
+
+type aiEventRepository struct {
+	db database.Service
+}
+
+func NewAIEventRepository(db database.Service) AIEventRepository {
+	return &aiEventRepository{db: db}
+}
+
+func (r *aiEventRepository) Create(ctx context.Context, event *models.AIEvent) error {
+	queryType := "create"
+	repository := "aiEvent"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("ai_events")
+	if _, err := collection.InsertOne(ctx, event); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to record AI event: %w", err)
+	}
+	return nil
+}
+
+// CountSince totals the events created since the given time by kind, prompt version
+// and action.
+func (r *aiEventRepository) CountSince(ctx context.Context, since time.Time) ([]models.AIEventCount, error) {
+	queryType := "countSince"
+	repository := "aiEvent"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("ai_events")
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"created_at": bson.M{"$gte": since}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"kind": "$kind", "prompt_version": "$prompt_version", "action": "$action"},
+			"count": bson.M{"$sum": "$count"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":            0,
+			"kind":           "$_id.kind",
+			"prompt_version": "$_id.prompt_version",
+			"action":         "$_id.action",
+			"count":          1,
+		}}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to count AI events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var counts []models.AIEventCount
+	if err := cursor.All(ctx, &counts); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding AI event counts: %w", err)
+	}
+	return counts, nil
+}
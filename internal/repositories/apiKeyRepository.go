@@ -0,0 +1,177 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/database"
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) (*models.APIKey, error)
+	FindByID(ctx context.Context, userID, keyID primitive.ObjectID) (*models.APIKey, error)
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.APIKey, error)
+	FindByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	Update(ctx context.Context, userID, keyID primitive.ObjectID, updateFields bson.M) (*mongo.UpdateResult, error)
+	Delete(ctx context.Context, userID, keyID primitive.ObjectID) (*mongo.DeleteResult, error)
+	TouchLastUsed(ctx context.Context, keyID primitive.ObjectID, usedAt time.Time, ip string) error
+}
+
+type apiKeyRepository struct {
+	db database.Service
+}
+
+func NewAPIKeyRepository(db database.Service) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	queryType := "create"
+	repository := "apiKey"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("api_keys")
+	_, err := collection.InsertOne(ctx, key)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to insert api key: %w", err)
+	}
+	return key, nil
+}
+
+func (r *apiKeyRepository) FindByID(ctx context.Context, userID, keyID primitive.ObjectID) (*models.APIKey, error) {
+	queryType := "findByID"
+	repository := "apiKey"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	var key models.APIKey
+	collection := r.db.Client().Database("markly").Collection("api_keys")
+	err := collection.FindOne(ctx, bson.M{"_id": keyID, "user_id": userID}).Decode(&key)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.APIKey, error) {
+	queryType := "findByUser"
+	repository := "apiKey"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	var keys []models.APIKey
+	collection := r.db.Client().Database("markly").Collection("api_keys")
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to retrieve api keys: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &keys); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding api keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *apiKeyRepository) FindByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	queryType := "findByHash"
+	repository := "apiKey"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	var key models.APIKey
+	collection := r.db.Client().Database("markly").Collection("api_keys")
+	err := collection.FindOne(ctx, bson.M{"key_hash": keyHash}).Decode(&key)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) Update(ctx context.Context, userID, keyID primitive.ObjectID, updateFields bson.M) (*mongo.UpdateResult, error) {
+	queryType := "update"
+	repository := "apiKey"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("api_keys")
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": keyID, "user_id": userID}, bson.M{"$set": updateFields})
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to update api key: %w", err)
+	}
+	return result, nil
+}
+
+func (r *apiKeyRepository) Delete(ctx context.Context, userID, keyID primitive.ObjectID) (*mongo.DeleteResult, error) {
+	queryType := "delete"
+	repository := "apiKey"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("api_keys")
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": keyID, "user_id": userID})
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to delete api key: %w", err)
+	}
+	return result, nil
+}
+
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, keyID primitive.ObjectID, usedAt time.Time, ip string) error {
+	queryType := "touchLastUsed"
+	repository := "apiKey"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("api_keys")
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": keyID}, bson.M{"$set": bson.M{"last_used_at": usedAt, "last_used_ip": ip}})
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to record api key usage: %w", err)
+	}
+	return nil
+}
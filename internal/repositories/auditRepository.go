@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/database"
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+type AuditRepository interface {
+	Create(ctx context.Context, event *models.AuditEvent) error
+	Find(ctx context.Context, filter bson.M, limit int64) ([]models.AuditEvent, error)
+	Anonymize(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
+
+type auditRepository struct {
+	db database.Service
+}
+
+func NewAuditRepository(db database.Service) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) Create(ctx context.Context, event *models.AuditEvent) error {
+	queryType := "create"
+	repository := "audit"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("audit_events")
+	if _, err := collection.InsertOne(ctx, event); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to insert audit event: %w", err)
+	}
+	return nil
+}
+
+// Find returns the newest events matching filter first.
+func (r *auditRepository) Find(ctx context.Context, filter bson.M, limit int64) ([]models.AuditEvent, error) {
+	queryType := "find"
+	repository := "audit"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("audit_events")
+	filter, opts := newFindOptions(bson.D{{Key: "created_at", Value: -1}}).offset(limit, 1).build(filter)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to find audit events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var events []models.AuditEvent
+	if err := cursor.All(ctx, &events); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding audit events: %w", err)
+	}
+	return events, nil
+}
+
+// Anonymize strips the IP address, user agent, path and details of the events in which userID
+// acted or was affected. The events themselves are kept for the audit trail.
+func (r *auditRepository) Anonymize(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	queryType := "anonymize"
+	repository := "audit"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("audit_events")
+	filter := bson.M{"$or": []bson.M{{"user_id": userID}, {"actor_id": userID}}}
+	result, err := collection.UpdateMany(ctx, filter, bson.M{"$unset": bson.M{"ip": "", "user_agent": "", "path": "", "details": ""}})
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return 0, fmt.Errorf("failed to anonymize audit events: %w", err)
+	}
+	return result.ModifiedCount, nil
+}
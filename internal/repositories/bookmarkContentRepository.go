@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"markly/internal/database"
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+type BookmarkContentRepository interface {
+	Upsert(ctx context.Context, content *models.BookmarkContent) error
+	FindByBookmark(ctx context.Context, userID, bookmarkID primitive.ObjectID) (*models.BookmarkContent, error)
+	DeleteByBookmark(ctx context.Context, userID, bookmarkID primitive.ObjectID) error
+}
+
+type bookmarkContentRepository struct {
+	db database.Service
+}
+
+func NewBookmarkContentRepository(db database.Service) BookmarkContentRepository {
+	return &bookmarkContentRepository{db: db}
+}
+
+// Upsert stores content as the only content document of its bookmark.
+func (r *bookmarkContentRepository) Upsert(ctx context.Context, content *models.BookmarkContent) error {
+	queryType := "upsert"
+	repository := "bookmarkContent"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("bookmark_contents")
+	filter := bson.M{"bookmark_id": content.BookmarkID, "user_id": content.UserID}
+	fields := bson.M{
+		"url":          content.URL,
+		"title":        content.Title,
+		"site_name":    content.SiteName,
+		"text":         content.Text,
+		"html":         content.HTML,
+		"word_count":   content.WordCount,
+		"status":       content.Status,
+		"error":        content.Error,
+		"extracted_at": content.ExtractedAt,
+	}
+	update := bson.M{
+		"$set":         fields,
+		"$setOnInsert": bson.M{"_id": primitive.NewObjectID()},
+	}
+	if _, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to store bookmark content: %w", err)
+	}
+	return nil
+}
+
+func (r *bookmarkContentRepository) FindByBookmark(ctx context.Context, userID, bookmarkID primitive.ObjectID) (*models.BookmarkContent, error) {
+	queryType := "findByBookmark"
+	repository := "bookmarkContent"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("bookmark_contents")
+	var content models.BookmarkContent
+	err := collection.FindOne(ctx, bson.M{"bookmark_id": bookmarkID, "user_id": userID}).Decode(&content)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			status = "error"
+			utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		}
+		return nil, err
+	}
+	return &content, nil
+}
+
+func (r *bookmarkContentRepository) DeleteByBookmark(ctx context.Context, userID, bookmarkID primitive.ObjectID) error {
+	queryType := "deleteByBookmark"
+	repository := "bookmarkContent"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("bookmark_contents")
+	if _, err := collection.DeleteMany(ctx, bson.M{"bookmark_id": bookmarkID, "user_id": userID}); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to delete bookmark content: %w", err)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus" // Added for Prometheus
 	"go.mongodb.org/mongo-driver/bson"
@@ -17,12 +18,27 @@ import (
 
 type BookmarkRepository interface {
 	Create(ctx context.Context, bm *models.Bookmark) (*models.Bookmark, error)
+	CreateMany(ctx context.Context, bms []*models.Bookmark) error
 	Find(ctx context.Context, filter bson.M, limit, page int64) ([]models.Bookmark, error)
+	FindPage(ctx context.Context, filter bson.M, page models.PageRequest) ([]models.Bookmark, string, error)
+	FindLitePage(ctx context.Context, filter bson.M, page models.PageRequest) ([]models.BookmarkLite, string, error)
 	FindOne(ctx context.Context, filter bson.M) (*models.Bookmark, error)
+	Count(ctx context.Context, filter bson.M) (int64, error)
 	UpdateOne(ctx context.Context, filter bson.M, update bson.M) (*mongo.UpdateResult, error)
+	UpdateMany(ctx context.Context, filter bson.M, update interface{}) (*mongo.UpdateResult, error)
 	DeleteOne(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error)
+	FindOneAndDelete(ctx context.Context, filter bson.M) (*models.Bookmark, error)
 	CountBookmarksCreatedBetween(ctx context.Context, startDate, endDate interface{}) (int64, error)
+	CountBookmarksByInterval(ctx context.Context, startDate, endDate time.Time, interval, timezone string) ([]models.TimeBucket, error)
 	CountFavoriteBookmarks(ctx context.Context, userID primitive.ObjectID) (int64, error)
+	CountBookmarksBySource(ctx context.Context, userID primitive.ObjectID) ([]models.SourceCount, error)
+	FindDuplicates(ctx context.Context, userID primitive.ObjectID) ([]models.DuplicateGroup, error)
+	FindFingerprinted(ctx context.Context, userID primitive.ObjectID) ([]models.Bookmark, error)
+	CountTagUsage(ctx context.Context, userID primitive.ObjectID, prevStart, weekStart time.Time) ([]models.TagTrend, error)
+	CountDomains(ctx context.Context, since time.Time, excludeUsers []primitive.ObjectID, minUsers, limit int) ([]models.TrendingDomain, error)
+	FindByGrams(ctx context.Context, userID primitive.ObjectID, field string, grams []string, limit int64) ([]models.Bookmark, error)
+	TextSearch(ctx context.Context, userID primitive.ObjectID, query string, limit int64) ([]models.Bookmark, error)
+	CollectionStats(ctx context.Context, userID, collectionID primitive.ObjectID, topTags int) (*models.CollectionStats, error)
 }
 
 type bookmarkRepository struct {
@@ -53,6 +69,30 @@ func (r *bookmarkRepository) Create(ctx context.Context, bm *models.Bookmark) (*
 	return bm, nil
 }
 
+// CreateMany inserts bookmarks unordered, so one failing document does not stop the
+// rest. Per-document failures are reported through a wrapped mongo.BulkWriteException.
+func (r *bookmarkRepository) CreateMany(ctx context.Context, bms []*models.Bookmark) error {
+	queryType := "create_many"
+	repository := "bookmark"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	docs := make([]interface{}, len(bms))
+	for i, bm := range bms {
+		docs[i] = bm
+	}
+	collection := r.db.Client().Database("markly").Collection("bookmarks")
+	if _, err := collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false)); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to add bookmarks: %w", err)
+	}
+	return nil
+}
+
 func (r *bookmarkRepository) Find(ctx context.Context, filter bson.M, limit, page int64) ([]models.Bookmark, error) {
 	queryType := "find"
 	repository := "bookmark"
@@ -63,7 +103,7 @@ func (r *bookmarkRepository) Find(ctx context.Context, filter bson.M, limit, pag
 	defer timer.ObserveDuration()
 
 	collection := r.db.Client().Database("markly").Collection("bookmarks")
-	opts := options.Find().SetLimit(limit).SetSkip((page - 1) * limit)
+	filter, opts := newFindOptions(bookmarkListSort).project(bookmarkGramsProjection).offset(limit, page).build(filter)
 
 	cursor, err := collection.Find(ctx, filter, opts)
 
@@ -83,6 +123,152 @@ func (r *bookmarkRepository) Find(ctx context.Context, filter bson.M, limit, pag
 	return bookmarks, nil
 }
 
+// bookmarkGramsProjection leaves out the search trigrams, which can hold a thousand
+// strings, of the bookmarks read for display or processing.
+var bookmarkGramsProjection = bson.M{"search_grams": 0, "note_grams": 0}
+
+// bookmarkListSort lists pinned bookmarks first, then the newest.
+var bookmarkListSort = bson.D{{Key: "pinned", Value: -1}, {Key: "created_at", Value: -1}}
+
+// FindPage returns one page of the bookmarks matching filter in list order, and the
+// cursor of the next page.
+func (r *bookmarkRepository) FindPage(ctx context.Context, filter bson.M, page models.PageRequest) ([]models.Bookmark, string, error) {
+	queryType := "findPage"
+	repository := "bookmark"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	fo, err := newFindOptions(bookmarkListSort).project(bookmarkGramsProjection).page(page)
+	if err != nil {
+		return nil, "", err
+	}
+	filter, opts := fo.build(filter)
+	collection := r.db.Client().Database("markly").Collection("bookmarks")
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, "", fmt.Errorf("failed to retrieve bookmarks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	bookmarks := []models.Bookmark{}
+	if err := cursor.All(ctx, &bookmarks); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, "", fmt.Errorf("error decoding bookmarks: %w", err)
+	}
+	return nextPage(fo, bookmarks)
+}
+
+// bookmarkLiteProjection reads the fields of BookmarkLite, including the sort keys
+// its cursors are built from.
+var bookmarkLiteProjection = bson.M{"title": 1, "url": 1, "pinned": 1, "created_at": 1}
+
+// FindLitePage is FindPage reading only the fields of the lite list.
+func (r *bookmarkRepository) FindLitePage(ctx context.Context, filter bson.M, page models.PageRequest) ([]models.BookmarkLite, string, error) {
+	queryType := "findLitePage"
+	repository := "bookmark"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	fo, err := newFindOptions(bookmarkListSort).project(bookmarkLiteProjection).page(page)
+	if err != nil {
+		return nil, "", err
+	}
+	filter, opts := fo.build(filter)
+	collection := r.db.Client().Database("markly").Collection("bookmarks")
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, "", fmt.Errorf("failed to retrieve bookmarks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	bookmarks := []models.BookmarkLite{}
+	if err := cursor.All(ctx, &bookmarks); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, "", fmt.Errorf("error decoding bookmarks: %w", err)
+	}
+	return nextPage(fo, bookmarks)
+}
+
+// FindByGrams returns the user's bookmarks sharing the most trigrams with grams in
+// field, search_grams or note_grams, as candidates for search.
+func (r *bookmarkRepository) FindByGrams(ctx context.Context, userID primitive.ObjectID, field string, grams []string, limit int64) ([]models.Bookmark, error) {
+	queryType := "findByGrams"
+	repository := "bookmark"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("bookmarks")
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID, field: bson.M{"$in": grams}}}},
+		{{Key: "$addFields", Value: bson.M{"_shared": bson.M{"$size": bson.M{"$setIntersection": bson.A{"$" + field, grams}}}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_shared", Value: -1}, {Key: "created_at", Value: -1}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$project", Value: bson.M{"_shared": 0, "search_grams": 0, "note_grams": 0}}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to search bookmarks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var bookmarks []models.Bookmark
+	if err := cursor.All(ctx, &bookmarks); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding bookmarks: %w", err)
+	}
+	return bookmarks, nil
+}
+
+// TextSearch runs a MongoDB text search over the user's bookmarks, best match first.
+func (r *bookmarkRepository) TextSearch(ctx context.Context, userID primitive.ObjectID, query string, limit int64) ([]models.Bookmark, error) {
+	queryType := "textSearch"
+	repository := "bookmark"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("bookmarks")
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().SetLimit(limit).
+		SetProjection(bson.M{"score": score, "search_grams": 0, "note_grams": 0}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "created_at", Value: -1}})
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID, "$text": bson.M{"$search": query}}, opts)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to search bookmarks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var bookmarks []models.Bookmark
+	if err := cursor.All(ctx, &bookmarks); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding bookmarks: %w", err)
+	}
+	return bookmarks, nil
+}
+
 func (r *bookmarkRepository) FindOne(ctx context.Context, filter bson.M) (*models.Bookmark, error) {
 	queryType := "findOne"
 	repository := "bookmark"
@@ -94,7 +280,7 @@ func (r *bookmarkRepository) FindOne(ctx context.Context, filter bson.M) (*model
 
 	var bm models.Bookmark
 	collection := r.db.Client().Database("markly").Collection("bookmarks")
-	err := collection.FindOne(ctx, filter).Decode(&bm)
+	err := collection.FindOne(ctx, filter, options.FindOne().SetProjection(bookmarkGramsProjection)).Decode(&bm)
 	if err != nil {
 		status = "error"
 		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
@@ -103,6 +289,25 @@ func (r *bookmarkRepository) FindOne(ctx context.Context, filter bson.M) (*model
 	return &bm, nil
 }
 
+func (r *bookmarkRepository) Count(ctx context.Context, filter bson.M) (int64, error) {
+	queryType := "count"
+	repository := "bookmark"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("bookmarks")
+	count, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return 0, fmt.Errorf("failed to count bookmarks: %w", err)
+	}
+	return count, nil
+}
+
 func (r *bookmarkRepository) UpdateOne(ctx context.Context, filter bson.M, update bson.M) (*mongo.UpdateResult, error) {
 	queryType := "updateOne"
 	repository := "bookmark"
@@ -122,6 +327,25 @@ func (r *bookmarkRepository) UpdateOne(ctx context.Context, filter bson.M, updat
 	return result, nil
 }
 
+func (r *bookmarkRepository) UpdateMany(ctx context.Context, filter bson.M, update interface{}) (*mongo.UpdateResult, error) {
+	queryType := "updateMany"
+	repository := "bookmark"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("bookmarks")
+	result, err := collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to update bookmarks: %w", err)
+	}
+	return result, nil
+}
+
 func (r *bookmarkRepository) DeleteOne(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error) {
 	queryType := "deleteOne"
 	repository := "bookmark"
@@ -141,6 +365,29 @@ func (r *bookmarkRepository) DeleteOne(ctx context.Context, filter bson.M) (*mon
 	return deleteResult, nil
 }
 
+// FindOneAndDelete deletes the matching bookmark and returns it as it was, or
+// mongo.ErrNoDocuments when nothing matched.
+func (r *bookmarkRepository) FindOneAndDelete(ctx context.Context, filter bson.M) (*models.Bookmark, error) {
+	queryType := "findOneAndDelete"
+	repository := "bookmark"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("bookmarks")
+	var bm models.Bookmark
+	if err := collection.FindOneAndDelete(ctx, filter).Decode(&bm); err != nil {
+		if err != mongo.ErrNoDocuments {
+			status = "error"
+			utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		}
+		return nil, err
+	}
+	return &bm, nil
+}
+
 func (r *bookmarkRepository) CountBookmarksCreatedBetween(ctx context.Context, startDate, endDate interface{}) (int64, error) {
 	queryType := "countBookmarksCreatedBetween"
 	repository := "bookmark"
@@ -166,6 +413,50 @@ func (r *bookmarkRepository) CountBookmarksCreatedBetween(ctx context.Context, s
 	return count, nil
 }
 
+// CountBookmarksByInterval counts the bookmarks created between startDate and endDate
+// per day or week in timezone.
+func (r *bookmarkRepository) CountBookmarksByInterval(ctx context.Context, startDate, endDate time.Time, interval, timezone string) ([]models.TimeBucket, error) {
+	queryType := "countBookmarksByInterval"
+	repository := "bookmark"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("bookmarks")
+	cursor, err := collection.Aggregate(ctx, timeBucketPipeline(startDate, endDate, interval, timezone))
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to count bookmarks by %s: %w", interval, err)
+	}
+	defer cursor.Close(ctx)
+
+	var buckets []models.TimeBucket
+	if err := cursor.All(ctx, &buckets); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding bookmark counts: %w", err)
+	}
+	return buckets, nil
+}
+
+// timeBucketPipeline groups the documents created between startDate and endDate by
+// the start of their day or week in timezone. $dateTrunc needs MongoDB 5.0 or later.
+func timeBucketPipeline(startDate, endDate time.Time, interval, timezone string) mongo.Pipeline {
+	filter := bson.M{"created_at": bson.M{"$gte": startDate, "$lte": endDate}}
+	trunc := bson.M{"date": "$created_at", "unit": interval, "timezone": timezone}
+	if interval == models.IntervalWeek {
+		trunc["startOfWeek"] = "monday"
+	}
+	return mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{"_id": bson.M{"$dateTrunc": trunc}, "count": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+}
+
 func (r *bookmarkRepository) CountFavoriteBookmarks(ctx context.Context, userID primitive.ObjectID) (int64, error) {
 	queryType := "countFavoriteBookmarks"
 	repository := "bookmark"
@@ -187,4 +478,294 @@ func (r *bookmarkRepository) CountFavoriteBookmarks(ctx context.Context, userID
 		return 0, fmt.Errorf("failed to count favorite bookmarks for user %s: %w", userID.Hex(), err)
 	}
 	return count, nil
-}
\ No newline at end of file
+}
+func (r *bookmarkRepository) CountBookmarksBySource(ctx context.Context, userID primitive.ObjectID) ([]models.SourceCount, error) {
+	queryType := "countBookmarksBySource"
+	repository := "bookmark"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("bookmarks")
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"$ifNull": bson.A{"$source.client", "unknown"}},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.M{"count": -1}}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to count bookmarks by source for user %s: %w", userID.Hex(), err)
+	}
+	defer cursor.Close(ctx)
+
+	var counts []models.SourceCount
+	if err := cursor.All(ctx, &counts); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding bookmark source counts: %w", err)
+	}
+	return counts, nil
+}
+
+// dedupURL is the URL bookmarks are grouped by: the canonical URL, or the raw URL
+// of bookmarks that have none. Older releases stored an empty canonical URL when
+// canonicalization failed, so that counts as none too.
+var dedupURL = bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$canonical_url", ""}}, "$canonical_url", "$url"}}
+
+// FindDuplicates groups the user's bookmarks by canonical URL and returns the groups
+// with more than one bookmark. Bookmarks saved before canonicalization was introduced
+// are grouped by their raw URL.
+func (r *bookmarkRepository) FindDuplicates(ctx context.Context, userID primitive.ObjectID) ([]models.DuplicateGroup, error) {
+	queryType := "findDuplicates"
+	repository := "bookmark"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("bookmarks")
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID}}},
+		{{Key: "$sort", Value: bson.M{"created_at": 1}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       dedupURL,
+			"count":     bson.M{"$sum": 1},
+			"bookmarks": bson.M{"$push": "$$ROOT"},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to find duplicate bookmarks for user %s: %w", userID.Hex(), err)
+	}
+	defer cursor.Close(ctx)
+
+	var groups []models.DuplicateGroup
+	if err := cursor.All(ctx, &groups); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding duplicate bookmarks: %w", err)
+	}
+	return groups, nil
+}
+
+// FindFingerprinted returns the user's bookmarks with a content fingerprint, oldest
+// first, without their search trigrams.
+func (r *bookmarkRepository) FindFingerprinted(ctx context.Context, userID primitive.ObjectID) ([]models.Bookmark, error) {
+	queryType := "findFingerprinted"
+	repository := "bookmark"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("bookmarks")
+	filter := bson.M{"user_id": userID, "content_hash": bson.M{"$nin": bson.A{nil, ""}}}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetProjection(bookmarkGramsProjection)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to find fingerprinted bookmarks for user %s: %w", userID.Hex(), err)
+	}
+	defer cursor.Close(ctx)
+
+	var bookmarks []models.Bookmark
+	if err := cursor.All(ctx, &bookmarks); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding fingerprinted bookmarks: %w", err)
+	}
+	return bookmarks, nil
+}
+
+// CountTagUsage counts, per tag, the user's bookmarks created since weekStart and those
+// created between prevStart and weekStart.
+func (r *bookmarkRepository) CountTagUsage(ctx context.Context, userID primitive.ObjectID, prevStart, weekStart time.Time) ([]models.TagTrend, error) {
+	queryType := "countTagUsage"
+	repository := "bookmark"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	inWeek := bson.M{"$gte": bson.A{"$created_at", weekStart}}
+	collection := r.db.Client().Database("markly").Collection("bookmarks")
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID, "created_at": bson.M{"$gte": prevStart}}}},
+		{{Key: "$unwind", Value: "$tagsid"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":          "$tagsid",
+			"weekly_count": bson.M{"$sum": bson.M{"$cond": bson.A{inWeek, 1, 0}}},
+			"prev_count":   bson.M{"$sum": bson.M{"$cond": bson.A{inWeek, 0, 1}}},
+		}}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to count tag usage for user %s: %w", userID.Hex(), err)
+	}
+	defer cursor.Close(ctx)
+
+	var trends []models.TagTrend
+	if err := cursor.All(ctx, &trends); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding tag usage: %w", err)
+	}
+	return trends, nil
+}
+
+// CountDomains counts the bookmarks created since the given time by domain across all
+// users. Only domains saved by at least minUsers distinct users are returned, so that
+// no single user's browsing can be inferred. The bookmarks of excludeUsers are ignored.
+func (r *bookmarkRepository) CountDomains(ctx context.Context, since time.Time, excludeUsers []primitive.ObjectID, minUsers, limit int) ([]models.TrendingDomain, error) {
+	queryType := "countDomains"
+	repository := "bookmark"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	host := bson.M{"$regexFind": bson.M{
+		"input":   dedupURL,
+		"regex":   `^[a-z][a-z0-9+.-]*://(?:www\.)?([^/:?#]+)`,
+		"options": "i",
+	}}
+	match := bson.M{"created_at": bson.M{"$gte": since}}
+	if len(excludeUsers) > 0 {
+		match["user_id"] = bson.M{"$nin": excludeUsers}
+	}
+	collection := r.db.Client().Database("markly").Collection("bookmarks")
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$project", Value: bson.M{"user_id": 1, "host": host}}},
+		{{Key: "$match", Value: bson.M{"host": bson.M{"$ne": nil}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       bson.M{"$toLower": bson.M{"$arrayElemAt": bson.A{"$host.captures", 0}}},
+			"bookmarks": bson.M{"$sum": 1},
+			"users":     bson.M{"$addToSet": "$user_id"},
+		}}},
+		{{Key: "$match", Value: bson.M{"$expr": bson.M{"$gte": bson.A{bson.M{"$size": "$users"}, minUsers}}}}},
+		{{Key: "$project", Value: bson.M{"bookmarks": 1}}},
+		{{Key: "$sort", Value: bson.D{{Key: "bookmarks", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to count bookmark domains: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var domains []models.TrendingDomain
+	if err := cursor.All(ctx, &domains); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding bookmark domains: %w", err)
+	}
+	return domains, nil
+}
+
+// CollectionStats summarises the bookmarks of a collection in one aggregation. All
+// figures but Archived ignore archived bookmarks. Word counts come from extracted
+// content that is still current for the bookmark's URL.
+func (r *bookmarkRepository) CollectionStats(ctx context.Context, userID, collectionID primitive.ObjectID, topTags int) (*models.CollectionStats, error) {
+	queryType := "collectionStats"
+	repository := "bookmark"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	active := bson.D{{Key: "$match", Value: bson.M{"archived_at": nil}}}
+	item := bson.D{{Key: "$project", Value: bson.M{"title": 1, "url": 1, "created_at": 1}}}
+	first := func(field string) bson.M {
+		return bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{field, 0}}, 0}}
+	}
+	collection := r.db.Client().Database("markly").Collection("bookmarks")
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID, "collectionsid": collectionID}}},
+		{{Key: "$facet", Value: bson.M{
+			"summary": bson.A{active, bson.D{{Key: "$group", Value: bson.M{
+				"_id":    nil,
+				"count":  bson.M{"$sum": 1},
+				"unread": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$read", true}}, 0, 1}}},
+			}}}},
+			"archived": bson.A{bson.D{{Key: "$match", Value: bson.M{"archived_at": bson.M{"$ne": nil}}}}, bson.D{{Key: "$count", Value: "count"}}},
+			"oldest":   bson.A{active, bson.D{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}}}, bson.D{{Key: "$limit", Value: 1}}, item},
+			"newest":   bson.A{active, bson.D{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}}}, bson.D{{Key: "$limit", Value: 1}}, item},
+			"top_tags": bson.A{
+				active,
+				bson.D{{Key: "$unwind", Value: "$tagsid"}},
+				bson.D{{Key: "$group", Value: bson.M{"_id": "$tagsid", "count": bson.M{"$sum": 1}}}},
+				bson.D{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+				// Tags deleted without being removed from bookmarks are skipped.
+				bson.D{{Key: "$lookup", Value: bson.M{"from": "tags", "localField": "_id", "foreignField": "_id", "as": "tag"}}},
+				bson.D{{Key: "$match", Value: bson.M{"tag.0": bson.M{"$exists": true}}}},
+				bson.D{{Key: "$limit", Value: topTags}},
+				bson.D{{Key: "$project", Value: bson.M{"count": 1, "name": bson.M{"$arrayElemAt": bson.A{"$tag.name", 0}}}}},
+			},
+			"reading": bson.A{
+				active,
+				bson.D{{Key: "$lookup", Value: bson.M{"from": "bookmark_contents", "localField": "_id", "foreignField": "bookmark_id", "as": "content"}}},
+				bson.D{{Key: "$unwind", Value: "$content"}},
+				bson.D{{Key: "$match", Value: bson.M{"content.status": models.ContentStatusOK, "$expr": bson.M{"$eq": bson.A{"$content.url", "$url"}}}}},
+				bson.D{{Key: "$group", Value: bson.M{
+					"_id":        nil,
+					"word_count": bson.M{"$sum": "$content.word_count"},
+					"measured":   bson.M{"$sum": 1},
+				}}},
+			},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"count":      first("$summary.count"),
+			"unread":     first("$summary.unread"),
+			"archived":   first("$archived.count"),
+			"oldest":     bson.M{"$arrayElemAt": bson.A{"$oldest", 0}},
+			"newest":     bson.M{"$arrayElemAt": bson.A{"$newest", 0}},
+			"top_tags":   "$top_tags",
+			"word_count": first("$reading.word_count"),
+			"measured":   first("$reading.measured"),
+		}}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to compute stats of collection %s: %w", collectionID.Hex(), err)
+	}
+	defer cursor.Close(ctx)
+
+	var stats models.CollectionStats
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&stats); err != nil {
+			status = "error"
+			utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+			return nil, fmt.Errorf("error decoding collection stats: %w", err)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error reading collection stats: %w", err)
+	}
+	return &stats, nil
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"markly/internal/database"
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+// CategoryIconRepository stores the uploaded icons of categories, one per category.
+type CategoryIconRepository interface {
+	Upsert(ctx context.Context, icon *models.CategoryIcon) error
+	FindByCategory(ctx context.Context, userID, categoryID primitive.ObjectID) (*models.CategoryIcon, error)
+	Delete(ctx context.Context, userID, categoryID primitive.ObjectID) error
+}
+
+type categoryIconRepository struct {
+	db database.Service
+}
+
+func NewCategoryIconRepository(db database.Service) CategoryIconRepository {
+	return &categoryIconRepository{db: db}
+}
+
+func (r *categoryIconRepository) Upsert(ctx context.Context, icon *models.CategoryIcon) error {
+	queryType := "upsert"
+	repository := "categoryIcon"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("category_icons")
+	filter := bson.M{"_id": icon.CategoryID, "user_id": icon.UserID}
+	if _, err := collection.ReplaceOne(ctx, filter, icon, options.Replace().SetUpsert(true)); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to store category icon: %w", err)
+	}
+	return nil
+}
+
+func (r *categoryIconRepository) FindByCategory(ctx context.Context, userID, categoryID primitive.ObjectID) (*models.CategoryIcon, error) {
+	queryType := "findByCategory"
+	repository := "categoryIcon"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("category_icons")
+	var icon models.CategoryIcon
+	if err := collection.FindOne(ctx, bson.M{"_id": categoryID, "user_id": userID}).Decode(&icon); err != nil {
+		if err != mongo.ErrNoDocuments {
+			status = "error"
+			utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		}
+		return nil, err
+	}
+	return &icon, nil
+}
+
+func (r *categoryIconRepository) Delete(ctx context.Context, userID, categoryID primitive.ObjectID) error {
+	queryType := "delete"
+	repository := "categoryIcon"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("category_icons")
+	if _, err := collection.DeleteOne(ctx, bson.M{"_id": categoryID, "user_id": userID}); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to delete category icon: %w", err)
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"markly/internal/database"
 	"markly/internal/models"
@@ -18,6 +19,12 @@ type CategoryRepository interface {
 	Create(ctx context.Context, category *models.Category) (*models.Category, error)
 	FindByID(ctx context.Context, userID, categoryID primitive.ObjectID) (*models.Category, error)
 	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Category, error)
+	FindPageByUser(ctx context.Context, userID primitive.ObjectID, page models.PageRequest) ([]models.Category, string, error)
+	FindBySlug(ctx context.Context, userID primitive.ObjectID, slug string) (*models.Category, error)
+	FindByIDs(ctx context.Context, userID primitive.ObjectID, ids []primitive.ObjectID) ([]models.Category, error)
+	FindWithEmoji(ctx context.Context) ([]models.Category, error)
+	FindWithoutSlug(ctx context.Context) ([]models.Category, error)
+	SlugInUse(ctx context.Context, userID primitive.ObjectID, slug string, excludeID primitive.ObjectID) (bool, error)
 	Update(ctx context.Context, userID, categoryID primitive.ObjectID, updateFields bson.M) (*mongo.UpdateResult, error)
 	Delete(ctx context.Context, userID, categoryID primitive.ObjectID) (*mongo.DeleteResult, error)
 }
@@ -71,6 +78,13 @@ func (r *categoryRepository) FindByID(ctx context.Context, userID, categoryID pr
 }
 
 func (r *categoryRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Category, error) {
+	categories, _, err := r.FindPageByUser(ctx, userID, models.PageRequest{})
+	return categories, err
+}
+
+// FindPageByUser returns one page of the user's categories, oldest first, and the
+// cursor of the next page. An unpaged request returns every category.
+func (r *categoryRepository) FindPageByUser(ctx context.Context, userID primitive.ObjectID, page models.PageRequest) ([]models.Category, string, error) {
 	queryType := "findByUser"
 	repository := "category"
 	status := "success"
@@ -79,17 +93,126 @@ func (r *categoryRepository) FindByUser(ctx context.Context, userID primitive.Ob
 	}))
 	defer timer.ObserveDuration()
 
+	fo, err := newFindOptions(nil).page(page)
+	if err != nil {
+		return nil, "", err
+	}
 	var categories []models.Category
 	collection := r.db.Client().Database("markly").Collection("categories")
-	filter := bson.M{"user_id": userID}
-	cursor, err := collection.Find(ctx, filter)
+	filter, opts := fo.build(bson.M{"user_id": userID})
+	cursor, err := collection.Find(ctx, filter, opts)
 	if err != nil {
 		status = "error"
 		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
-		return nil, fmt.Errorf("error fetching categories: %w", err)
+		return nil, "", fmt.Errorf("error fetching categories: %w", err)
 	}
 	defer cursor.Close(ctx)
 
+	if err := cursor.All(ctx, &categories); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, "", fmt.Errorf("error decoding categories: %w", err)
+	}
+	return nextPage(fo, categories)
+}
+
+func (r *categoryRepository) FindByIDs(ctx context.Context, userID primitive.ObjectID, ids []primitive.ObjectID) ([]models.Category, error) {
+	queryType := "findByIDs"
+	repository := "category"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	var categories []models.Category
+	collection := r.db.Client().Database("markly").Collection("categories")
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}, "user_id": userID})
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error finding categories: %w", err)
+	}
+	if err := cursor.All(ctx, &categories); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding categories: %w", err)
+	}
+	return categories, nil
+}
+
+// FindWithEmoji returns the ID, owner, name and emoji of every category of every
+// user that has an emoji.
+func (r *categoryRepository) FindWithEmoji(ctx context.Context) ([]models.Category, error) {
+	queryType := "findWithEmoji"
+	repository := "category"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	var categories []models.Category
+	collection := r.db.Client().Database("markly").Collection("categories")
+	opts := options.Find().SetProjection(bson.M{"user_id": 1, "name": 1, "emoji": 1})
+	cursor, err := collection.Find(ctx, bson.M{"emoji": bson.M{"$exists": true, "$ne": ""}}, opts)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error finding categories: %w", err)
+	}
+	if err := cursor.All(ctx, &categories); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding categories: %w", err)
+	}
+	return categories, nil
+}
+
+// FindBySlug finds a category by its current slug or by a slug it had before being
+// renamed. Callers compare the returned Slug to detect the latter.
+func (r *categoryRepository) FindBySlug(ctx context.Context, userID primitive.ObjectID, slug string) (*models.Category, error) {
+	queryType := "findBySlug"
+	repository := "category"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	var result models.Category
+	filter := bson.M{"user_id": userID, "$or": bson.A{bson.M{"slug": slug}, bson.M{"previous_slugs": slug}}}
+	collection := r.db.Client().Database("markly").Collection("categories")
+	if err := collection.FindOne(ctx, filter).Decode(&result); err != nil {
+		if err != mongo.ErrNoDocuments {
+			status = "error"
+			utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		}
+		return nil, err
+	}
+	return &result, nil
+}
+
+// FindWithoutSlug returns the ID, owner and name of every category of every user
+// that was created before slugs existed.
+func (r *categoryRepository) FindWithoutSlug(ctx context.Context) ([]models.Category, error) {
+	queryType := "findWithoutSlug"
+	repository := "category"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	var categories []models.Category
+	collection := r.db.Client().Database("markly").Collection("categories")
+	opts := options.Find().SetProjection(bson.M{"user_id": 1, "name": 1})
+	cursor, err := collection.Find(ctx, bson.M{"$or": bson.A{bson.M{"slug": bson.M{"$exists": false}}, bson.M{"slug": ""}}}, opts)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error finding categories without slug: %w", err)
+	}
 	if err := cursor.All(ctx, &categories); err != nil {
 		status = "error"
 		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
@@ -98,6 +221,32 @@ func (r *categoryRepository) FindByUser(ctx context.Context, userID primitive.Ob
 	return categories, nil
 }
 
+// SlugInUse reports whether a category of the user other than excludeID has, or had,
+// the slug.
+func (r *categoryRepository) SlugInUse(ctx context.Context, userID primitive.ObjectID, slug string, excludeID primitive.ObjectID) (bool, error) {
+	queryType := "slugInUse"
+	repository := "category"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	filter := bson.M{
+		"user_id": userID,
+		"_id":     bson.M{"$ne": excludeID},
+		"$or":     bson.A{bson.M{"slug": slug}, bson.M{"previous_slugs": slug}},
+	}
+	collection := r.db.Client().Database("markly").Collection("categories")
+	count, err := collection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return false, fmt.Errorf("failed to check category slug: %w", err)
+	}
+	return count > 0, nil
+}
+
 func (r *categoryRepository) Update(ctx context.Context, userID, categoryID primitive.ObjectID, updateFields bson.M) (*mongo.UpdateResult, error) {
 	queryType := "update"
 	repository := "category"
@@ -0,0 +1,147 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"markly/internal/database"
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+// CollectionFeedRepository stores the feeds followed into collections.
+type CollectionFeedRepository interface {
+	Create(ctx context.Context, feed *models.CollectionFeed) error
+	// Find returns the feeds matching filter in sort order, at most limit of them
+	// unless limit is 0.
+	Find(ctx context.Context, filter bson.M, sort bson.D, limit int64) ([]models.CollectionFeed, error)
+	// Update sets updateFields on the feed matching filter and returns the updated
+	// document, or mongo.ErrNoDocuments when nothing matched.
+	Update(ctx context.Context, filter bson.M, updateFields bson.M) (*models.CollectionFeed, error)
+	Delete(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error)
+	Count(ctx context.Context, filter bson.M) (int64, error)
+}
+
+type collectionFeedRepository struct {
+	db database.Service
+}
+
+func NewCollectionFeedRepository(db database.Service) CollectionFeedRepository {
+	return &collectionFeedRepository{db: db}
+}
+
+func (r *collectionFeedRepository) Create(ctx context.Context, feed *models.CollectionFeed) error {
+	queryType := "create"
+	repository := "collection_feed"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("collection_feeds")
+	if _, err := collection.InsertOne(ctx, feed); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to create collection feed: %w", err)
+	}
+	return nil
+}
+
+func (r *collectionFeedRepository) Find(ctx context.Context, filter bson.M, sort bson.D, limit int64) ([]models.CollectionFeed, error) {
+	queryType := "find"
+	repository := "collection_feed"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("collection_feeds")
+	filter, opts := newFindOptions(sort).offset(limit, 1).build(filter)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to find collection feeds: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	feeds := []models.CollectionFeed{}
+	if err := cursor.All(ctx, &feeds); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding collection feeds: %w", err)
+	}
+	return feeds, nil
+}
+
+func (r *collectionFeedRepository) Update(ctx context.Context, filter bson.M, updateFields bson.M) (*models.CollectionFeed, error) {
+	queryType := "update"
+	repository := "collection_feed"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	var feed models.CollectionFeed
+	collection := r.db.Client().Database("markly").Collection("collection_feeds")
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := collection.FindOneAndUpdate(ctx, filter, bson.M{"$set": updateFields}, opts).Decode(&feed)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, err
+		}
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to update collection feed: %w", err)
+	}
+	return &feed, nil
+}
+
+func (r *collectionFeedRepository) Delete(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error) {
+	queryType := "delete"
+	repository := "collection_feed"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("collection_feeds")
+	result, err := collection.DeleteOne(ctx, filter)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to delete collection feed: %w", err)
+	}
+	return result, nil
+}
+
+func (r *collectionFeedRepository) Count(ctx context.Context, filter bson.M) (int64, error) {
+	queryType := "count"
+	repository := "collection_feed"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("collection_feeds")
+	count, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return 0, fmt.Errorf("failed to count collection feeds: %w", err)
+	}
+	return count, nil
+}
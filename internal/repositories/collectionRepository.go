@@ -8,6 +8,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"markly/internal/database"
 	"markly/internal/models"
@@ -18,8 +19,14 @@ type CollectionRepository interface {
 	Create(ctx context.Context, col *models.Collection) (*models.Collection, error)
 	FindByID(ctx context.Context, userID, collectionID primitive.ObjectID) (*models.Collection, error)
 	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Collection, error)
+	FindPageByUser(ctx context.Context, userID primitive.ObjectID, page models.PageRequest) ([]models.Collection, string, error)
+	FindWithAutoArchive(ctx context.Context) ([]models.Collection, error)
+	FindBySlug(ctx context.Context, userID primitive.ObjectID, slug string) (*models.Collection, error)
+	FindWithoutSlug(ctx context.Context) ([]models.Collection, error)
+	SlugInUse(ctx context.Context, userID primitive.ObjectID, slug string, excludeID primitive.ObjectID) (bool, error)
 	Update(ctx context.Context, userID, collectionID primitive.ObjectID, updateFields bson.M) (*mongo.UpdateResult, error)
 	Delete(ctx context.Context, userID, collectionID primitive.ObjectID) (*mongo.DeleteResult, error)
+	CountByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
 }
 
 type collectionRepository struct {
@@ -71,6 +78,13 @@ func (r *collectionRepository) FindByID(ctx context.Context, userID, collectionI
 }
 
 func (r *collectionRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Collection, error) {
+	results, _, err := r.FindPageByUser(ctx, userID, models.PageRequest{})
+	return results, err
+}
+
+// FindPageByUser returns one page of the user's collections, oldest first, and the
+// cursor of the next page. An unpaged request returns every collection.
+func (r *collectionRepository) FindPageByUser(ctx context.Context, userID primitive.ObjectID, page models.PageRequest) ([]models.Collection, string, error) {
 	queryType := "findByUser"
 	repository := "collection"
 	status := "success"
@@ -79,13 +93,46 @@ func (r *collectionRepository) FindByUser(ctx context.Context, userID primitive.
 	}))
 	defer timer.ObserveDuration()
 
+	fo, err := newFindOptions(nil).page(page)
+	if err != nil {
+		return nil, "", err
+	}
+	var results []models.Collection
+	collection := r.db.Client().Database("markly").Collection("collections")
+	filter, opts := fo.build(bson.M{"user_id": userID})
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, "", fmt.Errorf("database error fetching collections: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &results); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, "", fmt.Errorf("error decoding collection results: %w", err)
+	}
+	return nextPage(fo, results)
+}
+
+// FindWithAutoArchive returns the collections of all users that have an auto-archive policy.
+func (r *collectionRepository) FindWithAutoArchive(ctx context.Context) ([]models.Collection, error) {
+	queryType := "findWithAutoArchive"
+	repository := "collection"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
 	var results []models.Collection
 	collection := r.db.Client().Database("markly").Collection("collections")
-	cursor, err := collection.Find(ctx, bson.M{"user_id": userID})
+	cursor, err := collection.Find(ctx, bson.M{"settings.auto_archive.after_days": bson.M{"$gt": 0}})
 	if err != nil {
 		status = "error"
 		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
-		return nil, fmt.Errorf("database error fetching collections: %w", err)
+		return nil, fmt.Errorf("database error fetching collections with auto-archive: %w", err)
 	}
 	defer cursor.Close(ctx)
 
@@ -97,6 +144,84 @@ func (r *collectionRepository) FindByUser(ctx context.Context, userID primitive.
 	return results, nil
 }
 
+// FindBySlug finds a collection by its current slug or by a slug it had before being
+// renamed. Callers compare the returned Slug to detect the latter.
+func (r *collectionRepository) FindBySlug(ctx context.Context, userID primitive.ObjectID, slug string) (*models.Collection, error) {
+	queryType := "findBySlug"
+	repository := "collection"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	var result models.Collection
+	filter := bson.M{"user_id": userID, "$or": bson.A{bson.M{"slug": slug}, bson.M{"previous_slugs": slug}}}
+	collection := r.db.Client().Database("markly").Collection("collections")
+	if err := collection.FindOne(ctx, filter).Decode(&result); err != nil {
+		if err != mongo.ErrNoDocuments {
+			status = "error"
+			utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		}
+		return nil, err
+	}
+	return &result, nil
+}
+
+// FindWithoutSlug returns the ID, owner and name of every collection of every user
+// that was created before slugs existed.
+func (r *collectionRepository) FindWithoutSlug(ctx context.Context) ([]models.Collection, error) {
+	queryType := "findWithoutSlug"
+	repository := "collection"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	var collections []models.Collection
+	collection := r.db.Client().Database("markly").Collection("collections")
+	opts := options.Find().SetProjection(bson.M{"user_id": 1, "name": 1})
+	cursor, err := collection.Find(ctx, bson.M{"$or": bson.A{bson.M{"slug": bson.M{"$exists": false}}, bson.M{"slug": ""}}}, opts)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error finding collections without slug: %w", err)
+	}
+	if err := cursor.All(ctx, &collections); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding collections: %w", err)
+	}
+	return collections, nil
+}
+
+// SlugInUse reports whether a collection of the user other than excludeID has, or had,
+// the slug.
+func (r *collectionRepository) SlugInUse(ctx context.Context, userID primitive.ObjectID, slug string, excludeID primitive.ObjectID) (bool, error) {
+	queryType := "slugInUse"
+	repository := "collection"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	filter := bson.M{
+		"user_id": userID,
+		"_id":     bson.M{"$ne": excludeID},
+		"$or":     bson.A{bson.M{"slug": slug}, bson.M{"previous_slugs": slug}},
+	}
+	collection := r.db.Client().Database("markly").Collection("collections")
+	count, err := collection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return false, fmt.Errorf("failed to check collection slug: %w", err)
+	}
+	return count > 0, nil
+}
+
 func (r *collectionRepository) Update(ctx context.Context, userID, collectionID primitive.ObjectID, updateFields bson.M) (*mongo.UpdateResult, error) {
 	queryType := "update"
 	repository := "collection"
@@ -136,4 +261,23 @@ func (r *collectionRepository) Delete(ctx context.Context, userID, collectionID
 		return nil, fmt.Errorf("database error deleting collection: %w", err)
 	}
 	return result, nil
-}
\ No newline at end of file
+}
+
+func (r *collectionRepository) CountByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	queryType := "countByUser"
+	repository := "collection"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("collections")
+	count, err := collection.CountDocuments(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return 0, fmt.Errorf("database error counting collections: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"markly/internal/database"
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+type CollectionTemplateRepository interface {
+	Create(ctx context.Context, template *models.CollectionTemplate) (*models.CollectionTemplate, error)
+	FindByID(ctx context.Context, userID, templateID primitive.ObjectID) (*models.CollectionTemplate, error)
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.CollectionTemplate, error)
+	Delete(ctx context.Context, userID, templateID primitive.ObjectID) (*mongo.DeleteResult, error)
+}
+
+type collectionTemplateRepository struct {
+	db database.Service
+}
+
+func NewCollectionTemplateRepository(db database.Service) CollectionTemplateRepository {
+	return &collectionTemplateRepository{db: db}
+}
+
+func (r *collectionTemplateRepository) Create(ctx context.Context, template *models.CollectionTemplate) (*models.CollectionTemplate, error) {
+	queryType := "create"
+	repository := "collectionTemplate"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("collection_templates")
+	if _, err := collection.InsertOne(ctx, template); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to create collection template: %w", err)
+	}
+	return template, nil
+}
+
+func (r *collectionTemplateRepository) FindByID(ctx context.Context, userID, templateID primitive.ObjectID) (*models.CollectionTemplate, error) {
+	queryType := "findByID"
+	repository := "collectionTemplate"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("collection_templates")
+	var template models.CollectionTemplate
+	err := collection.FindOne(ctx, bson.M{"_id": templateID, "user_id": userID}).Decode(&template)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			status = "error"
+			utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *collectionTemplateRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.CollectionTemplate, error) {
+	queryType := "findByUser"
+	repository := "collectionTemplate"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("collection_templates")
+	opts := options.Find().SetSort(bson.M{"name": 1})
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to find collection templates: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var templates []models.CollectionTemplate
+	if err := cursor.All(ctx, &templates); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to decode collection templates: %w", err)
+	}
+	return templates, nil
+}
+
+func (r *collectionTemplateRepository) Delete(ctx context.Context, userID, templateID primitive.ObjectID) (*mongo.DeleteResult, error) {
+	queryType := "delete"
+	repository := "collectionTemplate"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("collection_templates")
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": templateID, "user_id": userID})
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to delete collection template: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/database"
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+type DataKeyRepository interface {
+	Create(ctx context.Context, key *models.DataKey) (*models.DataKey, error)
+	FindByUser(ctx context.Context, userID primitive.ObjectID) (*models.DataKey, error)
+}
+
+type dataKeyRepository struct {
+	db database.Service
+}
+
+func NewDataKeyRepository(db database.Service) DataKeyRepository {
+	return &dataKeyRepository{db: db}
+}
+
+func (r *dataKeyRepository) Create(ctx context.Context, key *models.DataKey) (*models.DataKey, error) {
+	queryType := "create"
+	repository := "dataKey"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("data_keys")
+	_, err := collection.InsertOne(ctx, key)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to insert data key: %w", err)
+	}
+	return key, nil
+}
+
+func (r *dataKeyRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) (*models.DataKey, error) {
+	queryType := "findByUser"
+	repository := "dataKey"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	var key models.DataKey
+	collection := r.db.Client().Database("markly").Collection("data_keys")
+	err := collection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&key)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, err
+	}
+	return &key, nil
+}
@@ -0,0 +1,197 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/database"
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+// erasureKeptCollections are not purged by PurgeUser: erasure reports must outlive
+// the data, audit events are anonymized instead, and trending items belong to no
+// user.
+var erasureKeptCollections = map[string]bool{
+	"erasures":       true,
+	"audit_events":   true,
+	"trending_items": true,
+}
+
+type ErasureRepository interface {
+	Create(ctx context.Context, report *models.ErasureReport) error
+	Update(ctx context.Context, id primitive.ObjectID, updateFields bson.M) error
+	FindOne(ctx context.Context, filter bson.M) (*models.ErasureReport, error)
+	Find(ctx context.Context, filter bson.M, limit int64) ([]models.ErasureReport, error)
+	PurgeUser(ctx context.Context, userID primitive.ObjectID) (map[string]int64, error)
+	// PurgeSubscriptions deletes the newsletter subscriptions of email, whoever
+	// owns the newsletter, and returns how many were deleted.
+	PurgeSubscriptions(ctx context.Context, email string) (int64, error)
+}
+
+type erasureRepository struct {
+	db database.Service
+}
+
+func NewErasureRepository(db database.Service) ErasureRepository {
+	return &erasureRepository{db: db}
+}
+
+func (r *erasureRepository) Create(ctx context.Context, report *models.ErasureReport) error {
+	queryType := "create"
+	repository := "erasure"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("erasures")
+	if _, err := collection.InsertOne(ctx, report); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to insert erasure report: %w", err)
+	}
+	return nil
+}
+
+func (r *erasureRepository) Update(ctx context.Context, id primitive.ObjectID, updateFields bson.M) error {
+	queryType := "update"
+	repository := "erasure"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("erasures")
+	if _, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": updateFields}); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to update erasure report: %w", err)
+	}
+	return nil
+}
+
+func (r *erasureRepository) FindOne(ctx context.Context, filter bson.M) (*models.ErasureReport, error) {
+	queryType := "findOne"
+	repository := "erasure"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	var report models.ErasureReport
+	collection := r.db.Client().Database("markly").Collection("erasures")
+	if err := collection.FindOne(ctx, filter).Decode(&report); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Find returns the reports matching filter, newest first.
+func (r *erasureRepository) Find(ctx context.Context, filter bson.M, limit int64) ([]models.ErasureReport, error) {
+	queryType := "find"
+	repository := "erasure"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("erasures")
+	filter, opts := newFindOptions(bson.D{{Key: "started_at", Value: -1}}).offset(limit, 1).build(filter)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to find erasure reports: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	reports := []models.ErasureReport{}
+	if err := cursor.All(ctx, &reports); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding erasure reports: %w", err)
+	}
+	return reports, nil
+}
+
+// PurgeUser deletes the documents owned by userID from every collection of the
+// database, including collections added after this was written, and returns how
+// many were deleted from each. Collections without any are left out.
+func (r *erasureRepository) PurgeUser(ctx context.Context, userID primitive.ObjectID) (map[string]int64, error) {
+	queryType := "purgeUser"
+	repository := "erasure"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	db := r.db.Client().Database("markly")
+	names, err := db.ListCollectionNames(ctx, bson.M{})
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+	sort.Strings(names)
+	// The user document goes last, so a failed purge can still be retried while
+	// the account exists.
+	ordered := make([]string, 0, len(names)+1)
+	for _, name := range names {
+		if name != "users" && !erasureKeptCollections[name] {
+			ordered = append(ordered, name)
+		}
+	}
+	ordered = append(ordered, "users")
+
+	deleted := make(map[string]int64)
+	for _, name := range ordered {
+		filter := bson.M{"user_id": userID}
+		if name == "users" {
+			filter = bson.M{"_id": userID}
+		}
+		result, err := db.Collection(name).DeleteMany(ctx, filter)
+		if err != nil {
+			status = "error"
+			utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+			return deleted, fmt.Errorf("failed to purge collection %s: %w", name, err)
+		}
+		if result.DeletedCount > 0 {
+			deleted[name] += result.DeletedCount
+		}
+	}
+	return deleted, nil
+}
+
+func (r *erasureRepository) PurgeSubscriptions(ctx context.Context, email string) (int64, error) {
+	queryType := "purgeSubscriptions"
+	repository := "erasure"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	// Subscriber addresses are stored lowercased.
+	collection := r.db.Client().Database("markly").Collection("newsletter_subscribers")
+	result, err := collection.DeleteMany(ctx, bson.M{"email": strings.ToLower(strings.TrimSpace(email))})
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return 0, fmt.Errorf("failed to purge newsletter subscriptions: %w", err)
+	}
+	return result.DeletedCount, nil
+}
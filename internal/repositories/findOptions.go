@@ -0,0 +1,190 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+// findOptions builds the options of list queries: the sort, an optional projection
+// and either offset or cursor pagination. Cursors hold the sort values of the last
+// document of a page, so the next page starts right after it even when documents
+// are added in between.
+type findOptions struct {
+	sort       bson.D
+	projection bson.M
+	limit      int64
+	skip       int64
+	// after holds the sort values decoded from a cursor.
+	after bson.A
+	// paged is set for cursor pagination, which fetches one extra document to
+	// tell whether there is a next page.
+	paged bool
+}
+
+// newFindOptions sorts by sort, then by _id so that the order, and with it every
+// cursor, is unambiguous.
+func newFindOptions(sort bson.D) *findOptions {
+	for _, e := range sort {
+		if e.Key == "_id" {
+			return &findOptions{sort: sort}
+		}
+	}
+	keys := append(bson.D{}, sort...)
+	return &findOptions{sort: append(keys, bson.E{Key: "_id", Value: 1})}
+}
+
+func (o *findOptions) project(projection bson.M) *findOptions {
+	o.projection = projection
+	return o
+}
+
+// offset selects a page by number, starting at 1.
+func (o *findOptions) offset(limit, page int64) *findOptions {
+	o.limit = limit
+	if page > 1 {
+		o.skip = (page - 1) * limit
+	}
+	return o
+}
+
+// page selects the page after page.Cursor. It does nothing for an unpaged request.
+func (o *findOptions) page(page models.PageRequest) (*findOptions, error) {
+	if !page.Paged() {
+		return o, nil
+	}
+	o.paged = true
+	limits := utils.CurrentLimits()
+	o.limit = page.Limit
+	if o.limit <= 0 {
+		o.limit = limits.DefaultPageSize
+	}
+	if o.limit > limits.MaxPageSize {
+		return nil, fmt.Errorf("%w: limit must be at most %d", utils.ErrLimitExceeded, limits.MaxPageSize)
+	}
+	if page.Cursor == "" {
+		return o, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(page.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var decoded struct {
+		V bson.A `bson:"v"`
+	}
+	if err := bson.Unmarshal(raw, &decoded); err != nil || len(decoded.V) != len(o.sort) {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	// Cursors come from clients; documents in them would be read as query operators.
+	for _, v := range decoded.V {
+		switch v.(type) {
+		case nil, bool, string, int32, int64, float64, primitive.DateTime, primitive.ObjectID:
+		default:
+			return nil, fmt.Errorf("invalid cursor")
+		}
+	}
+	o.after = decoded.V
+	return o, nil
+}
+
+// build returns filter narrowed to the documents after the cursor, and the options
+// to find them with.
+func (o *findOptions) build(filter bson.M) (bson.M, *options.FindOptions) {
+	opts := options.Find().SetSort(o.sort)
+	if o.projection != nil {
+		opts.SetProjection(o.projection)
+	}
+	if o.limit > 0 {
+		limit := o.limit
+		if o.paged {
+			limit++
+		}
+		opts.SetLimit(limit)
+	}
+	if o.skip > 0 {
+		opts.SetSkip(o.skip)
+	}
+	if o.after != nil {
+		filter = bson.M{"$and": []bson.M{filter, o.afterFilter()}}
+	}
+	return filter, opts
+}
+
+// afterFilter matches the documents sorting after the cursor values. Missing and
+// null values sort first, and range operators never match them, so they are
+// handled explicitly.
+func (o *findOptions) afterFilter() bson.M {
+	var branches []bson.M
+	for i, e := range o.sort {
+		branch := bson.M{}
+		for j := 0; j < i; j++ {
+			branch[o.sort[j].Key] = o.after[j]
+		}
+		v := o.after[i]
+		if descending(e.Value) {
+			if v == nil {
+				continue
+			}
+			branch["$or"] = []bson.M{{e.Key: bson.M{"$lt": v}}, {e.Key: nil}}
+		} else if v == nil {
+			branch[e.Key] = bson.M{"$ne": nil}
+		} else {
+			branch[e.Key] = bson.M{"$gt": v}
+		}
+		branches = append(branches, branch)
+	}
+	if len(branches) == 0 {
+		// Nothing sorts after a cursor of only missing values in descending order.
+		return bson.M{"_id": bson.M{"$in": bson.A{}}}
+	}
+	return bson.M{"$or": branches}
+}
+
+func descending(v interface{}) bool {
+	switch n := v.(type) {
+	case int:
+		return n < 0
+	case int32:
+		return n < 0
+	case int64:
+		return n < 0
+	}
+	return false
+}
+
+// nextPage trims the extra document fetched for cursor pagination and returns the
+// cursor of the next page, or an empty string on the last one.
+func nextPage[T any](o *findOptions, items []T) ([]T, string, error) {
+	if !o.paged || int64(len(items)) <= o.limit {
+		return items, "", nil
+	}
+	items = items[:o.limit]
+	raw, err := bson.Marshal(items[len(items)-1])
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to build cursor: %w", err)
+	}
+	values := make(bson.A, len(o.sort))
+	for i, e := range o.sort {
+		rv, err := bson.Raw(raw).LookupErr(strings.Split(e.Key, ".")...)
+		if err != nil {
+			continue
+		}
+		var v interface{}
+		if err := rv.Unmarshal(&v); err != nil {
+			return nil, "", fmt.Errorf("failed to build cursor: %w", err)
+		}
+		values[i] = v
+	}
+	cursor, err := bson.Marshal(bson.M{"v": values})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to build cursor: %w", err)
+	}
+	return items, base64.RawURLEncoding.EncodeToString(cursor), nil
+}
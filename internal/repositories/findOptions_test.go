@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/models"
+)
+
+type pagedDoc struct {
+	ID     primitive.ObjectID `bson:"_id"`
+	Pinned bool               `bson:"pinned,omitempty"`
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	sort := bson.D{{Key: "pinned", Value: -1}}
+	docs := []pagedDoc{{ID: primitive.NewObjectID(), Pinned: true}, {ID: primitive.NewObjectID()}, {ID: primitive.NewObjectID()}}
+
+	o, err := newFindOptions(sort).page(models.PageRequest{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, cursor, err := nextPage(o, docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || cursor == "" {
+		t.Fatalf("got %d items and cursor %q, want 2 items and a cursor", len(items), cursor)
+	}
+
+	next, err := newFindOptions(sort).page(models.PageRequest{Limit: 2, Cursor: cursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The last document of the page is unpinned, so pinned is missing from it.
+	want := bson.A{nil, docs[1].ID}
+	if !reflect.DeepEqual(next.after, want) {
+		t.Errorf("after = %v, want %v", next.after, want)
+	}
+
+	_, last, err := nextPage(next, docs[2:])
+	if err != nil || last != "" {
+		t.Errorf("last page cursor = %q, %v; want none", last, err)
+	}
+}
+
+func TestCursorRejectsOperators(t *testing.T) {
+	raw, _ := bson.Marshal(bson.M{"v": bson.A{bson.M{"$gt": ""}}})
+	cursor := base64.RawURLEncoding.EncodeToString(raw)
+	if _, err := newFindOptions(nil).page(models.PageRequest{Cursor: cursor}); err == nil {
+		t.Error("cursor holding a document was accepted")
+	}
+	if _, err := newFindOptions(nil).page(models.PageRequest{Cursor: "not a cursor"}); err == nil {
+		t.Error("malformed cursor was accepted")
+	}
+}
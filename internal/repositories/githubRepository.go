@@ -0,0 +1,238 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"markly/internal/database"
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+// GitHubRepository stores GitHub connections, one per user.
+type GitHubRepository interface {
+	// SaveState starts an authorization of userID, replacing any pending one.
+	SaveState(ctx context.Context, userID primitive.ObjectID, stateHash string, expiresAt time.Time) error
+	// FindByState returns the connection with the pending authorization stateHash,
+	// or mongo.ErrNoDocuments if it is unknown or expired.
+	FindByState(ctx context.Context, stateHash string, now time.Time) (*models.GitHubConnection, error)
+	// Connect stores the account, token and granted scopes of a completed
+	// authorization.
+	Connect(ctx context.Context, userID primitive.ObjectID, login, encryptedToken string, scopes []string, now time.Time) error
+	FindByUser(ctx context.Context, userID primitive.ObjectID) (*models.GitHubConnection, error)
+	FindConnected(ctx context.Context) ([]models.GitHubConnection, error)
+	// ClaimSync takes the sync lease of the user's connection until the given time
+	// and reports whether it did. It fails while another sync holds the lease.
+	ClaimSync(ctx context.Context, userID primitive.ObjectID, now, until time.Time) (bool, error)
+	// RecordSync stores the outcome of a sync and releases its lease. A nil result
+	// keeps the previous one.
+	RecordSync(ctx context.Context, userID primitive.ObjectID, now time.Time, result *models.GitHubSyncResult, syncErr string) error
+	Delete(ctx context.Context, userID primitive.ObjectID) (*mongo.DeleteResult, error)
+}
+
+type githubRepository struct {
+	db database.Service
+}
+
+func NewGitHubRepository(db database.Service) GitHubRepository {
+	return &githubRepository{db: db}
+}
+
+func (r *githubRepository) SaveState(ctx context.Context, userID primitive.ObjectID, stateHash string, expiresAt time.Time) error {
+	queryType := "saveState"
+	repository := "github"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("github_connections")
+	update := bson.M{"$set": bson.M{"state_hash": stateHash, "state_expires_at": expiresAt}}
+	if _, err := collection.UpdateOne(ctx, bson.M{"user_id": userID}, update, options.Update().SetUpsert(true)); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to save github authorization: %w", err)
+	}
+	return nil
+}
+
+func (r *githubRepository) FindByState(ctx context.Context, stateHash string, now time.Time) (*models.GitHubConnection, error) {
+	queryType := "findByState"
+	repository := "github"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("github_connections")
+	var conn models.GitHubConnection
+	err := collection.FindOne(ctx, bson.M{"state_hash": stateHash, "state_expires_at": bson.M{"$gt": now}}).Decode(&conn)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			status = "error"
+			utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		}
+		return nil, err
+	}
+	return &conn, nil
+}
+
+func (r *githubRepository) Connect(ctx context.Context, userID primitive.ObjectID, login, encryptedToken string, scopes []string, now time.Time) error {
+	queryType := "connect"
+	repository := "github"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("github_connections")
+	update := bson.M{
+		"$set":   bson.M{"login": login, "token_enc": encryptedToken, "scopes": scopes, "connected_at": now},
+		"$unset": bson.M{"state_hash": "", "state_expires_at": "", "last_sync_error": ""},
+	}
+	if _, err := collection.UpdateOne(ctx, bson.M{"user_id": userID}, update); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to save github connection: %w", err)
+	}
+	return nil
+}
+
+func (r *githubRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) (*models.GitHubConnection, error) {
+	queryType := "findByUser"
+	repository := "github"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("github_connections")
+	var conn models.GitHubConnection
+	if err := collection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&conn); err != nil {
+		if err != mongo.ErrNoDocuments {
+			status = "error"
+			utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		}
+		return nil, err
+	}
+	conn.Connected = conn.EncryptedToken != ""
+	return &conn, nil
+}
+
+func (r *githubRepository) FindConnected(ctx context.Context) ([]models.GitHubConnection, error) {
+	queryType := "findConnected"
+	repository := "github"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("github_connections")
+	cursor, err := collection.Find(ctx, bson.M{"token_enc": bson.M{"$exists": true}})
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to retrieve github connections: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	conns := []models.GitHubConnection{}
+	if err := cursor.All(ctx, &conns); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding github connections: %w", err)
+	}
+	for i := range conns {
+		conns[i].Connected = true
+	}
+	return conns, nil
+}
+
+func (r *githubRepository) ClaimSync(ctx context.Context, userID primitive.ObjectID, now, until time.Time) (bool, error) {
+	queryType := "claimSync"
+	repository := "github"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("github_connections")
+	filter := bson.M{
+		"user_id":   userID,
+		"token_enc": bson.M{"$exists": true},
+		"$or": bson.A{
+			bson.M{"sync_lease_until": bson.M{"$exists": false}},
+			bson.M{"sync_lease_until": bson.M{"$lte": now}},
+		},
+	}
+	result, err := collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"sync_lease_until": until}})
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return false, fmt.Errorf("failed to claim github sync: %w", err)
+	}
+	return result.MatchedCount == 1, nil
+}
+
+func (r *githubRepository) RecordSync(ctx context.Context, userID primitive.ObjectID, now time.Time, result *models.GitHubSyncResult, syncErr string) error {
+	queryType := "recordSync"
+	repository := "github"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("github_connections")
+	set := bson.M{"last_synced_at": now}
+	unset := bson.M{"sync_lease_until": ""}
+	update := bson.M{"$set": set, "$unset": unset}
+	if result != nil {
+		set["last_sync"] = result
+	}
+	if syncErr != "" {
+		set["last_sync_error"] = syncErr
+	} else {
+		unset["last_sync_error"] = ""
+	}
+	// A user who disconnected during the sync stays disconnected.
+	filter := bson.M{"user_id": userID, "token_enc": bson.M{"$exists": true}}
+	if _, err := collection.UpdateOne(ctx, filter, update); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to record github sync: %w", err)
+	}
+	return nil
+}
+
+func (r *githubRepository) Delete(ctx context.Context, userID primitive.ObjectID) (*mongo.DeleteResult, error) {
+	queryType := "delete"
+	repository := "github"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("github_connections")
+	result, err := collection.DeleteOne(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to delete github connection: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,177 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"markly/internal/database"
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+type HighlightRepository interface {
+	Create(ctx context.Context, highlight *models.Highlight) (*models.Highlight, error)
+	FindByID(ctx context.Context, userID, highlightID primitive.ObjectID) (*models.Highlight, error)
+	FindByBookmarks(ctx context.Context, userID primitive.ObjectID, bookmarkIDs []primitive.ObjectID) ([]models.Highlight, error)
+	Update(ctx context.Context, userID, highlightID primitive.ObjectID, updateFields bson.M) (*mongo.UpdateResult, error)
+	Delete(ctx context.Context, userID, bookmarkID, highlightID primitive.ObjectID) (*mongo.DeleteResult, error)
+	DeleteByBookmark(ctx context.Context, userID, bookmarkID primitive.ObjectID) error
+	MoveToBookmark(ctx context.Context, userID, fromBookmarkID, toBookmarkID primitive.ObjectID) error
+}
+
+type highlightRepository struct {
+	db database.Service
+}
+
+func NewHighlightRepository(db database.Service) HighlightRepository {
+	return &highlightRepository{db: db}
+}
+
+func (r *highlightRepository) Create(ctx context.Context, highlight *models.Highlight) (*models.Highlight, error) {
+	queryType := "create"
+	repository := "highlight"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("highlights")
+	if _, err := collection.InsertOne(ctx, highlight); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to create highlight: %w", err)
+	}
+	return highlight, nil
+}
+
+func (r *highlightRepository) FindByID(ctx context.Context, userID, highlightID primitive.ObjectID) (*models.Highlight, error) {
+	queryType := "findByID"
+	repository := "highlight"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("highlights")
+	var highlight models.Highlight
+	if err := collection.FindOne(ctx, bson.M{"_id": highlightID, "user_id": userID}).Decode(&highlight); err != nil {
+		if err != mongo.ErrNoDocuments {
+			status = "error"
+			utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		}
+		return nil, err
+	}
+	return &highlight, nil
+}
+
+// FindByBookmarks returns the highlights of the given bookmarks in creation order.
+func (r *highlightRepository) FindByBookmarks(ctx context.Context, userID primitive.ObjectID, bookmarkIDs []primitive.ObjectID) ([]models.Highlight, error) {
+	queryType := "findByBookmarks"
+	repository := "highlight"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("highlights")
+	filter := bson.M{"user_id": userID, "bookmark_id": bson.M{"$in": bookmarkIDs}}
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to find highlights: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var highlights []models.Highlight
+	if err := cursor.All(ctx, &highlights); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to decode highlights: %w", err)
+	}
+	return highlights, nil
+}
+
+func (r *highlightRepository) Update(ctx context.Context, userID, highlightID primitive.ObjectID, updateFields bson.M) (*mongo.UpdateResult, error) {
+	queryType := "update"
+	repository := "highlight"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("highlights")
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": highlightID, "user_id": userID}, bson.M{"$set": updateFields})
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to update highlight: %w", err)
+	}
+	return result, nil
+}
+
+func (r *highlightRepository) Delete(ctx context.Context, userID, bookmarkID, highlightID primitive.ObjectID) (*mongo.DeleteResult, error) {
+	queryType := "delete"
+	repository := "highlight"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("highlights")
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": highlightID, "bookmark_id": bookmarkID, "user_id": userID})
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to delete highlight: %w", err)
+	}
+	return result, nil
+}
+
+func (r *highlightRepository) DeleteByBookmark(ctx context.Context, userID, bookmarkID primitive.ObjectID) error {
+	queryType := "deleteByBookmark"
+	repository := "highlight"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("highlights")
+	if _, err := collection.DeleteMany(ctx, bson.M{"bookmark_id": bookmarkID, "user_id": userID}); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to delete highlights of bookmark: %w", err)
+	}
+	return nil
+}
+
+func (r *highlightRepository) MoveToBookmark(ctx context.Context, userID, fromBookmarkID, toBookmarkID primitive.ObjectID) error {
+	queryType := "moveToBookmark"
+	repository := "highlight"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("highlights")
+	filter := bson.M{"bookmark_id": fromBookmarkID, "user_id": userID}
+	if _, err := collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"bookmark_id": toBookmarkID}}); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to move highlights: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"markly/internal/database"
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+type ImpersonationRepository interface {
+	Create(ctx context.Context, imp *models.Impersonation) error
+	FindOne(ctx context.Context, filter bson.M) (*models.Impersonation, error)
+	Find(ctx context.Context, filter bson.M) ([]models.Impersonation, error)
+	Transition(ctx context.Context, filter bson.M, updateFields bson.M) (*models.Impersonation, error)
+}
+
+type impersonationRepository struct {
+	db database.Service
+}
+
+func NewImpersonationRepository(db database.Service) ImpersonationRepository {
+	return &impersonationRepository{db: db}
+}
+
+func (r *impersonationRepository) Create(ctx context.Context, imp *models.Impersonation) error {
+	queryType := "create"
+	repository := "impersonation"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("impersonations")
+	if _, err := collection.InsertOne(ctx, imp); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to insert impersonation: %w", err)
+	}
+	return nil
+}
+
+func (r *impersonationRepository) FindOne(ctx context.Context, filter bson.M) (*models.Impersonation, error) {
+	queryType := "findOne"
+	repository := "impersonation"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	var imp models.Impersonation
+	collection := r.db.Client().Database("markly").Collection("impersonations")
+	if err := collection.FindOne(ctx, filter).Decode(&imp); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, err
+	}
+	return &imp, nil
+}
+
+// Find returns the impersonations matching filter, newest first.
+func (r *impersonationRepository) Find(ctx context.Context, filter bson.M) ([]models.Impersonation, error) {
+	queryType := "find"
+	repository := "impersonation"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("impersonations")
+	filter, opts := newFindOptions(bson.D{{Key: "created_at", Value: -1}}).offset(100, 1).build(filter)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to find impersonations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var imps []models.Impersonation
+	if err := cursor.All(ctx, &imps); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding impersonations: %w", err)
+	}
+	return imps, nil
+}
+
+// Transition sets updateFields on the impersonation matching filter and returns the
+// updated document. The filter carries the expected current status, so concurrent
+// approvals and revocations cannot both succeed. It returns mongo.ErrNoDocuments
+// when nothing matched.
+func (r *impersonationRepository) Transition(ctx context.Context, filter bson.M, updateFields bson.M) (*models.Impersonation, error) {
+	queryType := "transition"
+	repository := "impersonation"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	var imp models.Impersonation
+	collection := r.db.Client().Database("markly").Collection("impersonations")
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := collection.FindOneAndUpdate(ctx, filter, bson.M{"$set": updateFields}, opts).Decode(&imp)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, err
+		}
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to update impersonation: %w", err)
+	}
+	return &imp, nil
+}
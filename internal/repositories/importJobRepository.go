@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"markly/internal/database"
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+// ImportJobRepository stores background import jobs and their progress.
+type ImportJobRepository interface {
+	// Create fails with a duplicate key error when the user has an active job.
+	Create(ctx context.Context, job *models.ImportJob) error
+	// Save replaces the stored job with job while it is queued or running, and
+	// returns mongo.ErrNoDocuments once it has been finished or failed.
+	Save(ctx context.Context, job *models.ImportJob) error
+	FindByID(ctx context.Context, userID, jobID primitive.ObjectID) (*models.ImportJob, error)
+	// FindByUser returns the user's newest jobs.
+	FindByUser(ctx context.Context, userID primitive.ObjectID, limit int64) ([]models.ImportJob, error)
+	// FailStale fails the queued and running jobs last updated before cutoff.
+	FailStale(ctx context.Context, cutoff, now time.Time, reason string) (int64, error)
+}
+
+type importJobRepository struct {
+	db database.Service
+}
+
+func NewImportJobRepository(db database.Service) ImportJobRepository {
+	return &importJobRepository{db: db}
+}
+
+var activeImportStatuses = bson.M{"$in": []string{models.ImportJobQueued, models.ImportJobRunning}}
+
+func (r *importJobRepository) Create(ctx context.Context, job *models.ImportJob) error {
+	queryType := "create"
+	repository := "import_job"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("import_jobs")
+	if _, err := collection.InsertOne(ctx, job); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to create import job: %w", err)
+	}
+	return nil
+}
+
+func (r *importJobRepository) Save(ctx context.Context, job *models.ImportJob) error {
+	queryType := "save"
+	repository := "import_job"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("import_jobs")
+	result, err := collection.ReplaceOne(ctx, bson.M{"_id": job.ID, "user_id": job.UserID, "status": activeImportStatuses}, job)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to save import job: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *importJobRepository) FindByID(ctx context.Context, userID, jobID primitive.ObjectID) (*models.ImportJob, error) {
+	queryType := "findByID"
+	repository := "import_job"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("import_jobs")
+	var job models.ImportJob
+	if err := collection.FindOne(ctx, bson.M{"_id": jobID, "user_id": userID}).Decode(&job); err != nil {
+		if err != mongo.ErrNoDocuments {
+			status = "error"
+			utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *importJobRepository) FindByUser(ctx context.Context, userID primitive.ObjectID, limit int64) ([]models.ImportJob, error) {
+	queryType := "findByUser"
+	repository := "import_job"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("import_jobs")
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to find import jobs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var jobs []models.ImportJob
+	if err := cursor.All(ctx, &jobs); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding import jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+func (r *importJobRepository) FailStale(ctx context.Context, cutoff, now time.Time, reason string) (int64, error) {
+	queryType := "failStale"
+	repository := "import_job"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("import_jobs")
+	filter := bson.M{"status": activeImportStatuses, "updated_at": bson.M{"$lt": cutoff}}
+	update := bson.M{
+		"$set":   bson.M{"status": models.ImportJobFailed, "error": reason, "finished_at": now, "updated_at": now},
+		"$unset": bson.M{"active": ""},
+	}
+	result, err := collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return 0, fmt.Errorf("failed to fail stale import jobs: %w", err)
+	}
+	return result.ModifiedCount, nil
+}
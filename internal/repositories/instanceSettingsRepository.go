@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"markly/internal/database"
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+// instanceSettingsID is the _id of the single instance settings document.
+const instanceSettingsID = "instance"
+
+// InstanceSettingsRepository stores the settings of the deployment in a single
+// document.
+type InstanceSettingsRepository interface {
+	Get(ctx context.Context) (*models.InstanceSettings, error)
+	Update(ctx context.Context, updateFields bson.M) (*models.InstanceSettings, error)
+}
+
+type instanceSettingsRepository struct {
+	db database.Service
+}
+
+func NewInstanceSettingsRepository(db database.Service) InstanceSettingsRepository {
+	return &instanceSettingsRepository{db: db}
+}
+
+// Get returns the stored settings, or mongo.ErrNoDocuments when none were saved yet.
+func (r *instanceSettingsRepository) Get(ctx context.Context) (*models.InstanceSettings, error) {
+	queryType := "get"
+	repository := "instanceSettings"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("instance_settings")
+	var settings models.InstanceSettings
+	if err := collection.FindOne(ctx, bson.M{"_id": instanceSettingsID}).Decode(&settings); err != nil {
+		if err != mongo.ErrNoDocuments {
+			status = "error"
+			utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// Update sets fields of the settings, creating the document if needed, and returns
+// the result.
+func (r *instanceSettingsRepository) Update(ctx context.Context, updateFields bson.M) (*models.InstanceSettings, error) {
+	queryType := "update"
+	repository := "instanceSettings"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("instance_settings")
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var settings models.InstanceSettings
+	if err := collection.FindOneAndUpdate(ctx, bson.M{"_id": instanceSettingsID}, bson.M{"$set": updateFields}, opts).Decode(&settings); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to update instance settings: %w", err)
+	}
+	return &settings, nil
+}
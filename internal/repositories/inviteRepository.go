@@ -0,0 +1,169 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"markly/internal/database"
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+type InviteRepository interface {
+	Create(ctx context.Context, invite *models.Invite) (*models.Invite, error)
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Invite, error)
+	CountByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+	Delete(ctx context.Context, userID, inviteID primitive.ObjectID) (*mongo.DeleteResult, error)
+	// Redeem counts a use of code if it has not expired or run out of uses, and
+	// returns the invite. It returns mongo.ErrNoDocuments otherwise.
+	Redeem(ctx context.Context, code string, now time.Time) (*models.Invite, error)
+	// Release gives back a use counted by Redeem.
+	Release(ctx context.Context, code string) error
+}
+
+type inviteRepository struct {
+	db database.Service
+}
+
+func NewInviteRepository(db database.Service) InviteRepository {
+	return &inviteRepository{db: db}
+}
+
+func (r *inviteRepository) Create(ctx context.Context, invite *models.Invite) (*models.Invite, error) {
+	queryType := "create"
+	repository := "invite"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("invites")
+	if _, err := collection.InsertOne(ctx, invite); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to insert invite: %w", err)
+	}
+	return invite, nil
+}
+
+func (r *inviteRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Invite, error) {
+	queryType := "findByUser"
+	repository := "invite"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("invites")
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to retrieve invites: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	invites := []models.Invite{}
+	if err := cursor.All(ctx, &invites); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding invites: %w", err)
+	}
+	return invites, nil
+}
+
+func (r *inviteRepository) CountByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	queryType := "countByUser"
+	repository := "invite"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("invites")
+	count, err := collection.CountDocuments(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return 0, fmt.Errorf("failed to count invites: %w", err)
+	}
+	return count, nil
+}
+
+func (r *inviteRepository) Delete(ctx context.Context, userID, inviteID primitive.ObjectID) (*mongo.DeleteResult, error) {
+	queryType := "delete"
+	repository := "invite"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("invites")
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": inviteID, "user_id": userID})
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to delete invite: %w", err)
+	}
+	return result, nil
+}
+
+func (r *inviteRepository) Redeem(ctx context.Context, code string, now time.Time) (*models.Invite, error) {
+	queryType := "redeem"
+	repository := "invite"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("invites")
+	filter := bson.M{
+		"code": code,
+		"$and": bson.A{
+			bson.M{"$or": bson.A{bson.M{"max_uses": 0}, bson.M{"$expr": bson.M{"$lt": bson.A{"$uses", "$max_uses"}}}}},
+			bson.M{"$or": bson.A{bson.M{"expires_at": nil}, bson.M{"expires_at": bson.M{"$gt": now}}}},
+		},
+	}
+	var invite models.Invite
+	err := collection.FindOneAndUpdate(ctx, filter, bson.M{"$inc": bson.M{"uses": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&invite)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			status = "error"
+			utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		}
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func (r *inviteRepository) Release(ctx context.Context, code string) error {
+	queryType := "release"
+	repository := "invite"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("invites")
+	if _, err := collection.UpdateOne(ctx, bson.M{"code": code, "uses": bson.M{"$gt": 0}}, bson.M{"$inc": bson.M{"uses": -1}}); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to release invite: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"markly/internal/database"
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+// NewsletterRepository stores the subscribers and send history of collection
+// newsletters.
+type NewsletterRepository interface {
+	CreateSubscriber(ctx context.Context, sub *models.NewsletterSubscriber) error
+	FindSubscribers(ctx context.Context, filter bson.M) ([]models.NewsletterSubscriber, error)
+	CountSubscribers(ctx context.Context, filter bson.M) (int64, error)
+	UpdateSubscriber(ctx context.Context, filter bson.M, updateFields bson.M) (*models.NewsletterSubscriber, error)
+	DeleteSubscriber(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error)
+	CreateSend(ctx context.Context, send *models.NewsletterSend) error
+	FindSends(ctx context.Context, filter bson.M, limit int64) ([]models.NewsletterSend, error)
+	UpdateSend(ctx context.Context, filter bson.M, updateFields bson.M) error
+}
+
+type newsletterRepository struct {
+	db database.Service
+}
+
+func NewNewsletterRepository(db database.Service) NewsletterRepository {
+	return &newsletterRepository{db: db}
+}
+
+func (r *newsletterRepository) CreateSubscriber(ctx context.Context, sub *models.NewsletterSubscriber) error {
+	queryType := "createSubscriber"
+	repository := "newsletter"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("newsletter_subscribers")
+	if _, err := collection.InsertOne(ctx, sub); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return err
+	}
+	return nil
+}
+
+// FindSubscribers returns the subscribers matching filter, oldest first.
+func (r *newsletterRepository) FindSubscribers(ctx context.Context, filter bson.M) ([]models.NewsletterSubscriber, error) {
+	queryType := "findSubscribers"
+	repository := "newsletter"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("newsletter_subscribers")
+	filter, opts := newFindOptions(bson.D{{Key: "created_at", Value: 1}}).build(filter)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to find newsletter subscribers: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	subs := []models.NewsletterSubscriber{}
+	if err := cursor.All(ctx, &subs); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding newsletter subscribers: %w", err)
+	}
+	return subs, nil
+}
+
+func (r *newsletterRepository) CountSubscribers(ctx context.Context, filter bson.M) (int64, error) {
+	queryType := "countSubscribers"
+	repository := "newsletter"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("newsletter_subscribers")
+	count, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return 0, fmt.Errorf("failed to count newsletter subscribers: %w", err)
+	}
+	return count, nil
+}
+
+// UpdateSubscriber sets updateFields on the subscriber matching filter and returns
+// the updated document, or mongo.ErrNoDocuments when nothing matched.
+func (r *newsletterRepository) UpdateSubscriber(ctx context.Context, filter bson.M, updateFields bson.M) (*models.NewsletterSubscriber, error) {
+	queryType := "updateSubscriber"
+	repository := "newsletter"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	var sub models.NewsletterSubscriber
+	collection := r.db.Client().Database("markly").Collection("newsletter_subscribers")
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := collection.FindOneAndUpdate(ctx, filter, bson.M{"$set": updateFields}, opts).Decode(&sub)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, err
+		}
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to update newsletter subscriber: %w", err)
+	}
+	return &sub, nil
+}
+
+func (r *newsletterRepository) DeleteSubscriber(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error) {
+	queryType := "deleteSubscriber"
+	repository := "newsletter"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("newsletter_subscribers")
+	result, err := collection.DeleteOne(ctx, filter)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to delete newsletter subscriber: %w", err)
+	}
+	return result, nil
+}
+
+func (r *newsletterRepository) CreateSend(ctx context.Context, send *models.NewsletterSend) error {
+	queryType := "createSend"
+	repository := "newsletter"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("newsletter_sends")
+	if _, err := collection.InsertOne(ctx, send); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to insert newsletter send: %w", err)
+	}
+	return nil
+}
+
+// FindSends returns the sends matching filter, newest first.
+func (r *newsletterRepository) FindSends(ctx context.Context, filter bson.M, limit int64) ([]models.NewsletterSend, error) {
+	queryType := "findSends"
+	repository := "newsletter"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("newsletter_sends")
+	filter, opts := newFindOptions(bson.D{{Key: "created_at", Value: -1}}).offset(limit, 1).build(filter)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to find newsletter sends: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	sends := []models.NewsletterSend{}
+	if err := cursor.All(ctx, &sends); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding newsletter sends: %w", err)
+	}
+	return sends, nil
+}
+
+func (r *newsletterRepository) UpdateSend(ctx context.Context, filter bson.M, updateFields bson.M) error {
+	queryType := "updateSend"
+	repository := "newsletter"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("newsletter_sends")
+	if _, err := collection.UpdateOne(ctx, filter, bson.M{"$set": updateFields}); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to update newsletter send: %w", err)
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 
+	"markly/internal/database"
 	"markly/internal/models"
 )
 
@@ -20,19 +21,20 @@ type OTPRepository interface {
 }
 
 type otpRepository struct {
-	collection *mongo.Collection
-	userRepo   UserRepository
+	db       database.Service
+	userRepo UserRepository
 }
 
-func NewOTPRepository(db *mongo.Database, userRepo UserRepository) OTPRepository {
-	return &otpRepository{collection: db.Collection("otps"), userRepo: userRepo}
+func NewOTPRepository(db database.Service, userRepo UserRepository) OTPRepository {
+	return &otpRepository{db: db, userRepo: userRepo}
 }
 
 func (r *otpRepository) Create(ctx context.Context, otp *models.OTP) (*models.OTP, error) {
 	otp.ID = primitive.NewObjectID()
 	otp.CreatedAt = time.Now()
 	otp.UpdatedAt = time.Now()
-	_, err := r.collection.InsertOne(ctx, otp)
+	collection := r.db.Client().Database("markly").Collection("otps")
+	_, err := collection.InsertOne(ctx, otp)
 	if err != nil {
 		return nil, err
 	}
@@ -40,9 +42,10 @@ func (r *otpRepository) Create(ctx context.Context, otp *models.OTP) (*models.OT
 }
 
 func (r *otpRepository) FindByUserIDAndOTPCode(ctx context.Context, userID primitive.ObjectID, otpCode string, purpose string) (*models.OTP, error) {
+	collection := r.db.Client().Database("markly").Collection("otps")
 	var otp models.OTP
 	filter := bson.M{"user_id": userID, "otp_code": otpCode, "purpose": purpose, "is_used": false, "expires_at": bson.M{"$gt": time.Now()}}
-	err := r.collection.FindOne(ctx, filter).Decode(&otp)
+	err := collection.FindOne(ctx, filter).Decode(&otp)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
@@ -53,15 +56,17 @@ func (r *otpRepository) FindByUserIDAndOTPCode(ctx context.Context, userID primi
 }
 
 func (r *otpRepository) MarkAsUsed(ctx context.Context, otpID primitive.ObjectID) error {
+	collection := r.db.Client().Database("markly").Collection("otps")
 	filter := bson.M{"_id": otpID}
 	update := bson.M{"$set": bson.M{"is_used": true, "updated_at": time.Now()}}
-	_, err := r.collection.UpdateOne(ctx, filter, update)
+	_, err := collection.UpdateOne(ctx, filter, update)
 	return err
 }
 
 func (r *otpRepository) DeleteExpiredOTPs(ctx context.Context) error {
+	collection := r.db.Client().Database("markly").Collection("otps")
 	filter := bson.M{"expires_at": bson.M{"$lt": time.Now()}, "is_used": false}
-	_, err := r.collection.DeleteMany(ctx, filter)
+	_, err := collection.DeleteMany(ctx, filter)
 	return err
 }
 
@@ -74,9 +79,10 @@ func (r *otpRepository) FindByUserEmailAndOTPCodeAndPurpose(ctx context.Context,
 		return nil, nil // User not found
 	}
 
+	collection := r.db.Client().Database("markly").Collection("otps")
 	var otp models.OTP
 	filter := bson.M{"user_id": user.ID, "otp_code": otpCode, "purpose": purpose, "is_used": false, "expires_at": bson.M{"$gt": time.Now()}}
-	err = r.collection.FindOne(ctx, filter).Decode(&otp)
+	err = collection.FindOne(ctx, filter).Decode(&otp)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"markly/internal/database"
+	"markly/internal/utils"
+)
+
+// OwnershipRepository tells who owns a document of any user-owned collection, for
+// telling a missing resource apart from another user's.
+type OwnershipRepository interface {
+	// Owner returns the user_id of the document, or mongo.ErrNoDocuments.
+	Owner(ctx context.Context, collection string, id primitive.ObjectID) (primitive.ObjectID, error)
+}
+
+type ownershipRepository struct {
+	db database.Service
+}
+
+func NewOwnershipRepository(db database.Service) OwnershipRepository {
+	return &ownershipRepository{db: db}
+}
+
+func (r *ownershipRepository) Owner(ctx context.Context, collection string, id primitive.ObjectID) (primitive.ObjectID, error) {
+	queryType := "owner"
+	repository := "ownership"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	coll := r.db.Client().Database("markly").Collection(collection)
+	var doc struct {
+		UserID primitive.ObjectID `bson:"user_id"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"user_id": 1})
+	if err := coll.FindOne(ctx, bson.M{"_id": id}, opts).Decode(&doc); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return primitive.NilObjectID, err
+		}
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return primitive.NilObjectID, fmt.Errorf("failed to find owner in %s: %w", collection, err)
+	}
+	return doc.UserID, nil
+}
@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"markly/internal/database"
+	"markly/internal/utils"
+)
+
+// RetentionRepository counts and deletes the records of any collection whose
+// field is below a cutoff, such as a time, for enforcing the retention policy.
+// Records without the field, or with a value of another type, never expire.
+type RetentionRepository interface {
+	CountBefore(ctx context.Context, collection, field string, cutoff interface{}) (int64, error)
+	DeleteBefore(ctx context.Context, collection, field string, cutoff interface{}) (int64, error)
+}
+
+type retentionRepository struct {
+	db database.Service
+}
+
+func NewRetentionRepository(db database.Service) RetentionRepository {
+	return &retentionRepository{db: db}
+}
+
+func (r *retentionRepository) CountBefore(ctx context.Context, collection, field string, cutoff interface{}) (int64, error) {
+	queryType := "countBefore"
+	repository := "retention"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	coll := r.db.Client().Database("markly").Collection(collection)
+	count, err := coll.CountDocuments(ctx, bson.M{field: bson.M{"$lt": cutoff}})
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return 0, fmt.Errorf("failed to count expired records in %s: %w", collection, err)
+	}
+	return count, nil
+}
+
+func (r *retentionRepository) DeleteBefore(ctx context.Context, collection, field string, cutoff interface{}) (int64, error) {
+	queryType := "deleteBefore"
+	repository := "retention"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	coll := r.db.Client().Database("markly").Collection(collection)
+	result, err := coll.DeleteMany(ctx, bson.M{field: bson.M{"$lt": cutoff}})
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return 0, fmt.Errorf("failed to delete expired records in %s: %w", collection, err)
+	}
+	return result.DeletedCount, nil
+}
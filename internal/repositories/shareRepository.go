@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"markly/internal/database"
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+type ShareRepository interface {
+	Create(ctx context.Context, share *models.BookmarkShare) (*models.BookmarkShare, error)
+	FindByBookmark(ctx context.Context, userID, bookmarkID primitive.ObjectID) ([]models.BookmarkShare, error)
+	// CountActive counts the bookmark's links that are neither revoked nor expired.
+	CountActive(ctx context.Context, userID, bookmarkID primitive.ObjectID, now time.Time) (int64, error)
+	Revoke(ctx context.Context, userID, bookmarkID, shareID primitive.ObjectID, now time.Time) (*mongo.UpdateResult, error)
+	// RecordView counts a view of the link with tokenHash and returns it, or
+	// mongo.ErrNoDocuments if it is unknown, revoked or expired.
+	RecordView(ctx context.Context, tokenHash string, now time.Time) (*models.BookmarkShare, error)
+}
+
+type shareRepository struct {
+	db database.Service
+}
+
+func NewShareRepository(db database.Service) ShareRepository {
+	return &shareRepository{db: db}
+}
+
+func (r *shareRepository) Create(ctx context.Context, share *models.BookmarkShare) (*models.BookmarkShare, error) {
+	queryType := "create"
+	repository := "share"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("bookmark_shares")
+	if _, err := collection.InsertOne(ctx, share); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to insert share link: %w", err)
+	}
+	return share, nil
+}
+
+func (r *shareRepository) FindByBookmark(ctx context.Context, userID, bookmarkID primitive.ObjectID) ([]models.BookmarkShare, error) {
+	queryType := "findByBookmark"
+	repository := "share"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("bookmark_shares")
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID, "bookmark_id": bookmarkID}, opts)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to retrieve share links: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	shares := []models.BookmarkShare{}
+	if err := cursor.All(ctx, &shares); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding share links: %w", err)
+	}
+	return shares, nil
+}
+
+func (r *shareRepository) CountActive(ctx context.Context, userID, bookmarkID primitive.ObjectID, now time.Time) (int64, error) {
+	queryType := "countActive"
+	repository := "share"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("bookmark_shares")
+	filter := bson.M{"user_id": userID, "bookmark_id": bookmarkID, "revoked_at": nil, "expires_at": bson.M{"$gt": now}}
+	count, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return 0, fmt.Errorf("failed to count share links: %w", err)
+	}
+	return count, nil
+}
+
+func (r *shareRepository) Revoke(ctx context.Context, userID, bookmarkID, shareID primitive.ObjectID, now time.Time) (*mongo.UpdateResult, error) {
+	queryType := "revoke"
+	repository := "share"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("bookmark_shares")
+	filter := bson.M{"_id": shareID, "user_id": userID, "bookmark_id": bookmarkID, "revoked_at": nil}
+	result, err := collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked_at": now}})
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to revoke share link: %w", err)
+	}
+	return result, nil
+}
+
+func (r *shareRepository) RecordView(ctx context.Context, tokenHash string, now time.Time) (*models.BookmarkShare, error) {
+	queryType := "recordView"
+	repository := "share"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("bookmark_shares")
+	filter, update := recordViewQuery(tokenHash, now)
+	var share models.BookmarkShare
+	err := collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&share)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			status = "error"
+			utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		}
+		return nil, err
+	}
+	return &share, nil
+}
+
+// recordViewQuery matches the viewable link with tokenHash and counts a view in
+// the same update, so concurrent views are neither lost nor counted for links
+// revoked or expired meanwhile.
+func recordViewQuery(tokenHash string, now time.Time) (filter, update bson.M) {
+	filter = bson.M{"token_hash": tokenHash, "revoked_at": nil, "expires_at": bson.M{"$gt": now}}
+	update = bson.M{"$inc": bson.M{"views": 1}, "$set": bson.M{"last_viewed_at": now}}
+	return filter, update
+}
@@ -0,0 +1,25 @@
+package repositories
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestRecordViewQuery(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	filter, update := recordViewQuery("hash", now)
+
+	wantFilter := bson.M{"token_hash": "hash", "revoked_at": nil, "expires_at": bson.M{"$gt": now}}
+	if !reflect.DeepEqual(filter, wantFilter) {
+		t.Errorf("filter = %v, want %v", filter, wantFilter)
+	}
+	// The view is counted with $inc rather than by writing back a count read
+	// before, so that concurrent views all count.
+	wantUpdate := bson.M{"$inc": bson.M{"views": 1}, "$set": bson.M{"last_viewed_at": now}}
+	if !reflect.DeepEqual(update, wantUpdate) {
+		t.Errorf("update = %v, want %v", update, wantUpdate)
+	}
+}
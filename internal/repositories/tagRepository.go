@@ -19,6 +19,7 @@ type TagRepository interface {
 	Create(ctx context.Context, tag *models.Tag) (*models.Tag, error)
 	FindByID(ctx context.Context, userID, tagID primitive.ObjectID) (*models.Tag, error)
 	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Tag, error)
+	FindPageByUser(ctx context.Context, userID primitive.ObjectID, page models.PageRequest) ([]models.Tag, string, error)
 	Update(ctx context.Context, userID, tagID primitive.ObjectID, updateFields bson.M) (*mongo.UpdateResult, error)
 	Delete(ctx context.Context, userID, tagID primitive.ObjectID) (*mongo.DeleteResult, error)
 	FindAll(ctx context.Context) ([]models.Tag, error)
@@ -54,19 +55,30 @@ func (r *tagRepository) FindByID(ctx context.Context, userID, tagID primitive.Ob
 }
 
 func (r *tagRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Tag, error) {
-	var tags []models.Tag
+	tags, _, err := r.FindPageByUser(ctx, userID, models.PageRequest{})
+	return tags, err
+}
+
+// FindPageByUser returns one page of the user's tags, oldest first, and the cursor
+// of the next page. An unpaged request returns every tag.
+func (r *tagRepository) FindPageByUser(ctx context.Context, userID primitive.ObjectID, page models.PageRequest) ([]models.Tag, string, error) {
+	fo, err := newFindOptions(nil).page(page)
+	if err != nil {
+		return nil, "", err
+	}
+	filter, opts := fo.build(bson.M{"user_id": userID})
 	collection := r.db.Client().Database("markly").Collection("tags")
-	filter := bson.M{"user_id": userID}
-	cursor, err := collection.Find(ctx, filter)
+	cursor, err := collection.Find(ctx, filter, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve tags: %w", err)
+		return nil, "", fmt.Errorf("failed to retrieve tags: %w", err)
 	}
 	defer cursor.Close(ctx)
 
+	var tags []models.Tag
 	if err := cursor.All(ctx, &tags); err != nil {
-		return nil, fmt.Errorf("error decoding tags: %w", err)
+		return nil, "", fmt.Errorf("error decoding tags: %w", err)
 	}
-	return tags, nil
+	return nextPage(fo, tags)
 }
 
 func (r *tagRepository) Update(ctx context.Context, userID, tagID primitive.ObjectID, updateFields bson.M) (*mongo.UpdateResult, error) {
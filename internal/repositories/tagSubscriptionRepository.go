@@ -0,0 +1,198 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"markly/internal/database"
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+type TagSubscriptionRepository interface {
+	Create(ctx context.Context, sub *models.TagSubscription) (*models.TagSubscription, error)
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.TagSubscription, error)
+	CountByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+	// FindTrending returns the subscriptions of all users that asked for trending
+	// notifications.
+	FindTrending(ctx context.Context) ([]models.TagSubscription, error)
+	// Update applies updateFields and returns the updated subscription, or
+	// mongo.ErrNoDocuments if the user has no such subscription.
+	Update(ctx context.Context, userID, subID primitive.ObjectID, updateFields bson.M) (*models.TagSubscription, error)
+	Delete(ctx context.Context, userID, subID primitive.ObjectID) (*mongo.DeleteResult, error)
+	// MarkNotified records a notification and reports false if the user was
+	// already notified about the same item for the same tag and source.
+	MarkNotified(ctx context.Context, n *models.TagNotification) (bool, error)
+}
+
+type tagSubscriptionRepository struct {
+	db database.Service
+}
+
+func NewTagSubscriptionRepository(db database.Service) TagSubscriptionRepository {
+	return &tagSubscriptionRepository{db: db}
+}
+
+func (r *tagSubscriptionRepository) Create(ctx context.Context, sub *models.TagSubscription) (*models.TagSubscription, error) {
+	queryType := "create"
+	repository := "tagSubscription"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("tag_subscriptions")
+	if _, err := collection.InsertOne(ctx, sub); err != nil {
+		if !mongo.IsDuplicateKeyError(err) {
+			status = "error"
+			utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		}
+		return nil, fmt.Errorf("failed to insert tag subscription: %w", err)
+	}
+	return sub, nil
+}
+
+func (r *tagSubscriptionRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.TagSubscription, error) {
+	queryType := "findByUser"
+	repository := "tagSubscription"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("tag_subscriptions")
+	opts := options.Find().SetSort(bson.D{{Key: "tag", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to retrieve tag subscriptions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	subs := []models.TagSubscription{}
+	if err := cursor.All(ctx, &subs); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding tag subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+func (r *tagSubscriptionRepository) CountByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	queryType := "countByUser"
+	repository := "tagSubscription"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("tag_subscriptions")
+	count, err := collection.CountDocuments(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return 0, fmt.Errorf("failed to count tag subscriptions: %w", err)
+	}
+	return count, nil
+}
+
+func (r *tagSubscriptionRepository) FindTrending(ctx context.Context) ([]models.TagSubscription, error) {
+	queryType := "findTrending"
+	repository := "tagSubscription"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("tag_subscriptions")
+	opts := options.Find().SetSort(bson.D{{Key: "user_id", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"trending": true}, opts)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to retrieve trending tag subscriptions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	subs := []models.TagSubscription{}
+	if err := cursor.All(ctx, &subs); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding tag subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+func (r *tagSubscriptionRepository) Update(ctx context.Context, userID, subID primitive.ObjectID, updateFields bson.M) (*models.TagSubscription, error) {
+	queryType := "update"
+	repository := "tagSubscription"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("tag_subscriptions")
+	var sub models.TagSubscription
+	err := collection.FindOneAndUpdate(ctx, bson.M{"_id": subID, "user_id": userID}, bson.M{"$set": updateFields},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&sub)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			status = "error"
+			utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		}
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (r *tagSubscriptionRepository) Delete(ctx context.Context, userID, subID primitive.ObjectID) (*mongo.DeleteResult, error) {
+	queryType := "delete"
+	repository := "tagSubscription"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("tag_subscriptions")
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": subID, "user_id": userID})
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to delete tag subscription: %w", err)
+	}
+	return result, nil
+}
+
+func (r *tagSubscriptionRepository) MarkNotified(ctx context.Context, n *models.TagNotification) (bool, error) {
+	queryType := "markNotified"
+	repository := "tagSubscription"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("tag_notifications")
+	if _, err := collection.InsertOne(ctx, n); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return false, fmt.Errorf("failed to record tag notification: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"markly/internal/database"
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+// ThumbnailRepository caches bookmark thumbnails and counts how many each user
+// has had generated per month.
+type ThumbnailRepository interface {
+	Upsert(ctx context.Context, thumb *models.BookmarkThumbnail) error
+	FindByBookmark(ctx context.Context, userID, bookmarkID primitive.ObjectID) (*models.BookmarkThumbnail, error)
+	// ReserveGeneration counts one generation for the user in month, such as
+	// "2025-03", and reports false without counting once limit is reached.
+	ReserveGeneration(ctx context.Context, userID primitive.ObjectID, month string, limit int) (bool, error)
+	// CountGenerations returns the generations counted for the user in month.
+	CountGenerations(ctx context.Context, userID primitive.ObjectID, month string) (int64, error)
+}
+
+type thumbnailRepository struct {
+	db database.Service
+}
+
+func NewThumbnailRepository(db database.Service) ThumbnailRepository {
+	return &thumbnailRepository{db: db}
+}
+
+func (r *thumbnailRepository) Upsert(ctx context.Context, thumb *models.BookmarkThumbnail) error {
+	queryType := "upsert"
+	repository := "thumbnail"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("bookmark_thumbnails")
+	filter := bson.M{"_id": thumb.BookmarkID, "user_id": thumb.UserID}
+	if _, err := collection.ReplaceOne(ctx, filter, thumb, options.Replace().SetUpsert(true)); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to store thumbnail: %w", err)
+	}
+	return nil
+}
+
+func (r *thumbnailRepository) FindByBookmark(ctx context.Context, userID, bookmarkID primitive.ObjectID) (*models.BookmarkThumbnail, error) {
+	queryType := "findByBookmark"
+	repository := "thumbnail"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("bookmark_thumbnails")
+	var thumb models.BookmarkThumbnail
+	if err := collection.FindOne(ctx, bson.M{"_id": bookmarkID, "user_id": userID}).Decode(&thumb); err != nil {
+		if err != mongo.ErrNoDocuments {
+			status = "error"
+			utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		}
+		return nil, err
+	}
+	return &thumb, nil
+}
+
+func (r *thumbnailRepository) ReserveGeneration(ctx context.Context, userID primitive.ObjectID, month string, limit int) (bool, error) {
+	queryType := "reserveGeneration"
+	repository := "thumbnail"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	// Once the count reaches the limit the filter no longer matches, and the upsert
+	// collides with the existing document on _id.
+	collection := r.db.Client().Database("markly").Collection("thumbnail_usage")
+	filter := bson.M{"_id": userID.Hex() + ":" + month, "count": bson.M{"$lt": limit}}
+	update := bson.M{"$inc": bson.M{"count": 1}, "$setOnInsert": bson.M{"user_id": userID, "month": month}}
+	_, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return false, fmt.Errorf("failed to count thumbnail generation: %w", err)
+	}
+	return true, nil
+}
+
+func (r *thumbnailRepository) CountGenerations(ctx context.Context, userID primitive.ObjectID, month string) (int64, error) {
+	queryType := "countGenerations"
+	repository := "thumbnail"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("thumbnail_usage")
+	var usage struct {
+		Count int64 `bson:"count"`
+	}
+	err := collection.FindOne(ctx, bson.M{"_id": userID.Hex() + ":" + month}).Decode(&usage)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, nil
+		}
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return 0, fmt.Errorf("failed to count thumbnail generations: %w", err)
+	}
+	return usage.Count, nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"markly/internal/database"
 	"markly/internal/models"
@@ -19,8 +20,13 @@ type TrendingRepository interface {
 	FindByName(ctx context.Context, name string) (*models.TrendingItem, error)
 	Update(ctx context.Context, name string, updateFields bson.M) (*mongo.UpdateResult, error)
 	FindAll(ctx context.Context) ([]models.TrendingItem, error)
+	SaveDomains(ctx context.Context, snapshot *models.TrendingDomains) error
+	FindDomains(ctx context.Context) (*models.TrendingDomains, error)
 }
 
+// trendingDomainsID is the ID of the single trending domains snapshot document.
+const trendingDomainsID = "weekly"
+
 type trendingRepository struct {
 	db database.Service
 }
@@ -118,3 +124,44 @@ func (r *trendingRepository) FindAll(ctx context.Context) ([]models.TrendingItem
 	}
 	return items, nil
 }
+
+func (r *trendingRepository) SaveDomains(ctx context.Context, snapshot *models.TrendingDomains) error {
+	queryType := "saveDomains"
+	repository := "trending"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("trending_domains")
+	opts := options.Replace().SetUpsert(true)
+	if _, err := collection.ReplaceOne(ctx, bson.M{"_id": trendingDomainsID}, snapshot, opts); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return fmt.Errorf("failed to save trending domains: %w", err)
+	}
+	return nil
+}
+
+func (r *trendingRepository) FindDomains(ctx context.Context) (*models.TrendingDomains, error) {
+	queryType := "findDomains"
+	repository := "trending"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	var snapshot models.TrendingDomains
+	collection := r.db.Client().Database("markly").Collection("trending_domains")
+	err := collection.FindOne(ctx, bson.M{"_id": trendingDomainsID}).Decode(&snapshot)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			status = "error"
+			utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		}
+		return nil, err
+	}
+	return &snapshot, nil
+}
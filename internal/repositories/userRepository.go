@@ -3,12 +3,14 @@ package repositories
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"markly/internal/database"
 	"markly/internal/models"
@@ -22,7 +24,11 @@ type UserRepository interface {
 	Update(ctx context.Context, userID primitive.ObjectID, updateFields bson.M) (*mongo.UpdateResult, error)
 	Delete(ctx context.Context, userID primitive.ObjectID) (*mongo.DeleteResult, error)
 	CountAll(ctx context.Context) (int64, error)
+	CountByPlan(ctx context.Context) (map[string]int64, error)
 	CountUsersCreatedBetween(ctx context.Context, startDate, endDate interface{}) (int64, error)
+	CountUsersByInterval(ctx context.Context, startDate, endDate time.Time, interval, timezone string) ([]models.TimeBucket, error)
+	FindIDs(ctx context.Context, filter bson.M) ([]primitive.ObjectID, error)
+	FindReferrals(ctx context.Context, referrerID primitive.ObjectID) ([]models.Referral, error)
 }
 
 type userRepository struct {
@@ -155,6 +161,45 @@ func (r *userRepository) CountAll(ctx context.Context) (int64, error) {
 	return count, nil
 }
 
+// CountByPlan counts the users on each plan. Users without a plan are counted
+// under "".
+func (r *userRepository) CountByPlan(ctx context.Context) (map[string]int64, error) {
+	queryType := "countByPlan"
+	repository := "user"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("users")
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": bson.M{"$ifNull": bson.A{"$plan", ""}}, "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to count users by plan: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Plan  string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding user plan counts: %w", err)
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Plan] += row.Count
+	}
+	return counts, nil
+}
+
 func (r *userRepository) CountUsersCreatedBetween(ctx context.Context, startDate, endDate interface{}) (int64, error) {
 	queryType := "countUsersCreatedBetween"
 	repository := "user"
@@ -180,3 +225,98 @@ func (r *userRepository) CountUsersCreatedBetween(ctx context.Context, startDate
 	}
 	return count, nil
 }
+
+// CountUsersByInterval counts the users created between startDate and endDate per
+// day or week in timezone.
+func (r *userRepository) CountUsersByInterval(ctx context.Context, startDate, endDate time.Time, interval, timezone string) ([]models.TimeBucket, error) {
+	queryType := "countUsersByInterval"
+	repository := "user"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("users")
+	cursor, err := collection.Aggregate(ctx, timeBucketPipeline(startDate, endDate, interval, timezone))
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to count users by %s: %w", interval, err)
+	}
+	defer cursor.Close(ctx)
+
+	var buckets []models.TimeBucket
+	if err := cursor.All(ctx, &buckets); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding user counts: %w", err)
+	}
+	return buckets, nil
+}
+
+// FindIDs returns the IDs of the users matching filter.
+func (r *userRepository) FindIDs(ctx context.Context, filter bson.M) ([]primitive.ObjectID, error) {
+	queryType := "findIDs"
+	repository := "user"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("users")
+	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to find users: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &users); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding users: %w", err)
+	}
+	ids := make([]primitive.ObjectID, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+	return ids, nil
+}
+
+// FindReferrals returns the accounts created with the invite codes of referrerID,
+// oldest first.
+func (r *userRepository) FindReferrals(ctx context.Context, referrerID primitive.ObjectID) ([]models.Referral, error) {
+	queryType := "findReferrals"
+	repository := "user"
+	status := "success"
+	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(v float64) {
+		utils.DBQueryDurationSeconds.WithLabelValues(queryType, repository, status).Observe(v)
+	}))
+	defer timer.ObserveDuration()
+
+	collection := r.db.Client().Database("markly").Collection("users")
+	opts := options.Find().
+		SetProjection(bson.M{"_id": 1, "username": 1, "invite_code": 1, "created_at": 1}).
+		SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"referred_by": referrerID}, opts)
+	if err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("failed to find referrals: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	referrals := []models.Referral{}
+	if err := cursor.All(ctx, &referrals); err != nil {
+		status = "error"
+		utils.DBQueryErrorsTotal.WithLabelValues(queryType, repository).Inc()
+		return nil, fmt.Errorf("error decoding referrals: %w", err)
+	}
+	return referrals, nil
+}
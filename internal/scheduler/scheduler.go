@@ -0,0 +1,154 @@
+package scheduler
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/utils"
+)
+
+// JobFunc is the unit of work run by the scheduler.
+type JobFunc func(ctx context.Context) error
+
+// JobStatus describes the most recent run of a job.
+type JobStatus struct {
+	Name       string        `json:"name"`
+	Interval   time.Duration `json:"-"`
+	IntervalS  int64         `json:"interval_seconds"`
+	LastRunAt  *time.Time    `json:"last_run_at,omitempty"`
+	LastError  string        `json:"last_error,omitempty"`
+	LastStatus string        `json:"last_status,omitempty"`
+	Running    bool          `json:"running"`
+}
+
+type job struct {
+	name     string
+	interval time.Duration
+	fn       JobFunc
+}
+
+// Scheduler runs registered jobs on fixed intervals. A job never overlaps with itself.
+type Scheduler struct {
+	mu     sync.Mutex
+	jobs   []*job
+	status map[string]*JobStatus
+	wg     sync.WaitGroup
+}
+
+func New() *Scheduler {
+	return &Scheduler{status: make(map[string]*JobStatus)}
+}
+
+// Register adds a job. It must be called before Start.
+func (s *Scheduler) Register(name string, interval time.Duration, fn JobFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, &job{name: name, interval: interval, fn: fn})
+	s.status[name] = &JobStatus{Name: name, Interval: interval, IntervalS: int64(interval.Seconds())}
+}
+
+// Start launches every registered job and returns immediately. Jobs stop when ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	jobs := append([]*job(nil), s.jobs...)
+	s.mu.Unlock()
+
+	for _, j := range jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, j)
+	}
+	log.Info().Int("jobs", len(jobs)).Msg("Scheduler started")
+}
+
+// Wait blocks until every job loop has exited.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// RunNow executes a registered job synchronously, outside its schedule.
+func (s *Scheduler) RunNow(ctx context.Context, name string) bool {
+	s.mu.Lock()
+	var target *job
+	for _, j := range s.jobs {
+		if j.name == name {
+			target = j
+			break
+		}
+	}
+	s.mu.Unlock()
+	if target == nil {
+		return false
+	}
+	s.run(ctx, target)
+	return true
+}
+
+// Statuses returns a snapshot of all job statuses sorted by name.
+func (s *Scheduler) Statuses() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]JobStatus, 0, len(s.status))
+	for _, st := range s.status {
+		statuses = append(statuses, *st)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+func (s *Scheduler) loop(ctx context.Context, j *job) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.run(ctx, j)
+		}
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, j *job) {
+	s.mu.Lock()
+	st := s.status[j.name]
+	if st.Running {
+		s.mu.Unlock()
+		log.Warn().Str("job", j.name).Msg("Skipping scheduled job run, previous run still in progress")
+		return
+	}
+	st.Running = true
+	s.mu.Unlock()
+
+	start := time.Now()
+	err := j.fn(ctx)
+	duration := time.Since(start)
+
+	utils.ScheduledJobDurationSeconds.WithLabelValues(j.name).Observe(duration.Seconds())
+
+	s.mu.Lock()
+	st.Running = false
+	st.LastRunAt = &start
+	if err != nil {
+		st.LastStatus = "error"
+		st.LastError = err.Error()
+	} else {
+		st.LastStatus = "success"
+		st.LastError = ""
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		utils.ScheduledJobRunsTotal.WithLabelValues(j.name, "error").Inc()
+		log.Error().Err(err).Str("job", j.name).Dur("duration", duration).Msg("Scheduled job failed")
+		return
+	}
+	utils.ScheduledJobRunsTotal.WithLabelValues(j.name, "success").Inc()
+	utils.ScheduledJobLastSuccessTimestamp.WithLabelValues(j.name).Set(float64(time.Now().Unix()))
+	log.Info().Str("job", j.name).Dur("duration", duration).Msg("Scheduled job completed")
+}
@@ -0,0 +1,144 @@
+package sdk
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// schema is the subset of the OpenAPI 3.0 schema object the models need.
+type schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *schema            `json:"additionalProperties,omitempty"`
+
+	// order keeps the properties in field order for the TypeScript bundle.
+	order []string
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	dateTimeType   = reflect.TypeOf(primitive.DateTime(0))
+	objectIDType   = reflect.TypeOf(primitive.ObjectID{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemas turns Go types into schemas the way encoding/json serializes them.
+// Named structs become components referenced by name.
+type schemas struct {
+	components map[string]*schema
+	names      map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{components: map[string]*schema{}, names: map[reflect.Type]string{}}
+}
+
+func (g *schemas) of(t reflect.Type) *schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType, dateTimeType:
+		return &schema{Type: "string", Format: "date-time"}
+	case objectIDType:
+		return &schema{Type: "string", Pattern: "^[0-9a-f]{24}$"}
+	case rawMessageType:
+		return &schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &schema{Type: "string"}
+	case reflect.Bool:
+		return &schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &schema{Type: "string", Format: "byte"}
+		}
+		return &schema{Type: "array", Items: g.of(t.Elem())}
+	case reflect.Map:
+		return &schema{Type: "object", AdditionalProperties: g.of(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return &schema{Ref: "#/components/schemas/" + g.component(t)}
+	}
+	return &schema{}
+}
+
+// component registers the named struct t and returns its component name. Types
+// of other packages are prefixed with the package name when the plain name is
+// taken.
+func (g *schemas) component(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, taken := g.components[name]; taken {
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	g.names[t] = name
+	// The placeholder ends the recursion of self-referencing types.
+	g.components[name] = &schema{}
+	*g.components[name] = *g.object(t)
+	return name
+}
+
+// object describes the JSON object of struct t. Fields without omitempty that
+// are not pointers are always present, so they are required.
+func (g *schemas) object(t reflect.Type) *schema {
+	s := &schema{Type: "object", Properties: map[string]*schema{}}
+	g.addFields(s, t)
+	return s
+}
+
+func (g *schemas) addFields(s *schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.addFields(s, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if _, ok := s.Properties[name]; !ok {
+			s.order = append(s.order, name)
+		}
+		s.Properties[name] = g.of(ft)
+		if !strings.Contains(opts, "omitempty") && ft.Kind() != reflect.Ptr {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Package sdk generates the client SDK descriptors of the API: an OpenAPI
+// document and a TypeScript bundle with the request and response types. Both are
+// built from the routes the server registers and the Go models they exchange, so
+// clients get types that match the running release.
+package sdk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// APIVersion is the version of the descriptors. It changes when the API breaks
+// compatibility with clients built against an earlier one.
+const APIVersion = "v1"
+
+// Route is a route of the server: an HTTP method and a path template with
+// {name} variables.
+type Route struct {
+	Method string
+	Path   string
+}
+
+// Operation describes what a route exchanges. Request and Response are values
+// of the body types, or nil when the route reads or sends no JSON.
+type Operation struct {
+	Summary  string
+	Request  any
+	Response any
+	// Status is the success status, 200 when zero.
+	Status int
+	// Public routes need no credentials.
+	Public bool
+}
+
+// Artifact is one generated file.
+type Artifact struct {
+	Name        string
+	ContentType string
+	Content     []byte
+	// SHA256 is the hex digest of Content.
+	SHA256 string
+}
+
+// Bundle holds the descriptors of one API version.
+type Bundle struct {
+	APIVersion    string
+	ServerVersion string
+	OpenAPI       Artifact
+	TypeScript    Artifact
+}
+
+func newArtifact(name, contentType string, content []byte) Artifact {
+	sum := sha256.Sum256(content)
+	return Artifact{Name: name, ContentType: contentType, Content: content, SHA256: hex.EncodeToString(sum[:])}
+}
+
+var pathVariable = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// Build generates the descriptors of routes. Operations describe the routes
+// that exchange JSON; the others are listed with an untyped response.
+func Build(serverVersion string, routes []Route, operations map[Route]Operation) (*Bundle, error) {
+	g := newSchemas()
+	doc := openAPIDocument(serverVersion)
+	paths := map[string]map[string]any{}
+	var typed []typedRoute
+
+	sorted := append([]Route(nil), routes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Path != sorted[j].Path {
+			return sorted[i].Path < sorted[j].Path
+		}
+		return sorted[i].Method < sorted[j].Method
+	})
+	for _, route := range sorted {
+		op, known := operations[route]
+		path := pathVariable.ReplaceAllString(route.Path, "{$1}")
+		item, ok := paths[path]
+		if !ok {
+			item = map[string]any{}
+			if params := pathParameters(path); len(params) > 0 {
+				item["parameters"] = params
+			}
+			paths[path] = item
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		response := map[string]any{"description": http.StatusText(status)}
+		tr := typedRoute{Route: Route{Method: route.Method, Path: path}}
+		switch {
+		case !known:
+			response["description"] = "Undocumented response"
+			tr.response = &schema{}
+		case op.Response != nil:
+			tr.response = g.of(reflect.TypeOf(op.Response))
+			response["content"] = map[string]any{"application/json": map[string]any{"schema": tr.response}}
+		}
+		operation := map[string]any{
+			"operationId": operationID(route.Method, path),
+			"responses": map[string]any{
+				fmt.Sprint(status): response,
+				"default":          map[string]any{"$ref": "#/components/responses/Error"},
+			},
+		}
+		if op.Summary != "" {
+			operation["summary"] = op.Summary
+		}
+		if op.Public {
+			operation["security"] = []any{}
+		}
+		if op.Request != nil {
+			tr.request = g.of(reflect.TypeOf(op.Request))
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": tr.request}},
+			}
+		}
+		item[strings.ToLower(route.Method)] = operation
+		typed = append(typed, tr)
+	}
+	doc["paths"] = paths
+	components := doc["components"].(map[string]any)
+	components["schemas"] = g.components
+
+	openAPI, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode openapi document: %w", err)
+	}
+	return &Bundle{
+		APIVersion:    APIVersion,
+		ServerVersion: serverVersion,
+		OpenAPI:       newArtifact("openapi.json", "application/json", append(openAPI, '\n')),
+		TypeScript:    newArtifact("markly.d.ts", "application/typescript; charset=utf-8", typeScript(serverVersion, g.components, typed)),
+	}, nil
+}
+
+func openAPIDocument(serverVersion string) map[string]any {
+	errorSchema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"error": map[string]any{"type": "string"}},
+	}
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":         "Markly API",
+			"version":       serverVersion,
+			"x-api-version": APIVersion,
+		},
+		"security": []any{map[string]any{"bearerAuth": []any{}}, map[string]any{"apiKey": []any{}}},
+		"components": map[string]any{
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"apiKey":     map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+			"responses": map[string]any{
+				"Error": map[string]any{
+					"description": "Error",
+					"content":     map[string]any{"application/json": map[string]any{"schema": errorSchema}},
+				},
+			},
+		},
+	}
+}
+
+func pathParameters(path string) []any {
+	var params []any
+	for _, m := range pathVariable.FindAllStringSubmatch(path, -1) {
+		params = append(params, map[string]any{"name": m[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"}})
+	}
+	return params
+}
+
+// operationID names an operation after its method and path, such as
+// getBookmarksIdHighlights for GET /api/bookmarks/{id}/highlights.
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(strings.TrimPrefix(path, "/api"), func(r rune) bool {
+		return r == '/' || r == '-' || r == '.' || r == '{' || r == '}' || r == '_'
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
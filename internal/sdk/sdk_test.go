@@ -0,0 +1,113 @@
+package sdk
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type testBase struct {
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type testNote struct {
+	testBase
+	ID       primitive.ObjectID `json:"id"`
+	Text     string             `json:"text,omitempty"`
+	Parent   *testNote          `json:"parent,omitempty"`
+	Labels   map[string]int     `json:"labels"`
+	Data     []byte             `json:"data,omitempty"`
+	Internal string             `json:"-"`
+	hidden   string
+}
+
+func TestSchemaFollowsJSONEncoding(t *testing.T) {
+	g := newSchemas()
+	ref := g.of(reflect.TypeOf(testNote{}))
+	if ref.Ref != "#/components/schemas/testNote" {
+		t.Fatalf("named struct should be a reference, got %+v", ref)
+	}
+	s := g.components["testNote"]
+	if want := []string{"created_at", "id", "text", "parent", "labels", "data"}; strings.Join(s.order, ",") != strings.Join(want, ",") {
+		t.Errorf("properties = %v, want %v", s.order, want)
+	}
+	if strings.Join(s.Required, ",") != "created_at,id,labels" {
+		t.Errorf("required = %v", s.Required)
+	}
+	if p := s.Properties["created_at"]; p.Format != "date-time" {
+		t.Errorf("time should be a date-time string, got %+v", p)
+	}
+	if p := s.Properties["parent"]; p.Ref != ref.Ref {
+		t.Errorf("self reference = %+v", p)
+	}
+	if p := s.Properties["labels"]; p.AdditionalProperties == nil || p.AdditionalProperties.Type != "integer" {
+		t.Errorf("map should be an object of integers, got %+v", p)
+	}
+	if p := s.Properties["data"]; p.Format != "byte" {
+		t.Errorf("bytes should be base64 strings, got %+v", p)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	routes := []Route{
+		{Method: "GET", Path: "/api/notes/{id}"},
+		{Method: "DELETE", Path: "/api/notes/{id}"},
+		{Method: "POST", Path: "/api/notes"},
+		{Method: "GET", Path: "/api/other"},
+	}
+	ops := map[Route]Operation{
+		{Method: "GET", Path: "/api/notes/{id}"}:    {Response: testNote{}},
+		{Method: "DELETE", Path: "/api/notes/{id}"}: {Status: http.StatusNoContent},
+		{Method: "POST", Path: "/api/notes"}:        {Request: testNote{}, Response: testNote{}, Status: http.StatusCreated, Public: true},
+	}
+	bundle, err := Build("1.2.3", routes, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Info  map[string]string                     `json:"info"`
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(bundle.OpenAPI.Content, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Info["version"] != "1.2.3" || doc.Info["x-api-version"] != APIVersion {
+		t.Errorf("info = %v", doc.Info)
+	}
+	if len(doc.Paths) != 3 || doc.Paths["/api/notes/{id}"]["parameters"] == nil {
+		t.Errorf("paths = %v", doc.Paths)
+	}
+	if !strings.Contains(string(doc.Paths["/api/notes"]["post"]), `"security": []`) {
+		t.Errorf("public operation should clear security: %s", doc.Paths["/api/notes"]["post"])
+	}
+
+	ts := string(bundle.TypeScript.Content)
+	for _, want := range []string{
+		"export interface testNote {\n  created_at: string;\n  id: string;\n  text?: string;\n  parent?: testNote;\n",
+		`  "/api/notes": {` + "\n    post: { body: Partial<testNote>; response: testNote };\n",
+		"    delete: { response: void };\n    get: { response: testNote };\n",
+		`  "/api/other": {` + "\n    get: { response: unknown };\n",
+	} {
+		if !strings.Contains(ts, want) {
+			t.Errorf("typescript bundle is missing %q:\n%s", want, ts)
+		}
+	}
+	if bundle.OpenAPI.SHA256 == "" || bundle.OpenAPI.SHA256 == bundle.TypeScript.SHA256 {
+		t.Errorf("artifacts should carry their own digest")
+	}
+}
+
+func TestOperationID(t *testing.T) {
+	if got := operationID("GET", "/api/bookmarks/{id}/highlights"); got != "getBookmarksIdHighlights" {
+		t.Errorf("operationID = %q", got)
+	}
+	if got := operationID("GET", "/api/imports/{id}/errors.csv"); got != "getImportsIdErrorsCsv" {
+		t.Errorf("operationID = %q", got)
+	}
+}
@@ -0,0 +1,115 @@
+package sdk
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// typedRoute is a route with the schemas of its request and response bodies.
+// A nil response means the route sends no JSON.
+type typedRoute struct {
+	Route
+	request, response *schema
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// typeScript renders the components as interfaces, followed by a Paths
+// interface mapping each route to the types of its body and response. Request
+// bodies that are also resources, such as a collection, are Partial because the
+// server fills in the fields a client leaves out.
+func typeScript(serverVersion string, components map[string]*schema, routes []typedRoute) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Code generated by the Markly server from its Go models. DO NOT EDIT.\n")
+	fmt.Fprintf(&b, "// Markly API %s, server version %s.\n", APIVersion, serverVersion)
+
+	names := make([]string, 0, len(components))
+	for name := range components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "\nexport interface %s %s\n", name, tsObject(components[name], ""))
+	}
+
+	responses := map[string]bool{}
+	for _, r := range routes {
+		if r.response != nil {
+			responses[tsType(r.response, "")] = true
+		}
+	}
+	b.WriteString("\nexport interface Paths {\n")
+	for i, r := range routes {
+		if i == 0 || routes[i-1].Path != r.Path {
+			fmt.Fprintf(&b, "  %q: {\n", r.Path)
+		}
+		fmt.Fprintf(&b, "    %s: { ", strings.ToLower(r.Method))
+		if r.request != nil {
+			body := tsType(r.request, "")
+			if responses[body] && r.request.Ref != "" {
+				body = "Partial<" + body + ">"
+			}
+			fmt.Fprintf(&b, "body: %s; ", body)
+		}
+		response := "void"
+		if r.response != nil {
+			response = tsType(r.response, "    ")
+		}
+		fmt.Fprintf(&b, "response: %s };\n", response)
+		if i == len(routes)-1 || routes[i+1].Path != r.Path {
+			b.WriteString("  };\n")
+		}
+	}
+	b.WriteString("}\n")
+	return []byte(b.String())
+}
+
+func tsType(s *schema, indent string) string {
+	switch {
+	case s.Ref != "":
+		return strings.TrimPrefix(s.Ref, "#/components/schemas/")
+	case s.Type == "string":
+		return "string"
+	case s.Type == "integer" || s.Type == "number":
+		return "number"
+	case s.Type == "boolean":
+		return "boolean"
+	case s.Type == "array":
+		item := tsType(s.Items, indent)
+		if strings.HasPrefix(item, "{") || strings.Contains(item, "<") {
+			return "Array<" + item + ">"
+		}
+		return item + "[]"
+	case s.Type == "object" && s.AdditionalProperties != nil:
+		return "Record<string, " + tsType(s.AdditionalProperties, indent) + ">"
+	case s.Type == "object":
+		return tsObject(s, indent)
+	}
+	return "unknown"
+}
+
+func tsObject(s *schema, indent string) string {
+	if len(s.order) == 0 {
+		return "{}"
+	}
+	required := make(map[string]bool, len(s.Required))
+	for _, name := range s.Required {
+		required[name] = true
+	}
+	var b strings.Builder
+	b.WriteString("{\n")
+	for _, name := range s.order {
+		key := name
+		if !identifier.MatchString(name) {
+			key = fmt.Sprintf("%q", name)
+		}
+		if !required[name] {
+			key += "?"
+		}
+		fmt.Fprintf(&b, "%s  %s: %s;\n", indent, key, tsType(s.Properties[name], indent+"  "))
+	}
+	b.WriteString(indent + "}")
+	return b.String()
+}
@@ -8,6 +8,9 @@ import (
 
 	"markly/internal/handlers"
 	"markly/internal/middlewares"
+	"markly/internal/models"
+	"markly/internal/sdk"
+	"markly/internal/utils"
 )
 
 func (s *Server) RegisterRoutes() http.Handler {
@@ -16,12 +19,16 @@ func (s *Server) RegisterRoutes() http.Handler {
 	r.Use(middlewares.CorsMiddleware)
 	r.Use(middlewares.RateLimit)
 	r.Use(middlewares.PrometheusMiddleware)
+	r.Use(middlewares.ShadowMiddleware)
 
 	ch := handlers.NewCommonHandler(s.db)
 	r.HandleFunc("/", ch.HelloWorldHandler)
 	r.HandleFunc("/health", ch.HealthHandler)
 
-	r.Handle("/metrics", promhttp.Handler())
+	sh := handlers.NewStatusHandler(s.startedAt, Version, s.jobs.Statuses, s.queueDepths)
+	r.HandleFunc("/status", sh.GetStatus).Methods("GET", "OPTIONS")
+
+	r.Handle("/metrics", s.metricsHandler())
 
 	s.registerBookmarkRoutes(r)
 	s.registerAuthRoutes(r)
@@ -30,18 +37,86 @@ func (s *Server) RegisterRoutes() http.Handler {
 	s.registerCategoryRoutes(r)
 	s.registerAgentRoutes(r)
 	s.registerAnalyticsRoutes(r) // New: Register analytics routes
+	s.registerAPIKeyRoutes(r)
+	s.registerImpersonationRoutes(r)
+	s.registerErasureRoutes(r)
+	s.registerExportRoutes(r)
+	s.registerInstanceRoutes(r)
+	s.registerInviteRoutes(r)
+	s.registerTagSubscriptionRoutes(r)
+	s.registerIntegrationRoutes(r)
+	s.registerMetaRoutes(r)
 
 	return r
 }
 
+// registerMetaRoutes serves the SDK descriptors of the routes registered before
+// it, so it has to come last.
+func (s *Server) registerMetaRoutes(r *mux.Router) {
+	mh := handlers.NewSDKHandler(func() (*sdk.Bundle, error) {
+		return sdk.Build(Version, sdkRoutes(r), sdkOperations)
+	})
+	r.HandleFunc("/api/meta/sdk", mh.GetIndex).Methods("GET", "OPTIONS")
+	r.HandleFunc("/api/meta/sdk/{version}/{file}", mh.GetArtifact).Methods("GET", "OPTIONS")
+}
+
+// queueDepths reports the in-process background queues shown on the status page.
+func (s *Server) queueDepths() map[string]models.QueueDepth {
+	return map[string]models.QueueDepth{
+		"activity_webhooks":  s.activityWebhooks.QueueDepth(),
+		"content_extraction": s.contentService.QueueDepth(),
+		"imports":            s.importJobService.QueueDepth(),
+		"shadow":             middlewares.ShadowQueueDepth(),
+		"thumbnails":         s.thumbnailService.QueueDepth(),
+	}
+}
+
+// metricsHandler serves the Prometheus metrics, refreshing the queue depth
+// gauges first so they are current at every scrape.
+func (s *Server) metricsHandler() http.Handler {
+	metrics := promhttp.Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, depth := range s.queueDepths() {
+			utils.BackgroundQueueInFlight.WithLabelValues(name).Set(float64(depth.InFlight))
+			utils.BackgroundQueueCapacity.WithLabelValues(name).Set(float64(depth.Capacity))
+		}
+		metrics.ServeHTTP(w, r)
+	})
+}
+
 func (s *Server) registerBookmarkRoutes(r *mux.Router) {
 	bh := handlers.NewBookmarksHandler(s.bookmarkService)
+	bch := handlers.NewBookmarkContentHandler(s.contentService)
+	th := handlers.NewThumbnailHandler(s.thumbnailService)
+	hh := handlers.NewHighlightHandler(s.highlightService)
+	sh := handlers.NewShareHandler(s.shareService)
 
 	r.Handle("/api/bookmarks", middlewares.AuthMiddleware(http.HandlerFunc(bh.GetBookmarks))).Methods("GET", "OPTIONS")
 	r.Handle("/api/bookmarks", middlewares.AuthMiddleware(http.HandlerFunc(bh.AddBookmark))).Methods("POST", "OPTIONS")
+	r.Handle("/api/bookmarks/lite", middlewares.AuthMiddleware(http.HandlerFunc(bh.GetBookmarksLite))).Methods("GET", "OPTIONS")
+	r.Handle("/api/bookmarks/search", middlewares.AuthMiddleware(http.HandlerFunc(bh.SearchBookmarks))).Methods("GET", "OPTIONS")
+	r.Handle("/api/bookmarks/tag-suggestions", middlewares.AuthMiddleware(http.HandlerFunc(bh.SuggestTags))).Methods("GET", "OPTIONS")
+	r.Handle("/api/bookmarks/quick-save", middlewares.AuthMiddleware(http.HandlerFunc(handlers.NewQuickSaveHandler(s.quickSaveService).QuickSave))).Methods("POST", "OPTIONS")
+	r.Handle("/api/bookmarks/batch-create", middlewares.AuthMiddleware(http.HandlerFunc(bh.BatchCreateBookmarks))).Methods("POST", "OPTIONS")
+	r.Handle("/api/bookmarks/bulk-tag", middlewares.AuthMiddleware(http.HandlerFunc(bh.BulkTagBookmarks))).Methods("POST", "OPTIONS")
+	r.Handle("/api/bookmarks/duplicates", middlewares.AuthMiddleware(http.HandlerFunc(bh.GetDuplicateBookmarks))).Methods("GET", "OPTIONS")
 	r.Handle("/api/bookmarks/{id}", middlewares.AuthMiddleware(http.HandlerFunc(bh.GetBookmarkByID))).Methods("GET", "OPTIONS")
 	r.Handle("/api/bookmarks/{id}", middlewares.AuthMiddleware(http.HandlerFunc(bh.DeleteBookmark))).Methods("DELETE", "OPTIONS")
 	r.Handle("/api/bookmarks/{id}", middlewares.AuthMiddleware(http.HandlerFunc(bh.UpdateBookmark))).Methods("PUT", "OPTIONS")
+	r.Handle("/api/bookmarks/{id}/highlights", middlewares.AuthMiddleware(http.HandlerFunc(hh.GetHighlights))).Methods("GET", "OPTIONS")
+	r.Handle("/api/bookmarks/{id}/highlights", middlewares.AuthMiddleware(http.HandlerFunc(hh.AddHighlight))).Methods("POST", "OPTIONS")
+	r.Handle("/api/bookmarks/{id}/highlights/{highlightId}", middlewares.AuthMiddleware(http.HandlerFunc(hh.UpdateHighlight))).Methods("PATCH", "OPTIONS")
+	r.Handle("/api/bookmarks/{id}/highlights/{highlightId}", middlewares.AuthMiddleware(http.HandlerFunc(hh.DeleteHighlight))).Methods("DELETE", "OPTIONS")
+	r.Handle("/api/bookmarks/{id}/favorite", middlewares.AuthMiddleware(http.HandlerFunc(bh.FavoriteBookmark))).Methods("POST", "OPTIONS")
+	r.Handle("/api/bookmarks/{id}/favorite", middlewares.AuthMiddleware(http.HandlerFunc(bh.UnfavoriteBookmark))).Methods("DELETE", "OPTIONS")
+	r.Handle("/api/bookmarks/{id}/merge", middlewares.AuthMiddleware(http.HandlerFunc(bh.MergeBookmark))).Methods("POST", "OPTIONS")
+	r.Handle("/api/bookmarks/{id}/content", middlewares.AuthMiddleware(http.HandlerFunc(bch.GetContent))).Methods("GET", "OPTIONS")
+	r.Handle("/api/bookmarks/{id}/thumbnail", middlewares.AuthMiddleware(http.HandlerFunc(th.GetThumbnail))).Methods("GET", "OPTIONS")
+	r.Handle("/api/bookmarks/{id}/share", middlewares.AuthMiddleware(http.HandlerFunc(sh.CreateShare))).Methods("POST", "OPTIONS")
+	r.Handle("/api/bookmarks/{id}/shares", middlewares.AuthMiddleware(http.HandlerFunc(sh.ListShares))).Methods("GET", "OPTIONS")
+	r.Handle("/api/bookmarks/{id}/shares/{shareId}", middlewares.AuthMiddleware(http.HandlerFunc(sh.RevokeShare))).Methods("DELETE", "OPTIONS")
+	// Share links are public; the token is the only credential.
+	r.HandleFunc("/api/shared/{token}", sh.ViewShare).Methods("GET", "OPTIONS")
 }
 
 func (s *Server) registerAuthRoutes(r *mux.Router) {
@@ -50,10 +125,14 @@ func (s *Server) registerAuthRoutes(r *mux.Router) {
 
 	r.HandleFunc("/api/auth/register", uh.Register).Methods("POST", "OPTIONS")
 	r.HandleFunc("/api/auth/login", uh.Login).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/auth/introspect", handlers.NewIntrospectionHandler(s.introspection).Introspect).Methods("POST", "OPTIONS")
 	r.HandleFunc("/api/auth/forgot-password", ah.ForgotPasswordHandler).Methods("POST", "OPTIONS")
 	r.Handle("/api/me", middlewares.AuthMiddleware(http.HandlerFunc(uh.GetMyProfile))).Methods("GET", "OPTIONS")
 	r.Handle("/api/me", middlewares.AuthMiddleware(http.HandlerFunc(uh.UpdateMyProfile))).Methods("PATCH", "PUT", "OPTIONS")
 	r.Handle("/api/me", middlewares.AuthMiddleware(http.HandlerFunc(uh.DeleteMyProfile))).Methods("DELETE", "OPTIONS")
+	r.Handle("/api/me/settings", middlewares.AuthMiddleware(http.HandlerFunc(uh.GetMySettings))).Methods("GET", "OPTIONS")
+	r.Handle("/api/me/settings", middlewares.AuthMiddleware(http.HandlerFunc(uh.UpdateMySettings))).Methods("PATCH", "PUT", "OPTIONS")
+	r.Handle("/api/me/limits", middlewares.AuthMiddleware(http.HandlerFunc(handlers.NewLimitsHandler(s.limitsService, middlewares.RateLimitStatus).GetMyLimits))).Methods("GET", "OPTIONS")
 
 	r.HandleFunc("/api/auth/{provider}", ah.ProviderAuth).Methods("GET", "OPTIONS")
 	r.HandleFunc("/api/auth/{provider}/callback", ah.ProviderCallback).Methods("GET", "OPTIONS")
@@ -66,13 +145,40 @@ func (s *Server) registerCategoryRoutes(r *mux.Router) {
 	ch := handlers.NewCategoryHandler(s.categoryService)
 	r.Handle("/api/categories", middlewares.AuthMiddleware(http.HandlerFunc(ch.AddCategory))).Methods("POST", "OPTIONS")
 	r.Handle("/api/categories", middlewares.AuthMiddleware(http.HandlerFunc(ch.GetCategories))).Methods("GET", "OPTIONS")
+	r.Handle("/api/categories/colors", middlewares.AuthMiddleware(http.HandlerFunc(ch.GetCategoryColors))).Methods("GET", "OPTIONS")
+	r.Handle("/api/categories/by-slug/{slug}", middlewares.AuthMiddleware(http.HandlerFunc(ch.GetCategoryBySlug))).Methods("GET", "OPTIONS")
 	r.Handle("/api/categories/{id}", middlewares.AuthMiddleware(http.HandlerFunc(ch.GetCategoryByID))).Methods("GET", "OPTIONS")
 	r.Handle("/api/categories/{id}", middlewares.AuthMiddleware(http.HandlerFunc(ch.DeleteCategory))).Methods("DELETE", "OPTIONS")
 	r.Handle("/api/categories/{id}", middlewares.AuthMiddleware(http.HandlerFunc(ch.UpdateCategory))).Methods("PUT", "OPTIONS")
+	r.Handle("/api/categories/{id}/icon", middlewares.AuthMiddleware(http.HandlerFunc(ch.UploadCategoryIcon))).Methods("PUT", "OPTIONS")
+	r.Handle("/api/categories/{id}/icon", middlewares.AuthMiddleware(http.HandlerFunc(ch.GetCategoryIcon))).Methods("GET", "OPTIONS")
+	r.Handle("/api/categories/{id}/icon", middlewares.AuthMiddleware(http.HandlerFunc(ch.DeleteCategoryIcon))).Methods("DELETE", "OPTIONS")
 }
 
 func (s *Server) registerCollectionRoutes(r *mux.Router) {
 	clh := handlers.NewCollectionHandler(s.collectionService)
+	cth := handlers.NewCollectionTemplateHandler(s.templateService)
+	nh := handlers.NewNewsletterHandler(s.newsletterService)
+	fh := handlers.NewCollectionFeedHandler(s.collectionFeeds)
+	r.Handle("/api/collections/templates", middlewares.AuthMiddleware(http.HandlerFunc(cth.ListTemplates))).Methods("GET", "OPTIONS")
+	r.Handle("/api/collections/templates/{id}", middlewares.AuthMiddleware(http.HandlerFunc(cth.DeleteTemplate))).Methods("DELETE", "OPTIONS")
+	r.Handle("/api/collections/by-slug/{slug}", middlewares.AuthMiddleware(http.HandlerFunc(clh.GetCollectionBySlug))).Methods("GET", "OPTIONS")
+	r.Handle("/api/collections/from-template", middlewares.AuthMiddleware(http.HandlerFunc(cth.CreateFromTemplate))).Methods("POST", "OPTIONS")
+	r.Handle("/api/collections/{id}/auto-archive/preview", middlewares.AuthMiddleware(http.HandlerFunc(clh.PreviewAutoArchive))).Methods("GET", "OPTIONS")
+	r.Handle("/api/collections/{id}/stats", middlewares.AuthMiddleware(http.HandlerFunc(clh.GetCollectionStats))).Methods("GET", "OPTIONS")
+	r.Handle("/api/collections/{id}/template", middlewares.AuthMiddleware(http.HandlerFunc(cth.SaveAsTemplate))).Methods("POST", "OPTIONS")
+	r.Handle("/api/collections/{id}/newsletter", middlewares.AuthMiddleware(http.HandlerFunc(nh.SendNewsletter))).Methods("POST", "OPTIONS")
+	r.Handle("/api/collections/{id}/newsletter/sends", middlewares.AuthMiddleware(http.HandlerFunc(nh.GetSends))).Methods("GET", "OPTIONS")
+	r.Handle("/api/collections/{id}/newsletter/subscribers", middlewares.AuthMiddleware(http.HandlerFunc(nh.GetSubscribers))).Methods("GET", "OPTIONS")
+	r.Handle("/api/collections/{id}/newsletter/subscribers", middlewares.AuthMiddleware(http.HandlerFunc(nh.AddSubscriber))).Methods("POST", "OPTIONS")
+	r.Handle("/api/collections/{id}/newsletter/subscribers/{subscriberId}", middlewares.AuthMiddleware(http.HandlerFunc(nh.RemoveSubscriber))).Methods("DELETE", "OPTIONS")
+	r.Handle("/api/collections/{id}/feeds", middlewares.AuthMiddleware(http.HandlerFunc(fh.GetFeeds))).Methods("GET", "OPTIONS")
+	r.Handle("/api/collections/{id}/feeds", middlewares.AuthMiddleware(http.HandlerFunc(fh.AddFeed))).Methods("POST", "OPTIONS")
+	r.Handle("/api/collections/{id}/feeds/{feedId}", middlewares.AuthMiddleware(http.HandlerFunc(fh.UpdateFeed))).Methods("PATCH", "OPTIONS")
+	r.Handle("/api/collections/{id}/feeds/{feedId}", middlewares.AuthMiddleware(http.HandlerFunc(fh.RemoveFeed))).Methods("DELETE", "OPTIONS")
+	// Readers confirm and unsubscribe with the tokens from the links they are emailed.
+	r.HandleFunc("/api/newsletter/confirm", nh.Confirm).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/newsletter/unsubscribe", nh.Unsubscribe).Methods("POST", "OPTIONS")
 	r.Handle("/api/collections", middlewares.AuthMiddleware(http.HandlerFunc(clh.AddCollection))).Methods("POST", "OPTIONS")
 	r.Handle("/api/collections", middlewares.AuthMiddleware(http.HandlerFunc(clh.GetCollections))).Methods("GET", "OPTIONS")
 	r.Handle("/api/collections/{id}", middlewares.AuthMiddleware(http.HandlerFunc(clh.GetCollection))).Methods("GET", "OPTIONS")
@@ -90,10 +196,12 @@ func (s *Server) registerTagRoutes(r *mux.Router) {
 }
 
 func (s *Server) registerAgentRoutes(r *mux.Router) {
-	ah := handlers.NewAgentHandler(s.agentService)
+	ah := handlers.NewAgentHandler(s.agentService, s.tagSubscriptions)
 	r.Handle("/api/agent/summarize/{id}", middlewares.AuthMiddleware(http.HandlerFunc(ah.GenerateSummary))).Methods("POST", "OPTIONS")
+	r.Handle("/api/bookmarks/{id}/summary/translate", middlewares.AuthMiddleware(http.HandlerFunc(ah.TranslateSummary))).Methods("POST", "OPTIONS")
 	r.Handle("/api/agent/summarize-url", middlewares.AuthMiddleware(http.HandlerFunc(ah.SummarizeURL))).Methods("POST", "OPTIONS")
 	r.Handle("/api/agent/suggestions", middlewares.AuthMiddleware(http.HandlerFunc(ah.GenerateAISuggestions))).Methods("GET", "OPTIONS")
+	r.Handle("/api/agent/feedback", middlewares.AuthMiddleware(http.HandlerFunc(ah.RecordFeedback))).Methods("POST", "OPTIONS")
 }
 
 func (s *Server) registerAnalyticsRoutes(r *mux.Router) {
@@ -101,6 +209,94 @@ func (s *Server) registerAnalyticsRoutes(r *mux.Router) {
 	r.Handle("/api/analytics/users/growth", middlewares.AuthMiddleware(http.HandlerFunc(s.analyticsHandlers.GetUserGrowth))).Methods("GET", "OPTIONS")
 	r.Handle("/api/analytics/bookmarks/activity", middlewares.AuthMiddleware(http.HandlerFunc(s.analyticsHandlers.GetBookmarkActivity))).Methods("GET", "OPTIONS")
 	r.Handle("/api/analytics/bookmarks/engagement", middlewares.AuthMiddleware(http.HandlerFunc(s.analyticsHandlers.GetBookmarkEngagement))).Methods("GET", "OPTIONS")
+	r.Handle("/api/analytics/bookmarks/sources", middlewares.AuthMiddleware(http.HandlerFunc(s.analyticsHandlers.GetBookmarkSources))).Methods("GET", "OPTIONS")
 	r.Handle("/api/analytics/tags/trends", middlewares.AuthMiddleware(http.HandlerFunc(s.analyticsHandlers.GetTagTrends))).Methods("GET", "OPTIONS")
 	r.Handle("/api/analytics/trending/items", middlewares.AuthMiddleware(http.HandlerFunc(s.analyticsHandlers.GetTrendingItems))).Methods("GET", "OPTIONS")
+	r.Handle("/api/trending/domains", middlewares.AuthMiddleware(http.HandlerFunc(s.analyticsHandlers.GetTrendingDomains))).Methods("GET", "OPTIONS")
+
+	// Admin analytics additionally require the admin role
+	r.Handle("/api/admin/analytics/ai", middlewares.AuthMiddleware(middlewares.AdminMiddleware(http.HandlerFunc(s.analyticsHandlers.GetAIStats)))).Methods("GET", "OPTIONS")
+}
+
+func (s *Server) registerAPIKeyRoutes(r *mux.Router) {
+	akh := handlers.NewAPIKeyHandler(s.apiKeyService)
+	r.Handle("/api/me/api-keys", middlewares.AuthMiddleware(http.HandlerFunc(akh.CreateAPIKey))).Methods("POST", "OPTIONS")
+	r.Handle("/api/me/api-keys", middlewares.AuthMiddleware(http.HandlerFunc(akh.GetAPIKeys))).Methods("GET", "OPTIONS")
+	r.Handle("/api/me/api-keys/{id}", middlewares.AuthMiddleware(http.HandlerFunc(akh.UpdateAPIKey))).Methods("PATCH", "PUT", "OPTIONS")
+	r.Handle("/api/me/api-keys/{id}", middlewares.AuthMiddleware(http.HandlerFunc(akh.DeleteAPIKey))).Methods("DELETE", "OPTIONS")
+}
+
+func (s *Server) registerImpersonationRoutes(r *mux.Router) {
+	ih := handlers.NewImpersonationHandler(s.impersonations, s.auditService)
+	r.Handle("/api/admin/impersonations", middlewares.AuthMiddleware(middlewares.AdminMiddleware(http.HandlerFunc(ih.RequestImpersonation)))).Methods("POST", "OPTIONS")
+	r.Handle("/api/admin/impersonations", middlewares.AuthMiddleware(middlewares.AdminMiddleware(http.HandlerFunc(ih.ListImpersonations)))).Methods("GET", "OPTIONS")
+	r.Handle("/api/admin/impersonations/{id}/token", middlewares.AuthMiddleware(middlewares.AdminMiddleware(http.HandlerFunc(ih.IssueToken)))).Methods("POST", "OPTIONS")
+	r.Handle("/api/admin/audit", middlewares.AuthMiddleware(middlewares.AdminMiddleware(http.HandlerFunc(ih.GetAuditLog)))).Methods("GET", "OPTIONS")
+
+	// The user answers a request with the token from the approval email.
+	r.HandleFunc("/api/impersonations/approve", ih.ApproveImpersonation).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/impersonations/deny", ih.DenyImpersonation).Methods("POST", "OPTIONS")
+	r.Handle("/api/me/impersonations", middlewares.AuthMiddleware(http.HandlerFunc(ih.GetMyImpersonations))).Methods("GET", "OPTIONS")
+	r.Handle("/api/me/impersonations/{id}", middlewares.AuthMiddleware(http.HandlerFunc(ih.RevokeImpersonation))).Methods("DELETE", "OPTIONS")
+
+	sh := handlers.NewSecurityHandler(s.auditService)
+	r.Handle("/api/me/security/access-log", middlewares.AuthMiddleware(http.HandlerFunc(sh.GetAccessLog))).Methods("GET", "OPTIONS")
+}
+
+func (s *Server) registerErasureRoutes(r *mux.Router) {
+	eh := handlers.NewErasureHandler(s.erasureService)
+	r.Handle("/api/admin/users/{id}/erase", middlewares.AuthMiddleware(middlewares.AdminMiddleware(http.HandlerFunc(eh.EraseUser)))).Methods("POST", "OPTIONS")
+	r.Handle("/api/admin/erasures", middlewares.AuthMiddleware(middlewares.AdminMiddleware(http.HandlerFunc(eh.ListErasures)))).Methods("GET", "OPTIONS")
+	r.Handle("/api/admin/erasures/{id}", middlewares.AuthMiddleware(middlewares.AdminMiddleware(http.HandlerFunc(eh.GetErasure)))).Methods("GET", "OPTIONS")
+}
+
+func (s *Server) registerExportRoutes(r *mux.Router) {
+	eh := handlers.NewExportHandler(s.exportService)
+	r.Handle("/api/export/markly", middlewares.AuthMiddleware(http.HandlerFunc(eh.ExportMarkly))).Methods("GET", "OPTIONS")
+	r.Handle("/api/export/markdown", middlewares.AuthMiddleware(http.HandlerFunc(eh.ExportMarkdown))).Methods("GET", "OPTIONS")
+	r.Handle("/api/import/markly", middlewares.AuthMiddleware(http.HandlerFunc(eh.ImportMarkly))).Methods("POST", "OPTIONS")
+	r.Handle("/api/import/csv", middlewares.AuthMiddleware(http.HandlerFunc(eh.ImportCSV))).Methods("POST", "OPTIONS")
+
+	ih := handlers.NewImportJobHandler(s.importJobService)
+	r.Handle("/api/import/hackernews", middlewares.AuthMiddleware(http.HandlerFunc(ih.ImportHackerNews))).Methods("POST", "OPTIONS")
+	r.Handle("/api/import/reddit", middlewares.AuthMiddleware(http.HandlerFunc(ih.ImportReddit))).Methods("POST", "OPTIONS")
+	r.Handle("/api/import/jobs/{id}", middlewares.AuthMiddleware(http.HandlerFunc(ih.GetImportJob))).Methods("GET", "OPTIONS")
+	r.Handle("/api/imports", middlewares.AuthMiddleware(http.HandlerFunc(ih.ListImports))).Methods("GET", "OPTIONS")
+	r.Handle("/api/imports/{id}/errors.csv", middlewares.AuthMiddleware(http.HandlerFunc(ih.GetImportErrors))).Methods("GET", "OPTIONS")
+}
+
+func (s *Server) registerInstanceRoutes(r *mux.Router) {
+	ih := handlers.NewInstanceHandler(s.instanceService)
+	r.Handle("/api/admin/instance/settings", middlewares.AuthMiddleware(middlewares.AdminMiddleware(http.HandlerFunc(ih.GetSettings)))).Methods("GET", "OPTIONS")
+	r.Handle("/api/admin/instance/settings", middlewares.AuthMiddleware(middlewares.AdminMiddleware(http.HandlerFunc(ih.UpdateSettings)))).Methods("PATCH", "OPTIONS")
+
+	rh := handlers.NewRetentionHandler(s.retentionService)
+	r.Handle("/api/admin/retention", middlewares.AuthMiddleware(middlewares.AdminMiddleware(http.HandlerFunc(rh.GetStatus)))).Methods("GET", "OPTIONS")
+	r.Handle("/api/admin/retention/purge", middlewares.AuthMiddleware(middlewares.AdminMiddleware(http.HandlerFunc(rh.Purge)))).Methods("POST", "OPTIONS")
+}
+
+func (s *Server) registerInviteRoutes(r *mux.Router) {
+	ih := handlers.NewInviteHandler(s.inviteService)
+	r.Handle("/api/me/invites", middlewares.AuthMiddleware(http.HandlerFunc(ih.GetInvites))).Methods("GET", "OPTIONS")
+	r.Handle("/api/me/invites", middlewares.AuthMiddleware(http.HandlerFunc(ih.CreateInvite))).Methods("POST", "OPTIONS")
+	r.Handle("/api/me/invites/{id}", middlewares.AuthMiddleware(http.HandlerFunc(ih.DeleteInvite))).Methods("DELETE", "OPTIONS")
+}
+
+func (s *Server) registerTagSubscriptionRoutes(r *mux.Router) {
+	th := handlers.NewTagSubscriptionHandler(s.tagSubscriptions)
+	r.Handle("/api/me/tag-subscriptions", middlewares.AuthMiddleware(http.HandlerFunc(th.GetSubscriptions))).Methods("GET", "OPTIONS")
+	r.Handle("/api/me/tag-subscriptions", middlewares.AuthMiddleware(http.HandlerFunc(th.CreateSubscription))).Methods("POST", "OPTIONS")
+	r.Handle("/api/me/tag-subscriptions/{id}", middlewares.AuthMiddleware(http.HandlerFunc(th.UpdateSubscription))).Methods("PATCH", "OPTIONS")
+	r.Handle("/api/me/tag-subscriptions/{id}", middlewares.AuthMiddleware(http.HandlerFunc(th.DeleteSubscription))).Methods("DELETE", "OPTIONS")
+}
+
+func (s *Server) registerIntegrationRoutes(r *mux.Router) {
+	gh := handlers.NewGitHubHandler(s.githubService)
+	r.Handle("/api/integrations/github", middlewares.AuthMiddleware(http.HandlerFunc(gh.GetStatus))).Methods("GET", "OPTIONS")
+	r.Handle("/api/integrations/github", middlewares.AuthMiddleware(http.HandlerFunc(gh.Disconnect))).Methods("DELETE", "OPTIONS")
+	r.Handle("/api/integrations/github/connect", middlewares.AuthMiddleware(http.HandlerFunc(gh.Connect))).Methods("POST", "OPTIONS")
+	r.Handle("/api/integrations/github/sync", middlewares.AuthMiddleware(http.HandlerFunc(gh.Sync))).Methods("POST", "OPTIONS")
+	r.Handle("/api/integrations/github/markdown", middlewares.AuthMiddleware(http.HandlerFunc(gh.PushMarkdown))).Methods("POST", "OPTIONS")
+	// GitHub redirects the browser here; the state parameter identifies the user.
+	r.HandleFunc("/api/integrations/github/callback", gh.Callback).Methods("GET", "OPTIONS")
 }
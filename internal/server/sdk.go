@@ -0,0 +1,151 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"markly/internal/models"
+	"markly/internal/sdk"
+)
+
+// message is the body of the endpoints that only confirm what they did.
+type message struct {
+	Message string `json:"message"`
+}
+
+// token is the body of a successful login.
+type token struct {
+	Token string `json:"token"`
+}
+
+// suggestedTags is the body of the tag suggestions endpoint.
+type suggestedTags struct {
+	Tags []string `json:"tags"`
+}
+
+// sdkOperations types the routes clients call so the SDK descriptors carry
+// their bodies. Routes left out are still listed, with an untyped response; add
+// an entry when a handler starts exchanging a new model.
+var sdkOperations = map[sdk.Route]sdk.Operation{
+	{Method: "GET", Path: "/status"}:       {Summary: "Get service status", Response: models.Status{}, Public: true},
+	{Method: "GET", Path: "/api/meta/sdk"}: {Summary: "List SDK descriptors", Response: models.SDKIndex{}, Public: true},
+
+	{Method: "POST", Path: "/api/auth/register"}:               {Summary: "Register", Request: models.RegisterRequest{}, Response: models.User{}, Status: http.StatusCreated, Public: true},
+	{Method: "POST", Path: "/api/auth/login"}:                  {Summary: "Log in", Request: models.Login{}, Response: token{}, Public: true},
+	{Method: "POST", Path: "/api/auth/introspect"}:             {Summary: "Introspect a token", Request: models.IntrospectionRequest{}, Response: models.TokenIntrospection{}, Public: true},
+	{Method: "GET", Path: "/api/me"}:                           {Summary: "Get my profile", Response: models.User{}},
+	{Method: "PATCH", Path: "/api/me"}:                         {Summary: "Update my profile", Request: models.UserProfileUpdate{}, Response: models.User{}},
+	{Method: "PUT", Path: "/api/me"}:                           {Summary: "Update my profile", Request: models.UserProfileUpdate{}, Response: models.User{}},
+	{Method: "DELETE", Path: "/api/me"}:                        {Summary: "Delete my account", Status: http.StatusNoContent},
+	{Method: "GET", Path: "/api/me/settings"}:                  {Summary: "Get my settings", Response: models.UserSettings{}},
+	{Method: "PATCH", Path: "/api/me/settings"}:                {Summary: "Update my settings", Request: models.UserSettingsUpdate{}, Response: models.UserSettings{}},
+	{Method: "PUT", Path: "/api/me/settings"}:                  {Summary: "Update my settings", Request: models.UserSettingsUpdate{}, Response: models.UserSettings{}},
+	{Method: "GET", Path: "/api/me/limits"}:                    {Summary: "Get my limits", Response: models.LimitsStatus{}},
+	{Method: "GET", Path: "/api/me/api-keys"}:                  {Summary: "List API keys", Response: []models.APIKey{}},
+	{Method: "POST", Path: "/api/me/api-keys"}:                 {Summary: "Create an API key", Request: models.CreateAPIKeyRequest{}, Response: models.CreatedAPIKey{}, Status: http.StatusCreated},
+	{Method: "PATCH", Path: "/api/me/api-keys/{id}"}:           {Summary: "Update an API key", Request: models.APIKeyUpdate{}, Response: models.APIKey{}},
+	{Method: "PUT", Path: "/api/me/api-keys/{id}"}:             {Summary: "Update an API key", Request: models.APIKeyUpdate{}, Response: models.APIKey{}},
+	{Method: "DELETE", Path: "/api/me/api-keys/{id}"}:          {Summary: "Delete an API key", Status: http.StatusNoContent},
+	{Method: "GET", Path: "/api/me/invites"}:                   {Summary: "List my invites", Response: models.InviteOverview{}},
+	{Method: "POST", Path: "/api/me/invites"}:                  {Summary: "Create an invite", Request: models.CreateInviteRequest{}, Response: models.Invite{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/api/me/tag-subscriptions"}:         {Summary: "List tag subscriptions", Response: []models.TagSubscription{}},
+	{Method: "POST", Path: "/api/me/tag-subscriptions"}:        {Summary: "Subscribe to a tag", Request: models.TagSubscriptionRequest{}, Response: models.TagSubscription{}, Status: http.StatusCreated},
+	{Method: "PATCH", Path: "/api/me/tag-subscriptions/{id}"}:  {Summary: "Update a tag subscription", Request: models.TagSubscriptionRequest{}, Response: models.TagSubscription{}},
+	{Method: "DELETE", Path: "/api/me/tag-subscriptions/{id}"}: {Summary: "Delete a tag subscription", Status: http.StatusNoContent},
+
+	{Method: "GET", Path: "/api/bookmarks"}:                                  {Summary: "List bookmarks", Response: []models.Bookmark{}},
+	{Method: "POST", Path: "/api/bookmarks"}:                                 {Summary: "Add a bookmark", Request: models.AddBookmarkRequestBody{}, Response: models.Bookmark{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/api/bookmarks/lite"}:                             {Summary: "List bookmarks compactly", Response: []models.BookmarkLite{}},
+	{Method: "GET", Path: "/api/bookmarks/search"}:                           {Summary: "Search bookmarks", Response: []models.Bookmark{}},
+	{Method: "GET", Path: "/api/bookmarks/tag-suggestions"}:                  {Summary: "Suggest tags for a URL", Response: suggestedTags{}},
+	{Method: "POST", Path: "/api/bookmarks/quick-save"}:                      {Summary: "Quick-save from the extension", Request: models.QuickSaveRequest{}, Response: models.Bookmark{}, Status: http.StatusCreated},
+	{Method: "POST", Path: "/api/bookmarks/batch-create"}:                    {Summary: "Create bookmarks in a batch", Request: []models.AddBookmarkRequestBody{}, Response: models.BatchCreateResult{}},
+	{Method: "POST", Path: "/api/bookmarks/bulk-tag"}:                        {Summary: "Tag bookmarks in bulk", Request: models.BulkTagRequest{}, Response: models.BulkTagResult{}},
+	{Method: "GET", Path: "/api/bookmarks/duplicates"}:                       {Summary: "List duplicate bookmarks", Response: []models.DuplicateGroup{}},
+	{Method: "GET", Path: "/api/bookmarks/{id}"}:                             {Summary: "Get a bookmark", Response: models.Bookmark{}},
+	{Method: "PUT", Path: "/api/bookmarks/{id}"}:                             {Summary: "Update a bookmark", Request: models.UpdateBookmarkRequestBody{}, Response: models.Bookmark{}},
+	{Method: "DELETE", Path: "/api/bookmarks/{id}"}:                          {Summary: "Delete a bookmark", Status: http.StatusNoContent},
+	{Method: "POST", Path: "/api/bookmarks/{id}/favorite"}:                   {Summary: "Mark a bookmark as favorite", Response: models.Bookmark{}},
+	{Method: "DELETE", Path: "/api/bookmarks/{id}/favorite"}:                 {Summary: "Unmark a bookmark as favorite", Response: models.Bookmark{}},
+	{Method: "POST", Path: "/api/bookmarks/{id}/merge"}:                      {Summary: "Merge a bookmark into another", Request: models.MergeBookmarkRequest{}, Response: models.Bookmark{}},
+	{Method: "GET", Path: "/api/bookmarks/{id}/content"}:                     {Summary: "Get a bookmark's content", Response: models.BookmarkContent{}},
+	{Method: "GET", Path: "/api/bookmarks/{id}/highlights"}:                  {Summary: "List highlights", Response: []models.Highlight{}},
+	{Method: "POST", Path: "/api/bookmarks/{id}/highlights"}:                 {Summary: "Add a highlight", Request: models.CreateHighlightRequest{}, Response: models.Highlight{}, Status: http.StatusCreated},
+	{Method: "PATCH", Path: "/api/bookmarks/{id}/highlights/{highlightId}"}:  {Summary: "Update a highlight", Request: models.HighlightUpdate{}, Response: models.Highlight{}},
+	{Method: "DELETE", Path: "/api/bookmarks/{id}/highlights/{highlightId}"}: {Summary: "Delete a highlight", Status: http.StatusNoContent},
+	{Method: "POST", Path: "/api/bookmarks/{id}/share"}:                      {Summary: "Share a bookmark", Request: models.CreateShareRequest{}, Response: models.CreatedShare{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/api/bookmarks/{id}/shares"}:                      {Summary: "List a bookmark's shares", Response: []models.BookmarkShare{}},
+	{Method: "GET", Path: "/api/shared/{token}"}:                             {Summary: "View a shared bookmark", Response: models.SharedBookmark{}, Public: true},
+
+	{Method: "GET", Path: "/api/categories"}:                {Summary: "List categories", Response: []models.Category{}},
+	{Method: "POST", Path: "/api/categories"}:               {Summary: "Add a category", Request: models.Category{}, Response: models.Category{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/api/categories/by-slug/{slug}"}: {Summary: "Get a category by slug", Response: models.Category{}},
+	{Method: "GET", Path: "/api/categories/{id}"}:           {Summary: "Get a category", Response: models.Category{}},
+	{Method: "PUT", Path: "/api/categories/{id}"}:           {Summary: "Update a category", Request: models.CategoryUpdate{}, Response: models.Category{}},
+	{Method: "DELETE", Path: "/api/categories/{id}"}:        {Summary: "Delete a category", Status: http.StatusNoContent},
+
+	{Method: "GET", Path: "/api/collections"}:                                               {Summary: "List collections", Response: []models.Collection{}},
+	{Method: "POST", Path: "/api/collections"}:                                              {Summary: "Add a collection", Request: models.Collection{}, Response: models.Collection{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/api/collections/by-slug/{slug}"}:                                {Summary: "Get a collection by slug", Response: models.Collection{}},
+	{Method: "GET", Path: "/api/collections/{id}"}:                                          {Summary: "Get a collection", Response: models.Collection{}},
+	{Method: "PUT", Path: "/api/collections/{id}"}:                                          {Summary: "Update a collection", Request: models.CollectionUpdate{}, Response: models.Collection{}},
+	{Method: "DELETE", Path: "/api/collections/{id}"}:                                       {Summary: "Delete a collection", Status: http.StatusNoContent},
+	{Method: "GET", Path: "/api/collections/{id}/stats"}:                                    {Summary: "Get collection stats", Response: models.CollectionStats{}},
+	{Method: "GET", Path: "/api/collections/{id}/auto-archive/preview"}:                     {Summary: "Preview auto-archive", Response: models.AutoArchivePreview{}},
+	{Method: "GET", Path: "/api/collections/templates"}:                                     {Summary: "List collection templates", Response: []models.CollectionTemplate{}},
+	{Method: "POST", Path: "/api/collections/from-template"}:                                {Summary: "Create a collection from a template", Request: models.CreateFromTemplateRequest{}, Response: models.FromTemplateResult{}, Status: http.StatusCreated},
+	{Method: "POST", Path: "/api/collections/{id}/template"}:                                {Summary: "Save a collection as a template", Request: models.SaveAsTemplateRequest{}, Response: models.CollectionTemplate{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/api/collections/{id}/feeds"}:                                    {Summary: "List collection feeds", Response: []models.CollectionFeed{}},
+	{Method: "POST", Path: "/api/collections/{id}/feeds"}:                                   {Summary: "Follow a feed into a collection", Request: models.AddCollectionFeedRequest{}, Response: models.CollectionFeed{}, Status: http.StatusCreated},
+	{Method: "PATCH", Path: "/api/collections/{id}/feeds/{feedId}"}:                         {Summary: "Update a collection feed", Request: models.CollectionFeedUpdate{}, Response: models.CollectionFeed{}},
+	{Method: "DELETE", Path: "/api/collections/{id}/feeds/{feedId}"}:                        {Summary: "Stop following a feed", Status: http.StatusNoContent},
+	{Method: "POST", Path: "/api/collections/{id}/newsletter"}:                              {Summary: "Send a newsletter", Request: models.SendNewsletterRequest{}, Response: models.NewsletterSend{}, Status: http.StatusAccepted},
+	{Method: "GET", Path: "/api/collections/{id}/newsletter/sends"}:                         {Summary: "List newsletter sends", Response: []models.NewsletterSend{}},
+	{Method: "GET", Path: "/api/collections/{id}/newsletter/subscribers"}:                   {Summary: "List newsletter subscribers", Response: []models.NewsletterSubscriber{}},
+	{Method: "POST", Path: "/api/collections/{id}/newsletter/subscribers"}:                  {Summary: "Add a newsletter subscriber", Request: models.AddNewsletterSubscriberRequest{}, Response: models.NewsletterSubscriber{}, Status: http.StatusCreated},
+	{Method: "DELETE", Path: "/api/collections/{id}/newsletter/subscribers/{subscriberId}"}: {Summary: "Remove a newsletter subscriber", Status: http.StatusNoContent},
+	{Method: "POST", Path: "/api/newsletter/confirm"}:                                       {Summary: "Confirm a newsletter subscription", Request: models.NewsletterConfirmRequest{}, Response: message{}, Public: true},
+	{Method: "POST", Path: "/api/newsletter/unsubscribe"}:                                   {Summary: "Unsubscribe from a newsletter", Request: models.NewsletterUnsubscribeRequest{}, Response: message{}, Public: true},
+
+	{Method: "GET", Path: "/api/tags"}:         {Summary: "Get tags by ID", Response: []models.Tag{}},
+	{Method: "POST", Path: "/api/tags"}:        {Summary: "Add a tag", Request: models.Tag{}, Response: models.Tag{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/api/tags/user"}:    {Summary: "List my tags", Response: []models.Tag{}},
+	{Method: "PUT", Path: "/api/tags/{id}"}:    {Summary: "Update a tag", Request: models.TagUpdate{}, Response: models.Tag{}},
+	{Method: "DELETE", Path: "/api/tags/{id}"}: {Summary: "Delete a tag", Status: http.StatusNoContent},
+
+	{Method: "POST", Path: "/api/import/hackernews"}: {Summary: "Import Hacker News favorites", Request: models.HackerNewsImportRequest{}, Response: models.ImportJob{}, Status: http.StatusAccepted},
+	{Method: "GET", Path: "/api/import/jobs/{id}"}:   {Summary: "Get an import", Response: models.ImportJob{}},
+	{Method: "GET", Path: "/api/imports"}:            {Summary: "List imports", Response: []models.ImportJob{}},
+}
+
+// sdkRoutes lists the routes registered on r, without the OPTIONS preflights and
+// the routes outside the API.
+func sdkRoutes(r *mux.Router) []sdk.Route {
+	var routes []sdk.Route
+	r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil || (path != "/status" && !strings.HasPrefix(path, "/api/")) {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			if method != http.MethodOptions {
+				routes = append(routes, sdk.Route{Method: method, Path: path})
+			}
+		}
+		return nil
+	})
+	return routes
+}
+
+// BuildSDK generates the SDK descriptors of this build. Routes do not depend on
+// the configuration, so no services are needed.
+func BuildSDK() (*sdk.Bundle, error) {
+	r := (&Server{}).RegisterRoutes().(*mux.Router)
+	return sdk.Build(Version, sdkRoutes(r), sdkOperations)
+}
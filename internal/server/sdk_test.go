@@ -0,0 +1,34 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"markly/internal/sdk"
+)
+
+func TestSDKOperationsMatchRoutes(t *testing.T) {
+	registered := map[sdk.Route]bool{}
+	for _, route := range sdkRoutes((&Server{}).RegisterRoutes().(*mux.Router)) {
+		registered[route] = true
+	}
+	for route := range sdkOperations {
+		if !registered[route] {
+			t.Errorf("sdkOperations describes %s %s, which is not a registered route", route.Method, route.Path)
+		}
+	}
+	if !registered[sdk.Route{Method: "GET", Path: "/api/meta/sdk/{version}/{file}"}] {
+		t.Errorf("sdk routes should list the descriptor endpoints themselves")
+	}
+}
+
+func TestBuildSDK(t *testing.T) {
+	bundle, err := BuildSDK()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bundle.APIVersion != sdk.APIVersion || len(bundle.OpenAPI.Content) == 0 || len(bundle.TypeScript.Content) == 0 {
+		t.Errorf("incomplete bundle: version %q, %d and %d bytes", bundle.APIVersion, len(bundle.OpenAPI.Content), len(bundle.TypeScript.Content))
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"markly/internal/database"
 	"markly/internal/middlewares"
 	"markly/internal/repositories"
+	"markly/internal/scheduler"
 	"markly/internal/services"
 	"markly/internal/handlers"
 )
@@ -34,9 +35,39 @@ type Server struct {
 	authService       services.AuthService
 	otpService        services.OTPService
 	analyticsService  *services.AnalyticsService
+	apiKeyService     services.APIKeyService
+	templateService   services.CollectionTemplateService
+	contentService    services.BookmarkContentService
+	highlightService  services.HighlightService
+	auditService      services.AuditService
+	impersonations    services.ImpersonationService
+	newsletterService services.NewsletterService
+	collectionFeeds   services.CollectionFeedService
+	erasureService    services.ErasureService
+	exportService     services.ExportService
+	thumbnailService  services.ThumbnailService
+	tagSubscriptions  services.TagSubscriptionService
+	shareService      services.ShareService
+	introspection     services.IntrospectionService
+	limitsService     services.LimitsService
+	quickSaveService  services.QuickSaveService
+	githubService     services.GitHubService
+	importJobService  services.ImportJobService
+	activityWebhooks  services.ActivityWebhookService
+	instanceService   services.InstanceService
+	retentionService  services.RetentionService
+	inviteService     services.InviteService
 	analyticsHandlers *handlers.AnalyticsHandlers
+	jobs              *scheduler.Scheduler
+	jobsCtx           context.Context
+	stopJobs          context.CancelFunc
+	startedAt         time.Time
 }
 
+// Version is reported by /status. Release builds set it with
+// -ldflags "-X markly/internal/server.Version=...".
+var Version = "dev"
+
 func NewServer() *Server {
 	portStr := os.Getenv("PORT")
 	port, err := strconv.Atoi(portStr)
@@ -47,38 +78,132 @@ func NewServer() *Server {
 
 	db := database.New()
 
+	indexCtx, cancelIndexes := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := database.EnsureIndexes(indexCtx, db.Client()); err != nil {
+		log.Error().Err(err).Msg("Some database indexes could not be created")
+	}
+	cancelIndexes()
+
 	userRepo := repositories.NewUserRepository(db)
 	bookmarkRepo := repositories.NewBookmarkRepository(db)
 	categoryRepo := repositories.NewCategoryRepository(db)
 	collectionRepo := repositories.NewCollectionRepository(db)
 	tagRepo := repositories.NewTagRepository(db)
-	otpRepo := repositories.NewOTPRepository(db.Client().Database("markly"), userRepo)
+	otpRepo := repositories.NewOTPRepository(db, userRepo)
 	trendingRepo := repositories.NewTrendingRepository(db) // New: Trending Repository
+	apiKeyRepo := repositories.NewAPIKeyRepository(db)
+	dataKeyRepo := repositories.NewDataKeyRepository(db)
+	templateRepo := repositories.NewCollectionTemplateRepository(db)
+	contentRepo := repositories.NewBookmarkContentRepository(db)
+	highlightRepo := repositories.NewHighlightRepository(db)
+	aiEventRepo := repositories.NewAIEventRepository(db)
+	auditRepo := repositories.NewAuditRepository(db)
+	impersonationRepo := repositories.NewImpersonationRepository(db)
+	newsletterRepo := repositories.NewNewsletterRepository(db)
+	erasureRepo := repositories.NewErasureRepository(db)
 
-	emailService := services.NewEmailService()
-	authService := services.NewAuthService(userRepo)
-	otpService := services.NewOTPService(userRepo, otpRepo, emailService)
+	ownership := services.NewOwnership(repositories.NewOwnershipRepository(db))
+	mailer := services.NewSMTPMailer()
+	notifier := services.NewEmailNotifier(mailer)
+	summarizer := services.NewLLMSummarizer()
+	encryptionService := services.NewEncryptionService(dataKeyRepo)
+	urlService := services.NewURLService()
+	tagSuggester := services.NewTagSuggestionService(userRepo, tagRepo)
+	activityWebhooks := services.NewActivityWebhookService(userRepo, tagRepo)
+	contentService := services.NewBookmarkContentService(contentRepo, bookmarkRepo, ownership)
+	highlightService := services.NewHighlightService(highlightRepo, bookmarkRepo, ownership)
+	thumbnailRepo := repositories.NewThumbnailRepository(db)
+	inviteRepo := repositories.NewInviteRepository(db)
+	tagSubscriptionRepo := repositories.NewTagSubscriptionRepository(db)
+	collectionFeedRepo := repositories.NewCollectionFeedRepository(db)
+	thumbnailService := services.NewThumbnailService(thumbnailRepo, bookmarkRepo, ownership)
+	auditService := services.NewAuditService(auditRepo)
+	inviteService := services.NewInviteService(inviteRepo, userRepo)
+	instanceService := services.NewInstanceService(repositories.NewInstanceSettingsRepository(db), inviteService, auditService)
+	authService := services.NewAuthService(userRepo, instanceService, auditService)
+	otpService := services.NewOTPService(userRepo, otpRepo, notifier)
+	tagSubscriptionService := services.NewTagSubscriptionService(tagSubscriptionRepo, userRepo, notifier)
 	analyticsService := services.NewAnalyticsService( // New: Analytics Service
 		&userRepo,
 		&bookmarkRepo,
 		&tagRepo,
 		&trendingRepo,
+		&aiEventRepo,
+		tagSubscriptionService,
 	)
 
+	impersonationService := services.NewImpersonationService(impersonationRepo, userRepo, notifier, auditService)
+	userService := services.NewUserService(userRepo, instanceService, auditService)
+	exportService := services.NewExportService(userRepo, bookmarkRepo, tagRepo, categoryRepo, collectionRepo, highlightRepo, encryptionService, urlService, userService)
+
 	s := &Server{
 		port:              port,
+		startedAt:         time.Now(),
 		db:                db,
-		userService:       services.NewUserService(userRepo),
-		bookmarkService:   services.NewBookmarkService(bookmarkRepo, db),
-		categoryService:   services.NewCategoryService(categoryRepo),
-		collectionService: services.NewCollectionService(collectionRepo),
-		tagService:        services.NewTagService(tagRepo),
-		agentService:      services.NewAgentService(bookmarkRepo, categoryRepo, collectionRepo, tagRepo),
+		userService:       userService,
+		bookmarkService:   services.NewBookmarkService(bookmarkRepo, categoryRepo, tagRepo, db, encryptionService, urlService, tagSuggester, contentService, highlightService, activityWebhooks, ownership),
+		categoryService:   services.NewCategoryService(categoryRepo, repositories.NewCategoryIconRepository(db), ownership),
+		collectionService: services.NewCollectionService(collectionRepo, bookmarkRepo, ownership),
+		tagService:        services.NewTagService(tagRepo, ownership),
+		agentService:      services.NewAgentService(bookmarkRepo, categoryRepo, collectionRepo, tagRepo, highlightRepo, aiEventRepo, userRepo, summarizer),
 		authService:       authService,
 		otpService:        otpService,
 		analyticsService:  analyticsService, // New: Assign Analytics Service
 		analyticsHandlers: handlers.NewAnalyticsHandlers(analyticsService), // New: Assign Analytics Handlers
+		apiKeyService:     services.NewAPIKeyService(apiKeyRepo, auditService),
+		contentService:    contentService,
+		highlightService:  highlightService,
+		auditService:      auditService,
+		impersonations:    impersonationService,
+		newsletterService: services.NewNewsletterService(newsletterRepo, collectionRepo, bookmarkRepo, userRepo, mailer, summarizer, thumbnailService, encryptionService),
+		collectionFeeds:   services.NewCollectionFeedService(collectionFeedRepo, bookmarkRepo, collectionRepo, urlService),
+		erasureService:    services.NewErasureService(erasureRepo, userRepo, auditService),
+		exportService:     exportService,
+		templateService:   services.NewCollectionTemplateService(templateRepo, collectionRepo, tagRepo, categoryRepo, bookmarkRepo, urlService),
+		instanceService:   instanceService,
+		retentionService:  services.NewRetentionService(repositories.NewRetentionRepository(db), instanceService, auditService),
+		inviteService:     inviteService,
+		thumbnailService:  thumbnailService,
+		tagSubscriptions:  tagSubscriptionService,
+		shareService:      services.NewShareService(repositories.NewShareRepository(db), bookmarkRepo, contentRepo, ownership),
+		introspection:     services.NewIntrospectionService(impersonationService),
+		limitsService:     services.NewLimitsService(thumbnailRepo, collectionRepo, inviteRepo, tagSubscriptionRepo, collectionFeedRepo),
+		githubService:     services.NewGitHubService(repositories.NewGitHubRepository(db), bookmarkRepo, collectionRepo, tagSuggester, encryptionService, urlService, exportService),
+		importJobService:  services.NewImportJobService(repositories.NewImportJobRepository(db), bookmarkRepo, tagRepo, userRepo, urlService),
+		activityWebhooks:  activityWebhooks,
 	}
+	s.quickSaveService = services.NewQuickSaveService(s.bookmarkService, s.agentService)
+
+	migrateCtx, cancelMigrate := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := s.categoryService.MigrateEmojis(migrateCtx); err != nil {
+		log.Error().Err(err).Msg("Failed to migrate category emojis")
+	}
+	if err := s.categoryService.MigrateSlugs(migrateCtx); err != nil {
+		log.Error().Err(err).Msg("Failed to migrate category slugs")
+	}
+	if err := s.collectionService.MigrateSlugs(migrateCtx); err != nil {
+		log.Error().Err(err).Msg("Failed to migrate collection slugs")
+	}
+	if err := s.newsletterService.MigrateSubscribers(migrateCtx); err != nil {
+		log.Error().Err(err).Msg("Failed to migrate newsletter subscribers")
+	}
+	cancelMigrate()
+
+	middlewares.SetAPIKeyService(s.apiKeyService)
+	middlewares.SetUserService(s.userService)
+	middlewares.SetImpersonationService(s.impersonations)
+
+	s.jobs = scheduler.New()
+	s.jobs.Register("auto-archive", durationFromEnv("AUTO_ARCHIVE_INTERVAL", time.Hour), s.collectionService.RunAutoArchive)
+	s.jobs.Register("trending-domains", durationFromEnv("TRENDING_INTERVAL", time.Hour), s.analyticsService.RefreshTrendingDomains)
+	s.jobs.Register("metadata-backfill", durationFromEnv("METADATA_BACKFILL_INTERVAL", 10*time.Minute), s.contentService.BackfillMetadata)
+	s.jobs.Register("search-index", durationFromEnv("SEARCH_INDEX_INTERVAL", 10*time.Minute), s.bookmarkService.BackfillSearchIndex)
+	s.jobs.Register("collection-feeds", durationFromEnv("FEED_FETCH_INTERVAL", 5*time.Minute), s.collectionFeeds.FetchDue)
+	s.jobs.Register("github-stars-sync", durationFromEnv("GITHUB_SYNC_INTERVAL", 6*time.Hour), s.githubService.SyncAll)
+	s.jobs.Register("import-jobs-recovery", durationFromEnv("IMPORT_JOB_RECOVERY_INTERVAL", 10*time.Minute), s.importJobService.FailInterrupted)
+	s.jobs.Register("usage-metrics", durationFromEnv("USAGE_METRICS_INTERVAL", 5*time.Minute), s.analyticsService.RefreshUsageMetrics)
+	s.jobs.Register("retention-purge", durationFromEnv("RETENTION_PURGE_INTERVAL", time.Hour), s.retentionService.PurgeExpired)
+	s.jobsCtx, s.stopJobs = context.WithCancel(context.Background())
 
 	services.InitializeGoth()
 
@@ -95,8 +220,23 @@ func NewServer() *Server {
 	return s
 }
 
+// durationFromEnv reads a duration such as "1h" from the environment, falling back to def.
+func durationFromEnv(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Warn().Str(key, v).Dur("default", def).Msg("Invalid duration in environment, using default")
+		return def
+	}
+	return d
+}
+
 func (s *Server) Start() error {
 	log.Info().Int("port", s.port).Msg("Starting server")
+	s.jobs.Start(s.jobsCtx)
 	return s.httpServer.ListenAndServe()
 }
 
@@ -108,6 +248,7 @@ func (s *Server) GracefulShutdown(done chan bool) {
 
 	log.Info().Msg("Shutting down gracefully, press Ctrl+C again to force")
 	stop()
+	s.stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -115,6 +256,7 @@ func (s *Server) GracefulShutdown(done chan bool) {
 		log.Error().Err(err).Msg("Server forced to shutdown with error")
 	}
 
+	s.jobs.Wait()
 	log.Info().Msg("Server exiting")
 	done <- true
 }
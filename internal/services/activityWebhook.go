@@ -0,0 +1,135 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/extract"
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+// ActivityWebhookService posts bookmark activity to the webhook a user enabled in
+// their settings. Deliveries are best effort: they run in the background, are not
+// retried, and are dropped while too many are in flight.
+type ActivityWebhookService interface {
+	// Send posts event for each of bookmarks, one after another, when the user has
+	// a webhook enabled.
+	Send(ctx context.Context, userID primitive.ObjectID, event string, bookmarks ...*models.Bookmark)
+	QueueDepth() models.QueueDepth
+}
+
+type activityWebhookServiceImpl struct {
+	userRepo repositories.UserRepository
+	tagRepo  repositories.TagRepository
+	client   *http.Client
+	slots    chan struct{}
+}
+
+// NewActivityWebhookService only delivers to public addresses unless
+// ACTIVITY_WEBHOOK_ALLOW_PRIVATE is "true". Redirects are not followed, so that a
+// delivery only ever goes to the configured URL; they count as failures.
+func NewActivityWebhookService(userRepo repositories.UserRepository, tagRepo repositories.TagRepository) ActivityWebhookService {
+	client := extract.NewClient(5*time.Second, os.Getenv("ACTIVITY_WEBHOOK_ALLOW_PRIVATE") == "true")
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &activityWebhookServiceImpl{
+		userRepo: userRepo,
+		tagRepo:  tagRepo,
+		client:   client,
+		slots:    make(chan struct{}, 16),
+	}
+}
+
+// Send loads the user's settings before taking a slot, so that activity of users
+// without a webhook never fills the queue. A call takes one slot however many
+// bookmarks it sends.
+func (s *activityWebhookServiceImpl) Send(ctx context.Context, userID primitive.ObjectID, event string, bookmarks ...*models.Bookmark) {
+	if len(bookmarks) == 0 {
+		return
+	}
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		log.Warn().Err(err).Str("userID", userID.Hex()).Str("event", event).Msg("Failed to load user for activity webhook")
+		utils.ActivityWebhookDeliveriesTotal.WithLabelValues("failed").Inc()
+		return
+	}
+	settings := user.Settings
+	if settings == nil || !settings.ActivityWebhookEnabled || settings.ActivityWebhookURL == "" {
+		return
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		log.Warn().Str("userID", userID.Hex()).Str("event", event).Msg("Activity webhook queue full, dropping delivery")
+		utils.ActivityWebhookDeliveriesTotal.WithLabelValues("dropped").Add(float64(len(bookmarks)))
+		return
+	}
+	type delivery struct {
+		payload models.ActivityWebhookPayload
+		tagIDs  []primitive.ObjectID
+	}
+	deliveries := make([]delivery, len(bookmarks))
+	for i, bm := range bookmarks {
+		deliveries[i] = delivery{models.ActivityWebhookPayload{Event: event, URL: bm.URL, Title: bm.Title, Tags: []string{}}, bm.TagsID}
+	}
+	go func() {
+		defer func() { <-s.slots }()
+		for _, d := range deliveries {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if err := s.deliver(ctx, userID, *settings, d.payload, d.tagIDs); err != nil {
+				log.Warn().Err(err).Str("userID", userID.Hex()).Str("event", event).Msg("Activity webhook delivery failed")
+				utils.ActivityWebhookDeliveriesTotal.WithLabelValues("failed").Inc()
+			}
+			cancel()
+		}
+	}()
+}
+
+func (s *activityWebhookServiceImpl) QueueDepth() models.QueueDepth {
+	return models.QueueDepth{InFlight: len(s.slots), Capacity: cap(s.slots)}
+}
+
+func (s *activityWebhookServiceImpl) deliver(ctx context.Context, userID primitive.ObjectID, settings models.UserSettings, payload models.ActivityWebhookPayload, tagIDs []primitive.ObjectID) error {
+	for _, id := range tagIDs {
+		tag, err := s.tagRepo.FindByID(ctx, userID, id)
+		if err != nil {
+			continue
+		}
+		payload.Tags = append(payload.Tags, tag.Name)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, settings.ActivityWebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Markly-Webhook/1.0")
+	req.Header.Set("X-Markly-Secret", settings.ActivityWebhookSecret)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	utils.ActivityWebhookDeliveriesTotal.WithLabelValues("delivered").Inc()
+	log.Debug().Str("userID", userID.Hex()).Str("event", payload.Event).Msg("Activity webhook delivered")
+	return nil
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/models"
+)
+
+func TestSendOnlyQueuesForEnabledWebhooks(t *testing.T) {
+	received := make(chan models.ActivityWebhookPayload, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload models.ActivityWebhookPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		received <- payload
+	}))
+	defer srv.Close()
+
+	enabled := &models.User{ID: primitive.NewObjectID(), Settings: &models.UserSettings{ActivityWebhookEnabled: true, ActivityWebhookURL: srv.URL}}
+	disabled := &models.User{ID: primitive.NewObjectID(), Settings: &models.UserSettings{ActivityWebhookURL: srv.URL}}
+	users := fakeErasureUsers{users: map[primitive.ObjectID]*models.User{enabled.ID: enabled, disabled.ID: disabled}}
+	s := &activityWebhookServiceImpl{userRepo: users, client: srv.Client(), slots: make(chan struct{}, 1)}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		s.Send(ctx, disabled.ID, models.ActivityBookmarkCreated, &models.Bookmark{URL: "https://example.com/"})
+		if len(s.slots) != 0 {
+			t.Fatal("a user without a webhook took a slot")
+		}
+	}
+
+	// Both bookmarks go out with the single slot.
+	s.Send(ctx, enabled.ID, models.ActivityBookmarkCreated, &models.Bookmark{URL: "https://example.com/a"}, &models.Bookmark{URL: "https://example.com/b"})
+	for _, want := range []string{"https://example.com/a", "https://example.com/b"} {
+		select {
+		case payload := <-received:
+			if payload.URL != want || payload.Event != models.ActivityBookmarkCreated {
+				t.Errorf("delivered %s of %s, want %s", payload.Event, payload.URL, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s was not delivered", want)
+		}
+	}
+}
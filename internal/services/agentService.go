@@ -2,15 +2,18 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 
 	"markly/internal/models"
 	"markly/internal/repositories"
+	"markly/internal/utils"
 )
 
 type AgentService struct {
@@ -18,20 +21,112 @@ type AgentService struct {
 	categoryRepo   repositories.CategoryRepository
 	collectionRepo repositories.CollectionRepository
 	tagRepo        repositories.TagRepository
+	highlightRepo  repositories.HighlightRepository
+	aiEventRepo    repositories.AIEventRepository
+	userRepo       repositories.UserRepository
+	summarizer     Summarizer
 }
 
+// ErrExternalAIDisabled is returned when the user has disabled sending their data to
+// external LLM providers.
+var ErrExternalAIDisabled = errors.New("external AI features are disabled in your settings")
+
 func NewAgentService(
 	bookmarkRepo repositories.BookmarkRepository,
 	categoryRepo repositories.CategoryRepository,
 	collectionRepo repositories.CollectionRepository,
 	tagRepo repositories.TagRepository,
+	highlightRepo repositories.HighlightRepository,
+	aiEventRepo repositories.AIEventRepository,
+	userRepo repositories.UserRepository,
+	summarizer Summarizer,
 ) *AgentService {
 	return &AgentService{
 		bookmarkRepo:   bookmarkRepo,
 		categoryRepo:   categoryRepo,
 		collectionRepo: collectionRepo,
 		tagRepo:        tagRepo,
+		highlightRepo:  highlightRepo,
+		aiEventRepo:    aiEventRepo,
+		userRepo:       userRepo,
+		summarizer:     summarizer,
+	}
+}
+
+// CheckExternalAI returns ErrExternalAIDisabled when the user does not allow their
+// bookmark titles and URLs to be sent to an LLM provider. It must be called before
+// every LLM request made on behalf of a user.
+func (s *AgentService) CheckExternalAI(userID primitive.ObjectID) error {
+	user, err := s.userRepo.FindByID(context.Background(), userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to load user settings for AI check")
+		return fmt.Errorf("failed to load user settings")
+	}
+	if user.Settings != nil && user.Settings.DisableExternalAI {
+		log.Debug().Str("userID", userID.Hex()).Msg("External AI disabled by user settings")
+		return ErrExternalAIDisabled
+	}
+	return nil
+}
+
+// RecordAIEvent stores an AI event for the admin statistics. Failures are only logged
+// since the statistics must never fail the request they describe.
+func (s *AgentService) RecordAIEvent(kind, action, promptVersion string, count int) {
+	event := &models.AIEvent{
+		Kind:          kind,
+		Action:        action,
+		PromptVersion: promptVersion,
+		Count:         count,
+		CreatedAt:     time.Now(),
+	}
+	if err := s.aiEventRepo.Create(context.Background(), event); err != nil {
+		log.Warn().Err(err).Str("kind", kind).Str("action", action).Msg("Failed to record AI event")
+	}
+}
+
+// RecordAIFeedback records the user accepting or dismissing AI output.
+func (s *AgentService) RecordAIFeedback(req models.AIFeedbackRequest) error {
+	if req.Kind != models.AIEventKindSuggestion && req.Kind != models.AIEventKindSummary {
+		return fmt.Errorf("invalid kind: must be 'suggestion' or 'summary'")
+	}
+	if req.Action != models.AIEventAccepted && req.Action != models.AIEventDismissed {
+		return fmt.Errorf("invalid action: must be 'accepted' or 'dismissed'")
+	}
+	if req.PromptVersion == "" {
+		return fmt.Errorf("prompt_version is required")
+	}
+	if req.Count == 0 {
+		req.Count = 1
+	}
+	if req.Count < 0 || req.Count > 50 {
+		return fmt.Errorf("invalid count: must be between 1 and 50")
 	}
+	s.RecordAIEvent(req.Kind, req.Action, req.PromptVersion, req.Count)
+	return nil
+}
+
+// Summarize summarizes a page, centered on highlights when there are any.
+func (s *AgentService) Summarize(ctx context.Context, url, title string, highlights ...string) (string, error) {
+	return s.summarizer.Summarize(ctx, url, title, highlights...)
+}
+
+// GenerateSuggestions suggests new bookmarks from the user's recent ones.
+func (s *AgentService) GenerateSuggestions(ctx context.Context, recentBookmarks []models.PromptBookmarkInfo) ([]models.AISuggestion, error) {
+	return s.summarizer.GenerateSuggestions(ctx, recentBookmarks)
+}
+
+// GetHighlightTexts returns the text of the bookmark's highlights in creation order.
+func (s *AgentService) GetHighlightTexts(userID, bookmarkID primitive.ObjectID) ([]string, error) {
+	highlights, err := s.highlightRepo.FindByBookmarks(context.Background(), userID, []primitive.ObjectID{bookmarkID})
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Msg("Failed to retrieve highlights for summary")
+		return nil, err
+	}
+	texts := make([]string, len(highlights))
+	for i, h := range highlights {
+		texts[i] = h.Text
+	}
+	return texts, nil
 }
 
 func (s *AgentService) GetBookmarkForSummary(userID, bookmarkID primitive.ObjectID) (*models.Bookmark, error) {
@@ -46,10 +141,67 @@ func (s *AgentService) GetBookmarkForSummary(userID, bookmarkID primitive.Object
 	return bookmark, nil
 }
 
-func (s *AgentService) UpdateBookmarkSummary(bookmarkID primitive.ObjectID, userID primitive.ObjectID, summary string) error {
+// TranslateSummary translates the summary of a bookmark into the language to and
+// stores the translation alongside the original. A stored translation is returned
+// as is unless refresh is set.
+func (s *AgentService) TranslateSummary(ctx context.Context, userID, bookmarkID primitive.ObjectID, to string, refresh bool) (*models.Bookmark, error) {
+	lang := utils.NormalizeLanguage(to)
+	if lang == "" {
+		return nil, fmt.Errorf("invalid language: 'to' must be a language code such as 'ar'")
+	}
+	bookmark, err := s.bookmarkRepo.FindOne(ctx, bson.M{"_id": bookmarkID, "user_id": userID})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("bookmark not found")
+		}
+		log.Error().Err(err).Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Msg("Failed to retrieve bookmark for translation")
+		return nil, fmt.Errorf("failed to retrieve bookmark")
+	}
+	if bookmark.Summary == "" {
+		return nil, fmt.Errorf("bookmark has no summary to translate")
+	}
+	if _, ok := bookmark.SummaryTranslations[lang]; ok && !refresh {
+		return bookmark, nil
+	}
+
+	translation, err := s.summarizer.TranslateSummary(ctx, bookmark.Summary, utils.LanguageName(lang))
+	if err != nil {
+		log.Error().Err(err).Str("bookmarkID", bookmarkID.Hex()).Str("lang", lang).Msg("Failed to translate bookmark summary")
+		return nil, fmt.Errorf("failed to translate summary")
+	}
+	// The summary must not have changed during the request, or the translation would
+	// be stored for a different text.
+	filter := bson.M{"_id": bookmarkID, "user_id": userID, "summary": bookmark.Summary}
+	result, err := s.bookmarkRepo.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"summary_translations." + lang: translation}})
+	if err != nil {
+		log.Error().Err(err).Str("bookmarkID", bookmarkID.Hex()).Msg("Failed to save summary translation")
+		return nil, fmt.Errorf("failed to save translation")
+	}
+	if result.MatchedCount == 0 {
+		return nil, fmt.Errorf("bookmark summary changed during translation, try again")
+	}
+
+	if bookmark.SummaryTranslations == nil {
+		bookmark.SummaryTranslations = map[string]string{}
+	}
+	bookmark.SummaryTranslations[lang] = translation
+	log.Info().Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Str("lang", lang).Msg("Translated bookmark summary")
+	return bookmark, nil
+}
+
+// UpdateBookmarkSummary stores a new summary generated from the page with the
+// given content hash. An empty contentHash, for summaries that depend on more than
+// the page, means the summary is always regenerated.
+func (s *AgentService) UpdateBookmarkSummary(bookmarkID primitive.ObjectID, userID primitive.ObjectID, summary, contentHash string) error {
 	log.Debug().Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Msg("Attempting to update bookmark summary")
 	filter := bson.M{"_id": bookmarkID, "user_id": userID}
-	update := bson.M{"$set": bson.M{"summary": summary}}
+	// The search index job picks the new summary up.
+	update := bson.M{"$set": bson.M{"summary": summary}, "$unset": bson.M{"search_grams": "", "summary_translations": ""}}
+	if contentHash != "" {
+		update["$set"].(bson.M)["summary_content_hash"] = contentHash
+	} else {
+		update["$unset"].(bson.M)["summary_content_hash"] = ""
+	}
 	_, err := s.bookmarkRepo.UpdateOne(context.Background(), filter, update)
 	if err != nil {
 		log.Error().Err(err).Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Msg("Failed to update bookmark summary")
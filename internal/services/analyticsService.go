@@ -2,13 +2,27 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"sort"
+	"strconv"
 	"time"
 
 	"markly/internal/models"
 	"markly/internal/repositories"
+	"markly/internal/utils"
 
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// defaultTrendingMinUsers is the number of distinct users that must have saved a
+	// domain before it is listed as trending. Override with TRENDING_MIN_USERS.
+	defaultTrendingMinUsers = 5
+	trendingDomainsLimit    = 50
 )
 
 type AnalyticsService struct {
@@ -16,6 +30,8 @@ type AnalyticsService struct {
 	BookmarkRepository *repositories.BookmarkRepository
 	TagRepository      *repositories.TagRepository
 	TrendingRepository *repositories.TrendingRepository
+	AIEventRepository  *repositories.AIEventRepository
+	TagSubscriptions   TagSubscriptionService
 }
 
 func NewAnalyticsService(
@@ -23,29 +39,107 @@ func NewAnalyticsService(
 	bookmarkRepo *repositories.BookmarkRepository,
 	tagRepo *repositories.TagRepository,
 	trendingRepo *repositories.TrendingRepository,
+	aiEventRepo *repositories.AIEventRepository,
+	tagSubscriptions TagSubscriptionService,
 ) *AnalyticsService {
 	return &AnalyticsService{
 		UserRepository:     userRepo,
 		BookmarkRepository: bookmarkRepo,
 		TagRepository:      tagRepo,
 		TrendingRepository: trendingRepo,
+		AIEventRepository:  aiEventRepo,
+		TagSubscriptions:   tagSubscriptions,
 	}
 }
 
-func (s *AnalyticsService) GetUserGrowth(ctx context.Context, startDate, endDate time.Time) (map[string]int, error) {
-	count, err := (*s.UserRepository).CountUsersCreatedBetween(ctx, startDate, endDate)
+// GetUserGrowth counts the users created between startDate and endDate, in total and
+// per day or week in the timezone picked by resolveTimezone.
+func (s *AnalyticsService) GetUserGrowth(ctx context.Context, userID primitive.ObjectID, startDate, endDate time.Time, interval, tz string) (*models.UserGrowth, error) {
+	series, err := s.newTimeSeries(ctx, userID, interval, tz)
+	if err != nil {
+		return nil, err
+	}
+	series.Buckets, err = (*s.UserRepository).CountUsersByInterval(ctx, startDate, endDate, series.Interval, series.Timezone)
 	if err != nil {
 		return nil, err
 	}
-	return map[string]int{"new_users": int(count)}, nil
+	return &models.UserGrowth{NewUsers: series.total(), TimeSeries: series.TimeSeries}, nil
 }
 
-func (s *AnalyticsService) GetBookmarkActivity(ctx context.Context, startDate, endDate time.Time) (map[string]int, error) {
-	count, err := (*s.BookmarkRepository).CountBookmarksCreatedBetween(ctx, startDate, endDate)
+// GetBookmarkActivity counts the bookmarks created between startDate and endDate, in
+// total and per day or week in the timezone picked by resolveTimezone.
+func (s *AnalyticsService) GetBookmarkActivity(ctx context.Context, userID primitive.ObjectID, startDate, endDate time.Time, interval, tz string) (*models.BookmarkActivity, error) {
+	series, err := s.newTimeSeries(ctx, userID, interval, tz)
 	if err != nil {
 		return nil, err
 	}
-	return map[string]int{"new_bookmarks": int(count)}, nil
+	series.Buckets, err = (*s.BookmarkRepository).CountBookmarksByInterval(ctx, startDate, endDate, series.Interval, series.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	return &models.BookmarkActivity{NewBookmarks: series.total(), TimeSeries: series.TimeSeries}, nil
+}
+
+type timeSeries struct {
+	models.TimeSeries
+	loc *time.Location
+}
+
+// total sums the buckets and moves their starts into the series timezone, so they
+// are rendered with its offset.
+func (t *timeSeries) total() int {
+	if t.Buckets == nil {
+		t.Buckets = []models.TimeBucket{}
+	}
+	total := 0
+	for i := range t.Buckets {
+		t.Buckets[i].Start = t.Buckets[i].Start.In(t.loc)
+		total += t.Buckets[i].Count
+	}
+	return total
+}
+
+func (s *AnalyticsService) newTimeSeries(ctx context.Context, userID primitive.ObjectID, interval, tz string) (*timeSeries, error) {
+	switch interval {
+	case "":
+		interval = models.IntervalDay
+	case models.IntervalDay, models.IntervalWeek:
+	default:
+		return nil, fmt.Errorf("invalid interval %q: must be day or week", interval)
+	}
+	loc, err := s.resolveTimezone(ctx, userID, tz)
+	if err != nil {
+		return nil, err
+	}
+	return &timeSeries{TimeSeries: models.TimeSeries{Interval: interval, Timezone: loc.String()}, loc: loc}, nil
+}
+
+// resolveTimezone returns tz when given, else the timezone in the user's settings,
+// else UTC.
+func (s *AnalyticsService) resolveTimezone(ctx context.Context, userID primitive.ObjectID, tz string) (*time.Location, error) {
+	if tz != "" {
+		return loadTimezone(tz)
+	}
+	user, err := (*s.UserRepository).FindByID(ctx, userID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		log.Warn().Err(err).Str("userID", userID.Hex()).Msg("Failed to load user timezone, using UTC")
+	}
+	if err == nil && user.Settings != nil && user.Settings.Timezone != "" {
+		if loc, err := loadTimezone(user.Settings.Timezone); err == nil {
+			return loc, nil
+		}
+	}
+	return time.UTC, nil
+}
+
+// loadTimezone resolves an IANA timezone name. "Local" is rejected because it
+// depends on the server.
+func loadTimezone(name string) (*time.Location, error) {
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "Local" {
+		return nil, fmt.Errorf("invalid timezone %q", name)
+	}
+	return loc, nil
 }
 
 func (s *AnalyticsService) GetBookmarkEngagement(ctx context.Context, userID primitive.ObjectID) (map[string]interface{}, error) {
@@ -56,18 +150,58 @@ func (s *AnalyticsService) GetBookmarkEngagement(ctx context.Context, userID pri
 	return map[string]interface{}{"favorite_bookmarks": int(favoriteCount)}, nil
 }
 
-func (s *AnalyticsService) GetTagTrends(ctx context.Context) ([]models.Tag, error) {
-	tags, err := (*s.TagRepository).FindAll(ctx)
+func (s *AnalyticsService) GetBookmarkSources(ctx context.Context, userID primitive.ObjectID) ([]models.SourceCount, error) {
+	counts, err := (*s.BookmarkRepository).CountBookmarksBySource(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
+	if counts == nil {
+		counts = []models.SourceCount{}
+	}
+	return counts, nil
+}
 
-	// Sort tags by WeeklyCount in descending order
-	sort.Slice(tags, func(i, j int) bool {
-		return tags[i].WeeklyCount > tags[j].WeeklyCount
-	})
+// GetTagTrends compares the user's tag usage over the last 7 days with the 7 days
+// before, computed from the bookmarks' created_at. Tags are sorted by weekly count,
+// then by how much they grew.
+func (s *AnalyticsService) GetTagTrends(ctx context.Context, userID primitive.ObjectID) ([]models.TagTrend, error) {
+	weekStart := time.Now().AddDate(0, 0, -7)
+	prevStart := weekStart.AddDate(0, 0, -7)
+	counts, err := (*s.BookmarkRepository).CountTagUsage(ctx, userID, prevStart, weekStart)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := (*s.TagRepository).FindByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[primitive.ObjectID]string, len(tags))
+	for _, tag := range tags {
+		names[tag.ID] = tag.Name
+	}
 
-	return tags, nil
+	// Bookmarks can still reference deleted tags; those are left out.
+	trends := make([]models.TagTrend, 0, len(counts))
+	for _, c := range counts {
+		name, ok := names[c.TagID]
+		if !ok {
+			continue
+		}
+		c.Name = name
+		c.Delta = c.WeeklyCount - c.PrevCount
+		trends = append(trends, c)
+	}
+	sort.Slice(trends, func(i, j int) bool {
+		if trends[i].WeeklyCount != trends[j].WeeklyCount {
+			return trends[i].WeeklyCount > trends[j].WeeklyCount
+		}
+		if trends[i].Delta != trends[j].Delta {
+			return trends[i].Delta > trends[j].Delta
+		}
+		return trends[i].Name < trends[j].Name
+	})
+	return trends, nil
 }
 
 func (s *AnalyticsService) GetTrendingItems(ctx context.Context) ([]models.TrendingItem, error) {
@@ -83,3 +217,134 @@ func (s *AnalyticsService) GetTrendingItems(ctx context.Context) ([]models.Trend
 
 	return items, nil
 }
+
+// GetAIStats reports, per kind and prompt version, how often AI output generated since
+// the given time was accepted, dismissed or regenerated.
+func (s *AnalyticsService) GetAIStats(ctx context.Context, since time.Time) ([]models.AIPromptStats, error) {
+	counts, err := (*s.AIEventRepository).CountSince(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	byPrompt := make(map[[2]string]*models.AIPromptStats)
+	for _, c := range counts {
+		key := [2]string{c.Kind, c.PromptVersion}
+		st, ok := byPrompt[key]
+		if !ok {
+			st = &models.AIPromptStats{Kind: c.Kind, PromptVersion: c.PromptVersion}
+			byPrompt[key] = st
+		}
+		switch c.Action {
+		case models.AIEventGenerated:
+			st.Generated += c.Count
+		case models.AIEventAccepted:
+			st.Accepted += c.Count
+		case models.AIEventDismissed:
+			st.Dismissed += c.Count
+		case models.AIEventRegenerated:
+			st.Regenerated += c.Count
+		}
+	}
+
+	stats := make([]models.AIPromptStats, 0, len(byPrompt))
+	for _, st := range byPrompt {
+		if st.Generated > 0 {
+			generated := float64(st.Generated)
+			st.AcceptanceRate = float64(st.Accepted) / generated
+			st.DismissRate = float64(st.Dismissed) / generated
+			st.RegenerationRate = float64(st.Regenerated) / generated
+		}
+		stats = append(stats, *st)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Kind != stats[j].Kind {
+			return stats[i].Kind < stats[j].Kind
+		}
+		return stats[i].PromptVersion < stats[j].PromptVersion
+	})
+	return stats, nil
+}
+
+func trendingMinUsers() int {
+	if v := os.Getenv("TRENDING_MIN_USERS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+		log.Warn().Str("TRENDING_MIN_USERS", v).Msg("Invalid TRENDING_MIN_USERS, using default")
+	}
+	return defaultTrendingMinUsers
+}
+
+// RefreshTrendingDomains recomputes the domains bookmarked by the most distinct users in
+// the last week and caches the result, then notifies the users following the tags
+// of the trending domains. It is run by the scheduler.
+func (s *AnalyticsService) RefreshTrendingDomains(ctx context.Context) error {
+	snapshot, err := s.refreshTrendingDomains(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.TagSubscriptions.NotifyTrendingDomains(ctx, snapshot); err != nil {
+		log.Error().Err(err).Msg("Failed to send trending tag notifications")
+	}
+	return nil
+}
+
+func (s *AnalyticsService) refreshTrendingDomains(ctx context.Context) (*models.TrendingDomains, error) {
+	now := time.Now()
+	since := now.AddDate(0, 0, -7)
+	minUsers := trendingMinUsers()
+	optedOut, err := (*s.UserRepository).FindIDs(ctx, bson.M{"settings.exclude_from_trending": true})
+	if err != nil {
+		return nil, err
+	}
+	domains, err := (*s.BookmarkRepository).CountDomains(ctx, since, optedOut, minUsers, trendingDomainsLimit)
+	if err != nil {
+		return nil, err
+	}
+	if domains == nil {
+		domains = []models.TrendingDomain{}
+	}
+
+	snapshot := &models.TrendingDomains{Domains: domains, MinUsers: minUsers, Since: since, ComputedAt: now}
+	if err := (*s.TrendingRepository).SaveDomains(ctx, snapshot); err != nil {
+		return nil, err
+	}
+	log.Info().Int("domains", len(domains)).Int("minUsers", minUsers).Msg("Trending domains refreshed")
+	return snapshot, nil
+}
+
+// RefreshUsageMetrics sets the bookmark and per-plan user gauges exported to
+// Prometheus. It is run by the scheduler.
+func (s *AnalyticsService) RefreshUsageMetrics(ctx context.Context) error {
+	bookmarks, err := (*s.BookmarkRepository).Count(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	plans, err := (*s.UserRepository).CountByPlan(ctx)
+	if err != nil {
+		return err
+	}
+	utils.BookmarksTotal.Set(float64(bookmarks))
+	// Plans nobody is on any more should not keep reporting their last count.
+	utils.UsersByPlan.Reset()
+	for plan, count := range plans {
+		if plan == "" {
+			plan = "none"
+		}
+		utils.UsersByPlan.WithLabelValues(plan).Add(float64(count))
+	}
+	return nil
+}
+
+// GetTrendingDomains returns the cached trending domains, computing them if they have
+// not been computed yet.
+func (s *AnalyticsService) GetTrendingDomains(ctx context.Context) (*models.TrendingDomains, error) {
+	snapshot, err := (*s.TrendingRepository).FindDomains(ctx)
+	if err == mongo.ErrNoDocuments {
+		return s.refreshTrendingDomains(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, userID primitive.ObjectID, req models.CreateAPIKeyRequest) (*models.CreatedAPIKey, error)
+	GetAPIKeys(ctx context.Context, userID primitive.ObjectID) ([]models.APIKey, error)
+	UpdateAPIKey(ctx context.Context, userID, keyID primitive.ObjectID, updatePayload models.APIKeyUpdate) (*models.APIKey, error)
+	DeleteAPIKey(ctx context.Context, userID, keyID primitive.ObjectID) (bool, error)
+	Authenticate(ctx context.Context, rawKey, clientIP, userAgent string) (*models.APIKey, error)
+}
+
+// apiKeyAccessLogInterval is how long a key must go unused before its next use is
+// recorded in the access log again. Uses from a new IP address are always recorded.
+const apiKeyAccessLogInterval = time.Hour
+
+type apiKeyServiceImpl struct {
+	apiKeyRepo repositories.APIKeyRepository
+	audit      AuditService
+}
+
+func NewAPIKeyService(apiKeyRepo repositories.APIKeyRepository, audit AuditService) APIKeyService {
+	return &apiKeyServiceImpl{apiKeyRepo: apiKeyRepo, audit: audit}
+}
+
+func (s *apiKeyServiceImpl) CreateAPIKey(ctx context.Context, userID primitive.ObjectID, req models.CreateAPIKeyRequest) (*models.CreatedAPIKey, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("name", req.Name).Msg("Attempting to create api key")
+	if req.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	allowed, err := utils.NormalizeCIDRs(req.AllowedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	denied, err := utils.NormalizeCIDRs(req.DeniedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("invalid expiry: expires_at must be in the future")
+	}
+	scopes := utils.AllScopes
+	if req.Scopes != nil {
+		if scopes, err = utils.ParseScopes(req.Scopes); err != nil {
+			return nil, err
+		}
+	}
+
+	rawKey, keyHash, err := utils.GenerateAPIKey()
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to generate api key")
+		return nil, fmt.Errorf("failed to generate api key")
+	}
+
+	key := models.APIKey{
+		ID:           primitive.NewObjectID(),
+		UserID:       userID,
+		Name:         req.Name,
+		Prefix:       rawKey[:len(utils.APIKeyPrefix)+6],
+		KeyHash:      keyHash,
+		AllowedCIDRs: allowed,
+		DeniedCIDRs:  denied,
+		Scopes:       scopes,
+		ExpiresAt:    req.ExpiresAt,
+		CreatedAt:    time.Now(),
+	}
+
+	created, err := s.apiKeyRepo.Create(ctx, &key)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to store api key")
+		return nil, err
+	}
+	log.Info().Str("userID", userID.Hex()).Str("apiKeyID", created.ID.Hex()).Msg("API key created successfully")
+	return &models.CreatedAPIKey{APIKey: *created, Key: rawKey}, nil
+}
+
+func (s *apiKeyServiceImpl) GetAPIKeys(ctx context.Context, userID primitive.ObjectID) ([]models.APIKey, error) {
+	log.Debug().Str("userID", userID.Hex()).Msg("Attempting to retrieve api keys")
+	keys, err := s.apiKeyRepo.FindByUser(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.Hex()).Msg("Error finding api keys for user")
+		return nil, err
+	}
+	if keys == nil {
+		keys = []models.APIKey{}
+	}
+	for i := range keys {
+		withDefaultScopes(&keys[i])
+	}
+	return keys, nil
+}
+
+func (s *apiKeyServiceImpl) UpdateAPIKey(ctx context.Context, userID, keyID primitive.ObjectID, updatePayload models.APIKeyUpdate) (*models.APIKey, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("apiKeyID", keyID.Hex()).Msg("Attempting to update api key")
+	updateFields := bson.M{}
+	if updatePayload.Name != nil {
+		if *updatePayload.Name == "" {
+			return nil, fmt.Errorf("name is required")
+		}
+		updateFields["name"] = *updatePayload.Name
+	}
+	if updatePayload.AllowedCIDRs != nil {
+		allowed, err := utils.NormalizeCIDRs(*updatePayload.AllowedCIDRs)
+		if err != nil {
+			return nil, err
+		}
+		updateFields["allowed_cidrs"] = allowed
+	}
+	if updatePayload.DeniedCIDRs != nil {
+		denied, err := utils.NormalizeCIDRs(*updatePayload.DeniedCIDRs)
+		if err != nil {
+			return nil, err
+		}
+		updateFields["denied_cidrs"] = denied
+	}
+	if updatePayload.Scopes != nil {
+		scopes, err := utils.ParseScopes(*updatePayload.Scopes)
+		if err != nil {
+			return nil, err
+		}
+		updateFields["scopes"] = scopes
+	}
+	if updatePayload.ExpiresAt != nil {
+		if !updatePayload.ExpiresAt.After(time.Now()) {
+			return nil, fmt.Errorf("invalid expiry: expires_at must be in the future")
+		}
+		updateFields["expires_at"] = *updatePayload.ExpiresAt
+	}
+	if len(updateFields) == 0 {
+		return nil, fmt.Errorf("no fields to update")
+	}
+
+	result, err := s.apiKeyRepo.Update(ctx, userID, keyID, updateFields)
+	if err != nil {
+		log.Error().Err(err).Str("apiKeyID", keyID.Hex()).Msg("Failed to update api key")
+		return nil, fmt.Errorf("failed to update api key")
+	}
+	if result.MatchedCount == 0 {
+		return nil, fmt.Errorf("api key not found")
+	}
+
+	updated, err := s.apiKeyRepo.FindByID(ctx, userID, keyID)
+	if err != nil {
+		log.Error().Err(err).Str("apiKeyID", keyID.Hex()).Msg("Failed to find updated api key")
+		return nil, fmt.Errorf("failed to retrieve the updated api key")
+	}
+	log.Info().Str("userID", userID.Hex()).Str("apiKeyID", keyID.Hex()).Msg("API key updated successfully")
+	withDefaultScopes(updated)
+	return updated, nil
+}
+
+// withDefaultScopes reports every scope on keys created before scopes existed.
+func withDefaultScopes(key *models.APIKey) {
+	if len(key.Scopes) == 0 {
+		key.Scopes = utils.AllScopes
+	}
+}
+
+func (s *apiKeyServiceImpl) DeleteAPIKey(ctx context.Context, userID, keyID primitive.ObjectID) (bool, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("apiKeyID", keyID.Hex()).Msg("Attempting to delete api key")
+	result, err := s.apiKeyRepo.Delete(ctx, userID, keyID)
+	if err != nil {
+		return false, err
+	}
+	if result.DeletedCount == 0 {
+		return false, fmt.Errorf("api key not found")
+	}
+	log.Info().Str("userID", userID.Hex()).Str("apiKeyID", keyID.Hex()).Msg("API key deleted successfully")
+	return true, nil
+}
+
+// Authenticate resolves a raw key, enforcing expiry and the deny/allow IP rules, and records its usage.
+func (s *apiKeyServiceImpl) Authenticate(ctx context.Context, rawKey, clientIP, userAgent string) (*models.APIKey, error) {
+	key, err := s.apiKeyRepo.FindByHash(ctx, utils.HashAPIKey(rawKey))
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("invalid api key")
+		}
+		log.Error().Err(err).Msg("Failed to look up api key")
+		return nil, fmt.Errorf("failed to verify api key")
+	}
+
+	now := time.Now()
+	if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+		log.Warn().Str("apiKeyID", key.ID.Hex()).Msg("Expired api key used")
+		return nil, fmt.Errorf("api key expired")
+	}
+
+	ip := net.ParseIP(clientIP)
+	if len(key.DeniedCIDRs) > 0 || len(key.AllowedCIDRs) > 0 {
+		if ip == nil {
+			return nil, fmt.Errorf("ip address not allowed for this api key")
+		}
+		if utils.IPInCIDRs(ip, key.DeniedCIDRs) {
+			log.Warn().Str("apiKeyID", key.ID.Hex()).Str("ip", clientIP).Msg("API key used from denied address")
+			return nil, fmt.Errorf("ip address not allowed for this api key")
+		}
+		if len(key.AllowedCIDRs) > 0 && !utils.IPInCIDRs(ip, key.AllowedCIDRs) {
+			log.Warn().Str("apiKeyID", key.ID.Hex()).Str("ip", clientIP).Msg("API key used from address outside allowlist")
+			return nil, fmt.Errorf("ip address not allowed for this api key")
+		}
+	}
+
+	if key.LastUsedAt == nil || key.LastUsedIP != clientIP || now.Sub(*key.LastUsedAt) >= apiKeyAccessLogInterval {
+		s.audit.RecordLogin(ctx, key.UserID, models.AuthMethodAPIKey, clientIP, userAgent, "api key "+key.Name+" ("+key.Prefix+")")
+	}
+	if err := s.apiKeyRepo.TouchLastUsed(ctx, key.ID, now, clientIP); err != nil {
+		log.Warn().Err(err).Str("apiKeyID", key.ID.Hex()).Msg("Failed to record api key usage")
+	}
+	key.LastUsedAt = &now
+	key.LastUsedIP = clientIP
+	return key, nil
+}
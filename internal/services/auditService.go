@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+	// maxUserAgentLength truncates the user agents stored with logins, in runes.
+	maxUserAgentLength = 512
+)
+
+// AuditService keeps the audit log of security-relevant actions.
+type AuditService interface {
+	Record(ctx context.Context, event models.AuditEvent) error
+	List(ctx context.Context, userID *primitive.ObjectID, limit int) ([]models.AuditEvent, error)
+	// RecordLogin records a successful authentication of userID. Failures are only
+	// logged so they never block a login.
+	RecordLogin(ctx context.Context, userID primitive.ObjectID, method, ip, userAgent, details string)
+	// AccessLog returns the newest authentications of userID.
+	AccessLog(ctx context.Context, userID primitive.ObjectID, limit int) ([]models.AccessLogEntry, error)
+	Anonymize(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
+
+type auditServiceImpl struct {
+	auditRepo repositories.AuditRepository
+}
+
+func NewAuditService(auditRepo repositories.AuditRepository) AuditService {
+	return &auditServiceImpl{auditRepo: auditRepo}
+}
+
+func (s *auditServiceImpl) Record(ctx context.Context, event models.AuditEvent) error {
+	event.ID = primitive.NewObjectID()
+	event.CreatedAt = time.Now()
+	if err := s.auditRepo.Create(ctx, &event); err != nil {
+		log.Error().Err(err).Str("action", event.Action).Str("userID", event.UserID.Hex()).Msg("Failed to record audit event")
+		return fmt.Errorf("failed to record audit event")
+	}
+	return nil
+}
+
+// List returns the newest events, optionally only those affecting userID.
+func (s *auditServiceImpl) List(ctx context.Context, userID *primitive.ObjectID, limit int) ([]models.AuditEvent, error) {
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	if limit > maxAuditLimit {
+		return nil, fmt.Errorf("invalid limit: at most %d events can be listed", maxAuditLimit)
+	}
+	filter := bson.M{}
+	if userID != nil {
+		filter["user_id"] = *userID
+	}
+	events, err := s.auditRepo.Find(ctx, filter, int64(limit))
+	if err != nil {
+		return nil, err
+	}
+	if events == nil {
+		events = []models.AuditEvent{}
+	}
+	return events, nil
+}
+
+func (s *auditServiceImpl) RecordLogin(ctx context.Context, userID primitive.ObjectID, method, ip, userAgent, details string) {
+	userAgent = truncateRunes(userAgent, maxUserAgentLength)
+	s.Record(ctx, models.AuditEvent{
+		Action:     models.AuditLogin,
+		ActorID:    userID,
+		UserID:     userID,
+		AuthMethod: method,
+		IP:         ip,
+		UserAgent:  userAgent,
+		Details:    details,
+	})
+}
+
+func (s *auditServiceImpl) AccessLog(ctx context.Context, userID primitive.ObjectID, limit int) ([]models.AccessLogEntry, error) {
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	if limit > maxAuditLimit {
+		return nil, fmt.Errorf("invalid limit: at most %d entries can be listed", maxAuditLimit)
+	}
+	events, err := s.auditRepo.Find(ctx, bson.M{"user_id": userID, "action": models.AuditLogin}, int64(limit))
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to retrieve access log")
+		return nil, fmt.Errorf("failed to retrieve access log")
+	}
+	entries := make([]models.AccessLogEntry, 0, len(events))
+	for _, e := range events {
+		entries = append(entries, models.AccessLogEntry{
+			Time:      e.CreatedAt,
+			IP:        e.IP,
+			UserAgent: e.UserAgent,
+			Method:    e.AuthMethod,
+			Details:   e.Details,
+		})
+	}
+	return entries, nil
+}
+
+// Anonymize removes the personal data of userID from the audit log, keeping what
+// happened and when.
+func (s *auditServiceImpl) Anonymize(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return s.auditRepo.Anonymize(ctx, userID)
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+)
+
+type recordedAudit struct {
+	repositories.AuditRepository
+	events []models.AuditEvent
+}
+
+func (f *recordedAudit) Create(ctx context.Context, event *models.AuditEvent) error {
+	f.events = append(f.events, *event)
+	return nil
+}
+
+func TestRecordLoginTruncatesUserAgentOnRunes(t *testing.T) {
+	repo := &recordedAudit{}
+	s := NewAuditService(repo)
+	userAgent := "Mozilla/5.0 " + strings.Repeat("é", maxUserAgentLength)
+
+	s.RecordLogin(context.Background(), primitive.NewObjectID(), "password", "192.0.2.1", userAgent, "")
+	if len(repo.events) != 1 {
+		t.Fatalf("recorded %d events, want 1", len(repo.events))
+	}
+	stored := repo.events[0].UserAgent
+	if !utf8.ValidString(stored) {
+		t.Errorf("stored user agent %q is not valid UTF-8", stored)
+	}
+	if n := utf8.RuneCountInString(stored); n > maxUserAgentLength+1 || !strings.HasPrefix(userAgent, strings.TrimSuffix(stored, "…")) {
+		t.Errorf("stored %d runes %q, want the first %d runes of the user agent", n, stored, maxUserAgentLength)
+	}
+}
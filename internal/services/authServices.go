@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gorilla/sessions"
@@ -13,6 +14,8 @@ import (
 	"github.com/markbates/goth/providers/facebook"
 	"github.com/markbates/goth/providers/google"
 	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 
 	"markly/internal/models"
 	"markly/internal/repositories"
@@ -26,16 +29,20 @@ const (
 )
 
 type AuthService interface {
-	HandleLogin(ctx context.Context, u goth.User) (string, error)
+	// HandleLogin signs in or provisions the user of an OAuth login and returns a
+	// JWT. ip and userAgent are recorded in the user's access log.
+	HandleLogin(ctx context.Context, u goth.User, ip, userAgent string) (string, error)
 	ResetPassword(ctx context.Context, email, newPassword string) error
 }
 
 type authService struct {
 	userRepo repositories.UserRepository
+	instance InstanceService
+	audit    AuditService
 }
 
-func NewAuthService(UserRepo repositories.UserRepository) *authService {
-	return &authService{userRepo: UserRepo}
+func NewAuthService(UserRepo repositories.UserRepository, instance InstanceService, audit AuditService) *authService {
+	return &authService{userRepo: UserRepo, instance: instance, audit: audit}
 }
 
 func InitializeGoth() {
@@ -64,7 +71,7 @@ func InitializeGoth() {
 	log.Info().Msg("Goth providers initialized")
 }
 
-func (a *authService) HandleLogin(ctx context.Context, u goth.User) (string, error) {
+func (a *authService) HandleLogin(ctx context.Context, u goth.User, ip, userAgent string) (string, error) {
 	log.Info().Str("email", u.Email).Msg("Attempting to handle login for user")
 	if u.Email == "" {
 		log.Error().Msg("Missing email in Goth user data")
@@ -72,7 +79,9 @@ func (a *authService) HandleLogin(ctx context.Context, u goth.User) (string, err
 	}
 
 	user, err := a.userRepo.FindByEmail(ctx, u.Email)
-
+	if err == mongo.ErrNoDocuments {
+		user, err = nil, nil
+	}
 	if err != nil {
 		log.Error().Err(err).Str("email", u.Email).Msg("Error finding user by email")
 		return "", errors.New("error finding user by email")
@@ -80,11 +89,20 @@ func (a *authService) HandleLogin(ctx context.Context, u goth.User) (string, err
 
 	if user == nil {
 		log.Info().Str("email", u.Email).Msg("User not found, creating new user")
+		now := time.Now()
 		newUser := &models.User{
-			Email:    u.Email,
-			Username: u.NickName,
+			ID:        primitive.NewObjectID(),
+			Email:     u.Email,
+			Username:  u.NickName,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := a.instance.AdmitSignup(ctx, newUser); err != nil {
+			log.Warn().Err(err).Str("email", u.Email).Msg("OAuth signup rejected by instance settings")
+			return "", err
 		}
 		if _, err := a.userRepo.Create(ctx, newUser); err != nil {
+			a.instance.AbortSignup(ctx, newUser)
 			log.Error().Err(err).Str("email", u.Email).Msg("Error creating new user")
 			return "", errors.New("error creating user")
 		}
@@ -94,12 +112,13 @@ func (a *authService) HandleLogin(ctx context.Context, u goth.User) (string, err
 		log.Info().Str("email", u.Email).Str("userID", user.ID.Hex()).Msg("User found in database")
 	}
 
-	token, err := utils.GenerateJWT(user.ID)
+	token, err := utils.GenerateJWT(user.ID, utils.AudienceWeb)
 	if err != nil {
 		log.Error().Err(err).Str("userID", user.ID.Hex()).Msg("Error generating JWT for user")
 		return "", errors.New("error generating JWT")
 	}
 	log.Info().Str("userID", user.ID.Hex()).Msg("JWT generated successfully")
+	a.audit.RecordLogin(ctx, user.ID, strings.ToLower(u.Provider), ip, userAgent, "client: "+utils.AudienceWeb)
 
 	return token, nil
 }
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/extract"
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+// BookmarkContentService maintains the readable-mode copies of bookmarked pages.
+// Pages are extracted in the background when a bookmark is saved, and on demand
+// when a client asks for content that was never extracted.
+type BookmarkContentService interface {
+	GetContent(ctx context.Context, userID, bookmarkID primitive.ObjectID, refresh bool) (*models.BookmarkContent, error)
+	ExtractAsync(userID, bookmarkID primitive.ObjectID, rawURL string)
+	DeleteForBookmark(ctx context.Context, userID, bookmarkID primitive.ObjectID) error
+	QueueDepth() models.QueueDepth
+	BackfillMetadata(ctx context.Context) error
+}
+
+type bookmarkContentServiceImpl struct {
+	contentRepo  repositories.BookmarkContentRepository
+	bookmarkRepo repositories.BookmarkRepository
+	ownership    *Ownership
+	fetcher      *extract.Fetcher
+	onSave       bool
+	// slots bounds background extractions; bookmarks saved while all slots are busy
+	// are extracted on first read instead.
+	slots chan struct{}
+}
+
+// NewBookmarkContentService reads CONTENT_EXTRACTION_ON_SAVE ("false" disables
+// background extraction) and CONTENT_EXTRACTION_ALLOW_PRIVATE ("true" allows
+// fetching from private networks, for development).
+func NewBookmarkContentService(contentRepo repositories.BookmarkContentRepository, bookmarkRepo repositories.BookmarkRepository, ownership *Ownership) BookmarkContentService {
+	return &bookmarkContentServiceImpl{
+		contentRepo:  contentRepo,
+		bookmarkRepo: bookmarkRepo,
+		ownership:    ownership,
+		fetcher:      extract.NewFetcher(os.Getenv("CONTENT_EXTRACTION_ALLOW_PRIVATE") == "true"),
+		onSave:       os.Getenv("CONTENT_EXTRACTION_ON_SAVE") != "false",
+		slots:        make(chan struct{}, 4),
+	}
+}
+
+func (s *bookmarkContentServiceImpl) extract(ctx context.Context, userID, bookmarkID primitive.ObjectID, rawURL string) (*models.BookmarkContent, error) {
+	content := &models.BookmarkContent{
+		BookmarkID:  bookmarkID,
+		UserID:      userID,
+		URL:         rawURL,
+		ExtractedAt: time.Now(),
+	}
+
+	article, err := s.fetcher.Fetch(ctx, rawURL)
+	if err != nil {
+		utils.ContentExtractionsTotal.WithLabelValues(models.ContentStatusFailed).Inc()
+		log.Warn().Err(err).Str("bookmarkID", bookmarkID.Hex()).Str("url", rawURL).Msg("Failed to extract bookmark content")
+		content.Status = models.ContentStatusFailed
+		content.Error = err.Error()
+	} else {
+		utils.ContentExtractionsTotal.WithLabelValues(models.ContentStatusOK).Inc()
+		content.Status = models.ContentStatusOK
+		content.Title = article.Title
+		content.SiteName = article.SiteName
+		content.FaviconURL = article.FaviconURL
+		content.Text = article.Text
+		content.HTML = article.HTML
+		content.WordCount = article.WordCount
+	}
+
+	s.saveMetadata(ctx, userID, bookmarkID, article)
+
+	if err := s.contentRepo.Upsert(ctx, content); err != nil {
+		log.Error().Err(err).Str("bookmarkID", bookmarkID.Hex()).Msg("Failed to store bookmark content")
+		return nil, err
+	}
+	return content, nil
+}
+
+// saveMetadata copies the site name, favicon and content fingerprints of a fetched
+// page onto its bookmark.
+// A nil article records a failed attempt, so the backfill job does not retry it.
+func (s *bookmarkContentServiceImpl) saveMetadata(ctx context.Context, userID, bookmarkID primitive.ObjectID, article *extract.Article) {
+	now := primitive.NewDateTimeFromTime(time.Now())
+	fields := bson.M{"metadata_at": now}
+	if article != nil {
+		fields["site_name"] = article.SiteName
+		fields["favicon_url"] = article.FaviconURL
+		fields["image_url"] = article.ImageURL
+		if lang := utils.NormalizeLanguage(article.Language); lang != "" {
+			fields["language"] = lang
+		}
+		if hash := utils.ContentHash(article.Text); hash != "" {
+			fields["content_hash"] = hash
+			fields["content_simhash"] = int64(utils.Simhash(article.Text))
+		}
+	}
+	if _, err := s.bookmarkRepo.UpdateOne(ctx, bson.M{"_id": bookmarkID, "user_id": userID}, bson.M{"$set": fields}); err != nil {
+		log.Warn().Err(err).Str("bookmarkID", bookmarkID.Hex()).Msg("Failed to store bookmark metadata")
+	}
+}
+
+// metadataBackfillBatch is how many bookmarks one backfill run handles.
+const metadataBackfillBatch = 50
+
+// BackfillMetadata fills in the site name and favicon of bookmarks saved before
+// they were recorded. Stored content is reused when it is for the current URL and
+// has a favicon; otherwise the page is fetched. It runs as a scheduled job and
+// handles one batch per run.
+func (s *bookmarkContentServiceImpl) BackfillMetadata(ctx context.Context) error {
+	bookmarks, err := s.bookmarkRepo.Find(ctx, bson.M{"metadata_at": nil}, metadataBackfillBatch, 1)
+	if err != nil {
+		return fmt.Errorf("failed to find bookmarks without metadata: %w", err)
+	}
+
+	for _, bm := range bookmarks {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		content, err := s.contentRepo.FindByBookmark(ctx, bm.UserID, bm.ID)
+		if err == nil && content.Status == models.ContentStatusOK && content.URL == bm.URL && content.FaviconURL != "" {
+			s.saveMetadata(ctx, bm.UserID, bm.ID, &extract.Article{SiteName: content.SiteName, FaviconURL: content.FaviconURL, Text: content.Text})
+			continue
+		}
+
+		fetchCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		article, err := s.fetcher.Fetch(fetchCtx, bm.URL)
+		cancel()
+		if err != nil {
+			log.Debug().Err(err).Str("bookmarkID", bm.ID.Hex()).Str("url", bm.URL).Msg("Failed to fetch page for metadata backfill")
+			article = nil
+		}
+		s.saveMetadata(ctx, bm.UserID, bm.ID, article)
+	}
+	if len(bookmarks) > 0 {
+		log.Info().Int("count", len(bookmarks)).Msg("Backfilled bookmark metadata")
+	}
+	return nil
+}
+
+func (s *bookmarkContentServiceImpl) ExtractAsync(userID, bookmarkID primitive.ObjectID, rawURL string) {
+	if !s.onSave {
+		return
+	}
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		log.Debug().Str("bookmarkID", bookmarkID.Hex()).Msg("Content extraction queue full, deferring to first read")
+		return
+	}
+	go func() {
+		defer func() { <-s.slots }()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		s.extract(ctx, userID, bookmarkID, rawURL)
+	}()
+}
+
+// QueueDepth reports the background extractions in flight.
+func (s *bookmarkContentServiceImpl) QueueDepth() models.QueueDepth {
+	return models.QueueDepth{InFlight: len(s.slots), Capacity: cap(s.slots)}
+}
+
+func (s *bookmarkContentServiceImpl) GetContent(ctx context.Context, userID, bookmarkID primitive.ObjectID, refresh bool) (*models.BookmarkContent, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Bool("refresh", refresh).Msg("Attempting to retrieve bookmark content")
+	bm, err := s.bookmarkRepo.FindOne(ctx, bson.M{"_id": bookmarkID, "user_id": userID})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, s.ownership.Missing(ctx, userID, ResourceBookmark, bookmarkID, fmt.Errorf("bookmark not found"))
+		}
+		log.Error().Err(err).Str("bookmarkID", bookmarkID.Hex()).Msg("Error finding bookmark for content")
+		return nil, fmt.Errorf("failed to retrieve bookmark")
+	}
+
+	content, err := s.contentRepo.FindByBookmark(ctx, userID, bookmarkID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		log.Error().Err(err).Str("bookmarkID", bookmarkID.Hex()).Msg("Error finding bookmark content")
+		return nil, fmt.Errorf("failed to retrieve bookmark content")
+	}
+
+	// Content extracted for a previous URL of the bookmark is stale.
+	if content == nil || refresh || content.URL != bm.URL {
+		content, err = s.extract(ctx, userID, bookmarkID, bm.URL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve bookmark content")
+		}
+	}
+
+	if content.Status == models.ContentStatusFailed {
+		return nil, fmt.Errorf("content extraction failed: %s", content.Error)
+	}
+	return content, nil
+}
+
+func (s *bookmarkContentServiceImpl) DeleteForBookmark(ctx context.Context, userID, bookmarkID primitive.ObjectID) error {
+	return s.contentRepo.DeleteByBookmark(ctx, userID, bookmarkID)
+}
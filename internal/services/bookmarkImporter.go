@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+)
+
+// bookmarkImporter creates the bookmarks of one import and the tags they name, for
+// CSV imports and import jobs alike. Tags are matched by lowercase name and
+// created when the user has none; bookmarks the user already has are counted
+// and left unchanged.
+type bookmarkImporter struct {
+	bookmarkRepo repositories.BookmarkRepository
+	tagRepo      repositories.TagRepository
+	urls         URLService
+	userID       primitive.ObjectID
+	// source names the import in logs.
+	source    string
+	tags      map[string]primitive.ObjectID
+	usedTags  map[string]bool
+	tagCount  *models.ImportCount
+	bookmarks *models.ImportCount
+}
+
+func newBookmarkImporter(bookmarkRepo repositories.BookmarkRepository, tagRepo repositories.TagRepository, urls URLService, userID primitive.ObjectID, source string, tagCount, bookmarks *models.ImportCount) *bookmarkImporter {
+	return &bookmarkImporter{
+		bookmarkRepo: bookmarkRepo,
+		tagRepo:      tagRepo,
+		urls:         urls,
+		userID:       userID,
+		source:       source,
+		usedTags:     map[string]bool{},
+		tagCount:     tagCount,
+		bookmarks:    bookmarks,
+	}
+}
+
+func (imp *bookmarkImporter) loadTags(ctx context.Context) error {
+	tags, err := imp.tagRepo.FindByUser(ctx, imp.userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", imp.userID.Hex()).Str("source", imp.source).Msg("Failed to load tags for import")
+		return fmt.Errorf("failed to fetch tags")
+	}
+	imp.tags = make(map[string]primitive.ObjectID, len(tags))
+	for _, t := range tags {
+		imp.tags[strings.ToLower(t.Name)] = t.ID
+	}
+	return nil
+}
+
+// tag returns the ID of the named tag, creating it when the user has none.
+func (imp *bookmarkImporter) tag(ctx context.Context, name string) (primitive.ObjectID, error) {
+	key := strings.ToLower(name)
+	if id, ok := imp.tags[key]; ok {
+		if !imp.usedTags[key] {
+			imp.usedTags[key] = true
+			imp.tagCount.Existing++
+		}
+		return id, nil
+	}
+	tag := models.Tag{ID: primitive.NewObjectID(), Name: name, UserID: imp.userID, CreatedAt: primitive.NewDateTimeFromTime(time.Now())}
+	if _, err := imp.tagRepo.Create(ctx, &tag); err != nil {
+		log.Error().Err(err).Str("userID", imp.userID.Hex()).Str("source", imp.source).Str("tag", name).Msg("Failed to create tag during import")
+		return primitive.NilObjectID, fmt.Errorf("failed to import tag %q", name)
+	}
+	imp.tags[key] = tag.ID
+	imp.usedTags[key] = true
+	imp.tagCount.Created++
+	return tag.ID, nil
+}
+
+// find returns the canonical form of the normalized URL and whether the user
+// already has a bookmark with it, which is then counted as existing.
+func (imp *bookmarkImporter) find(ctx context.Context, normalized string) (string, bool, error) {
+	existing, canonical, err := findExistingBookmark(ctx, imp.bookmarkRepo, imp.urls, imp.userID, normalized)
+	if err != nil {
+		return "", false, err
+	}
+	if existing != nil {
+		imp.bookmarks.Existing++
+	}
+	return canonical, existing != nil, nil
+}
+
+// create saves a new bookmark of the import.
+func (imp *bookmarkImporter) create(ctx context.Context, bm *models.Bookmark) error {
+	bm.SearchGrams = bookmarkSearchGrams(bm)
+	if _, err := imp.bookmarkRepo.Create(ctx, bm); err != nil {
+		log.Error().Err(err).Str("userID", imp.userID.Hex()).Str("source", imp.source).Msg("Failed to import bookmark")
+		return fmt.Errorf("failed to import bookmark %s", bm.URL)
+	}
+	imp.bookmarks.Created++
+	return nil
+}
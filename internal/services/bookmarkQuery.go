@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BookmarkQuery is a parsed bookmark query string such as
+//
+//	tag:go -tag:video domain:github.com is:unread before:2024-01-01 "release notes"
+//
+// Filters are combined with AND, except domains, which match any of the listed
+// domains. A leading "-" negates a filter or word.
+type BookmarkQuery struct {
+	Tags            []string
+	ExcludedTags    []string
+	Domains         []string
+	ExcludedDomains []string
+	// Is maps the flags read, fav, pinned and archived to whether they must be set.
+	Is map[string]bool
+	// After is inclusive and Before exclusive, both on the creation time.
+	After  *time.Time
+	Before *time.Time
+	// Words must appear in the title, summary or URL; ExcludedWords must not.
+	Words         []string
+	ExcludedWords []string
+}
+
+// queryFlags maps the values of is: to the flag they set and its value.
+var queryFlags = map[string]struct {
+	flag  string
+	value bool
+}{
+	"read":     {"read", true},
+	"unread":   {"read", false},
+	"fav":      {"fav", true},
+	"favorite": {"fav", true},
+	"pinned":   {"pinned", true},
+	"archived": {"archived", true},
+}
+
+// ParseBookmarkQuery parses the compact query syntax. Values with spaces are
+// quoted, as in tag:"machine learning". Dates are YYYY-MM-DD in UTC or RFC3339.
+func ParseBookmarkQuery(q string) (*BookmarkQuery, error) {
+	tokens, err := tokenizeQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	query := &BookmarkQuery{Is: map[string]bool{}}
+	for _, tok := range tokens {
+		negated := strings.HasPrefix(tok.text, "-") && len(tok.text) > 1 && !tok.quoted
+		text := tok.text
+		if negated {
+			text = text[1:]
+		}
+		key, value, hasKey := strings.Cut(text, ":")
+		if !hasKey || tok.quoted || value == "" {
+			if negated {
+				query.ExcludedWords = append(query.ExcludedWords, text)
+			} else {
+				query.Words = append(query.Words, text)
+			}
+			continue
+		}
+		value = unquote(value)
+
+		switch strings.ToLower(key) {
+		case "tag":
+			if negated {
+				query.ExcludedTags = append(query.ExcludedTags, value)
+			} else {
+				query.Tags = append(query.Tags, value)
+			}
+		case "domain":
+			domain := strings.TrimPrefix(strings.ToLower(value), "www.")
+			if negated {
+				query.ExcludedDomains = append(query.ExcludedDomains, domain)
+			} else {
+				query.Domains = append(query.Domains, domain)
+			}
+		case "is":
+			f, ok := queryFlags[strings.ToLower(value)]
+			if !ok {
+				return nil, fmt.Errorf("invalid query: unknown is:%s", value)
+			}
+			want := f.value != negated
+			if prev, seen := query.Is[f.flag]; seen && prev != want {
+				return nil, fmt.Errorf("invalid query: conflicting is:%s", value)
+			}
+			query.Is[f.flag] = want
+		case "before", "after":
+			if negated {
+				return nil, fmt.Errorf("invalid query: %s: cannot be negated", key)
+			}
+			t, err := parseQueryDate(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid query: %s:%s is not a date", key, value)
+			}
+			if strings.ToLower(key) == "before" {
+				query.Before = &t
+			} else {
+				query.After = &t
+			}
+		default:
+			return nil, fmt.Errorf("invalid query: unknown filter %q", key)
+		}
+	}
+	return query, nil
+}
+
+type queryToken struct {
+	text string
+	// quoted is set for tokens that are entirely a quoted phrase.
+	quoted bool
+}
+
+// tokenizeQuery splits q on spaces outside double quotes. Quotes are dropped from
+// phrases but kept in filter values, which unquote removes.
+func tokenizeQuery(q string) ([]queryToken, error) {
+	var tokens []queryToken
+	var b strings.Builder
+	inQuotes, quoted := false, false
+	flush := func() {
+		if b.Len() > 0 {
+			tokens = append(tokens, queryToken{text: b.String(), quoted: quoted})
+			b.Reset()
+		}
+		quoted = false
+	}
+	for _, r := range q {
+		switch {
+		case r == '"':
+			if !inQuotes && b.Len() == 0 {
+				quoted = true
+			} else if !quoted {
+				b.WriteRune(r)
+			}
+			inQuotes = !inQuotes
+		case (r == ' ' || r == '\t' || r == '\n') && !inQuotes:
+			flush()
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("invalid query: unterminated quote")
+	}
+	flush()
+	return tokens, nil
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+func parseQueryDate(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// queryClauses turns a parsed query into filter clauses for the user's bookmarks.
+// Tag names are matched case-insensitively; an unknown tag matches no bookmark.
+func (s *bookmarkServiceImpl) queryClauses(ctx context.Context, userID primitive.ObjectID, q *BookmarkQuery) ([]bson.M, error) {
+	var clauses []bson.M
+
+	if len(q.Tags) > 0 || len(q.ExcludedTags) > 0 {
+		tags, err := s.tagRepo.FindByUser(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve query tags: %w", err)
+		}
+		ids := make(map[string]primitive.ObjectID, len(tags))
+		for _, tag := range tags {
+			ids[strings.ToLower(tag.Name)] = tag.ID
+		}
+		for _, name := range q.Tags {
+			id, ok := ids[strings.ToLower(name)]
+			if !ok {
+				clauses = append(clauses, bson.M{"tagsid": bson.M{"$in": []primitive.ObjectID{}}})
+				continue
+			}
+			clauses = append(clauses, bson.M{"tagsid": id})
+		}
+		var excluded []primitive.ObjectID
+		for _, name := range q.ExcludedTags {
+			if id, ok := ids[strings.ToLower(name)]; ok {
+				excluded = append(excluded, id)
+			}
+		}
+		if len(excluded) > 0 {
+			clauses = append(clauses, bson.M{"tagsid": bson.M{"$nin": excluded}})
+		}
+	}
+
+	if len(q.Domains) > 0 {
+		var domains []bson.M
+		for _, d := range q.Domains {
+			domains = append(domains, bson.M{"url": domainRegex(d)})
+		}
+		clauses = append(clauses, bson.M{"$or": domains})
+	}
+	for _, d := range q.ExcludedDomains {
+		clauses = append(clauses, bson.M{"url": bson.M{"$not": domainRegex(d)}})
+	}
+
+	for flag, want := range q.Is {
+		switch flag {
+		case "read", "pinned":
+			if want {
+				clauses = append(clauses, bson.M{flag: true})
+			} else {
+				clauses = append(clauses, bson.M{flag: bson.M{"$ne": true}})
+			}
+		case "fav":
+			clauses = append(clauses, bson.M{"is_fav": want})
+		}
+	}
+
+	created := bson.M{}
+	if q.After != nil {
+		created["$gte"] = primitive.NewDateTimeFromTime(*q.After)
+	}
+	if q.Before != nil {
+		created["$lt"] = primitive.NewDateTimeFromTime(*q.Before)
+	}
+	if len(created) > 0 {
+		clauses = append(clauses, bson.M{"created_at": created})
+	}
+
+	for _, w := range q.Words {
+		clauses = append(clauses, bson.M{"$or": wordMatch(w)})
+	}
+	for _, w := range q.ExcludedWords {
+		clauses = append(clauses, bson.M{"$nor": wordMatch(w)})
+	}
+	return clauses, nil
+}
+
+// domainRegex matches URLs on domain or its subdomains.
+func domainRegex(domain string) primitive.Regex {
+	return primitive.Regex{Pattern: `^[a-z][a-z0-9+.-]*://([^/?#@]*\.)?` + regexp.QuoteMeta(domain) + `(:[0-9]+)?([/?#]|$)`, Options: "i"}
+}
+
+func wordMatch(word string) []bson.M {
+	re := primitive.Regex{Pattern: regexp.QuoteMeta(word), Options: "i"}
+	return []bson.M{{"title": re}, {"summary": re}, {"url": re}}
+}
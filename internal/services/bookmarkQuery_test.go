@@ -0,0 +1,50 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseBookmarkQuery(t *testing.T) {
+	q, err := ParseBookmarkQuery(`tag:go -tag:video tag:"machine learning" domain:www.GitHub.com is:unread -is:pinned before:2024-01-01 "release notes" -draft`)
+	if err != nil {
+		t.Fatalf("ParseBookmarkQuery: %v", err)
+	}
+	if want := []string{"go", "machine learning"}; !reflect.DeepEqual(q.Tags, want) {
+		t.Errorf("Tags = %q, want %q", q.Tags, want)
+	}
+	if want := []string{"video"}; !reflect.DeepEqual(q.ExcludedTags, want) {
+		t.Errorf("ExcludedTags = %q, want %q", q.ExcludedTags, want)
+	}
+	if want := []string{"github.com"}; !reflect.DeepEqual(q.Domains, want) {
+		t.Errorf("Domains = %q, want %q", q.Domains, want)
+	}
+	if want := map[string]bool{"read": false, "pinned": false}; !reflect.DeepEqual(q.Is, want) {
+		t.Errorf("Is = %v, want %v", q.Is, want)
+	}
+	if q.Before == nil || !q.Before.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || q.After != nil {
+		t.Errorf("Before = %v, After = %v, want 2024-01-01 and nil", q.Before, q.After)
+	}
+	if want := []string{"release notes"}; !reflect.DeepEqual(q.Words, want) {
+		t.Errorf("Words = %q, want %q", q.Words, want)
+	}
+	if want := []string{"draft"}; !reflect.DeepEqual(q.ExcludedWords, want) {
+		t.Errorf("ExcludedWords = %q, want %q", q.ExcludedWords, want)
+	}
+}
+
+func TestParseBookmarkQueryErrors(t *testing.T) {
+	for _, q := range []string{
+		"color:red",
+		"is:lost",
+		"is:read is:unread",
+		"before:yesterday",
+		"-after:2024-01-01",
+		`tag:"unterminated`,
+	} {
+		if _, err := ParseBookmarkQuery(q); err == nil {
+			t.Errorf("ParseBookmarkQuery(%q) succeeded, want an error", q)
+		}
+	}
+}
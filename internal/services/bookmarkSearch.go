@@ -0,0 +1,420 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+const (
+	// searchCandidates is how many bookmarks sharing trigrams with the query are
+	// scored by fuzzy search.
+	searchCandidates = 200
+	// minTermSimilarity is the WordSimilarity every query term needs with some word
+	// of a bookmark for the bookmark to match.
+	minTermSimilarity = 0.5
+	maxSearchTerms    = 10
+	searchIndexBatch  = 200
+	// noteExcerptRadius is about how many characters of the notes a search result
+	// shows on each side of the first matching word.
+	noteExcerptRadius = 60
+)
+
+// bookmarkSearchGrams returns the trigrams bm is found by in fuzzy search.
+func bookmarkSearchGrams(bm *models.Bookmark) []string {
+	return utils.SearchGrams(bm.Title, bm.Summary, searchableURL(bm.URL))
+}
+
+// searchableURL keeps the host, without "www.", and the path of a URL; the scheme
+// and query would match almost every search for "https" or "utm".
+func searchableURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.") + " " + u.Path
+}
+
+// noteSearchGrams returns the blind index of notes: their search trigrams, hashed
+// with the user's key.
+func noteSearchGrams(ctx context.Context, encryption EncryptionService, userID primitive.ObjectID, notes string) ([]string, error) {
+	grams := utils.SearchGrams(notes)
+	if len(grams) == 0 {
+		return []string{}, nil
+	}
+	return encryption.BlindIndex(ctx, userID, grams)
+}
+
+// reindex stores the search trigrams of bm after its title, summary or URL changed.
+func (s *bookmarkServiceImpl) reindex(ctx context.Context, bm *models.Bookmark) {
+	bm.SearchGrams = bookmarkSearchGrams(bm)
+	update := bson.M{"$set": bson.M{"search_grams": bm.SearchGrams}}
+	if _, err := s.bookmarkRepo.UpdateOne(ctx, bson.M{"_id": bm.ID, "user_id": bm.UserID}, update); err != nil {
+		log.Warn().Err(err).Str("bookmarkID", bm.ID.Hex()).Msg("Failed to update bookmark search index")
+	}
+}
+
+// SearchBookmarks finds the user's bookmarks matching query in their title, summary,
+// URL or notes; the notes scope only looks at notes. Fuzzy search tolerates typos
+// and partly typed words; exact search uses the MongoDB text index, which matches
+// whole words with stemming, and matches notes holding words that start with each
+// term. fuzzy nil uses the BOOKMARK_SEARCH_FUZZY default. Every result tells where
+// it matched.
+func (s *bookmarkServiceImpl) SearchBookmarks(ctx context.Context, userID primitive.ObjectID, query string, fuzzy *bool, scope string, limit int) ([]models.Bookmark, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("query", query).Str("scope", scope).Msg("Attempting to search bookmarks")
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("search query is required")
+	}
+	if limit == 0 {
+		limit = models.DefaultSearchLimit
+	}
+	if limit < 1 || limit > models.MaxSearchLimit {
+		return nil, fmt.Errorf("invalid limit: must be between 1 and %d", models.MaxSearchLimit)
+	}
+	switch scope {
+	case "":
+		scope = models.SearchScopeAll
+	case models.SearchScopeAll:
+	case models.SearchScopeNotes:
+		if !s.encryption.Enabled() {
+			return nil, ErrEncryptionNotConfigured
+		}
+	default:
+		return nil, fmt.Errorf("invalid scope: must be %s or %s", models.SearchScopeAll, models.SearchScopeNotes)
+	}
+	useFuzzy := s.fuzzySearch
+	if fuzzy != nil {
+		useFuzzy = *fuzzy
+	}
+
+	terms := utils.SearchWords(query)
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+	var bookmarks []models.Bookmark
+	var err error
+	if useFuzzy {
+		bookmarks, err = s.fuzzySearchBookmarks(ctx, userID, terms, scope, limit)
+	} else {
+		bookmarks, err = s.exactSearchBookmarks(ctx, userID, query, terms, scope, limit)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Error searching bookmarks")
+		return nil, fmt.Errorf("failed to search bookmarks")
+	}
+	if bookmarks == nil {
+		bookmarks = []models.Bookmark{}
+	}
+
+	results := make([]*models.Bookmark, len(bookmarks))
+	for i := range bookmarks {
+		bm := &bookmarks[i]
+		if bm.SearchMatch.Notes {
+			bm.SearchMatch.NoteTerms, bm.SearchMatch.NoteExcerpt = noteMatches(bm.Notes, terms, useFuzzy)
+		}
+		results[i] = bm
+	}
+	s.attachHighlights(ctx, userID, results...)
+	return bookmarks, nil
+}
+
+// searchNoteCandidates returns the user's bookmarks whose notes share the most
+// trigrams with terms, with their notes decrypted. Without encryption there are no
+// notes to search.
+func (s *bookmarkServiceImpl) searchNoteCandidates(ctx context.Context, userID primitive.ObjectID, terms []string) ([]models.Bookmark, error) {
+	if !s.encryption.Enabled() || len(terms) == 0 {
+		return nil, nil
+	}
+	grams, err := s.encryption.BlindIndex(ctx, userID, utils.SearchGrams(strings.Join(terms, " ")))
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := s.bookmarkRepo.FindByGrams(ctx, userID, "note_grams", grams, searchCandidates)
+	if err != nil {
+		return nil, err
+	}
+	for i := range candidates {
+		s.decryptNotes(ctx, userID, &candidates[i])
+	}
+	return candidates, nil
+}
+
+// termScore returns how alike term is to the closest of words, from 0 to 1.
+func termScore(term string, words []string) float64 {
+	best := 0.0
+	for _, w := range words {
+		if sim := utils.WordSimilarity(term, w); sim > best {
+			best = sim
+		}
+	}
+	return best
+}
+
+// fuzzySearchBookmarks loads the bookmarks sharing the most trigrams with the query
+// and keeps those where every term is close to one of their words, best first. A
+// term may match the title, summary or URL and another one the notes.
+func (s *bookmarkServiceImpl) fuzzySearchBookmarks(ctx context.Context, userID primitive.ObjectID, terms []string, scope string, limit int) ([]models.Bookmark, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	candidates, err := s.searchNoteCandidates(ctx, userID, terms)
+	if err != nil {
+		return nil, err
+	}
+	if scope == models.SearchScopeAll {
+		byGrams, err := s.bookmarkRepo.FindByGrams(ctx, userID, "search_grams", utils.SearchGrams(strings.Join(terms, " ")), searchCandidates)
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[primitive.ObjectID]bool, len(candidates))
+		for _, bm := range candidates {
+			seen[bm.ID] = true
+		}
+		for i := range byGrams {
+			if !seen[byGrams[i].ID] {
+				s.decryptNotes(ctx, userID, &byGrams[i])
+				candidates = append(candidates, byGrams[i])
+			}
+		}
+	}
+
+	type scored struct {
+		bm    models.Bookmark
+		score float64
+	}
+	var matches []scored
+	for _, bm := range candidates {
+		var words []string
+		if scope == models.SearchScopeAll {
+			words = utils.SearchWords(bm.Title + " " + bm.Summary + " " + searchableURL(bm.URL))
+		}
+		noteWords := utils.SearchWords(bm.Notes)
+		match := &models.SearchMatch{}
+		total := 0.0
+		for _, term := range terms {
+			inBookmark, inNotes := termScore(term, words), termScore(term, noteWords)
+			match.Bookmark = match.Bookmark || inBookmark >= minTermSimilarity
+			match.Notes = match.Notes || inNotes >= minTermSimilarity
+			best := math.Max(inBookmark, inNotes)
+			if best < minTermSimilarity {
+				total = -1
+				break
+			}
+			total += best
+		}
+		if total > 0 {
+			bm.SearchMatch = match
+			matches = append(matches, scored{bm: bm, score: total})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].bm.CreatedAt > matches[j].bm.CreatedAt
+	})
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	bookmarks := make([]models.Bookmark, len(matches))
+	for i, m := range matches {
+		bookmarks[i] = m.bm
+	}
+	return bookmarks, nil
+}
+
+// exactSearchBookmarks lists the text search results, best first, followed by the
+// bookmarks matched by their notes only, newest first.
+func (s *bookmarkServiceImpl) exactSearchBookmarks(ctx context.Context, userID primitive.ObjectID, query string, terms []string, scope string, limit int) ([]models.Bookmark, error) {
+	var bookmarks []models.Bookmark
+	if scope == models.SearchScopeAll {
+		var err error
+		if bookmarks, err = s.bookmarkRepo.TextSearch(ctx, userID, query, int64(limit)); err != nil {
+			return nil, err
+		}
+	}
+	candidates, err := s.searchNoteCandidates(ctx, userID, terms)
+	if err != nil {
+		return nil, err
+	}
+	inNotes := make(map[primitive.ObjectID]bool)
+	var notesOnly []models.Bookmark
+	for _, bm := range candidates {
+		words := utils.SearchWords(bm.Notes)
+		matched := true
+		for _, term := range terms {
+			if termScore(term, words) < 1 {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			inNotes[bm.ID] = true
+			notesOnly = append(notesOnly, bm)
+		}
+	}
+
+	for i := range bookmarks {
+		bm := &bookmarks[i]
+		s.decryptNotes(ctx, userID, bm)
+		bm.SearchMatch = &models.SearchMatch{Bookmark: true, Notes: inNotes[bm.ID]}
+		delete(inNotes, bm.ID)
+	}
+	sort.SliceStable(notesOnly, func(i, j int) bool { return notesOnly[i].CreatedAt > notesOnly[j].CreatedAt })
+	for _, bm := range notesOnly {
+		if len(bookmarks) == limit {
+			break
+		}
+		if inNotes[bm.ID] {
+			bm.SearchMatch = &models.SearchMatch{Notes: true}
+			bookmarks = append(bookmarks, bm)
+		}
+	}
+	return bookmarks, nil
+}
+
+// noteMatches returns the distinct words of notes, as written, that match one of
+// terms, and the notes around the first of them, cut at word boundaries.
+func noteMatches(notes string, terms []string, fuzzy bool) ([]string, string) {
+	var matched []string
+	seen := map[string]bool{}
+	first, firstEnd := -1, -1
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		word := notes[start:end]
+		for _, w := range utils.SearchWords(word) {
+			for _, term := range terms {
+				sim := utils.WordSimilarity(term, w)
+				if sim < 1 && (!fuzzy || sim < minTermSimilarity) {
+					continue
+				}
+				if first < 0 {
+					first, firstEnd = start, end
+				}
+				if !seen[word] && len(matched) < maxSearchTerms {
+					seen[word] = true
+					matched = append(matched, word)
+				}
+			}
+		}
+		start = -1
+	}
+	for i, r := range notes {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(notes))
+	if first < 0 {
+		return nil, ""
+	}
+	return matched, noteExcerpt(notes, first, firstEnd)
+}
+
+// noteExcerpt returns the notes around notes[from:to], with about
+// noteExcerptRadius characters on each side.
+func noteExcerpt(notes string, from, to int) string {
+	begin, end := from, to
+	for n := 0; begin > 0 && n < noteExcerptRadius; n++ {
+		_, size := utf8.DecodeLastRuneInString(notes[:begin])
+		begin -= size
+	}
+	for n := 0; end < len(notes) && n < noteExcerptRadius; n++ {
+		_, size := utf8.DecodeRuneInString(notes[end:])
+		end += size
+	}
+	// Cut at the nearest space so the excerpt starts and ends with whole words.
+	if begin > 0 {
+		if i := strings.IndexAny(notes[begin:from], " \n\t"); i >= 0 {
+			begin += i + 1
+		}
+	}
+	if end < len(notes) {
+		if i := strings.LastIndexAny(notes[to:end], " \n\t"); i >= 0 {
+			end = to + i
+		}
+	}
+	excerpt := strings.Join(strings.Fields(notes[begin:end]), " ")
+	if begin > 0 {
+		excerpt = "…" + excerpt
+	}
+	if end < len(notes) {
+		excerpt += "…"
+	}
+	return excerpt
+}
+
+// BackfillSearchIndex computes the search trigrams of bookmarks that have none,
+// such as those saved before fuzzy search existed or whose summary was regenerated,
+// and the blind index of notes saved before notes were searchable. It runs as a
+// scheduled job and handles one batch of each per run.
+func (s *bookmarkServiceImpl) BackfillSearchIndex(ctx context.Context) error {
+	bookmarks, err := s.bookmarkRepo.Find(ctx, bson.M{"search_grams": nil}, searchIndexBatch, 1)
+	if err != nil {
+		return fmt.Errorf("failed to find bookmarks to index: %w", err)
+	}
+	for i := range bookmarks {
+		bm := &bookmarks[i]
+		update := bson.M{"$set": bson.M{"search_grams": bookmarkSearchGrams(bm)}}
+		if _, err := s.bookmarkRepo.UpdateOne(ctx, bson.M{"_id": bm.ID, "user_id": bm.UserID}, update); err != nil {
+			return fmt.Errorf("failed to index bookmark %s: %w", bm.ID.Hex(), err)
+		}
+	}
+	if len(bookmarks) > 0 {
+		log.Info().Int("count", len(bookmarks)).Msg("Indexed bookmarks for search")
+	}
+	return s.backfillNoteIndex(ctx)
+}
+
+func (s *bookmarkServiceImpl) backfillNoteIndex(ctx context.Context) error {
+	if !s.encryption.Enabled() {
+		return nil
+	}
+	filter := bson.M{"notes_enc": bson.M{"$nin": bson.A{nil, ""}}, "note_grams": bson.M{"$exists": false}}
+	bookmarks, err := s.bookmarkRepo.Find(ctx, filter, searchIndexBatch, 1)
+	if err != nil {
+		return fmt.Errorf("failed to find notes to index: %w", err)
+	}
+	indexed := 0
+	for i := range bookmarks {
+		bm := &bookmarks[i]
+		notes, err := s.encryption.Decrypt(ctx, bm.UserID, bm.EncryptedNotes)
+		if err != nil {
+			log.Warn().Err(err).Str("bookmarkID", bm.ID.Hex()).Msg("Failed to decrypt bookmark notes for search indexing")
+			continue
+		}
+		grams, err := noteSearchGrams(ctx, s.encryption, bm.UserID, notes)
+		if err != nil {
+			return fmt.Errorf("failed to index notes of bookmark %s: %w", bm.ID.Hex(), err)
+		}
+		if _, err := s.bookmarkRepo.UpdateOne(ctx, bson.M{"_id": bm.ID, "user_id": bm.UserID}, bson.M{"$set": bson.M{"note_grams": grams}}); err != nil {
+			return fmt.Errorf("failed to index notes of bookmark %s: %w", bm.ID.Hex(), err)
+		}
+		indexed++
+	}
+	if indexed > 0 {
+		log.Info().Int("count", indexed).Msg("Indexed bookmark notes for search")
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNoteMatches(t *testing.T) {
+	notes := "Read before the Kubernetes migration. Deploying with Helm is covered in part two."
+	terms, excerpt := noteMatches(notes, []string{"deploy", "kubernetis"}, true)
+	if want := []string{"Kubernetes", "Deploying"}; !reflect.DeepEqual(terms, want) {
+		t.Errorf("fuzzy terms = %v, want %v", terms, want)
+	}
+	if excerpt != notes {
+		t.Errorf("short notes excerpt = %q", excerpt)
+	}
+
+	// Exact matching only accepts words starting with a term.
+	if terms, _ := noteMatches(notes, []string{"deploy", "kubernetis"}, false); !reflect.DeepEqual(terms, []string{"Deploying"}) {
+		t.Errorf("exact terms = %v", terms)
+	}
+	if terms, excerpt := noteMatches(notes, []string{"terraform"}, true); terms != nil || excerpt != "" {
+		t.Errorf("no match = %v, %q", terms, excerpt)
+	}
+}
+
+func TestNoteExcerpt(t *testing.T) {
+	notes := strings.Repeat("lorem ipsum ", 20) + "needle" + strings.Repeat(" dolor sit", 20)
+	terms, excerpt := noteMatches(notes, []string{"needle"}, false)
+	if !reflect.DeepEqual(terms, []string{"needle"}) {
+		t.Fatalf("terms = %v", terms)
+	}
+	if !strings.HasPrefix(excerpt, "…ipsum ") && !strings.HasPrefix(excerpt, "…lorem ") {
+		t.Errorf("excerpt does not start with a whole word: %q", excerpt)
+	}
+	if !strings.HasSuffix(excerpt, " sit…") && !strings.HasSuffix(excerpt, " dolor…") {
+		t.Errorf("excerpt does not end with a whole word: %q", excerpt)
+	}
+	if !strings.Contains(excerpt, " needle ") || len([]rune(excerpt)) > 2*noteExcerptRadius+10 {
+		t.Errorf("excerpt = %q", excerpt)
+	}
+}
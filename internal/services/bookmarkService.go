@@ -2,8 +2,11 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"sort"
 	"strconv"
 	"time"
 
@@ -19,23 +22,110 @@ import (
 )
 
 type BookmarkService interface {
-	GetBookmarks(ctx context.Context, userID primitive.ObjectID, r *http.Request) ([]models.Bookmark, error)
+	GetBookmarks(ctx context.Context, userID primitive.ObjectID, r *http.Request) ([]models.Bookmark, string, error)
+	GetBookmarksLite(ctx context.Context, userID primitive.ObjectID, r *http.Request) ([]models.BookmarkLite, string, error)
 	AddBookmark(ctx context.Context, userID primitive.ObjectID, reqBody models.AddBookmarkRequestBody) (*models.Bookmark, error)
 	GetBookmarkByID(ctx context.Context, userID, bookmarkID primitive.ObjectID) (*models.Bookmark, error)
 	DeleteBookmark(ctx context.Context, userID, bookmarkID primitive.ObjectID) (bool, error)
 	UpdateBookmark(ctx context.Context, userID, bookmarkID primitive.ObjectID, updatePayload models.UpdateBookmarkRequestBody) (*models.Bookmark, error)
+	SetFavorite(ctx context.Context, userID, bookmarkID primitive.ObjectID, fav bool) (*models.Bookmark, error)
+	GetDuplicateBookmarks(ctx context.Context, userID primitive.ObjectID) ([]models.DuplicateGroup, error)
+	GetContentDuplicates(ctx context.Context, userID primitive.ObjectID) ([]models.DuplicateGroup, error)
+	SuggestTags(ctx context.Context, userID primitive.ObjectID, rawURL string) ([]string, error)
+	MergeBookmarks(ctx context.Context, userID, targetID, sourceID primitive.ObjectID) (*models.Bookmark, error)
+	BulkTag(ctx context.Context, userID primitive.ObjectID, r *http.Request, reqBody models.BulkTagRequest) (*models.BulkTagResult, error)
+	BatchCreate(ctx context.Context, userID primitive.ObjectID, items []models.AddBookmarkRequestBody) (*models.BatchCreateResult, error)
+	ExpandCategories(ctx context.Context, userID primitive.ObjectID, bookmarks ...*models.Bookmark)
+	SearchBookmarks(ctx context.Context, userID primitive.ObjectID, query string, fuzzy *bool, scope string, limit int) ([]models.Bookmark, error)
+	BackfillSearchIndex(ctx context.Context) error
 }
 
 type bookmarkServiceImpl struct {
 	bookmarkRepo repositories.BookmarkRepository
+	categoryRepo repositories.CategoryRepository
+	tagRepo      repositories.TagRepository
 	db           database.Service
+	encryption   EncryptionService
+	urls         URLService
+	tagSuggester TagSuggestionService
+	contents     BookmarkContentService
+	highlights   HighlightService
+	webhooks     ActivityWebhookService
+	ownership    *Ownership
+	// fuzzySearch is the default search mode; BOOKMARK_SEARCH_FUZZY=false makes
+	// exact text search the default.
+	fuzzySearch bool
 }
 
-func NewBookmarkService(bookmarkRepo repositories.BookmarkRepository, db database.Service) BookmarkService {
-	return &bookmarkServiceImpl{bookmarkRepo: bookmarkRepo, db: db}
+func NewBookmarkService(bookmarkRepo repositories.BookmarkRepository, categoryRepo repositories.CategoryRepository, tagRepo repositories.TagRepository, db database.Service, encryption EncryptionService, urls URLService, tagSuggester TagSuggestionService, contents BookmarkContentService, highlights HighlightService, webhooks ActivityWebhookService, ownership *Ownership) BookmarkService {
+	return &bookmarkServiceImpl{bookmarkRepo: bookmarkRepo, categoryRepo: categoryRepo, tagRepo: tagRepo, db: db, encryption: encryption, urls: urls, tagSuggester: tagSuggester, contents: contents, highlights: highlights, webhooks: webhooks, ownership: ownership, fuzzySearch: os.Getenv("BOOKMARK_SEARCH_FUZZY") != "false"}
 }
 
-func (s *bookmarkServiceImpl) buildBookmarkFilter(r *http.Request, userID primitive.ObjectID) (bson.M, error) {
+// attachHighlights fills in the highlights of bookmarks. Failing to load them is
+// logged rather than failing the request.
+func (s *bookmarkServiceImpl) attachHighlights(ctx context.Context, userID primitive.ObjectID, bookmarks ...*models.Bookmark) {
+	if err := s.highlights.AttachHighlights(ctx, userID, bookmarks); err != nil {
+		log.Warn().Err(err).Str("userID", userID.Hex()).Msg("Failed to load bookmark highlights")
+	}
+}
+
+// ExpandCategories fills in the category details of bookmarks with one query.
+// Failing to load them is logged rather than failing the request.
+func (s *bookmarkServiceImpl) ExpandCategories(ctx context.Context, userID primitive.ObjectID, bookmarks ...*models.Bookmark) {
+	var ids []primitive.ObjectID
+	for _, bm := range bookmarks {
+		if bm.CategoryID != nil && !containsObjectID(ids, *bm.CategoryID) {
+			ids = append(ids, *bm.CategoryID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	categories, err := s.categoryRepo.FindByIDs(ctx, userID, ids)
+	if err != nil {
+		log.Warn().Err(err).Str("userID", userID.Hex()).Msg("Failed to load bookmark categories")
+		return
+	}
+	byID := make(map[primitive.ObjectID]*models.CategorySummary, len(categories))
+	for _, c := range categories {
+		setCategoryIconURL(&c)
+		byID[c.ID] = &models.CategorySummary{ID: c.ID, Name: c.Name, Slug: c.Slug, Emoji: c.Emoji, Color: c.Color, IconURL: c.IconURL}
+	}
+	for _, bm := range bookmarks {
+		if bm.CategoryID != nil {
+			bm.CategoryDetails = byID[*bm.CategoryID]
+		}
+	}
+}
+
+// canonicalURL returns the canonical form of rawURL, or an empty string when it cannot
+// be canonicalized so that saving the bookmark is never blocked by it.
+func (s *bookmarkServiceImpl) canonicalURL(ctx context.Context, rawURL string) string {
+	canonical, err := s.urls.Canonicalize(ctx, rawURL)
+	if err != nil {
+		log.Warn().Err(err).Str("url", rawURL).Msg("Failed to canonicalize bookmark URL")
+		return ""
+	}
+	return canonical
+}
+
+// decryptNotes fills in the plaintext Notes of bookmarks that carry an encrypted note.
+// A note that cannot be decrypted is left empty rather than failing the whole request.
+func (s *bookmarkServiceImpl) decryptNotes(ctx context.Context, userID primitive.ObjectID, bookmarks ...*models.Bookmark) {
+	for _, bm := range bookmarks {
+		if bm.EncryptedNotes == "" {
+			continue
+		}
+		notes, err := s.encryption.Decrypt(ctx, userID, bm.EncryptedNotes)
+		if err != nil {
+			log.Warn().Err(err).Str("bookmarkID", bm.ID.Hex()).Msg("Failed to decrypt bookmark notes")
+			continue
+		}
+		bm.Notes = notes
+	}
+}
+
+func (s *bookmarkServiceImpl) buildBookmarkFilter(ctx context.Context, r *http.Request, userID primitive.ObjectID) (bson.M, error) {
 	log.Debug().Str("userID", userID.Hex()).Msg("Building bookmark filter")
 	filter := bson.M{"user_id": userID}
 
@@ -78,48 +168,176 @@ func (s *bookmarkServiceImpl) buildBookmarkFilter(r *http.Request, userID primit
 		}
 		filter["is_fav"] = isFav
 	}
+
+	sourceParam := r.URL.Query().Get("source")
+	if sourceParam != "" {
+		if !models.IsValidSourceClient(sourceParam) && sourceParam != models.SourceClientFeed {
+			log.Warn().Str("sourceParam", sourceParam).Msg("Invalid source client")
+			return nil, fmt.Errorf("invalid source format. Must be one of 'extension', 'web', 'mobile', 'api' or 'feed'.")
+		}
+		filter["source.client"] = sourceParam
+	}
+
+	if langParam := r.URL.Query().Get("lang"); langParam != "" {
+		lang := utils.NormalizeLanguage(langParam)
+		if lang == "" {
+			log.Warn().Str("langParam", langParam).Msg("Invalid lang")
+			return nil, fmt.Errorf("invalid lang. Must be a language code such as 'en'.")
+		}
+		filter["language"] = lang
+	}
+
+	for param, field := range map[string]string{"read": "read", "pinned": "pinned"} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Warn().Err(err).Str(param+"Param", value).Msg("Invalid " + param + " format")
+			return nil, fmt.Errorf("invalid %s format. Must be 'true' or 'false'.", param)
+		}
+		if b {
+			filter[field] = true
+		} else {
+			filter[field] = bson.M{"$ne": true}
+		}
+	}
+
+	// Archived bookmarks are hidden unless explicitly requested.
+	archivedParam := r.URL.Query().Get("archived")
+	archived := false
+	if archivedParam != "" {
+		var err error
+		archived, err = strconv.ParseBool(archivedParam)
+		if err != nil {
+			log.Warn().Err(err).Str("archivedParam", archivedParam).Msg("Invalid archived format")
+			return nil, fmt.Errorf("invalid archived format. Must be 'true' or 'false'.")
+		}
+	}
+	if archived {
+		filter["archived_at"] = bson.M{"$ne": nil}
+	} else {
+		filter["archived_at"] = nil
+	}
+
+	// q holds the compact query syntax; its clauses narrow the filters above, and
+	// is:archived overrides the archived parameter.
+	if q := r.URL.Query().Get("q"); q != "" {
+		query, err := ParseBookmarkQuery(q)
+		if err != nil {
+			log.Warn().Err(err).Str("q", q).Msg("Invalid bookmark query")
+			return nil, err
+		}
+		if want, ok := query.Is["archived"]; ok {
+			if want {
+				filter["archived_at"] = bson.M{"$ne": nil}
+			} else {
+				filter["archived_at"] = nil
+			}
+		}
+		clauses, err := s.queryClauses(ctx, userID, query)
+		if err != nil {
+			return nil, err
+		}
+		if len(clauses) > 0 {
+			filter["$and"] = clauses
+		}
+	}
 	log.Debug().Str("userID", userID.Hex()).Interface("filter", filter).Msg("Bookmark filter built successfully")
 	return filter, nil
 }
 
-func (s *bookmarkServiceImpl) GetBookmarks(ctx context.Context, userID primitive.ObjectID, r *http.Request) ([]models.Bookmark, error) {
+// GetBookmarksLite lists the id, title and url of the bookmarks matching the same
+// filters as GetBookmarks. It is always paged, with the default page size when no
+// limit is given.
+func (s *bookmarkServiceImpl) GetBookmarksLite(ctx context.Context, userID primitive.ObjectID, r *http.Request) ([]models.BookmarkLite, string, error) {
+	filter, err := s.buildBookmarkFilter(ctx, r, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	pageReq, err := utils.GetPageRequest(r)
+	if err != nil {
+		return nil, "", err
+	}
+	if pageReq.Limit == 0 {
+		pageReq.Limit = utils.CurrentLimits().DefaultPageSize
+	}
+	bookmarks, next, err := s.bookmarkRepo.FindLitePage(ctx, filter, pageReq)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Error finding lite bookmarks")
+		return nil, "", err
+	}
+	return bookmarks, next, nil
+}
+
+func (s *bookmarkServiceImpl) GetBookmarks(ctx context.Context, userID primitive.ObjectID, r *http.Request) ([]models.Bookmark, string, error) {
 	log.Debug().Str("userID", userID.Hex()).Msg("Attempting to retrieve bookmarks")
-	filter, err := s.buildBookmarkFilter(r, userID)
+	filter, err := s.buildBookmarkFilter(ctx, r, userID)
 	if err != nil {
 		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to build bookmark filter")
-		return nil, err
+		return nil, "", err
 	}
 
-	var limit int64 = 5
-	page, err := strconv.Atoi(r.URL.Query().Get("page"))
-
+	pageReq, err := utils.GetPageRequest(r)
 	if err != nil {
-		log.Error().Err(err).Msg("Page Query should be an integer")
-		return nil, err
+		return nil, "", err
 	}
 
-	bookmarks, err := s.bookmarkRepo.Find(ctx, filter, limit, int64(page))
+	var bookmarks []models.Bookmark
+	var next string
+	if pageReq.Paged() {
+		bookmarks, next, err = s.bookmarkRepo.FindPage(ctx, filter, pageReq)
+	} else {
+		limit := utils.CurrentLimits().BookmarkPageSize
+		page, convErr := strconv.Atoi(r.URL.Query().Get("page"))
+		if convErr != nil {
+			log.Error().Err(convErr).Msg("Page Query should be an integer")
+			return nil, "", convErr
+		}
+		bookmarks, err = s.bookmarkRepo.Find(ctx, filter, limit, int64(page))
+	}
 	if err != nil {
 		log.Error().Err(err).Str("userID", userID.Hex()).Interface("filter", filter).Msg("Error finding bookmarks")
-		return nil, err
+		return nil, "", err
+	}
+
+	results := make([]*models.Bookmark, len(bookmarks))
+	for i := range bookmarks {
+		s.decryptNotes(ctx, userID, &bookmarks[i])
+		results[i] = &bookmarks[i]
 	}
+	s.attachHighlights(ctx, userID, results...)
 
 	log.Debug().Str("userID", userID.Hex()).Int("count", len(bookmarks)).Msg("Successfully retrieved bookmarks")
-	return bookmarks, nil
+	return bookmarks, next, nil
 }
 
-func (s *bookmarkServiceImpl) AddBookmark(ctx context.Context, userID primitive.ObjectID, reqBody models.AddBookmarkRequestBody) (*models.Bookmark, error) {
-	log.Debug().Str("userID", userID.Hex()).Interface("reqBody", reqBody).Msg("Attempting to add bookmark")
+// parsedAddRequest holds the fields of an AddBookmarkRequestBody after parsing.
+type parsedAddRequest struct {
+	url         string
+	originalURL string
+	tags        []primitive.ObjectID
+	collections []primitive.ObjectID
+	category    *primitive.ObjectID
+	// offline canonicalizes the URL without following redirects, so that a batch
+	// does not make a request per bookmark.
+	offline bool
+}
+
+// parseAddRequest checks the fields of a new bookmark that need no database access.
+func parseAddRequest(userID primitive.ObjectID, reqBody models.AddBookmarkRequestBody) (*parsedAddRequest, error) {
 	if reqBody.URL == "" || reqBody.Title == "" {
 		log.Warn().Str("userID", userID.Hex()).Msg("URL and Title are required for adding bookmark")
 		return nil, fmt.Errorf("URL and Title are required")
 	}
 
-	var (
-		tagsObjectIDs        []primitive.ObjectID
-		collectionsObjectIDs []primitive.ObjectID
-		categoryObjectIDPtr  *primitive.ObjectID
-	)
+	normalized, err := utils.NormalizeURL(reqBody.URL)
+	if err != nil {
+		log.Warn().Err(err).Str("userID", userID.Hex()).Str("url", reqBody.URL).Msg("Invalid URL during AddBookmark")
+		return nil, err
+	}
+	p := &parsedAddRequest{url: normalized, originalURL: originalURL(reqBody.URL, normalized)}
 
 	for _, tagIDStr := range reqBody.Tags {
 		if tagIDStr == "" {
@@ -130,7 +348,10 @@ func (s *bookmarkServiceImpl) AddBookmark(ctx context.Context, userID primitive.
 			log.Warn().Err(err).Str("userID", userID.Hex()).Str("tagIDStr", tagIDStr).Msg("Invalid tag ID format during AddBookmark")
 			return nil, fmt.Errorf("invalid tag ID format: %s", tagIDStr)
 		}
-		tagsObjectIDs = append(tagsObjectIDs, objID)
+		p.tags = append(p.tags, objID)
+	}
+	if err := checkTagLimit(len(p.tags)); err != nil {
+		return nil, err
 	}
 
 	for _, colIDStr := range reqBody.Collections {
@@ -142,7 +363,7 @@ func (s *bookmarkServiceImpl) AddBookmark(ctx context.Context, userID primitive.
 			log.Warn().Err(err).Str("userID", userID.Hex()).Str("colIDStr", colIDStr).Msg("Invalid collection ID format during AddBookmark")
 			return nil, fmt.Errorf("invalid collection ID format: %s", colIDStr)
 		}
-		collectionsObjectIDs = append(collectionsObjectIDs, objID)
+		p.collections = append(p.collections, objID)
 	}
 
 	if reqBody.CategoryID != nil && *reqBody.CategoryID != "" {
@@ -151,37 +372,262 @@ func (s *bookmarkServiceImpl) AddBookmark(ctx context.Context, userID primitive.
 			log.Warn().Err(err).Str("userID", userID.Hex()).Str("categoryIDStr", *reqBody.CategoryID).Msg("Invalid category ID format during AddBookmark")
 			return nil, fmt.Errorf("invalid category ID format: %s", *reqBody.CategoryID)
 		}
-		categoryObjectIDPtr = &catID
+		p.category = &catID
 	}
 
-	if err := utils.ValidateReferences(s.db.Client(), userID, tagsObjectIDs, collectionsObjectIDs, categoryObjectIDPtr); err != nil {
-		log.Warn().Err(err).Str("userID", userID.Hex()).Msg("Invalid reference during AddBookmark")
-		return nil, fmt.Errorf("invalid reference: %w", err)
+	if reqBody.Source != nil && !models.IsValidSourceClient(reqBody.Source.Client) {
+		log.Warn().Str("userID", userID.Hex()).Str("client", reqBody.Source.Client).Msg("Invalid source client during AddBookmark")
+		return nil, fmt.Errorf("invalid source client: %s", reqBody.Source.Client)
 	}
+	return p, nil
+}
 
-	bm := models.Bookmark{
+// originalURL returns the URL as the user entered it when it differs from its
+// normalized form, so clients can display what was typed.
+func originalURL(raw, normalized string) string {
+	if raw == normalized {
+		return ""
+	}
+	return raw
+}
+
+// requestSource copies the source a client sent. FeedID is left out: only feed
+// ingestion sets it.
+func requestSource(source *models.BookmarkSource) *models.BookmarkSource {
+	if source == nil {
+		return nil
+	}
+	return &models.BookmarkSource{Client: source.Client, Referrer: source.Referrer}
+}
+
+// newBookmark builds a bookmark from a request whose references were already
+// validated, applying domain tags and encrypting the notes.
+func (s *bookmarkServiceImpl) newBookmark(ctx context.Context, userID primitive.ObjectID, reqBody models.AddBookmarkRequestBody, p *parsedAddRequest) (*models.Bookmark, error) {
+	tagsObjectIDs := p.tags
+	// Domain tags never block saving: on failure the bookmark is saved without them.
+	suggestedTags, settings, err := s.tagSuggester.SuggestTagNames(ctx, userID, p.url)
+	if err != nil {
+		log.Warn().Err(err).Str("userID", userID.Hex()).Msg("Failed to suggest domain tags during AddBookmark")
+		suggestedTags = nil
+	}
+	if len(suggestedTags) > 0 && settings.AutoApplyDomainTags {
+		domainTagIDs, err := s.tagSuggester.ResolveTags(ctx, userID, suggestedTags)
+		if err != nil {
+			log.Warn().Err(err).Str("userID", userID.Hex()).Msg("Failed to apply domain tags during AddBookmark")
+		}
+		for _, id := range domainTagIDs {
+			if len(tagsObjectIDs) >= utils.CurrentLimits().MaxTagsPerBookmark {
+				break
+			}
+			if !containsObjectID(tagsObjectIDs, id) {
+				tagsObjectIDs = append(tagsObjectIDs, id)
+			}
+		}
+		suggestedTags = nil
+	}
+
+	var canonical string
+	if p.offline {
+		canonical, err = s.urls.CanonicalizeOffline(p.url)
+		if err != nil {
+			log.Warn().Err(err).Str("url", p.url).Msg("Failed to canonicalize bookmark URL")
+			canonical = ""
+		}
+	} else {
+		canonical = s.canonicalURL(ctx, p.url)
+	}
+
+	bm := &models.Bookmark{
 		ID:            primitive.NewObjectID(),
 		CreatedAt:     primitive.NewDateTimeFromTime(time.Now()),
 		UserID:        userID,
-		URL:           reqBody.URL,
+		URL:           p.url,
+		OriginalURL:   p.originalURL,
+		CanonicalURL:  canonical,
 		Title:         reqBody.Title,
 		Summary:       reqBody.Summary,
 		TagsID:        tagsObjectIDs,
-		CollectionsID: collectionsObjectIDs,
-		CategoryID:    categoryObjectIDPtr,
+		CollectionsID: p.collections,
+		CategoryID:    p.category,
 		IsFav:         reqBody.IsFav,
+		Source:        requestSource(reqBody.Source),
+		SuggestedTags: suggestedTags,
+	}
+	bm.SearchGrams = bookmarkSearchGrams(bm)
+
+	if reqBody.Notes != "" {
+		encrypted, err := s.encryption.Encrypt(ctx, userID, reqBody.Notes)
+		if err != nil {
+			log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to encrypt bookmark notes")
+			return nil, err
+		}
+		bm.EncryptedNotes = encrypted
+		if bm.NoteGrams, err = noteSearchGrams(ctx, s.encryption, userID, reqBody.Notes); err != nil {
+			log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to index bookmark notes")
+			return nil, err
+		}
+	}
+	return bm, nil
+}
+
+func (s *bookmarkServiceImpl) AddBookmark(ctx context.Context, userID primitive.ObjectID, reqBody models.AddBookmarkRequestBody) (*models.Bookmark, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("url", reqBody.URL).Int("tags", len(reqBody.Tags)).Int("collections", len(reqBody.Collections)).Bool("notes", reqBody.Notes != "").Msg("Attempting to add bookmark")
+	parsed, err := parseAddRequest(userID, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := utils.ValidateReferences(s.db.Client(), userID, parsed.tags, parsed.collections, parsed.category); err != nil {
+		log.Warn().Err(err).Str("userID", userID.Hex()).Msg("Invalid reference during AddBookmark")
+		return nil, fmt.Errorf("invalid reference: %w", err)
+	}
+
+	bm, err := s.newBookmark(ctx, userID, reqBody, parsed)
+	if err != nil {
+		return nil, err
 	}
 
-	createdBookmark, err := s.bookmarkRepo.Create(ctx, &bm)
+	createdBookmark, err := s.bookmarkRepo.Create(ctx, bm)
 	if err != nil {
 		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Error inserting bookmark")
 		return nil, err
 	}
 
+	s.contents.ExtractAsync(userID, createdBookmark.ID, createdBookmark.URL)
+	s.webhooks.Send(ctx, userID, models.ActivityBookmarkCreated, createdBookmark)
+
+	createdBookmark.Notes = reqBody.Notes
 	log.Info().Str("userID", userID.Hex()).Str("bookmarkID", createdBookmark.ID.Hex()).Msg("Bookmark added successfully")
 	return createdBookmark, nil
 }
 
+// BatchCreate adds many bookmarks in one request. Invalid items are reported in
+// their result instead of failing the batch. References are checked with one query
+// per kind for the whole batch, and the valid bookmarks are inserted together.
+func (s *bookmarkServiceImpl) BatchCreate(ctx context.Context, userID primitive.ObjectID, items []models.AddBookmarkRequestBody) (*models.BatchCreateResult, error) {
+	log.Debug().Str("userID", userID.Hex()).Int("count", len(items)).Msg("Attempting to batch create bookmarks")
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no bookmarks provided")
+	}
+	if max := utils.CurrentLimits().MaxBatchSize; len(items) > max {
+		return nil, fmt.Errorf("%w: at most %d bookmarks per batch", utils.ErrLimitExceeded, max)
+	}
+
+	results := make([]models.BatchCreateItemResult, len(items))
+	refs := make([]*parsedAddRequest, len(items))
+	var allTags, allCollections, allCategories []primitive.ObjectID
+	for i, item := range items {
+		results[i].Index = i
+		ref, err := parseAddRequest(userID, item)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		refs[i] = ref
+		allTags = unionObjectIDs(allTags, ref.tags)
+		allCollections = unionObjectIDs(allCollections, ref.collections)
+		if ref.category != nil {
+			allCategories = unionObjectIDs(allCategories, []primitive.ObjectID{*ref.category})
+		}
+	}
+
+	client := s.db.Client()
+	ownedTags, err := utils.OwnedIDs(ctx, client, "tags", userID, allTags)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to validate tags during BatchCreate")
+		return nil, fmt.Errorf("failed to validate references")
+	}
+	ownedCollections, err := utils.OwnedIDs(ctx, client, "collections", userID, allCollections)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to validate collections during BatchCreate")
+		return nil, fmt.Errorf("failed to validate references")
+	}
+	ownedCategories, err := utils.OwnedIDs(ctx, client, "categories", userID, allCategories)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to validate categories during BatchCreate")
+		return nil, fmt.Errorf("failed to validate references")
+	}
+
+	var (
+		toInsert []*models.Bookmark
+		indexes  []int // request index of each bookmark in toInsert
+	)
+	for i, ref := range refs {
+		if ref == nil {
+			continue
+		}
+		if err := checkOwned(ref.tags, ownedTags, "tags"); err != nil {
+			results[i].Error = "invalid reference: " + err.Error()
+			continue
+		}
+		if err := checkOwned(ref.collections, ownedCollections, "collections"); err != nil {
+			results[i].Error = "invalid reference: " + err.Error()
+			continue
+		}
+		if ref.category != nil && !ownedCategories[*ref.category] {
+			results[i].Error = "invalid reference: category not found or does not belong to user"
+			continue
+		}
+		ref.offline = true
+		bm, err := s.newBookmark(ctx, userID, items[i], ref)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		toInsert = append(toInsert, bm)
+		indexes = append(indexes, i)
+	}
+
+	failedInserts := make(map[int]string)
+	if len(toInsert) > 0 {
+		if err := s.bookmarkRepo.CreateMany(ctx, toInsert); err != nil {
+			var bulkErr mongo.BulkWriteException
+			if !errors.As(err, &bulkErr) || len(bulkErr.WriteErrors) == 0 {
+				log.Error().Err(err).Str("userID", userID.Hex()).Msg("Error inserting bookmarks")
+				return nil, fmt.Errorf("failed to add bookmarks")
+			}
+			for _, we := range bulkErr.WriteErrors {
+				failedInserts[we.Index] = "failed to add bookmark"
+			}
+		}
+	}
+
+	var created []*models.Bookmark
+	for j, bm := range toInsert {
+		i := indexes[j]
+		if msg, failed := failedInserts[j]; failed {
+			results[i].Error = msg
+			continue
+		}
+		s.contents.ExtractAsync(userID, bm.ID, bm.URL)
+		created = append(created, bm)
+		bm.Notes = items[i].Notes
+		results[i].Bookmark = bm
+	}
+	s.webhooks.Send(ctx, userID, models.ActivityBookmarkCreated, created...)
+
+	out := &models.BatchCreateResult{Results: results}
+	for _, r := range results {
+		if r.Error != "" {
+			out.Failed++
+		} else {
+			out.Created++
+		}
+	}
+	log.Info().Str("userID", userID.Hex()).Int("created", out.Created).Int("failed", out.Failed).Msg("Batch create completed")
+	return out, nil
+}
+
+// checkOwned reports an error unless every id is in owned.
+func checkOwned(ids []primitive.ObjectID, owned map[primitive.ObjectID]bool, kind string) error {
+	for _, id := range ids {
+		if !owned[id] {
+			return fmt.Errorf("one or more %s not found or do not belong to user", kind)
+		}
+	}
+	return nil
+}
+
 func (s *bookmarkServiceImpl) GetBookmarkByID(ctx context.Context, userID, bookmarkID primitive.ObjectID) (*models.Bookmark, error) {
 	log.Debug().Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Msg("Attempting to retrieve bookmark by ID")
 	filter := bson.M{"_id": bookmarkID, "user_id": userID}
@@ -190,11 +636,13 @@ func (s *bookmarkServiceImpl) GetBookmarkByID(ctx context.Context, userID, bookm
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			log.Warn().Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Msg("Bookmark not found")
-			return nil, fmt.Errorf("bookmark not found")
+			return nil, s.ownership.Missing(ctx, userID, ResourceBookmark, bookmarkID, fmt.Errorf("bookmark not found"))
 		}
 		log.Error().Err(err).Str("bookmark_id", bookmarkID.Hex()).Str("userID", userID.Hex()).Msg("Error finding bookmark by ID")
 		return nil, fmt.Errorf("failed to retrieve bookmark")
 	}
+	s.decryptNotes(ctx, userID, bm)
+	s.attachHighlights(ctx, userID, bm)
 	log.Debug().Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Msg("Successfully retrieved bookmark by ID")
 	return bm, nil
 }
@@ -203,32 +651,82 @@ func (s *bookmarkServiceImpl) DeleteBookmark(ctx context.Context, userID, bookma
 	log.Debug().Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Msg("Attempting to delete bookmark")
 	filter := bson.M{"_id": bookmarkID, "user_id": userID}
 
-	deleteResult, err := s.bookmarkRepo.DeleteOne(ctx, filter)
+	deleted, err := s.bookmarkRepo.FindOneAndDelete(ctx, filter)
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			log.Warn().Str("bookmark_id", bookmarkID.Hex()).Str("userID", userID.Hex()).Msg("Bookmark not found or not authorized to delete")
+			return false, s.ownership.Missing(ctx, userID, ResourceBookmark, bookmarkID, fmt.Errorf("bookmark not found or not authorized to delete"))
+		}
 		log.Error().Err(err).Str("bookmark_id", bookmarkID.Hex()).Str("userID", userID.Hex()).Msg("Error deleting bookmark")
-		return false, err
+		return false, fmt.Errorf("failed to delete bookmark: %w", err)
 	}
-
-	if deleteResult.DeletedCount == 0 {
-		log.Warn().Str("bookmark_id", bookmarkID.Hex()).Str("userID", userID.Hex()).Msg("Bookmark not found or not authorized to delete")
-		return false, fmt.Errorf("bookmark not found or not authorized to delete")
+	if err := s.contents.DeleteForBookmark(ctx, userID, bookmarkID); err != nil {
+		log.Warn().Err(err).Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Msg("Failed to delete content of deleted bookmark")
 	}
+	if err := s.highlights.DeleteForBookmark(ctx, userID, bookmarkID); err != nil {
+		log.Warn().Err(err).Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Msg("Failed to delete highlights of deleted bookmark")
+	}
+	s.webhooks.Send(ctx, userID, models.ActivityBookmarkDeleted, deleted)
 	log.Info().Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Msg("Bookmark deleted successfully")
 	return true, nil
 }
 
-func (s *bookmarkServiceImpl) buildUpdateFields(updatePayload models.UpdateBookmarkRequestBody, userID primitive.ObjectID) (bson.M, error) {
-	log.Debug().Str("userID", userID.Hex()).Interface("updatePayload", updatePayload).Msg("Building update fields for bookmark")
+// updatedBookmarkFields names the fields set in an update, for logging it without
+// the values: notes must not reach the logs.
+func updatedBookmarkFields(p models.UpdateBookmarkRequestBody) []string {
+	fields := []string{}
+	for name, set := range map[string]bool{
+		"url":         p.URL != nil,
+		"title":       p.Title != nil,
+		"summary":     p.Summary != nil,
+		"tags":        p.Tags != nil,
+		"collections": p.Collections != nil,
+		"category_id": p.CategoryID != nil,
+		"is_fav":      p.IsFav != nil,
+		"notes":       p.Notes != nil,
+		"pinned":      p.Pinned != nil,
+		"read":        p.Read != nil,
+		"archived":    p.Archived != nil,
+	} {
+		if set {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func (s *bookmarkServiceImpl) buildUpdateFields(ctx context.Context, updatePayload models.UpdateBookmarkRequestBody, userID primitive.ObjectID) (bson.M, error) {
+	log.Debug().Str("userID", userID.Hex()).Strs("fields", updatedBookmarkFields(updatePayload)).Msg("Building update fields for bookmark")
 	updateFields := bson.M{}
 
 	if updatePayload.URL != nil {
-		updateFields["url"] = *updatePayload.URL
+		normalized, err := utils.NormalizeURL(*updatePayload.URL)
+		if err != nil {
+			log.Warn().Err(err).Str("userID", userID.Hex()).Str("url", *updatePayload.URL).Msg("Invalid URL during UpdateBookmark")
+			return nil, err
+		}
+		updateFields["url"] = normalized
+		updateFields["original_url"] = originalURL(*updatePayload.URL, normalized)
+		if canonical := s.canonicalURL(ctx, normalized); canonical != "" {
+			updateFields["canonical_url"] = canonical
+		}
+		// The metadata belongs to the old page; extraction or the backfill job refills it.
+		updateFields["site_name"] = ""
+		updateFields["favicon_url"] = ""
+		updateFields["image_url"] = ""
+		updateFields["language"] = ""
+		updateFields["content_hash"] = ""
+		updateFields["content_simhash"] = int64(0)
+		updateFields["metadata_at"] = nil
 	}
 	if updatePayload.Title != nil {
 		updateFields["title"] = *updatePayload.Title
 	}
 	if updatePayload.Summary != nil {
 		updateFields["summary"] = *updatePayload.Summary
+		updateFields["summary_translations"] = nil
+		updateFields["summary_content_hash"] = ""
 	}
 
 	// Handle Tags
@@ -245,6 +743,9 @@ func (s *bookmarkServiceImpl) buildUpdateFields(updatePayload models.UpdateBookm
 			}
 			tagsObjectIDs = append(tagsObjectIDs, objID)
 		}
+		if err := checkTagLimit(len(tagsObjectIDs)); err != nil {
+			return nil, err
+		}
 		if err := utils.ValidateReferences(s.db.Client(), userID, tagsObjectIDs, nil, nil); err != nil {
 			log.Warn().Err(err).Str("userID", userID.Hex()).Msg("Invalid tag reference during buildUpdateFields")
 			return nil, fmt.Errorf("invalid tag reference: %w", err)
@@ -299,13 +800,44 @@ func (s *bookmarkServiceImpl) buildUpdateFields(updatePayload models.UpdateBookm
 	if updatePayload.IsFav != nil {
 		updateFields["is_fav"] = *updatePayload.IsFav
 	}
+	if updatePayload.Pinned != nil {
+		updateFields["pinned"] = *updatePayload.Pinned
+	}
+	if updatePayload.Read != nil {
+		updateFields["read"] = *updatePayload.Read
+	}
+	if updatePayload.Archived != nil {
+		if *updatePayload.Archived {
+			updateFields["archived_at"] = primitive.NewDateTimeFromTime(time.Now())
+		} else {
+			updateFields["archived_at"] = nil
+		}
+	}
+
+	if updatePayload.Notes != nil {
+		if *updatePayload.Notes == "" {
+			updateFields["notes_enc"] = ""
+			updateFields["note_grams"] = []string{}
+		} else {
+			encrypted, err := s.encryption.Encrypt(ctx, userID, *updatePayload.Notes)
+			if err != nil {
+				log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to encrypt bookmark notes during buildUpdateFields")
+				return nil, err
+			}
+			updateFields["notes_enc"] = encrypted
+			if updateFields["note_grams"], err = noteSearchGrams(ctx, s.encryption, userID, *updatePayload.Notes); err != nil {
+				log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to index bookmark notes during buildUpdateFields")
+				return nil, err
+			}
+		}
+	}
 	log.Debug().Str("userID", userID.Hex()).Interface("updateFields", updateFields).Msg("Bookmark update fields built successfully")
 	return updateFields, nil
 }
 
 func (s *bookmarkServiceImpl) UpdateBookmark(ctx context.Context, userID, bookmarkID primitive.ObjectID, updatePayload models.UpdateBookmarkRequestBody) (*models.Bookmark, error) {
-	log.Debug().Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Interface("updatePayload", updatePayload).Msg("Attempting to update bookmark")
-	updateFields, err := s.buildUpdateFields(updatePayload, userID)
+	log.Debug().Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Strs("fields", updatedBookmarkFields(updatePayload)).Msg("Attempting to update bookmark")
+	updateFields, err := s.buildUpdateFields(ctx, updatePayload, userID)
 	if err != nil {
 		log.Error().Err(err).Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Msg("Failed to build update fields for bookmark")
 		return nil, err
@@ -318,6 +850,11 @@ func (s *bookmarkServiceImpl) UpdateBookmark(ctx context.Context, userID, bookma
 
 	filter := bson.M{"_id": bookmarkID, "user_id": userID}
 	update := bson.M{"$set": updateFields}
+	// Without a canonical form of the new URL the old one must not stay behind,
+	// or duplicate detection would keep grouping the bookmark by its old page.
+	if _, ok := updateFields["canonical_url"]; updatePayload.URL != nil && !ok {
+		update["$unset"] = bson.M{"canonical_url": ""}
+	}
 
 	result, err := s.bookmarkRepo.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -327,7 +864,7 @@ func (s *bookmarkServiceImpl) UpdateBookmark(ctx context.Context, userID, bookma
 
 	if result.MatchedCount == 0 {
 		log.Warn().Str("bookmark_id", bookmarkID.Hex()).Str("userID", userID.Hex()).Msg("Bookmark not found or not authorized to update")
-		return nil, fmt.Errorf("bookmark not found or not authorized to update")
+		return nil, s.ownership.Missing(ctx, userID, ResourceBookmark, bookmarkID, fmt.Errorf("bookmark not found or not authorized to update"))
 	}
 
 	updatedBookmark, err := s.bookmarkRepo.FindOne(ctx, filter)
@@ -335,6 +872,400 @@ func (s *bookmarkServiceImpl) UpdateBookmark(ctx context.Context, userID, bookma
 		log.Error().Err(err).Str("bookmark_id", bookmarkID.Hex()).Str("userID", userID.Hex()).Msg("Error fetching updated bookmark")
 		return nil, fmt.Errorf("failed to retrieve updated bookmark")
 	}
+	if updatePayload.URL != nil {
+		s.contents.ExtractAsync(userID, bookmarkID, updatedBookmark.URL)
+	}
+	if updatePayload.URL != nil || updatePayload.Title != nil || updatePayload.Summary != nil {
+		s.reindex(ctx, updatedBookmark)
+	}
+	s.webhooks.Send(ctx, userID, models.ActivityBookmarkUpdated, updatedBookmark)
+	s.decryptNotes(ctx, userID, updatedBookmark)
+	s.attachHighlights(ctx, userID, updatedBookmark)
 	log.Info().Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Msg("Bookmark updated successfully")
 	return updatedBookmark, nil
 }
+
+// SetFavorite marks or unmarks a bookmark as favorite. Setting the status it
+// already has changes nothing and sends no event, so clients can repeat it.
+func (s *bookmarkServiceImpl) SetFavorite(ctx context.Context, userID, bookmarkID primitive.ObjectID, fav bool) (*models.Bookmark, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Bool("isFav", fav).Msg("Attempting to set bookmark favorite status")
+	filter := bson.M{"_id": bookmarkID, "user_id": userID}
+
+	result, err := s.bookmarkRepo.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"is_fav": fav}})
+	if err != nil {
+		log.Error().Err(err).Str("bookmark_id", bookmarkID.Hex()).Str("userID", userID.Hex()).Msg("Error setting bookmark favorite status")
+		return nil, fmt.Errorf("failed to update bookmark: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		log.Warn().Str("bookmark_id", bookmarkID.Hex()).Str("userID", userID.Hex()).Msg("Bookmark not found or not authorized to update")
+		return nil, s.ownership.Missing(ctx, userID, ResourceBookmark, bookmarkID, fmt.Errorf("bookmark not found or not authorized to update"))
+	}
+
+	bm, err := s.bookmarkRepo.FindOne(ctx, filter)
+	if err != nil {
+		log.Error().Err(err).Str("bookmark_id", bookmarkID.Hex()).Str("userID", userID.Hex()).Msg("Error fetching updated bookmark")
+		return nil, fmt.Errorf("failed to retrieve updated bookmark")
+	}
+	if result.ModifiedCount > 0 {
+		event, action := models.ActivityBookmarkFavorited, "favorited"
+		if !fav {
+			event, action = models.ActivityBookmarkUnfavorited, "unfavorited"
+		}
+		s.webhooks.Send(ctx, userID, event, bm)
+		utils.BookmarkFavoritesTotal.WithLabelValues(action).Inc()
+	}
+	s.decryptNotes(ctx, userID, bm)
+	s.attachHighlights(ctx, userID, bm)
+	log.Info().Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Bool("isFav", fav).Bool("changed", result.ModifiedCount > 0).Msg("Bookmark favorite status set")
+	return bm, nil
+}
+
+func (s *bookmarkServiceImpl) GetDuplicateBookmarks(ctx context.Context, userID primitive.ObjectID) ([]models.DuplicateGroup, error) {
+	log.Debug().Str("userID", userID.Hex()).Msg("Attempting to find duplicate bookmarks")
+	groups, err := s.bookmarkRepo.FindDuplicates(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Error finding duplicate bookmarks")
+		return nil, fmt.Errorf("failed to find duplicate bookmarks")
+	}
+	if groups == nil {
+		groups = []models.DuplicateGroup{}
+	}
+	for i := range groups {
+		for j := range groups[i].Bookmarks {
+			s.decryptNotes(ctx, userID, &groups[i].Bookmarks[j])
+		}
+	}
+	log.Debug().Str("userID", userID.Hex()).Int("groups", len(groups)).Msg("Successfully found duplicate bookmarks")
+	return groups, nil
+}
+
+// GetContentDuplicates groups the user's bookmarks whose pages have the same or
+// nearly the same text but different URLs, such as an article and its syndicated
+// copy. Only bookmarks whose content has been extracted are considered.
+func (s *bookmarkServiceImpl) GetContentDuplicates(ctx context.Context, userID primitive.ObjectID) ([]models.DuplicateGroup, error) {
+	log.Debug().Str("userID", userID.Hex()).Msg("Attempting to find bookmarks with duplicate content")
+	bookmarks, err := s.bookmarkRepo.FindFingerprinted(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Error finding fingerprinted bookmarks")
+		return nil, fmt.Errorf("failed to find duplicate bookmarks")
+	}
+	groups := groupNearDuplicates(bookmarks)
+	for i := range groups {
+		for j := range groups[i].Bookmarks {
+			s.decryptNotes(ctx, userID, &groups[i].Bookmarks[j])
+		}
+	}
+	log.Debug().Str("userID", userID.Hex()).Int("groups", len(groups)).Msg("Successfully found bookmarks with duplicate content")
+	return groups, nil
+}
+
+// simhashBands is how many parts the simhashes are split into to find near
+// duplicates. Two fingerprints within utils.NearDuplicateDistance bits differ in
+// at most that many parts, so with more parts than that they agree on one of them.
+const simhashBands = utils.NearDuplicateDistance + 1
+
+// groupNearDuplicates groups bookmarks, given oldest first, whose content hashes
+// are equal or whose simhashes are within utils.NearDuplicateDistance, directly or
+// through other bookmarks. Only bookmarks sharing a content hash or one band of
+// their simhash are compared, so bookmarks with unrelated pages cost nothing.
+// Groups whose bookmarks all share one URL are left to GetDuplicateBookmarks.
+// Larger groups come first.
+func groupNearDuplicates(bookmarks []models.Bookmark) []models.DuplicateGroup {
+	parent := make([]int, len(bookmarks))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) {
+		// The older bookmark stays the root, so it names the group.
+		if ri, rj := find(i), find(j); ri != rj {
+			if ri < rj {
+				parent[rj] = ri
+			} else {
+				parent[ri] = rj
+			}
+		}
+	}
+
+	type band struct {
+		index int
+		value uint64
+	}
+	byHash := map[string]int{}
+	byBand := map[band][]int{}
+	bandBits := 64 / simhashBands
+	for i, bm := range bookmarks {
+		if first, ok := byHash[bm.ContentHash]; ok {
+			union(first, i)
+		} else {
+			byHash[bm.ContentHash] = i
+		}
+		if bm.ContentSimhash == 0 {
+			continue
+		}
+		hash := uint64(bm.ContentSimhash)
+		for b := 0; b < simhashBands; b++ {
+			key := band{b, hash >> (b * bandBits) & (1<<bandBits - 1)}
+			for _, j := range byBand[key] {
+				if find(i) != find(j) && utils.HammingDistance(hash, uint64(bookmarks[j].ContentSimhash)) <= utils.NearDuplicateDistance {
+					union(i, j)
+				}
+			}
+			byBand[key] = append(byBand[key], i)
+		}
+	}
+
+	members := map[int][]models.Bookmark{}
+	var roots []int
+	for i, bm := range bookmarks {
+		root := find(i)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], bm)
+	}
+
+	groups := []models.DuplicateGroup{}
+	for _, root := range roots {
+		group := members[root]
+		urls := map[string]bool{}
+		for _, bm := range group {
+			if bm.CanonicalURL != "" {
+				urls[bm.CanonicalURL] = true
+			} else {
+				urls[bm.URL] = true
+			}
+		}
+		if len(urls) < 2 {
+			continue
+		}
+		groups = append(groups, models.DuplicateGroup{ContentHash: bookmarks[root].ContentHash, Count: len(group), Bookmarks: group})
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Count > groups[j].Count })
+	return groups
+}
+
+func (s *bookmarkServiceImpl) SuggestTags(ctx context.Context, userID primitive.ObjectID, rawURL string) ([]string, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("url", rawURL).Msg("Attempting to suggest domain tags")
+	if rawURL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	tags, _, err := s.tagSuggester.SuggestTagNames(ctx, userID, rawURL)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Error suggesting domain tags")
+		return nil, fmt.Errorf("failed to suggest tags")
+	}
+	return tags, nil
+}
+
+func containsObjectID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
+
+// notesSeparator joins the notes of merged bookmarks.
+const notesSeparator = "\n\n---\n\n"
+
+// MergeBookmarks merges the source bookmark into the target and deletes the source.
+// Tags and collections are united, the earliest created_at is kept, notes are
+// concatenated, the target's summary, category and source win when set, and the
+// source's highlights move to the target. The target records the source in
+// merged_from as it is updated, so a merge that failed afterwards can be retried
+// without merging the source, and its notes, twice.
+func (s *bookmarkServiceImpl) MergeBookmarks(ctx context.Context, userID, targetID, sourceID primitive.ObjectID) (*models.Bookmark, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("targetID", targetID.Hex()).Str("sourceID", sourceID.Hex()).Msg("Attempting to merge bookmarks")
+	if targetID == sourceID {
+		return nil, fmt.Errorf("invalid merge: cannot merge a bookmark into itself")
+	}
+
+	target, err := s.bookmarkRepo.FindOne(ctx, bson.M{"_id": targetID, "user_id": userID})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, s.ownership.Missing(ctx, userID, ResourceBookmark, targetID, fmt.Errorf("bookmark not found"))
+		}
+		return nil, fmt.Errorf("failed to retrieve bookmark")
+	}
+	source, err := s.bookmarkRepo.FindOne(ctx, bson.M{"_id": sourceID, "user_id": userID})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, s.ownership.Missing(ctx, userID, ResourceBookmark, sourceID, fmt.Errorf("source bookmark not found"))
+		}
+		return nil, fmt.Errorf("failed to retrieve source bookmark")
+	}
+
+	if !containsObjectID(target.MergedFrom, sourceID) {
+		updateFields, err := s.mergedFields(ctx, userID, target, source)
+		if err != nil {
+			return nil, err
+		}
+		filter := bson.M{"_id": targetID, "user_id": userID, "merged_from": bson.M{"$ne": sourceID}}
+		update := bson.M{"$set": updateFields, "$addToSet": bson.M{"merged_from": sourceID}}
+		if _, err := s.bookmarkRepo.UpdateOne(ctx, filter, update); err != nil {
+			log.Error().Err(err).Str("targetID", targetID.Hex()).Msg("Error updating target bookmark during merge")
+			return nil, fmt.Errorf("failed to merge bookmarks")
+		}
+	}
+
+	if err := s.highlights.MoveToBookmark(ctx, userID, sourceID, targetID); err != nil {
+		log.Error().Err(err).Str("sourceID", sourceID.Hex()).Msg("Error moving highlights during merge")
+		return nil, fmt.Errorf("failed to merge bookmarks")
+	}
+	if _, err := s.DeleteBookmark(ctx, userID, sourceID); err != nil {
+		log.Error().Err(err).Str("sourceID", sourceID.Hex()).Msg("Error deleting source bookmark during merge")
+		return nil, fmt.Errorf("failed to delete merged bookmark")
+	}
+
+	log.Info().Str("userID", userID.Hex()).Str("targetID", targetID.Hex()).Str("sourceID", sourceID.Hex()).Msg("Bookmarks merged successfully")
+	// DeleteBookmark sent the deleted event of the source.
+	merged, err := s.GetBookmarkByID(ctx, userID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	s.webhooks.Send(ctx, userID, models.ActivityBookmarkUpdated, merged)
+	return merged, nil
+}
+
+// mergedFields returns the fields of target after merging source into it.
+func (s *bookmarkServiceImpl) mergedFields(ctx context.Context, userID primitive.ObjectID, target, source *models.Bookmark) (bson.M, error) {
+	mergedTags := unionObjectIDs(target.TagsID, source.TagsID)
+	if err := checkTagLimit(len(mergedTags)); err != nil {
+		return nil, err
+	}
+	updateFields := bson.M{
+		"tagsid":        mergedTags,
+		"collectionsid": unionObjectIDs(target.CollectionsID, source.CollectionsID),
+		"is_fav":        target.IsFav || source.IsFav,
+	}
+	if source.CreatedAt < target.CreatedAt {
+		updateFields["created_at"] = source.CreatedAt
+	}
+	if target.Summary == "" && source.Summary != "" {
+		updateFields["summary"] = source.Summary
+		updateFields["summary_translations"] = source.SummaryTranslations
+		merged := *target
+		merged.Summary = source.Summary
+		updateFields["search_grams"] = bookmarkSearchGrams(&merged)
+	}
+	if target.CategoryID == nil && source.CategoryID != nil {
+		updateFields["categoryid"] = source.CategoryID
+	}
+	if target.Source == nil && source.Source != nil {
+		updateFields["source"] = source.Source
+	}
+
+	if source.EncryptedNotes != "" {
+		notes, err := s.encryption.Decrypt(ctx, userID, source.EncryptedNotes)
+		if err != nil {
+			log.Error().Err(err).Str("sourceID", source.ID.Hex()).Msg("Failed to decrypt source notes during merge")
+			return nil, err
+		}
+		if target.EncryptedNotes != "" {
+			targetNotes, err := s.encryption.Decrypt(ctx, userID, target.EncryptedNotes)
+			if err != nil {
+				log.Error().Err(err).Str("targetID", target.ID.Hex()).Msg("Failed to decrypt target notes during merge")
+				return nil, err
+			}
+			notes = targetNotes + notesSeparator + notes
+		}
+		encrypted, err := s.encryption.Encrypt(ctx, userID, notes)
+		if err != nil {
+			return nil, err
+		}
+		updateFields["notes_enc"] = encrypted
+		if updateFields["note_grams"], err = noteSearchGrams(ctx, s.encryption, userID, notes); err != nil {
+			return nil, err
+		}
+	}
+	return updateFields, nil
+}
+
+func unionObjectIDs(a, b []primitive.ObjectID) []primitive.ObjectID {
+	union := append([]primitive.ObjectID{}, a...)
+	for _, id := range b {
+		if !containsObjectID(union, id) {
+			union = append(union, id)
+		}
+	}
+	return union
+}
+
+// BulkTag adds and removes tags on every bookmark matching the filter of r, which
+// uses the same query parameters as GetBookmarks without paging.
+func (s *bookmarkServiceImpl) BulkTag(ctx context.Context, userID primitive.ObjectID, r *http.Request, reqBody models.BulkTagRequest) (*models.BulkTagResult, error) {
+	log.Debug().Str("userID", userID.Hex()).Interface("reqBody", reqBody).Msg("Attempting to bulk tag bookmarks")
+	if len(reqBody.Add) == 0 && len(reqBody.Remove) == 0 {
+		return nil, fmt.Errorf("no tags provided to add or remove")
+	}
+
+	addIDs, err := parseTagIDs(reqBody.Add)
+	if err != nil {
+		return nil, err
+	}
+	removeIDs, err := parseTagIDs(reqBody.Remove)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range addIDs {
+		if containsObjectID(removeIDs, id) {
+			return nil, fmt.Errorf("invalid request: tag %s is both added and removed", id.Hex())
+		}
+	}
+	maxTags := utils.CurrentLimits().MaxTagsPerBookmark
+	if err := checkTagLimit(len(addIDs)); err != nil {
+		return nil, err
+	}
+
+	filter, err := s.buildBookmarkFilter(ctx, r, userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to build bookmark filter")
+		return nil, err
+	}
+
+	// A single pipeline update applies both changes so that each bookmark is
+	// counted once in the modified count. Bookmarks that would end up with more
+	// tags than allowed are left unchanged.
+	current := bson.M{"$ifNull": bson.A{"$tagsid", bson.A{}}}
+	updated := bson.M{"$setDifference": bson.A{bson.M{"$setUnion": bson.A{current, addIDs}}, removeIDs}}
+	tags := bson.M{"$cond": bson.A{bson.M{"$lte": bson.A{bson.M{"$size": updated}, maxTags}}, updated, current}}
+	res, err := s.bookmarkRepo.UpdateMany(ctx, filter, mongo.Pipeline{{{Key: "$set", Value: bson.M{"tagsid": tags}}}})
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Interface("filter", filter).Msg("Error tagging bookmarks in bulk")
+		return nil, fmt.Errorf("failed to update bookmark tags")
+	}
+	result := &models.BulkTagResult{Matched: res.MatchedCount, Modified: res.ModifiedCount}
+
+	log.Info().Str("userID", userID.Hex()).Int64("matched", result.Matched).Int64("modified", result.Modified).Msg("Bookmarks tagged in bulk")
+	return result, nil
+}
+
+// checkTagLimit rejects a bookmark with more tags than MaxTagsPerBookmark.
+func checkTagLimit(n int) error {
+	if max := utils.CurrentLimits().MaxTagsPerBookmark; n > max {
+		return fmt.Errorf("%w: at most %d tags per bookmark", utils.ErrLimitExceeded, max)
+	}
+	return nil
+}
+
+func parseTagIDs(ids []string) ([]primitive.ObjectID, error) {
+	objIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, idStr := range ids {
+		objID, err := primitive.ObjectIDFromHex(idStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag ID format: %s", idStr)
+		}
+		if !containsObjectID(objIDs, objID) {
+			objIDs = append(objIDs, objID)
+		}
+	}
+	return objIDs, nil
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+)
+
+func TestGroupNearDuplicates(t *testing.T) {
+	bookmarks := []models.Bookmark{
+		{Title: "original", URL: "https://a.example/post", ContentHash: "h1", ContentSimhash: 0b1111_0000},
+		{Title: "syndicated", URL: "https://b.example/post", ContentHash: "h2", ContentSimhash: 0b1111_0011},
+		{Title: "same url", URL: "https://c.example/x", CanonicalURL: "https://c.example/x", ContentHash: "h3", ContentSimhash: 0x0f0f_0000},
+		{Title: "same url again", URL: "https://C.example/x", CanonicalURL: "https://c.example/x", ContentHash: "h3", ContentSimhash: 0x0f0f_0000},
+		{Title: "unrelated", URL: "https://d.example/", ContentHash: "h4", ContentSimhash: 0x7fff_ffff},
+		{Title: "copy", URL: "https://e.example/copy", ContentHash: "h1"},
+	}
+
+	groups := groupNearDuplicates(bookmarks)
+	if len(groups) != 1 {
+		t.Fatalf("groupNearDuplicates returned %d groups, want 1: %+v", len(groups), groups)
+	}
+	g := groups[0]
+	if g.ContentHash != "h1" || g.Count != 3 {
+		t.Errorf("group = %s with %d bookmarks, want h1 with 3", g.ContentHash, g.Count)
+	}
+	for i, want := range []string{"original", "syndicated", "copy"} {
+		if g.Bookmarks[i].Title != want {
+			t.Errorf("bookmark %d = %q, want %q", i, g.Bookmarks[i].Title, want)
+		}
+	}
+}
+
+// mergedBookmarks is a BookmarkRepository applying the updates of MergeBookmarks.
+// FindOneAndDelete fails while failDelete is set.
+type mergedBookmarks struct {
+	repositories.BookmarkRepository
+	bookmarks  map[primitive.ObjectID]*models.Bookmark
+	failDelete bool
+}
+
+func (f *mergedBookmarks) FindOne(ctx context.Context, filter bson.M) (*models.Bookmark, error) {
+	if bm, ok := f.bookmarks[filter["_id"].(primitive.ObjectID)]; ok && bm.UserID == filter["user_id"] {
+		found := *bm
+		return &found, nil
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (f *mergedBookmarks) UpdateOne(ctx context.Context, filter bson.M, update bson.M) (*mongo.UpdateResult, error) {
+	bm := f.bookmarks[filter["_id"].(primitive.ObjectID)]
+	if containsObjectID(bm.MergedFrom, filter["merged_from"].(bson.M)["$ne"].(primitive.ObjectID)) {
+		return &mongo.UpdateResult{}, nil
+	}
+	if notes, ok := update["$set"].(bson.M)["notes_enc"]; ok {
+		bm.EncryptedNotes = notes.(string)
+	}
+	bm.MergedFrom = append(bm.MergedFrom, update["$addToSet"].(bson.M)["merged_from"].(primitive.ObjectID))
+	return &mongo.UpdateResult{MatchedCount: 1, ModifiedCount: 1}, nil
+}
+
+func (f *mergedBookmarks) FindOneAndDelete(ctx context.Context, filter bson.M) (*models.Bookmark, error) {
+	if f.failDelete {
+		return nil, errors.New("connection reset")
+	}
+	bm, err := f.FindOne(ctx, filter)
+	if err == nil {
+		delete(f.bookmarks, bm.ID)
+	}
+	return bm, err
+}
+
+type mergedHighlights struct{ HighlightService }
+
+func (mergedHighlights) MoveToBookmark(ctx context.Context, userID, fromBookmarkID, toBookmarkID primitive.ObjectID) error {
+	return nil
+}
+
+func (mergedHighlights) DeleteForBookmark(ctx context.Context, userID, bookmarkID primitive.ObjectID) error {
+	return nil
+}
+
+func (mergedHighlights) AttachHighlights(ctx context.Context, userID primitive.ObjectID, bookmarks []*models.Bookmark) error {
+	return nil
+}
+
+type mergedContents struct{ BookmarkContentService }
+
+func (mergedContents) DeleteForBookmark(ctx context.Context, userID, bookmarkID primitive.ObjectID) error {
+	return nil
+}
+
+// sentWebhooks is an ActivityWebhookService recording the events sent.
+type sentWebhooks struct {
+	ActivityWebhookService
+	events []string
+}
+
+func (f *sentWebhooks) Send(ctx context.Context, userID primitive.ObjectID, event string, bookmarks ...*models.Bookmark) {
+	for range bookmarks {
+		f.events = append(f.events, event)
+	}
+}
+
+func TestMergeBookmarksCanBeRetried(t *testing.T) {
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	encryption := newTestEncryption(&fakeDataKeys{keys: map[primitive.ObjectID]*models.DataKey{}})
+	encrypt := func(notes string) string {
+		encrypted, err := encryption.Encrypt(ctx, userID, notes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encrypted
+	}
+	target := &models.Bookmark{ID: primitive.NewObjectID(), UserID: userID, EncryptedNotes: encrypt("target notes")}
+	source := &models.Bookmark{ID: primitive.NewObjectID(), UserID: userID, EncryptedNotes: encrypt("source notes")}
+	repo := &mergedBookmarks{bookmarks: map[primitive.ObjectID]*models.Bookmark{target.ID: target, source.ID: source}, failDelete: true}
+	webhooks := &sentWebhooks{}
+	s := &bookmarkServiceImpl{bookmarkRepo: repo, encryption: encryption, highlights: mergedHighlights{}, contents: mergedContents{}, webhooks: webhooks}
+
+	if _, err := s.MergeBookmarks(ctx, userID, target.ID, source.ID); err == nil {
+		t.Fatal("merge succeeded without deleting the source")
+	}
+	repo.failDelete = false
+	merged, err := s.MergeBookmarks(ctx, userID, target.ID, source.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "target notes" + notesSeparator + "source notes"; merged.Notes != want {
+		t.Errorf("notes = %q, want %q", merged.Notes, want)
+	}
+	if _, ok := repo.bookmarks[source.ID]; ok {
+		t.Error("the source was not deleted")
+	}
+	if want := []string{models.ActivityBookmarkDeleted, models.ActivityBookmarkUpdated}; !reflect.DeepEqual(webhooks.events, want) {
+		t.Errorf("events = %v, want %v", webhooks.events, want)
+	}
+}
+
+func TestUpdatedBookmarkFieldsLeavesOutValues(t *testing.T) {
+	notes, read := "private", true
+	fields := updatedBookmarkFields(models.UpdateBookmarkRequestBody{Notes: &notes, Read: &read})
+	if want := []string{"notes", "read"}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("fields = %v, want %v", fields, want)
+	}
+}
+
+type noDomainTags struct{ TagSuggestionService }
+
+func (noDomainTags) SuggestTagNames(ctx context.Context, userID primitive.ObjectID, rawURL string) ([]string, *models.UserSettings, error) {
+	return nil, &models.UserSettings{}, nil
+}
+
+func TestNewBookmarkInBatchDoesNotFollowRedirects(t *testing.T) {
+	s := &bookmarkServiceImpl{urls: offlineURLs{t: t}, tagSuggester: noDomainTags{}}
+	req := models.AddBookmarkRequestBody{URL: "https://Example.com/post?utm_source=feed", Title: "Post"}
+	parsed, err := parseAddRequest(primitive.NewObjectID(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed.offline = true
+
+	bm, err := s.newBookmark(context.Background(), primitive.NewObjectID(), req, parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bm.CanonicalURL != "https://example.com/post" {
+		t.Errorf("canonical url = %q", bm.CanonicalURL)
+	}
+}
+
+func TestNewBookmarkIgnoresClientFeedID(t *testing.T) {
+	s := &bookmarkServiceImpl{urls: offlineURLs{t: t}, tagSuggester: noDomainTags{}}
+	feedID := primitive.NewObjectID()
+	req := models.AddBookmarkRequestBody{URL: "https://example.com/", Title: "Home", Source: &models.BookmarkSource{Client: models.SourceClientWeb, Referrer: "https://news.example/", FeedID: &feedID}}
+	userID := primitive.NewObjectID()
+	parsed, err := parseAddRequest(userID, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed.offline = true
+
+	bm, err := s.newBookmark(context.Background(), userID, req, parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&models.BookmarkSource{Client: models.SourceClientWeb, Referrer: "https://news.example/"}); !reflect.DeepEqual(bm.Source, want) {
+		t.Errorf("source = %+v, want %+v", bm.Source, want)
+	}
+}
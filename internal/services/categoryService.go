@@ -3,6 +3,8 @@ package services
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson"
@@ -11,30 +13,61 @@ import (
 
 	"markly/internal/models"
 	"markly/internal/repositories"
+	"markly/internal/utils"
 )
 
 type CategoryService interface {
 	AddCategory(ctx context.Context, userID primitive.ObjectID, category models.Category) (*models.Category, error)
-	GetCategories(ctx context.Context, userID primitive.ObjectID) ([]models.Category, error)
+	GetCategories(ctx context.Context, userID primitive.ObjectID, page models.PageRequest) ([]models.Category, string, error)
 	GetCategoryByID(ctx context.Context, userID, categoryID primitive.ObjectID) (*models.Category, error)
+	GetCategoryBySlug(ctx context.Context, userID primitive.ObjectID, slug string) (*models.Category, error)
 	DeleteCategory(ctx context.Context, userID, categoryID primitive.ObjectID) (bool, error)
 	UpdateCategory(ctx context.Context, userID, categoryID primitive.ObjectID, updatePayload models.CategoryUpdate) (*models.Category, error)
+	MigrateEmojis(ctx context.Context) error
+	MigrateSlugs(ctx context.Context) error
+	SetIcon(ctx context.Context, userID, categoryID primitive.ObjectID, data []byte) (*models.Category, error)
+	GetIcon(ctx context.Context, userID, categoryID primitive.ObjectID) (*models.CategoryIcon, error)
+	DeleteIcon(ctx context.Context, userID, categoryID primitive.ObjectID) error
 }
 
 type categoryServiceImpl struct {
 	categoryRepo repositories.CategoryRepository
+	iconRepo     repositories.CategoryIconRepository
+	ownership    *Ownership
 }
 
-func NewCategoryService(categoryRepo repositories.CategoryRepository) CategoryService {
-	return &categoryServiceImpl{categoryRepo: categoryRepo}
+func NewCategoryService(categoryRepo repositories.CategoryRepository, iconRepo repositories.CategoryIconRepository, ownership *Ownership) CategoryService {
+	return &categoryServiceImpl{categoryRepo: categoryRepo, iconRepo: iconRepo, ownership: ownership}
 }
 
+// CategoryIconSize is the width and height uploaded icons are scaled down to fit.
+const CategoryIconSize = 128
+
 func (s *categoryServiceImpl) AddCategory(ctx context.Context, userID primitive.ObjectID, category models.Category) (*models.Category, error) {
 	log.Debug().Str("userID", userID.Hex()).Interface("categoryName", category.Name).Msg("Attempting to add category")
 	category.ID = primitive.NewObjectID()
 	category.UserID = userID
+	category.PreviousSlugs = nil
+	category.IconUpdatedAt = nil
+	category.IconURL = ""
+	emoji, err := categoryEmoji(category.Name, category.Emoji)
+	if err != nil {
+		log.Warn().Str("userID", userID.Hex()).Str("emoji", category.Emoji).Msg("Invalid category emoji")
+		return nil, err
+	}
+	category.Emoji = emoji
+	color, err := categoryColor(category.Color)
+	if err != nil {
+		return nil, err
+	}
+	category.Color = color
 
-	createdCategory, err := s.categoryRepo.Create(ctx, &category)
+	var createdCategory *models.Category
+	err = saveWithSlug(ctx, s.categoryRepo, userID, category.ID, category.Name, "category", func(slug string) (err error) {
+		category.Slug = slug
+		createdCategory, err = s.categoryRepo.Create(ctx, &category)
+		return err
+	})
 	if err != nil {
 		if mongo.IsDuplicateKeyError(err) {
 			log.Warn().Err(err).Str("userID", userID.Hex()).Interface("categoryName", category.Name).Msg("Category name already exists for this user")
@@ -47,15 +80,18 @@ func (s *categoryServiceImpl) AddCategory(ctx context.Context, userID primitive.
 	return createdCategory, nil
 }
 
-func (s *categoryServiceImpl) GetCategories(ctx context.Context, userID primitive.ObjectID) ([]models.Category, error) {
+func (s *categoryServiceImpl) GetCategories(ctx context.Context, userID primitive.ObjectID, page models.PageRequest) ([]models.Category, string, error) {
 	log.Debug().Str("userID", userID.Hex()).Msg("Attempting to retrieve categories")
-	categories, err := s.categoryRepo.FindByUser(ctx, userID)
+	categories, next, err := s.categoryRepo.FindPageByUser(ctx, userID, page)
 	if err != nil {
 		log.Error().Err(err).Str("user_id", userID.Hex()).Msg("Error finding categories")
-		return nil, err
+		return nil, "", err
+	}
+	for i := range categories {
+		setCategoryIconURL(&categories[i])
 	}
 	log.Debug().Str("userID", userID.Hex()).Int("count", len(categories)).Msg("Successfully retrieved categories")
-	return categories, nil
+	return categories, next, nil
 }
 
 func (s *categoryServiceImpl) GetCategoryByID(ctx context.Context, userID, categoryID primitive.ObjectID) (*models.Category, error) {
@@ -64,15 +100,33 @@ func (s *categoryServiceImpl) GetCategoryByID(ctx context.Context, userID, categ
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			log.Warn().Str("userID", userID.Hex()).Str("categoryID", categoryID.Hex()).Msg("Category not found")
-			return nil, fmt.Errorf("category not found")
+			return nil, s.ownership.Missing(ctx, userID, ResourceCategory, categoryID, fmt.Errorf("category not found"))
 		}
 		log.Error().Err(err).Str("category_id", categoryID.Hex()).Str("user_id", userID.Hex()).Msg("Error finding category by ID")
 		return nil, fmt.Errorf("failed to retrieve category")
 	}
+	setCategoryIconURL(category)
 	log.Debug().Str("userID", userID.Hex()).Str("categoryID", categoryID.Hex()).Msg("Successfully retrieved category by ID")
 	return category, nil
 }
 
+// GetCategoryBySlug finds a category by its current or a previous slug. The
+// returned category's Slug differs from slug when the category was renamed.
+func (s *categoryServiceImpl) GetCategoryBySlug(ctx context.Context, userID primitive.ObjectID, slug string) (*models.Category, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("slug", slug).Msg("Attempting to retrieve category by slug")
+	category, err := s.categoryRepo.FindBySlug(ctx, userID, slug)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			log.Warn().Str("userID", userID.Hex()).Str("slug", slug).Msg("Category not found by slug")
+			return nil, fmt.Errorf("category not found")
+		}
+		log.Error().Err(err).Str("user_id", userID.Hex()).Str("slug", slug).Msg("Error finding category by slug")
+		return nil, fmt.Errorf("failed to retrieve category")
+	}
+	setCategoryIconURL(category)
+	return category, nil
+}
+
 func (s *categoryServiceImpl) DeleteCategory(ctx context.Context, userID, categoryID primitive.ObjectID) (bool, error) {
 	log.Debug().Str("userID", userID.Hex()).Str("categoryID", categoryID.Hex()).Msg("Attempting to delete category")
 	result, err := s.categoryRepo.Delete(ctx, userID, categoryID)
@@ -83,7 +137,10 @@ func (s *categoryServiceImpl) DeleteCategory(ctx context.Context, userID, catego
 
 	if result.DeletedCount == 0 {
 		log.Warn().Str("userID", userID.Hex()).Str("categoryID", categoryID.Hex()).Msg("Category not found or unauthorized to delete")
-		return false, fmt.Errorf("category not found or unauthorized to delete")
+		return false, s.ownership.Missing(ctx, userID, ResourceCategory, categoryID, fmt.Errorf("category not found or unauthorized to delete"))
+	}
+	if err := s.iconRepo.Delete(ctx, userID, categoryID); err != nil {
+		log.Warn().Err(err).Str("categoryID", categoryID.Hex()).Msg("Failed to delete icon of deleted category")
 	}
 	log.Info().Str("userID", userID.Hex()).Str("categoryID", categoryID.Hex()).Msg("Category deleted successfully")
 	return true, nil
@@ -96,7 +153,22 @@ func (s *categoryServiceImpl) buildCategoryUpdateFields(updatePayload models.Cat
 		updateFields["name"] = *updatePayload.Name
 	}
 	if updatePayload.Emoji != nil {
-		updateFields["emoji"] = *updatePayload.Emoji
+		name := ""
+		if updatePayload.Name != nil {
+			name = *updatePayload.Name
+		}
+		emoji, err := categoryEmoji(name, *updatePayload.Emoji)
+		if err != nil {
+			return nil, err
+		}
+		updateFields["emoji"] = emoji
+	}
+	if updatePayload.Color != nil {
+		color, err := categoryColor(*updatePayload.Color)
+		if err != nil {
+			return nil, err
+		}
+		updateFields["color"] = color
 	}
 	log.Debug().Interface("updateFields", updateFields).Msg("Category update fields built successfully")
 	return updateFields, nil
@@ -115,7 +187,40 @@ func (s *categoryServiceImpl) UpdateCategory(ctx context.Context, userID, catego
 		return nil, fmt.Errorf("no fields to update")
 	}
 
-	result, err := s.categoryRepo.Update(ctx, userID, categoryID, updateFields)
+	// Renaming changes the slug, and clearing the emoji without renaming needs the
+	// current name to pick the fallback.
+	clearsEmoji := updatePayload.Emoji != nil && strings.TrimSpace(*updatePayload.Emoji) == ""
+	var existing *models.Category
+	if updatePayload.Name != nil || clearsEmoji {
+		existing, err = s.categoryRepo.FindByID(ctx, userID, categoryID)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, s.ownership.Missing(ctx, userID, ResourceCategory, categoryID, fmt.Errorf("category not found or unauthorized to update"))
+			}
+			log.Error().Err(err).Str("category_id", categoryID.Hex()).Str("user_id", userID.Hex()).Msg("Failed to find category to update")
+			return nil, fmt.Errorf("failed to update category")
+		}
+		if clearsEmoji && updatePayload.Name == nil {
+			updateFields["emoji"] = fallbackCategoryEmoji(existing.Name)
+		}
+	}
+
+	var result *mongo.UpdateResult
+	update := func() (err error) {
+		result, err = s.categoryRepo.Update(ctx, userID, categoryID, updateFields)
+		return err
+	}
+	if updatePayload.Name == nil {
+		err = update()
+	} else {
+		err = saveWithSlug(ctx, s.categoryRepo, userID, categoryID, *updatePayload.Name, "category", func(slug string) error {
+			if slug != existing.Slug {
+				updateFields["slug"] = slug
+				updateFields["previous_slugs"] = renamedSlugs(existing.Slug, existing.PreviousSlugs, slug)
+			}
+			return update()
+		})
+	}
 	if err != nil {
 		if mongo.IsDuplicateKeyError(err) {
 			log.Warn().Err(err).Str("userID", userID.Hex()).Str("categoryID", categoryID.Hex()).Msg("Category name already exists for this user during update")
@@ -127,7 +232,7 @@ func (s *categoryServiceImpl) UpdateCategory(ctx context.Context, userID, catego
 
 	if result.MatchedCount == 0 {
 		log.Warn().Str("userID", userID.Hex()).Str("categoryID", categoryID.Hex()).Msg("Category not found or unauthorized to update")
-		return nil, fmt.Errorf("category not found or unauthorized to update")
+		return nil, s.ownership.Missing(ctx, userID, ResourceCategory, categoryID, fmt.Errorf("category not found or unauthorized to update"))
 	}
 
 	updatedCategory, err := s.categoryRepo.FindByID(ctx, userID, categoryID)
@@ -135,6 +240,179 @@ func (s *categoryServiceImpl) UpdateCategory(ctx context.Context, userID, catego
 		log.Error().Err(err).Str("category_id", categoryID.Hex()).Str("user_id", userID.Hex()).Msg("Failed to find updated category")
 		return nil, fmt.Errorf("failed to retrieve the updated category")
 	}
+	setCategoryIconURL(updatedCategory)
 	log.Info().Str("userID", userID.Hex()).Str("categoryID", categoryID.Hex()).Msg("Category updated successfully")
 	return updatedCategory, nil
 }
+
+// categoryColor validates the color of a category against the palette. An empty
+// color clears it.
+func categoryColor(color string) (string, error) {
+	color = strings.ToLower(strings.TrimSpace(color))
+	if color == "" {
+		return "", nil
+	}
+	for _, c := range models.CategoryColors {
+		if c == color {
+			return color, nil
+		}
+	}
+	return "", fmt.Errorf("invalid color: must be one of the palette colors")
+}
+
+// setCategoryIconURL fills in the icon URL of a category with an uploaded icon. The
+// upload time in the URL busts caches when the icon is replaced.
+func setCategoryIconURL(category *models.Category) {
+	if category.IconUpdatedAt == nil {
+		category.IconURL = ""
+		return
+	}
+	category.IconURL = fmt.Sprintf("/api/categories/%s/icon?v=%d", category.ID.Hex(), category.IconUpdatedAt.UnixMilli())
+}
+
+// SetIcon stores data, an uploaded image, as the icon of a category after scaling
+// it down to CategoryIconSize.
+func (s *categoryServiceImpl) SetIcon(ctx context.Context, userID, categoryID primitive.ObjectID, data []byte) (*models.Category, error) {
+	category, err := s.GetCategoryByID(ctx, userID, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	resized, err := utils.ResizeImage(data, CategoryIconSize)
+	if err != nil {
+		log.Warn().Err(err).Str("userID", userID.Hex()).Str("categoryID", categoryID.Hex()).Msg("Rejected category icon")
+		return nil, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	icon := &models.CategoryIcon{CategoryID: categoryID, UserID: userID, ContentType: "image/png", Data: resized, UpdatedAt: now}
+	if err := s.iconRepo.Upsert(ctx, icon); err != nil {
+		log.Error().Err(err).Str("categoryID", categoryID.Hex()).Msg("Failed to store category icon")
+		return nil, fmt.Errorf("failed to store category icon")
+	}
+	if _, err := s.categoryRepo.Update(ctx, userID, categoryID, bson.M{"icon_updated_at": now}); err != nil {
+		log.Error().Err(err).Str("categoryID", categoryID.Hex()).Msg("Failed to record category icon")
+		return nil, fmt.Errorf("failed to store category icon")
+	}
+	category.IconUpdatedAt = &now
+	setCategoryIconURL(category)
+	log.Info().Str("userID", userID.Hex()).Str("categoryID", categoryID.Hex()).Int("bytes", len(resized)).Msg("Category icon uploaded")
+	return category, nil
+}
+
+func (s *categoryServiceImpl) GetIcon(ctx context.Context, userID, categoryID primitive.ObjectID) (*models.CategoryIcon, error) {
+	icon, err := s.iconRepo.FindByCategory(ctx, userID, categoryID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("category icon not found")
+		}
+		log.Error().Err(err).Str("categoryID", categoryID.Hex()).Msg("Error finding category icon")
+		return nil, fmt.Errorf("failed to retrieve category icon")
+	}
+	return icon, nil
+}
+
+// DeleteIcon removes the uploaded icon of a category, leaving its emoji.
+func (s *categoryServiceImpl) DeleteIcon(ctx context.Context, userID, categoryID primitive.ObjectID) error {
+	result, err := s.categoryRepo.Update(ctx, userID, categoryID, bson.M{"icon_updated_at": nil})
+	if err != nil {
+		log.Error().Err(err).Str("categoryID", categoryID.Hex()).Msg("Failed to clear category icon")
+		return fmt.Errorf("failed to delete category icon")
+	}
+	if result.MatchedCount == 0 {
+		return s.ownership.Missing(ctx, userID, ResourceCategory, categoryID, fmt.Errorf("category not found"))
+	}
+	if err := s.iconRepo.Delete(ctx, userID, categoryID); err != nil {
+		log.Error().Err(err).Str("categoryID", categoryID.Hex()).Msg("Failed to delete category icon")
+		return fmt.Errorf("failed to delete category icon")
+	}
+	log.Info().Str("userID", userID.Hex()).Str("categoryID", categoryID.Hex()).Msg("Category icon deleted")
+	return nil
+}
+
+// fallbackCategoryEmojis picks an emoji for a category without a valid one, by the
+// first keyword found in its name.
+var fallbackCategoryEmojis = []struct {
+	keyword string
+	emoji   string
+}{
+	{"tech", "💻"}, {"program", "💻"}, {"code", "💻"}, {"news", "📰"}, {"music", "🎵"},
+	{"recipe", "🍳"}, {"food", "🍳"}, {"cook", "🍳"}, {"travel", "✈️"}, {"trip", "✈️"},
+	{"financ", "💰"}, {"money", "💰"}, {"design", "🎨"}, {"art", "🎨"}, {"science", "🔬"},
+	{"research", "🔬"}, {"health", "🩺"}, {"fitness", "🏋️"}, {"sport", "⚽"}, {"learn", "📚"},
+	{"book", "📚"}, {"educat", "📚"}, {"work", "💼"}, {"job", "💼"}, {"career", "💼"},
+	{"game", "🎮"}, {"video", "🎬"}, {"movie", "🎬"}, {"film", "🎬"}, {"shop", "🛒"},
+}
+
+// defaultCategoryEmoji is used when no keyword of fallbackCategoryEmojis matches.
+const defaultCategoryEmoji = "📁"
+
+// fallbackCategoryEmoji returns the curated emoji for a category name.
+func fallbackCategoryEmoji(name string) string {
+	lower := strings.ToLower(name)
+	for _, f := range fallbackCategoryEmojis {
+		if strings.Contains(lower, f.keyword) {
+			return f.emoji
+		}
+	}
+	return defaultCategoryEmoji
+}
+
+// categoryEmoji validates the emoji of a category. An empty emoji is replaced by
+// the fallback for the name.
+func categoryEmoji(name, emoji string) (string, error) {
+	emoji = strings.TrimSpace(emoji)
+	if emoji == "" {
+		return fallbackCategoryEmoji(name), nil
+	}
+	if !utils.IsSingleEmoji(emoji) {
+		return "", fmt.Errorf("invalid emoji: must be a single emoji")
+	}
+	return emoji, nil
+}
+
+// MigrateSlugs assigns a slug to every category created before slugs existed. It
+// is safe to run repeatedly.
+func (s *categoryServiceImpl) MigrateSlugs(ctx context.Context) error {
+	categories, err := s.categoryRepo.FindWithoutSlug(ctx)
+	if err != nil {
+		return err
+	}
+	for _, category := range categories {
+		err := saveWithSlug(ctx, s.categoryRepo, category.UserID, category.ID, category.Name, "category", func(slug string) error {
+			_, err := s.categoryRepo.Update(ctx, category.UserID, category.ID, bson.M{"slug": slug})
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to migrate slug of category %s: %w", category.ID.Hex(), err)
+		}
+	}
+	if len(categories) > 0 {
+		log.Info().Int("count", len(categories)).Msg("Assigned category slugs")
+	}
+	return nil
+}
+
+// MigrateEmojis replaces the emoji of every category whose emoji is not a single
+// emoji, as allowed before emojis were validated, with its fallback. It is safe to
+// run repeatedly.
+func (s *categoryServiceImpl) MigrateEmojis(ctx context.Context) error {
+	categories, err := s.categoryRepo.FindWithEmoji(ctx)
+	if err != nil {
+		return err
+	}
+	migrated := 0
+	for _, category := range categories {
+		if utils.IsSingleEmoji(category.Emoji) {
+			continue
+		}
+		emoji := fallbackCategoryEmoji(category.Name)
+		if _, err := s.categoryRepo.Update(ctx, category.UserID, category.ID, bson.M{"emoji": emoji}); err != nil {
+			return fmt.Errorf("failed to migrate emoji of category %s: %w", category.ID.Hex(), err)
+		}
+		migrated++
+	}
+	if migrated > 0 {
+		log.Info().Int("count", migrated).Msg("Replaced invalid category emojis")
+	}
+	return nil
+}
@@ -0,0 +1,384 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/extract"
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+const (
+	maxFeedsPerUser = 50
+	// maxFeedEntriesPerFetch bounds the bookmarks one fetch creates. Older unseen
+	// entries past it are skipped.
+	maxFeedEntriesPerFetch = 50
+	// maxFeedSeenEntries bounds the entry IDs remembered per feed, which must
+	// exceed the length of the feeds followed.
+	maxFeedSeenEntries   = 500
+	maxFeedSummaryLength = 500
+	maxFeedBackoff       = 24 * time.Hour
+	// feedFetchBatch bounds the feeds fetched by one scheduler run.
+	feedFetchBatch = 100
+)
+
+// CollectionFeedService follows RSS and Atom feeds into collections. Every new
+// entry of a feed is saved as a bookmark of the collection, marked with the
+// "feed" source client, unless the user already saved its URL.
+type CollectionFeedService interface {
+	// AddFeed fetches the feed once to check it and saves its entries in the
+	// background.
+	AddFeed(ctx context.Context, userID, collectionID primitive.ObjectID, req models.AddCollectionFeedRequest) (*models.CollectionFeed, error)
+	ListFeeds(ctx context.Context, userID, collectionID primitive.ObjectID) ([]models.CollectionFeed, error)
+	UpdateFeed(ctx context.Context, userID, collectionID, feedID primitive.ObjectID, update models.CollectionFeedUpdate) (*models.CollectionFeed, error)
+	RemoveFeed(ctx context.Context, userID, collectionID, feedID primitive.ObjectID) (bool, error)
+	// FetchDue fetches the feeds whose next fetch is due. It is run by the
+	// scheduler.
+	FetchDue(ctx context.Context) error
+}
+
+type collectionFeedServiceImpl struct {
+	feedRepo       repositories.CollectionFeedRepository
+	bookmarkRepo   repositories.BookmarkRepository
+	collectionRepo repositories.CollectionRepository
+	urls           URLService
+	fetcher        *extract.Fetcher
+	slots          chan struct{}
+}
+
+func NewCollectionFeedService(feedRepo repositories.CollectionFeedRepository, bookmarkRepo repositories.BookmarkRepository, collectionRepo repositories.CollectionRepository, urls URLService) CollectionFeedService {
+	return &collectionFeedServiceImpl{
+		feedRepo:       feedRepo,
+		bookmarkRepo:   bookmarkRepo,
+		collectionRepo: collectionRepo,
+		urls:           urls,
+		fetcher:        extract.NewFetcher(os.Getenv("CONTENT_EXTRACTION_ALLOW_PRIVATE") == "true"),
+		slots:          make(chan struct{}, 4),
+	}
+}
+
+func validateFeedInterval(minutes int) error {
+	if minutes < models.MinFeedIntervalMinutes || minutes > models.MaxFeedIntervalMinutes {
+		return fmt.Errorf("invalid interval_minutes: must be between %d and %d", models.MinFeedIntervalMinutes, models.MaxFeedIntervalMinutes)
+	}
+	return nil
+}
+
+func (s *collectionFeedServiceImpl) findCollection(ctx context.Context, userID, collectionID primitive.ObjectID) error {
+	if _, err := s.collectionRepo.FindByID(ctx, userID, collectionID); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return fmt.Errorf("collection not found")
+		}
+		log.Error().Err(err).Str("userID", userID.Hex()).Str("collectionID", collectionID.Hex()).Msg("Failed to find collection for feed")
+		return fmt.Errorf("failed to retrieve collection")
+	}
+	return nil
+}
+
+func (s *collectionFeedServiceImpl) AddFeed(ctx context.Context, userID, collectionID primitive.ObjectID, req models.AddCollectionFeedRequest) (*models.CollectionFeed, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("collectionID", collectionID.Hex()).Str("url", req.URL).Msg("Attempting to add collection feed")
+	feedURL, err := utils.NormalizeURL(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	interval := req.IntervalMinutes
+	if interval == 0 {
+		interval = models.DefaultFeedIntervalMinutes
+	}
+	if err := validateFeedInterval(interval); err != nil {
+		return nil, err
+	}
+	if err := s.findCollection(ctx, userID, collectionID); err != nil {
+		return nil, err
+	}
+	count, err := s.feedRepo.Count(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to count collection feeds")
+		return nil, fmt.Errorf("failed to add feed")
+	}
+	if count >= maxFeedsPerUser {
+		return nil, fmt.Errorf("%w: at most %d feeds per user", utils.ErrLimitExceeded, maxFeedsPerUser)
+	}
+
+	resp, err := s.fetcher.FetchFeed(ctx, feedURL, "", "")
+	if err != nil {
+		log.Warn().Err(err).Str("userID", userID.Hex()).Str("url", feedURL).Msg("Failed to fetch new collection feed")
+		return nil, fmt.Errorf("invalid feed: %v", err)
+	}
+	now := time.Now().UTC()
+	feed := &models.CollectionFeed{
+		ID:              primitive.NewObjectID(),
+		UserID:          userID,
+		CollectionID:    collectionID,
+		URL:             feedURL,
+		Title:           resp.Feed.Title,
+		IntervalMinutes: interval,
+		// The first entries are saved below, so the scheduler waits a full interval.
+		NextFetchAt: now.Add(time.Duration(interval) * time.Minute),
+		Seen:        []string{},
+		CreatedAt:   now,
+	}
+	if err := s.feedRepo.Create(ctx, feed); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("feed already followed by this collection")
+		}
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to store collection feed")
+		return nil, fmt.Errorf("failed to add feed")
+	}
+	log.Info().Str("userID", userID.Hex()).Str("feedID", feed.ID.Hex()).Str("url", feedURL).Msg("Collection feed added")
+
+	select {
+	case s.slots <- struct{}{}:
+		go func() {
+			defer func() { <-s.slots }()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			defer cancel()
+			if err := s.record(ctx, feed, resp, nil); err != nil {
+				log.Warn().Err(err).Str("feedID", feed.ID.Hex()).Msg("First collection feed ingestion failed")
+			}
+		}()
+	default:
+		// Every slot is busy: the scheduler saves the entries instead.
+		if _, err := s.feedRepo.Update(ctx, bson.M{"_id": feed.ID}, bson.M{"next_fetch_at": now}); err != nil {
+			log.Error().Err(err).Str("feedID", feed.ID.Hex()).Msg("Failed to schedule collection feed")
+		}
+	}
+	return feed, nil
+}
+
+func (s *collectionFeedServiceImpl) ListFeeds(ctx context.Context, userID, collectionID primitive.ObjectID) ([]models.CollectionFeed, error) {
+	if err := s.findCollection(ctx, userID, collectionID); err != nil {
+		return nil, err
+	}
+	feeds, err := s.feedRepo.Find(ctx, bson.M{"user_id": userID, "collection_id": collectionID}, bson.D{{Key: "created_at", Value: 1}}, 0)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to retrieve collection feeds")
+		return nil, fmt.Errorf("failed to retrieve feeds")
+	}
+	return feeds, nil
+}
+
+// UpdateFeed changes how often the feed is fetched. The feed is fetched at the
+// next scheduler run, also when it was backing off after failures.
+func (s *collectionFeedServiceImpl) UpdateFeed(ctx context.Context, userID, collectionID, feedID primitive.ObjectID, update models.CollectionFeedUpdate) (*models.CollectionFeed, error) {
+	if update.IntervalMinutes == nil {
+		return nil, fmt.Errorf("no valid fields provided for update")
+	}
+	if err := validateFeedInterval(*update.IntervalMinutes); err != nil {
+		return nil, err
+	}
+	fields := bson.M{"interval_minutes": *update.IntervalMinutes, "next_fetch_at": time.Now().UTC()}
+	feed, err := s.feedRepo.Update(ctx, bson.M{"_id": feedID, "user_id": userID, "collection_id": collectionID}, fields)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("feed not found")
+		}
+		log.Error().Err(err).Str("userID", userID.Hex()).Str("feedID", feedID.Hex()).Msg("Failed to update collection feed")
+		return nil, fmt.Errorf("failed to update feed")
+	}
+	return feed, nil
+}
+
+// RemoveFeed stops following the feed. The bookmarks it created are kept.
+func (s *collectionFeedServiceImpl) RemoveFeed(ctx context.Context, userID, collectionID, feedID primitive.ObjectID) (bool, error) {
+	result, err := s.feedRepo.Delete(ctx, bson.M{"_id": feedID, "user_id": userID, "collection_id": collectionID})
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Str("feedID", feedID.Hex()).Msg("Failed to delete collection feed")
+		return false, fmt.Errorf("failed to remove feed")
+	}
+	if result.DeletedCount > 0 {
+		log.Info().Str("userID", userID.Hex()).Str("feedID", feedID.Hex()).Msg("Collection feed removed")
+	}
+	return result.DeletedCount > 0, nil
+}
+
+func (s *collectionFeedServiceImpl) FetchDue(ctx context.Context) error {
+	now := time.Now().UTC()
+	feeds, err := s.feedRepo.Find(ctx, bson.M{"next_fetch_at": bson.M{"$lte": now}}, bson.D{{Key: "next_fetch_at", Value: 1}}, feedFetchBatch)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for i := range feeds {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		feed := &feeds[i]
+		if _, err := s.collectionRepo.FindByID(ctx, feed.UserID, feed.CollectionID); err == mongo.ErrNoDocuments {
+			// The collection was deleted, and its feeds go with it.
+			if _, err := s.feedRepo.Delete(ctx, bson.M{"_id": feed.ID}); err != nil {
+				log.Error().Err(err).Str("feedID", feed.ID.Hex()).Msg("Failed to delete orphaned collection feed")
+			}
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to retrieve collection: %w", err)
+		}
+
+		fetchCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		resp, fetchErr := s.fetcher.FetchFeed(fetchCtx, feed.URL, feed.ETag, feed.LastModified)
+		cancel()
+		if err := s.record(ctx, feed, resp, fetchErr); err != nil {
+			failed++
+			log.Warn().Err(err).Str("feedID", feed.ID.Hex()).Str("url", feed.URL).Int("failures", feed.Failures+1).Msg("Collection feed fetch failed")
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("fetching failed for %d of %d feeds", failed, len(feeds))
+	}
+	return nil
+}
+
+// record saves the entries of a fetched feed and schedules its next fetch, or
+// backs off after fetchErr. It returns the error that failed the fetch.
+func (s *collectionFeedServiceImpl) record(ctx context.Context, feed *models.CollectionFeed, resp *extract.FeedResponse, fetchErr error) error {
+	now := time.Now().UTC()
+	interval := time.Duration(feed.IntervalMinutes) * time.Minute
+	created := 0
+	fields := bson.M{}
+	if fetchErr == nil && !resp.NotModified {
+		var seen []string
+		if created, seen, fetchErr = s.ingest(ctx, feed, resp.Feed.Entries); fetchErr == nil {
+			fields["seen"] = seen
+			if resp.Feed.Title != "" {
+				fields["title"] = resp.Feed.Title
+			}
+		}
+	}
+	// Bookmarks saved before a failure still count.
+	fields["ingested"] = feed.Ingested + created
+	if fetchErr != nil {
+		fields["failures"] = feed.Failures + 1
+		fields["last_error"] = fetchErr.Error()
+		fields["next_fetch_at"] = now.Add(feedBackoff(interval, feed.Failures+1))
+	} else {
+		// The validators are only kept once every entry is saved, so a failed
+		// ingestion is retried with the full document.
+		fields["etag"] = resp.ETag
+		fields["last_modified"] = resp.LastModified
+		fields["last_fetched_at"] = now
+		fields["next_fetch_at"] = now.Add(interval)
+		fields["failures"] = 0
+		fields["last_error"] = ""
+		if created > 0 {
+			log.Info().Str("feedID", feed.ID.Hex()).Int("created", created).Msg("Collection feed entries saved")
+		}
+	}
+	if _, err := s.feedRepo.Update(ctx, bson.M{"_id": feed.ID}, fields); err != nil && err != mongo.ErrNoDocuments {
+		log.Error().Err(err).Str("feedID", feed.ID.Hex()).Msg("Failed to record collection feed fetch")
+	}
+	return fetchErr
+}
+
+// feedBackoff is the wait after the given number of failed fetches in a row:
+// the interval doubled for each failure, up to maxFeedBackoff.
+func feedBackoff(interval time.Duration, failures int) time.Duration {
+	wait := interval
+	for i := 0; i < failures && wait < maxFeedBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxFeedBackoff {
+		wait = maxFeedBackoff
+	}
+	return wait
+}
+
+// unseenFeedEntries returns the entries whose ID is not in seen, at most
+// maxFeedEntriesPerFetch of them in document order, and the IDs to remember
+// afterwards: the document's first, then the older ones, up to
+// maxFeedSeenEntries.
+func unseenFeedEntries(entries []extract.FeedEntry, seen []string) ([]extract.FeedEntry, []string) {
+	known := make(map[string]bool, len(seen))
+	for _, id := range seen {
+		known[id] = true
+	}
+	var unseen []extract.FeedEntry
+	remembered := make([]string, 0, len(entries)+len(seen))
+	inDocument := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if inDocument[entry.ID] {
+			continue
+		}
+		inDocument[entry.ID] = true
+		remembered = append(remembered, entry.ID)
+		if !known[entry.ID] && len(unseen) < maxFeedEntriesPerFetch {
+			unseen = append(unseen, entry)
+		}
+	}
+	for _, id := range seen {
+		if !inDocument[id] {
+			remembered = append(remembered, id)
+		}
+	}
+	if len(remembered) > maxFeedSeenEntries {
+		remembered = remembered[:maxFeedSeenEntries]
+	}
+	return unseen, remembered
+}
+
+// ingest saves the unseen entries as bookmarks of the feed's collection, the
+// last listed first, and adds the ones the user already saved to the collection.
+// Entries are matched by their normalized URL, without following redirects, so a
+// fetch makes no request per entry, and entries listing the same URL are saved
+// once. It returns how many bookmarks it created and the entry IDs to remember.
+func (s *collectionFeedServiceImpl) ingest(ctx context.Context, feed *models.CollectionFeed, entries []extract.FeedEntry) (int, []string, error) {
+	unseen, seen := unseenFeedEntries(entries, feed.Seen)
+	now := time.Now()
+	created := 0
+	ingested := make(map[string]bool, len(unseen))
+	for i := len(unseen) - 1; i >= 0; i-- {
+		entry := unseen[i]
+		normalized, err := utils.NormalizeURL(entry.URL)
+		if err != nil || ingested[normalized] {
+			continue
+		}
+		ingested[normalized] = true
+		existing, canonical, err := findExistingBookmark(ctx, s.bookmarkRepo, s.urls, feed.UserID, normalized)
+		if err != nil {
+			return created, nil, err
+		}
+		if existing != nil {
+			if !containsObjectID(existing.CollectionsID, feed.CollectionID) {
+				filter := bson.M{"_id": existing.ID, "user_id": feed.UserID}
+				if _, err := s.bookmarkRepo.UpdateOne(ctx, filter, bson.M{"$addToSet": bson.M{"collectionsid": feed.CollectionID}}); err != nil {
+					return created, nil, fmt.Errorf("failed to import bookmark %s", normalized)
+				}
+			}
+			continue
+		}
+
+		feedID := feed.ID
+		bm := &models.Bookmark{
+			ID:            primitive.NewObjectID(),
+			UserID:        feed.UserID,
+			URL:           normalized,
+			CanonicalURL:  canonical,
+			Title:         entry.Title,
+			Summary:       truncateRunes(entry.Summary, maxFeedSummaryLength),
+			CollectionsID: []primitive.ObjectID{feed.CollectionID},
+			Source:        &models.BookmarkSource{Client: models.SourceClientFeed, Referrer: feed.URL, FeedID: &feedID},
+			CreatedAt:     primitive.NewDateTimeFromTime(entry.Published),
+		}
+		if bm.Title == "" {
+			bm.Title = normalized
+		}
+		if entry.Published.IsZero() || entry.Published.After(now) {
+			bm.CreatedAt = primitive.NewDateTimeFromTime(now)
+		}
+		bm.SearchGrams = bookmarkSearchGrams(bm)
+		if _, err := s.bookmarkRepo.Create(ctx, bm); err != nil {
+			log.Error().Err(err).Str("userID", feed.UserID.Hex()).Str("feedID", feed.ID.Hex()).Msg("Failed to save collection feed entry")
+			return created, nil, fmt.Errorf("failed to import bookmark %s", bm.URL)
+		}
+		created++
+	}
+	return created, seen, nil
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/extract"
+	"markly/internal/models"
+)
+
+func TestFeedBackoff(t *testing.T) {
+	cases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, time.Hour},
+		{1, 2 * time.Hour},
+		{3, 8 * time.Hour},
+		{5, maxFeedBackoff},
+		{80, maxFeedBackoff},
+	}
+	for _, c := range cases {
+		if got := feedBackoff(time.Hour, c.failures); got != c.want {
+			t.Errorf("feedBackoff(1h, %d) = %v, want %v", c.failures, got, c.want)
+		}
+	}
+}
+
+func TestUnseenFeedEntries(t *testing.T) {
+	entries := []extract.FeedEntry{{ID: "c"}, {ID: "b"}, {ID: "b"}, {ID: "a"}}
+	unseen, seen := unseenFeedEntries(entries, []string{"a", "old"})
+	if len(unseen) != 2 || unseen[0].ID != "c" || unseen[1].ID != "b" {
+		t.Errorf("unseen = %v, want c and b", unseen)
+	}
+	if want := []string{"c", "b", "a", "old"}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("seen = %v, want %v", seen, want)
+	}
+}
+
+func TestUnseenFeedEntriesBounds(t *testing.T) {
+	var entries []extract.FeedEntry
+	for i := 0; i < maxFeedEntriesPerFetch+10; i++ {
+		entries = append(entries, extract.FeedEntry{ID: fmt.Sprint("new", i)})
+	}
+	var old []string
+	for i := 0; i < maxFeedSeenEntries; i++ {
+		old = append(old, fmt.Sprint("old", i))
+	}
+	unseen, seen := unseenFeedEntries(entries, old)
+	if len(unseen) != maxFeedEntriesPerFetch || unseen[0].ID != "new0" {
+		t.Errorf("got %d unseen entries starting with %q, want the first %d", len(unseen), unseen[0].ID, maxFeedEntriesPerFetch)
+	}
+	if len(seen) != maxFeedSeenEntries || seen[0] != "new0" || seen[len(entries)] != "old0" {
+		t.Errorf("seen should keep the document's entries first, up to %d", maxFeedSeenEntries)
+	}
+}
+
+func TestCollectionFeedIngest(t *testing.T) {
+	userID := primitive.NewObjectID()
+	saved := &models.Bookmark{ID: primitive.NewObjectID(), UserID: userID, URL: "https://example.com/saved"}
+	bookmarks := &fakeBookmarks{bookmarks: []*models.Bookmark{saved}}
+	s := &collectionFeedServiceImpl{bookmarkRepo: bookmarks, urls: offlineURLs{t}}
+	feed := &models.CollectionFeed{ID: primitive.NewObjectID(), UserID: userID, CollectionID: primitive.NewObjectID(), URL: "https://example.com/feed.xml"}
+	entries := []extract.FeedEntry{
+		{ID: "3", URL: "https://EXAMPLE.com/post", Title: "Post, again"},
+		{ID: "2", URL: "https://example.com/saved", Title: "Saved"},
+		{ID: "1", URL: "https://example.com/post", Title: "Post"},
+	}
+
+	created, seen, err := s.ingest(context.Background(), feed, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != 1 || len(bookmarks.bookmarks) != 2 || bookmarks.bookmarks[1].Title != "Post" {
+		t.Errorf("created %d bookmarks, want the entries sharing a URL saved once", created)
+	}
+	if len(bookmarks.updates) != 1 || bookmarks.updates[0] != saved.ID {
+		t.Errorf("updated %v, want the saved bookmark added to the collection", bookmarks.updates)
+	}
+	if want := []string{"3", "2", "1"}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("seen = %v, want %v", seen, want)
+	}
+}
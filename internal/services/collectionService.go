@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson"
@@ -11,30 +12,62 @@ import (
 
 	"markly/internal/models"
 	"markly/internal/repositories"
+	"markly/internal/utils"
 )
 
 type CollectionService interface {
 	AddCollection(ctx context.Context, userID primitive.ObjectID, col models.Collection) (*models.Collection, error)
-	GetCollections(ctx context.Context, userID primitive.ObjectID) ([]models.Collection, error)
+	GetCollections(ctx context.Context, userID primitive.ObjectID, page models.PageRequest) ([]models.Collection, string, error)
 	GetCollectionByID(ctx context.Context, userID, collectionID primitive.ObjectID) (*models.Collection, error)
+	GetCollectionBySlug(ctx context.Context, userID primitive.ObjectID, slug string) (*models.Collection, error)
 	DeleteCollection(ctx context.Context, userID, collectionID primitive.ObjectID) (bool, error)
 	UpdateCollection(ctx context.Context, userID, collectionID primitive.ObjectID, updatePayload models.CollectionUpdate) (*models.Collection, error)
+	PreviewAutoArchive(ctx context.Context, userID, collectionID primitive.ObjectID, policy *models.AutoArchivePolicy) (*models.AutoArchivePreview, error)
+	GetCollectionStats(ctx context.Context, userID, collectionID primitive.ObjectID) (*models.CollectionStats, error)
+	RunAutoArchive(ctx context.Context) error
+	MigrateSlugs(ctx context.Context) error
 }
 
+// autoArchivePreviewLimit caps the bookmarks listed by an auto-archive preview.
+const autoArchivePreviewLimit = 50
+
+const (
+	// collectionStatsTopTags is the number of tags listed by collection stats.
+	collectionStatsTopTags = 10
+	// readingWordsPerMinute is the reading speed behind estimated reading times.
+	readingWordsPerMinute = 200
+)
+
 type collectionServiceImpl struct {
 	collectionRepo repositories.CollectionRepository
+	bookmarkRepo   repositories.BookmarkRepository
+	ownership      *Ownership
 }
 
-func NewCollectionService(collectionRepo repositories.CollectionRepository) CollectionService {
-	return &collectionServiceImpl{collectionRepo: collectionRepo}
+func NewCollectionService(collectionRepo repositories.CollectionRepository, bookmarkRepo repositories.BookmarkRepository, ownership *Ownership) CollectionService {
+	return &collectionServiceImpl{collectionRepo: collectionRepo, bookmarkRepo: bookmarkRepo, ownership: ownership}
 }
 
 func (s *collectionServiceImpl) AddCollection(ctx context.Context, userID primitive.ObjectID, col models.Collection) (*models.Collection, error) {
 	log.Debug().Str("userID", userID.Hex()).Interface("collectionName", col.Name).Msg("Attempting to add collection")
 	col.UserID = userID
 	col.ID = primitive.NewObjectID()
+	col.PreviousSlugs = nil
+	if col.Settings != nil {
+		if err := validateAutoArchivePolicy(col.Settings.AutoArchive); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkCollectionLimit(ctx, s.collectionRepo, userID); err != nil {
+		return nil, err
+	}
 
-	createdCol, err := s.collectionRepo.Create(ctx, &col)
+	var createdCol *models.Collection
+	err := saveWithSlug(ctx, s.collectionRepo, userID, col.ID, col.Name, "collection", func(slug string) (err error) {
+		col.Slug = slug
+		createdCol, err = s.collectionRepo.Create(ctx, &col)
+		return err
+	})
 	if err != nil {
 		if mongo.IsDuplicateKeyError(err) {
 			log.Warn().Err(err).Str("userID", userID.Hex()).Interface("collectionName", col.Name).Msg("Collection name already exists for this user")
@@ -43,19 +76,59 @@ func (s *collectionServiceImpl) AddCollection(ctx context.Context, userID primit
 		log.Error().Err(err).Str("collection_name", col.Name).Str("user_id", userID.Hex()).Msg("Failed to insert collection")
 		return nil, err
 	}
+	if err := keepWithinCollectionLimit(ctx, s.collectionRepo, createdCol); err != nil {
+		return nil, err
+	}
 	log.Info().Str("userID", userID.Hex()).Str("collectionID", createdCol.ID.Hex()).Interface("collectionName", createdCol.Name).Msg("Collection added successfully")
 	return createdCol, nil
 }
 
-func (s *collectionServiceImpl) GetCollections(ctx context.Context, userID primitive.ObjectID) ([]models.Collection, error) {
+// checkCollectionLimit rejects a new collection when the user already has
+// MaxCollectionsPerUser of them.
+func checkCollectionLimit(ctx context.Context, repo repositories.CollectionRepository, userID primitive.ObjectID) error {
+	count, err := repo.CountByUser(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to count collections")
+		return fmt.Errorf("failed to count collections")
+	}
+	if max := utils.CurrentLimits().MaxCollectionsPerUser; count >= int64(max) {
+		return fmt.Errorf("%w: at most %d collections per user", utils.ErrLimitExceeded, max)
+	}
+	return nil
+}
+
+// keepWithinCollectionLimit counts the user's collections after col was created
+// and deletes it again when that went over MaxCollectionsPerUser. Counting after
+// the insert keeps concurrent creations from going over the limit together;
+// checkCollectionLimit only saves creating a collection that cannot fit.
+func keepWithinCollectionLimit(ctx context.Context, repo repositories.CollectionRepository, col *models.Collection) error {
+	max := utils.CurrentLimits().MaxCollectionsPerUser
+	count, err := repo.CountByUser(ctx, col.UserID)
+	if err == nil && count <= int64(max) {
+		return nil
+	}
+	// The collection is removed even when the request was canceled.
+	discardCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := repo.Delete(discardCtx, col.UserID, col.ID); err != nil {
+		log.Error().Err(err).Str("collectionID", col.ID.Hex()).Msg("Failed to discard collection over the limit")
+	}
+	if err != nil {
+		log.Error().Err(err).Str("userID", col.UserID.Hex()).Msg("Failed to count collections")
+		return fmt.Errorf("failed to count collections")
+	}
+	return fmt.Errorf("%w: at most %d collections per user", utils.ErrLimitExceeded, max)
+}
+
+func (s *collectionServiceImpl) GetCollections(ctx context.Context, userID primitive.ObjectID, page models.PageRequest) ([]models.Collection, string, error) {
 	log.Debug().Str("userID", userID.Hex()).Msg("Attempting to retrieve collections")
-	results, err := s.collectionRepo.FindByUser(ctx, userID)
+	results, next, err := s.collectionRepo.FindPageByUser(ctx, userID, page)
 	if err != nil {
 		log.Error().Err(err).Str("user_id", userID.Hex()).Msg("Database error fetching collections")
-		return nil, err
+		return nil, "", err
 	}
 	log.Debug().Str("userID", userID.Hex()).Int("count", len(results)).Msg("Successfully retrieved collections")
-	return results, nil
+	return results, next, nil
 }
 
 func (s *collectionServiceImpl) GetCollectionByID(ctx context.Context, userID, collectionID primitive.ObjectID) (*models.Collection, error) {
@@ -64,7 +137,7 @@ func (s *collectionServiceImpl) GetCollectionByID(ctx context.Context, userID, c
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			log.Warn().Str("userID", userID.Hex()).Str("collectionID", collectionID.Hex()).Msg("Collection not found or unauthorized")
-			return nil, fmt.Errorf("collection not found or unauthorized")
+			return nil, s.ownership.Missing(ctx, userID, ResourceCollection, collectionID, fmt.Errorf("collection not found or unauthorized"))
 		}
 		log.Error().Err(err).Str("collection_id", collectionID.Hex()).Str("user_id", userID.Hex()).Msg("Database error finding collection")
 		return nil, fmt.Errorf("database error finding collection")
@@ -73,6 +146,44 @@ func (s *collectionServiceImpl) GetCollectionByID(ctx context.Context, userID, c
 	return col, nil
 }
 
+// GetCollectionBySlug finds a collection by its current or a previous slug. The
+// returned collection's Slug differs from slug when the collection was renamed.
+func (s *collectionServiceImpl) GetCollectionBySlug(ctx context.Context, userID primitive.ObjectID, slug string) (*models.Collection, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("slug", slug).Msg("Attempting to retrieve collection by slug")
+	col, err := s.collectionRepo.FindBySlug(ctx, userID, slug)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			log.Warn().Str("userID", userID.Hex()).Str("slug", slug).Msg("Collection not found by slug")
+			return nil, fmt.Errorf("collection not found or unauthorized")
+		}
+		log.Error().Err(err).Str("user_id", userID.Hex()).Str("slug", slug).Msg("Database error finding collection by slug")
+		return nil, fmt.Errorf("database error finding collection")
+	}
+	return col, nil
+}
+
+// MigrateSlugs assigns a slug to every collection created before slugs existed.
+// It is safe to run repeatedly.
+func (s *collectionServiceImpl) MigrateSlugs(ctx context.Context) error {
+	collections, err := s.collectionRepo.FindWithoutSlug(ctx)
+	if err != nil {
+		return err
+	}
+	for _, col := range collections {
+		err := saveWithSlug(ctx, s.collectionRepo, col.UserID, col.ID, col.Name, "collection", func(slug string) error {
+			_, err := s.collectionRepo.Update(ctx, col.UserID, col.ID, bson.M{"slug": slug})
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to migrate slug of collection %s: %w", col.ID.Hex(), err)
+		}
+	}
+	if len(collections) > 0 {
+		log.Info().Int("count", len(collections)).Msg("Assigned collection slugs")
+	}
+	return nil
+}
+
 func (s *collectionServiceImpl) DeleteCollection(ctx context.Context, userID, collectionID primitive.ObjectID) (bool, error) {
 	log.Debug().Str("userID", userID.Hex()).Str("collectionID", collectionID.Hex()).Msg("Attempting to delete collection")
 	result, err := s.collectionRepo.Delete(ctx, userID, collectionID)
@@ -82,7 +193,7 @@ func (s *collectionServiceImpl) DeleteCollection(ctx context.Context, userID, co
 	}
 	if result.DeletedCount == 0 {
 		log.Warn().Str("userID", userID.Hex()).Str("collectionID", collectionID.Hex()).Msg("Collection not found or unauthorized to delete")
-		return false, fmt.Errorf("collection not found or unauthorized to delete")
+		return false, s.ownership.Missing(ctx, userID, ResourceCollection, collectionID, fmt.Errorf("collection not found or unauthorized to delete"))
 	}
 	log.Info().Str("userID", userID.Hex()).Str("collectionID", collectionID.Hex()).Msg("Collection deleted successfully")
 	return true, nil
@@ -94,6 +205,15 @@ func (s *collectionServiceImpl) buildCollectionUpdateFields(updatePayload models
 	if updatePayload.Name != nil {
 		updateFields["name"] = *updatePayload.Name
 	}
+	if updatePayload.Settings != nil {
+		if err := validateAutoArchivePolicy(updatePayload.Settings.AutoArchive); err != nil {
+			return nil, err
+		}
+		// Settings are set field by field so that settings added later are not
+		// cleared by clients that only know auto_archive. A missing policy
+		// disables auto-archiving.
+		updateFields["settings.auto_archive"] = updatePayload.Settings.AutoArchive
+	}
 	log.Debug().Interface("updateFields", updateFields).Msg("Collection update fields built successfully")
 	return updateFields, nil
 }
@@ -111,7 +231,31 @@ func (s *collectionServiceImpl) UpdateCollection(ctx context.Context, userID, co
 		return nil, fmt.Errorf("no fields to update")
 	}
 
-	result, err := s.collectionRepo.Update(ctx, userID, collectionID, updateFields)
+	var result *mongo.UpdateResult
+	update := func() (err error) {
+		result, err = s.collectionRepo.Update(ctx, userID, collectionID, updateFields)
+		return err
+	}
+	if updatePayload.Name == nil {
+		err = update()
+	} else {
+		var existing *models.Collection
+		existing, err = s.collectionRepo.FindByID(ctx, userID, collectionID)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, s.ownership.Missing(ctx, userID, ResourceCollection, collectionID, fmt.Errorf("collection not found or unauthorized to update"))
+			}
+			log.Error().Err(err).Str("collection_id", collectionID.Hex()).Str("user_id", userID.Hex()).Msg("Failed to find collection to rename")
+			return nil, fmt.Errorf("failed to update collection")
+		}
+		err = saveWithSlug(ctx, s.collectionRepo, userID, collectionID, *updatePayload.Name, "collection", func(slug string) error {
+			if slug != existing.Slug {
+				updateFields["slug"] = slug
+				updateFields["previous_slugs"] = renamedSlugs(existing.Slug, existing.PreviousSlugs, slug)
+			}
+			return update()
+		})
+	}
 	if err != nil {
 		if mongo.IsDuplicateKeyError(err) {
 			log.Warn().Err(err).Str("userID", userID.Hex()).Str("collectionID", collectionID.Hex()).Msg("Collection name already exists for this user during update")
@@ -123,7 +267,7 @@ func (s *collectionServiceImpl) UpdateCollection(ctx context.Context, userID, co
 
 	if result.MatchedCount == 0 {
 		log.Warn().Str("userID", userID.Hex()).Str("collectionID", collectionID.Hex()).Msg("Collection not found or unauthorized to update")
-		return nil, fmt.Errorf("collection not found or unauthorized to update")
+		return nil, s.ownership.Missing(ctx, userID, ResourceCollection, collectionID, fmt.Errorf("collection not found or unauthorized to update"))
 	}
 
 	updatedCollection, err := s.collectionRepo.FindByID(ctx, userID, collectionID)
@@ -134,3 +278,125 @@ func (s *collectionServiceImpl) UpdateCollection(ctx context.Context, userID, co
 	log.Info().Str("userID", userID.Hex()).Str("collectionID", collectionID.Hex()).Msg("Collection updated successfully")
 	return updatedCollection, nil
 }
+
+func validateAutoArchivePolicy(policy *models.AutoArchivePolicy) error {
+	if policy == nil {
+		return nil
+	}
+	if policy.AfterDays < 1 || policy.AfterDays > models.MaxAutoArchiveDays {
+		return fmt.Errorf("invalid auto-archive policy: after_days must be between 1 and %d", models.MaxAutoArchiveDays)
+	}
+	return nil
+}
+
+// autoArchiveFilter matches the bookmarks of a collection that policy archives at now.
+func autoArchiveFilter(col *models.Collection, policy models.AutoArchivePolicy, now time.Time) bson.M {
+	cutoff := now.AddDate(0, 0, -policy.AfterDays)
+	filter := bson.M{
+		"user_id":       col.UserID,
+		"collectionsid": col.ID,
+		"archived_at":   nil,
+		"pinned":        bson.M{"$ne": true},
+		"created_at":    bson.M{"$lt": primitive.NewDateTimeFromTime(cutoff)},
+	}
+	if policy.UnreadOnly {
+		filter["read"] = bson.M{"$ne": true}
+	}
+	return filter
+}
+
+// PreviewAutoArchive reports what an auto-archive run would archive in the collection.
+// policy overrides the saved policy so that a policy can be tried before saving it.
+func (s *collectionServiceImpl) PreviewAutoArchive(ctx context.Context, userID, collectionID primitive.ObjectID, policy *models.AutoArchivePolicy) (*models.AutoArchivePreview, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("collectionID", collectionID.Hex()).Msg("Attempting to preview auto-archive")
+	col, err := s.GetCollectionByID(ctx, userID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if policy == nil && col.Settings != nil {
+		policy = col.Settings.AutoArchive
+	}
+	if policy == nil {
+		return nil, fmt.Errorf("invalid request: collection has no auto-archive policy")
+	}
+	if err := validateAutoArchivePolicy(policy); err != nil {
+		return nil, err
+	}
+
+	filter := autoArchiveFilter(col, *policy, time.Now())
+	count, err := s.bookmarkRepo.Count(ctx, filter)
+	if err != nil {
+		log.Error().Err(err).Str("collectionID", collectionID.Hex()).Msg("Failed to count bookmarks for auto-archive preview")
+		return nil, fmt.Errorf("failed to preview auto-archive")
+	}
+	bookmarks, err := s.bookmarkRepo.Find(ctx, filter, autoArchivePreviewLimit, 1)
+	if err != nil {
+		log.Error().Err(err).Str("collectionID", collectionID.Hex()).Msg("Failed to list bookmarks for auto-archive preview")
+		return nil, fmt.Errorf("failed to preview auto-archive")
+	}
+	if bookmarks == nil {
+		bookmarks = []models.Bookmark{}
+	}
+	return &models.AutoArchivePreview{Policy: *policy, Count: count, Bookmarks: bookmarks}, nil
+}
+
+// GetCollectionStats summarises the collection for its header: counts, the oldest
+// and newest bookmarks, the most used tags and the estimated reading time.
+func (s *collectionServiceImpl) GetCollectionStats(ctx context.Context, userID, collectionID primitive.ObjectID) (*models.CollectionStats, error) {
+	if _, err := s.GetCollectionByID(ctx, userID, collectionID); err != nil {
+		return nil, err
+	}
+	stats, err := s.bookmarkRepo.CollectionStats(ctx, userID, collectionID, collectionStatsTopTags)
+	if err != nil {
+		log.Error().Err(err).Str("collectionID", collectionID.Hex()).Msg("Failed to compute collection stats")
+		return nil, fmt.Errorf("failed to compute collection stats")
+	}
+	if stats.TopTags == nil {
+		stats.TopTags = []models.CollectionTagCount{}
+	}
+	stats.ReadingMinutes = readingMinutes(stats.WordCount)
+	return stats, nil
+}
+
+// readingMinutes estimates the minutes needed to read words, rounded up.
+func readingMinutes(words int64) int64 {
+	return (words + readingWordsPerMinute - 1) / readingWordsPerMinute
+}
+
+// RunAutoArchive applies the auto-archive policy of every collection. It is run by the
+// scheduler; a failing collection is logged and does not stop the others.
+func (s *collectionServiceImpl) RunAutoArchive(ctx context.Context) error {
+	collections, err := s.collectionRepo.FindWithAutoArchive(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	archivedAt := primitive.NewDateTimeFromTime(now)
+	var total int64
+	var failed int
+	for i := range collections {
+		col := &collections[i]
+		if col.Settings == nil || col.Settings.AutoArchive == nil {
+			continue
+		}
+		filter := autoArchiveFilter(col, *col.Settings.AutoArchive, now)
+		result, err := s.bookmarkRepo.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"archived_at": archivedAt}})
+		if err != nil {
+			failed++
+			log.Error().Err(err).Str("userID", col.UserID.Hex()).Str("collectionID", col.ID.Hex()).Msg("Failed to auto-archive collection")
+			continue
+		}
+		if result.ModifiedCount > 0 {
+			total += result.ModifiedCount
+			log.Info().Str("userID", col.UserID.Hex()).Str("collectionID", col.ID.Hex()).Int64("archived", result.ModifiedCount).Msg("Bookmarks auto-archived")
+		}
+	}
+	utils.AutoArchivedBookmarksTotal.Add(float64(total))
+
+	if failed > 0 {
+		return fmt.Errorf("auto-archive failed for %d of %d collections", failed, len(collections))
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+func TestBuildCollectionUpdateFieldsSetsAutoArchiveOnly(t *testing.T) {
+	s := &collectionServiceImpl{}
+	policy := &models.AutoArchivePolicy{AfterDays: 30}
+	got, err := s.buildCollectionUpdateFields(models.CollectionUpdate{Settings: &models.CollectionSettings{AutoArchive: policy}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (bson.M{"settings.auto_archive": policy}); !reflect.DeepEqual(got, want) {
+		t.Errorf("update fields = %v, want %v", got, want)
+	}
+
+	if _, err := s.buildCollectionUpdateFields(models.CollectionUpdate{Settings: &models.CollectionSettings{AutoArchive: &models.AutoArchivePolicy{}}}); err == nil {
+		t.Error("accepted a policy archiving after 0 days")
+	}
+}
+
+// countedCollections is a CollectionRepository holding count collections of a
+// user; Delete forgets one of them.
+type countedCollections struct {
+	repositories.CollectionRepository
+	count   int64
+	deleted []primitive.ObjectID
+}
+
+func (f *countedCollections) CountByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return f.count, nil
+}
+
+func (f *countedCollections) Delete(ctx context.Context, userID, collectionID primitive.ObjectID) (*mongo.DeleteResult, error) {
+	f.count--
+	f.deleted = append(f.deleted, collectionID)
+	return &mongo.DeleteResult{DeletedCount: 1}, nil
+}
+
+func TestKeepWithinCollectionLimit(t *testing.T) {
+	max := int64(utils.CurrentLimits().MaxCollectionsPerUser)
+	col := &models.Collection{ID: primitive.NewObjectID(), UserID: primitive.NewObjectID()}
+
+	// The new collection is the last one allowed.
+	repo := &countedCollections{count: max}
+	if err := keepWithinCollectionLimit(context.Background(), repo, col); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.deleted) != 0 {
+		t.Errorf("deleted %v within the limit", repo.deleted)
+	}
+
+	// A concurrent creation got in first, so the new collection is over the limit.
+	repo = &countedCollections{count: max + 1}
+	if err := keepWithinCollectionLimit(context.Background(), repo, col); !errors.Is(err, utils.ErrLimitExceeded) {
+		t.Fatalf("err = %v, want the limit exceeded", err)
+	}
+	if len(repo.deleted) != 1 || repo.deleted[0] != col.ID || repo.count != max {
+		t.Errorf("deleted %v leaving %d collections, want the new one deleted", repo.deleted, repo.count)
+	}
+}
+
+// ownCollections is a CollectionRepository finding the collections in cols.
+type ownCollections struct {
+	repositories.CollectionRepository
+	cols []*models.Collection
+}
+
+func (f ownCollections) FindByID(ctx context.Context, userID, collectionID primitive.ObjectID) (*models.Collection, error) {
+	for _, col := range f.cols {
+		if col.ID == collectionID && col.UserID == userID {
+			return col, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+// statsBookmarks is a BookmarkRepository returning stats and recording the
+// collections aggregated.
+type statsBookmarks struct {
+	repositories.BookmarkRepository
+	stats      models.CollectionStats
+	aggregated []primitive.ObjectID
+}
+
+func (f *statsBookmarks) CollectionStats(ctx context.Context, userID, collectionID primitive.ObjectID, topTags int) (*models.CollectionStats, error) {
+	f.aggregated = append(f.aggregated, collectionID)
+	stats := f.stats
+	return &stats, nil
+}
+
+func TestGetCollectionStats(t *testing.T) {
+	col := &models.Collection{ID: primitive.NewObjectID(), UserID: primitive.NewObjectID()}
+	bookmarks := &statsBookmarks{stats: models.CollectionStats{Count: 3, Measured: 2, WordCount: 401}}
+	s := NewCollectionService(ownCollections{cols: []*models.Collection{col}}, bookmarks, nil)
+
+	stats, err := s.GetCollectionStats(context.Background(), col.UserID, col.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.ReadingMinutes != 3 {
+		t.Errorf("reading minutes for 401 words = %d, want 3", stats.ReadingMinutes)
+	}
+	if stats.TopTags == nil || len(stats.TopTags) != 0 {
+		t.Errorf("top tags = %#v, want an empty list", stats.TopTags)
+	}
+
+	// Another user's collection is not found, and its bookmarks are not aggregated.
+	if _, err := s.GetCollectionStats(context.Background(), primitive.NewObjectID(), col.ID); err == nil || err.Error() != "collection not found or unauthorized" {
+		t.Errorf("err = %v, want collection not found", err)
+	}
+	if len(bookmarks.aggregated) != 1 {
+		t.Errorf("aggregated %d collections, want 1", len(bookmarks.aggregated))
+	}
+}
+
+func TestReadingMinutes(t *testing.T) {
+	for words, want := range map[int64]int64{0: 0, 1: 1, readingWordsPerMinute: 1, readingWordsPerMinute + 1: 2} {
+		if got := readingMinutes(words); got != want {
+			t.Errorf("readingMinutes(%d) = %d, want %d", words, got, want)
+		}
+	}
+}
+
+func TestAddCollectionValidatesAutoArchive(t *testing.T) {
+	repo := &namedCollections{}
+	s := NewCollectionService(repo, nil, nil)
+	col := models.Collection{Name: "Reading", Settings: &models.CollectionSettings{AutoArchive: &models.AutoArchivePolicy{AfterDays: models.MaxAutoArchiveDays + 1}}}
+
+	if _, err := s.AddCollection(context.Background(), primitive.NewObjectID(), col); err == nil {
+		t.Fatal("accepted an auto-archive policy beyond the maximum")
+	}
+	if len(repo.cols) != 0 {
+		t.Errorf("stored %d collections with an invalid policy", len(repo.cols))
+	}
+}
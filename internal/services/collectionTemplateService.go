@@ -0,0 +1,421 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+)
+
+// maxTemplateBookmarks caps the placeholder bookmarks copied into a saved template.
+const maxTemplateBookmarks = 100
+
+var builtinCollectionTemplates = []models.CollectionTemplate{
+	{
+		Key:         "job-hunt",
+		Name:        "Job Hunt",
+		Description: "Track openings, companies and interview preparation.",
+		Tags:        []string{"applied", "interview", "offer"},
+		Categories: []models.TemplateCategory{
+			{Name: "Job Boards", Emoji: "📋"},
+			{Name: "Interview Prep", Emoji: "🎯"},
+		},
+		Bookmarks: []models.TemplateBookmark{
+			{URL: "https://www.linkedin.com/jobs/", Title: "LinkedIn Jobs", Category: "Job Boards"},
+			{URL: "https://news.ycombinator.com/jobs", Title: "Hacker News Jobs", Category: "Job Boards"},
+			{URL: "https://www.techinterviewhandbook.org/", Title: "Tech Interview Handbook", Tags: []string{"interview"}, Category: "Interview Prep"},
+		},
+	},
+	{
+		Key:         "trip-planning",
+		Name:        "Trip Planning",
+		Description: "Collect flights, places to stay and things to do.",
+		Tags:        []string{"booked", "to-visit"},
+		Categories: []models.TemplateCategory{
+			{Name: "Travel", Emoji: "✈️"},
+			{Name: "Accommodation", Emoji: "🏨"},
+		},
+		Bookmarks: []models.TemplateBookmark{
+			{URL: "https://www.google.com/travel/flights", Title: "Google Flights", Category: "Travel"},
+			{URL: "https://www.booking.com/", Title: "Booking.com", Category: "Accommodation"},
+			{URL: "https://www.wikivoyage.org/", Title: "Wikivoyage", Tags: []string{"to-visit"}, Category: "Travel"},
+		},
+	},
+	{
+		Key:         "learning-go",
+		Name:        "Learning Go",
+		Description: "Starter resources for learning the Go programming language.",
+		Tags:        []string{"golang", "tutorial", "reference"},
+		Categories: []models.TemplateCategory{
+			{Name: "Programming", Emoji: "💻"},
+		},
+		Bookmarks: []models.TemplateBookmark{
+			{URL: "https://go.dev/tour/", Title: "A Tour of Go", Tags: []string{"golang", "tutorial"}, Category: "Programming"},
+			{URL: "https://go.dev/doc/effective_go", Title: "Effective Go", Tags: []string{"golang", "reference"}, Category: "Programming"},
+			{URL: "https://gobyexample.com/", Title: "Go by Example", Tags: []string{"golang", "tutorial"}, Category: "Programming"},
+		},
+	},
+}
+
+type CollectionTemplateService interface {
+	ListTemplates(ctx context.Context, userID primitive.ObjectID) ([]models.CollectionTemplate, error)
+	CreateFromTemplate(ctx context.Context, userID primitive.ObjectID, req models.CreateFromTemplateRequest) (*models.FromTemplateResult, error)
+	SaveAsTemplate(ctx context.Context, userID, collectionID primitive.ObjectID, req models.SaveAsTemplateRequest) (*models.CollectionTemplate, error)
+	DeleteTemplate(ctx context.Context, userID, templateID primitive.ObjectID) error
+}
+
+type collectionTemplateServiceImpl struct {
+	templateRepo   repositories.CollectionTemplateRepository
+	collectionRepo repositories.CollectionRepository
+	tagRepo        repositories.TagRepository
+	categoryRepo   repositories.CategoryRepository
+	bookmarkRepo   repositories.BookmarkRepository
+	urls           URLService
+}
+
+func NewCollectionTemplateService(
+	templateRepo repositories.CollectionTemplateRepository,
+	collectionRepo repositories.CollectionRepository,
+	tagRepo repositories.TagRepository,
+	categoryRepo repositories.CategoryRepository,
+	bookmarkRepo repositories.BookmarkRepository,
+	urls URLService,
+) CollectionTemplateService {
+	return &collectionTemplateServiceImpl{
+		templateRepo:   templateRepo,
+		collectionRepo: collectionRepo,
+		tagRepo:        tagRepo,
+		categoryRepo:   categoryRepo,
+		bookmarkRepo:   bookmarkRepo,
+		urls:           urls,
+	}
+}
+
+func (s *collectionTemplateServiceImpl) ListTemplates(ctx context.Context, userID primitive.ObjectID) ([]models.CollectionTemplate, error) {
+	log.Debug().Str("userID", userID.Hex()).Msg("Attempting to list collection templates")
+	saved, err := s.templateRepo.FindByUser(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Error finding saved collection templates")
+		return nil, fmt.Errorf("failed to retrieve collection templates")
+	}
+	templates := append([]models.CollectionTemplate{}, builtinCollectionTemplates...)
+	return append(templates, saved...), nil
+}
+
+func (s *collectionTemplateServiceImpl) findTemplate(ctx context.Context, userID primitive.ObjectID, ref string) (*models.CollectionTemplate, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, fmt.Errorf("template is required")
+	}
+	for i := range builtinCollectionTemplates {
+		if builtinCollectionTemplates[i].Key == ref {
+			return &builtinCollectionTemplates[i], nil
+		}
+	}
+
+	templateID, err := primitive.ObjectIDFromHex(ref)
+	if err != nil {
+		return nil, fmt.Errorf("template not found")
+	}
+	template, err := s.templateRepo.FindByID(ctx, userID, templateID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("template not found")
+		}
+		log.Error().Err(err).Str("userID", userID.Hex()).Str("templateID", ref).Msg("Error finding collection template")
+		return nil, fmt.Errorf("failed to retrieve collection template")
+	}
+	return template, nil
+}
+
+func (s *collectionTemplateServiceImpl) CreateFromTemplate(ctx context.Context, userID primitive.ObjectID, req models.CreateFromTemplateRequest) (*models.FromTemplateResult, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("template", req.Template).Msg("Attempting to create collection from template")
+	template, err := s.findTemplate(ctx, userID, req.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = template.Name
+	}
+
+	if err := checkCollectionLimit(ctx, s.collectionRepo, userID); err != nil {
+		return nil, err
+	}
+	col := &models.Collection{ID: primitive.NewObjectID(), UserID: userID, Name: name}
+	err = saveWithSlug(ctx, s.collectionRepo, userID, col.ID, col.Name, "collection", func(slug string) error {
+		col.Slug = slug
+		_, err := s.collectionRepo.Create(ctx, col)
+		return err
+	})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			log.Warn().Str("userID", userID.Hex()).Str("collectionName", name).Msg("Collection name already exists for this user")
+			return nil, fmt.Errorf("collection name already exists for this user")
+		}
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to create collection from template")
+		return nil, fmt.Errorf("failed to create collection")
+	}
+	if err := keepWithinCollectionLimit(ctx, s.collectionRepo, col); err != nil {
+		return nil, err
+	}
+
+	result := &models.FromTemplateResult{Collection: col, Tags: []models.Tag{}, Categories: []models.Category{}, Bookmarks: []models.Bookmark{}}
+
+	tagIDs, err := s.ensureTags(ctx, userID, templateTagNames(template), result)
+	if err != nil {
+		s.discardFromTemplate(col, nil)
+		return nil, err
+	}
+	categoryIDs, err := s.ensureCategories(ctx, userID, template.Categories, result)
+	if err != nil {
+		s.discardFromTemplate(col, nil)
+		return nil, err
+	}
+
+	for _, placeholder := range template.Bookmarks {
+		bm := models.Bookmark{
+			ID:            primitive.NewObjectID(),
+			UserID:        userID,
+			URL:           placeholder.URL,
+			Title:         placeholder.Title,
+			Summary:       placeholder.Summary,
+			CollectionsID: []primitive.ObjectID{col.ID},
+			CreatedAt:     primitive.NewDateTimeFromTime(time.Now()),
+		}
+		if canonical, err := s.urls.Canonicalize(ctx, placeholder.URL); err == nil {
+			bm.CanonicalURL = canonical
+		}
+		for _, tagName := range placeholder.Tags {
+			if id, ok := tagIDs[strings.ToLower(tagName)]; ok {
+				bm.TagsID = append(bm.TagsID, id)
+			}
+		}
+		if id, ok := categoryIDs[strings.ToLower(placeholder.Category)]; ok {
+			bm.CategoryID = &id
+		}
+		bm.SearchGrams = bookmarkSearchGrams(&bm)
+		created, err := s.bookmarkRepo.Create(ctx, &bm)
+		if err != nil {
+			log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to create placeholder bookmark from template")
+			// The insert may have been applied even though it failed.
+			s.discardFromTemplate(col, append(result.Bookmarks, bm))
+			return nil, fmt.Errorf("failed to create placeholder bookmarks")
+		}
+		result.Bookmarks = append(result.Bookmarks, *created)
+	}
+
+	log.Info().Str("userID", userID.Hex()).Str("collectionID", col.ID.Hex()).Str("template", req.Template).Msg("Collection created from template")
+	return result, nil
+}
+
+// discardFromTemplate deletes the bookmarks and the collection that
+// CreateFromTemplate created before failing, even when the request was canceled,
+// so that a retry can use the collection name again. Tags and categories are
+// kept: the retry reuses them.
+func (s *collectionTemplateServiceImpl) discardFromTemplate(col *models.Collection, bookmarks []models.Bookmark) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, bm := range bookmarks {
+		if _, err := s.bookmarkRepo.DeleteOne(ctx, bson.M{"_id": bm.ID, "user_id": col.UserID}); err != nil {
+			log.Error().Err(err).Str("bookmarkID", bm.ID.Hex()).Msg("Failed to discard placeholder bookmark")
+		}
+	}
+	if _, err := s.collectionRepo.Delete(ctx, col.UserID, col.ID); err != nil {
+		log.Error().Err(err).Str("collectionID", col.ID.Hex()).Msg("Failed to discard collection created from template")
+	}
+}
+
+// templateTagNames returns the template tags plus any tag only referenced by a bookmark.
+func templateTagNames(template *models.CollectionTemplate) []string {
+	names := append([]string{}, template.Tags...)
+	for _, bm := range template.Bookmarks {
+		names = append(names, bm.Tags...)
+	}
+	return names
+}
+
+// ensureTags returns the IDs of the named tags keyed by lowercase name, reusing the
+// user's existing tags and creating the missing ones.
+func (s *collectionTemplateServiceImpl) ensureTags(ctx context.Context, userID primitive.ObjectID, names []string, result *models.FromTemplateResult) (map[string]primitive.ObjectID, error) {
+	existing, err := s.tagRepo.FindByUser(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to load tags for template")
+		return nil, fmt.Errorf("failed to create starter tags")
+	}
+	byName := make(map[string]models.Tag, len(existing))
+	for _, tag := range existing {
+		byName[strings.ToLower(tag.Name)] = tag
+	}
+
+	ids := make(map[string]primitive.ObjectID)
+	for _, name := range names {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if key == "" {
+			continue
+		}
+		if _, done := ids[key]; done {
+			continue
+		}
+		tag, ok := byName[key]
+		if !ok {
+			tag = models.Tag{ID: primitive.NewObjectID(), Name: strings.TrimSpace(name), UserID: userID, CreatedAt: primitive.NewDateTimeFromTime(time.Now())}
+			if _, err := s.tagRepo.Create(ctx, &tag); err != nil {
+				log.Error().Err(err).Str("userID", userID.Hex()).Str("tagName", name).Msg("Failed to create starter tag")
+				return nil, fmt.Errorf("failed to create starter tags")
+			}
+		}
+		ids[key] = tag.ID
+		result.Tags = append(result.Tags, tag)
+	}
+	return ids, nil
+}
+
+// ensureCategories is the category counterpart of ensureTags.
+func (s *collectionTemplateServiceImpl) ensureCategories(ctx context.Context, userID primitive.ObjectID, categories []models.TemplateCategory, result *models.FromTemplateResult) (map[string]primitive.ObjectID, error) {
+	existing, err := s.categoryRepo.FindByUser(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to load categories for template")
+		return nil, fmt.Errorf("failed to create starter categories")
+	}
+	byName := make(map[string]models.Category, len(existing))
+	for _, category := range existing {
+		byName[strings.ToLower(category.Name)] = category
+	}
+
+	ids := make(map[string]primitive.ObjectID)
+	for _, tc := range categories {
+		key := strings.ToLower(strings.TrimSpace(tc.Name))
+		if key == "" {
+			continue
+		}
+		if _, done := ids[key]; done {
+			continue
+		}
+		category, ok := byName[key]
+		if !ok {
+			emoji, err := categoryEmoji(tc.Name, tc.Emoji)
+			if err != nil {
+				// Templates saved before emojis were validated may hold free text.
+				emoji = fallbackCategoryEmoji(tc.Name)
+			}
+			category = models.Category{ID: primitive.NewObjectID(), UserID: userID, Name: strings.TrimSpace(tc.Name), Emoji: emoji}
+			err = saveWithSlug(ctx, s.categoryRepo, userID, category.ID, category.Name, "category", func(slug string) error {
+				category.Slug = slug
+				_, err := s.categoryRepo.Create(ctx, &category)
+				return err
+			})
+			if err != nil {
+				log.Error().Err(err).Str("userID", userID.Hex()).Str("categoryName", tc.Name).Msg("Failed to create starter category")
+				return nil, fmt.Errorf("failed to create starter categories")
+			}
+		}
+		ids[key] = category.ID
+		result.Categories = append(result.Categories, category)
+	}
+	return ids, nil
+}
+
+func (s *collectionTemplateServiceImpl) SaveAsTemplate(ctx context.Context, userID, collectionID primitive.ObjectID, req models.SaveAsTemplateRequest) (*models.CollectionTemplate, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("collectionID", collectionID.Hex()).Msg("Attempting to save collection as template")
+	col, err := s.collectionRepo.FindByID(ctx, userID, collectionID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("collection not found")
+		}
+		log.Error().Err(err).Str("userID", userID.Hex()).Str("collectionID", collectionID.Hex()).Msg("Error finding collection to save as template")
+		return nil, fmt.Errorf("failed to retrieve collection")
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = col.Name
+	}
+
+	bookmarks, err := s.bookmarkRepo.Find(ctx, bson.M{"user_id": userID, "collectionsid": collectionID}, maxTemplateBookmarks, 1)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Error finding collection bookmarks for template")
+		return nil, fmt.Errorf("failed to retrieve collection bookmarks")
+	}
+	tags, err := s.tagRepo.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve tags")
+	}
+	categories, err := s.categoryRepo.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve categories")
+	}
+	tagNames := make(map[primitive.ObjectID]string, len(tags))
+	for _, tag := range tags {
+		tagNames[tag.ID] = tag.Name
+	}
+	categoriesByID := make(map[primitive.ObjectID]models.Category, len(categories))
+	for _, category := range categories {
+		categoriesByID[category.ID] = category
+	}
+
+	now := time.Now()
+	template := &models.CollectionTemplate{
+		ID:          primitive.NewObjectID(),
+		UserID:      &userID,
+		Name:        name,
+		Description: req.Description,
+		CreatedAt:   &now,
+	}
+	seenTags := make(map[string]bool)
+	seenCategories := make(map[primitive.ObjectID]bool)
+	for _, bm := range bookmarks {
+		placeholder := models.TemplateBookmark{URL: bm.URL, Title: bm.Title, Summary: bm.Summary}
+		for _, tagID := range bm.TagsID {
+			tagName, ok := tagNames[tagID]
+			if !ok {
+				continue
+			}
+			placeholder.Tags = append(placeholder.Tags, tagName)
+			if !seenTags[tagName] {
+				seenTags[tagName] = true
+				template.Tags = append(template.Tags, tagName)
+			}
+		}
+		if bm.CategoryID != nil {
+			if category, ok := categoriesByID[*bm.CategoryID]; ok {
+				placeholder.Category = category.Name
+				if !seenCategories[category.ID] {
+					seenCategories[category.ID] = true
+					template.Categories = append(template.Categories, models.TemplateCategory{Name: category.Name, Emoji: category.Emoji})
+				}
+			}
+		}
+		template.Bookmarks = append(template.Bookmarks, placeholder)
+	}
+
+	if _, err := s.templateRepo.Create(ctx, template); err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to save collection template")
+		return nil, fmt.Errorf("failed to save collection template")
+	}
+	log.Info().Str("userID", userID.Hex()).Str("templateID", template.ID.Hex()).Msg("Collection saved as template")
+	return template, nil
+}
+
+func (s *collectionTemplateServiceImpl) DeleteTemplate(ctx context.Context, userID, templateID primitive.ObjectID) error {
+	log.Debug().Str("userID", userID.Hex()).Str("templateID", templateID.Hex()).Msg("Attempting to delete collection template")
+	result, err := s.templateRepo.Delete(ctx, userID, templateID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Str("templateID", templateID.Hex()).Msg("Error deleting collection template")
+		return fmt.Errorf("failed to delete collection template")
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("template not found")
+	}
+	log.Info().Str("userID", userID.Hex()).Str("templateID", templateID.Hex()).Msg("Collection template deleted successfully")
+	return nil
+}
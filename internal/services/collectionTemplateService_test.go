@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+)
+
+// namedCollections is a CollectionRepository keeping collection names unique.
+type namedCollections struct {
+	repositories.CollectionRepository
+	cols []*models.Collection
+}
+
+func (f *namedCollections) CountByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return int64(len(f.cols)), nil
+}
+
+func (f *namedCollections) SlugInUse(ctx context.Context, userID primitive.ObjectID, slug string, excludeID primitive.ObjectID) (bool, error) {
+	for _, c := range f.cols {
+		if c.Slug == slug && c.ID != excludeID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *namedCollections) Create(ctx context.Context, col *models.Collection) (*models.Collection, error) {
+	for _, c := range f.cols {
+		if c.Name == col.Name {
+			return nil, slugConflict("user_id_1_name_1")
+		}
+	}
+	f.cols = append(f.cols, col)
+	return col, nil
+}
+
+func (f *namedCollections) Delete(ctx context.Context, userID, collectionID primitive.ObjectID) (*mongo.DeleteResult, error) {
+	for i, c := range f.cols {
+		if c.ID == collectionID {
+			f.cols = append(f.cols[:i], f.cols[i+1:]...)
+			return &mongo.DeleteResult{DeletedCount: 1}, nil
+		}
+	}
+	return &mongo.DeleteResult{}, nil
+}
+
+type templateTags struct {
+	repositories.TagRepository
+	tags []models.Tag
+}
+
+func (f *templateTags) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Tag, error) {
+	return f.tags, nil
+}
+
+func (f *templateTags) Create(ctx context.Context, tag *models.Tag) (*models.Tag, error) {
+	f.tags = append(f.tags, *tag)
+	return tag, nil
+}
+
+type templateCategories struct {
+	repositories.CategoryRepository
+	categories []models.Category
+}
+
+func (f *templateCategories) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Category, error) {
+	return f.categories, nil
+}
+
+func (f *templateCategories) SlugInUse(ctx context.Context, userID primitive.ObjectID, slug string, excludeID primitive.ObjectID) (bool, error) {
+	for _, c := range f.categories {
+		if c.Slug == slug && c.ID != excludeID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *templateCategories) Create(ctx context.Context, category *models.Category) (*models.Category, error) {
+	f.categories = append(f.categories, *category)
+	return category, nil
+}
+
+// templateBookmarks is a BookmarkRepository whose Create fails once failAt
+// bookmarks are stored, when failAt is positive.
+type templateBookmarks struct {
+	repositories.BookmarkRepository
+	bookmarks []models.Bookmark
+	failAt    int
+}
+
+func (f *templateBookmarks) Create(ctx context.Context, bm *models.Bookmark) (*models.Bookmark, error) {
+	if f.failAt > 0 && len(f.bookmarks) == f.failAt {
+		return nil, errors.New("connection reset")
+	}
+	f.bookmarks = append(f.bookmarks, *bm)
+	return bm, nil
+}
+
+func (f *templateBookmarks) DeleteOne(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error) {
+	for i, bm := range f.bookmarks {
+		if bm.ID == filter["_id"] && bm.UserID == filter["user_id"] {
+			f.bookmarks = append(f.bookmarks[:i], f.bookmarks[i+1:]...)
+			return &mongo.DeleteResult{DeletedCount: 1}, nil
+		}
+	}
+	return &mongo.DeleteResult{}, nil
+}
+
+type unchangedURLs struct{ URLService }
+
+func (unchangedURLs) Canonicalize(ctx context.Context, rawURL string) (string, error) {
+	return rawURL, nil
+}
+
+func TestCreateFromTemplateCanBeRetriedAfterFailing(t *testing.T) {
+	cols := &namedCollections{}
+	tags := &templateTags{}
+	categories := &templateCategories{}
+	bookmarks := &templateBookmarks{failAt: 2}
+	s := NewCollectionTemplateService(nil, cols, tags, categories, bookmarks, unchangedURLs{})
+	userID := primitive.NewObjectID()
+	req := models.CreateFromTemplateRequest{Template: "job-hunt"}
+
+	if _, err := s.CreateFromTemplate(context.Background(), userID, req); err == nil || err.Error() != "failed to create placeholder bookmarks" {
+		t.Fatalf("err = %v, want the bookmarks to fail", err)
+	}
+	if len(cols.cols) != 0 || len(bookmarks.bookmarks) != 0 {
+		t.Fatalf("left %d collections and %d bookmarks behind", len(cols.cols), len(bookmarks.bookmarks))
+	}
+	createdTags, createdCategories := len(tags.tags), len(categories.categories)
+
+	bookmarks.failAt = 0
+	result, err := s.CreateFromTemplate(context.Background(), userID, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cols.cols) != 1 || len(bookmarks.bookmarks) != 3 || len(result.Bookmarks) != 3 {
+		t.Errorf("retry left %d collections and %d bookmarks, want 1 and 3", len(cols.cols), len(bookmarks.bookmarks))
+	}
+	if len(tags.tags) != createdTags || len(categories.categories) != createdCategories {
+		t.Errorf("retry created %d tags and %d categories again", len(tags.tags)-createdTags, len(categories.categories)-createdCategories)
+	}
+	if result.Collection.Slug != "job-hunt" {
+		t.Errorf("collection slug = %q, want job-hunt", result.Collection.Slug)
+	}
+	for _, category := range categories.categories {
+		if category.Slug == "" {
+			t.Errorf("starter category %q has no slug", category.Name)
+		}
+	}
+}
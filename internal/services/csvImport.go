@@ -0,0 +1,339 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+const (
+	// maxCSVImportErrors bounds the row errors reported by a CSV import.
+	maxCSVImportErrors = 100
+	// maxCSVImportRows bounds the records read by one CSV import, like the items
+	// of an import job.
+	maxCSVImportRows = maxImportJobItems
+)
+
+// csvRowError rejects one record of a CSV import without aborting the import.
+type csvRowError struct {
+	err error
+}
+
+func (e *csvRowError) Error() string { return e.err.Error() }
+func (e *csvRowError) Unwrap() error { return e.err }
+
+func rejectRow(err error) error {
+	return &csvRowError{err: err}
+}
+
+// csvTimeLayouts are the created_at formats accepted besides Unix seconds.
+var csvTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+// csvColumns holds the index of each mapped column, or -1.
+type csvColumns struct {
+	url, title, tags, createdAt, collection int
+}
+
+// resolveCSVColumns finds the mapped columns in the header. Header names are
+// matched case-insensitively.
+func resolveCSVColumns(header []string, mapping models.CSVColumnMapping) (csvColumns, error) {
+	if strings.TrimSpace(mapping.URL) == "" {
+		return csvColumns{}, fmt.Errorf("invalid mapping: the url column is required")
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if _, dup := index[key]; !dup {
+			index[key] = i
+		}
+	}
+	find := func(name string) (int, error) {
+		if strings.TrimSpace(name) == "" {
+			return -1, nil
+		}
+		i, ok := index[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return -1, fmt.Errorf("invalid mapping: column %q not found in the header", name)
+		}
+		return i, nil
+	}
+
+	var cols csvColumns
+	var err error
+	for _, c := range []struct {
+		name string
+		dst  *int
+	}{
+		{mapping.URL, &cols.url},
+		{mapping.Title, &cols.title},
+		{mapping.Tags, &cols.tags},
+		{mapping.CreatedAt, &cols.createdAt},
+		{mapping.Collection, &cols.collection},
+	} {
+		if *c.dst, err = find(c.name); err != nil {
+			return csvColumns{}, err
+		}
+	}
+	return cols, nil
+}
+
+// parseCSVTime reads a created_at value: RFC 3339, a date with or without a time
+// (taken as UTC), or Unix seconds.
+func parseCSVTime(value string) (time.Time, error) {
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC(), nil
+	}
+	for _, layout := range csvTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid created_at %q: use RFC 3339, YYYY-MM-DD or Unix seconds", value)
+}
+
+// splitCSVTags splits a tags cell into distinct, trimmed tag names.
+func splitCSVTags(cell, separator string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, name := range strings.Split(cell, separator) {
+		name = strings.TrimSpace(name)
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// csvImporter holds the state of one CSV import. Collections are keyed by
+// lowercase name.
+type csvImporter struct {
+	*bookmarkImporter
+	s               *exportServiceImpl
+	cols            csvColumns
+	separator       string
+	collections     map[string]primitive.ObjectID
+	usedCollections map[string]bool
+	result          *models.CSVImportResult
+}
+
+// ImportCSV reads bookmarks from a CSV file with a header row, one record at a
+// time, so that large files are never held in memory, and stops after
+// maxCSVImportRows records. Records that cannot be imported are reported and
+// skipped. Tags and collections are matched by name,
+// case-insensitively, and created when missing; bookmarks the user already has
+// are left unchanged, so the import can safely be run again.
+func (s *exportServiceImpl) ImportCSV(ctx context.Context, userID primitive.ObjectID, r io.Reader, mapping models.CSVColumnMapping) (*models.CSVImportResult, error) {
+	log.Debug().Str("userID", userID.Hex()).Interface("mapping", mapping).Msg("Attempting to import CSV")
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("invalid csv: the file is empty")
+	}
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, fmt.Errorf("invalid csv: %v", err)
+		}
+		return nil, fmt.Errorf("failed to read csv: %w", err)
+	}
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	cols, err := resolveCSVColumns(header, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.CSVImportResult{Errors: []models.CSVRowError{}}
+	imp := &csvImporter{
+		bookmarkImporter: newBookmarkImporter(s.bookmarkRepo, s.tagRepo, s.urls, userID, "csv", &result.Tags, &result.Bookmarks),
+		s:                s,
+		cols:             cols,
+		separator:        mapping.TagSeparator,
+		usedCollections:  map[string]bool{},
+		result:           result,
+	}
+	if imp.separator == "" {
+		imp.separator = ","
+	}
+	if err := imp.loadNames(ctx); err != nil {
+		return nil, err
+	}
+
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if imp.result.Rows == maxCSVImportRows {
+			imp.result.Truncated = true
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				log.Error().Err(err).Str("userID", userID.Hex()).Int("row", row).Msg("Failed to read CSV import")
+				return imp.result, fmt.Errorf("failed to read csv: %w", err)
+			}
+			imp.result.Rows++
+			imp.fail(row, parseErr.Err.Error())
+			continue
+		}
+		imp.result.Rows++
+		if err := imp.importRow(ctx, record); err != nil {
+			var rowErr *csvRowError
+			if !errors.As(err, &rowErr) {
+				return imp.result, err
+			}
+			imp.fail(row, rowErr.Error())
+		}
+	}
+
+	log.Info().Str("userID", userID.Hex()).Int("rows", imp.result.Rows).Int("failed", imp.result.Failed).Msg("CSV imported")
+	return imp.result, nil
+}
+
+func (imp *csvImporter) loadNames(ctx context.Context) error {
+	if err := imp.loadTags(ctx); err != nil {
+		return err
+	}
+	collections, err := imp.s.collectionRepo.FindByUser(ctx, imp.userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", imp.userID.Hex()).Msg("Failed to load collections for CSV import")
+		return fmt.Errorf("failed to fetch collections")
+	}
+	imp.collections = make(map[string]primitive.ObjectID, len(collections))
+	for _, c := range collections {
+		imp.collections[strings.ToLower(c.Name)] = c.ID
+	}
+	return nil
+}
+
+func (imp *csvImporter) fail(row int, msg string) {
+	imp.result.Failed++
+	if len(imp.result.Errors) < maxCSVImportErrors {
+		imp.result.Errors = append(imp.result.Errors, models.CSVRowError{Row: row, Error: msg})
+	} else {
+		imp.result.ErrorsTruncated = true
+	}
+}
+
+// csvCell returns the trimmed value of column i, or "" when it is not mapped or the
+// record is short.
+func csvCell(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// importRow creates the bookmark of one record. A *csvRowError rejects the
+// record; any other error aborts the import.
+func (imp *csvImporter) importRow(ctx context.Context, record []string) error {
+	rawURL := csvCell(record, imp.cols.url)
+	if rawURL == "" {
+		return rejectRow(fmt.Errorf("invalid url: empty"))
+	}
+	normalized, err := utils.NormalizeURL(rawURL)
+	if err != nil {
+		return rejectRow(err)
+	}
+	createdAt := time.Now()
+	if v := csvCell(record, imp.cols.createdAt); v != "" {
+		if createdAt, err = parseCSVTime(v); err != nil {
+			return rejectRow(err)
+		}
+	}
+	tagNames := splitCSVTags(csvCell(record, imp.cols.tags), imp.separator)
+	if err := checkTagLimit(len(tagNames)); err != nil {
+		return rejectRow(err)
+	}
+
+	canonical, exists, err := imp.find(ctx, normalized)
+	if err != nil || exists {
+		return err
+	}
+
+	bm := &models.Bookmark{
+		ID:           primitive.NewObjectID(),
+		UserID:       imp.userID,
+		URL:          normalized,
+		OriginalURL:  originalURL(rawURL, normalized),
+		CanonicalURL: canonical,
+		Title:        csvCell(record, imp.cols.title),
+		CreatedAt:    primitive.NewDateTimeFromTime(createdAt),
+	}
+	if bm.Title == "" {
+		bm.Title = normalized
+	}
+	if name := csvCell(record, imp.cols.collection); name != "" {
+		id, err := imp.collection(ctx, name)
+		if err != nil {
+			return err
+		}
+		bm.CollectionsID = []primitive.ObjectID{id}
+	}
+	for _, name := range tagNames {
+		id, err := imp.tag(ctx, name)
+		if err != nil {
+			return err
+		}
+		bm.TagsID = append(bm.TagsID, id)
+	}
+	return imp.create(ctx, bm)
+}
+
+// collection returns the ID of the named collection, creating it when the user has
+// none. Reaching the collection limit only rejects the record.
+func (imp *csvImporter) collection(ctx context.Context, name string) (primitive.ObjectID, error) {
+	key := strings.ToLower(name)
+	if id, ok := imp.collections[key]; ok {
+		if !imp.usedCollections[key] {
+			imp.usedCollections[key] = true
+			imp.result.Collections.Existing++
+		}
+		return id, nil
+	}
+	if err := checkCollectionLimit(ctx, imp.s.collectionRepo, imp.userID); err != nil {
+		if errors.Is(err, utils.ErrLimitExceeded) {
+			return primitive.NilObjectID, rejectRow(err)
+		}
+		return primitive.NilObjectID, err
+	}
+	col := models.Collection{ID: primitive.NewObjectID(), UserID: imp.userID, Name: name}
+	err := saveWithSlug(ctx, imp.s.collectionRepo, imp.userID, col.ID, name, "collection", func(slug string) error {
+		col.Slug = slug
+		_, err := imp.s.collectionRepo.Create(ctx, &col)
+		return err
+	})
+	if err != nil {
+		log.Error().Err(err).Str("userID", imp.userID.Hex()).Str("collection", name).Msg("Failed to create collection during CSV import")
+		return primitive.NilObjectID, fmt.Errorf("failed to import collection %q", name)
+	}
+	if err := keepWithinCollectionLimit(ctx, imp.s.collectionRepo, &col); err != nil {
+		if errors.Is(err, utils.ErrLimitExceeded) {
+			return primitive.NilObjectID, rejectRow(err)
+		}
+		return primitive.NilObjectID, err
+	}
+	imp.collections[key] = col.ID
+	imp.usedCollections[key] = true
+	imp.result.Collections.Created++
+	return col.ID, nil
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+)
+
+// fakeTagRepo is a TagRepository over a list of tags.
+type fakeTagRepo struct {
+	repositories.TagRepository
+	tags []models.Tag
+}
+
+func (f *fakeTagRepo) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Tag, error) {
+	return f.tags, nil
+}
+
+func (f *fakeTagRepo) Create(ctx context.Context, tag *models.Tag) (*models.Tag, error) {
+	f.tags = append(f.tags, *tag)
+	return tag, nil
+}
+
+func TestResolveCSVColumns(t *testing.T) {
+	header := []string{"Title", " URL ", "time_added", "tags"}
+	cols, err := resolveCSVColumns(header, models.CSVColumnMapping{URL: "url", Title: "title", CreatedAt: "Time_Added"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (csvColumns{url: 1, title: 0, tags: -1, createdAt: 2, collection: -1}); cols != want {
+		t.Errorf("resolveCSVColumns = %+v, want %+v", cols, want)
+	}
+
+	if _, err := resolveCSVColumns(header, models.CSVColumnMapping{Title: "title"}); err == nil {
+		t.Error("mapping without url was accepted")
+	}
+	if _, err := resolveCSVColumns(header, models.CSVColumnMapping{URL: "url", Collection: "folder"}); err == nil {
+		t.Error("mapping to a missing column was accepted")
+	}
+}
+
+func TestParseCSVTime(t *testing.T) {
+	cases := map[string]time.Time{
+		"1700000000":           time.Unix(1700000000, 0).UTC(),
+		"2024-03-01":           time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		"2024-03-01 10:30:00":  time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC),
+		"2024-03-01T10:30:00Z": time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC),
+	}
+	for in, want := range cases {
+		got, err := parseCSVTime(in)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseCSVTime(%q) = %v, %v, want %v", in, got, err, want)
+		}
+	}
+	if _, err := parseCSVTime("March 1st"); err == nil {
+		t.Error("parseCSVTime accepted free text")
+	}
+}
+
+func TestSplitCSVTags(t *testing.T) {
+	got := splitCSVTags(" go| Reading ||go|reading |news", "|")
+	if want := []string{"go", "Reading", "news"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("splitCSVTags = %v, want %v", got, want)
+	}
+	if got := splitCSVTags("", ","); got != nil {
+		t.Errorf("splitCSVTags(empty) = %v, want nil", got)
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	userID := primitive.NewObjectID()
+	bookmarks := &fakeBookmarks{bookmarks: []*models.Bookmark{{UserID: userID, URL: "https://go.dev/"}}}
+	tags := &fakeTagRepo{tags: []models.Tag{{ID: primitive.NewObjectID(), Name: "Go"}}}
+	s := &exportServiceImpl{bookmarkRepo: bookmarks, tagRepo: tags, collectionRepo: &fakeCollections{}, urls: offlineURLs{t}}
+
+	file := "url,title,tags,added\n" +
+		"https://go.dev/,Go,go,2026-01-02\n" +
+		"https://pkg.go.dev/?utm_source=x,Packages,go|docs,\n" +
+		",No URL,,\n" +
+		"https://example.com/,Bad date,,yesterday\n"
+	mapping := models.CSVColumnMapping{URL: "url", Title: "title", Tags: "tags", CreatedAt: "added", TagSeparator: "|"}
+	result, err := s.ImportCSV(context.Background(), userID, strings.NewReader(file), mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Rows != 4 || result.Bookmarks.Created != 1 || result.Bookmarks.Existing != 1 || result.Failed != 2 || result.Truncated {
+		t.Errorf("result = %+v", result)
+	}
+	if want := []models.CSVRowError{{Row: 4, Error: "invalid url: empty"}, {Row: 5, Error: `invalid created_at "yesterday": use RFC 3339, YYYY-MM-DD or Unix seconds`}}; !reflect.DeepEqual(result.Errors, want) {
+		t.Errorf("errors = %+v, want %+v", result.Errors, want)
+	}
+	if result.Tags.Existing != 1 || result.Tags.Created != 1 {
+		t.Errorf("tags = %+v", result.Tags)
+	}
+	created := bookmarks.bookmarks[1]
+	if created.CanonicalURL != "https://pkg.go.dev/" || created.Title != "Packages" || len(created.TagsID) != 2 {
+		t.Errorf("created bookmark = %+v", created)
+	}
+}
+
+func TestImportCSVStopsAtRowLimit(t *testing.T) {
+	userID := primitive.NewObjectID()
+	bookmarks := &fakeBookmarks{}
+	s := &exportServiceImpl{bookmarkRepo: bookmarks, tagRepo: &fakeTagRepo{}, collectionRepo: &fakeCollections{}, urls: offlineURLs{t}}
+
+	var file strings.Builder
+	file.WriteString("url\n")
+	for i := 0; i <= maxCSVImportRows; i++ {
+		file.WriteString("https://example.com/\n")
+	}
+	result, err := s.ImportCSV(context.Background(), userID, strings.NewReader(file.String()), models.CSVColumnMapping{URL: "url"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Rows != maxCSVImportRows || !result.Truncated || result.Bookmarks.Created != 1 || result.Bookmarks.Existing != maxCSVImportRows-1 {
+		t.Errorf("result = %+v", result)
+	}
+}
@@ -1,32 +1,64 @@
 package services
 
 import (
+	"io"
 	"os"
 
 	"gopkg.in/gomail.v2"
 )
 
-type EmailService interface {
+// Mailer sends HTML email. Services take one instead of dialing SMTP themselves, so
+// that tests can record messages and deployments can swap the transport.
+type Mailer interface {
 	SendEmail(to, subject, msg string) error
 }
 
-type emailService struct {
+// InlineImage is an image embedded in an HTML email, which refers to it as
+// "cid:" + Name.
+type InlineImage struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// InlineImageMailer is implemented by mailers that can embed images in a message.
+// Callers send without the images through SendEmail when a mailer does not.
+type InlineImageMailer interface {
+	SendEmailWithImages(to, subject, msg string, images []InlineImage) error
+}
+
+type smtpMailer struct {
 	from string
 }
 
-func NewEmailService() EmailService {
-	return &emailService{
+// NewSMTPMailer sends through Gmail's SMTP server as SMTP_USERNAME.
+func NewSMTPMailer() Mailer {
+	return &smtpMailer{
 		from: os.Getenv("SMTP_USERNAME"),
 	}
 }
 
-func (e *emailService) SendEmail(to, subject, msg string) error {
+func (e *smtpMailer) SendEmail(to, subject, msg string) error {
+	return e.SendEmailWithImages(to, subject, msg, nil)
+}
+
+func (e *smtpMailer) SendEmailWithImages(to, subject, msg string, images []InlineImage) error {
 	m := gomail.NewMessage()
 
 	m.SetHeader("From", e.from)
 	m.SetHeader("To", to)
 	m.SetHeader("Subject", subject)
 	m.SetBody("text/html", msg)
+	for _, img := range images {
+		data := img.Data
+		m.Embed(img.Name,
+			gomail.SetHeader(map[string][]string{"Content-Type": {img.ContentType}}),
+			gomail.SetCopyFunc(func(w io.Writer) error {
+				_, err := w.Write(data)
+				return err
+			}),
+		)
+	}
 
 	d := gomail.NewDialer("smtp.gmail.com", 587, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
 
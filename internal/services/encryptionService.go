@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/sync/singleflight"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+const encryptedValuePrefix = "v1:"
+
+// blindIndexContext separates the key of a user's blind index from their data key.
+const blindIndexContext = "markly blind index v1"
+
+var ErrEncryptionNotConfigured = errors.New("private notes are not configured on this server")
+
+// EncryptionService envelope-encrypts user data: every user gets a random data key,
+// and only the data key wrapped by the master key (NOTES_MASTER_KEY) is persisted.
+type EncryptionService interface {
+	Encrypt(ctx context.Context, userID primitive.ObjectID, plaintext string) (string, error)
+	Decrypt(ctx context.Context, userID primitive.ObjectID, ciphertext string) (string, error)
+	// BlindIndex returns a keyed hash of each value, with a key derived from the
+	// user's data key: equal values of a user hash alike, but the values cannot be
+	// read back from the hashes.
+	BlindIndex(ctx context.Context, userID primitive.ObjectID, values []string) ([]string, error)
+	Enabled() bool
+}
+
+type encryptionServiceImpl struct {
+	dataKeyRepo repositories.DataKeyRepository
+	masterKey   []byte
+
+	mu    sync.Mutex
+	cache map[primitive.ObjectID][]byte
+	// loads lets one caller per user load or create the data key while the
+	// others wait for it, without holding mu across database calls.
+	loads singleflight.Group
+}
+
+func NewEncryptionService(dataKeyRepo repositories.DataKeyRepository) EncryptionService {
+	s := &encryptionServiceImpl{
+		dataKeyRepo: dataKeyRepo,
+		cache:       make(map[primitive.ObjectID][]byte),
+	}
+
+	encoded := os.Getenv("NOTES_MASTER_KEY")
+	if encoded == "" {
+		log.Warn().Msg("NOTES_MASTER_KEY not set, private bookmark notes are disabled")
+		return s
+	}
+	masterKey, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(masterKey) != 32 {
+		log.Error().Msg("NOTES_MASTER_KEY must be 32 bytes encoded as base64, private bookmark notes are disabled")
+		return s
+	}
+	s.masterKey = masterKey
+	return s
+}
+
+func (s *encryptionServiceImpl) Enabled() bool {
+	return s.masterKey != nil
+}
+
+func (s *encryptionServiceImpl) Encrypt(ctx context.Context, userID primitive.ObjectID, plaintext string) (string, error) {
+	if !s.Enabled() {
+		return "", ErrEncryptionNotConfigured
+	}
+	dataKey, err := s.dataKeyFor(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := utils.SealAESGCM(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt value: %w", err)
+	}
+	return encryptedValuePrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (s *encryptionServiceImpl) Decrypt(ctx context.Context, userID primitive.ObjectID, ciphertext string) (string, error) {
+	if !s.Enabled() {
+		return "", ErrEncryptionNotConfigured
+	}
+	if !strings.HasPrefix(ciphertext, encryptedValuePrefix) {
+		return "", errors.New("unsupported ciphertext format")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, encryptedValuePrefix))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode ciphertext: %w", err)
+	}
+	dataKey, err := s.dataKeyFor(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := utils.OpenAESGCM(dataKey, sealed)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func (s *encryptionServiceImpl) BlindIndex(ctx context.Context, userID primitive.ObjectID, values []string) ([]string, error) {
+	if !s.Enabled() {
+		return nil, ErrEncryptionNotConfigured
+	}
+	dataKey, err := s.dataKeyFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	derive := hmac.New(sha256.New, dataKey)
+	derive.Write([]byte(blindIndexContext))
+	key := derive.Sum(nil)
+
+	hashes := make([]string, len(values))
+	for i, v := range values {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(v))
+		// 12 bytes keep collisions between a user's values out of reach.
+		hashes[i] = base64.RawStdEncoding.EncodeToString(mac.Sum(nil)[:12])
+	}
+	return hashes, nil
+}
+
+// dataKeyFor returns the user's unwrapped data key, creating one on first use.
+func (s *encryptionServiceImpl) dataKeyFor(ctx context.Context, userID primitive.ObjectID) ([]byte, error) {
+	if key, ok := s.cachedDataKey(userID); ok {
+		return key, nil
+	}
+	key, err, _ := s.loads.Do(userID.Hex(), func() (interface{}, error) {
+		// A load that finished after the cache was checked has stored the key.
+		if key, ok := s.cachedDataKey(userID); ok {
+			return key, nil
+		}
+		key, err := s.loadDataKey(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		s.cache[userID] = key
+		s.mu.Unlock()
+		return key, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return key.([]byte), nil
+}
+
+func (s *encryptionServiceImpl) cachedDataKey(userID primitive.ObjectID) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.cache[userID]
+	return key, ok
+}
+
+// loadDataKey unwraps the stored data key of the user or creates one. A key
+// created meanwhile by another instance is used instead of the new one.
+func (s *encryptionServiceImpl) loadDataKey(ctx context.Context, userID primitive.ObjectID) ([]byte, error) {
+	key, err := s.findDataKey(ctx, userID)
+	if err != mongo.ErrNoDocuments {
+		return key, err
+	}
+
+	key = make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	wrapped, err := utils.SealAESGCM(s.masterKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	if _, err := s.dataKeyRepo.Create(ctx, &models.DataKey{
+		ID:         primitive.NewObjectID(),
+		UserID:     userID,
+		WrappedKey: wrapped,
+		CreatedAt:  time.Now(),
+	}); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return s.findDataKey(ctx, userID)
+		}
+		return nil, err
+	}
+	log.Info().Str("userID", userID.Hex()).Msg("Created data key for user")
+	return key, nil
+}
+
+// findDataKey unwraps the stored data key of the user. It returns
+// mongo.ErrNoDocuments if the user has none.
+func (s *encryptionServiceImpl) findDataKey(ctx context.Context, userID primitive.ObjectID) ([]byte, error) {
+	stored, err := s.dataKeyRepo.FindByUser(ctx, userID)
+	if err == mongo.ErrNoDocuments {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load data key: %w", err)
+	}
+	key, err := utils.OpenAESGCM(s.masterKey, stored.WrappedKey)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to unwrap data key")
+		return nil, fmt.Errorf("failed to unwrap data key")
+	}
+	return key, nil
+}
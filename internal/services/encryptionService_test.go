@@ -0,0 +1,133 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+)
+
+// fakeDataKeys is a DataKeyRepository with a unique key per user. A FindByUser
+// call for the blocked user closes entered and waits until release is closed.
+type fakeDataKeys struct {
+	repositories.DataKeyRepository
+	mu      sync.Mutex
+	keys    map[primitive.ObjectID]*models.DataKey
+	blocked primitive.ObjectID
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (f *fakeDataKeys) FindByUser(ctx context.Context, userID primitive.ObjectID) (*models.DataKey, error) {
+	if userID == f.blocked {
+		close(f.entered)
+		<-f.release
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if key, ok := f.keys[userID]; ok {
+		return key, nil
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (f *fakeDataKeys) Create(ctx context.Context, key *models.DataKey) (*models.DataKey, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.keys[key.UserID]; ok {
+		return nil, slugConflict("user_unique")
+	}
+	f.keys[key.UserID] = key
+	return key, nil
+}
+
+func newTestEncryption(keys repositories.DataKeyRepository) *encryptionServiceImpl {
+	return &encryptionServiceImpl{dataKeyRepo: keys, masterKey: bytes.Repeat([]byte{7}, 32), cache: map[primitive.ObjectID][]byte{}}
+}
+
+func TestDataKeyForLoadsOncePerUser(t *testing.T) {
+	keys := &fakeDataKeys{keys: map[primitive.ObjectID]*models.DataKey{}}
+	s := newTestEncryption(keys)
+	userID := primitive.NewObjectID()
+
+	var wg sync.WaitGroup
+	got := make([][]byte, 8)
+	for i := range got {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key, err := s.dataKeyFor(context.Background(), userID)
+			if err != nil {
+				t.Error(err)
+			}
+			got[i] = key
+		}(i)
+	}
+	wg.Wait()
+	for _, key := range got {
+		if !bytes.Equal(key, got[0]) || len(key) != 32 {
+			t.Fatalf("callers got different data keys")
+		}
+	}
+	if len(keys.keys) != 1 {
+		t.Errorf("created %d data keys, want 1", len(keys.keys))
+	}
+}
+
+func TestDataKeyForUsesKeyCreatedElsewhere(t *testing.T) {
+	keys := &fakeDataKeys{keys: map[primitive.ObjectID]*models.DataKey{}}
+	userID := primitive.NewObjectID()
+	// Another instance creates the user's key.
+	key, err := newTestEncryption(keys).dataKeyFor(context.Background(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// This one misses it at first and runs into the unique index.
+	s := newTestEncryption(&fakeDataKeysMissingOnce{fakeDataKeys: keys})
+	got, err := s.dataKeyFor(context.Background(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, key) {
+		t.Error("a second data key was used for the user")
+	}
+}
+
+// fakeDataKeysMissingOnce finds no key at the first FindByUser.
+type fakeDataKeysMissingOnce struct {
+	*fakeDataKeys
+	missed bool
+}
+
+func (f *fakeDataKeysMissingOnce) FindByUser(ctx context.Context, userID primitive.ObjectID) (*models.DataKey, error) {
+	if !f.missed {
+		f.missed = true
+		return nil, mongo.ErrNoDocuments
+	}
+	return f.fakeDataKeys.FindByUser(ctx, userID)
+}
+
+func TestDataKeyForDoesNotWaitForOtherUsers(t *testing.T) {
+	keys := &fakeDataKeys{keys: map[primitive.ObjectID]*models.DataKey{}, blocked: primitive.NewObjectID(), entered: make(chan struct{}), release: make(chan struct{})}
+	s := newTestEncryption(keys)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := s.dataKeyFor(context.Background(), keys.blocked); err != nil {
+			t.Error(err)
+		}
+	}()
+	// The blocked user's load is stuck in the database; another user's is not held up.
+	<-keys.entered
+	if _, err := s.dataKeyFor(context.Background(), primitive.NewObjectID()); err != nil {
+		t.Fatal(err)
+	}
+	close(keys.release)
+	<-done
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+)
+
+// ErasureService carries out legal erasure requests. Erasing a user deletes every
+// document they own, anonymizes the audit events about them and produces a signed
+// report. Backups cannot be edited, so restores skip the documents of erased users
+// instead.
+type ErasureService interface {
+	EraseUser(ctx context.Context, adminID, userID primitive.ObjectID, req models.EraseUserRequest, ip string) (*models.ErasureReport, error)
+	List(ctx context.Context, userID *primitive.ObjectID) ([]models.ErasureReport, error)
+	Get(ctx context.Context, id primitive.ObjectID) (*models.ErasureVerification, error)
+}
+
+type erasureServiceImpl struct {
+	erasureRepo  repositories.ErasureRepository
+	userRepo     repositories.UserRepository
+	auditService AuditService
+	// signingKey is ERASURE_SIGNING_KEY; erasures are refused without it.
+	signingKey []byte
+}
+
+func NewErasureService(erasureRepo repositories.ErasureRepository, userRepo repositories.UserRepository, auditService AuditService) ErasureService {
+	return &erasureServiceImpl{
+		erasureRepo:  erasureRepo,
+		userRepo:     userRepo,
+		auditService: auditService,
+		signingKey:   []byte(os.Getenv("ERASURE_SIGNING_KEY")),
+	}
+}
+
+func (s *erasureServiceImpl) EraseUser(ctx context.Context, adminID, userID primitive.ObjectID, req models.EraseUserRequest, ip string) (*models.ErasureReport, error) {
+	if len(s.signingKey) == 0 {
+		return nil, fmt.Errorf("erasure is not configured: ERASURE_SIGNING_KEY is not set")
+	}
+	if req.Confirm != userID.Hex() {
+		return nil, fmt.Errorf("invalid confirm: must repeat the ID of the user to erase")
+	}
+	reference := strings.TrimSpace(req.Reference)
+	if reference == "" {
+		return nil, fmt.Errorf("reference is required")
+	}
+	if userID == adminID {
+		return nil, fmt.Errorf("invalid user: admins cannot erase their own account")
+	}
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Times are stored with millisecond precision; truncating them keeps the
+	// signature valid when the report is read back.
+	report := &models.ErasureReport{
+		ID:          primitive.NewObjectID(),
+		UserID:      userID,
+		RequestedBy: adminID,
+		Reference:   reference,
+		Collections: map[string]int64{},
+		StartedAt:   time.Now().UTC().Truncate(time.Millisecond),
+	}
+	// The report is written first so that restores skip the user even if the
+	// purge fails part way.
+	if err := s.erasureRepo.Create(ctx, report); err != nil {
+		return nil, err
+	}
+	log.Warn().Str("userID", adminID.Hex()).Str("erasedUserID", userID.Hex()).Str("erasureID", report.ID.Hex()).Msg("Erasing user data")
+
+	// The email is only known while the user document exists, which the purge
+	// deletes last.
+	if user != nil {
+		removed, err := s.erasureRepo.PurgeSubscriptions(ctx, user.Email)
+		if err != nil {
+			log.Error().Err(err).Str("erasureID", report.ID.Hex()).Msg("Failed to purge newsletter subscriptions")
+			return nil, fmt.Errorf("failed to erase user data; the erasure can be retried")
+		}
+		report.SubscriptionsRemoved = removed
+	}
+
+	deleted, err := s.erasureRepo.PurgeUser(ctx, userID)
+	if deleted != nil {
+		report.Collections = deleted
+	}
+	if err != nil {
+		log.Error().Err(err).Str("erasureID", report.ID.Hex()).Msg("Failed to purge user data")
+		if updateErr := s.erasureRepo.Update(ctx, report.ID, bson.M{"collections": report.Collections, "subscriptions_removed": report.SubscriptionsRemoved}); updateErr != nil {
+			log.Error().Err(updateErr).Str("erasureID", report.ID.Hex()).Msg("Failed to record partial erasure")
+		}
+		return nil, fmt.Errorf("failed to erase user data; the erasure can be retried")
+	}
+
+	anonymized, err := s.auditService.Anonymize(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("erasureID", report.ID.Hex()).Msg("Failed to anonymize audit events")
+		return nil, fmt.Errorf("failed to anonymize audit events; the erasure can be retried")
+	}
+	report.AuditEventsAnonymized = anonymized
+
+	completed := time.Now().UTC().Truncate(time.Millisecond)
+	report.CompletedAt = &completed
+	report.Signature = s.sign(*report)
+	if err := s.erasureRepo.Update(ctx, report.ID, bson.M{
+		"collections":             report.Collections,
+		"audit_events_anonymized": report.AuditEventsAnonymized,
+		"subscriptions_removed":   report.SubscriptionsRemoved,
+		"completed_at":            completed,
+		"signature":               report.Signature,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to store erasure report")
+	}
+
+	if err := s.auditService.Record(ctx, models.AuditEvent{
+		Action:  models.AuditUserErased,
+		ActorID: adminID,
+		UserID:  userID,
+		IP:      ip,
+		Details: "erasure " + report.ID.Hex(),
+	}); err != nil {
+		log.Error().Err(err).Str("erasureID", report.ID.Hex()).Msg("Failed to audit user erasure")
+	}
+	log.Warn().Str("userID", adminID.Hex()).Str("erasedUserID", userID.Hex()).Str("erasureID", report.ID.Hex()).Msg("User data erased")
+	return report, nil
+}
+
+// findUser returns the user to erase, or nil for a user whose earlier erasure
+// deleted the account but failed afterwards, so that it can be retried.
+func (s *erasureServiceImpl) findUser(ctx context.Context, userID primitive.ObjectID) (*models.User, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err == nil {
+		return user, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		log.Error().Err(err).Str("erasedUserID", userID.Hex()).Msg("Failed to load user to erase")
+		return nil, fmt.Errorf("failed to retrieve user")
+	}
+	if _, err := s.erasureRepo.FindOne(ctx, bson.M{"user_id": userID, "completed_at": nil}); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("user not found")
+		}
+		log.Error().Err(err).Str("erasedUserID", userID.Hex()).Msg("Failed to look up earlier erasures")
+		return nil, fmt.Errorf("failed to retrieve erasure reports")
+	}
+	return nil, nil
+}
+
+// sign returns the signature of report, computed over its JSON encoding without
+// the signature.
+func (s *erasureServiceImpl) sign(report models.ErasureReport) string {
+	report.Signature = ""
+	report.StartedAt = report.StartedAt.UTC()
+	if report.CompletedAt != nil {
+		completed := report.CompletedAt.UTC()
+		report.CompletedAt = &completed
+	}
+	if report.Collections == nil {
+		report.Collections = map[string]int64{}
+	}
+	payload, _ := json.Marshal(report)
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *erasureServiceImpl) List(ctx context.Context, userID *primitive.ObjectID) ([]models.ErasureReport, error) {
+	filter := bson.M{}
+	if userID != nil {
+		filter["user_id"] = *userID
+	}
+	return s.erasureRepo.Find(ctx, filter, 100)
+}
+
+func (s *erasureServiceImpl) Get(ctx context.Context, id primitive.ObjectID) (*models.ErasureVerification, error) {
+	report, err := s.erasureRepo.FindOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("erasure report not found")
+		}
+		return nil, fmt.Errorf("failed to retrieve erasure report")
+	}
+	valid := report.Signature != "" && len(s.signingKey) > 0 &&
+		hmac.Equal([]byte(report.Signature), []byte(s.sign(*report)))
+	return &models.ErasureVerification{Report: *report, SignatureValid: valid}, nil
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+)
+
+// fakeErasures is an ErasureRepository that records the purges in calls.
+type fakeErasures struct {
+	repositories.ErasureRepository
+	reports []*models.ErasureReport
+	calls   []string
+}
+
+func (f *fakeErasures) Create(ctx context.Context, report *models.ErasureReport) error {
+	f.reports = append(f.reports, report)
+	return nil
+}
+
+func (f *fakeErasures) Update(ctx context.Context, id primitive.ObjectID, updateFields bson.M) error {
+	return nil
+}
+
+// FindOne only understands the filter of findUser.
+func (f *fakeErasures) FindOne(ctx context.Context, filter bson.M) (*models.ErasureReport, error) {
+	for _, report := range f.reports {
+		if report.UserID == filter["user_id"] && report.CompletedAt == nil {
+			return report, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (f *fakeErasures) PurgeSubscriptions(ctx context.Context, email string) (int64, error) {
+	f.calls = append(f.calls, "subscriptions "+email)
+	return 2, nil
+}
+
+func (f *fakeErasures) PurgeUser(ctx context.Context, userID primitive.ObjectID) (map[string]int64, error) {
+	f.calls = append(f.calls, "user "+userID.Hex())
+	return map[string]int64{"users": 1}, nil
+}
+
+type fakeErasureUsers struct {
+	repositories.UserRepository
+	users map[primitive.ObjectID]*models.User
+}
+
+func (f fakeErasureUsers) FindByID(ctx context.Context, userID primitive.ObjectID) (*models.User, error) {
+	if user, ok := f.users[userID]; ok {
+		return user, nil
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+type fakeAnonymizer struct{ AuditService }
+
+func (fakeAnonymizer) Anonymize(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return 0, nil
+}
+
+func (fakeAnonymizer) Record(ctx context.Context, event models.AuditEvent) error { return nil }
+
+func newTestErasure(users ...*models.User) (*erasureServiceImpl, *fakeErasures) {
+	erasures := &fakeErasures{}
+	byID := map[primitive.ObjectID]*models.User{}
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+	return &erasureServiceImpl{erasureRepo: erasures, userRepo: fakeErasureUsers{users: byID}, auditService: fakeAnonymizer{}, signingKey: []byte("key")}, erasures
+}
+
+func TestEraseUserRemovesNewsletterSubscriptions(t *testing.T) {
+	user := &models.User{ID: primitive.NewObjectID(), Email: "Jane@Example.com"}
+	s, erasures := newTestErasure(user)
+
+	report, err := s.EraseUser(context.Background(), primitive.NewObjectID(), user.ID, models.EraseUserRequest{Confirm: user.ID.Hex(), Reference: "DSR-1"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"subscriptions Jane@Example.com", "user " + user.ID.Hex()}; !reflect.DeepEqual(erasures.calls, want) {
+		t.Errorf("purges = %v, want %v", erasures.calls, want)
+	}
+	if report.SubscriptionsRemoved != 2 || report.CompletedAt == nil {
+		t.Errorf("report = %+v, want 2 subscriptions removed and completed", report)
+	}
+}
+
+func TestEraseUserRequiresTheUser(t *testing.T) {
+	s, erasures := newTestErasure()
+	userID := primitive.NewObjectID()
+	req := models.EraseUserRequest{Confirm: userID.Hex(), Reference: "DSR-1"}
+
+	if _, err := s.EraseUser(context.Background(), primitive.NewObjectID(), userID, req, ""); err == nil || err.Error() != "user not found" {
+		t.Fatalf("err = %v, want user not found", err)
+	}
+	if len(erasures.reports) != 0 || len(erasures.calls) != 0 {
+		t.Fatalf("erased a user that does not exist: %d reports, purges %v", len(erasures.reports), erasures.calls)
+	}
+
+	// An erasure that deleted the account and failed afterwards can be retried.
+	erasures.reports = append(erasures.reports, &models.ErasureReport{ID: primitive.NewObjectID(), UserID: userID})
+	if _, err := s.EraseUser(context.Background(), primitive.NewObjectID(), userID, req, ""); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"user " + userID.Hex()}; !reflect.DeepEqual(erasures.calls, want) {
+		t.Errorf("purges = %v, want %v", erasures.calls, want)
+	}
+}
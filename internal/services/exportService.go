@@ -0,0 +1,445 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+// ExportService exports a user's data as a Markly JSON export and imports such an
+// export back, into the same account or another one. It also imports bookmarks
+// from CSV files made by other tools and exports them as Markdown notes.
+type ExportService interface {
+	Export(ctx context.Context, userID primitive.ObjectID) (*models.MarklyExport, error)
+	ExportMarkdown(ctx context.Context, userID primitive.ObjectID) ([]models.MarkdownFile, error)
+	Import(ctx context.Context, userID primitive.ObjectID, export *models.MarklyExport) (*models.MarklyImportResult, error)
+	ImportCSV(ctx context.Context, userID primitive.ObjectID, r io.Reader, mapping models.CSVColumnMapping) (*models.CSVImportResult, error)
+}
+
+type exportServiceImpl struct {
+	userRepo       repositories.UserRepository
+	bookmarkRepo   repositories.BookmarkRepository
+	tagRepo        repositories.TagRepository
+	categoryRepo   repositories.CategoryRepository
+	collectionRepo repositories.CollectionRepository
+	highlightRepo  repositories.HighlightRepository
+	encryption     EncryptionService
+	urls           URLService
+	users          UserService
+}
+
+func NewExportService(userRepo repositories.UserRepository, bookmarkRepo repositories.BookmarkRepository, tagRepo repositories.TagRepository, categoryRepo repositories.CategoryRepository, collectionRepo repositories.CollectionRepository, highlightRepo repositories.HighlightRepository, encryption EncryptionService, urls URLService, users UserService) ExportService {
+	return &exportServiceImpl{
+		userRepo:       userRepo,
+		bookmarkRepo:   bookmarkRepo,
+		tagRepo:        tagRepo,
+		categoryRepo:   categoryRepo,
+		collectionRepo: collectionRepo,
+		highlightRepo:  highlightRepo,
+		encryption:     encryption,
+		urls:           urls,
+		users:          users,
+	}
+}
+
+func (s *exportServiceImpl) Export(ctx context.Context, userID primitive.ObjectID) (*models.MarklyExport, error) {
+	log.Debug().Str("userID", userID.Hex()).Msg("Attempting to export user data")
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to fetch user")
+	}
+	export := &models.MarklyExport{Format: models.MarklyExportFormat, ExportedAt: time.Now().UTC(), Settings: user.Settings}
+
+	if export.Tags, err = s.tagRepo.FindByUser(ctx, userID); err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to export tags")
+		return nil, fmt.Errorf("failed to export tags")
+	}
+	if export.Categories, err = s.categoryRepo.FindByUser(ctx, userID); err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to export categories")
+		return nil, fmt.Errorf("failed to export categories")
+	}
+	if export.Collections, err = s.collectionRepo.FindByUser(ctx, userID); err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to export collections")
+		return nil, fmt.Errorf("failed to export collections")
+	}
+	if export.Bookmarks, err = s.bookmarkRepo.Find(ctx, bson.M{"user_id": userID}, 0, 0); err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to export bookmarks")
+		return nil, fmt.Errorf("failed to export bookmarks")
+	}
+
+	ids := make([]primitive.ObjectID, len(export.Bookmarks))
+	byID := make(map[primitive.ObjectID]*models.Bookmark, len(export.Bookmarks))
+	for i := range export.Bookmarks {
+		bm := &export.Bookmarks[i]
+		ids[i] = bm.ID
+		byID[bm.ID] = bm
+		if bm.EncryptedNotes != "" {
+			notes, err := s.encryption.Decrypt(ctx, userID, bm.EncryptedNotes)
+			if err != nil {
+				log.Error().Err(err).Str("bookmarkID", bm.ID.Hex()).Msg("Failed to decrypt notes for export")
+				return nil, fmt.Errorf("failed to decrypt bookmark notes")
+			}
+			bm.Notes = notes
+		}
+	}
+	highlights, err := s.highlightRepo.FindByBookmarks(ctx, userID, ids)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to export highlights")
+		return nil, fmt.Errorf("failed to export highlights")
+	}
+	for _, h := range highlights {
+		if bm := byID[h.BookmarkID]; bm != nil {
+			bm.Highlights = append(bm.Highlights, h)
+		}
+	}
+
+	log.Info().Str("userID", userID.Hex()).Int("bookmarks", len(export.Bookmarks)).Msg("User data exported")
+	return export, nil
+}
+
+// Import adds the contents of an export to the user's data. Tags, categories and
+// collections are matched by name and bookmarks by URL, so that items the user
+// already has are reused instead of duplicated and the import can safely be run
+// again. References between items are re-mapped to the IDs they get here.
+// Highlights are only imported with the bookmarks the import creates.
+func (s *exportServiceImpl) Import(ctx context.Context, userID primitive.ObjectID, export *models.MarklyExport) (*models.MarklyImportResult, error) {
+	log.Debug().Str("userID", userID.Hex()).Int("bookmarks", len(export.Bookmarks)).Msg("Attempting to import Markly export")
+	if export.Format != models.MarklyExportFormat {
+		return nil, fmt.Errorf("invalid export: not a Markly export or unsupported format version")
+	}
+	if err := s.validateExport(export); err != nil {
+		return nil, err
+	}
+
+	result := &models.MarklyImportResult{}
+	tagIDs, err := s.importTags(ctx, userID, export.Tags, &result.Tags)
+	if err != nil {
+		return result, err
+	}
+	categoryIDs, err := s.importCategories(ctx, userID, export.Categories, &result.Categories)
+	if err != nil {
+		return result, err
+	}
+	collectionIDs, err := s.importCollections(ctx, userID, export.Collections, &result.Collections)
+	if err != nil {
+		return result, err
+	}
+	for i := range export.Bookmarks {
+		if err := s.importBookmark(ctx, userID, &export.Bookmarks[i], tagIDs, categoryIDs, collectionIDs, result); err != nil {
+			return result, err
+		}
+	}
+
+	if export.Settings != nil {
+		if _, err := s.users.UpdateSettings(ctx, userID, settingsUpdateFromExport(export.Settings)); err != nil {
+			log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to restore settings")
+			return result, fmt.Errorf("failed to restore settings")
+		}
+		result.SettingsRestored = true
+	}
+
+	log.Info().Str("userID", userID.Hex()).Interface("result", result).Msg("Markly export imported")
+	return result, nil
+}
+
+// validateExport checks the whole export before anything is written.
+func (s *exportServiceImpl) validateExport(export *models.MarklyExport) error {
+	for i, bm := range export.Bookmarks {
+		if bm.Title == "" {
+			return fmt.Errorf("invalid export: bookmark %d has no title", i)
+		}
+		if _, err := utils.NormalizeURL(bm.URL); err != nil {
+			return fmt.Errorf("invalid export: bookmark %d: %v", i, err)
+		}
+		if err := checkTagLimit(len(bm.TagsID)); err != nil {
+			return err
+		}
+		if bm.Notes != "" && !s.encryption.Enabled() {
+			return ErrEncryptionNotConfigured
+		}
+	}
+	for _, c := range export.Categories {
+		if _, err := categoryColor(c.Color); err != nil {
+			return fmt.Errorf("invalid export: category %q: %v", c.Name, err)
+		}
+	}
+	for _, c := range export.Collections {
+		if c.Settings != nil {
+			if err := validateAutoArchivePolicy(c.Settings.AutoArchive); err != nil {
+				return fmt.Errorf("invalid export: collection %q: %v", c.Name, err)
+			}
+		}
+	}
+	if settings := export.Settings; settings != nil {
+		rules, err := normalizeDomainTagRules(settings.DomainTagRules)
+		if err != nil {
+			return err
+		}
+		settings.DomainTagRules = rules
+		if settings.Timezone != "" {
+			if _, err := loadTimezone(settings.Timezone); err != nil {
+				return err
+			}
+		}
+		if err := validateActivityWebhookURL(settings.ActivityWebhookURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settingsUpdateFromExport turns exported settings into an update of every
+// setting, so that they go through the same checks as an update by the user. The
+// activity webhook gets a new secret rather than the one in the file, which anyone
+// holding the export could read, and a webhook without a URL is disabled.
+func settingsUpdateFromExport(settings *models.UserSettings) *models.UserSettingsUpdate {
+	rules := settings.DomainTagRules
+	if rules == nil {
+		rules = []models.DomainTagRule{}
+	}
+	webhookURL := settings.ActivityWebhookURL
+	enabled := settings.ActivityWebhookEnabled && webhookURL != ""
+	return &models.UserSettingsUpdate{
+		DomainTagRules:              &rules,
+		AutoApplyDomainTags:         &settings.AutoApplyDomainTags,
+		ExcludeFromTrending:         &settings.ExcludeFromTrending,
+		DisableExternalAI:           &settings.DisableExternalAI,
+		Timezone:                    &settings.Timezone,
+		ActivityWebhookURL:          &webhookURL,
+		ActivityWebhookEnabled:      &enabled,
+		RotateActivityWebhookSecret: webhookURL != "",
+	}
+}
+
+func (s *exportServiceImpl) importTags(ctx context.Context, userID primitive.ObjectID, tags []models.Tag, count *models.ImportCount) (map[primitive.ObjectID]primitive.ObjectID, error) {
+	existing, err := s.tagRepo.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tags")
+	}
+	byName := make(map[string]primitive.ObjectID, len(existing))
+	for _, t := range existing {
+		byName[t.Name] = t.ID
+	}
+
+	ids := make(map[primitive.ObjectID]primitive.ObjectID, len(tags))
+	for _, t := range tags {
+		if id, ok := byName[t.Name]; ok {
+			ids[t.ID] = id
+			count.Existing++
+			continue
+		}
+		oldID := t.ID
+		t.ID = primitive.NewObjectID()
+		t.UserID = userID
+		if _, err := s.tagRepo.Create(ctx, &t); err != nil {
+			log.Error().Err(err).Str("userID", userID.Hex()).Str("tag", t.Name).Msg("Failed to import tag")
+			return nil, fmt.Errorf("failed to import tag %q", t.Name)
+		}
+		byName[t.Name] = t.ID
+		ids[oldID] = t.ID
+		count.Created++
+	}
+	return ids, nil
+}
+
+func (s *exportServiceImpl) importCategories(ctx context.Context, userID primitive.ObjectID, categories []models.Category, count *models.ImportCount) (map[primitive.ObjectID]primitive.ObjectID, error) {
+	existing, err := s.categoryRepo.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch categories")
+	}
+	byName := make(map[string]primitive.ObjectID, len(existing))
+	for _, c := range existing {
+		byName[c.Name] = c.ID
+	}
+
+	ids := make(map[primitive.ObjectID]primitive.ObjectID, len(categories))
+	for _, c := range categories {
+		if id, ok := byName[c.Name]; ok {
+			ids[c.ID] = id
+			count.Existing++
+			continue
+		}
+		oldID := c.ID
+		c.ID = primitive.NewObjectID()
+		c.UserID = userID
+		c.PreviousSlugs = nil
+		c.IconUpdatedAt = nil
+		emoji, err := categoryEmoji(c.Name, c.Emoji)
+		if err != nil {
+			// Exports made before emojis were validated may hold free text.
+			emoji = fallbackCategoryEmoji(c.Name)
+		}
+		c.Emoji = emoji
+		// validateExport checked the color.
+		c.Color, _ = categoryColor(c.Color)
+		err = saveWithSlug(ctx, s.categoryRepo, userID, c.ID, c.Name, "category", func(slug string) error {
+			c.Slug = slug
+			_, err := s.categoryRepo.Create(ctx, &c)
+			return err
+		})
+		if err != nil {
+			log.Error().Err(err).Str("userID", userID.Hex()).Str("category", c.Name).Msg("Failed to import category")
+			return nil, fmt.Errorf("failed to import category %q", c.Name)
+		}
+		byName[c.Name] = c.ID
+		ids[oldID] = c.ID
+		count.Created++
+	}
+	return ids, nil
+}
+
+func (s *exportServiceImpl) importCollections(ctx context.Context, userID primitive.ObjectID, collections []models.Collection, count *models.ImportCount) (map[primitive.ObjectID]primitive.ObjectID, error) {
+	existing, err := s.collectionRepo.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch collections")
+	}
+	byName := make(map[string]primitive.ObjectID, len(existing))
+	for _, c := range existing {
+		byName[c.Name] = c.ID
+	}
+
+	ids := make(map[primitive.ObjectID]primitive.ObjectID, len(collections))
+	for _, c := range collections {
+		if id, ok := byName[c.Name]; ok {
+			ids[c.ID] = id
+			count.Existing++
+			continue
+		}
+		if err := checkCollectionLimit(ctx, s.collectionRepo, userID); err != nil {
+			return nil, err
+		}
+		oldID := c.ID
+		c.ID = primitive.NewObjectID()
+		c.UserID = userID
+		c.PreviousSlugs = nil
+		err := saveWithSlug(ctx, s.collectionRepo, userID, c.ID, c.Name, "collection", func(slug string) error {
+			c.Slug = slug
+			_, err := s.collectionRepo.Create(ctx, &c)
+			return err
+		})
+		if err != nil {
+			log.Error().Err(err).Str("userID", userID.Hex()).Str("collection", c.Name).Msg("Failed to import collection")
+			return nil, fmt.Errorf("failed to import collection %q", c.Name)
+		}
+		if err := keepWithinCollectionLimit(ctx, s.collectionRepo, &c); err != nil {
+			return nil, err
+		}
+		byName[c.Name] = c.ID
+		ids[oldID] = c.ID
+		count.Created++
+	}
+	return ids, nil
+}
+
+// findImported returns the canonical form of the normalized URL and whether the
+// user already has a bookmark with it.
+func (s *exportServiceImpl) findImported(ctx context.Context, userID primitive.ObjectID, url string) (string, bool, error) {
+	existing, canonical, err := findExistingBookmark(ctx, s.bookmarkRepo, s.urls, userID, url)
+	return canonical, existing != nil, err
+}
+
+// findExistingBookmark returns the user's bookmark with the normalized URL, or nil,
+// and the canonical form of the URL. Importers use it to skip what the user
+// already saved. Redirects are not followed, since imports read many URLs.
+func findExistingBookmark(ctx context.Context, bookmarkRepo repositories.BookmarkRepository, urls URLService, userID primitive.ObjectID, url string) (*models.Bookmark, string, error) {
+	canonical, err := urls.CanonicalizeOffline(url)
+	if err != nil {
+		canonical = url
+	}
+	filter := bson.M{"user_id": userID, "$or": bson.A{bson.M{"canonical_url": canonical}, bson.M{"url": url}}}
+	existing, err := bookmarkRepo.FindOne(ctx, filter)
+	if err == mongo.ErrNoDocuments {
+		return nil, canonical, nil
+	}
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to look up bookmark during import")
+		return nil, "", fmt.Errorf("failed to import bookmark %s", url)
+	}
+	return existing, canonical, nil
+}
+
+// importBookmark creates the bookmark unless the user already has one with the
+// same URL.
+func (s *exportServiceImpl) importBookmark(ctx context.Context, userID primitive.ObjectID, bm *models.Bookmark, tagIDs, categoryIDs, collectionIDs map[primitive.ObjectID]primitive.ObjectID, result *models.MarklyImportResult) error {
+	bm.URL, _ = utils.NormalizeURL(bm.URL)
+	canonical, exists, err := s.findImported(ctx, userID, bm.URL)
+	if err != nil {
+		return err
+	}
+	if exists {
+		result.Bookmarks.Existing++
+		result.Highlights.Existing += len(bm.Highlights)
+		return nil
+	}
+
+	bm.ID = primitive.NewObjectID()
+	bm.UserID = userID
+	bm.CanonicalURL = canonical
+	bm.TagsID = remapIDs(bm.TagsID, tagIDs)
+	bm.CollectionsID = remapIDs(bm.CollectionsID, collectionIDs)
+	if bm.CategoryID != nil {
+		if id, ok := categoryIDs[*bm.CategoryID]; ok {
+			bm.CategoryID = &id
+		} else {
+			bm.CategoryID = nil
+		}
+	}
+	bm.MetadataAt = nil
+	bm.EncryptedNotes = ""
+	bm.NoteGrams = nil
+	if bm.Notes != "" {
+		if bm.EncryptedNotes, err = s.encryption.Encrypt(ctx, userID, bm.Notes); err != nil {
+			log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to encrypt notes during import")
+			return fmt.Errorf("failed to encrypt bookmark notes")
+		}
+		if bm.NoteGrams, err = noteSearchGrams(ctx, s.encryption, userID, bm.Notes); err != nil {
+			log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to index notes during import")
+			return fmt.Errorf("failed to encrypt bookmark notes")
+		}
+	}
+	bm.SearchGrams = bookmarkSearchGrams(bm)
+	highlights := bm.Highlights
+	bm.Highlights = nil
+	if _, err := s.bookmarkRepo.Create(ctx, bm); err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to import bookmark")
+		return fmt.Errorf("failed to import bookmark %s", bm.URL)
+	}
+	result.Bookmarks.Created++
+
+	for _, h := range highlights {
+		h.ID = primitive.NewObjectID()
+		h.UserID = userID
+		h.BookmarkID = bm.ID
+		if _, err := s.highlightRepo.Create(ctx, &h); err != nil {
+			log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to import highlight")
+			return fmt.Errorf("failed to import highlights of bookmark %s", bm.URL)
+		}
+		result.Highlights.Created++
+	}
+	return nil
+}
+
+// remapIDs translates exported IDs to imported ones, dropping those the export
+// did not define.
+func remapIDs(ids []primitive.ObjectID, mapping map[primitive.ObjectID]primitive.ObjectID) []primitive.ObjectID {
+	var remapped []primitive.ObjectID
+	for _, id := range ids {
+		if newID, ok := mapping[id]; ok && !containsObjectID(remapped, newID) {
+			remapped = append(remapped, newID)
+		}
+	}
+	return remapped
+}
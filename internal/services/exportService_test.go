@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+)
+
+func TestRemapIDs(t *testing.T) {
+	oldA, oldB, oldC := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	newA := primitive.NewObjectID()
+	// oldB was matched to the same existing tag as oldA; oldC is not in the export.
+	mapping := map[primitive.ObjectID]primitive.ObjectID{oldA: newA, oldB: newA}
+
+	got := remapIDs([]primitive.ObjectID{oldA, oldB, oldC}, mapping)
+	if want := []primitive.ObjectID{newA}; !reflect.DeepEqual(got, want) {
+		t.Errorf("remapIDs = %v, want %v", got, want)
+	}
+	if got := remapIDs(nil, mapping); got != nil {
+		t.Errorf("remapIDs(nil) = %v, want nil", got)
+	}
+}
+
+func TestSettingsUpdateFromExport(t *testing.T) {
+	update := settingsUpdateFromExport(&models.UserSettings{
+		Timezone:               "Europe/Berlin",
+		ActivityWebhookURL:     "https://hooks.example.com/markly",
+		ActivityWebhookEnabled: true,
+		ActivityWebhookSecret:  "exported-secret",
+	})
+	if update.DomainTagRules == nil || len(*update.DomainTagRules) != 0 {
+		t.Errorf("domain tag rules = %v, want them cleared", update.DomainTagRules)
+	}
+	if *update.Timezone != "Europe/Berlin" || *update.ActivityWebhookURL != "https://hooks.example.com/markly" || !*update.ActivityWebhookEnabled {
+		t.Errorf("update = %+v", update)
+	}
+	if !update.RotateActivityWebhookSecret {
+		t.Error("exported webhook secret is kept")
+	}
+
+	update = settingsUpdateFromExport(&models.UserSettings{ActivityWebhookEnabled: true})
+	if *update.ActivityWebhookEnabled || update.RotateActivityWebhookSecret {
+		t.Errorf("webhook without a url = %+v, want it disabled", update)
+	}
+}
+
+func TestValidateExportChecksCollectionSettings(t *testing.T) {
+	s := &exportServiceImpl{}
+	export := &models.MarklyExport{Collections: []models.Collection{
+		{Name: "Kept", Settings: &models.CollectionSettings{AutoArchive: &models.AutoArchivePolicy{AfterDays: 30}}},
+		{Name: "Broken", Settings: &models.CollectionSettings{AutoArchive: &models.AutoArchivePolicy{}}},
+	}}
+	if err := s.validateExport(export); err == nil || !strings.HasPrefix(err.Error(), "invalid export") {
+		t.Errorf("err = %v, want an invalid export", err)
+	}
+	export.Collections = export.Collections[:1]
+	if err := s.validateExport(export); err != nil {
+		t.Errorf("valid collections: %v", err)
+	}
+}
+
+func TestValidateExportChecksCategoryColors(t *testing.T) {
+	s := &exportServiceImpl{}
+	export := &models.MarklyExport{Categories: []models.Category{{Name: "Work", Color: models.CategoryColors[0]}, {Name: "Home", Color: "#123456"}}}
+	if err := s.validateExport(export); err == nil || !strings.HasPrefix(err.Error(), "invalid export") {
+		t.Errorf("err = %v, want an invalid export", err)
+	}
+}
+
+// importedCategories is a CategoryRepository storing the categories created.
+type importedCategories struct {
+	repositories.CategoryRepository
+	created []models.Category
+}
+
+func (f *importedCategories) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Category, error) {
+	return nil, nil
+}
+
+func (f *importedCategories) SlugInUse(ctx context.Context, userID primitive.ObjectID, slug string, excludeID primitive.ObjectID) (bool, error) {
+	return false, nil
+}
+
+func (f *importedCategories) Create(ctx context.Context, category *models.Category) (*models.Category, error) {
+	f.created = append(f.created, *category)
+	return category, nil
+}
+
+func TestImportCategoriesReplacesFreeTextEmoji(t *testing.T) {
+	repo := &importedCategories{}
+	s := &exportServiceImpl{categoryRepo: repo}
+	categories := []models.Category{{ID: primitive.NewObjectID(), Name: "Reading", Emoji: "books"}, {ID: primitive.NewObjectID(), Name: "Music", Emoji: "🎵"}}
+
+	if _, err := s.importCategories(context.Background(), primitive.NewObjectID(), categories, &models.ImportCount{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.created) != 2 {
+		t.Fatalf("created %d categories, want 2", len(repo.created))
+	}
+	if got, want := repo.created[0].Emoji, fallbackCategoryEmoji("Reading"); got != want {
+		t.Errorf("free-text emoji imported as %q, want %q", got, want)
+	}
+	if repo.created[1].Emoji != "🎵" {
+		t.Errorf("emoji imported as %q, want it kept", repo.created[1].Emoji)
+	}
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+const (
+	// defaultMarkdownPushDirectory is where pushed notes go in the repository.
+	defaultMarkdownPushDirectory = "markly"
+	// maxMarkdownPushBytes bounds the notes of one push, which GitHub receives in a
+	// single request.
+	maxMarkdownPushBytes = 20 << 20
+)
+
+var (
+	// githubRepository matches "owner/name" as GitHub allows them.
+	githubRepository = regexp.MustCompile(`^[A-Za-z0-9-]{1,39}/[A-Za-z0-9._-]{1,100}$`)
+	// githubPathSegment matches one part of a branch name or directory.
+	githubPathSegment = regexp.MustCompile(`^[A-Za-z0-9 ._-]+$`)
+)
+
+// githubPath checks a slash-separated branch name or directory and returns it
+// without surrounding slashes. Parts cannot be "." or "..", so the notes stay in
+// the directory.
+func githubPath(value string) (string, bool) {
+	value = strings.Trim(strings.TrimSpace(value), "/")
+	if value == "" {
+		return "", false
+	}
+	for _, part := range strings.Split(value, "/") {
+		if part == "." || part == ".." || strings.TrimSpace(part) != part || !githubPathSegment.MatchString(part) {
+			return "", false
+		}
+	}
+	return value, true
+}
+
+// canPush reports whether conn was granted access to the account's repositories.
+func canPush(conn *models.GitHubConnection) bool {
+	for _, scope := range conn.Scopes {
+		if scope == models.GitHubPushScope {
+			return true
+		}
+	}
+	return false
+}
+
+// PushMarkdown commits one note per bookmark, as ExportMarkdown writes them, on
+// top of the branch. Notes of an earlier push are overwritten when their
+// bookmark's title is unchanged and kept otherwise. The branch only moves
+// forward: a commit pushed meanwhile makes the push fail instead of being lost.
+func (s *githubServiceImpl) PushMarkdown(ctx context.Context, userID primitive.ObjectID, req models.MarkdownPushRequest) (*models.MarkdownPushResult, error) {
+	if !s.configured() {
+		return nil, ErrGitHubNotConfigured
+	}
+	repo := strings.TrimSpace(req.Repository)
+	if !githubRepository.MatchString(repo) || strings.HasSuffix(repo, "/.") || strings.HasSuffix(repo, "/..") {
+		return nil, fmt.Errorf("invalid repository: expected owner/name")
+	}
+	branch := ""
+	if strings.TrimSpace(req.Branch) != "" {
+		var ok bool
+		if branch, ok = githubPath(req.Branch); !ok || strings.HasSuffix(branch, ".lock") || strings.Contains(branch, " ") {
+			return nil, fmt.Errorf("invalid branch %q", req.Branch)
+		}
+	}
+	dir := defaultMarkdownPushDirectory
+	if strings.TrimSpace(req.Directory) != "" {
+		var ok bool
+		if dir, ok = githubPath(req.Directory); !ok {
+			return nil, fmt.Errorf("invalid directory %q", req.Directory)
+		}
+	}
+
+	conn, err := s.repo.FindByUser(ctx, userID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to load github connection")
+		return nil, fmt.Errorf("failed to retrieve github connection")
+	}
+	if err != nil || !conn.Connected {
+		return nil, fmt.Errorf("github account not connected")
+	}
+	if !canPush(conn) {
+		return nil, ErrGitHubPushNotAllowed
+	}
+
+	files, err := s.exports.ExportMarkdown(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("invalid export: there are no bookmarks to push")
+	}
+	size := 0
+	for _, f := range files {
+		size += len(f.Content)
+	}
+	if size > maxMarkdownPushBytes {
+		return nil, fmt.Errorf("%w: the markdown export is larger than %d MB", utils.ErrLimitExceeded, maxMarkdownPushBytes>>20)
+	}
+
+	token, err := s.encryption.Decrypt(ctx, userID, conn.EncryptedToken)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to decrypt github token")
+		return nil, fmt.Errorf("failed to decrypt github token")
+	}
+	result, err := s.commitFiles(ctx, token, repo, branch, dir, files)
+	if err != nil {
+		log.Warn().Err(err).Str("userID", userID.Hex()).Str("repository", repo).Msg("Failed to push markdown export to github")
+		return nil, err
+	}
+	log.Info().Str("userID", userID.Hex()).Str("repository", repo).Str("branch", result.Branch).Str("commit", result.Commit).Int("files", result.Files).Msg("Markdown export pushed to github")
+	return result, nil
+}
+
+// commitFiles creates a commit adding files under dir on top of branch, or the
+// default branch when branch is empty, and moves the branch to it.
+func (s *githubServiceImpl) commitFiles(ctx context.Context, token, repo, branch, dir string, files []models.MarkdownFile) (*models.MarkdownPushResult, error) {
+	const accept = "application/vnd.github+json"
+	api := githubAPIURL + "/repos/" + repo
+
+	var repository struct {
+		DefaultBranch string `json:"default_branch"`
+		Permissions   struct {
+			Push bool `json:"push"`
+		} `json:"permissions"`
+	}
+	if err := s.get(ctx, token, api, accept, &repository); err != nil {
+		if status := githubStatus(err); status == http.StatusNotFound || status == http.StatusForbidden {
+			return nil, fmt.Errorf("invalid repository: %s was not found", repo)
+		}
+		return nil, githubPushError(err)
+	}
+	if !repository.Permissions.Push {
+		return nil, fmt.Errorf("invalid repository: the account cannot push to %s", repo)
+	}
+	if branch == "" {
+		branch = repository.DefaultBranch
+	}
+
+	var ref struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	if err := s.get(ctx, token, api+"/git/ref/heads/"+branch, accept, &ref); err != nil {
+		// GitHub answers 409 for a repository without commits.
+		if status := githubStatus(err); status == http.StatusNotFound || status == http.StatusConflict {
+			return nil, fmt.Errorf("invalid branch: %s has no branch %q with commits", repo, branch)
+		}
+		return nil, githubPushError(err)
+	}
+	var parent struct {
+		Tree struct {
+			SHA string `json:"sha"`
+		} `json:"tree"`
+	}
+	if err := s.get(ctx, token, api+"/git/commits/"+ref.Object.SHA, accept, &parent); err != nil {
+		return nil, githubPushError(err)
+	}
+
+	type treeEntry struct {
+		Path    string `json:"path"`
+		Mode    string `json:"mode"`
+		Type    string `json:"type"`
+		Content string `json:"content"`
+	}
+	entries := make([]treeEntry, len(files))
+	for i, f := range files {
+		entries[i] = treeEntry{Path: dir + "/" + f.Name, Mode: "100644", Type: "blob", Content: string(f.Content)}
+	}
+	var tree struct {
+		SHA string `json:"sha"`
+	}
+	if err := s.call(ctx, token, http.MethodPost, api+"/git/trees", accept, map[string]interface{}{"base_tree": parent.Tree.SHA, "tree": entries}, &tree); err != nil {
+		return nil, githubPushError(err)
+	}
+	var commit struct {
+		SHA     string `json:"sha"`
+		HTMLURL string `json:"html_url"`
+	}
+	message := fmt.Sprintf("Export %d bookmarks from Markly", len(files))
+	if err := s.call(ctx, token, http.MethodPost, api+"/git/commits", accept, map[string]interface{}{"message": message, "tree": tree.SHA, "parents": []string{ref.Object.SHA}}, &commit); err != nil {
+		return nil, githubPushError(err)
+	}
+	var updated struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	if err := s.call(ctx, token, http.MethodPatch, api+"/git/refs/heads/"+branch, accept, map[string]interface{}{"sha": commit.SHA, "force": false}, &updated); err != nil {
+		// GitHub answers 422 when the branch moved since it was read.
+		if githubStatus(err) == http.StatusUnprocessableEntity {
+			return nil, fmt.Errorf("github push conflicted with a commit pushed meanwhile, try again")
+		}
+		return nil, githubPushError(err)
+	}
+	return &models.MarkdownPushResult{Repository: repo, Branch: branch, Commit: commit.SHA, CommitURL: commit.HTMLURL, Files: len(files)}, nil
+}
+
+// githubPushError keeps a revoked token recognisable and reports other failures
+// as failed pushes.
+func githubPushError(err error) error {
+	if err == errGitHubUnauthorized {
+		return err
+	}
+	return fmt.Errorf("failed to push to github: %w", err)
+}
@@ -0,0 +1,544 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+const (
+	githubURL    = "https://github.com"
+	githubAPIURL = "https://api.github.com"
+	// githubStateTTL is how long the user has to approve the connection on GitHub.
+	githubStateTTL = 10 * time.Minute
+	// githubStarPages bounds the pages of 100 stars read by one sync.
+	githubStarPages = 50
+	// githubSyncLease bounds how long a sync holds its connection, so that a sync
+	// that died with its server does not block the next ones for long.
+	githubSyncLease = 15 * time.Minute
+)
+
+// ErrGitHubNotConfigured is returned by every GitHub operation when the server
+// has no GitHub OAuth app.
+var ErrGitHubNotConfigured = errors.New("github integration is not configured on this server")
+
+// errGitHubUnauthorized means GitHub no longer accepts the stored token.
+var errGitHubUnauthorized = errors.New("github access was revoked, connect the account again")
+
+// errGitHubSyncRunning means another sync of the account has not finished yet.
+var errGitHubSyncRunning = errors.New("github sync already running")
+
+// ErrGitHubPushNotAllowed is returned when pushing to an account that was connected
+// without access to its repositories.
+var ErrGitHubPushNotAllowed = errors.New("github account was connected without access to repositories, connect it again with push allowed")
+
+// GitHubService connects users' GitHub accounts and imports their starred
+// repositories as bookmarks in the "GitHub Stars" collection, tagged with the
+// repository's language. Accounts connected with push allowed can also receive
+// Markdown exports.
+type GitHubService interface {
+	// Authorize starts connecting the user's GitHub account. push asks for access
+	// to the account's repositories as well, for PushMarkdown.
+	Authorize(ctx context.Context, userID primitive.ObjectID, push bool) (*models.GitHubAuthorization, error)
+	// Callback completes the authorization GitHub redirected back with and runs a
+	// first sync in the background. browserState is the state the browser that
+	// started the authorization was given, so that a user cannot be sent to the
+	// callback of an authorization someone else started.
+	Callback(ctx context.Context, code, state, browserState string) (*models.GitHubConnection, error)
+	Status(ctx context.Context, userID primitive.ObjectID) (*models.GitHubConnection, error)
+	Sync(ctx context.Context, userID primitive.ObjectID) (*models.GitHubSyncResult, error)
+	Disconnect(ctx context.Context, userID primitive.ObjectID) error
+	// SyncAll syncs every connected account. It is run by the scheduler.
+	SyncAll(ctx context.Context) error
+	// PushMarkdown commits the user's Markdown export to a repository of the
+	// connected account.
+	PushMarkdown(ctx context.Context, userID primitive.ObjectID, req models.MarkdownPushRequest) (*models.MarkdownPushResult, error)
+}
+
+type githubServiceImpl struct {
+	repo           repositories.GitHubRepository
+	bookmarkRepo   repositories.BookmarkRepository
+	collectionRepo repositories.CollectionRepository
+	tags           TagSuggestionService
+	encryption     EncryptionService
+	urls           URLService
+	exports        ExportService
+	client         *http.Client
+	clientID       string
+	clientSecret   string
+	redirectURL    string
+}
+
+// NewGitHubService reads the OAuth app from GITHUB_CLIENT_ID, GITHUB_CLIENT_SECRET
+// and GITHUB_REDIRECT_URL, the URL of the callback endpoint registered with the
+// app. Without them the integration is disabled.
+func NewGitHubService(repo repositories.GitHubRepository, bookmarkRepo repositories.BookmarkRepository, collectionRepo repositories.CollectionRepository, tags TagSuggestionService, encryption EncryptionService, urls URLService, exports ExportService) GitHubService {
+	return &githubServiceImpl{
+		repo:           repo,
+		bookmarkRepo:   bookmarkRepo,
+		collectionRepo: collectionRepo,
+		tags:           tags,
+		encryption:     encryption,
+		urls:           urls,
+		exports:        exports,
+		client:         &http.Client{Timeout: 15 * time.Second},
+		clientID:       os.Getenv("GITHUB_CLIENT_ID"),
+		clientSecret:   os.Getenv("GITHUB_CLIENT_SECRET"),
+		redirectURL:    os.Getenv("GITHUB_REDIRECT_URL"),
+	}
+}
+
+func (s *githubServiceImpl) configured() bool {
+	return s.clientID != "" && s.clientSecret != "" && s.redirectURL != ""
+}
+
+func (s *githubServiceImpl) Authorize(ctx context.Context, userID primitive.ObjectID, push bool) (*models.GitHubAuthorization, error) {
+	if !s.configured() {
+		return nil, ErrGitHubNotConfigured
+	}
+	// The token is encrypted like notes, so connecting needs encryption too.
+	if !s.encryption.Enabled() {
+		return nil, ErrEncryptionNotConfigured
+	}
+	state, err := utils.GenerateToken()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate github oauth state")
+		return nil, fmt.Errorf("failed to start github authorization")
+	}
+	expiresAt := time.Now().Add(githubStateTTL).UTC()
+	if err := s.repo.SaveState(ctx, userID, utils.HashAPIKey(state), expiresAt); err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to save github oauth state")
+		return nil, fmt.Errorf("failed to start github authorization")
+	}
+
+	scope := "read:user"
+	if push {
+		scope += " " + models.GitHubPushScope
+	}
+	query := url.Values{"client_id": {s.clientID}, "redirect_uri": {s.redirectURL}, "scope": {scope}, "state": {state}}
+	return &models.GitHubAuthorization{AuthorizeURL: githubURL + "/login/oauth/authorize?" + query.Encode(), State: state, ExpiresAt: expiresAt}, nil
+}
+
+func (s *githubServiceImpl) Callback(ctx context.Context, code, state, browserState string) (*models.GitHubConnection, error) {
+	if !s.configured() {
+		return nil, ErrGitHubNotConfigured
+	}
+	if code == "" || state == "" {
+		return nil, fmt.Errorf("invalid authorization: code and state are required")
+	}
+	if subtle.ConstantTimeCompare([]byte(state), []byte(browserState)) != 1 {
+		return nil, fmt.Errorf("invalid authorization: it was not started in this browser")
+	}
+	pending, err := s.repo.FindByState(ctx, utils.HashAPIKey(state), time.Now())
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("invalid authorization: unknown or expired state")
+		}
+		log.Error().Err(err).Msg("Failed to look up github oauth state")
+		return nil, fmt.Errorf("failed to complete github authorization")
+	}
+	userID := pending.UserID
+
+	token, scopes, err := s.exchangeCode(ctx, code)
+	if err != nil {
+		log.Warn().Err(err).Str("userID", userID.Hex()).Msg("Failed to exchange github authorization code")
+		return nil, fmt.Errorf("invalid authorization: %v", err)
+	}
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := s.get(ctx, token, githubAPIURL+"/user", "application/vnd.github+json", &user); err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to read github user")
+		return nil, fmt.Errorf("failed to read github account")
+	}
+	encrypted, err := s.encryption.Encrypt(ctx, userID, token)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to encrypt github token")
+		return nil, err
+	}
+	if err := s.repo.Connect(ctx, userID, user.Login, encrypted, scopes, time.Now().UTC()); err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to save github connection")
+		return nil, fmt.Errorf("failed to complete github authorization")
+	}
+	log.Info().Str("userID", userID.Hex()).Str("login", user.Login).Msg("GitHub account connected")
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		if _, err := s.Sync(ctx, userID); err != nil {
+			log.Warn().Err(err).Str("userID", userID.Hex()).Msg("First github stars sync failed")
+		}
+	}()
+	return s.Status(ctx, userID)
+}
+
+func (s *githubServiceImpl) Status(ctx context.Context, userID primitive.ObjectID) (*models.GitHubConnection, error) {
+	conn, err := s.repo.FindByUser(ctx, userID)
+	if err == mongo.ErrNoDocuments {
+		return &models.GitHubConnection{}, nil
+	}
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to load github connection")
+		return nil, fmt.Errorf("failed to retrieve github connection")
+	}
+	return conn, nil
+}
+
+func (s *githubServiceImpl) Sync(ctx context.Context, userID primitive.ObjectID) (*models.GitHubSyncResult, error) {
+	if !s.configured() {
+		return nil, ErrGitHubNotConfigured
+	}
+	conn, err := s.repo.FindByUser(ctx, userID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to load github connection")
+		return nil, fmt.Errorf("failed to retrieve github connection")
+	}
+	if err != nil || !conn.Connected {
+		return nil, fmt.Errorf("github account not connected")
+	}
+	return s.syncConnection(ctx, conn)
+}
+
+// syncConnection imports the stars of one account and records the outcome. The
+// sync lease keeps a manual sync and the scheduled one from importing the same
+// stars twice.
+func (s *githubServiceImpl) syncConnection(ctx context.Context, conn *models.GitHubConnection) (*models.GitHubSyncResult, error) {
+	now := time.Now().UTC()
+	claimed, err := s.repo.ClaimSync(ctx, conn.UserID, now, now.Add(githubSyncLease))
+	if err != nil {
+		log.Error().Err(err).Str("userID", conn.UserID.Hex()).Msg("Failed to claim github sync")
+		return nil, fmt.Errorf("failed to start github sync")
+	}
+	if !claimed {
+		return nil, errGitHubSyncRunning
+	}
+
+	result, err := s.importStars(ctx, conn)
+	syncErr := ""
+	if err != nil {
+		syncErr = err.Error()
+		result = nil
+	}
+	// The outcome is recorded even when the request that ran the sync went away,
+	// so that its lease is released.
+	if recordErr := s.repo.RecordSync(context.WithoutCancel(ctx), conn.UserID, time.Now().UTC(), result, syncErr); recordErr != nil {
+		log.Error().Err(recordErr).Str("userID", conn.UserID.Hex()).Msg("Failed to record github sync")
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Str("userID", conn.UserID.Hex()).Int("starred", result.Starred).Int("created", result.Created).Msg("GitHub stars synced")
+	return result, nil
+}
+
+func (s *githubServiceImpl) SyncAll(ctx context.Context) error {
+	if !s.configured() {
+		return nil
+	}
+	conns, err := s.repo.FindConnected(ctx)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for i := range conns {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, err := s.syncConnection(ctx, &conns[i]); err != nil {
+			if errors.Is(err, errGitHubSyncRunning) {
+				continue
+			}
+			failed++
+			log.Warn().Err(err).Str("userID", conns[i].UserID.Hex()).Msg("GitHub stars sync failed")
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("github sync failed for %d of %d accounts", failed, len(conns))
+	}
+	return nil
+}
+
+func (s *githubServiceImpl) Disconnect(ctx context.Context, userID primitive.ObjectID) error {
+	result, err := s.repo.Delete(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to delete github connection")
+		return fmt.Errorf("failed to disconnect github account")
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("github account not connected")
+	}
+	log.Info().Str("userID", userID.Hex()).Msg("GitHub account disconnected")
+	return nil
+}
+
+// githubStar is a starred repository as listed with the star+json media type.
+type githubStar struct {
+	StarredAt time.Time `json:"starred_at"`
+	Repo      struct {
+		FullName    string `json:"full_name"`
+		HTMLURL     string `json:"html_url"`
+		Description string `json:"description"`
+		Language    string `json:"language"`
+	} `json:"repo"`
+}
+
+// languageTag is the tag name of a repository language, or "" for none.
+func languageTag(language string) string {
+	return strings.ToLower(strings.TrimSpace(language))
+}
+
+// importStars creates a bookmark for each starred repository the user has not
+// saved yet, and adds the ones they saved themselves to the stars collection.
+// Unstarring a repository leaves its bookmark alone.
+func (s *githubServiceImpl) importStars(ctx context.Context, conn *models.GitHubConnection) (*models.GitHubSyncResult, error) {
+	userID := conn.UserID
+	token, err := s.encryption.Decrypt(ctx, userID, conn.EncryptedToken)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to decrypt github token")
+		return nil, fmt.Errorf("failed to decrypt github token")
+	}
+	stars, truncated, err := s.fetchStars(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.GitHubSyncResult{Starred: len(stars), Truncated: truncated}
+	if len(stars) == 0 {
+		return result, nil
+	}
+
+	collectionID, err := s.starsCollection(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	tagIDs := map[string]primitive.ObjectID{}
+	for _, star := range stars {
+		name := languageTag(star.Repo.Language)
+		if name == "" {
+			continue
+		}
+		if _, ok := tagIDs[name]; ok {
+			continue
+		}
+		ids, err := s.tags.ResolveTags(ctx, userID, []string{name})
+		if err != nil {
+			log.Error().Err(err).Str("userID", userID.Hex()).Str("tag", name).Msg("Failed to resolve github language tag")
+			return nil, fmt.Errorf("failed to import tag %q", name)
+		}
+		if len(ids) == 1 {
+			tagIDs[name] = ids[0]
+		}
+	}
+
+	for _, star := range stars {
+		normalized, err := utils.NormalizeURL(star.Repo.HTMLURL)
+		if err != nil {
+			continue
+		}
+		existing, canonical, err := findExistingBookmark(ctx, s.bookmarkRepo, s.urls, userID, normalized)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			result.Existing++
+			if !containsObjectID(existing.CollectionsID, collectionID) {
+				filter := bson.M{"_id": existing.ID, "user_id": userID}
+				if _, err := s.bookmarkRepo.UpdateOne(ctx, filter, bson.M{"$addToSet": bson.M{"collectionsid": collectionID}}); err != nil {
+					return nil, fmt.Errorf("failed to import bookmark %s", normalized)
+				}
+			}
+			continue
+		}
+
+		bm := &models.Bookmark{
+			ID:            primitive.NewObjectID(),
+			UserID:        userID,
+			URL:           normalized,
+			CanonicalURL:  canonical,
+			Title:         star.Repo.FullName,
+			Summary:       star.Repo.Description,
+			CollectionsID: []primitive.ObjectID{collectionID},
+			CreatedAt:     primitive.NewDateTimeFromTime(star.StarredAt),
+		}
+		if id, ok := tagIDs[languageTag(star.Repo.Language)]; ok {
+			bm.TagsID = []primitive.ObjectID{id}
+		}
+		if star.StarredAt.IsZero() {
+			bm.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+		}
+		bm.SearchGrams = bookmarkSearchGrams(bm)
+		if _, err := s.bookmarkRepo.Create(ctx, bm); err != nil {
+			log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to import github star")
+			return nil, fmt.Errorf("failed to import bookmark %s", bm.URL)
+		}
+		result.Created++
+	}
+	return result, nil
+}
+
+// starsCollection returns the user's "GitHub Stars" collection, creating it when
+// it is missing.
+func (s *githubServiceImpl) starsCollection(ctx context.Context, userID primitive.ObjectID) (primitive.ObjectID, error) {
+	collections, err := s.collectionRepo.FindByUser(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to load collections for github sync")
+		return primitive.NilObjectID, fmt.Errorf("failed to fetch collections")
+	}
+	for _, c := range collections {
+		if strings.EqualFold(c.Name, models.GitHubStarsCollection) {
+			return c.ID, nil
+		}
+	}
+	if err := checkCollectionLimit(ctx, s.collectionRepo, userID); err != nil {
+		return primitive.NilObjectID, err
+	}
+	col := models.Collection{ID: primitive.NewObjectID(), UserID: userID, Name: models.GitHubStarsCollection}
+	err = saveWithSlug(ctx, s.collectionRepo, userID, col.ID, col.Name, "collection", func(slug string) error {
+		col.Slug = slug
+		_, err := s.collectionRepo.Create(ctx, &col)
+		return err
+	})
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to create github stars collection")
+		return primitive.NilObjectID, fmt.Errorf("failed to create collection %q", col.Name)
+	}
+	if err := keepWithinCollectionLimit(ctx, s.collectionRepo, &col); err != nil {
+		return primitive.NilObjectID, err
+	}
+	return col.ID, nil
+}
+
+// fetchStars lists the account's starred repositories, newest first, reading at
+// most githubStarPages pages.
+func (s *githubServiceImpl) fetchStars(ctx context.Context, token string) ([]githubStar, bool, error) {
+	var stars []githubStar
+	for page := 1; page <= githubStarPages; page++ {
+		var batch []githubStar
+		endpoint := fmt.Sprintf("%s/user/starred?per_page=100&page=%d", githubAPIURL, page)
+		if err := s.get(ctx, token, endpoint, "application/vnd.github.star+json", &batch); err != nil {
+			if errors.Is(err, errGitHubUnauthorized) {
+				return nil, false, err
+			}
+			return nil, false, fmt.Errorf("failed to list github stars: %w", err)
+		}
+		stars = append(stars, batch...)
+		if len(batch) < 100 {
+			return stars, false, nil
+		}
+	}
+	return stars, true, nil
+}
+
+// githubStatusError is a GitHub API response with an unexpected status.
+type githubStatusError struct {
+	code   int
+	status string
+}
+
+func (e *githubStatusError) Error() string { return "github responded with " + e.status }
+
+// githubStatus returns the status code of a githubStatusError, or 0.
+func githubStatus(err error) int {
+	var statusErr *githubStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.code
+	}
+	return 0
+}
+
+// get reads a GitHub API resource into v.
+func (s *githubServiceImpl) get(ctx context.Context, token, endpoint, accept string, v interface{}) error {
+	return s.call(ctx, token, http.MethodGet, endpoint, accept, nil, v)
+}
+
+// call sends body, unless nil, as JSON to a GitHub API endpoint and reads the
+// response into v.
+func (s *githubServiceImpl) call(ctx context.Context, token, method, endpoint, accept string, body, v interface{}) error {
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errGitHubUnauthorized
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return &githubStatusError{code: resp.StatusCode, status: resp.Status}
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// exchangeCode trades an authorization code for an access token and the scopes
+// it was granted.
+func (s *githubServiceImpl) exchangeCode(ctx context.Context, code string) (string, []string, error) {
+	form := url.Values{"client_id": {s.clientID}, "client_secret": {s.clientSecret}, "code": {code}, "redirect_uri": {s.redirectURL}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, githubURL+"/login/oauth/access_token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		Scope            string `json:"scope"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", nil, fmt.Errorf("unreadable token response: %w", err)
+	}
+	if body.AccessToken == "" {
+		if body.ErrorDescription != "" {
+			return "", nil, errors.New(body.ErrorDescription)
+		}
+		return "", nil, fmt.Errorf("github did not issue a token (%s)", body.Error)
+	}
+	var scopes []string
+	for _, scope := range strings.Split(body.Scope, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return body.AccessToken, scopes, nil
+}
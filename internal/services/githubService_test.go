@@ -0,0 +1,377 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+// fakeGitHub is a GitHubRepository holding one connection and its sync lease.
+type fakeGitHub struct {
+	repositories.GitHubRepository
+	conn       models.GitHubConnection
+	leaseUntil time.Time
+	recorded   []string
+}
+
+func (f *fakeGitHub) FindByUser(ctx context.Context, userID primitive.ObjectID) (*models.GitHubConnection, error) {
+	if userID != f.conn.UserID {
+		return nil, mongo.ErrNoDocuments
+	}
+	conn := f.conn
+	return &conn, nil
+}
+
+func (f *fakeGitHub) FindConnected(ctx context.Context) ([]models.GitHubConnection, error) {
+	return []models.GitHubConnection{f.conn}, nil
+}
+
+func (f *fakeGitHub) ClaimSync(ctx context.Context, userID primitive.ObjectID, now, until time.Time) (bool, error) {
+	if now.Before(f.leaseUntil) {
+		return false, nil
+	}
+	f.leaseUntil = until
+	return true, nil
+}
+
+func (f *fakeGitHub) RecordSync(ctx context.Context, userID primitive.ObjectID, now time.Time, result *models.GitHubSyncResult, syncErr string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	f.leaseUntil = time.Time{}
+	f.recorded = append(f.recorded, syncErr)
+	return nil
+}
+
+// fakeBookmarks is a BookmarkRepository over a list of bookmarks. FindOne only
+// understands the filter of findExistingBookmark.
+type fakeBookmarks struct {
+	repositories.BookmarkRepository
+	bookmarks []*models.Bookmark
+	updates   []primitive.ObjectID
+}
+
+func (f *fakeBookmarks) FindOne(ctx context.Context, filter bson.M) (*models.Bookmark, error) {
+	or := filter["$or"].(bson.A)
+	canonical := or[0].(bson.M)["canonical_url"]
+	url := or[1].(bson.M)["url"]
+	for _, bm := range f.bookmarks {
+		if bm.CanonicalURL == canonical || bm.URL == url {
+			return bm, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (f *fakeBookmarks) Create(ctx context.Context, bm *models.Bookmark) (*models.Bookmark, error) {
+	f.bookmarks = append(f.bookmarks, bm)
+	return bm, nil
+}
+
+func (f *fakeBookmarks) UpdateOne(ctx context.Context, filter bson.M, update bson.M) (*mongo.UpdateResult, error) {
+	f.updates = append(f.updates, filter["_id"].(primitive.ObjectID))
+	return &mongo.UpdateResult{MatchedCount: 1, ModifiedCount: 1}, nil
+}
+
+type fakeCollections struct {
+	repositories.CollectionRepository
+	collections []models.Collection
+}
+
+func (f *fakeCollections) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Collection, error) {
+	return f.collections, nil
+}
+
+// fakeTags resolves every tag name to the same ID each time.
+type fakeTags struct {
+	TagSuggestionService
+	ids map[string]primitive.ObjectID
+}
+
+func (f *fakeTags) ResolveTags(ctx context.Context, userID primitive.ObjectID, names []string) ([]primitive.ObjectID, error) {
+	ids := make([]primitive.ObjectID, len(names))
+	for i, name := range names {
+		if _, ok := f.ids[name]; !ok {
+			f.ids[name] = primitive.NewObjectID()
+		}
+		ids[i] = f.ids[name]
+	}
+	return ids, nil
+}
+
+// plainEncryption "decrypts" by returning the ciphertext.
+type plainEncryption struct{ EncryptionService }
+
+func (plainEncryption) Decrypt(ctx context.Context, userID primitive.ObjectID, ciphertext string) (string, error) {
+	return ciphertext, nil
+}
+
+// offlineURLs fails the test when a URL is canonicalized with redirects.
+type offlineURLs struct{ t *testing.T }
+
+func (u offlineURLs) Canonicalize(ctx context.Context, rawURL string) (string, error) {
+	u.t.Errorf("Canonicalize(%q) makes a request per URL", rawURL)
+	return utils.CanonicalizeURL(rawURL, utils.DefaultURLRules)
+}
+
+func (u offlineURLs) CanonicalizeOffline(rawURL string) (string, error) {
+	return utils.CanonicalizeURL(rawURL, utils.DefaultURLRules)
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// starsAPI serves the stars as a single page of the starred repositories API and
+// counts the requests it gets.
+func starsAPI(t *testing.T, stars []githubStar, requests *int) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		*requests++
+		if r.URL.Path != "/user/starred" || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		body, _ := json.Marshal(stars)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(string(body))), Request: r}, nil
+	})}
+}
+
+func githubStarFor(fullName, language string) githubStar {
+	var star githubStar
+	star.StarredAt = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	star.Repo.FullName = fullName
+	star.Repo.HTMLURL = "https://github.com/" + fullName
+	star.Repo.Language = language
+	return star
+}
+
+func newTestGitHubService(t *testing.T, repo *fakeGitHub, bookmarks *fakeBookmarks, collectionID primitive.ObjectID, client *http.Client) *githubServiceImpl {
+	return &githubServiceImpl{
+		repo:           repo,
+		bookmarkRepo:   bookmarks,
+		collectionRepo: &fakeCollections{collections: []models.Collection{{ID: collectionID, Name: models.GitHubStarsCollection}}},
+		tags:           &fakeTags{ids: map[string]primitive.ObjectID{}},
+		encryption:     plainEncryption{},
+		urls:           offlineURLs{t},
+		client:         client,
+		clientID:       "id",
+		clientSecret:   "secret",
+		redirectURL:    "https://markly.example.com/api/integrations/github/callback",
+	}
+}
+
+func TestGitHubSyncImportsStars(t *testing.T) {
+	userID, collectionID := primitive.NewObjectID(), primitive.NewObjectID()
+	saved := &models.Bookmark{ID: primitive.NewObjectID(), UserID: userID, URL: "https://github.com/golang/go"}
+	bookmarks := &fakeBookmarks{bookmarks: []*models.Bookmark{saved}}
+	repo := &fakeGitHub{conn: models.GitHubConnection{UserID: userID, EncryptedToken: "token", Connected: true}}
+	requests := 0
+	stars := []githubStar{githubStarFor("golang/go", "Go"), githubStarFor("rust-lang/rust", "Rust"), githubStarFor("gohugoio/hugo", "Go")}
+	s := newTestGitHubService(t, repo, bookmarks, collectionID, starsAPI(t, stars, &requests))
+
+	result, err := s.Sync(context.Background(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Starred != 3 || result.Created != 2 || result.Existing != 1 {
+		t.Errorf("result = %+v", result)
+	}
+	if len(bookmarks.updates) != 1 || bookmarks.updates[0] != saved.ID {
+		t.Errorf("updated %v, want the saved bookmark added to the collection", bookmarks.updates)
+	}
+	rust, hugo := bookmarks.bookmarks[1], bookmarks.bookmarks[2]
+	if rust.Title != "rust-lang/rust" || rust.CanonicalURL != "https://github.com/rust-lang/rust" || len(rust.CollectionsID) != 1 || rust.CollectionsID[0] != collectionID {
+		t.Errorf("imported bookmark = %+v", rust)
+	}
+	if len(hugo.TagsID) != 1 || len(rust.TagsID) != 1 || hugo.TagsID[0] == rust.TagsID[0] {
+		t.Errorf("language tags = %v and %v", hugo.TagsID, rust.TagsID)
+	}
+	if len(repo.recorded) != 1 || repo.recorded[0] != "" || !repo.leaseUntil.IsZero() {
+		t.Errorf("recorded %q, lease %v; want the sync recorded and its lease released", repo.recorded, repo.leaseUntil)
+	}
+
+	// A second sync finds every star saved.
+	result, err = s.Sync(context.Background(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != 0 || result.Existing != 3 || len(bookmarks.bookmarks) != 3 {
+		t.Errorf("second sync = %+v with %d bookmarks", result, len(bookmarks.bookmarks))
+	}
+}
+
+func TestGitHubSyncWaitsForRunningSync(t *testing.T) {
+	userID := primitive.NewObjectID()
+	repo := &fakeGitHub{
+		conn:       models.GitHubConnection{UserID: userID, EncryptedToken: "token", Connected: true},
+		leaseUntil: time.Now().Add(time.Minute),
+	}
+	requests := 0
+	s := newTestGitHubService(t, repo, &fakeBookmarks{}, primitive.NewObjectID(), starsAPI(t, []githubStar{githubStarFor("golang/go", "Go")}, &requests))
+
+	if _, err := s.Sync(context.Background(), userID); !errors.Is(err, errGitHubSyncRunning) {
+		t.Errorf("Sync during another sync = %v, want %v", err, errGitHubSyncRunning)
+	}
+	if err := s.SyncAll(context.Background()); err != nil {
+		t.Errorf("SyncAll counted the running sync as failed: %v", err)
+	}
+	if requests != 0 || len(repo.recorded) != 0 {
+		t.Errorf("made %d requests and recorded %v while another sync held the lease", requests, repo.recorded)
+	}
+
+	// An expired lease is taken over.
+	repo.leaseUntil = time.Now().Add(-time.Second)
+	if _, err := s.Sync(context.Background(), userID); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 {
+		t.Errorf("requests = %d, want 1", requests)
+	}
+}
+
+func TestGitHubSyncReleasesLeaseOfCanceledRequest(t *testing.T) {
+	userID := primitive.NewObjectID()
+	repo := &fakeGitHub{conn: models.GitHubConnection{UserID: userID, EncryptedToken: "token", Connected: true}}
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		cancel()
+		return nil, context.Canceled
+	})}
+	s := newTestGitHubService(t, repo, &fakeBookmarks{}, primitive.NewObjectID(), client)
+
+	if _, err := s.Sync(ctx, userID); err == nil {
+		t.Fatal("canceled sync succeeded")
+	}
+	if len(repo.recorded) != 1 || !repo.leaseUntil.IsZero() {
+		t.Errorf("recorded %q, lease %v; want the failure recorded and the lease released", repo.recorded, repo.leaseUntil)
+	}
+}
+
+func TestGitHubCallbackChecksBrowserState(t *testing.T) {
+	s := newTestGitHubService(t, &fakeGitHub{}, &fakeBookmarks{}, primitive.NewObjectID(), nil)
+	for _, browserState := range []string{"", "other-state"} {
+		_, err := s.Callback(context.Background(), "code", "state", browserState)
+		if err == nil || !strings.HasPrefix(err.Error(), "invalid authorization") {
+			t.Errorf("Callback with browser state %q = %v, want an invalid authorization", browserState, err)
+		}
+	}
+}
+
+// markdownExports exports the same notes for every user.
+type markdownExports struct {
+	ExportService
+	files []models.MarkdownFile
+}
+
+func (e markdownExports) ExportMarkdown(ctx context.Context, userID primitive.ObjectID) ([]models.MarkdownFile, error) {
+	return e.files, nil
+}
+
+// gitDataAPI serves a repository whose main branch is at commit "base", and
+// records the requests that change it. moved makes the branch move before the
+// push updates it.
+func gitDataAPI(t *testing.T, requests map[string]map[string]interface{}, moved bool) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		respond := func(status int, body string) (*http.Response, error) {
+			return &http.Response{StatusCode: status, Status: http.StatusText(status), Body: io.NopCloser(strings.NewReader(body)), Request: r}, nil
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		if r.Body != nil {
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			requests[r.Method+" "+r.URL.Path] = body
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/ada/notes":
+			return respond(http.StatusOK, `{"default_branch": "main", "permissions": {"push": true}}`)
+		case "GET /repos/ada/notes/git/ref/heads/main":
+			return respond(http.StatusOK, `{"object": {"sha": "base"}}`)
+		case "GET /repos/ada/notes/git/commits/base":
+			return respond(http.StatusOK, `{"tree": {"sha": "base-tree"}}`)
+		case "POST /repos/ada/notes/git/trees":
+			return respond(http.StatusCreated, `{"sha": "tree"}`)
+		case "POST /repos/ada/notes/git/commits":
+			return respond(http.StatusCreated, `{"sha": "export", "html_url": "https://github.com/ada/notes/commit/export"}`)
+		case "PATCH /repos/ada/notes/git/refs/heads/main":
+			if moved {
+				return respond(http.StatusUnprocessableEntity, `{"message": "Update is not a fast forward"}`)
+			}
+			return respond(http.StatusOK, `{"object": {"sha": "export"}}`)
+		}
+		return respond(http.StatusNotFound, `{"message": "Not Found"}`)
+	})}
+}
+
+func TestGitHubPushMarkdown(t *testing.T) {
+	userID := primitive.NewObjectID()
+	repo := &fakeGitHub{conn: models.GitHubConnection{UserID: userID, EncryptedToken: "token", Connected: true, Scopes: []string{"read:user", models.GitHubPushScope}}}
+	requests := map[string]map[string]interface{}{}
+	s := newTestGitHubService(t, repo, &fakeBookmarks{}, primitive.NewObjectID(), gitDataAPI(t, requests, false))
+	s.exports = markdownExports{files: []models.MarkdownFile{{Name: "go-docs.md", Content: []byte("# Go docs\n")}, {Name: "go-docs-2.md", Content: []byte("# Go docs\n")}}}
+
+	result, err := s.PushMarkdown(context.Background(), userID, models.MarkdownPushRequest{Repository: "ada/notes", Directory: "/reading/markly/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Branch != "main" || result.Commit != "export" || result.Files != 2 {
+		t.Errorf("result = %+v", result)
+	}
+	tree := requests["POST /repos/ada/notes/git/trees"]
+	entries, _ := tree["tree"].([]interface{})
+	if tree["base_tree"] != "base-tree" || len(entries) != 2 || entries[0].(map[string]interface{})["path"] != "reading/markly/go-docs.md" {
+		t.Errorf("tree = %v, want the notes added under reading/markly to the branch's tree", tree)
+	}
+	if parents := requests["POST /repos/ada/notes/git/commits"]["parents"]; !reflect.DeepEqual(parents, []interface{}{"base"}) {
+		t.Errorf("commit parents = %v, want the branch head", parents)
+	}
+	if ref := requests["PATCH /repos/ada/notes/git/refs/heads/main"]; ref["sha"] != "export" || ref["force"] != false {
+		t.Errorf("ref update = %v, want a fast forward to the export", ref)
+	}
+
+	// A branch that moved meanwhile is not overwritten.
+	s.client = gitDataAPI(t, map[string]map[string]interface{}{}, true)
+	if _, err := s.PushMarkdown(context.Background(), userID, models.MarkdownPushRequest{Repository: "ada/notes"}); err == nil || !strings.Contains(err.Error(), "conflicted") {
+		t.Errorf("push to a moved branch = %v, want a conflict", err)
+	}
+}
+
+func TestGitHubPushMarkdownChecksRequest(t *testing.T) {
+	userID := primitive.NewObjectID()
+	repo := &fakeGitHub{conn: models.GitHubConnection{UserID: userID, EncryptedToken: "token", Connected: true, Scopes: []string{"read:user"}}}
+	requests := 0
+	s := newTestGitHubService(t, repo, &fakeBookmarks{}, primitive.NewObjectID(), starsAPI(t, nil, &requests))
+	s.exports = markdownExports{files: []models.MarkdownFile{{Name: "go-docs.md"}}}
+
+	if _, err := s.PushMarkdown(context.Background(), userID, models.MarkdownPushRequest{Repository: "ada/notes"}); !errors.Is(err, ErrGitHubPushNotAllowed) {
+		t.Errorf("push without the repo scope = %v, want %v", err, ErrGitHubPushNotAllowed)
+	}
+	repo.conn.Scopes = append(repo.conn.Scopes, models.GitHubPushScope)
+	for _, req := range []models.MarkdownPushRequest{
+		{Repository: "notes"},
+		{Repository: "ada/notes?ref=x"},
+		{Repository: "ada/notes", Branch: "../main"},
+		{Repository: "ada/notes", Directory: "notes/../../etc"},
+	} {
+		if _, err := s.PushMarkdown(context.Background(), userID, req); err == nil || !strings.HasPrefix(err.Error(), "invalid") {
+			t.Errorf("PushMarkdown(%+v) = %v, want it rejected", req, err)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("made %d requests for rejected pushes", requests)
+	}
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+)
+
+const (
+	maxHighlightTextLength    = 5000
+	maxHighlightContextLength = 500
+)
+
+type HighlightService interface {
+	AddHighlight(ctx context.Context, userID, bookmarkID primitive.ObjectID, req models.CreateHighlightRequest) (*models.Highlight, error)
+	GetHighlights(ctx context.Context, userID, bookmarkID primitive.ObjectID) ([]models.Highlight, error)
+	UpdateHighlight(ctx context.Context, userID, bookmarkID, highlightID primitive.ObjectID, updatePayload models.HighlightUpdate) (*models.Highlight, error)
+	DeleteHighlight(ctx context.Context, userID, bookmarkID, highlightID primitive.ObjectID) error
+	// AttachHighlights sets Highlights on each bookmark with a single query.
+	AttachHighlights(ctx context.Context, userID primitive.ObjectID, bookmarks []*models.Bookmark) error
+	DeleteForBookmark(ctx context.Context, userID, bookmarkID primitive.ObjectID) error
+	MoveToBookmark(ctx context.Context, userID, fromBookmarkID, toBookmarkID primitive.ObjectID) error
+}
+
+type highlightServiceImpl struct {
+	highlightRepo repositories.HighlightRepository
+	bookmarkRepo  repositories.BookmarkRepository
+	ownership     *Ownership
+}
+
+func NewHighlightService(highlightRepo repositories.HighlightRepository, bookmarkRepo repositories.BookmarkRepository, ownership *Ownership) HighlightService {
+	return &highlightServiceImpl{highlightRepo: highlightRepo, bookmarkRepo: bookmarkRepo, ownership: ownership}
+}
+
+func (s *highlightServiceImpl) ensureBookmark(ctx context.Context, userID, bookmarkID primitive.ObjectID) error {
+	if _, err := s.bookmarkRepo.FindOne(ctx, bson.M{"_id": bookmarkID, "user_id": userID}); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return s.ownership.Missing(ctx, userID, ResourceBookmark, bookmarkID, fmt.Errorf("bookmark not found"))
+		}
+		log.Error().Err(err).Str("bookmarkID", bookmarkID.Hex()).Msg("Error finding bookmark for highlight")
+		return fmt.Errorf("failed to retrieve bookmark")
+	}
+	return nil
+}
+
+func (s *highlightServiceImpl) AddHighlight(ctx context.Context, userID, bookmarkID primitive.ObjectID, req models.CreateHighlightRequest) (*models.Highlight, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Msg("Attempting to add highlight")
+	text := strings.TrimSpace(req.Text)
+	if text == "" {
+		return nil, fmt.Errorf("highlight text is required")
+	}
+	if len(text) > maxHighlightTextLength {
+		return nil, fmt.Errorf("invalid highlight: text is longer than %d characters", maxHighlightTextLength)
+	}
+	if len(req.Prefix) > maxHighlightContextLength || len(req.Suffix) > maxHighlightContextLength {
+		return nil, fmt.Errorf("invalid highlight: prefix and suffix must be at most %d characters", maxHighlightContextLength)
+	}
+	color := req.Color
+	if color == "" {
+		color = models.DefaultHighlightColor
+	}
+	if !models.IsValidHighlightColor(color) {
+		return nil, fmt.Errorf("invalid highlight color: %s", color)
+	}
+
+	if err := s.ensureBookmark(ctx, userID, bookmarkID); err != nil {
+		return nil, err
+	}
+
+	highlight := &models.Highlight{
+		ID:         primitive.NewObjectID(),
+		BookmarkID: bookmarkID,
+		UserID:     userID,
+		Text:       text,
+		Prefix:     req.Prefix,
+		Suffix:     req.Suffix,
+		Color:      color,
+		CreatedAt:  time.Now(),
+	}
+	if _, err := s.highlightRepo.Create(ctx, highlight); err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Msg("Error creating highlight")
+		return nil, fmt.Errorf("failed to add highlight")
+	}
+	log.Info().Str("userID", userID.Hex()).Str("highlightID", highlight.ID.Hex()).Msg("Highlight added successfully")
+	return highlight, nil
+}
+
+func (s *highlightServiceImpl) GetHighlights(ctx context.Context, userID, bookmarkID primitive.ObjectID) ([]models.Highlight, error) {
+	if err := s.ensureBookmark(ctx, userID, bookmarkID); err != nil {
+		return nil, err
+	}
+	highlights, err := s.highlightRepo.FindByBookmarks(ctx, userID, []primitive.ObjectID{bookmarkID})
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Msg("Error finding highlights")
+		return nil, fmt.Errorf("failed to retrieve highlights")
+	}
+	if highlights == nil {
+		highlights = []models.Highlight{}
+	}
+	return highlights, nil
+}
+
+func (s *highlightServiceImpl) UpdateHighlight(ctx context.Context, userID, bookmarkID, highlightID primitive.ObjectID, updatePayload models.HighlightUpdate) (*models.Highlight, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("highlightID", highlightID.Hex()).Msg("Attempting to update highlight")
+	if updatePayload.Color == nil {
+		return nil, fmt.Errorf("no valid fields provided for update")
+	}
+	if !models.IsValidHighlightColor(*updatePayload.Color) {
+		return nil, fmt.Errorf("invalid highlight color: %s", *updatePayload.Color)
+	}
+
+	highlight, err := s.highlightRepo.FindByID(ctx, userID, highlightID)
+	if err != nil || highlight.BookmarkID != bookmarkID {
+		if err == nil || err == mongo.ErrNoDocuments {
+			return nil, s.ownership.Missing(ctx, userID, ResourceHighlight, highlightID, fmt.Errorf("highlight not found"))
+		}
+		return nil, fmt.Errorf("failed to retrieve highlight")
+	}
+
+	if _, err := s.highlightRepo.Update(ctx, userID, highlightID, bson.M{"color": *updatePayload.Color}); err != nil {
+		log.Error().Err(err).Str("highlightID", highlightID.Hex()).Msg("Error updating highlight")
+		return nil, fmt.Errorf("failed to update highlight")
+	}
+	highlight.Color = *updatePayload.Color
+	return highlight, nil
+}
+
+func (s *highlightServiceImpl) DeleteHighlight(ctx context.Context, userID, bookmarkID, highlightID primitive.ObjectID) error {
+	result, err := s.highlightRepo.Delete(ctx, userID, bookmarkID, highlightID)
+	if err != nil {
+		log.Error().Err(err).Str("highlightID", highlightID.Hex()).Msg("Error deleting highlight")
+		return fmt.Errorf("failed to delete highlight")
+	}
+	if result.DeletedCount == 0 {
+		return s.ownership.Missing(ctx, userID, ResourceHighlight, highlightID, fmt.Errorf("highlight not found"))
+	}
+	log.Info().Str("userID", userID.Hex()).Str("highlightID", highlightID.Hex()).Msg("Highlight deleted successfully")
+	return nil
+}
+
+func (s *highlightServiceImpl) AttachHighlights(ctx context.Context, userID primitive.ObjectID, bookmarks []*models.Bookmark) error {
+	if len(bookmarks) == 0 {
+		return nil
+	}
+	ids := make([]primitive.ObjectID, len(bookmarks))
+	for i, bm := range bookmarks {
+		ids[i] = bm.ID
+	}
+	highlights, err := s.highlightRepo.FindByBookmarks(ctx, userID, ids)
+	if err != nil {
+		return err
+	}
+	byBookmark := make(map[primitive.ObjectID][]models.Highlight)
+	for _, h := range highlights {
+		byBookmark[h.BookmarkID] = append(byBookmark[h.BookmarkID], h)
+	}
+	for _, bm := range bookmarks {
+		bm.Highlights = byBookmark[bm.ID]
+	}
+	return nil
+}
+
+func (s *highlightServiceImpl) DeleteForBookmark(ctx context.Context, userID, bookmarkID primitive.ObjectID) error {
+	return s.highlightRepo.DeleteByBookmark(ctx, userID, bookmarkID)
+}
+
+func (s *highlightServiceImpl) MoveToBookmark(ctx context.Context, userID, fromBookmarkID, toBookmarkID primitive.ObjectID) error {
+	return s.highlightRepo.MoveToBookmark(ctx, userID, fromBookmarkID, toBookmarkID)
+}
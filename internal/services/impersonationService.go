@@ -0,0 +1,351 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+const (
+	defaultImpersonationMinutes = 30
+	maxImpersonationMinutes     = 120
+	// impersonationApprovalWindow is how long the user has to answer a request.
+	impersonationApprovalWindow = 24 * time.Hour
+)
+
+// ImpersonationService runs the support flow in which an admin acts as a user. The
+// admin requests access, the user approves it through a link sent by email, and the
+// admin then gets a read-only token that expires after the approved duration. Every
+// step and every request made with the token is written to the audit log.
+type ImpersonationService interface {
+	Request(ctx context.Context, adminID primitive.ObjectID, req models.CreateImpersonationRequest, ip string) (*models.Impersonation, error)
+	List(ctx context.Context, userID *primitive.ObjectID) ([]models.Impersonation, error)
+	IssueToken(ctx context.Context, adminID, id primitive.ObjectID, ip string) (*models.ImpersonationToken, error)
+	Approve(ctx context.Context, token, ip string) (*models.Impersonation, error)
+	Deny(ctx context.Context, token, ip string) (*models.Impersonation, error)
+	ListForUser(ctx context.Context, userID primitive.ObjectID) ([]models.Impersonation, error)
+	Revoke(ctx context.Context, userID, id primitive.ObjectID, ip string) (*models.Impersonation, error)
+	Authorize(ctx context.Context, claims *utils.Claims, r *http.Request) error
+	// Verify checks that the impersonation of an impersonation token is still
+	// active, without applying the route restrictions of Authorize.
+	Verify(ctx context.Context, claims *utils.Claims) (*models.Impersonation, error)
+}
+
+type impersonationServiceImpl struct {
+	impersonationRepo repositories.ImpersonationRepository
+	userRepo          repositories.UserRepository
+	notifier          Notifier
+	auditService      AuditService
+	// approvalURL is IMPERSONATION_APPROVAL_URL, the frontend page that approves
+	// or denies a request. The token is appended as the "token" query parameter.
+	approvalURL string
+}
+
+func NewImpersonationService(impersonationRepo repositories.ImpersonationRepository, userRepo repositories.UserRepository, notifier Notifier, auditService AuditService) ImpersonationService {
+	return &impersonationServiceImpl{
+		impersonationRepo: impersonationRepo,
+		userRepo:          userRepo,
+		notifier:          notifier,
+		auditService:      auditService,
+		approvalURL:       os.Getenv("IMPERSONATION_APPROVAL_URL"),
+	}
+}
+
+func (s *impersonationServiceImpl) Request(ctx context.Context, adminID primitive.ObjectID, req models.CreateImpersonationRequest, ip string) (*models.Impersonation, error) {
+	log.Debug().Str("userID", adminID.Hex()).Str("targetUserID", req.UserID).Msg("Attempting to request impersonation")
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, fmt.Errorf("reason is required")
+	}
+	userID, err := primitive.ObjectIDFromHex(req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user_id format")
+	}
+	if userID == adminID {
+		return nil, fmt.Errorf("invalid user_id: cannot impersonate yourself")
+	}
+	duration := req.DurationMinutes
+	if duration == 0 {
+		duration = defaultImpersonationMinutes
+	}
+	if duration < 1 || duration > maxImpersonationMinutes {
+		return nil, fmt.Errorf("invalid duration_minutes: must be between 1 and %d", maxImpersonationMinutes)
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to retrieve user")
+	}
+	admin, err := s.userRepo.FindByID(ctx, adminID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve admin")
+	}
+
+	token, err := utils.GenerateToken()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate impersonation approval token")
+		return nil, fmt.Errorf("failed to generate approval token")
+	}
+	now := time.Now()
+	imp := &models.Impersonation{
+		ID:              primitive.NewObjectID(),
+		AdminID:         adminID,
+		UserID:          userID,
+		Reason:          reason,
+		Status:          models.ImpersonationPending,
+		DurationMinutes: duration,
+		ApprovalHash:    utils.HashAPIKey(token),
+		ApproveBy:       now.Add(impersonationApprovalWindow),
+		CreatedAt:       now,
+	}
+	if err := s.impersonationRepo.Create(ctx, imp); err != nil {
+		return nil, err
+	}
+	if err := s.audit(ctx, models.AuditImpersonationRequested, adminID, imp, ip, reason); err != nil {
+		return nil, err
+	}
+
+	if err := s.notifier.Notify(ctx, user, "Support access request", s.approvalEmail(admin, imp, token)); err != nil {
+		log.Error().Err(err).Str("impersonationID", imp.ID.Hex()).Msg("Failed to send impersonation approval email")
+		return nil, fmt.Errorf("failed to send approval email")
+	}
+
+	log.Info().Str("userID", adminID.Hex()).Str("targetUserID", userID.Hex()).Str("impersonationID", imp.ID.Hex()).Msg("Impersonation requested")
+	return imp, nil
+}
+
+func (s *impersonationServiceImpl) approvalEmail(admin *models.User, imp *models.Impersonation, token string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<p>%s from Markly support asked to access your account for %d minutes, read-only.</p>", html.EscapeString(admin.Username), imp.DurationMinutes)
+	fmt.Fprintf(&b, "<p>Reason: %s</p>", html.EscapeString(imp.Reason))
+	if s.approvalURL != "" {
+		link := s.approvalURL + "?token=" + url.QueryEscape(token)
+		fmt.Fprintf(&b, `<p><a href="%s">Review the request</a></p>`, html.EscapeString(link))
+	} else {
+		fmt.Fprintf(&b, "<p>Your approval code is: %s</p>", token)
+	}
+	fmt.Fprintf(&b, "<p>The request lapses on %s if you do nothing. You can revoke access at any time from your account.</p>", imp.ApproveBy.UTC().Format(time.RFC1123))
+	return b.String()
+}
+
+// List returns the impersonations of all admins, optionally only those of userID.
+func (s *impersonationServiceImpl) List(ctx context.Context, userID *primitive.ObjectID) ([]models.Impersonation, error) {
+	filter := bson.M{}
+	if userID != nil {
+		filter["user_id"] = *userID
+	}
+	return s.find(ctx, filter)
+}
+
+func (s *impersonationServiceImpl) ListForUser(ctx context.Context, userID primitive.ObjectID) ([]models.Impersonation, error) {
+	return s.find(ctx, bson.M{"user_id": userID})
+}
+
+func (s *impersonationServiceImpl) find(ctx context.Context, filter bson.M) ([]models.Impersonation, error) {
+	imps, err := s.impersonationRepo.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if imps == nil {
+		imps = []models.Impersonation{}
+	}
+	return imps, nil
+}
+
+// IssueToken returns a read-only token for an approved impersonation requested by
+// adminID. It expires with the impersonation.
+func (s *impersonationServiceImpl) IssueToken(ctx context.Context, adminID, id primitive.ObjectID, ip string) (*models.ImpersonationToken, error) {
+	imp, err := s.impersonationRepo.FindOne(ctx, bson.M{"_id": id, "admin_id": adminID})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("impersonation not found")
+		}
+		return nil, fmt.Errorf("failed to retrieve impersonation")
+	}
+	if !imp.Active(time.Now()) {
+		return nil, fmt.Errorf("impersonation is not active (status %s)", imp.Status)
+	}
+
+	token, err := utils.GenerateImpersonationJWT(imp.UserID, adminID, imp.ID, *imp.ExpiresAt)
+	if err != nil {
+		log.Error().Err(err).Str("impersonationID", id.Hex()).Msg("Failed to sign impersonation token")
+		return nil, fmt.Errorf("failed to generate token")
+	}
+	if err := s.audit(ctx, models.AuditImpersonationTokenIssued, adminID, imp, ip, ""); err != nil {
+		return nil, err
+	}
+	return &models.ImpersonationToken{Token: token, ExpiresAt: *imp.ExpiresAt}, nil
+}
+
+// Approve starts the approved duration of the pending request the token was sent for.
+func (s *impersonationServiceImpl) Approve(ctx context.Context, token, ip string) (*models.Impersonation, error) {
+	imp, err := s.pendingByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	expiresAt := now.Add(time.Duration(imp.DurationMinutes) * time.Minute)
+	imp, err = s.transition(ctx, bson.M{"_id": imp.ID, "status": models.ImpersonationPending},
+		bson.M{"status": models.ImpersonationApproved, "approved_at": now, "expires_at": expiresAt})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.audit(ctx, models.AuditImpersonationApproved, imp.UserID, imp, ip, ""); err != nil {
+		return nil, err
+	}
+	log.Info().Str("userID", imp.UserID.Hex()).Str("impersonationID", imp.ID.Hex()).Msg("Impersonation approved")
+	return imp, nil
+}
+
+func (s *impersonationServiceImpl) Deny(ctx context.Context, token, ip string) (*models.Impersonation, error) {
+	imp, err := s.pendingByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	imp, err = s.transition(ctx, bson.M{"_id": imp.ID, "status": models.ImpersonationPending},
+		bson.M{"status": models.ImpersonationDenied})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.audit(ctx, models.AuditImpersonationDenied, imp.UserID, imp, ip, ""); err != nil {
+		return nil, err
+	}
+	log.Info().Str("userID", imp.UserID.Hex()).Str("impersonationID", imp.ID.Hex()).Msg("Impersonation denied")
+	return imp, nil
+}
+
+func (s *impersonationServiceImpl) pendingByToken(ctx context.Context, token string) (*models.Impersonation, error) {
+	if token == "" {
+		return nil, fmt.Errorf("token is required")
+	}
+	imp, err := s.impersonationRepo.FindOne(ctx, bson.M{"approval_hash": utils.HashAPIKey(token)})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("invalid or expired approval token")
+		}
+		return nil, fmt.Errorf("failed to retrieve impersonation")
+	}
+	if imp.Status != models.ImpersonationPending || time.Now().After(imp.ApproveBy) {
+		return nil, fmt.Errorf("invalid or expired approval token")
+	}
+	return imp, nil
+}
+
+// Revoke ends a pending or approved impersonation of the user. Tokens already issued
+// stop working on their next request.
+func (s *impersonationServiceImpl) Revoke(ctx context.Context, userID, id primitive.ObjectID, ip string) (*models.Impersonation, error) {
+	filter := bson.M{
+		"_id":     id,
+		"user_id": userID,
+		"status":  bson.M{"$in": []string{models.ImpersonationPending, models.ImpersonationApproved}},
+	}
+	imp, err := s.transition(ctx, filter, bson.M{"status": models.ImpersonationRevoked, "revoked_at": time.Now()})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.audit(ctx, models.AuditImpersonationRevoked, userID, imp, ip, ""); err != nil {
+		return nil, err
+	}
+	log.Info().Str("userID", userID.Hex()).Str("impersonationID", id.Hex()).Msg("Impersonation revoked")
+	return imp, nil
+}
+
+func (s *impersonationServiceImpl) transition(ctx context.Context, filter, fields bson.M) (*models.Impersonation, error) {
+	imp, err := s.impersonationRepo.Transition(ctx, filter, fields)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("impersonation not found or no longer active")
+		}
+		log.Error().Err(err).Msg("Failed to update impersonation")
+		return nil, fmt.Errorf("failed to update impersonation")
+	}
+	return imp, nil
+}
+
+// Verify returns the impersonation of a token's claims while it is still active.
+func (s *impersonationServiceImpl) Verify(ctx context.Context, claims *utils.Claims) (*models.Impersonation, error) {
+	id, err := primitive.ObjectIDFromHex(claims.ImpersonationID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid impersonation token")
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid impersonation token")
+	}
+	adminID, err := primitive.ObjectIDFromHex(claims.ImpersonatorID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid impersonation token")
+	}
+
+	imp, err := s.impersonationRepo.FindOne(ctx, bson.M{"_id": id, "user_id": userID, "admin_id": adminID})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("invalid impersonation token")
+		}
+		return nil, fmt.Errorf("failed to verify impersonation")
+	}
+	if !imp.Active(time.Now()) {
+		return nil, fmt.Errorf("impersonation expired or revoked")
+	}
+	return imp, nil
+}
+
+// Authorize checks a request made with an impersonation token. The impersonation
+// must still be approved and unexpired, the request must be read-only and outside
+// the admin endpoints, and it must be recorded in the audit log before it is served.
+func (s *impersonationServiceImpl) Authorize(ctx context.Context, claims *utils.Claims, r *http.Request) error {
+	imp, err := s.Verify(ctx, claims)
+	if err != nil {
+		return err
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		return fmt.Errorf("impersonation tokens are read-only")
+	}
+	if strings.HasPrefix(r.URL.Path, "/api/admin") {
+		return fmt.Errorf("impersonation tokens cannot access admin endpoints")
+	}
+
+	event := models.AuditEvent{
+		Action:          models.AuditImpersonatedRequest,
+		ActorID:         imp.AdminID,
+		UserID:          imp.UserID,
+		ImpersonationID: &imp.ID,
+		Method:          r.Method,
+		Path:            r.URL.Path,
+		IP:              utils.ClientIP(r),
+	}
+	if err := s.auditService.Record(ctx, event); err != nil {
+		return fmt.Errorf("failed to verify impersonation: %w", err)
+	}
+	return nil
+}
+
+func (s *impersonationServiceImpl) audit(ctx context.Context, action string, actorID primitive.ObjectID, imp *models.Impersonation, ip, details string) error {
+	return s.auditService.Record(ctx, models.AuditEvent{
+		Action:          action,
+		ActorID:         actorID,
+		UserID:          imp.UserID,
+		ImpersonationID: &imp.ID,
+		IP:              ip,
+		Details:         details,
+	})
+}
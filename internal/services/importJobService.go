@@ -0,0 +1,460 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+const (
+	// maxImportJobItems bounds the items saved by one import; sources list the
+	// newest items first, so the oldest ones are left out.
+	maxImportJobItems = 10000
+	// maxImportJobErrors bounds the item errors kept on a job.
+	maxImportJobErrors = 100
+	// hackerNewsFavoritePages bounds the favorites pages, of 30 stories each, read
+	// by one import. Pages are read a second apart.
+	hackerNewsFavoritePages = 50
+	// importJobSaveEvery is how many items are imported between progress updates.
+	importJobSaveEvery = 25
+	// importJobStaleAfter is how long a queued or running job may go without an
+	// update before it is taken for one whose server stopped.
+	importJobStaleAfter  = 10 * time.Minute
+	importJobTimeout     = time.Hour
+	defaultImportJobList = 20
+	maxImportJobList     = 100
+)
+
+// errImportJobTaken stops a job that was failed as interrupted while it ran, so
+// that its outcome does not overwrite the failure.
+var errImportJobTaken = errors.New("the import job is no longer active")
+
+// hackerNewsUsername matches the account names Hacker News allows.
+var hackerNewsUsername = regexp.MustCompile(`^[A-Za-z0-9_-]{2,15}$`)
+
+// ImportJobService imports bookmarks saved on other sites in the background, one
+// job per import. Reddit posts are tagged with their subreddit, other items with
+// the tags mapped to their domain, and bookmarks the user already has are left
+// unchanged, so an import can safely be run again.
+type ImportJobService interface {
+	// ImportHackerNews queues an import of the favorites of a Hacker News account.
+	ImportHackerNews(ctx context.Context, userID primitive.ObjectID, username string) (*models.ImportJob, error)
+	// ImportReddit queues an import of the saved posts and comments in a Reddit
+	// export, which is read before the job is queued.
+	ImportReddit(ctx context.Context, userID primitive.ObjectID, r io.Reader) (*models.ImportJob, error)
+	GetJob(ctx context.Context, userID, jobID primitive.ObjectID) (*models.ImportJob, error)
+	// ListJobs returns the user's newest jobs, running and finished.
+	ListJobs(ctx context.Context, userID primitive.ObjectID, limit int) ([]models.ImportJob, error)
+	QueueDepth() models.QueueDepth
+	// FailInterrupted fails the jobs left queued or running by a server that
+	// stopped. It is run by the scheduler.
+	FailInterrupted(ctx context.Context) error
+}
+
+type importJobServiceImpl struct {
+	repo         repositories.ImportJobRepository
+	bookmarkRepo repositories.BookmarkRepository
+	tagRepo      repositories.TagRepository
+	userRepo     repositories.UserRepository
+	urls         URLService
+	client       *http.Client
+	// slots bounds the jobs running at once; the others wait queued.
+	slots chan struct{}
+}
+
+func NewImportJobService(repo repositories.ImportJobRepository, bookmarkRepo repositories.BookmarkRepository, tagRepo repositories.TagRepository, userRepo repositories.UserRepository, urls URLService) ImportJobService {
+	return &importJobServiceImpl{
+		repo:         repo,
+		bookmarkRepo: bookmarkRepo,
+		tagRepo:      tagRepo,
+		userRepo:     userRepo,
+		urls:         urls,
+		client:       &http.Client{Timeout: 15 * time.Second},
+		slots:        make(chan struct{}, 2),
+	}
+}
+
+// importSource reads the items of a job once it runs, newest first.
+type importSource func(ctx context.Context, job *models.ImportJob) ([]importItem, error)
+
+func (s *importJobServiceImpl) ImportHackerNews(ctx context.Context, userID primitive.ObjectID, username string) (*models.ImportJob, error) {
+	username = strings.TrimSpace(username)
+	if !hackerNewsUsername.MatchString(username) {
+		return nil, fmt.Errorf("invalid username: expected a Hacker News account name")
+	}
+	return s.start(ctx, userID, models.ImportSourceHackerNews, func(ctx context.Context, job *models.ImportJob) ([]importItem, error) {
+		return s.readHackerNews(ctx, job, username)
+	})
+}
+
+func (s *importJobServiceImpl) ImportReddit(ctx context.Context, userID primitive.ObjectID, r io.Reader) (*models.ImportJob, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reddit export: %w", err)
+	}
+	items, err := parseRedditExport(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("invalid reddit export: no saved posts or comments found")
+	}
+	return s.start(ctx, userID, models.ImportSourceReddit, func(context.Context, *models.ImportJob) ([]importItem, error) {
+		return items, nil
+	})
+}
+
+func (s *importJobServiceImpl) GetJob(ctx context.Context, userID, jobID primitive.ObjectID) (*models.ImportJob, error) {
+	job, err := s.repo.FindByID(ctx, userID, jobID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("import job not found")
+		}
+		log.Error().Err(err).Str("jobID", jobID.Hex()).Msg("Failed to load import job")
+		return nil, fmt.Errorf("failed to retrieve import job")
+	}
+	setImportJobDuration(job, time.Now())
+	return job, nil
+}
+
+func (s *importJobServiceImpl) ListJobs(ctx context.Context, userID primitive.ObjectID, limit int) ([]models.ImportJob, error) {
+	if limit <= 0 {
+		limit = defaultImportJobList
+	}
+	if limit > maxImportJobList {
+		return nil, fmt.Errorf("invalid limit: at most %d imports can be listed", maxImportJobList)
+	}
+	jobs, err := s.repo.FindByUser(ctx, userID, int64(limit))
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to list import jobs")
+		return nil, fmt.Errorf("failed to retrieve imports")
+	}
+	if jobs == nil {
+		jobs = []models.ImportJob{}
+	}
+	now := time.Now()
+	for i := range jobs {
+		setImportJobDuration(&jobs[i], now)
+	}
+	return jobs, nil
+}
+
+// setImportJobDuration sets how long job has run by now. Queued jobs have not
+// run yet.
+func setImportJobDuration(job *models.ImportJob, now time.Time) {
+	if job.StartedAt == nil {
+		return
+	}
+	end := now
+	if job.FinishedAt != nil {
+		end = *job.FinishedAt
+	}
+	if d := end.Sub(*job.StartedAt); d > 0 {
+		job.DurationSeconds = d.Seconds()
+	}
+}
+
+// QueueDepth reports the import jobs running.
+func (s *importJobServiceImpl) QueueDepth() models.QueueDepth {
+	return models.QueueDepth{InFlight: len(s.slots), Capacity: cap(s.slots)}
+}
+
+func (s *importJobServiceImpl) FailInterrupted(ctx context.Context) error {
+	now := time.Now().UTC()
+	n, err := s.repo.FailStale(ctx, now.Add(-importJobStaleAfter), now, "the import was interrupted, start it again")
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Warn().Int64("jobs", n).Msg("Failed interrupted import jobs")
+	}
+	return nil
+}
+
+// start records a queued job and runs it in the background. A user runs one
+// import at a time.
+func (s *importJobServiceImpl) start(ctx context.Context, userID primitive.ObjectID, source string, read importSource) (*models.ImportJob, error) {
+	now := time.Now().UTC()
+	job := &models.ImportJob{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Source:    source,
+		Status:    models.ImportJobQueued,
+		Errors:    []models.ImportItemError{},
+		CreatedAt: now,
+		UpdatedAt: now,
+		Active:    true,
+	}
+	if err := s.repo.Create(ctx, job); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("an import is already running, wait for it to finish")
+		}
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to create import job")
+		return nil, fmt.Errorf("failed to start import")
+	}
+	log.Info().Str("userID", userID.Hex()).Str("jobID", job.ID.Hex()).Str("source", source).Msg("Import queued")
+
+	running := *job
+	go s.run(&running, read)
+	return job, nil
+}
+
+func (s *importJobServiceImpl) run(job *models.ImportJob, read importSource) {
+	ctx, cancel := context.WithTimeout(context.Background(), importJobTimeout)
+	defer cancel()
+
+	err := s.acquire(ctx, job)
+	if err == nil {
+		defer func() { <-s.slots }()
+		started := time.Now().UTC()
+		job.StartedAt = &started
+		job.Status = models.ImportJobRunning
+		if err = s.save(ctx, job); err == nil {
+			err = s.importAll(ctx, job, read)
+		}
+	}
+	if errors.Is(err, errImportJobTaken) {
+		log.Warn().Str("userID", job.UserID.Hex()).Str("jobID", job.ID.Hex()).Msg("Import stopped: it was failed as interrupted")
+		return
+	}
+
+	finished := time.Now().UTC()
+	job.FinishedAt = &finished
+	job.Active = false
+	if err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("the import took too long and was stopped")
+		}
+		job.Status = models.ImportJobFailed
+		job.Error = err.Error()
+		log.Warn().Err(err).Str("userID", job.UserID.Hex()).Str("jobID", job.ID.Hex()).Msg("Import failed")
+	} else {
+		job.Status = models.ImportJobCompleted
+		log.Info().Str("userID", job.UserID.Hex()).Str("jobID", job.ID.Hex()).Int("created", job.Bookmarks.Created).Int("failed", job.Failed).Msg("Import completed")
+	}
+	// The job context may have expired; the outcome is saved regardless.
+	saveCtx, saveCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer saveCancel()
+	s.save(saveCtx, job)
+}
+
+// acquire waits for a free slot, refreshing the queued job meanwhile so that it
+// is not taken for an interrupted one.
+func (s *importJobServiceImpl) acquire(ctx context.Context, job *models.ImportJob) error {
+	heartbeat := time.NewTicker(time.Minute)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case s.slots <- struct{}{}:
+			return nil
+		case <-heartbeat.C:
+			if err := s.save(ctx, job); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// save stores the progress of job. It returns errImportJobTaken once the stored
+// job is no longer active; other failures are only logged: the job carries on
+// and its next update may succeed.
+func (s *importJobServiceImpl) save(ctx context.Context, job *models.ImportJob) error {
+	job.UpdatedAt = time.Now().UTC()
+	if err := s.repo.Save(ctx, job); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return errImportJobTaken
+		}
+		log.Error().Err(err).Str("jobID", job.ID.Hex()).Msg("Failed to save import job progress")
+	}
+	return nil
+}
+
+// importAll saves the items of the source oldest first, so that the newest item
+// becomes the newest bookmark.
+func (s *importJobServiceImpl) importAll(ctx context.Context, job *models.ImportJob, read importSource) error {
+	items, err := read(ctx, job)
+	if err != nil {
+		return err
+	}
+	if len(items) > maxImportJobItems {
+		items = items[:maxImportJobItems]
+		job.Truncated = true
+	}
+	job.Total = len(items)
+
+	imp := &jobImporter{
+		bookmarkImporter: newBookmarkImporter(s.bookmarkRepo, s.tagRepo, s.urls, job.UserID, job.Source, &job.Tags, &job.Bookmarks),
+		s:                s,
+		job:              job,
+	}
+	if err := imp.load(ctx); err != nil {
+		return err
+	}
+	for i := len(items) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		itemErr, err := imp.importItem(ctx, items[i])
+		if err != nil {
+			return err
+		}
+		if itemErr != nil {
+			imp.fail(i+1, items[i].URL, itemErr.Error())
+		}
+		job.Processed++
+		if job.Processed%importJobSaveEvery == 0 {
+			if err := s.save(ctx, job); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readHackerNews reads the favorite stories of username, page by page.
+func (s *importJobServiceImpl) readHackerNews(ctx context.Context, job *models.ImportJob, username string) ([]importItem, error) {
+	var items []importItem
+	next := hackerNewsURL + "/favorites?id=" + url.QueryEscape(username)
+	for page := 1; next != ""; page++ {
+		if page > hackerNewsFavoritePages {
+			job.Truncated = true
+			break
+		}
+		if page > 1 {
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		batch, more, err := s.fetchHackerNewsPage(ctx, next)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, batch...)
+		next = more
+		job.Total = len(items)
+		if err := s.save(ctx, job); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+func (s *importJobServiceImpl) fetchHackerNewsPage(ctx context.Context, pageURL string) ([]importItem, string, error) {
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", "MarklyBot/1.0 (+https://markly.app)")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read hacker news favorites: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to read hacker news favorites: hacker news responded with %s", resp.Status)
+	}
+	return parseHackerNewsFavorites(io.LimitReader(resp.Body, 4<<20), page)
+}
+
+// importItemTags returns the tag names of item: its subreddit, or the tags
+// mapped to its domain.
+func importItemTags(item importItem, settings *models.UserSettings) []string {
+	if item.Subreddit != "" {
+		return []string{strings.ToLower(item.Subreddit)}
+	}
+	return domainTagNames(utils.URLHost(item.URL), settings)
+}
+
+// jobImporter holds the state of one running job.
+type jobImporter struct {
+	*bookmarkImporter
+	s        *importJobServiceImpl
+	job      *models.ImportJob
+	settings *models.UserSettings
+}
+
+func (imp *jobImporter) load(ctx context.Context) error {
+	userID := imp.job.UserID
+	user, err := imp.s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to load user settings for import")
+		return fmt.Errorf("failed to fetch user settings")
+	}
+	imp.settings = &models.UserSettings{}
+	if user.Settings != nil {
+		imp.settings = user.Settings
+	}
+	return imp.loadTags(ctx)
+}
+
+func (imp *jobImporter) fail(item int, rawURL, msg string) {
+	job := imp.job
+	job.Failed++
+	if len(job.Errors) < maxImportJobErrors {
+		job.Errors = append(job.Errors, models.ImportItemError{Item: item, URL: rawURL, Error: msg})
+	} else {
+		job.ErrorsTruncated = true
+	}
+}
+
+// importItem creates the bookmark of one item. itemErr rejects the item; err
+// fails the job.
+func (imp *jobImporter) importItem(ctx context.Context, item importItem) (itemErr error, err error) {
+	normalized, err := utils.NormalizeURL(item.URL)
+	if err != nil {
+		return err, nil
+	}
+	tagNames := importItemTags(item, imp.settings)
+	if err := checkTagLimit(len(tagNames)); err != nil {
+		return err, nil
+	}
+	canonical, exists, err := imp.find(ctx, normalized)
+	if err != nil || exists {
+		return nil, err
+	}
+
+	bm := &models.Bookmark{
+		ID:           primitive.NewObjectID(),
+		UserID:       imp.userID,
+		URL:          normalized,
+		OriginalURL:  originalURL(item.URL, normalized),
+		CanonicalURL: canonical,
+		Title:        item.Title,
+		CreatedAt:    primitive.NewDateTimeFromTime(time.Now()),
+	}
+	if bm.Title == "" {
+		bm.Title = normalized
+	}
+	for _, name := range tagNames {
+		id, err := imp.tag(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if !containsObjectID(bm.TagsID, id) {
+			bm.TagsID = append(bm.TagsID, id)
+		}
+	}
+	return nil, imp.create(ctx, bm)
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+)
+
+// fakeImportJobs is an ImportJobRepository holding one user's jobs. Failing a
+// job, as FailInterrupted does, makes its saves match nothing.
+type fakeImportJobs struct {
+	repositories.ImportJobRepository
+	active bool
+	failed bool
+	saved  []models.ImportJob
+}
+
+func (f *fakeImportJobs) Create(ctx context.Context, job *models.ImportJob) error {
+	if f.active {
+		return slugConflict("user_active_unique")
+	}
+	f.active = job.Active
+	return nil
+}
+
+func (f *fakeImportJobs) Save(ctx context.Context, job *models.ImportJob) error {
+	if f.failed {
+		return mongo.ErrNoDocuments
+	}
+	f.active = job.Active
+	f.saved = append(f.saved, *job)
+	return nil
+}
+
+func newTestImportJobService(repo *fakeImportJobs, bookmarks *fakeBookmarks) *importJobServiceImpl {
+	return &importJobServiceImpl{
+		repo:         repo,
+		bookmarkRepo: bookmarks,
+		tagRepo:      &fakeTagRepo{},
+		userRepo:     fakeNewsletterUsers{},
+		urls:         offlineURLs{},
+		slots:        make(chan struct{}, 1),
+	}
+}
+
+func TestImportJobStopsOnceFailedElsewhere(t *testing.T) {
+	repo := &fakeImportJobs{}
+	bookmarks := &fakeBookmarks{}
+	s := newTestImportJobService(repo, bookmarks)
+	job := &models.ImportJob{ID: primitive.NewObjectID(), UserID: primitive.NewObjectID(), Source: models.ImportSourceReddit, Status: models.ImportJobQueued, Active: true}
+
+	s.run(job, func(context.Context, *models.ImportJob) ([]importItem, error) {
+		// Another server fails the job as interrupted once it is read.
+		repo.failed = true
+		items := make([]importItem, 2*importJobSaveEvery)
+		for i := range items {
+			items[i] = importItem{URL: fmt.Sprintf("https://example.com/%d", i)}
+		}
+		return items, nil
+	})
+	if len(bookmarks.bookmarks) != importJobSaveEvery {
+		t.Errorf("imported %d items, want the job stopped at its first save", len(bookmarks.bookmarks))
+	}
+	for _, saved := range repo.saved {
+		if saved.Status != models.ImportJobRunning {
+			t.Errorf("saved a %s job over the failure", saved.Status)
+		}
+	}
+}
+
+func TestImportJobStartRefusesSecondImport(t *testing.T) {
+	repo := &fakeImportJobs{active: true}
+	s := newTestImportJobService(repo, &fakeBookmarks{})
+	_, err := s.ImportReddit(context.Background(), primitive.NewObjectID(), strings.NewReader(`{"data":{"children":[{"kind":"t3","data":{"url":"https://go.dev/","title":"Go","subreddit":"golang"}}]}}`))
+	if err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("ImportReddit during another import = %v, want it refused", err)
+	}
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	hackerNewsURL = "https://news.ycombinator.com"
+	redditURL     = "https://www.reddit.com"
+)
+
+// errHackerNewsUnknownUser is returned for a favorites page of an account that does
+// not exist.
+var errHackerNewsUnknownUser = errors.New("hacker news user not found")
+
+// importItem is a bookmark read from an import source. Subreddit is set for Reddit
+// posts; other items are tagged by their domain.
+type importItem struct {
+	URL       string
+	Title     string
+	Subreddit string
+}
+
+// parseHackerNewsFavorites reads the stories of a Hacker News favorites page and
+// the address of the next page, or "" on the last one. Links to discussions, such
+// as Ask HN posts, are resolved against page.
+func parseHackerNewsFavorites(r io.Reader, page *url.URL) ([]importItem, string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, "", fmt.Errorf("unreadable favorites page: %w", err)
+	}
+	var items []importItem
+	var next string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch {
+			case n.DataAtom == atom.Tr && hasClass(n, "athing"):
+				if link := findElement(n, func(c *html.Node) bool { return c.DataAtom == atom.Span && hasClass(c, "titleline") }); link != nil {
+					if a := findElement(link, func(c *html.Node) bool { return c.DataAtom == atom.A }); a != nil {
+						if u, err := page.Parse(htmlAttr(a, "href")); err == nil {
+							items = append(items, importItem{URL: u.String(), Title: strings.TrimSpace(nodeText(a))})
+						}
+					}
+				}
+				return
+			case n.DataAtom == atom.A && hasClass(n, "morelink"):
+				if u, err := page.Parse(htmlAttr(n, "href")); err == nil {
+					next = u.String()
+				}
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	if len(items) == 0 && strings.Contains(nodeText(doc), "No such user.") {
+		return nil, "", errHackerNewsUnknownUser
+	}
+	return items, next, nil
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(htmlAttr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func htmlAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// findElement returns the first element below n that matches, depth first.
+func findElement(n *html.Node, match func(*html.Node) bool) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && match(c) {
+			return c
+		}
+		if found := findElement(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+// redditListing is a page of the Reddit API, such as /user/{name}/saved.json.
+type redditListing struct {
+	Data struct {
+		Children []struct {
+			Kind string `json:"kind"`
+			Data struct {
+				Title     string `json:"title"`
+				URL       string `json:"url"`
+				IsSelf    bool   `json:"is_self"`
+				Permalink string `json:"permalink"`
+				Subreddit string `json:"subreddit"`
+				LinkTitle string `json:"link_title"`
+			} `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+// parseRedditExport reads the saved posts and comments of a Reddit account from
+// either the saved.json listing of the Reddit API, one listing or an array of
+// them, or the saved_posts.csv and saved_comments.csv files of a Reddit data
+// export. Link posts are saved under their link; self posts and comments under
+// their permalink.
+func parseRedditExport(data []byte) ([]importItem, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("\ufeff"))
+	if len(data) == 0 {
+		return nil, fmt.Errorf("invalid reddit export: the file is empty")
+	}
+	if data[0] != '{' && data[0] != '[' {
+		return parseRedditCSV(data)
+	}
+
+	var listings []redditListing
+	if data[0] == '{' {
+		var listing redditListing
+		if err := json.Unmarshal(data, &listing); err != nil {
+			return nil, fmt.Errorf("invalid reddit export: %v", err)
+		}
+		listings = []redditListing{listing}
+	} else if err := json.Unmarshal(data, &listings); err != nil {
+		return nil, fmt.Errorf("invalid reddit export: %v", err)
+	}
+	var items []importItem
+	for _, listing := range listings {
+		for _, child := range listing.Data.Children {
+			thing := child.Data
+			item := importItem{URL: redditPermalink(thing.Permalink), Title: thing.Title, Subreddit: thing.Subreddit}
+			switch child.Kind {
+			case "t3":
+				if !thing.IsSelf && thing.URL != "" {
+					item.URL = thing.URL
+				}
+			case "t1":
+				item.Title = thing.LinkTitle
+			default:
+				continue
+			}
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// parseRedditCSV reads a saved_posts.csv or saved_comments.csv file, whose rows
+// hold an ID and a permalink. The subreddit and title come from the permalink.
+func parseRedditCSV(data []byte) ([]importItem, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid reddit export: %v", err)
+	}
+	col := -1
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), "permalink") {
+			col = i
+			break
+		}
+	}
+	if col < 0 {
+		return nil, fmt.Errorf("invalid reddit export: expected saved.json or a csv file with a permalink column")
+	}
+
+	var items []importItem
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return items, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid reddit export: %v", err)
+		}
+		if col >= len(record) || strings.TrimSpace(record[col]) == "" {
+			continue
+		}
+		link := redditPermalink(strings.TrimSpace(record[col]))
+		subreddit, title := parseRedditPermalink(link)
+		items = append(items, importItem{URL: link, Title: title, Subreddit: subreddit})
+	}
+}
+
+// redditPermalink makes a permalink of the Reddit API absolute.
+func redditPermalink(permalink string) string {
+	if strings.HasPrefix(permalink, "/") {
+		return redditURL + permalink
+	}
+	return permalink
+}
+
+// parseRedditPermalink returns the subreddit of a permalink such as
+// /r/golang/comments/abc123/some_title/ and the title spelled by its slug.
+func parseRedditPermalink(link string) (subreddit, title string) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", ""
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) >= 2 && parts[0] == "r" {
+		subreddit = parts[1]
+	}
+	for i := 0; i+2 < len(parts); i++ {
+		if parts[i] == "comments" {
+			title = strings.TrimSpace(strings.ReplaceAll(parts[i+2], "_", " "))
+			break
+		}
+	}
+	return subreddit, title
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"markly/internal/models"
+)
+
+func TestParseHackerNewsFavorites(t *testing.T) {
+	page, _ := url.Parse("https://news.ycombinator.com/favorites?id=pg")
+	body := `<table>
+<tr class="athing submission" id="1"><td class="title"><span class="titleline"><a href="https://go.dev/blog">Go <b>blog</b></a><span class="sitebit comhead"> (<a href="from?site=go.dev"><span class="sitestr">go.dev</span></a>)</span></span></td></tr>
+<tr><td class="subtext"><a href="user?id=rsc">rsc</a></td></tr>
+<tr class="athing" id="2"><td class="title"><span class="titleline"><a href="item?id=2">Ask HN: Tabs?</a></span></td></tr>
+</table><a href="favorites?id=pg&amp;p=2" class="morelink" rel="next">More</a>`
+	items, next, err := parseHackerNewsFavorites(strings.NewReader(body), page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []importItem{
+		{URL: "https://go.dev/blog", Title: "Go blog"},
+		{URL: "https://news.ycombinator.com/item?id=2", Title: "Ask HN: Tabs?"},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("items = %+v, want %+v", items, want)
+	}
+	if next != "https://news.ycombinator.com/favorites?id=pg&p=2" {
+		t.Errorf("next = %q", next)
+	}
+
+	if _, _, err := parseHackerNewsFavorites(strings.NewReader("<body>No such user.</body>"), page); err != errHackerNewsUnknownUser {
+		t.Errorf("unknown user error = %v", err)
+	}
+	if items, next, err := parseHackerNewsFavorites(strings.NewReader("<table></table>"), page); err != nil || len(items) != 0 || next != "" {
+		t.Errorf("empty page = %v, %q, %v", items, next, err)
+	}
+}
+
+func TestParseRedditExport(t *testing.T) {
+	listing := `{"kind": "Listing", "data": {"children": [
+		{"kind": "t3", "data": {"title": "Go 1.24", "url": "https://go.dev/doc/go1.24", "subreddit": "golang", "permalink": "/r/golang/comments/a1/go_124/"}},
+		{"kind": "t3", "data": {"title": "Tabs or spaces?", "url": "https://www.reddit.com/r/golang/comments/a2/tabs/", "is_self": true, "subreddit": "golang", "permalink": "/r/golang/comments/a2/tabs/"}},
+		{"kind": "t1", "data": {"link_title": "Rust 2024", "subreddit": "rust", "permalink": "/r/rust/comments/a3/rust_2024/c1/"}},
+		{"kind": "more", "data": {}}
+	]}}`
+	want := []importItem{
+		{URL: "https://go.dev/doc/go1.24", Title: "Go 1.24", Subreddit: "golang"},
+		{URL: "https://www.reddit.com/r/golang/comments/a2/tabs/", Title: "Tabs or spaces?", Subreddit: "golang"},
+		{URL: "https://www.reddit.com/r/rust/comments/a3/rust_2024/c1/", Title: "Rust 2024", Subreddit: "rust"},
+	}
+	items, err := parseRedditExport([]byte(listing))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("listing items = %+v, want %+v", items, want)
+	}
+	if items, err := parseRedditExport([]byte("[" + listing + "," + listing + "]")); err != nil || len(items) != 6 {
+		t.Errorf("array of listings = %d items, %v", len(items), err)
+	}
+
+	csvExport := "\ufeffid,permalink\na1,https://www.reddit.com/r/golang/comments/a1/go_124_released/\na2,\na3,/user/someone/comments/a3/my_post/\n"
+	items, err = parseRedditExport([]byte(csvExport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []importItem{
+		{URL: "https://www.reddit.com/r/golang/comments/a1/go_124_released/", Title: "go 124 released", Subreddit: "golang"},
+		{URL: "https://www.reddit.com/user/someone/comments/a3/my_post/", Title: "my post"},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("csv items = %+v, want %+v", items, want)
+	}
+
+	for _, bad := range []string{"", "id,url\n1,https://example.com\n", "{not json"} {
+		if _, err := parseRedditExport([]byte(bad)); err == nil || !strings.HasPrefix(err.Error(), "invalid") {
+			t.Errorf("parseRedditExport(%q) error = %v, want an invalid export", bad, err)
+		}
+	}
+}
+
+func TestImportItemTags(t *testing.T) {
+	settings := &models.UserSettings{}
+	if got := importItemTags(importItem{URL: "https://github.com/golang/go", Subreddit: "GoLang"}, settings); !reflect.DeepEqual(got, []string{"golang"}) {
+		t.Errorf("reddit tags = %v", got)
+	}
+	if got := importItemTags(importItem{URL: "https://www.youtube.com/watch?v=1"}, settings); !reflect.DeepEqual(got, []string{"video"}) {
+		t.Errorf("domain tags = %v", got)
+	}
+	if got := importItemTags(importItem{URL: "https://example.com"}, settings); len(got) != 0 {
+		t.Errorf("unmapped domain tags = %v", got)
+	}
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+)
+
+// ErrSignupNotAllowed is wrapped by errors rejecting a new account because of the
+// instance's signup settings.
+var ErrSignupNotAllowed = errors.New("signup not allowed")
+
+// ErrEmailDomainNotAllowed is wrapped by errors rejecting an existing account's
+// new email address because of the instance's allowed email domains.
+var ErrEmailDomainNotAllowed = errors.New("email domain not allowed")
+
+const maxDefaultPlanLength = 64
+
+// maxRetentionDays bounds the days of a retention target, about a century.
+const maxRetentionDays = 36500
+
+// InstanceService manages the deployment-wide settings and applies them to new
+// accounts, whether they register with a password or are provisioned on first
+// OAuth login.
+type InstanceService interface {
+	GetSettings(ctx context.Context) (*models.InstanceSettings, error)
+	UpdateSettings(ctx context.Context, adminID primitive.ObjectID, update models.InstanceSettingsUpdate, ip string) (*models.InstanceSettings, error)
+	// AdmitSignup checks that user may create an account and fills in the fields
+	// new accounts get from the settings. A user with an InviteCode uses up the
+	// invite and is attributed to its owner.
+	AdmitSignup(ctx context.Context, user *models.User) error
+	// AbortSignup undoes AdmitSignup for an account that could not be created.
+	AbortSignup(ctx context.Context, user *models.User)
+	// CheckEmailChange checks that an account may change its email address to
+	// email, which has to be of an allowed domain like the email of new accounts.
+	CheckEmailChange(ctx context.Context, email string) error
+}
+
+type instanceServiceImpl struct {
+	settingsRepo repositories.InstanceSettingsRepository
+	invites      InviteService
+	auditService AuditService
+}
+
+func NewInstanceService(settingsRepo repositories.InstanceSettingsRepository, invites InviteService, auditService AuditService) InstanceService {
+	return &instanceServiceImpl{settingsRepo: settingsRepo, invites: invites, auditService: auditService}
+}
+
+func (s *instanceServiceImpl) GetSettings(ctx context.Context) (*models.InstanceSettings, error) {
+	settings, err := s.settingsRepo.Get(ctx)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return &models.InstanceSettings{SignupMode: models.SignupOpen, AllowedEmailDomains: []string{}, Retention: models.RetentionPolicy{Days: map[string]int{}}}, nil
+		}
+		log.Error().Err(err).Msg("Failed to fetch instance settings")
+		return nil, fmt.Errorf("failed to fetch instance settings")
+	}
+	if settings.SignupMode == "" {
+		settings.SignupMode = models.SignupOpen
+	}
+	if settings.AllowedEmailDomains == nil {
+		settings.AllowedEmailDomains = []string{}
+	}
+	days := map[string]int{}
+	for target, d := range settings.Retention.Days {
+		if d > 0 {
+			days[target] = d
+		}
+	}
+	settings.Retention.Days = days
+	return settings, nil
+}
+
+func (s *instanceServiceImpl) UpdateSettings(ctx context.Context, adminID primitive.ObjectID, update models.InstanceSettingsUpdate, ip string) (*models.InstanceSettings, error) {
+	log.Debug().Str("adminID", adminID.Hex()).Interface("update", update).Msg("Attempting to update instance settings")
+	updateFields := bson.M{}
+	if update.SignupMode != nil {
+		if !models.IsValidSignupMode(*update.SignupMode) {
+			return nil, fmt.Errorf("invalid signup_mode: must be %s, %s or %s", models.SignupOpen, models.SignupInviteOnly, models.SignupDisabled)
+		}
+		updateFields["signup_mode"] = *update.SignupMode
+	}
+	if update.AllowedEmailDomains != nil {
+		domains, err := normalizeEmailDomains(*update.AllowedEmailDomains)
+		if err != nil {
+			return nil, err
+		}
+		updateFields["allowed_email_domains"] = domains
+	}
+	if update.DefaultPlan != nil {
+		plan := strings.TrimSpace(*update.DefaultPlan)
+		if len(plan) > maxDefaultPlanLength {
+			return nil, fmt.Errorf("invalid default_plan: at most %d characters", maxDefaultPlanLength)
+		}
+		updateFields["default_plan"] = plan
+	}
+	if update.Retention != nil {
+		fields, err := retentionUpdateFields(*update.Retention)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range fields {
+			updateFields[k] = v
+		}
+	}
+	if len(updateFields) == 0 {
+		return nil, fmt.Errorf("no valid fields provided for update")
+	}
+	updateFields["updated_at"] = time.Now().UTC()
+	updateFields["updated_by"] = adminID
+
+	if _, err := s.settingsRepo.Update(ctx, updateFields); err != nil {
+		log.Error().Err(err).Str("adminID", adminID.Hex()).Msg("Failed to update instance settings")
+		return nil, fmt.Errorf("failed to update instance settings")
+	}
+	details, _ := json.Marshal(update)
+	if err := s.auditService.Record(ctx, models.AuditEvent{
+		Action:  models.AuditInstanceSettingsUpdated,
+		ActorID: adminID,
+		UserID:  adminID,
+		IP:      ip,
+		Details: string(details),
+	}); err != nil {
+		log.Error().Err(err).Str("adminID", adminID.Hex()).Msg("Failed to audit instance settings update")
+	}
+	log.Info().Str("adminID", adminID.Hex()).Msg("Instance settings updated")
+	return s.GetSettings(ctx)
+}
+
+func (s *instanceServiceImpl) AdmitSignup(ctx context.Context, user *models.User) error {
+	settings, err := s.GetSettings(ctx)
+	if err != nil {
+		return err
+	}
+	if settings.SignupMode == models.SignupDisabled {
+		return fmt.Errorf("%w: registration is disabled on this instance", ErrSignupNotAllowed)
+	}
+	if settings.SignupMode == models.SignupInviteOnly && user.InviteCode == "" {
+		return fmt.Errorf("%w: registration requires an invitation", ErrSignupNotAllowed)
+	}
+	if !emailDomainAllowed(user.Email, settings.AllowedEmailDomains) {
+		return fmt.Errorf("%w: email domain is not allowed on this instance", ErrSignupNotAllowed)
+	}
+
+	user.ReferredBy = nil
+	if user.InviteCode != "" {
+		invite, err := s.invites.Redeem(ctx, user.InviteCode)
+		if err != nil {
+			return err
+		}
+		user.InviteCode = invite.Code
+		user.ReferredBy = &invite.UserID
+	}
+	user.Plan = settings.DefaultPlan
+	return nil
+}
+
+func (s *instanceServiceImpl) AbortSignup(ctx context.Context, user *models.User) {
+	if user.ReferredBy != nil {
+		s.invites.Release(ctx, user.InviteCode)
+	}
+}
+
+func (s *instanceServiceImpl) CheckEmailChange(ctx context.Context, email string) error {
+	settings, err := s.GetSettings(ctx)
+	if err != nil {
+		return err
+	}
+	if !emailDomainAllowed(email, settings.AllowedEmailDomains) {
+		return fmt.Errorf("%w: email domain is not allowed on this instance", ErrEmailDomainNotAllowed)
+	}
+	return nil
+}
+
+// retentionUpdateFields returns the fields a retention policy update sets. Days
+// are set per target so that the targets an update leaves out keep theirs.
+func retentionUpdateFields(update models.RetentionPolicyUpdate) (bson.M, error) {
+	fields := bson.M{}
+	for target, days := range update.Days {
+		if !models.IsRetentionTarget(target) {
+			return nil, fmt.Errorf("invalid retention target %q: must be one of %s", target, strings.Join(models.RetentionTargets, ", "))
+		}
+		if days < 0 || days > maxRetentionDays {
+			return nil, fmt.Errorf("invalid retention days for %s: must be between 0 and %d", target, maxRetentionDays)
+		}
+		fields["retention.days."+target] = days
+	}
+	if update.DryRun != nil {
+		fields["retention.dry_run"] = *update.DryRun
+	}
+	return fields, nil
+}
+
+// normalizeEmailDomains lowercases domains, strips a leading "@" and drops
+// duplicates.
+func normalizeEmailDomains(domains []string) ([]string, error) {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, d := range domains {
+		d = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), "@")
+		if d == "" {
+			continue
+		}
+		if !strings.Contains(d, ".") || strings.ContainsAny(d, "@/ ") {
+			return nil, fmt.Errorf("invalid email domain: %s", d)
+		}
+		if !seen[d] {
+			seen[d] = true
+			normalized = append(normalized, d)
+		}
+	}
+	return normalized, nil
+}
+
+// emailDomainAllowed reports whether the domain of email is one of domains or a
+// subdomain of one. An empty list allows every domain.
+func emailDomainAllowed(email string, domains []string) bool {
+	if len(domains) == 0 {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(strings.TrimSpace(email[at+1:]))
+	for _, d := range domains {
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
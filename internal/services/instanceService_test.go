@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+)
+
+func TestNormalizeEmailDomains(t *testing.T) {
+	got, err := normalizeEmailDomains([]string{" Example.COM ", "@example.com", "", "corp.example.org"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"example.com", "corp.example.org"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("normalizeEmailDomains = %v, want %v", got, want)
+	}
+
+	for _, bad := range []string{"localhost", "user@example.com", "example.com/path"} {
+		if _, err := normalizeEmailDomains([]string{bad}); err == nil {
+			t.Errorf("normalizeEmailDomains accepted %q", bad)
+		}
+	}
+}
+
+func TestEmailDomainAllowed(t *testing.T) {
+	domains := []string{"example.com"}
+	cases := []struct {
+		email string
+		want  bool
+	}{
+		{"jane@example.com", true},
+		{"jane@EXAMPLE.com", true},
+		{"jane@eu.example.com", true},
+		{"jane@badexample.com", false},
+		{"jane@example.com.evil.org", false},
+		{"no-at-sign", false},
+	}
+	for _, c := range cases {
+		if got := emailDomainAllowed(c.email, domains); got != c.want {
+			t.Errorf("emailDomainAllowed(%q) = %v, want %v", c.email, got, c.want)
+		}
+	}
+	if !emailDomainAllowed("jane@anything.net", nil) {
+		t.Error("emailDomainAllowed rejected an email with no domains configured")
+	}
+}
+
+func TestRetentionUpdateFields(t *testing.T) {
+	dryRun := true
+	got, err := retentionUpdateFields(models.RetentionPolicyUpdate{Days: map[string]int{models.RetentionAuditEvents: 365, models.RetentionAIEvents: 0}, DryRun: &dryRun})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := bson.M{"retention.days.audit_events": 365, "retention.days.ai_events": 0, "retention.dry_run": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("retentionUpdateFields = %v, want %v", got, want)
+	}
+
+	for _, days := range []map[string]int{{"bookmarks": 30}, {models.RetentionAuditEvents: -1}, {models.RetentionAIEvents: maxRetentionDays + 1}} {
+		if _, err := retentionUpdateFields(models.RetentionPolicyUpdate{Days: days}); err == nil {
+			t.Errorf("retentionUpdateFields accepted %v", days)
+		}
+	}
+}
+
+type fakeInstanceSettings struct {
+	repositories.InstanceSettingsRepository
+	settings models.InstanceSettings
+}
+
+func (f *fakeInstanceSettings) Get(ctx context.Context) (*models.InstanceSettings, error) {
+	settings := f.settings
+	return &settings, nil
+}
+
+func TestCheckEmailChange(t *testing.T) {
+	s := &instanceServiceImpl{settingsRepo: &fakeInstanceSettings{settings: models.InstanceSettings{SignupMode: models.SignupDisabled, AllowedEmailDomains: []string{"example.com"}}}}
+	// Disabled signups do not stop existing accounts from changing their email.
+	if err := s.CheckEmailChange(context.Background(), "jane@eu.example.com"); err != nil {
+		t.Errorf("allowed domain: %v", err)
+	}
+	if err := s.CheckEmailChange(context.Background(), "jane@elsewhere.org"); !errors.Is(err, ErrEmailDomainNotAllowed) {
+		t.Errorf("other domain: err = %v, want ErrEmailDomainNotAllowed", err)
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+// IntrospectionService lets internal services check the JWTs they are handed
+// without sharing JWT_SECRET.
+type IntrospectionService interface {
+	// Introspect reports whether token is currently valid. callerSecret must match
+	// INTROSPECTION_SECRET.
+	Introspect(ctx context.Context, callerSecret, token string) (*models.TokenIntrospection, error)
+}
+
+type introspectionServiceImpl struct {
+	impersonations ImpersonationService
+	secret         string
+}
+
+// NewIntrospectionService reads INTROSPECTION_SECRET. Without it introspection is
+// disabled.
+func NewIntrospectionService(impersonations ImpersonationService) IntrospectionService {
+	return &introspectionServiceImpl{impersonations: impersonations, secret: os.Getenv("INTROSPECTION_SECRET")}
+}
+
+func (s *introspectionServiceImpl) Introspect(ctx context.Context, callerSecret, token string) (*models.TokenIntrospection, error) {
+	if s.secret == "" {
+		return nil, fmt.Errorf("token introspection is disabled")
+	}
+	if subtle.ConstantTimeCompare([]byte(callerSecret), []byte(s.secret)) != 1 {
+		return nil, fmt.Errorf("invalid introspection secret")
+	}
+
+	inactive := &models.TokenIntrospection{}
+	claims, err := utils.ParseJWT(token)
+	if err != nil {
+		return inactive, nil
+	}
+	if claims.ImpersonationID != "" {
+		if _, err := s.impersonations.Verify(ctx, claims); err != nil {
+			if err.Error() == "failed to verify impersonation" {
+				log.Error().Str("impersonationID", claims.ImpersonationID).Msg("Failed to verify impersonation during token introspection")
+				return nil, fmt.Errorf("failed to introspect token")
+			}
+			return inactive, nil
+		}
+	}
+
+	result := &models.TokenIntrospection{
+		Active:         true,
+		Subject:        claims.ID,
+		Audience:       claims.Client(),
+		Scope:          strings.Join(claims.Scopes(), " "),
+		ImpersonatedBy: claims.ImpersonatorID,
+	}
+	if claims.IssuedAt != nil {
+		result.IssuedAt = claims.IssuedAt.Unix()
+	}
+	if claims.ExpiresAt != nil {
+		result.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	return result, nil
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+const (
+	maxInvitesPerUser = 50
+	// maxOpenInviteUses bounds the accounts the unexpired codes of a regular user
+	// can still create together. Admins can create codes without limits.
+	maxOpenInviteUses = 10
+	inviteCodeLength  = 10
+	// inviteCodeAlphabet leaves out characters that are easily confused, such as
+	// 0 and O, so codes can be read out and typed.
+	inviteCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+// InviteService manages the invite codes users hand out and the accounts created
+// with them.
+type InviteService interface {
+	CreateInvite(ctx context.Context, userID primitive.ObjectID, req models.CreateInviteRequest) (*models.Invite, error)
+	GetInvites(ctx context.Context, userID primitive.ObjectID) (*models.InviteOverview, error)
+	DeleteInvite(ctx context.Context, userID, inviteID primitive.ObjectID) (bool, error)
+	// Redeem counts a use of code for a new account.
+	Redeem(ctx context.Context, code string) (*models.Invite, error)
+	// Release undoes Redeem when the account could not be created.
+	Release(ctx context.Context, code string)
+}
+
+type inviteServiceImpl struct {
+	inviteRepo repositories.InviteRepository
+	userRepo   repositories.UserRepository
+}
+
+func NewInviteService(inviteRepo repositories.InviteRepository, userRepo repositories.UserRepository) InviteService {
+	return &inviteServiceImpl{inviteRepo: inviteRepo, userRepo: userRepo}
+}
+
+func (s *inviteServiceImpl) CreateInvite(ctx context.Context, userID primitive.ObjectID, req models.CreateInviteRequest) (*models.Invite, error) {
+	log.Debug().Str("userID", userID.Hex()).Msg("Attempting to create invite")
+	if req.MaxUses < 0 {
+		return nil, fmt.Errorf("invalid max_uses: must not be negative")
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("invalid expiry: expires_at must be in the future")
+	}
+	count, err := s.inviteRepo.CountByUser(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to count invites")
+		return nil, fmt.Errorf("failed to create invite")
+	}
+	if count >= maxInvitesPerUser {
+		return nil, fmt.Errorf("%w: at most %d invite codes per user", utils.ErrLimitExceeded, maxInvitesPerUser)
+	}
+	maxUses, err := s.inviteUses(ctx, userID, req.MaxUses)
+	if err != nil {
+		return nil, err
+	}
+
+	// A collision on the unique code index is unlikely enough that retrying once is
+	// plenty.
+	for attempt := 0; ; attempt++ {
+		code, err := generateInviteCode()
+		if err != nil {
+			log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to generate invite code")
+			return nil, fmt.Errorf("failed to create invite")
+		}
+		invite := &models.Invite{
+			ID:        primitive.NewObjectID(),
+			UserID:    userID,
+			Code:      code,
+			MaxUses:   maxUses,
+			ExpiresAt: req.ExpiresAt,
+			CreatedAt: time.Now(),
+		}
+		created, err := s.inviteRepo.Create(ctx, invite)
+		if err == nil {
+			log.Info().Str("userID", userID.Hex()).Str("inviteID", created.ID.Hex()).Msg("Invite created successfully")
+			return created, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) || attempt > 0 {
+			log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to store invite")
+			return nil, fmt.Errorf("failed to create invite")
+		}
+	}
+}
+
+// inviteUses returns the uses of a new code of userID that asked for maxUses.
+// Only admins get unlimited codes; a regular user's code defaults to one use, and
+// their unexpired codes together create at most maxOpenInviteUses more accounts.
+func (s *inviteServiceImpl) inviteUses(ctx context.Context, userID primitive.ObjectID, maxUses int) (int, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to load user for invite")
+		return 0, fmt.Errorf("failed to create invite")
+	}
+	if user.Role == models.RoleAdmin {
+		return maxUses, nil
+	}
+	if maxUses == 0 {
+		maxUses = 1
+	}
+	invites, err := s.inviteRepo.FindByUser(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to retrieve invites")
+		return 0, fmt.Errorf("failed to create invite")
+	}
+	open := openInviteUses(invites, time.Now())
+	if open+maxUses > maxOpenInviteUses {
+		return 0, fmt.Errorf("%w: your invite codes can create at most %d more accounts, %d are left", utils.ErrLimitExceeded, maxOpenInviteUses, max(maxOpenInviteUses-open, 0))
+	}
+	return maxUses, nil
+}
+
+// openInviteUses counts the accounts the unexpired invites can still create.
+// Unlimited invites, which only admins create, count as maxOpenInviteUses.
+func openInviteUses(invites []models.Invite, now time.Time) int {
+	open := 0
+	for _, invite := range invites {
+		if invite.ExpiresAt != nil && !invite.ExpiresAt.After(now) {
+			continue
+		}
+		if invite.MaxUses == 0 {
+			open += maxOpenInviteUses
+		} else if invite.MaxUses > invite.Uses {
+			open += invite.MaxUses - invite.Uses
+		}
+	}
+	return open
+}
+
+func (s *inviteServiceImpl) GetInvites(ctx context.Context, userID primitive.ObjectID) (*models.InviteOverview, error) {
+	log.Debug().Str("userID", userID.Hex()).Msg("Attempting to retrieve invites")
+	invites, err := s.inviteRepo.FindByUser(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to retrieve invites")
+		return nil, fmt.Errorf("failed to retrieve invites")
+	}
+	referrals, err := s.userRepo.FindReferrals(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to retrieve referrals")
+		return nil, fmt.Errorf("failed to retrieve referrals")
+	}
+	return &models.InviteOverview{Invites: invites, Referrals: referrals}, nil
+}
+
+// DeleteInvite stops the code from being used. Accounts already created with it
+// stay attributed to the user.
+func (s *inviteServiceImpl) DeleteInvite(ctx context.Context, userID, inviteID primitive.ObjectID) (bool, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("inviteID", inviteID.Hex()).Msg("Attempting to delete invite")
+	result, err := s.inviteRepo.Delete(ctx, userID, inviteID)
+	if err != nil {
+		return false, err
+	}
+	if result.DeletedCount == 0 {
+		return false, fmt.Errorf("invite not found")
+	}
+	log.Info().Str("userID", userID.Hex()).Str("inviteID", inviteID.Hex()).Msg("Invite deleted successfully")
+	return true, nil
+}
+
+func (s *inviteServiceImpl) Redeem(ctx context.Context, code string) (*models.Invite, error) {
+	invite, err := s.inviteRepo.Redeem(ctx, normalizeInviteCode(code), time.Now())
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("invalid invite code: it does not exist, has expired or has been used up")
+		}
+		log.Error().Err(err).Msg("Failed to redeem invite code")
+		return nil, fmt.Errorf("failed to redeem invite code")
+	}
+	return invite, nil
+}
+
+func (s *inviteServiceImpl) Release(ctx context.Context, code string) {
+	if err := s.inviteRepo.Release(ctx, normalizeInviteCode(code)); err != nil {
+		log.Error().Err(err).Str("code", code).Msg("Failed to release invite code")
+	}
+}
+
+func generateInviteCode() (string, error) {
+	max := big.NewInt(int64(len(inviteCodeAlphabet)))
+	code := make([]byte, inviteCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = inviteCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// normalizeInviteCode accepts codes typed in lowercase or split up with spaces or
+// dashes.
+func normalizeInviteCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(code)))
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+func TestGenerateInviteCode(t *testing.T) {
+	code, err := generateInviteCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) != inviteCodeLength {
+		t.Errorf("generateInviteCode() = %q, want %d characters", code, inviteCodeLength)
+	}
+	for _, c := range code {
+		if !strings.ContainsRune(inviteCodeAlphabet, c) {
+			t.Errorf("generateInviteCode() = %q, contains %q", code, c)
+		}
+	}
+	if normalizeInviteCode(code) != code {
+		t.Errorf("normalizeInviteCode changed a generated code %q", code)
+	}
+}
+
+func TestNormalizeInviteCode(t *testing.T) {
+	for _, in := range []string{"ABCDE23456", " abcde-23456 ", "abc de 234 56"} {
+		if got := normalizeInviteCode(in); got != "ABCDE23456" {
+			t.Errorf("normalizeInviteCode(%q) = %q", in, got)
+		}
+	}
+}
+
+// fakeInvites is an InviteRepository over a list of invites.
+type fakeInvites struct {
+	repositories.InviteRepository
+	invites []models.Invite
+}
+
+func (f *fakeInvites) Create(ctx context.Context, invite *models.Invite) (*models.Invite, error) {
+	f.invites = append(f.invites, *invite)
+	return invite, nil
+}
+
+func (f *fakeInvites) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Invite, error) {
+	return f.invites, nil
+}
+
+func (f *fakeInvites) CountByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return int64(len(f.invites)), nil
+}
+
+type fakeInviteUsers struct {
+	repositories.UserRepository
+	role string
+}
+
+func (f fakeInviteUsers) FindByID(ctx context.Context, userID primitive.ObjectID) (*models.User, error) {
+	return &models.User{ID: userID, Role: f.role}, nil
+}
+
+func TestCreateInviteBoundsUsesOfRegularUsers(t *testing.T) {
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	invites := &fakeInvites{}
+	s := &inviteServiceImpl{inviteRepo: invites, userRepo: fakeInviteUsers{}}
+
+	invite, err := s.CreateInvite(ctx, userID, models.CreateInviteRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if invite.MaxUses != 1 {
+		t.Errorf("max_uses = %d, want 1 by default", invite.MaxUses)
+	}
+	if _, err := s.CreateInvite(ctx, userID, models.CreateInviteRequest{MaxUses: maxOpenInviteUses}); !errors.Is(err, utils.ErrLimitExceeded) {
+		t.Fatalf("err = %v, want the limit exceeded", err)
+	}
+	if _, err := s.CreateInvite(ctx, userID, models.CreateInviteRequest{MaxUses: maxOpenInviteUses - 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateInvite(ctx, userID, models.CreateInviteRequest{}); !errors.Is(err, utils.ErrLimitExceeded) {
+		t.Fatalf("err = %v, want the limit exceeded with every use handed out", err)
+	}
+
+	// Used up and expired codes give their uses back.
+	past := time.Now().Add(-time.Hour)
+	invites.invites[0].Uses = 1
+	invites.invites[1].ExpiresAt = &past
+	if _, err := s.CreateInvite(ctx, userID, models.CreateInviteRequest{MaxUses: maxOpenInviteUses}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateInviteUnlimitedForAdmins(t *testing.T) {
+	s := &inviteServiceImpl{inviteRepo: &fakeInvites{}, userRepo: fakeInviteUsers{role: models.RoleAdmin}}
+	for i := 0; i < 2; i++ {
+		invite, err := s.CreateInvite(context.Background(), primitive.NewObjectID(), models.CreateInviteRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if invite.MaxUses != 0 {
+			t.Errorf("max_uses = %d, want unlimited", invite.MaxUses)
+		}
+	}
+}
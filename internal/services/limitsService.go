@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+// LimitsService reports how much of each per-user quota a user has consumed.
+type LimitsService interface {
+	GetQuotas(ctx context.Context, userID primitive.ObjectID) ([]models.QuotaUsage, error)
+}
+
+type limitsServiceImpl struct {
+	thumbRepo           repositories.ThumbnailRepository
+	collectionRepo      repositories.CollectionRepository
+	inviteRepo          repositories.InviteRepository
+	tagSubscriptionRepo repositories.TagSubscriptionRepository
+	feedRepo            repositories.CollectionFeedRepository
+}
+
+func NewLimitsService(thumbRepo repositories.ThumbnailRepository, collectionRepo repositories.CollectionRepository, inviteRepo repositories.InviteRepository, tagSubscriptionRepo repositories.TagSubscriptionRepository, feedRepo repositories.CollectionFeedRepository) LimitsService {
+	return &limitsServiceImpl{thumbRepo: thumbRepo, collectionRepo: collectionRepo, inviteRepo: inviteRepo, tagSubscriptionRepo: tagSubscriptionRepo, feedRepo: feedRepo}
+}
+
+func (s *limitsServiceImpl) GetQuotas(ctx context.Context, userID primitive.ObjectID) ([]models.QuotaUsage, error) {
+	now := time.Now().UTC()
+	limits := utils.CurrentLimits()
+
+	thumbnails, err := s.thumbRepo.CountGenerations(ctx, userID, now.Format("2006-01"))
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to count thumbnail generations")
+		return nil, fmt.Errorf("failed to retrieve quotas")
+	}
+	collections, err := s.collectionRepo.CountByUser(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to count collections")
+		return nil, fmt.Errorf("failed to retrieve quotas")
+	}
+	invites, err := s.inviteRepo.CountByUser(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to count invites")
+		return nil, fmt.Errorf("failed to retrieve quotas")
+	}
+	subscriptions, err := s.tagSubscriptionRepo.CountByUser(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to count tag subscriptions")
+		return nil, fmt.Errorf("failed to retrieve quotas")
+	}
+	feeds, err := s.feedRepo.Count(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to count collection feeds")
+		return nil, fmt.Errorf("failed to retrieve quotas")
+	}
+
+	// The thumbnail quota is counted per calendar month in UTC.
+	monthEnd := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	return []models.QuotaUsage{
+		{Name: "thumbnails_per_month", Used: thumbnails, Limit: int64(limits.MaxThumbnailsPerMonth), ResetAt: &monthEnd},
+		{Name: "collections", Used: collections, Limit: int64(limits.MaxCollectionsPerUser)},
+		{Name: "invites", Used: invites, Limit: maxInvitesPerUser},
+		{Name: "tag_subscriptions", Used: subscriptions, Limit: maxTagSubscriptionsPerUser},
+		{Name: "collection_feeds", Used: feeds, Limit: maxFeedsPerUser},
+	}, nil
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+// monthlyThumbnails is a ThumbnailRepository counting generations by month.
+type monthlyThumbnails struct {
+	repositories.ThumbnailRepository
+	generations map[string]int64
+}
+
+func (f monthlyThumbnails) CountGenerations(ctx context.Context, userID primitive.ObjectID, month string) (int64, error) {
+	return f.generations[month], nil
+}
+
+type countedTagSubscriptions struct {
+	repositories.TagSubscriptionRepository
+	count int64
+}
+
+func (f countedTagSubscriptions) CountByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return f.count, nil
+}
+
+// userFeeds is a CollectionFeedRepository counting the feeds of its users.
+type userFeeds struct {
+	repositories.CollectionFeedRepository
+	counts map[primitive.ObjectID]int64
+}
+
+func (f userFeeds) Count(ctx context.Context, filter bson.M) (int64, error) {
+	return f.counts[filter["user_id"].(primitive.ObjectID)], nil
+}
+
+func TestGetQuotas(t *testing.T) {
+	now := time.Now().UTC()
+	lastMonth := now.AddDate(0, 0, -now.Day()).Format("2006-01")
+	thumbnails := monthlyThumbnails{generations: map[string]int64{now.Format("2006-01"): 7, lastMonth: 40}}
+	invites := &fakeInvites{invites: []models.Invite{{}, {}}}
+	userID := primitive.NewObjectID()
+	feeds := userFeeds{counts: map[primitive.ObjectID]int64{userID: 3, primitive.NewObjectID(): 9}}
+	s := NewLimitsService(thumbnails, &countedCollections{count: 4}, invites, countedTagSubscriptions{count: 1}, feeds)
+
+	quotas, err := s.GetQuotas(context.Background(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limits := utils.CurrentLimits()
+	want := map[string][2]int64{
+		"thumbnails_per_month": {7, int64(limits.MaxThumbnailsPerMonth)},
+		"collections":          {4, int64(limits.MaxCollectionsPerUser)},
+		"invites":              {2, maxInvitesPerUser},
+		"tag_subscriptions":    {1, maxTagSubscriptionsPerUser},
+		"collection_feeds":     {3, maxFeedsPerUser},
+	}
+	if len(quotas) != len(want) {
+		t.Fatalf("got %d quotas, want %d: %+v", len(quotas), len(want), quotas)
+	}
+	for _, q := range quotas {
+		if got := [2]int64{q.Used, q.Limit}; got != want[q.Name] {
+			t.Errorf("%s: used %d of %d, want %d of %d", q.Name, q.Used, q.Limit, want[q.Name][0], want[q.Name][1])
+		}
+		if q.Name != "thumbnails_per_month" {
+			if q.ResetAt != nil {
+				t.Errorf("%s resets at %v, want no reset", q.Name, q.ResetAt)
+			}
+			continue
+		}
+		if q.ResetAt == nil || q.ResetAt.Day() != 1 || !q.ResetAt.After(now) || q.ResetAt.Sub(now) > 31*24*time.Hour {
+			t.Errorf("thumbnails reset at %v, want the start of next month", q.ResetAt)
+		}
+	}
+}
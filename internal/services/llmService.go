@@ -6,24 +6,143 @@ import (
 	"errors"
 	"fmt"
 	"markly/internal/models"
+	"markly/internal/utils"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/llms/googleai"
 )
 
-var apiKey = os.Getenv("API_KEY")
+const (
+	llmProvider = "googleai"
+	llmModel    = "gemini-2.5-flash"
+)
+
+// Prices in USD per million tokens, used to estimate the cost of each request. They
+// default to the list price of llmModel and can be overridden as prices change.
+var (
+	llmPromptPricePerMTok     = priceFromEnv("LLM_PRICE_PROMPT_PER_MTOK", 0.30)
+	llmCompletionPricePerMTok = priceFromEnv("LLM_PRICE_COMPLETION_PER_MTOK", 2.50)
+)
+
+func priceFromEnv(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	price, err := strconv.ParseFloat(v, 64)
+	if err != nil || price < 0 {
+		log.Warn().Str(key, v).Float64("default", def).Msg("Invalid LLM price, using default")
+		return def
+	}
+	return price
+}
+
+// Summarizer generates text about bookmarks with an LLM. Services take one instead
+// of calling the provider, so that tests can use canned responses.
+type Summarizer interface {
+	// Summarize summarizes a page. When highlights are given, the summary focuses on
+	// the passages the user highlighted.
+	Summarize(ctx context.Context, url, title string, highlights ...string) (string, error)
+	GenerateSuggestions(ctx context.Context, recentBookmarks []models.PromptBookmarkInfo) ([]models.AISuggestion, error)
+	NewsletterIntro(ctx context.Context, collectionName string, bookmarks []models.Bookmark) (string, error)
+	TranslateSummary(ctx context.Context, summary, language string) (string, error)
+}
+
+// llmSummarizer is the Summarizer backed by llmModel.
+type llmSummarizer struct {
+	apiKey string
+}
+
+// NewLLMSummarizer reads the provider's key from API_KEY. Without one, every request
+// fails with a missing api key error.
+func NewLLMSummarizer() Summarizer {
+	return &llmSummarizer{apiKey: os.Getenv("API_KEY")}
+}
+
+// newLLM creates a client for llmModel.
+func (l *llmSummarizer) newLLM(ctx context.Context) (llms.Model, error) {
+	return googleai.New(ctx, googleai.WithAPIKey(l.apiKey), googleai.WithDefaultModel(llmModel))
+}
+
+// generate sends prompt to the model and records the latency, token usage and
+// estimated cost of the request. Metrics are labeled by provider, model and operation
+// only; the user is logged instead to keep label cardinality bounded.
+func generate(ctx context.Context, llm llms.Model, operation, prompt string) (string, error) {
+	start := time.Now()
+	resp, err := llm.GenerateContent(ctx, []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, prompt)})
+	duration := time.Since(start)
+	utils.LLMRequestDurationSeconds.WithLabelValues(llmProvider, llmModel, operation).Observe(duration.Seconds())
+	if err == nil && len(resp.Choices) == 0 {
+		err = errors.New("empty response from model")
+	}
+	if err != nil {
+		utils.LLMRequestsTotal.WithLabelValues(llmProvider, llmModel, operation, "error").Inc()
+		return "", err
+	}
+	utils.LLMRequestsTotal.WithLabelValues(llmProvider, llmModel, operation, "success").Inc()
+
+	choice := resp.Choices[0]
+	promptTokens := tokenCount(choice.GenerationInfo["input_tokens"])
+	completionTokens := tokenCount(choice.GenerationInfo["output_tokens"])
+	cost := (float64(promptTokens)*llmPromptPricePerMTok + float64(completionTokens)*llmCompletionPricePerMTok) / 1e6
+
+	utils.LLMTokensTotal.WithLabelValues(llmProvider, llmModel, operation, "prompt").Add(float64(promptTokens))
+	utils.LLMTokensTotal.WithLabelValues(llmProvider, llmModel, operation, "completion").Add(float64(completionTokens))
+	utils.LLMTokensPerRequest.WithLabelValues(llmProvider, llmModel, operation, "prompt").Observe(float64(promptTokens))
+	utils.LLMTokensPerRequest.WithLabelValues(llmProvider, llmModel, operation, "completion").Observe(float64(completionTokens))
+	utils.LLMCostUSDTotal.WithLabelValues(llmProvider, llmModel, operation).Add(cost)
+
+	userID, _ := ctx.Value("userID").(string)
+	log.Info().Str("userID", userID).Str("operation", operation).Str("model", llmModel).
+		Int64("prompt_tokens", promptTokens).Int64("completion_tokens", completionTokens).
+		Float64("cost_usd", cost).Dur("duration", duration).Msg("LLM request completed")
+	return choice.Content, nil
+}
+
+// tokenCount reads a token count from the generation info of a response.
+func tokenCount(v any) int64 {
+	switch n := v.(type) {
+	case int32:
+		return int64(n)
+	case int:
+		return int64(n)
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	}
+	return 0
+}
+
+// Prompt versions are recorded with AI events so that prompts can be compared.
+// Bump a version whenever its prompt changes.
+const (
+	SummaryPromptVersion               = "summary-v1"
+	SummaryWithHighlightsPromptVersion = "summary-highlights-v1"
+	SuggestionsPromptVersion           = "suggestions-v1"
+)
 
-func LLMSummarize(url, title string) (string, error) {
+// SummaryPrompt returns the version of the prompt Summarize uses.
+func SummaryPrompt(highlights []string) string {
+	if len(highlights) > 0 {
+		return SummaryWithHighlightsPromptVersion
+	}
+	return SummaryPromptVersion
+}
+
+func (l *llmSummarizer) Summarize(ctx context.Context, url, title string, highlights ...string) (string, error) {
 	log.Debug().Str("url", url).Str("title", title).Msg("Attempting to summarize URL with LLM")
-	if apiKey == "" {
+	if l.apiKey == "" {
 		log.Error().Msg("Missing API key for LLM summarization")
 		return "", errors.New("missing api key.")
 	}
 
-	llm, err := googleai.New(context.Background(), googleai.WithAPIKey(apiKey), googleai.WithDefaultModel("gemini-2.5-flash"))
+	llm, err := l.newLLM(ctx)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create Google AI LLM for summarization")
 		return "", fmt.Errorf("failed to create Google AI LLM: %w", err)
@@ -35,8 +154,14 @@ func LLMSummarize(url, title string) (string, error) {
 		title,
 		url,
 	)
+	if len(highlights) > 0 {
+		prompt += "\n\nThe user highlighted these passages. Center the summary on them and quote the most important ones:\n"
+		for _, h := range highlights {
+			prompt += "- " + strings.ReplaceAll(h, "\n", " ") + "\n"
+		}
+	}
 
-	summary, err := llms.GenerateFromSinglePrompt(context.Background(), llm, prompt)
+	summary, err := generate(ctx, llm, "summarize", prompt)
 	if err != nil {
 		log.Error().Err(err).Str("url", url).Str("title", title).Msg("Failed to generate summary from LLM")
 		return "", fmt.Errorf("failed to generate summary from LLM: %w", err)
@@ -45,14 +170,14 @@ func LLMSummarize(url, title string) (string, error) {
 	return summary, nil
 }
 
-func LLMGenerateSuggestions(recentBookmarks []models.PromptBookmarkInfo) ([]models.AISuggestion, error) {
+func (l *llmSummarizer) GenerateSuggestions(ctx context.Context, recentBookmarks []models.PromptBookmarkInfo) ([]models.AISuggestion, error) {
 	log.Debug().Int("recentBookmarksCount", len(recentBookmarks)).Msg("Attempting to generate LLM suggestions")
-	if apiKey == "" {
+	if l.apiKey == "" {
 		log.Error().Msg("Missing API key for LLM suggestion generation")
 		return nil, errors.New("missing api key")
 	}
 
-	llm, err := googleai.New(context.Background(), googleai.WithAPIKey(apiKey), googleai.WithDefaultModel("gemini-2.5-flash"))
+	llm, err := l.newLLM(ctx)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create Google AI LLM for suggestion generation")
 		return nil, fmt.Errorf("failed to create Google AI LLM: %w", err)
@@ -147,7 +272,7 @@ Example of expected JSON output:
 
 	const maxRetries = 3
 	for i := 0; i < maxRetries; i++ {
-		llmResponse, err := llms.GenerateFromSinglePrompt(context.Background(), llm, prompt)
+		llmResponse, err := generate(ctx, llm, "suggestions", prompt)
 		if err != nil {
 			log.Error().Err(err).Int("retry", i+1).Msg("Failed to generate suggestions from LLM")
 			return nil, fmt.Errorf("failed to generate suggestions from LLM on retry %d: %w", i+1, err)
@@ -176,6 +301,9 @@ Example of expected JSON output:
 		}
 
 		if len(suggestions) == 3 {
+			for i := range suggestions {
+				suggestions[i].PromptVersion = SuggestionsPromptVersion
+			}
 			log.Info().Int("suggestionsCount", len(suggestions)).Msg("Successfully generated LLM suggestions")
 			return suggestions, nil // Success
 		}
@@ -185,3 +313,51 @@ Example of expected JSON output:
 	log.Error().Msg("LLM failed to generate exactly 3 suggestions after multiple retries")
 	return nil, errors.New("LLM failed to generate exactly 3 suggestions after multiple retries")
 }
+
+// NewsletterIntro writes a short plain-text introduction to a newsletter of the
+// bookmarks recently added to a collection.
+func (l *llmSummarizer) NewsletterIntro(ctx context.Context, collectionName string, bookmarks []models.Bookmark) (string, error) {
+	if l.apiKey == "" {
+		return "", errors.New("missing api key")
+	}
+
+	llm, err := l.newLLM(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create Google AI LLM: %w", err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "You are writing the introduction of a newsletter about the collection %q. "+
+		"In two or three friendly sentences, tell subscribers what the links below have in common and why they are worth reading. "+
+		"Return plain text only, without Markdown, greetings or sign-off.\n\nLinks:\n", collectionName)
+	for _, bm := range bookmarks {
+		fmt.Fprintf(&b, "- %s (%s)\n", bm.Title, bm.URL)
+	}
+
+	intro, err := generate(ctx, llm, "newsletter_intro", b.String())
+	if err != nil {
+		return "", fmt.Errorf("failed to generate newsletter introduction from LLM: %w", err)
+	}
+	return strings.TrimSpace(intro), nil
+}
+
+// TranslateSummary translates a bookmark summary into the language named
+// language, keeping its Markdown formatting.
+func (l *llmSummarizer) TranslateSummary(ctx context.Context, summary, language string) (string, error) {
+	if l.apiKey == "" {
+		return "", errors.New("missing api key")
+	}
+
+	llm, err := l.newLLM(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create Google AI LLM: %w", err)
+	}
+
+	prompt := fmt.Sprintf("Translate the following bookmark summary into %s. Keep its Markdown formatting, "+
+		"and leave code, URLs and product names untranslated. Return only the translation.\n\n%s", language, summary)
+	translation, err := generate(ctx, llm, "translate_summary", prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to translate summary with LLM: %w", err)
+	}
+	return strings.TrimSpace(translation), nil
+}
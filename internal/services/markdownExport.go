@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+// ExportMarkdown returns one Markdown file per bookmark, for note-taking tools
+// such as Obsidian and Notion.
+func (s *exportServiceImpl) ExportMarkdown(ctx context.Context, userID primitive.ObjectID) ([]models.MarkdownFile, error) {
+	export, err := s.Export(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	files := markdownFiles(export)
+	log.Info().Str("userID", userID.Hex()).Int("files", len(files)).Msg("Bookmarks exported as Markdown")
+	return files, nil
+}
+
+// markdownFiles writes the bookmarks of an export as Markdown. File names come
+// from the titles and are made unique with a numeric suffix.
+func markdownFiles(export *models.MarklyExport) []models.MarkdownFile {
+	tags := make(map[primitive.ObjectID]string, len(export.Tags))
+	for _, t := range export.Tags {
+		tags[t.ID] = t.Name
+	}
+	categories := make(map[primitive.ObjectID]string, len(export.Categories))
+	for _, c := range export.Categories {
+		categories[c.ID] = c.Name
+	}
+	collections := make(map[primitive.ObjectID]string, len(export.Collections))
+	for _, c := range export.Collections {
+		collections[c.ID] = c.Name
+	}
+
+	used := make(map[string]bool, len(export.Bookmarks))
+	files := make([]models.MarkdownFile, 0, len(export.Bookmarks))
+	for i := range export.Bookmarks {
+		bm := &export.Bookmarks[i]
+		base := utils.Slugify(bm.Title)
+		if base == "" {
+			base = "bookmark"
+		}
+		name := base + ".md"
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s-%d.md", base, n)
+		}
+		used[name] = true
+		files = append(files, models.MarkdownFile{
+			Name:       name,
+			ModifiedAt: bm.CreatedAt.Time().UTC(),
+			Content:    []byte(bookmarkMarkdown(bm, tags, categories, collections)),
+		})
+	}
+	return files
+}
+
+// bookmarkMarkdown renders a bookmark as front matter with its URL, tags and
+// dates, followed by its summary and notes.
+func bookmarkMarkdown(bm *models.Bookmark, tags, categories, collections map[primitive.ObjectID]string) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	b.WriteString("title: " + strconv.Quote(bm.Title) + "\n")
+	b.WriteString("url: " + strconv.Quote(bm.URL) + "\n")
+	writeYAMLList(&b, "tags", namesOf(bm.TagsID, tags, markdownTag))
+	writeYAMLList(&b, "collections", namesOf(bm.CollectionsID, collections, nil))
+	if bm.CategoryID != nil {
+		if name, ok := categories[*bm.CategoryID]; ok {
+			b.WriteString("category: " + strconv.Quote(name) + "\n")
+		}
+	}
+	b.WriteString("created: " + bm.CreatedAt.Time().UTC().Format(time.RFC3339) + "\n")
+	if bm.ArchivedAt != nil {
+		b.WriteString("archived: " + bm.ArchivedAt.Time().UTC().Format(time.RFC3339) + "\n")
+	}
+	b.WriteString("favorite: " + strconv.FormatBool(bm.IsFav) + "\n")
+	b.WriteString("---\n\n")
+
+	b.WriteString("# " + strings.Join(strings.Fields(bm.Title), " ") + "\n")
+	if summary := strings.TrimSpace(bm.Summary); summary != "" {
+		b.WriteString("\n## Summary\n\n" + summary + "\n")
+	}
+	if notes := strings.TrimSpace(bm.Notes); notes != "" {
+		b.WriteString("\n## Notes\n\n" + notes + "\n")
+	}
+	return b.String()
+}
+
+// markdownTag makes a tag name usable as an Obsidian tag, which cannot contain
+// spaces.
+func markdownTag(name string) string {
+	return strings.Join(strings.Fields(name), "-")
+}
+
+func namesOf(ids []primitive.ObjectID, names map[primitive.ObjectID]string, format func(string) string) []string {
+	var out []string
+	for _, id := range ids {
+		name, ok := names[id]
+		if !ok {
+			continue
+		}
+		if format != nil {
+			name = format(name)
+		}
+		out = append(out, name)
+	}
+	return out
+}
+
+// writeYAMLList writes a block list. Values are written as double-quoted YAML
+// strings, whose escapes are a superset of strconv.Quote's.
+func writeYAMLList(b *strings.Builder, key string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	b.WriteString(key + ":\n")
+	for _, v := range values {
+		b.WriteString("  - " + strconv.Quote(v) + "\n")
+	}
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/models"
+)
+
+func TestMarkdownFiles(t *testing.T) {
+	tag, collection, category := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	created := primitive.NewDateTimeFromTime(time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC))
+	export := &models.MarklyExport{
+		Tags:        []models.Tag{{ID: tag, Name: "machine learning"}},
+		Collections: []models.Collection{{ID: collection, Name: "Reading"}},
+		Categories:  []models.Category{{ID: category, Name: "Research"}},
+		Bookmarks: []models.Bookmark{
+			{
+				Title: `Say "hi"`, URL: "https://example.com/a", CreatedAt: created, IsFav: true,
+				TagsID: []primitive.ObjectID{tag, primitive.NewObjectID()}, CollectionsID: []primitive.ObjectID{collection}, CategoryID: &category,
+				Summary: "A summary.", Notes: "My note.",
+			},
+			{Title: `Say "hi"`, URL: "https://example.com/b", CreatedAt: created},
+			{Title: "日本語", URL: "https://example.com/c", CreatedAt: created},
+		},
+	}
+
+	files := markdownFiles(export)
+	names := []string{"say-hi.md", "say-hi-2.md", "bookmark.md"}
+	if len(files) != len(names) {
+		t.Fatalf("got %d files, want %d", len(files), len(names))
+	}
+	for i, want := range names {
+		if files[i].Name != want {
+			t.Errorf("file %d name = %q, want %q", i, files[i].Name, want)
+		}
+	}
+
+	want := `---
+title: "Say \"hi\""
+url: "https://example.com/a"
+tags:
+  - "machine-learning"
+collections:
+  - "Reading"
+category: "Research"
+created: 2025-03-01T10:00:00Z
+favorite: true
+---
+
+# Say "hi"
+
+## Summary
+
+A summary.
+
+## Notes
+
+My note.
+`
+	if got := string(files[0].Content); got != want {
+		t.Errorf("content =\n%s\nwant\n%s", got, want)
+	}
+	if !files[0].ModifiedAt.Equal(created.Time()) {
+		t.Errorf("ModifiedAt = %v", files[0].ModifiedAt)
+	}
+}
@@ -0,0 +1,557 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"net/mail"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+const (
+	// maxNewsletterBookmarks caps the additions listed in one newsletter.
+	maxNewsletterBookmarks = 50
+	maxNewsletterSubject   = 200
+	// maxNewsletterSubscribers caps the subscribers of one collection, including
+	// those pending confirmation and those who unsubscribed.
+	maxNewsletterSubscribers = 1000
+	// newsletterSendTimeout bounds delivering one newsletter. A send still marked as
+	// sending after it no longer blocks the next one.
+	newsletterSendTimeout = time.Hour
+	// newsletterSummaryRunes is where bookmark summaries are cut in the email.
+	newsletterSummaryRunes = 300
+)
+
+// NewsletterService sends the bookmarks recently added to a collection to the
+// collection's subscribers. Subscribers are added by the collection owner, receive
+// newsletters once they confirm through the link emailed to them, and can
+// unsubscribe through the link in every newsletter.
+type NewsletterService interface {
+	ListSubscribers(ctx context.Context, userID, collectionID primitive.ObjectID) ([]models.NewsletterSubscriber, error)
+	AddSubscriber(ctx context.Context, userID, collectionID primitive.ObjectID, req models.AddNewsletterSubscriberRequest) (*models.NewsletterSubscriber, error)
+	RemoveSubscriber(ctx context.Context, userID, collectionID, subscriberID primitive.ObjectID) error
+	Confirm(ctx context.Context, token string) error
+	Unsubscribe(ctx context.Context, token string) error
+	// MigrateSubscribers hashes the unsubscribe tokens of subscribers added before
+	// tokens were stored encrypted. They were added before confirmation was
+	// required and are kept as confirmed.
+	MigrateSubscribers(ctx context.Context) error
+	Send(ctx context.Context, userID, collectionID primitive.ObjectID, req models.SendNewsletterRequest) (*models.NewsletterSend, error)
+	ListSends(ctx context.Context, userID, collectionID primitive.ObjectID) ([]models.NewsletterSend, error)
+}
+
+type newsletterServiceImpl struct {
+	newsletterRepo repositories.NewsletterRepository
+	collectionRepo repositories.CollectionRepository
+	bookmarkRepo   repositories.BookmarkRepository
+	userRepo       repositories.UserRepository
+	mailer         Mailer
+	summarizer     Summarizer
+	thumbnails     ThumbnailService
+	encryption     EncryptionService
+	// unsubscribeURL is NEWSLETTER_UNSUBSCRIBE_URL, the frontend page that
+	// unsubscribes a reader, and confirmURL is NEWSLETTER_CONFIRM_URL, the page
+	// that confirms a subscription. The token is appended to both as the "token"
+	// query parameter.
+	unsubscribeURL string
+	confirmURL     string
+}
+
+func NewNewsletterService(newsletterRepo repositories.NewsletterRepository, collectionRepo repositories.CollectionRepository, bookmarkRepo repositories.BookmarkRepository, userRepo repositories.UserRepository, mailer Mailer, summarizer Summarizer, thumbnails ThumbnailService, encryption EncryptionService) NewsletterService {
+	return &newsletterServiceImpl{
+		newsletterRepo: newsletterRepo,
+		collectionRepo: collectionRepo,
+		bookmarkRepo:   bookmarkRepo,
+		userRepo:       userRepo,
+		mailer:         mailer,
+		summarizer:     summarizer,
+		thumbnails:     thumbnails,
+		encryption:     encryption,
+		unsubscribeURL: os.Getenv("NEWSLETTER_UNSUBSCRIBE_URL"),
+		confirmURL:     os.Getenv("NEWSLETTER_CONFIRM_URL"),
+	}
+}
+
+// configured returns why newsletters cannot be used, or nil. The unsubscribe
+// tokens are stored encrypted, so the encryption must be configured as well.
+func (s *newsletterServiceImpl) configured() error {
+	switch {
+	case s.unsubscribeURL == "":
+		return fmt.Errorf("newsletters are not configured: NEWSLETTER_UNSUBSCRIBE_URL is not set")
+	case s.confirmURL == "":
+		return fmt.Errorf("newsletters are not configured: NEWSLETTER_CONFIRM_URL is not set")
+	case !s.encryption.Enabled():
+		return fmt.Errorf("newsletters are not configured: NOTES_MASTER_KEY is not set")
+	}
+	return nil
+}
+
+func (s *newsletterServiceImpl) collection(ctx context.Context, userID, collectionID primitive.ObjectID) (*models.Collection, error) {
+	col, err := s.collectionRepo.FindByID(ctx, userID, collectionID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("collection not found")
+		}
+		log.Error().Err(err).Str("collectionID", collectionID.Hex()).Msg("Error finding collection for newsletter")
+		return nil, fmt.Errorf("failed to retrieve collection")
+	}
+	return col, nil
+}
+
+func (s *newsletterServiceImpl) ListSubscribers(ctx context.Context, userID, collectionID primitive.ObjectID) ([]models.NewsletterSubscriber, error) {
+	if _, err := s.collection(ctx, userID, collectionID); err != nil {
+		return nil, err
+	}
+	return s.newsletterRepo.FindSubscribers(ctx, bson.M{"user_id": userID, "collection_id": collectionID})
+}
+
+// AddSubscriber adds a subscriber pending confirmation and emails them the
+// confirmation link. The subscriber is removed again when the email cannot be
+// sent or the collection is over its limit.
+func (s *newsletterServiceImpl) AddSubscriber(ctx context.Context, userID, collectionID primitive.ObjectID, req models.AddNewsletterSubscriberRequest) (*models.NewsletterSubscriber, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("collectionID", collectionID.Hex()).Msg("Attempting to add newsletter subscriber")
+	if strings.TrimSpace(req.Email) == "" {
+		return nil, fmt.Errorf("email is required")
+	}
+	addr, err := mail.ParseAddress(req.Email)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email address")
+	}
+	if err := s.configured(); err != nil {
+		return nil, err
+	}
+	col, err := s.collection(ctx, userID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve user")
+	}
+
+	unsubscribeToken, err := utils.GenerateToken()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate newsletter unsubscribe token")
+		return nil, fmt.Errorf("failed to generate unsubscribe token")
+	}
+	confirmToken, err := utils.GenerateToken()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate newsletter confirmation token")
+		return nil, fmt.Errorf("failed to generate confirmation token")
+	}
+	encrypted, err := s.encryption.Encrypt(ctx, userID, unsubscribeToken)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to encrypt newsletter unsubscribe token")
+		return nil, fmt.Errorf("failed to add subscriber")
+	}
+	sub := &models.NewsletterSubscriber{
+		ID:               primitive.NewObjectID(),
+		UserID:           userID,
+		CollectionID:     collectionID,
+		Email:            strings.ToLower(addr.Address),
+		UnsubscribeToken: utils.HashAPIKey(unsubscribeToken),
+		EncryptedToken:   encrypted,
+		ConfirmToken:     utils.HashAPIKey(confirmToken),
+		CreatedAt:        time.Now(),
+	}
+	if err := s.newsletterRepo.CreateSubscriber(ctx, sub); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("subscriber already exists or has unsubscribed")
+		}
+		log.Error().Err(err).Str("collectionID", collectionID.Hex()).Msg("Failed to add newsletter subscriber")
+		return nil, fmt.Errorf("failed to add subscriber")
+	}
+	// Counting after the insert keeps concurrent additions from going over the
+	// limit together.
+	count, err := s.newsletterRepo.CountSubscribers(ctx, bson.M{"collection_id": collectionID})
+	if err != nil || count > maxNewsletterSubscribers {
+		s.discardSubscriber(sub)
+		if err != nil {
+			log.Error().Err(err).Str("collectionID", collectionID.Hex()).Msg("Failed to count newsletter subscribers")
+			return nil, fmt.Errorf("failed to add subscriber")
+		}
+		return nil, fmt.Errorf("%w: at most %d subscribers per collection", utils.ErrLimitExceeded, maxNewsletterSubscribers)
+	}
+
+	var body bytes.Buffer
+	err = confirmationTemplate.Execute(&body, confirmationData{
+		Collection:     col.Name,
+		Owner:          owner.Username,
+		ConfirmURL:     s.confirmURL + "?token=" + url.QueryEscape(confirmToken),
+		UnsubscribeURL: s.unsubscribeURL + "?token=" + url.QueryEscape(unsubscribeToken),
+	})
+	if err == nil {
+		err = s.mailer.SendEmail(sub.Email, "Confirm your subscription to "+col.Name, body.String())
+	}
+	if err != nil {
+		log.Error().Err(err).Str("collectionID", collectionID.Hex()).Str("subscriberID", sub.ID.Hex()).Msg("Failed to send newsletter confirmation")
+		s.discardSubscriber(sub)
+		return nil, fmt.Errorf("failed to send confirmation email")
+	}
+	log.Info().Str("userID", userID.Hex()).Str("collectionID", collectionID.Hex()).Str("subscriberID", sub.ID.Hex()).Msg("Newsletter subscriber added")
+	return sub, nil
+}
+
+// discardSubscriber deletes a subscriber that AddSubscriber could not finish
+// adding, even when the request was canceled.
+func (s *newsletterServiceImpl) discardSubscriber(sub *models.NewsletterSubscriber) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := s.newsletterRepo.DeleteSubscriber(ctx, bson.M{"_id": sub.ID}); err != nil {
+		log.Error().Err(err).Str("subscriberID", sub.ID.Hex()).Msg("Failed to discard newsletter subscriber")
+	}
+}
+
+func (s *newsletterServiceImpl) RemoveSubscriber(ctx context.Context, userID, collectionID, subscriberID primitive.ObjectID) error {
+	result, err := s.newsletterRepo.DeleteSubscriber(ctx, bson.M{"_id": subscriberID, "user_id": userID, "collection_id": collectionID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("subscriber not found")
+	}
+	log.Info().Str("userID", userID.Hex()).Str("subscriberID", subscriberID.Hex()).Msg("Newsletter subscriber removed")
+	return nil
+}
+
+// Confirm is called without authentication, with the token from a confirmation
+// email. Confirming twice succeeds; a subscriber who unsubscribed stays
+// unsubscribed.
+func (s *newsletterServiceImpl) Confirm(ctx context.Context, token string) error {
+	if token == "" {
+		return fmt.Errorf("token is required")
+	}
+	hash := utils.HashAPIKey(token)
+	_, err := s.newsletterRepo.UpdateSubscriber(ctx, bson.M{"confirm_token": hash, "confirmed_at": nil, "unsubscribed_at": nil}, bson.M{"confirmed_at": time.Now()})
+	if err == nil {
+		return nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return fmt.Errorf("failed to confirm subscription")
+	}
+	count, err := s.newsletterRepo.CountSubscribers(ctx, bson.M{"confirm_token": hash})
+	if err != nil {
+		return fmt.Errorf("failed to confirm subscription")
+	}
+	if count == 0 {
+		return fmt.Errorf("invalid confirmation token")
+	}
+	return nil
+}
+
+// Unsubscribe is called without authentication, with the token from a newsletter
+// or confirmation email. Unsubscribing twice succeeds.
+func (s *newsletterServiceImpl) Unsubscribe(ctx context.Context, token string) error {
+	if token == "" {
+		return fmt.Errorf("token is required")
+	}
+	hash := utils.HashAPIKey(token)
+	_, err := s.newsletterRepo.UpdateSubscriber(ctx, bson.M{"unsubscribe_token": hash, "unsubscribed_at": nil}, bson.M{"unsubscribed_at": time.Now()})
+	if err == nil {
+		return nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return fmt.Errorf("failed to unsubscribe")
+	}
+	count, err := s.newsletterRepo.CountSubscribers(ctx, bson.M{"unsubscribe_token": hash})
+	if err != nil {
+		return fmt.Errorf("failed to unsubscribe")
+	}
+	if count == 0 {
+		return fmt.Errorf("invalid unsubscribe token")
+	}
+	return nil
+}
+
+func (s *newsletterServiceImpl) MigrateSubscribers(ctx context.Context) error {
+	subs, err := s.newsletterRepo.FindSubscribers(ctx, bson.M{"encrypted_token": nil})
+	if err != nil || len(subs) == 0 {
+		return err
+	}
+	if !s.encryption.Enabled() {
+		log.Warn().Int("count", len(subs)).Msg("NOTES_MASTER_KEY not set, newsletter unsubscribe tokens are not migrated")
+		return nil
+	}
+	migrated := 0
+	for _, sub := range subs {
+		encrypted, err := s.encryption.Encrypt(ctx, sub.UserID, sub.UnsubscribeToken)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt unsubscribe token of subscriber %s: %w", sub.ID.Hex(), err)
+		}
+		fields := bson.M{"unsubscribe_token": utils.HashAPIKey(sub.UnsubscribeToken), "encrypted_token": encrypted}
+		if sub.ConfirmedAt == nil {
+			fields["confirmed_at"] = sub.CreatedAt
+		}
+		if _, err := s.newsletterRepo.UpdateSubscriber(ctx, bson.M{"_id": sub.ID, "encrypted_token": nil}, fields); err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+		migrated++
+	}
+	log.Info().Int("count", migrated).Msg("Migrated newsletter unsubscribe tokens")
+	return nil
+}
+
+func (s *newsletterServiceImpl) ListSends(ctx context.Context, userID, collectionID primitive.ObjectID) ([]models.NewsletterSend, error) {
+	if _, err := s.collection(ctx, userID, collectionID); err != nil {
+		return nil, err
+	}
+	return s.newsletterRepo.FindSends(ctx, bson.M{"user_id": userID, "collection_id": collectionID}, 50)
+}
+
+// Send records a newsletter of the bookmarks added to the collection since
+// req.Since and delivers it in the background. The returned send is updated with
+// the delivery counts once every subscriber has been emailed.
+func (s *newsletterServiceImpl) Send(ctx context.Context, userID, collectionID primitive.ObjectID, req models.SendNewsletterRequest) (*models.NewsletterSend, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("collectionID", collectionID.Hex()).Msg("Attempting to send newsletter")
+	if err := s.configured(); err != nil {
+		return nil, err
+	}
+	col, err := s.collection(ctx, userID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	subject := strings.TrimSpace(req.Subject)
+	if subject == "" {
+		subject = col.Name
+	}
+	if utf8.RuneCountInString(subject) > maxNewsletterSubject {
+		return nil, fmt.Errorf("invalid subject: must be at most %d characters", maxNewsletterSubject)
+	}
+
+	now := time.Now()
+	// A failed send delivered nothing, so its additions are still new.
+	recent, err := s.newsletterRepo.FindSends(ctx, bson.M{"user_id": userID, "collection_id": collectionID, "status": models.NewsletterSent}, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve newsletter history")
+	}
+	since := now.AddDate(0, 0, -7)
+	if len(recent) > 0 {
+		since = recent[0].CreatedAt
+	}
+	if req.Since != nil {
+		since = *req.Since
+	}
+	if !since.Before(now) {
+		return nil, fmt.Errorf("invalid since: must be in the past")
+	}
+
+	subscribers, err := s.newsletterRepo.FindSubscribers(ctx, bson.M{"user_id": userID, "collection_id": collectionID, "confirmed_at": bson.M{"$ne": nil}, "unsubscribed_at": nil})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve subscribers")
+	}
+	if len(subscribers) == 0 {
+		return nil, fmt.Errorf("no subscribers to send the newsletter to")
+	}
+	bookmarks, err := s.bookmarkRepo.Find(ctx, bson.M{
+		"user_id":       userID,
+		"collectionsid": collectionID,
+		"created_at":    bson.M{"$gt": since},
+		"archived_at":   nil,
+	}, maxNewsletterBookmarks, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve bookmarks")
+	}
+	if len(bookmarks) == 0 {
+		return nil, fmt.Errorf("no bookmarks added since %s", since.UTC().Format(time.RFC3339))
+	}
+
+	owner, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve user")
+	}
+	if req.AIIntro && owner.Settings != nil && owner.Settings.DisableExternalAI {
+		return nil, ErrExternalAIDisabled
+	}
+
+	send := &models.NewsletterSend{
+		ID:           primitive.NewObjectID(),
+		UserID:       userID,
+		CollectionID: collectionID,
+		Subject:      subject,
+		Since:        since,
+		BookmarkIDs:  make([]primitive.ObjectID, len(bookmarks)),
+		AIIntro:      req.AIIntro,
+		Status:       models.NewsletterSending,
+		Recipients:   len(subscribers),
+		CreatedAt:    now,
+	}
+	for i, bm := range bookmarks {
+		send.BookmarkIDs[i] = bm.ID
+	}
+	// A send that is still sending after the timeout was abandoned, and would
+	// otherwise keep the collection_sending_unique index from taking a new one.
+	err = s.newsletterRepo.UpdateSend(ctx, bson.M{
+		"collection_id": collectionID,
+		"status":        models.NewsletterSending,
+		"created_at":    bson.M{"$lt": now.Add(-newsletterSendTimeout)},
+	}, bson.M{"status": models.NewsletterFailed, "finished_at": now})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.newsletterRepo.CreateSend(ctx, send); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("newsletter is already being sent")
+		}
+		return nil, err
+	}
+
+	go s.deliver(*send, col, owner, bookmarks, subscribers)
+	log.Info().Str("userID", userID.Hex()).Str("collectionID", collectionID.Hex()).Str("sendID", send.ID.Hex()).Int("recipients", len(subscribers)).Msg("Newsletter send started")
+	return send, nil
+}
+
+func (s *newsletterServiceImpl) deliver(send models.NewsletterSend, col *models.Collection, owner *models.User, bookmarks []models.Bookmark, subscribers []models.NewsletterSubscriber) {
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), "userID", owner.ID.Hex()), newsletterSendTimeout)
+	defer cancel()
+
+	data := newsletterData{Collection: col.Name, Owner: owner.Username}
+	if send.AIIntro {
+		introCtx, cancelIntro := context.WithTimeout(ctx, time.Minute)
+		intro, err := s.summarizer.NewsletterIntro(introCtx, col.Name, bookmarks)
+		cancelIntro()
+		if err != nil {
+			// The newsletter is still worth sending without its introduction.
+			log.Warn().Err(err).Str("sendID", send.ID.Hex()).Msg("Failed to generate newsletter introduction")
+			send.AIIntro = false
+		}
+		data.Intro = intro
+	}
+	// Thumbnails are embedded in the message, so they are only made for mailers
+	// that can embed images.
+	imageMailer, embedImages := s.mailer.(InlineImageMailer)
+	var thumbs map[primitive.ObjectID]*models.BookmarkThumbnail
+	if embedImages {
+		thumbs = s.thumbnails.Thumbnails(ctx, owner.ID, bookmarks)
+	}
+	var images []InlineImage
+	for _, bm := range bookmarks {
+		title := bm.Title
+		if title == "" {
+			title = bm.URL
+		}
+		item := newsletterBookmark{Title: title, URL: bm.URL, Summary: truncateRunes(bm.Summary, newsletterSummaryRunes)}
+		if thumb, ok := thumbs[bm.ID]; ok {
+			name := "thumb-" + bm.ID.Hex() + ".png"
+			images = append(images, InlineImage{Name: name, ContentType: thumb.ContentType, Data: thumb.Data})
+			item.Thumbnail = template.URL("cid:" + name)
+		}
+		data.Bookmarks = append(data.Bookmarks, item)
+	}
+
+	for _, sub := range subscribers {
+		if ctx.Err() != nil {
+			utils.DigestEmailsTotal.WithLabelValues("newsletter", "failed").Add(float64(send.Recipients - send.Delivered - send.Failed))
+			send.Failed += send.Recipients - send.Delivered - send.Failed
+			break
+		}
+		token, err := s.encryption.Decrypt(ctx, owner.ID, sub.EncryptedToken)
+		data.UnsubscribeURL = s.unsubscribeURL + "?token=" + url.QueryEscape(token)
+		var body bytes.Buffer
+		if err == nil {
+			err = newsletterTemplate.Execute(&body, data)
+		}
+		if err == nil {
+			if len(images) > 0 {
+				err = imageMailer.SendEmailWithImages(sub.Email, send.Subject, body.String(), images)
+			} else {
+				err = s.mailer.SendEmail(sub.Email, send.Subject, body.String())
+			}
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("sendID", send.ID.Hex()).Str("subscriberID", sub.ID.Hex()).Msg("Failed to send newsletter")
+			utils.DigestEmailsTotal.WithLabelValues("newsletter", "failed").Inc()
+			send.Failed++
+			continue
+		}
+		utils.DigestEmailsTotal.WithLabelValues("newsletter", "sent").Inc()
+		send.Delivered++
+	}
+
+	send.Status = models.NewsletterSent
+	if send.Delivered == 0 {
+		send.Status = models.NewsletterFailed
+	}
+	finishCtx, cancelFinish := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFinish()
+	err := s.newsletterRepo.UpdateSend(finishCtx, bson.M{"_id": send.ID}, bson.M{
+		"status":      send.Status,
+		"delivered":   send.Delivered,
+		"failed":      send.Failed,
+		"ai_intro":    send.AIIntro,
+		"finished_at": time.Now(),
+	})
+	if err != nil {
+		log.Error().Err(err).Str("sendID", send.ID.Hex()).Msg("Failed to record newsletter delivery")
+	}
+	log.Info().Str("userID", owner.ID.Hex()).Str("sendID", send.ID.Hex()).Int("delivered", send.Delivered).Int("failed", send.Failed).Msg("Newsletter send finished")
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return strings.TrimSpace(string([]rune(s)[:n])) + "…"
+}
+
+type newsletterData struct {
+	Collection     string
+	Owner          string
+	Intro          string
+	Bookmarks      []newsletterBookmark
+	UnsubscribeURL string
+}
+
+type newsletterBookmark struct {
+	Title   string
+	URL     string
+	Summary string
+	// Thumbnail is the cid: URL of the embedded thumbnail, if there is one.
+	Thumbnail template.URL
+}
+
+var newsletterTemplate = template.Must(template.New("newsletter").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; max-width: 600px; margin: 0 auto;">
+<h1>{{.Collection}}</h1>
+{{if .Intro}}<p>{{.Intro}}</p>{{end}}
+{{range .Bookmarks}}<div style="margin-bottom: 16px; overflow: hidden;">
+{{if .Thumbnail}}<img src="{{.Thumbnail}}" alt="" width="80" style="float: right; margin-left: 12px; border-radius: 4px;">{{end}}
+<a href="{{.URL}}" style="font-weight: bold;">{{.Title}}</a>
+{{if .Summary}}<p style="margin: 4px 0;">{{.Summary}}</p>{{end}}
+</div>
+{{end}}<hr>
+<p style="font-size: 12px; color: #666;">You receive this newsletter because {{.Owner}} added you to the subscribers of {{.Collection}} on Markly. <a href="{{.UnsubscribeURL}}">Unsubscribe</a></p>
+</body>
+</html>
+`))
+
+type confirmationData struct {
+	Collection     string
+	Owner          string
+	ConfirmURL     string
+	UnsubscribeURL string
+}
+
+var confirmationTemplate = template.Must(template.New("confirmation").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; max-width: 600px; margin: 0 auto;">
+<p>{{.Owner}} added you to the subscribers of the {{.Collection}} newsletter on Markly.</p>
+<p><a href="{{.ConfirmURL}}" style="font-weight: bold;">Confirm your subscription</a></p>
+<p style="font-size: 12px; color: #666;">You will not receive the newsletter unless you confirm. If you do not want to be added again, <a href="{{.UnsubscribeURL}}">unsubscribe</a>.</p>
+</body>
+</html>
+`))
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"html/template"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+func TestNewsletterTemplateEmbedsThumbnails(t *testing.T) {
+	data := newsletterData{
+		Collection: "Reading list",
+		Owner:      "ada",
+		Bookmarks: []newsletterBookmark{
+			{Title: "With image", URL: "https://example.com/a", Thumbnail: template.URL("cid:thumb-1.png")},
+			{Title: "Without image", URL: "https://example.com/b"},
+		},
+		UnsubscribeURL: "https://markly.app/unsubscribe?token=x",
+	}
+	var body bytes.Buffer
+	if err := newsletterTemplate.Execute(&body, data); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(body.String(), "<img "); n != 1 {
+		t.Errorf("newsletter has %d images, want 1", n)
+	}
+	if !strings.Contains(body.String(), `src="cid:thumb-1.png"`) {
+		t.Errorf("newsletter does not refer to the embedded thumbnail: %s", body.String())
+	}
+}
+
+// fakeNewsletterRepo keeps subscribers in memory. Its filters only understand
+// the fields the service queries subscribers by.
+type fakeNewsletterRepo struct {
+	repositories.NewsletterRepository
+	subs  []*models.NewsletterSubscriber
+	count int64
+}
+
+func (f *fakeNewsletterRepo) matches(sub *models.NewsletterSubscriber, filter bson.M) bool {
+	for key, want := range filter {
+		switch key {
+		case "_id":
+			if sub.ID != want {
+				return false
+			}
+		case "unsubscribe_token":
+			if sub.UnsubscribeToken != want {
+				return false
+			}
+		case "confirm_token":
+			if sub.ConfirmToken != want {
+				return false
+			}
+		case "confirmed_at":
+			if sub.ConfirmedAt != nil {
+				return false
+			}
+		case "unsubscribed_at":
+			if sub.UnsubscribedAt != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (f *fakeNewsletterRepo) CreateSubscriber(ctx context.Context, sub *models.NewsletterSubscriber) error {
+	f.subs = append(f.subs, sub)
+	return nil
+}
+
+func (f *fakeNewsletterRepo) CountSubscribers(ctx context.Context, filter bson.M) (int64, error) {
+	if f.count > 0 {
+		return f.count, nil
+	}
+	var n int64
+	for _, sub := range f.subs {
+		if f.matches(sub, filter) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (f *fakeNewsletterRepo) UpdateSubscriber(ctx context.Context, filter bson.M, updateFields bson.M) (*models.NewsletterSubscriber, error) {
+	for _, sub := range f.subs {
+		if !f.matches(sub, filter) {
+			continue
+		}
+		if at, ok := updateFields["confirmed_at"].(time.Time); ok {
+			sub.ConfirmedAt = &at
+		}
+		if at, ok := updateFields["unsubscribed_at"].(time.Time); ok {
+			sub.UnsubscribedAt = &at
+		}
+		return sub, nil
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (f *fakeNewsletterRepo) DeleteSubscriber(ctx context.Context, filter bson.M) (*mongo.DeleteResult, error) {
+	for i, sub := range f.subs {
+		if f.matches(sub, filter) {
+			f.subs = append(f.subs[:i], f.subs[i+1:]...)
+			return &mongo.DeleteResult{DeletedCount: 1}, nil
+		}
+	}
+	return &mongo.DeleteResult{}, nil
+}
+
+type fakeNewsletterCollections struct {
+	repositories.CollectionRepository
+	col models.Collection
+}
+
+func (f *fakeNewsletterCollections) FindByID(ctx context.Context, userID, collectionID primitive.ObjectID) (*models.Collection, error) {
+	if collectionID != f.col.ID {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &f.col, nil
+}
+
+type fakeNewsletterUsers struct {
+	repositories.UserRepository
+}
+
+func (fakeNewsletterUsers) FindByID(ctx context.Context, userID primitive.ObjectID) (*models.User, error) {
+	return &models.User{ID: userID, Username: "ada"}, nil
+}
+
+// reversingEncryption "encrypts" by reversing the plaintext, so that tests can
+// tell stored values from the tokens.
+type reversingEncryption struct{ EncryptionService }
+
+func (reversingEncryption) Enabled() bool { return true }
+
+func (reversingEncryption) Encrypt(ctx context.Context, userID primitive.ObjectID, plaintext string) (string, error) {
+	return reverseString(plaintext), nil
+}
+
+func (reversingEncryption) Decrypt(ctx context.Context, userID primitive.ObjectID, ciphertext string) (string, error) {
+	return reverseString(ciphertext), nil
+}
+
+func reverseString(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
+
+func newTestNewsletterService(repo *fakeNewsletterRepo, col models.Collection, mailer Mailer) *newsletterServiceImpl {
+	return &newsletterServiceImpl{
+		newsletterRepo: repo,
+		collectionRepo: &fakeNewsletterCollections{col: col},
+		userRepo:       fakeNewsletterUsers{},
+		mailer:         mailer,
+		encryption:     reversingEncryption{},
+		unsubscribeURL: "https://markly.app/unsubscribe",
+		confirmURL:     "https://markly.app/confirm",
+	}
+}
+
+// linkToken returns the token of the link to page in an email.
+func linkToken(t *testing.T, body, page string) string {
+	t.Helper()
+	m := regexp.MustCompile(regexp.QuoteMeta(page) + `\?token=([^"&]+)`).FindStringSubmatch(body)
+	if m == nil {
+		t.Fatalf("email has no link to %s: %s", page, body)
+	}
+	return m[1]
+}
+
+func TestNewsletterSubscriberConfirmsByEmail(t *testing.T) {
+	userID := primitive.NewObjectID()
+	col := models.Collection{ID: primitive.NewObjectID(), UserID: userID, Name: "Reading list"}
+	repo := &fakeNewsletterRepo{}
+	mailer := &recordingMailer{}
+	s := newTestNewsletterService(repo, col, mailer)
+
+	sub, err := s.AddSubscriber(context.Background(), userID, col.ID, models.AddNewsletterSubscriberRequest{Email: "Reader@Example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.ConfirmedAt != nil || sub.Email != "reader@example.com" {
+		t.Errorf("added subscriber = %+v, want an unconfirmed reader@example.com", sub)
+	}
+	if len(mailer.to) != 1 || mailer.to[0] != "reader@example.com" {
+		t.Fatalf("sent to %v, want the confirmation sent to the subscriber", mailer.to)
+	}
+	confirm := linkToken(t, mailer.bodies[0], "https://markly.app/confirm")
+	unsubscribe := linkToken(t, mailer.bodies[0], "https://markly.app/unsubscribe")
+	if sub.ConfirmToken != utils.HashAPIKey(confirm) || sub.UnsubscribeToken != utils.HashAPIKey(unsubscribe) {
+		t.Error("stored tokens are not the hashes of the emailed ones")
+	}
+	if token, _ := s.encryption.Decrypt(context.Background(), userID, sub.EncryptedToken); token != unsubscribe {
+		t.Errorf("encrypted token decrypts to %q, want the unsubscribe token", token)
+	}
+
+	if err := s.Confirm(context.Background(), sub.ConfirmToken); err == nil || !strings.HasPrefix(err.Error(), "invalid") {
+		t.Errorf("Confirm with the stored hash = %v, want an invalid token", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.Confirm(context.Background(), confirm); err != nil {
+			t.Fatalf("Confirm #%d: %v", i+1, err)
+		}
+	}
+	if sub.ConfirmedAt == nil {
+		t.Error("subscriber is not confirmed")
+	}
+	if err := s.Unsubscribe(context.Background(), unsubscribe); err != nil || sub.UnsubscribedAt == nil {
+		t.Errorf("Unsubscribe = %v, unsubscribed at %v", err, sub.UnsubscribedAt)
+	}
+}
+
+func TestNewsletterAddSubscriberLimit(t *testing.T) {
+	userID := primitive.NewObjectID()
+	col := models.Collection{ID: primitive.NewObjectID(), UserID: userID, Name: "Reading list"}
+	repo := &fakeNewsletterRepo{count: maxNewsletterSubscribers + 1}
+	mailer := &recordingMailer{}
+	s := newTestNewsletterService(repo, col, mailer)
+
+	_, err := s.AddSubscriber(context.Background(), userID, col.ID, models.AddNewsletterSubscriberRequest{Email: "reader@example.com"})
+	if !errors.Is(err, utils.ErrLimitExceeded) {
+		t.Fatalf("AddSubscriber over the limit = %v, want %v", err, utils.ErrLimitExceeded)
+	}
+	if len(repo.subs) != 0 || len(mailer.to) != 0 {
+		t.Errorf("kept %d subscribers and sent %d emails over the limit", len(repo.subs), len(mailer.to))
+	}
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"markly/internal/models"
+)
+
+// Notifier tells users about events on their own account, such as a password reset
+// code or a support access request. Mail to people who are not users, such as
+// newsletter subscribers, goes through a Mailer instead.
+type Notifier interface {
+	Notify(ctx context.Context, user *models.User, subject, body string) error
+}
+
+// emailNotifier notifies users by email. body is HTML.
+type emailNotifier struct {
+	mailer Mailer
+}
+
+func NewEmailNotifier(mailer Mailer) Notifier {
+	return &emailNotifier{mailer: mailer}
+}
+
+func (n *emailNotifier) Notify(ctx context.Context, user *models.User, subject, body string) error {
+	if user.Email == "" {
+		return fmt.Errorf("user %s has no email address", user.ID.Hex())
+	}
+	return n.mailer.SendEmail(user.Email, subject, body)
+}
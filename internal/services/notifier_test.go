@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"markly/internal/models"
+)
+
+// recordingMailer is a Mailer that keeps the messages it is asked to send.
+type recordingMailer struct {
+	to, subjects, bodies []string
+}
+
+func (m *recordingMailer) SendEmail(to, subject, msg string) error {
+	m.to = append(m.to, to)
+	m.subjects = append(m.subjects, subject)
+	m.bodies = append(m.bodies, msg)
+	return nil
+}
+
+func TestEmailNotifier(t *testing.T) {
+	mailer := &recordingMailer{}
+	notifier := NewEmailNotifier(mailer)
+
+	if err := notifier.Notify(context.Background(), &models.User{Email: "ada@example.com"}, "Hello", "<p>Hi</p>"); err != nil {
+		t.Fatal(err)
+	}
+	if len(mailer.to) != 1 || mailer.to[0] != "ada@example.com" || mailer.subjects[0] != "Hello" {
+		t.Errorf("sent to %v with subjects %v", mailer.to, mailer.subjects)
+	}
+
+	if err := notifier.Notify(context.Background(), &models.User{}, "Hello", "<p>Hi</p>"); err == nil {
+		t.Error("Notify succeeded for a user without an email address")
+	}
+	if len(mailer.to) != 1 {
+		t.Errorf("sent %d messages, want 1", len(mailer.to))
+	}
+}
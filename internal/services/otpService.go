@@ -23,13 +23,13 @@ type OTPService interface {
 }
 
 type otpService struct {
-	userRepo     repositories.UserRepository
-	otpRepo      repositories.OTPRepository
-	emailService EmailService
+	userRepo repositories.UserRepository
+	otpRepo  repositories.OTPRepository
+	notifier Notifier
 }
 
-func NewOTPService(userRepo repositories.UserRepository, otpRepo repositories.OTPRepository, emailService EmailService) OTPService {
-	return &otpService{userRepo: userRepo, otpRepo: otpRepo, emailService: emailService}
+func NewOTPService(userRepo repositories.UserRepository, otpRepo repositories.OTPRepository, notifier Notifier) OTPService {
+	return &otpService{userRepo: userRepo, otpRepo: otpRepo, notifier: notifier}
 }
 
 func (s *otpService) GenerateOTPForgotPassword(ctx context.Context, email string) (string, error) {
@@ -63,7 +63,7 @@ func (s *otpService) GenerateOTPForgotPassword(ctx context.Context, email string
 
 	subject := "Your Password Reset OTP"
 	body := fmt.Sprintf("Your OTP for password reset is: %s", otpCode)
-	err = s.emailService.SendEmail(email, subject, body)
+	err = s.notifier.Notify(ctx, user, subject, body)
 	if err != nil {
 		return "", err
 	}
@@ -135,7 +135,7 @@ func (s *otpService) SendOTP(ctx context.Context, email string) error {
 
 	subject := "Your One-Time Password"
 	body := fmt.Sprintf("Your One-Time Password is: %s", otpCode)
-	err = s.emailService.SendEmail(email, subject, body)
+	err = s.notifier.Notify(ctx, user, subject, body)
 	if err != nil {
 		return err
 	}
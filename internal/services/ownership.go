@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/repositories"
+)
+
+// ErrForbidden is wrapped by the errors of lookups that found the resource, but
+// owned by another user.
+var ErrForbidden = errors.New("forbidden")
+
+// Resource names a kind of user-owned document for ownership checks.
+type Resource struct {
+	Collection string
+	Name       string
+}
+
+var (
+	ResourceBookmark   = Resource{Collection: "bookmarks", Name: "bookmark"}
+	ResourceCategory   = Resource{Collection: "categories", Name: "category"}
+	ResourceCollection = Resource{Collection: "collections", Name: "collection"}
+	ResourceTag        = Resource{Collection: "tags", Name: "tag"}
+	ResourceHighlight  = Resource{Collection: "highlights", Name: "highlight"}
+)
+
+// Ownership decides how a lookup of a user's resource that found nothing is
+// reported. By default every such lookup is "not found", so that IDs of other users'
+// resources cannot be probed. With REVEAL_RESOURCE_OWNERSHIP set to "true", a
+// resource that exists but belongs to another user is reported as forbidden.
+type Ownership struct {
+	repo   repositories.OwnershipRepository
+	reveal bool
+}
+
+func NewOwnership(repo repositories.OwnershipRepository) *Ownership {
+	return &Ownership{repo: repo, reveal: os.Getenv("REVEAL_RESOURCE_OWNERSHIP") == "true"}
+}
+
+// Missing returns the error for a resource id that userID has no access to:
+// notFound, or an error wrapping ErrForbidden when ownership is revealed and the
+// resource is someone else's. A nil Ownership always returns notFound.
+func (o *Ownership) Missing(ctx context.Context, userID primitive.ObjectID, resource Resource, id primitive.ObjectID, notFound error) error {
+	if o == nil || !o.reveal {
+		return notFound
+	}
+	owner, err := o.repo.Owner(ctx, resource.Collection, id)
+	if err != nil {
+		// Gone, or unknown because the lookup failed: either way not found.
+		return notFound
+	}
+	if owner == userID {
+		// Another request deleted or changed it in between.
+		return notFound
+	}
+	log.Warn().Str("userID", userID.Hex()).Str(resource.Name+"ID", id.Hex()).Msg("Access to another user's resource denied")
+	return fmt.Errorf("%w: %s belongs to another user", ErrForbidden, resource.Name)
+}
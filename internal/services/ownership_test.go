@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fakeOwners is an OwnershipRepository over a map of document IDs to owners.
+type fakeOwners map[primitive.ObjectID]primitive.ObjectID
+
+func (f fakeOwners) Owner(ctx context.Context, collection string, id primitive.ObjectID) (primitive.ObjectID, error) {
+	owner, ok := f[id]
+	if !ok {
+		return primitive.NilObjectID, mongo.ErrNoDocuments
+	}
+	return owner, nil
+}
+
+func TestOwnershipMissing(t *testing.T) {
+	me, other := primitive.NewObjectID(), primitive.NewObjectID()
+	mine, theirs, gone := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	repo := fakeOwners{mine: me, theirs: other}
+	notFound := errors.New("bookmark not found")
+	ctx := context.Background()
+
+	hidden := &Ownership{repo: repo}
+	if err := hidden.Missing(ctx, me, ResourceBookmark, theirs, notFound); err != notFound {
+		t.Errorf("hidden ownership: got %v, want not found", err)
+	}
+
+	revealed := &Ownership{repo: repo, reveal: true}
+	if err := revealed.Missing(ctx, me, ResourceBookmark, theirs, notFound); !errors.Is(err, ErrForbidden) {
+		t.Errorf("another user's bookmark: got %v, want forbidden", err)
+	}
+	for _, id := range []primitive.ObjectID{mine, gone} {
+		if err := revealed.Missing(ctx, me, ResourceBookmark, id, notFound); err != notFound {
+			t.Errorf("bookmark %s: got %v, want not found", id.Hex(), err)
+		}
+	}
+
+	var unset *Ownership
+	if err := unset.Missing(ctx, me, ResourceBookmark, theirs, notFound); err != notFound {
+		t.Errorf("nil ownership: got %v, want not found", err)
+	}
+}
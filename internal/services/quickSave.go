@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/extract"
+	"markly/internal/models"
+	"markly/internal/utils"
+)
+
+// Outcomes of the summary a quick save was asked for.
+const (
+	SelectionSummaryGenerated = "generated"
+	SelectionSummaryDisabled  = "disabled"
+	SelectionSummaryFailed    = "failed"
+)
+
+// QuickSaveService saves bookmarks from the browser extension, keeping the text
+// the user selected on the page as the bookmark's note.
+type QuickSaveService interface {
+	// QuickSave creates the bookmark and returns it with the outcome of the
+	// requested summary, or "" when none was requested.
+	QuickSave(ctx context.Context, userID primitive.ObjectID, req models.QuickSaveRequest) (*models.Bookmark, string, error)
+}
+
+type quickSaveServiceImpl struct {
+	bookmarks BookmarkService
+	agent     *AgentService
+}
+
+func NewQuickSaveService(bookmarks BookmarkService, agent *AgentService) QuickSaveService {
+	return &quickSaveServiceImpl{bookmarks: bookmarks, agent: agent}
+}
+
+// selectionText reduces a selection to its plain text and checks it against
+// the selection limit.
+func selectionText(selection string) (string, error) {
+	text := extract.FragmentText(selection)
+	if max := utils.CurrentLimits().MaxSelectionLength; utf8.RuneCountInString(text) > max {
+		return "", fmt.Errorf("%w: selection longer than %d characters", utils.ErrLimitExceeded, max)
+	}
+	return text, nil
+}
+
+func (s *quickSaveServiceImpl) QuickSave(ctx context.Context, userID primitive.ObjectID, req models.QuickSaveRequest) (*models.Bookmark, string, error) {
+	selection, err := selectionText(req.Selection)
+	if err != nil {
+		return nil, "", err
+	}
+
+	body := req.AddBookmarkRequestBody
+	if body.Source == nil {
+		body.Source = &models.BookmarkSource{Client: models.SourceClientExtension}
+	}
+	// The selection follows any note the user typed.
+	if body.Notes == "" {
+		body.Notes = selection
+	} else if selection != "" {
+		body.Notes += "\n\n" + selection
+	}
+
+	bm, err := s.bookmarks.AddBookmark(ctx, userID, body)
+	if err != nil {
+		return nil, "", err
+	}
+	if !req.SummarizeSelection || selection == "" {
+		return bm, "", nil
+	}
+	return bm, s.summarizeSelection(ctx, userID, bm, selection), nil
+}
+
+// summarizeSelection replaces the summary of a newly saved bookmark with one
+// centered on the selection. The bookmark is already saved, so failures are only
+// reported as the outcome.
+func (s *quickSaveServiceImpl) summarizeSelection(ctx context.Context, userID primitive.ObjectID, bm *models.Bookmark, selection string) string {
+	if err := s.agent.CheckExternalAI(userID); err != nil {
+		if errors.Is(err, ErrExternalAIDisabled) {
+			return SelectionSummaryDisabled
+		}
+		return SelectionSummaryFailed
+	}
+
+	highlights := []string{selection}
+	summary, err := s.agent.Summarize(ctx, bm.URL, bm.Title, highlights...)
+	if err != nil {
+		log.Warn().Err(err).Str("bookmark_id", bm.ID.Hex()).Msg("Failed to summarize quick save selection")
+		return SelectionSummaryFailed
+	}
+	s.agent.RecordAIEvent(models.AIEventKindSummary, models.AIEventGenerated, SummaryPrompt(highlights), 1)
+
+	if err := s.agent.UpdateBookmarkSummary(bm.ID, userID, summary, ""); err != nil {
+		log.Warn().Err(err).Str("bookmark_id", bm.ID.Hex()).Msg("Failed to save quick save summary")
+		return SelectionSummaryFailed
+	}
+	bm.Summary = summary
+	return SelectionSummaryGenerated
+}
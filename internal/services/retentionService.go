@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+// retentionTarget is where the records of a retention target are stored and the
+// date field they expire by. A monthly field holds a month as YYYY-MM instead.
+type retentionTarget struct {
+	collection string
+	field      string
+	monthly    bool
+}
+
+// retentionTargets maps each retention target to its records. Import jobs and
+// newsletter sends expire by the time they finished, so running ones are kept.
+// Impersonation requests expire by the end of their approval window, after which
+// no session started from them is left. Thumbnail usage expires once its month
+// has ended before the cutoff, so the current month's count is kept.
+var retentionTargets = map[string]retentionTarget{
+	models.RetentionAuditEvents:     {collection: "audit_events", field: "created_at"},
+	models.RetentionAIEvents:        {collection: "ai_events", field: "created_at"},
+	models.RetentionImportJobs:      {collection: "import_jobs", field: "finished_at"},
+	models.RetentionNewsletterSends: {collection: "newsletter_sends", field: "finished_at"},
+	models.RetentionImpersonations:  {collection: "impersonations", field: "approve_by"},
+	models.RetentionThumbnailUsage:  {collection: "thumbnail_usage", field: "month", monthly: true},
+}
+
+// before returns the value of the field below which records expire at cutoff.
+func (t retentionTarget) before(cutoff time.Time) interface{} {
+	if t.monthly {
+		return cutoff.Format("2006-01")
+	}
+	return cutoff
+}
+
+// RetentionService enforces the retention policy of the instance settings by
+// deleting the records that are older than their target allows.
+type RetentionService interface {
+	// Status returns the policy and the last purge this server ran.
+	Status(ctx context.Context) (*models.RetentionStatus, error)
+	// Purge applies the policy now on behalf of an admin. A dry run only counts
+	// the records that would be deleted.
+	Purge(ctx context.Context, adminID primitive.ObjectID, dryRun bool, ip string) (*models.RetentionReport, error)
+	// PurgeExpired is run by the scheduler. It follows the dry run setting of the
+	// policy.
+	PurgeExpired(ctx context.Context) error
+}
+
+type retentionServiceImpl struct {
+	retentionRepo repositories.RetentionRepository
+	instance      InstanceService
+	auditService  AuditService
+
+	mu      sync.Mutex
+	lastRun *models.RetentionReport
+}
+
+func NewRetentionService(retentionRepo repositories.RetentionRepository, instance InstanceService, auditService AuditService) RetentionService {
+	return &retentionServiceImpl{retentionRepo: retentionRepo, instance: instance, auditService: auditService}
+}
+
+func (s *retentionServiceImpl) Status(ctx context.Context) (*models.RetentionStatus, error) {
+	settings, err := s.instance.GetSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &models.RetentionStatus{Policy: settings.Retention, LastRun: s.lastRun}, nil
+}
+
+func (s *retentionServiceImpl) Purge(ctx context.Context, adminID primitive.ObjectID, dryRun bool, ip string) (*models.RetentionReport, error) {
+	log.Debug().Str("adminID", adminID.Hex()).Bool("dryRun", dryRun).Msg("Attempting to purge expired records")
+	settings, err := s.instance.GetSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.purge(ctx, settings.Retention, dryRun)
+	if !dryRun {
+		details, _ := json.Marshal(report.Targets)
+		if auditErr := s.auditService.Record(ctx, models.AuditEvent{
+			Action:  models.AuditRetentionPurged,
+			ActorID: adminID,
+			UserID:  adminID,
+			IP:      ip,
+			Details: string(details),
+		}); auditErr != nil {
+			log.Error().Err(auditErr).Str("adminID", adminID.Hex()).Msg("Failed to audit retention purge")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *retentionServiceImpl) PurgeExpired(ctx context.Context) error {
+	settings, err := s.instance.GetSettings(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = s.purge(ctx, settings.Retention, settings.Retention.DryRun)
+	return err
+}
+
+// purge counts and, unless dryRun, deletes the expired records of every target
+// the policy limits. A failing target is reported and does not stop the others.
+func (s *retentionServiceImpl) purge(ctx context.Context, policy models.RetentionPolicy, dryRun bool) (*models.RetentionReport, error) {
+	now := time.Now().UTC()
+	report := &models.RetentionReport{DryRun: dryRun, RanAt: now, Targets: []models.RetentionTargetReport{}}
+	var errs []error
+	for _, name := range models.RetentionTargets {
+		days := policy.Days[name]
+		if days <= 0 {
+			continue
+		}
+		target := retentionTargets[name]
+		tr := models.RetentionTargetReport{Target: name, Days: days, Cutoff: retentionCutoff(now, days)}
+		if err := s.purgeTarget(ctx, target, &tr, dryRun); err != nil {
+			log.Error().Err(err).Str("target", name).Int("days", days).Msg("Failed to purge expired records")
+			tr.Error = err.Error()
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		} else {
+			log.Info().Str("target", name).Int("days", days).Time("cutoff", tr.Cutoff).Int64("expired", tr.Expired).Int64("deleted", tr.Deleted).Bool("dryRun", dryRun).Msg("Retention policy applied")
+		}
+		report.Targets = append(report.Targets, tr)
+	}
+
+	s.mu.Lock()
+	s.lastRun = report
+	s.mu.Unlock()
+	if len(errs) > 0 {
+		return report, fmt.Errorf("failed to purge expired records: %w", errors.Join(errs...))
+	}
+	return report, nil
+}
+
+func (s *retentionServiceImpl) purgeTarget(ctx context.Context, target retentionTarget, tr *models.RetentionTargetReport, dryRun bool) error {
+	expired, err := s.retentionRepo.CountBefore(ctx, target.collection, target.field, target.before(tr.Cutoff))
+	if err != nil {
+		return err
+	}
+	tr.Expired = expired
+	utils.RetentionExpiredRecords.WithLabelValues(tr.Target).Set(float64(expired))
+	if dryRun || expired == 0 {
+		return nil
+	}
+	deleted, err := s.retentionRepo.DeleteBefore(ctx, target.collection, target.field, target.before(tr.Cutoff))
+	tr.Deleted = deleted
+	utils.RetentionPurgedRecordsTotal.WithLabelValues(tr.Target).Add(float64(deleted))
+	return err
+}
+
+// retentionCutoff returns the time before which records kept for days expire.
+func retentionCutoff(now time.Time, days int) time.Time {
+	return now.AddDate(0, 0, -days)
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"markly/internal/models"
+)
+
+// fakeRetention is a RetentionRepository over a map of collections to their
+// number of expired records. Collections in failing return an error.
+type fakeRetention struct {
+	expired map[string]int64
+	failing map[string]bool
+	deletes []string
+}
+
+func (f *fakeRetention) CountBefore(ctx context.Context, collection, field string, cutoff interface{}) (int64, error) {
+	if f.failing[collection] {
+		return 0, errors.New("connection reset")
+	}
+	return f.expired[collection], nil
+}
+
+func (f *fakeRetention) DeleteBefore(ctx context.Context, collection, field string, cutoff interface{}) (int64, error) {
+	f.deletes = append(f.deletes, collection)
+	n := f.expired[collection]
+	f.expired[collection] = 0
+	return n, nil
+}
+
+func TestRetentionTargetsAreMapped(t *testing.T) {
+	for _, target := range models.RetentionTargets {
+		if _, ok := retentionTargets[target]; !ok {
+			t.Errorf("retention target %s has no collection", target)
+		}
+	}
+}
+
+func TestRetentionPurge(t *testing.T) {
+	ctx := context.Background()
+	policy := models.RetentionPolicy{Days: map[string]int{models.RetentionAuditEvents: 365, models.RetentionAIEvents: 90}}
+
+	repo := &fakeRetention{expired: map[string]int64{"audit_events": 4, "ai_events": 0, "import_jobs": 7}}
+	s := &retentionServiceImpl{retentionRepo: repo}
+	report, err := s.purge(ctx, policy, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.deletes) != 0 {
+		t.Errorf("dry run deleted from %v", repo.deletes)
+	}
+	if len(report.Targets) != 2 || report.Targets[0].Target != models.RetentionAuditEvents || report.Targets[0].Expired != 4 {
+		t.Fatalf("dry run report = %+v", report.Targets)
+	}
+	if want := report.RanAt.AddDate(0, 0, -365); !report.Targets[0].Cutoff.Equal(want) {
+		t.Errorf("cutoff = %v, want %v", report.Targets[0].Cutoff, want)
+	}
+
+	report, err = s.purge(ctx, policy, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.deletes) != 1 || repo.deletes[0] != "audit_events" {
+		t.Errorf("deleted from %v, want only audit_events", repo.deletes)
+	}
+	if report.Targets[0].Deleted != 4 || s.lastRun != report {
+		t.Errorf("report = %+v", report.Targets)
+	}
+
+	repo = &fakeRetention{expired: map[string]int64{"ai_events": 2}, failing: map[string]bool{"audit_events": true}}
+	s = &retentionServiceImpl{retentionRepo: repo}
+	report, err = s.purge(ctx, policy, false)
+	if err == nil {
+		t.Fatal("purge with a failing target returned no error")
+	}
+	if report.Targets[0].Error == "" || report.Targets[1].Deleted != 2 {
+		t.Errorf("a failing target stopped the others: %+v", report.Targets)
+	}
+}
+
+func TestRetentionTargetBefore(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	cutoff := retentionCutoff(now, 30)
+	if got := retentionTargets[models.RetentionAuditEvents].before(cutoff); got != cutoff {
+		t.Errorf("audit events expire before %v, want %v", got, cutoff)
+	}
+	// Months before September 2026 ended before the cutoff of 14 September.
+	before := retentionTargets[models.RetentionThumbnailUsage].before(cutoff)
+	if before != "2026-09" {
+		t.Errorf("thumbnail usage expires before %v, want 2026-09", before)
+	}
+	if month := now.Format("2006-01"); month < before.(string) {
+		t.Error("the current month would expire")
+	}
+}
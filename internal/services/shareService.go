@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+const (
+	defaultShareLifetime = 7 * 24 * time.Hour
+	maxShareLifetime     = 90 * 24 * time.Hour
+	// maxActiveSharesPerBookmark bounds the unexpired, unrevoked links of one bookmark.
+	maxActiveSharesPerBookmark = 20
+)
+
+// ShareService manages expiring public links to single bookmarks. Links are
+// independent of collections: anyone with the token sees the bookmark's title, URL,
+// summary and archived content until the link expires or is revoked.
+type ShareService interface {
+	CreateShare(ctx context.Context, userID, bookmarkID primitive.ObjectID, req models.CreateShareRequest) (*models.CreatedShare, error)
+	ListShares(ctx context.Context, userID, bookmarkID primitive.ObjectID) ([]models.BookmarkShare, error)
+	RevokeShare(ctx context.Context, userID, bookmarkID, shareID primitive.ObjectID) error
+	// ViewShare returns the shared bookmark and counts a view. It needs no
+	// authentication.
+	ViewShare(ctx context.Context, token string) (*models.SharedBookmark, error)
+}
+
+type shareServiceImpl struct {
+	shareRepo    repositories.ShareRepository
+	bookmarkRepo repositories.BookmarkRepository
+	contentRepo  repositories.BookmarkContentRepository
+	ownership    *Ownership
+	linkURL      string
+}
+
+// NewShareService reads SHARE_LINK_URL, the page that shows shared bookmarks. Links
+// handed out are SHARE_LINK_URL?token=<token>.
+func NewShareService(shareRepo repositories.ShareRepository, bookmarkRepo repositories.BookmarkRepository, contentRepo repositories.BookmarkContentRepository, ownership *Ownership) ShareService {
+	return &shareServiceImpl{
+		shareRepo:    shareRepo,
+		bookmarkRepo: bookmarkRepo,
+		contentRepo:  contentRepo,
+		ownership:    ownership,
+		linkURL:      os.Getenv("SHARE_LINK_URL"),
+	}
+}
+
+// findBookmark checks that the bookmark exists and belongs to userID.
+func (s *shareServiceImpl) findBookmark(ctx context.Context, userID, bookmarkID primitive.ObjectID) error {
+	if _, err := s.bookmarkRepo.FindOne(ctx, bson.M{"_id": bookmarkID, "user_id": userID}); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return s.ownership.Missing(ctx, userID, ResourceBookmark, bookmarkID, fmt.Errorf("bookmark not found"))
+		}
+		log.Error().Err(err).Str("bookmarkID", bookmarkID.Hex()).Msg("Error finding bookmark for share link")
+		return fmt.Errorf("failed to retrieve bookmark")
+	}
+	return nil
+}
+
+func (s *shareServiceImpl) CreateShare(ctx context.Context, userID, bookmarkID primitive.ObjectID, req models.CreateShareRequest) (*models.CreatedShare, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Msg("Attempting to create share link")
+	now := time.Now()
+	expiresAt := now.Add(defaultShareLifetime)
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(now) {
+			return nil, fmt.Errorf("invalid expiry: expires_at must be in the future")
+		}
+		if req.ExpiresAt.Sub(now) > maxShareLifetime {
+			return nil, fmt.Errorf("invalid expiry: share links last at most %d days", int(maxShareLifetime.Hours()/24))
+		}
+		expiresAt = *req.ExpiresAt
+	}
+	if err := s.findBookmark(ctx, userID, bookmarkID); err != nil {
+		return nil, err
+	}
+	count, err := s.shareRepo.CountActive(ctx, userID, bookmarkID, now)
+	if err != nil {
+		log.Error().Err(err).Str("bookmarkID", bookmarkID.Hex()).Msg("Failed to count share links")
+		return nil, fmt.Errorf("failed to create share link")
+	}
+	if count >= maxActiveSharesPerBookmark {
+		return nil, fmt.Errorf("%w: at most %d active share links per bookmark", utils.ErrLimitExceeded, maxActiveSharesPerBookmark)
+	}
+
+	token, err := utils.GenerateToken()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate share token")
+		return nil, fmt.Errorf("failed to create share link")
+	}
+	share := &models.BookmarkShare{
+		ID:         primitive.NewObjectID(),
+		UserID:     userID,
+		BookmarkID: bookmarkID,
+		TokenHash:  utils.HashAPIKey(token),
+		ExpiresAt:  expiresAt,
+		CreatedAt:  now,
+	}
+	if _, err := s.shareRepo.Create(ctx, share); err != nil {
+		log.Error().Err(err).Str("bookmarkID", bookmarkID.Hex()).Msg("Failed to store share link")
+		return nil, fmt.Errorf("failed to create share link")
+	}
+
+	created := &models.CreatedShare{BookmarkShare: *share, Token: token}
+	if s.linkURL != "" {
+		created.URL = s.linkURL + "?token=" + url.QueryEscape(token)
+	}
+	log.Info().Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Str("shareID", share.ID.Hex()).Msg("Share link created successfully")
+	return created, nil
+}
+
+func (s *shareServiceImpl) ListShares(ctx context.Context, userID, bookmarkID primitive.ObjectID) ([]models.BookmarkShare, error) {
+	if err := s.findBookmark(ctx, userID, bookmarkID); err != nil {
+		return nil, err
+	}
+	shares, err := s.shareRepo.FindByBookmark(ctx, userID, bookmarkID)
+	if err != nil {
+		log.Error().Err(err).Str("bookmarkID", bookmarkID.Hex()).Msg("Failed to retrieve share links")
+		return nil, fmt.Errorf("failed to retrieve share links")
+	}
+	return shares, nil
+}
+
+// RevokeShare stops the link from working. It stays listed with its view count.
+func (s *shareServiceImpl) RevokeShare(ctx context.Context, userID, bookmarkID, shareID primitive.ObjectID) error {
+	log.Debug().Str("userID", userID.Hex()).Str("shareID", shareID.Hex()).Msg("Attempting to revoke share link")
+	result, err := s.shareRepo.Revoke(ctx, userID, bookmarkID, shareID, time.Now())
+	if err != nil {
+		log.Error().Err(err).Str("shareID", shareID.Hex()).Msg("Failed to revoke share link")
+		return fmt.Errorf("failed to revoke share link")
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("share link not found")
+	}
+	log.Info().Str("userID", userID.Hex()).Str("shareID", shareID.Hex()).Msg("Share link revoked successfully")
+	return nil
+}
+
+func (s *shareServiceImpl) ViewShare(ctx context.Context, token string) (*models.SharedBookmark, error) {
+	// Unknown, revoked and expired links are indistinguishable to the viewer.
+	notFound := fmt.Errorf("share link not found or expired")
+	if token == "" {
+		return nil, notFound
+	}
+	share, err := s.shareRepo.RecordView(ctx, utils.HashAPIKey(token), time.Now())
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, notFound
+		}
+		log.Error().Err(err).Msg("Failed to look up share link")
+		return nil, fmt.Errorf("failed to retrieve shared bookmark")
+	}
+
+	bm, err := s.bookmarkRepo.FindOne(ctx, bson.M{"_id": share.BookmarkID, "user_id": share.UserID})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, notFound
+		}
+		log.Error().Err(err).Str("shareID", share.ID.Hex()).Msg("Failed to load shared bookmark")
+		return nil, fmt.Errorf("failed to retrieve shared bookmark")
+	}
+
+	shared := &models.SharedBookmark{Title: bm.Title, URL: bm.URL, Summary: bm.Summary, ExpiresAt: share.ExpiresAt}
+	// Only content already archived for the current URL is shown; viewers never
+	// trigger an extraction.
+	content, err := s.contentRepo.FindByBookmark(ctx, share.UserID, share.BookmarkID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		log.Warn().Err(err).Str("shareID", share.ID.Hex()).Msg("Failed to load archived content of shared bookmark")
+	}
+	if content != nil && content.Status == models.ContentStatusOK && content.URL == bm.URL {
+		shared.Content = &models.SharedContent{
+			SiteName:    content.SiteName,
+			Text:        content.Text,
+			HTML:        content.HTML,
+			WordCount:   content.WordCount,
+			ExtractedAt: content.ExtractedAt,
+		}
+	}
+	return shared, nil
+}
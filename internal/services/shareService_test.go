@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+// fakeShares is a ShareRepository over a list of links.
+type fakeShares struct {
+	repositories.ShareRepository
+	shares []*models.BookmarkShare
+}
+
+func (f *fakeShares) Create(ctx context.Context, share *models.BookmarkShare) (*models.BookmarkShare, error) {
+	f.shares = append(f.shares, share)
+	return share, nil
+}
+
+func (f *fakeShares) viewable(share *models.BookmarkShare, now time.Time) bool {
+	return share.RevokedAt == nil && share.ExpiresAt.After(now)
+}
+
+func (f *fakeShares) CountActive(ctx context.Context, userID, bookmarkID primitive.ObjectID, now time.Time) (int64, error) {
+	var count int64
+	for _, share := range f.shares {
+		if share.UserID == userID && share.BookmarkID == bookmarkID && f.viewable(share, now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeShares) RecordView(ctx context.Context, tokenHash string, now time.Time) (*models.BookmarkShare, error) {
+	for _, share := range f.shares {
+		if share.TokenHash == tokenHash && f.viewable(share, now) {
+			share.Views++
+			share.LastViewedAt = &now
+			viewed := *share
+			return &viewed, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+// fakeShareBookmarks is a BookmarkRepository whose FindOne matches _id and user_id.
+type fakeShareBookmarks struct {
+	repositories.BookmarkRepository
+	bookmarks []*models.Bookmark
+}
+
+func (f *fakeShareBookmarks) FindOne(ctx context.Context, filter bson.M) (*models.Bookmark, error) {
+	for _, bm := range f.bookmarks {
+		if bm.ID == filter["_id"] && bm.UserID == filter["user_id"] {
+			return bm, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+type noContent struct {
+	repositories.BookmarkContentRepository
+}
+
+func (noContent) FindByBookmark(ctx context.Context, userID, bookmarkID primitive.ObjectID) (*models.BookmarkContent, error) {
+	return nil, mongo.ErrNoDocuments
+}
+
+func newTestShareService() (*shareServiceImpl, *fakeShares, *models.Bookmark) {
+	bm := &models.Bookmark{ID: primitive.NewObjectID(), UserID: primitive.NewObjectID(), Title: "Shared", URL: "https://example.com/a"}
+	shares := &fakeShares{}
+	s := &shareServiceImpl{
+		shareRepo:    shares,
+		bookmarkRepo: &fakeShareBookmarks{bookmarks: []*models.Bookmark{bm}},
+		contentRepo:  noContent{},
+		linkURL:      "https://markly.example/share",
+	}
+	return s, shares, bm
+}
+
+func TestShareTokenIsStoredHashed(t *testing.T) {
+	s, shares, bm := newTestShareService()
+	ctx := context.Background()
+
+	created, err := s.CreateShare(ctx, bm.UserID, bm.ID, models.CreateShareRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares.shares) != 1 {
+		t.Fatalf("stored %d links, want 1", len(shares.shares))
+	}
+	stored := shares.shares[0]
+	if created.Token == "" || stored.TokenHash == created.Token || stored.TokenHash != utils.HashAPIKey(created.Token) {
+		t.Errorf("stored token hash %q for token %q, want its hash", stored.TokenHash, created.Token)
+	}
+	if !strings.HasSuffix(created.URL, "?token="+created.Token) {
+		t.Errorf("url = %q, want it to carry the token", created.URL)
+	}
+
+	if _, err := s.ViewShare(ctx, stored.TokenHash); err == nil {
+		t.Error("the stored hash opened the link")
+	}
+	shared, err := s.ViewShare(ctx, created.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shared.Title != bm.Title || shared.URL != bm.URL {
+		t.Errorf("shared %q at %q, want %q at %q", shared.Title, shared.URL, bm.Title, bm.URL)
+	}
+}
+
+func TestCreateShareCapsExpiry(t *testing.T) {
+	s, shares, bm := newTestShareService()
+	ctx := context.Background()
+	at := func(d time.Duration) *time.Time {
+		t := time.Now().Add(d)
+		return &t
+	}
+
+	for _, expiresAt := range []*time.Time{at(-time.Minute), at(maxShareLifetime + time.Hour)} {
+		if _, err := s.CreateShare(ctx, bm.UserID, bm.ID, models.CreateShareRequest{ExpiresAt: expiresAt}); err == nil || !strings.Contains(err.Error(), "invalid expiry") {
+			t.Errorf("expiry %v: err = %v, want an invalid expiry", expiresAt, err)
+		}
+	}
+	if len(shares.shares) != 0 {
+		t.Fatalf("stored %d links with invalid expiries", len(shares.shares))
+	}
+
+	longest := at(maxShareLifetime - time.Minute)
+	created, err := s.CreateShare(ctx, bm.UserID, bm.ID, models.CreateShareRequest{ExpiresAt: longest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created.ExpiresAt.Equal(*longest) {
+		t.Errorf("expires at %v, want %v", created.ExpiresAt, *longest)
+	}
+	created, err = s.CreateShare(ctx, bm.UserID, bm.ID, models.CreateShareRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Until(created.ExpiresAt); d > defaultShareLifetime || d < defaultShareLifetime-time.Minute {
+		t.Errorf("default link expires in %v, want %v", d, defaultShareLifetime)
+	}
+}
+
+func TestCreateShareLimitsActiveLinks(t *testing.T) {
+	s, shares, bm := newTestShareService()
+	ctx := context.Background()
+	for i := 0; i < maxActiveSharesPerBookmark; i++ {
+		if _, err := s.CreateShare(ctx, bm.UserID, bm.ID, models.CreateShareRequest{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.CreateShare(ctx, bm.UserID, bm.ID, models.CreateShareRequest{}); !errors.Is(err, utils.ErrLimitExceeded) {
+		t.Fatalf("err = %v, want the limit exceeded", err)
+	}
+
+	// Revoked links no longer count.
+	now := time.Now()
+	shares.shares[0].RevokedAt = &now
+	if _, err := s.CreateShare(ctx, bm.UserID, bm.ID, models.CreateShareRequest{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestViewShareCountsViewsOfViewableLinks(t *testing.T) {
+	s, shares, bm := newTestShareService()
+	ctx := context.Background()
+	created, err := s.CreateShare(ctx, bm.UserID, bm.ID, models.CreateShareRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := s.ViewShare(ctx, created.Token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stored := shares.shares[0]
+	if stored.Views != 3 || stored.LastViewedAt == nil {
+		t.Errorf("views = %d, last viewed at %v, want 3 views", stored.Views, stored.LastViewedAt)
+	}
+
+	stored.ExpiresAt = time.Now().Add(-time.Second)
+	if _, err := s.ViewShare(ctx, created.Token); err == nil || err.Error() != "share link not found or expired" {
+		t.Errorf("expired link: err = %v", err)
+	}
+	if stored.Views != 3 {
+		t.Errorf("views = %d after viewing an expired link, want 3", stored.Views)
+	}
+	if _, err := s.ViewShare(ctx, ""); err == nil {
+		t.Error("an empty token opened a link")
+	}
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/utils"
+)
+
+// slugRepository is implemented by the repositories of resources that can be
+// addressed by slug.
+type slugRepository interface {
+	SlugInUse(ctx context.Context, userID primitive.ObjectID, slug string, excludeID primitive.ObjectID) (bool, error)
+}
+
+// maxSlugAttempts bounds the numeric suffixes tried before falling back to the ID.
+const maxSlugAttempts = 20
+
+// uniqueSlug derives a slug from name that no other resource of the user has or
+// had, appending -2, -3, ... on conflict. fallback is used when the name has no
+// characters a slug can keep.
+func uniqueSlug(ctx context.Context, repo slugRepository, userID, id primitive.ObjectID, name, fallback string) (string, error) {
+	base := slugBase(name, fallback)
+	for i := 1; i <= maxSlugAttempts; i++ {
+		candidate := base
+		if i > 1 {
+			candidate = fmt.Sprintf("%s-%d", base, i)
+		}
+		inUse, err := repo.SlugInUse(ctx, userID, candidate, id)
+		if err != nil {
+			return "", err
+		}
+		if !inUse {
+			return candidate, nil
+		}
+	}
+	return base + "-" + id.Hex(), nil
+}
+
+func slugBase(name, fallback string) string {
+	if base := utils.Slugify(name); base != "" {
+		return base
+	}
+	return fallback
+}
+
+// maxSlugSaves bounds the saves tried when concurrent writes take the slug.
+const maxSlugSaves = 3
+
+// saveWithSlug saves a new or renamed resource with a slug from uniqueSlug. Another
+// write can take the slug between the check and the save, which then fails on the
+// user_slug_unique index; it is retried with a fresh slug, and the last attempt
+// uses the slug suffixed with the ID that nothing else derives.
+func saveWithSlug(ctx context.Context, repo slugRepository, userID, id primitive.ObjectID, name, fallback string, save func(slug string) error) error {
+	for attempt := 1; ; attempt++ {
+		var slug string
+		if attempt < maxSlugSaves {
+			var err error
+			if slug, err = uniqueSlug(ctx, repo, userID, id, name, fallback); err != nil {
+				return fmt.Errorf("failed to generate %s slug: %w", fallback, err)
+			}
+		} else {
+			slug = slugBase(name, fallback) + "-" + id.Hex()
+		}
+		err := save(slug)
+		if !isSlugConflict(err) || attempt == maxSlugSaves {
+			return err
+		}
+		log.Debug().Str("slug", slug).Str("id", id.Hex()).Msg("Slug taken by a concurrent write, retrying")
+	}
+}
+
+// isSlugConflict reports whether err is a write rejected by the user_slug_unique
+// index rather than another unique index, such as the one on names.
+func isSlugConflict(err error) bool {
+	return mongo.IsDuplicateKeyError(err) && strings.Contains(err.Error(), "user_slug_unique")
+}
+
+// renamedSlugs returns the previous slugs of a resource whose slug changes from
+// current to next. The old slug keeps redirecting; next stops being a redirect.
+func renamedSlugs(current string, previous []string, next string) []string {
+	result := make([]string, 0, len(previous)+1)
+	for _, slug := range previous {
+		if slug != next && slug != current {
+			result = append(result, slug)
+		}
+	}
+	if current != "" {
+		result = append(result, current)
+	}
+	return result
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// freeSlugs reports every slug free, as for a check that runs before a concurrent
+// write takes the slug.
+type freeSlugs struct{}
+
+func (freeSlugs) SlugInUse(ctx context.Context, userID primitive.ObjectID, slug string, excludeID primitive.ObjectID) (bool, error) {
+	return false, nil
+}
+
+func slugConflict(index string) error {
+	return mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000, Message: "E11000 duplicate key error collection: markly.collections index: " + index + " dup key"}}}
+}
+
+func TestSaveWithSlugRetriesConflicts(t *testing.T) {
+	id := primitive.NewObjectID()
+	var saved []string
+	err := saveWithSlug(context.Background(), freeSlugs{}, primitive.NewObjectID(), id, "Reading list", "collection", func(slug string) error {
+		saved = append(saved, slug)
+		return slugConflict("user_slug_unique")
+	})
+	if !isSlugConflict(err) {
+		t.Errorf("err = %v, want the last slug conflict", err)
+	}
+	want := []string{"reading-list", "reading-list", "reading-list-" + id.Hex()}
+	if len(saved) != len(want) {
+		t.Fatalf("saved with %q, want %q", saved, want)
+	}
+	for i := range want {
+		if saved[i] != want[i] {
+			t.Errorf("save %d used %q, want %q", i+1, saved[i], want[i])
+		}
+	}
+
+	// A duplicate name is not retried.
+	saved = nil
+	err = saveWithSlug(context.Background(), freeSlugs{}, primitive.NewObjectID(), id, "Reading list", "collection", func(slug string) error {
+		saved = append(saved, slug)
+		return slugConflict("user_name_unique")
+	})
+	if !mongo.IsDuplicateKeyError(err) || isSlugConflict(err) || len(saved) != 1 {
+		t.Errorf("duplicate name: err = %v after %d saves", err, len(saved))
+	}
+}
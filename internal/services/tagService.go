@@ -19,17 +19,18 @@ import (
 type TagService interface {
 	AddTag(ctx context.Context, userID primitive.ObjectID, tag models.Tag) (*models.Tag, error)
 	GetTagsByID(ctx context.Context, userID primitive.ObjectID, ids []string) ([]models.Tag, error)
-	GetUserTags(ctx context.Context, userID primitive.ObjectID) ([]models.Tag, error)
+	GetUserTags(ctx context.Context, userID primitive.ObjectID, page models.PageRequest) ([]models.Tag, string, error)
 	DeleteTag(ctx context.Context, userID, tagID primitive.ObjectID) (bool, error)
 	UpdateTag(ctx context.Context, userID, tagID primitive.ObjectID, updatePayload models.TagUpdate) (*models.Tag, error)
 }
 
 type tagServiceImpl struct {
-	tagRepo repositories.TagRepository
+	tagRepo   repositories.TagRepository
+	ownership *Ownership
 }
 
-func NewTagService(tagRepo repositories.TagRepository) TagService {
-	return &tagServiceImpl{tagRepo: tagRepo}
+func NewTagService(tagRepo repositories.TagRepository, ownership *Ownership) TagService {
+	return &tagServiceImpl{tagRepo: tagRepo, ownership: ownership}
 }
 
 func (s *tagServiceImpl) AddTag(ctx context.Context, userID primitive.ObjectID, tag models.Tag) (*models.Tag, error) {
@@ -104,15 +105,15 @@ func (s *tagServiceImpl) GetTagsByID(ctx context.Context, userID primitive.Objec
 	return tags, nil
 }
 
-func (s *tagServiceImpl) GetUserTags(ctx context.Context, userID primitive.ObjectID) ([]models.Tag, error) {
+func (s *tagServiceImpl) GetUserTags(ctx context.Context, userID primitive.ObjectID, page models.PageRequest) ([]models.Tag, string, error) {
 	log.Debug().Str("userID", userID.Hex()).Msg("Attempting to retrieve user tags")
-	tags, err := s.tagRepo.FindByUser(ctx, userID)
+	tags, next, err := s.tagRepo.FindPageByUser(ctx, userID, page)
 	if err != nil {
 		log.Error().Err(err).Str("user_id", userID.Hex()).Msg("Error finding tags for user")
-		return nil, err
+		return nil, "", err
 	}
 	log.Debug().Str("userID", userID.Hex()).Int("count", len(tags)).Msg("Successfully retrieved user tags")
-	return tags, nil
+	return tags, next, nil
 }
 
 func (s *tagServiceImpl) DeleteTag(ctx context.Context, userID, tagID primitive.ObjectID) (bool, error) {
@@ -125,7 +126,7 @@ func (s *tagServiceImpl) DeleteTag(ctx context.Context, userID, tagID primitive.
 
 	if result.DeletedCount == 0 {
 		log.Warn().Str("userID", userID.Hex()).Str("tagID", tagID.Hex()).Msg("Tag not found or unauthorized to delete")
-		return false, fmt.Errorf("tag not found or unauthorized to delete")
+		return false, s.ownership.Missing(ctx, userID, ResourceTag, tagID, fmt.Errorf("tag not found or unauthorized to delete"))
 	}
 	log.Info().Str("userID", userID.Hex()).Str("tagID", tagID.Hex()).Msg("Tag deleted successfully")
 	return true, nil
@@ -172,7 +173,7 @@ func (s *tagServiceImpl) UpdateTag(ctx context.Context, userID, tagID primitive.
 
 	if result.MatchedCount == 0 {
 		log.Warn().Str("userID", userID.Hex()).Str("tagID", tagID.Hex()).Msg("Tag not found or unauthorized to update")
-		return nil, fmt.Errorf("tag not found or unauthorized to update")
+		return nil, s.ownership.Missing(ctx, userID, ResourceTag, tagID, fmt.Errorf("tag not found or unauthorized to update"))
 	}
 
 	updatedTag, err := s.tagRepo.FindByID(ctx, userID, tagID)
@@ -0,0 +1,347 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+const (
+	maxTagSubscriptionsPerUser = 100
+	maxSubscribedTagLength     = 64
+)
+
+// TagSubscriptionService manages the tags users follow and notifies them when
+// trending domains or AI suggestions match one. Each item is reported once per tag.
+type TagSubscriptionService interface {
+	CreateSubscription(ctx context.Context, userID primitive.ObjectID, req models.TagSubscriptionRequest) (*models.TagSubscription, error)
+	GetSubscriptions(ctx context.Context, userID primitive.ObjectID) ([]models.TagSubscription, error)
+	UpdateSubscription(ctx context.Context, userID, subID primitive.ObjectID, req models.TagSubscriptionRequest) (*models.TagSubscription, error)
+	DeleteSubscription(ctx context.Context, userID, subID primitive.ObjectID) (bool, error)
+	// NotifyTrendingDomains notifies the subscribers of the tags the snapshot's
+	// domains map to, using each subscriber's own domain tag rules.
+	NotifyTrendingDomains(ctx context.Context, snapshot *models.TrendingDomains) error
+	// NotifySuggestions notifies userID in the background about the suggestions
+	// carrying a tag they follow.
+	NotifySuggestions(userID primitive.ObjectID, suggestions []models.AISuggestion)
+}
+
+type tagSubscriptionServiceImpl struct {
+	subRepo  repositories.TagSubscriptionRepository
+	userRepo repositories.UserRepository
+	notifier Notifier
+	slots    chan struct{}
+}
+
+func NewTagSubscriptionService(subRepo repositories.TagSubscriptionRepository, userRepo repositories.UserRepository, notifier Notifier) TagSubscriptionService {
+	return &tagSubscriptionServiceImpl{
+		subRepo:  subRepo,
+		userRepo: userRepo,
+		notifier: notifier,
+		slots:    make(chan struct{}, 8),
+	}
+}
+
+func (s *tagSubscriptionServiceImpl) CreateSubscription(ctx context.Context, userID primitive.ObjectID, req models.TagSubscriptionRequest) (*models.TagSubscription, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("tag", req.Tag).Msg("Attempting to create tag subscription")
+	tag := normalizeSubscribedTag(req.Tag)
+	if tag == "" {
+		return nil, fmt.Errorf("invalid tag: must not be empty")
+	}
+	if len(tag) > maxSubscribedTagLength {
+		return nil, fmt.Errorf("invalid tag: at most %d characters", maxSubscribedTagLength)
+	}
+	count, err := s.subRepo.CountByUser(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to count tag subscriptions")
+		return nil, fmt.Errorf("failed to create tag subscription")
+	}
+	if count >= maxTagSubscriptionsPerUser {
+		return nil, fmt.Errorf("%w: at most %d tag subscriptions per user", utils.ErrLimitExceeded, maxTagSubscriptionsPerUser)
+	}
+
+	now := time.Now()
+	sub := &models.TagSubscription{
+		ID:          primitive.NewObjectID(),
+		UserID:      userID,
+		Tag:         tag,
+		Trending:    req.Trending == nil || *req.Trending,
+		Suggestions: req.Suggestions == nil || *req.Suggestions,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	created, err := s.subRepo.Create(ctx, sub)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("tag subscription already exists")
+		}
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to store tag subscription")
+		return nil, fmt.Errorf("failed to create tag subscription")
+	}
+	log.Info().Str("userID", userID.Hex()).Str("subscriptionID", created.ID.Hex()).Msg("Tag subscription created successfully")
+	return created, nil
+}
+
+func (s *tagSubscriptionServiceImpl) GetSubscriptions(ctx context.Context, userID primitive.ObjectID) ([]models.TagSubscription, error) {
+	subs, err := s.subRepo.FindByUser(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to retrieve tag subscriptions")
+		return nil, fmt.Errorf("failed to retrieve tag subscriptions")
+	}
+	return subs, nil
+}
+
+// UpdateSubscription changes which sources notify for the subscription. The tag
+// itself cannot be changed.
+func (s *tagSubscriptionServiceImpl) UpdateSubscription(ctx context.Context, userID, subID primitive.ObjectID, req models.TagSubscriptionRequest) (*models.TagSubscription, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("subscriptionID", subID.Hex()).Msg("Attempting to update tag subscription")
+	if req.Tag != "" {
+		return nil, fmt.Errorf("invalid update: the tag of a subscription cannot be changed")
+	}
+	updateFields := bson.M{}
+	if req.Trending != nil {
+		updateFields["trending"] = *req.Trending
+	}
+	if req.Suggestions != nil {
+		updateFields["suggestions"] = *req.Suggestions
+	}
+	if len(updateFields) == 0 {
+		return nil, fmt.Errorf("no valid fields provided for update")
+	}
+	updateFields["updated_at"] = time.Now()
+
+	sub, err := s.subRepo.Update(ctx, userID, subID, updateFields)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("tag subscription not found")
+		}
+		log.Error().Err(err).Str("userID", userID.Hex()).Str("subscriptionID", subID.Hex()).Msg("Failed to update tag subscription")
+		return nil, fmt.Errorf("failed to update tag subscription")
+	}
+	return sub, nil
+}
+
+func (s *tagSubscriptionServiceImpl) DeleteSubscription(ctx context.Context, userID, subID primitive.ObjectID) (bool, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("subscriptionID", subID.Hex()).Msg("Attempting to delete tag subscription")
+	result, err := s.subRepo.Delete(ctx, userID, subID)
+	if err != nil {
+		return false, err
+	}
+	if result.DeletedCount == 0 {
+		return false, fmt.Errorf("tag subscription not found")
+	}
+	log.Info().Str("userID", userID.Hex()).Str("subscriptionID", subID.Hex()).Msg("Tag subscription deleted successfully")
+	return true, nil
+}
+
+// tagMatch is an item that matched one of a user's subscribed tags.
+type tagMatch struct {
+	Tag   string
+	Title string
+	URL   string
+}
+
+func (s *tagSubscriptionServiceImpl) NotifyTrendingDomains(ctx context.Context, snapshot *models.TrendingDomains) error {
+	if len(snapshot.Domains) == 0 {
+		return nil
+	}
+	subs, err := s.subRepo.FindTrending(ctx)
+	if err != nil {
+		return err
+	}
+	byUser := map[primitive.ObjectID][]string{}
+	var userIDs []primitive.ObjectID
+	for _, sub := range subs {
+		if _, ok := byUser[sub.UserID]; !ok {
+			userIDs = append(userIDs, sub.UserID)
+		}
+		byUser[sub.UserID] = append(byUser[sub.UserID], sub.Tag)
+	}
+
+	notified := 0
+	for _, userID := range userIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		user, err := s.userRepo.FindByID(ctx, userID)
+		if err != nil {
+			log.Warn().Err(err).Str("userID", userID.Hex()).Msg("Failed to load tag subscriber")
+			continue
+		}
+		settings := user.Settings
+		if settings == nil {
+			settings = &models.UserSettings{}
+		}
+
+		var matches []tagMatch
+		for _, d := range snapshot.Domains {
+			for _, tag := range matchSubscribedTags(byUser[userID], domainTagNames(d.Domain, settings)) {
+				matches = append(matches, tagMatch{
+					Tag:   tag,
+					Title: fmt.Sprintf("%s (%d bookmarks this week)", d.Domain, d.Bookmarks),
+					URL:   "https://" + d.Domain,
+				})
+			}
+		}
+		if s.notify(ctx, user, models.TagNotificationTrending, "Trending for your tags", matches) {
+			notified++
+		}
+	}
+	log.Info().Int("subscribers", len(userIDs)).Int("notified", notified).Msg("Trending tag notifications sent")
+	return nil
+}
+
+func (s *tagSubscriptionServiceImpl) NotifySuggestions(userID primitive.ObjectID, suggestions []models.AISuggestion) {
+	if len(suggestions) == 0 {
+		return
+	}
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		log.Warn().Str("userID", userID.Hex()).Msg("Tag notification queue full, dropping suggestion notification")
+		return
+	}
+	go func() {
+		defer func() { <-s.slots }()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.notifySuggestions(ctx, userID, suggestions); err != nil {
+			log.Warn().Err(err).Str("userID", userID.Hex()).Msg("Suggestion tag notification failed")
+		}
+	}()
+}
+
+func (s *tagSubscriptionServiceImpl) notifySuggestions(ctx context.Context, userID primitive.ObjectID, suggestions []models.AISuggestion) error {
+	subs, err := s.subRepo.FindByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	var tags []string
+	for _, sub := range subs {
+		if sub.Suggestions {
+			tags = append(tags, sub.Tag)
+		}
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	var matches []tagMatch
+	for _, sg := range suggestions {
+		// Suggested URLs come from the LLM, so only well-formed http(s) links are
+		// put in the email.
+		link, err := utils.NormalizeURL(sg.URL)
+		if err != nil {
+			continue
+		}
+		for _, tag := range matchSubscribedTags(tags, sg.Tags) {
+			matches = append(matches, tagMatch{Tag: tag, Title: sg.Title, URL: link})
+		}
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+	s.notify(ctx, user, models.TagNotificationSuggestion, "AI suggestions for your tags", matches)
+	return nil
+}
+
+// notify sends the matches the user has not been told about yet and reports whether
+// a message was sent. Matches are marked as notified before sending, so a failed
+// delivery is not retried.
+func (s *tagSubscriptionServiceImpl) notify(ctx context.Context, user *models.User, source, subject string, matches []tagMatch) bool {
+	var fresh []tagMatch
+	for _, m := range matches {
+		ok, err := s.subRepo.MarkNotified(ctx, &models.TagNotification{
+			UserID:    user.ID,
+			Tag:       m.Tag,
+			Source:    source,
+			Item:      m.URL,
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			log.Error().Err(err).Str("userID", user.ID.Hex()).Str("tag", m.Tag).Msg("Failed to record tag notification")
+			continue
+		}
+		if ok {
+			fresh = append(fresh, m)
+		}
+	}
+	if len(fresh) == 0 {
+		return false
+	}
+	if err := s.notifier.Notify(ctx, user, subject, tagMatchesEmail(fresh)); err != nil {
+		log.Warn().Err(err).Str("userID", user.ID.Hex()).Str("source", source).Msg("Failed to send tag notification")
+		utils.DigestEmailsTotal.WithLabelValues("tag_notification", "failed").Inc()
+		return false
+	}
+	utils.DigestEmailsTotal.WithLabelValues("tag_notification", "sent").Inc()
+	return true
+}
+
+// normalizeSubscribedTag trims and lowercases a tag name so subscriptions match
+// tags regardless of case.
+func normalizeSubscribedTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// matchSubscribedTags returns the subscribed tags found in tags, each once, in the
+// order they appear in tags.
+func matchSubscribedTags(subscribed, tags []string) []string {
+	wanted := make(map[string]bool, len(subscribed))
+	for _, t := range subscribed {
+		wanted[t] = true
+	}
+	var matched []string
+	for _, t := range tags {
+		t = normalizeSubscribedTag(t)
+		if wanted[t] {
+			matched = append(matched, t)
+			delete(wanted, t)
+		}
+	}
+	return matched
+}
+
+// tagMatchesEmail lists the matches grouped by tag, in the order the tags first
+// appear.
+func tagMatchesEmail(matches []tagMatch) string {
+	var order []string
+	byTag := map[string][]tagMatch{}
+	for _, m := range matches {
+		if _, ok := byTag[m.Tag]; !ok {
+			order = append(order, m.Tag)
+		}
+		byTag[m.Tag] = append(byTag[m.Tag], m)
+	}
+
+	var b strings.Builder
+	b.WriteString("<p>New items match tags you follow on Markly.</p>")
+	for _, tag := range order {
+		fmt.Fprintf(&b, "<h3>#%s</h3><ul>", html.EscapeString(tag))
+		for _, m := range byTag[tag] {
+			title := m.Title
+			if title == "" {
+				title = m.URL
+			}
+			fmt.Fprintf(&b, `<li><a href="%s">%s</a></li>`, html.EscapeString(m.URL), html.EscapeString(title))
+		}
+		b.WriteString("</ul>")
+	}
+	b.WriteString("<p>You can change which tags you follow in your account settings.</p>")
+	return b.String()
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"markly/internal/models"
+)
+
+func TestMatchSubscribedTags(t *testing.T) {
+	subscribed := []string{"go", "video"}
+	got := matchSubscribedTags(subscribed, []string{"Video", "rust", " GO ", "go"})
+	if want := []string{"video", "go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("matchSubscribedTags = %v, want %v", got, want)
+	}
+	if got := matchSubscribedTags(subscribed, []string{"rust"}); len(got) != 0 {
+		t.Errorf("matchSubscribedTags = %v, want none", got)
+	}
+}
+
+func TestDomainTagNamesPrefersUserRules(t *testing.T) {
+	settings := &models.UserSettings{DomainTagRules: []models.DomainTagRule{{Domain: "github.com", Tags: []string{"go"}}}}
+	if got := domainTagNames("github.com", settings); !reflect.DeepEqual(got, []string{"go"}) {
+		t.Errorf("domainTagNames with a user rule = %v", got)
+	}
+	if got := domainTagNames("gist.github.com", &models.UserSettings{}); !reflect.DeepEqual(got, []string{"code"}) {
+		t.Errorf("domainTagNames with built-in rules = %v", got)
+	}
+}
+
+func TestTagMatchesEmail(t *testing.T) {
+	body := tagMatchesEmail([]tagMatch{
+		{Tag: "go", Title: "Go <1.24>", URL: "https://go.dev"},
+		{Tag: "video", URL: "https://youtube.com"},
+		{Tag: "go", Title: "Tour", URL: "https://go.dev/tour"},
+	})
+	if strings.Count(body, "<h3>") != 2 {
+		t.Errorf("expected one heading per tag, got %q", body)
+	}
+	if strings.Index(body, "go.dev/tour") > strings.Index(body, "#video") {
+		t.Error("matches of a tag are not grouped together")
+	}
+	if !strings.Contains(body, "Go &lt;1.24&gt;") || !strings.Contains(body, ">https://youtube.com</a>") {
+		t.Errorf("titles are not escaped or missing titles are not replaced by the URL: %q", body)
+	}
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+// defaultDomainTags is the built-in domain to tag mapping. Users extend or override it
+// through the domain_tag_rules setting.
+var defaultDomainTags = []models.DomainTagRule{
+	{Domain: "github.com", Tags: []string{"code"}},
+	{Domain: "gitlab.com", Tags: []string{"code"}},
+	{Domain: "bitbucket.org", Tags: []string{"code"}},
+	{Domain: "stackoverflow.com", Tags: []string{"code"}},
+	{Domain: "youtube.com", Tags: []string{"video"}},
+	{Domain: "youtu.be", Tags: []string{"video"}},
+	{Domain: "vimeo.com", Tags: []string{"video"}},
+	{Domain: "arxiv.org", Tags: []string{"paper"}},
+	{Domain: "scholar.google.com", Tags: []string{"paper"}},
+	{Domain: "medium.com", Tags: []string{"article"}},
+	{Domain: "dev.to", Tags: []string{"article"}},
+	{Domain: "wikipedia.org", Tags: []string{"reference"}},
+	{Domain: "news.ycombinator.com", Tags: []string{"news"}},
+	{Domain: "reddit.com", Tags: []string{"discussion"}},
+}
+
+// TagSuggestionService suggests tags for a URL from its domain, without calling an LLM.
+type TagSuggestionService interface {
+	// SuggestTagNames returns the tag names mapped to the URL's domain.
+	SuggestTagNames(ctx context.Context, userID primitive.ObjectID, rawURL string) ([]string, *models.UserSettings, error)
+	// ResolveTags returns the IDs of the user's tags with the given names, creating missing tags.
+	ResolveTags(ctx context.Context, userID primitive.ObjectID, names []string) ([]primitive.ObjectID, error)
+}
+
+type tagSuggestionServiceImpl struct {
+	userRepo repositories.UserRepository
+	tagRepo  repositories.TagRepository
+}
+
+func NewTagSuggestionService(userRepo repositories.UserRepository, tagRepo repositories.TagRepository) TagSuggestionService {
+	return &tagSuggestionServiceImpl{userRepo: userRepo, tagRepo: tagRepo}
+}
+
+func (s *tagSuggestionServiceImpl) SuggestTagNames(ctx context.Context, userID primitive.ObjectID, rawURL string) ([]string, *models.UserSettings, error) {
+	settings := &models.UserSettings{}
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to load user settings for tag suggestions")
+		return nil, nil, err
+	}
+	if user.Settings != nil {
+		settings = user.Settings
+	}
+
+	host := utils.URLHost(rawURL)
+	if host == "" {
+		return []string{}, settings, nil
+	}
+	return domainTagNames(host, settings), settings, nil
+}
+
+// domainTagNames returns the tags mapped to host by the user's rules or, when none
+// match, by the built-in mapping.
+func domainTagNames(host string, settings *models.UserSettings) []string {
+	// User rules are checked first so they can override a built-in domain.
+	if tags, ok := matchDomainTags(host, settings.DomainTagRules); ok {
+		return tags
+	}
+	tags, _ := matchDomainTags(host, defaultDomainTags)
+	return tags
+}
+
+// matchDomainTags returns the tags of the most specific rule matching host.
+func matchDomainTags(host string, rules []models.DomainTagRule) ([]string, bool) {
+	var best *models.DomainTagRule
+	for i := range rules {
+		domain := rules[i].Domain
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			continue
+		}
+		if best == nil || len(domain) > len(best.Domain) {
+			best = &rules[i]
+		}
+	}
+	if best == nil {
+		return []string{}, false
+	}
+	return append([]string{}, best.Tags...), true
+}
+
+func (s *tagSuggestionServiceImpl) ResolveTags(ctx context.Context, userID primitive.ObjectID, names []string) ([]primitive.ObjectID, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	existing, err := s.tagRepo.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]primitive.ObjectID, len(existing))
+	for _, tag := range existing {
+		byName[strings.ToLower(tag.Name)] = tag.ID
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(names))
+	for _, name := range names {
+		if id, ok := byName[strings.ToLower(name)]; ok {
+			ids = append(ids, id)
+			continue
+		}
+
+		tag := &models.Tag{
+			ID:        primitive.NewObjectID(),
+			Name:      name,
+			UserID:    userID,
+			CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
+		}
+		if _, err := s.tagRepo.Create(ctx, tag); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				// Created concurrently; skip it rather than fail the save.
+				log.Warn().Str("userID", userID.Hex()).Str("tagName", name).Msg("Suggested tag was created concurrently")
+				continue
+			}
+			return nil, err
+		}
+		log.Info().Str("userID", userID.Hex()).Str("tagName", name).Msg("Created tag from domain suggestion")
+		byName[strings.ToLower(name)] = tag.ID
+		ids = append(ids, tag.ID)
+	}
+	return ids, nil
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"markly/internal/extract"
+	"markly/internal/models"
+	"markly/internal/repositories"
+	"markly/internal/utils"
+)
+
+const (
+	// thumbnailSize is the largest width and height of a thumbnail, in pixels.
+	thumbnailSize               = 160
+	defaultThumbnailConcurrency = 2
+)
+
+// ThumbnailService makes the small preview images of bookmarks shown in
+// newsletters. Thumbnails are made from the page's preview image the first time
+// they are needed and cached until the preview image changes. Every generation,
+// failed or not, counts against the user's monthly limit.
+type ThumbnailService interface {
+	GetThumbnail(ctx context.Context, userID, bookmarkID primitive.ObjectID) (*models.BookmarkThumbnail, error)
+	// Thumbnails returns the thumbnails of the bookmarks that can have one, keyed by
+	// bookmark ID. Bookmarks whose thumbnail cannot be made are left out.
+	Thumbnails(ctx context.Context, userID primitive.ObjectID, bookmarks []models.Bookmark) map[primitive.ObjectID]*models.BookmarkThumbnail
+	QueueDepth() models.QueueDepth
+}
+
+type thumbnailServiceImpl struct {
+	thumbRepo    repositories.ThumbnailRepository
+	bookmarkRepo repositories.BookmarkRepository
+	ownership    *Ownership
+	fetcher      *extract.Fetcher
+	// slots bounds the images downloaded and resized at once; generations wait
+	// for a free slot.
+	slots chan struct{}
+}
+
+// NewThumbnailService reads THUMBNAIL_CONCURRENCY, the number of thumbnails made
+// at once, and CONTENT_EXTRACTION_ALLOW_PRIVATE like the content service.
+func NewThumbnailService(thumbRepo repositories.ThumbnailRepository, bookmarkRepo repositories.BookmarkRepository, ownership *Ownership) ThumbnailService {
+	return &thumbnailServiceImpl{
+		thumbRepo:    thumbRepo,
+		bookmarkRepo: bookmarkRepo,
+		ownership:    ownership,
+		fetcher:      extract.NewFetcher(os.Getenv("CONTENT_EXTRACTION_ALLOW_PRIVATE") == "true"),
+		slots:        make(chan struct{}, thumbnailConcurrency()),
+	}
+}
+
+func thumbnailConcurrency() int {
+	if v := os.Getenv("THUMBNAIL_CONCURRENCY"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+		log.Warn().Str("THUMBNAIL_CONCURRENCY", v).Msg("Invalid THUMBNAIL_CONCURRENCY, using default")
+	}
+	return defaultThumbnailConcurrency
+}
+
+func (s *thumbnailServiceImpl) GetThumbnail(ctx context.Context, userID, bookmarkID primitive.ObjectID) (*models.BookmarkThumbnail, error) {
+	log.Debug().Str("userID", userID.Hex()).Str("bookmarkID", bookmarkID.Hex()).Msg("Attempting to retrieve bookmark thumbnail")
+	bm, err := s.bookmarkRepo.FindOne(ctx, bson.M{"_id": bookmarkID, "user_id": userID})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, s.ownership.Missing(ctx, userID, ResourceBookmark, bookmarkID, fmt.Errorf("bookmark not found"))
+		}
+		log.Error().Err(err).Str("bookmarkID", bookmarkID.Hex()).Msg("Error finding bookmark for thumbnail")
+		return nil, fmt.Errorf("failed to retrieve bookmark")
+	}
+	return s.thumbnail(ctx, bm)
+}
+
+func (s *thumbnailServiceImpl) Thumbnails(ctx context.Context, userID primitive.ObjectID, bookmarks []models.Bookmark) map[primitive.ObjectID]*models.BookmarkThumbnail {
+	thumbs := map[primitive.ObjectID]*models.BookmarkThumbnail{}
+	for i := range bookmarks {
+		if bookmarks[i].ImageURL == "" {
+			continue
+		}
+		thumb, err := s.thumbnail(ctx, &bookmarks[i])
+		if err != nil {
+			log.Debug().Err(err).Str("bookmarkID", bookmarks[i].ID.Hex()).Msg("Skipping bookmark thumbnail")
+			continue
+		}
+		thumbs[bookmarks[i].ID] = thumb
+	}
+	return thumbs
+}
+
+func (s *thumbnailServiceImpl) thumbnail(ctx context.Context, bm *models.Bookmark) (*models.BookmarkThumbnail, error) {
+	if bm.ImageURL == "" {
+		return nil, fmt.Errorf("preview image not found: the page declares none or its content was not extracted")
+	}
+	cached, err := s.thumbRepo.FindByBookmark(ctx, bm.UserID, bm.ID)
+	if err == nil && cached.SourceURL == bm.ImageURL {
+		return cached, nil
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		log.Error().Err(err).Str("bookmarkID", bm.ID.Hex()).Msg("Error finding cached thumbnail")
+		return nil, fmt.Errorf("failed to retrieve thumbnail")
+	}
+
+	limit := utils.CurrentLimits().MaxThumbnailsPerMonth
+	ok, err := s.thumbRepo.ReserveGeneration(ctx, bm.UserID, time.Now().UTC().Format("2006-01"), limit)
+	if err != nil {
+		log.Error().Err(err).Str("userID", bm.UserID.Hex()).Msg("Failed to count thumbnail generation")
+		return nil, fmt.Errorf("failed to generate thumbnail")
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: at most %d thumbnails per month", utils.ErrLimitExceeded, limit)
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-s.slots }()
+
+	data, err := s.fetcher.FetchImage(ctx, bm.ImageURL)
+	if err != nil {
+		log.Warn().Err(err).Str("bookmarkID", bm.ID.Hex()).Str("imageURL", bm.ImageURL).Msg("Failed to fetch preview image")
+		return nil, fmt.Errorf("failed to fetch preview image: %v", err)
+	}
+	resized, err := utils.ResizeImage(data, thumbnailSize)
+	if err != nil {
+		log.Warn().Err(err).Str("bookmarkID", bm.ID.Hex()).Str("imageURL", bm.ImageURL).Msg("Failed to resize preview image")
+		return nil, fmt.Errorf("failed to fetch preview image: %v", err)
+	}
+
+	thumb := &models.BookmarkThumbnail{
+		BookmarkID:  bm.ID,
+		UserID:      bm.UserID,
+		SourceURL:   bm.ImageURL,
+		ContentType: "image/png",
+		Data:        resized,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.thumbRepo.Upsert(ctx, thumb); err != nil {
+		// The thumbnail is still usable for this request.
+		log.Error().Err(err).Str("bookmarkID", bm.ID.Hex()).Msg("Failed to cache thumbnail")
+	}
+	log.Debug().Str("bookmarkID", bm.ID.Hex()).Int("bytes", len(resized)).Msg("Generated bookmark thumbnail")
+	return thumb, nil
+}
+
+// QueueDepth reports the thumbnails being made.
+func (s *thumbnailServiceImpl) QueueDepth() models.QueueDepth {
+	return models.QueueDepth{InFlight: len(s.slots), Capacity: cap(s.slots)}
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/extract"
+	"markly/internal/utils"
+)
+
+// URLService canonicalizes URLs for every code path that stores or compares them,
+// so that duplicate detection sees the same URL regardless of how it was captured.
+type URLService interface {
+	Canonicalize(ctx context.Context, rawURL string) (string, error)
+	// CanonicalizeOffline applies the rules without following redirects. Imports and
+	// syncs use it, so that they do not make a request for every URL they read.
+	CanonicalizeOffline(rawURL string) (string, error)
+}
+
+type urlServiceImpl struct {
+	rules            []utils.URLRule
+	resolveRedirects bool
+	client           *http.Client
+}
+
+// NewURLService loads per-domain rules from the JSON file named by URL_RULES_FILE
+// (ahead of the built-in defaults) and follows redirects before canonicalizing when
+// URL_RESOLVE_REDIRECTS is "true". Like page extraction, redirects are only followed
+// on public addresses unless CONTENT_EXTRACTION_ALLOW_PRIVATE is "true".
+func NewURLService() URLService {
+	s := &urlServiceImpl{
+		rules:            utils.DefaultURLRules,
+		resolveRedirects: os.Getenv("URL_RESOLVE_REDIRECTS") == "true",
+		client:           extract.NewClient(3*time.Second, os.Getenv("CONTENT_EXTRACTION_ALLOW_PRIVATE") == "true"),
+	}
+
+	if path := os.Getenv("URL_RULES_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Error().Err(err).Str("path", path).Msg("Failed to read URL rules file, using default rules")
+			return s
+		}
+		var rules []utils.URLRule
+		if err := json.Unmarshal(data, &rules); err != nil {
+			log.Error().Err(err).Str("path", path).Msg("Failed to parse URL rules file, using default rules")
+			return s
+		}
+		s.rules = append(rules, utils.DefaultURLRules...)
+		log.Info().Int("count", len(rules)).Msg("Loaded URL canonicalization rules")
+	}
+	return s
+}
+
+func (s *urlServiceImpl) Canonicalize(ctx context.Context, rawURL string) (string, error) {
+	canonical, err := utils.CanonicalizeURL(rawURL, s.rules)
+	if err != nil {
+		return "", err
+	}
+	if !s.resolveRedirects {
+		return canonical, nil
+	}
+
+	resolved, err := s.resolve(ctx, canonical)
+	if err != nil {
+		log.Debug().Err(err).Str("url", canonical).Msg("Failed to resolve redirects, keeping unresolved URL")
+		return canonical, nil
+	}
+	if final, err := utils.CanonicalizeURL(resolved, s.rules); err == nil {
+		return final, nil
+	}
+	return canonical, nil
+}
+
+func (s *urlServiceImpl) CanonicalizeOffline(rawURL string) (string, error) {
+	return utils.CanonicalizeURL(rawURL, s.rules)
+}
+
+func (s *urlServiceImpl) resolve(ctx context.Context, rawURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Request.URL.String(), nil
+}
@@ -3,12 +3,14 @@ package services
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"golang.org/x/crypto/bcrypt"
 
 	"markly/internal/models"
 	"markly/internal/repositories"
@@ -17,23 +19,31 @@ import (
 
 // UserService defines the interface for user-related business logic.
 type UserService interface {
-	RegisterUser(ctx context.Context, user *models.User) (*models.User, error)
-	LoginUser(ctx context.Context, creds *models.Login) (string, error)
+	RegisterUser(ctx context.Context, req *models.RegisterRequest) (*models.User, error)
+	// LoginUser checks the credentials and returns a JWT. ip and userAgent are
+	// recorded in the user's access log.
+	LoginUser(ctx context.Context, creds *models.Login, ip, userAgent string) (string, error)
 	GetUserProfile(ctx context.Context, userID primitive.ObjectID) (*models.User, error)
 	UpdateUserProfile(ctx context.Context, userID primitive.ObjectID, updatePayload *models.UserProfileUpdate) (*models.User, error)
 	DeleteUser(ctx context.Context, userID primitive.ObjectID) error
 	GetTotalUsers(ctx context.Context) (int64, error)
+	GetSettings(ctx context.Context, userID primitive.ObjectID) (*models.UserSettings, error)
+	UpdateSettings(ctx context.Context, userID primitive.ObjectID, updatePayload *models.UserSettingsUpdate) (*models.UserSettings, error)
 }
 
 // userService implements UserService using a UserRepository.
 type userService struct {
 	userRepo repositories.UserRepository
+	instance InstanceService
+	audit    AuditService
 }
 
 // NewUserService creates a new UserService.
-func NewUserService(userRepo repositories.UserRepository) UserService {
+func NewUserService(userRepo repositories.UserRepository, instance InstanceService, audit AuditService) UserService {
 	return &userService{
 		userRepo: userRepo,
+		instance: instance,
+		audit:    audit,
 	}
 }
 
@@ -41,24 +51,36 @@ func (s *userService) GetTotalUsers(ctx context.Context) (int64, error) {
 	return s.userRepo.CountAll(ctx)
 }
 
-func (s *userService) RegisterUser(ctx context.Context, user *models.User) (*models.User, error) {
-	log.Debug().Str("email", user.Email).Msg("Attempting to register user")
-	if user.Username == "" || user.Email == "" || user.Password == "" {
+func (s *userService) RegisterUser(ctx context.Context, req *models.RegisterRequest) (*models.User, error) {
+	log.Debug().Str("email", req.Email).Msg("Attempting to register user")
+	if req.Username == "" || req.Email == "" || req.Password == "" {
 		log.Warn().Msg("Username, email, and password are required for registration")
 		return nil, fmt.Errorf("username, email, and password are required")
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), 8)
+	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to hash password during registration")
 		return nil, fmt.Errorf("failed to hash password")
 	}
-
-	user.Password = string(hashedPassword)
-	user.ID = primitive.NewObjectID()
+	now := time.Now()
+	user := &models.User{
+		ID:         primitive.NewObjectID(),
+		Username:   req.Username,
+		Email:      req.Email,
+		Password:   string(hashedPassword),
+		InviteCode: req.InviteCode,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := s.instance.AdmitSignup(ctx, user); err != nil {
+		log.Warn().Err(err).Str("email", user.Email).Msg("Registration rejected by instance settings")
+		return nil, err
+	}
 
 	createdUser, err := s.userRepo.Create(ctx, user)
 	if err != nil {
+		s.instance.AbortSignup(ctx, user)
 		if mongo.IsDuplicateKeyError(err) {
 			log.Warn().Str("email", user.Email).Msg("Email already exists during user insertion")
 			return nil, fmt.Errorf("email already exists")
@@ -72,8 +94,18 @@ func (s *userService) RegisterUser(ctx context.Context, user *models.User) (*mod
 	return createdUser, nil
 }
 
-func (s *userService) LoginUser(ctx context.Context, creds *models.Login) (string, error) {
+func (s *userService) LoginUser(ctx context.Context, creds *models.Login, ip, userAgent string) (string, error) {
 	log.Debug().Str("email", creds.Email).Msg("Attempting user login")
+	audience, err := utils.ParseAudience(creds.Client)
+	if err != nil {
+		return "", err
+	}
+	var scopes []string
+	if creds.Scopes != nil {
+		if scopes, err = utils.ParseScopes(creds.Scopes); err != nil {
+			return "", err
+		}
+	}
 	user, err := s.userRepo.FindByEmail(ctx, creds.Email)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
@@ -84,21 +116,46 @@ func (s *userService) LoginUser(ctx context.Context, creds *models.Login) (strin
 		return "", fmt.Errorf("internal server error")
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(creds.Password)); err != nil {
+	if !utils.CheckPasswordHash(creds.Password, user.Password) {
 		log.Warn().Str("email", creds.Email).Msg("Invalid credentials (password mismatch) during login attempt")
 		return "", fmt.Errorf("invalid credentials")
 	}
 
-	token, err := utils.GenerateJWT(user.ID)
+	token, err := utils.GenerateScopedJWT(user.ID, audience, scopes)
 	if err != nil {
 		log.Error().Err(err).Str("user_id", user.ID.Hex()).Msg("Could not generate token for user")
 		return "", fmt.Errorf("could not generate token")
 	}
 
+	s.rehashPassword(ctx, user, creds.Password)
+	details := "client: " + audience
+	if scopes != nil {
+		details += ", scopes: " + strings.Join(scopes, " ")
+	}
+	s.audit.RecordLogin(ctx, user.ID, models.AuthMethodPassword, ip, userAgent, details)
 	log.Info().Str("user_id", user.ID.Hex()).Msg("User logged in successfully")
 	return token, nil
 }
 
+// rehashPassword replaces the stored hash of a user who just logged in when it was
+// made with other hashing settings than are configured now. Failures are only
+// logged; the old hash keeps working.
+func (s *userService) rehashPassword(ctx context.Context, user *models.User, password string) {
+	if !utils.Passwords().NeedsRehash(user.Password) {
+		return
+	}
+	hashed, err := utils.HashPassword(password)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", user.ID.Hex()).Msg("Failed to rehash password")
+		return
+	}
+	if _, err := s.userRepo.Update(ctx, user.ID, bson.M{"password": hashed}); err != nil {
+		log.Error().Err(err).Str("user_id", user.ID.Hex()).Msg("Failed to store rehashed password")
+		return
+	}
+	log.Info().Str("user_id", user.ID.Hex()).Msg("Password rehashed with the current settings")
+}
+
 func (s *userService) GetUserProfile(ctx context.Context, userID primitive.ObjectID) (*models.User, error) {
 	log.Debug().Str("userID", userID.Hex()).Msg("Attempting to retrieve user profile")
 	user, err := s.userRepo.FindByID(ctx, userID)
@@ -138,11 +195,15 @@ func (s *userService) UpdateUserProfile(ctx context.Context, userID primitive.Ob
 				log.Error().Err(err).Str("email", *updatePayload.Email).Msg("Failed to check email availability during profile update")
 				return nil, fmt.Errorf("failed to check email availability: %w", err)
 			}
+			if err := s.instance.CheckEmailChange(ctx, *updatePayload.Email); err != nil {
+				log.Warn().Err(err).Str("user_id", userID.Hex()).Msg("Email change rejected by instance settings")
+				return nil, err
+			}
 		}
 		updateFields["email"] = *updatePayload.Email
 	}
 	if updatePayload.Password != nil && *updatePayload.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(*updatePayload.Password), 8)
+		hashedPassword, err := utils.HashPassword(*updatePayload.Password)
 		if err != nil {
 			log.Error().Err(err).Str("user_id", userID.Hex()).Msg("Failed to hash new password for profile update")
 			return nil, fmt.Errorf("failed to hash new password: %w", err)
@@ -192,3 +253,156 @@ func (s *userService) DeleteUser(ctx context.Context, userID primitive.ObjectID)
 
 	return nil
 }
+
+const maxDomainTagRules = 200
+
+func (s *userService) GetSettings(ctx context.Context, userID primitive.ObjectID) (*models.UserSettings, error) {
+	log.Debug().Str("userID", userID.Hex()).Msg("Attempting to retrieve user settings")
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			log.Warn().Str("user_id", userID.Hex()).Msg("User not found for GetSettings")
+			return nil, fmt.Errorf("user not found")
+		}
+		log.Error().Err(err).Str("user_id", userID.Hex()).Msg("Failed to fetch user settings")
+		return nil, fmt.Errorf("failed to fetch user settings")
+	}
+	if user.Settings == nil {
+		return &models.UserSettings{}, nil
+	}
+	return user.Settings, nil
+}
+
+func (s *userService) UpdateSettings(ctx context.Context, userID primitive.ObjectID, updatePayload *models.UserSettingsUpdate) (*models.UserSettings, error) {
+	log.Debug().Str("userID", userID.Hex()).Interface("updatePayload", updatePayload).Msg("Attempting to update user settings")
+	updateFields := bson.M{}
+
+	if updatePayload.DomainTagRules != nil {
+		rules, err := normalizeDomainTagRules(*updatePayload.DomainTagRules)
+		if err != nil {
+			log.Warn().Err(err).Str("userID", userID.Hex()).Msg("Invalid domain tag rules")
+			return nil, err
+		}
+		updateFields["settings.domain_tag_rules"] = rules
+	}
+	if updatePayload.AutoApplyDomainTags != nil {
+		updateFields["settings.auto_apply_domain_tags"] = *updatePayload.AutoApplyDomainTags
+	}
+	if updatePayload.ExcludeFromTrending != nil {
+		updateFields["settings.exclude_from_trending"] = *updatePayload.ExcludeFromTrending
+	}
+	if updatePayload.DisableExternalAI != nil {
+		updateFields["settings.disable_external_ai"] = *updatePayload.DisableExternalAI
+	}
+	if updatePayload.Timezone != nil {
+		tz := strings.TrimSpace(*updatePayload.Timezone)
+		if _, err := loadTimezone(tz); err != nil {
+			return nil, err
+		}
+		updateFields["settings.timezone"] = tz
+	}
+	if err := s.activityWebhookFields(ctx, userID, updatePayload, updateFields); err != nil {
+		return nil, err
+	}
+
+	if len(updateFields) == 0 {
+		log.Warn().Str("userID", userID.Hex()).Msg("No valid fields provided for user settings update")
+		return nil, fmt.Errorf("no valid fields provided for update")
+	}
+
+	result, err := s.userRepo.Update(ctx, userID, updateFields)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		log.Warn().Str("user_id", userID.Hex()).Msg("User not found while updating settings")
+		return nil, fmt.Errorf("user not found")
+	}
+
+	log.Info().Str("user_id", userID.Hex()).Msg("User settings updated successfully")
+	return s.GetSettings(ctx, userID)
+}
+
+// activityWebhookFields adds the activity webhook settings to updateFields. Enabling
+// the webhook requires a URL and generates its secret if it has none yet.
+func (s *userService) activityWebhookFields(ctx context.Context, userID primitive.ObjectID, updatePayload *models.UserSettingsUpdate, updateFields bson.M) error {
+	if updatePayload.ActivityWebhookURL == nil && updatePayload.ActivityWebhookEnabled == nil && !updatePayload.RotateActivityWebhookSecret {
+		return nil
+	}
+	current, err := s.GetSettings(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	webhookURL := current.ActivityWebhookURL
+	if updatePayload.ActivityWebhookURL != nil {
+		webhookURL = strings.TrimSpace(*updatePayload.ActivityWebhookURL)
+		if err := validateActivityWebhookURL(webhookURL); err != nil {
+			return err
+		}
+		updateFields["settings.activity_webhook_url"] = webhookURL
+	}
+	enabled := current.ActivityWebhookEnabled
+	if updatePayload.ActivityWebhookEnabled != nil {
+		enabled = *updatePayload.ActivityWebhookEnabled
+		updateFields["settings.activity_webhook_enabled"] = enabled
+	}
+	if webhookURL == "" && enabled {
+		if updatePayload.ActivityWebhookEnabled != nil {
+			return fmt.Errorf("invalid activity webhook: a url is required to enable it")
+		}
+		updateFields["settings.activity_webhook_enabled"] = false
+	}
+
+	if updatePayload.RotateActivityWebhookSecret || (enabled && current.ActivityWebhookSecret == "") {
+		secret, err := utils.GenerateToken()
+		if err != nil {
+			log.Error().Err(err).Str("userID", userID.Hex()).Msg("Failed to generate activity webhook secret")
+			return fmt.Errorf("failed to generate activity webhook secret")
+		}
+		updateFields["settings.activity_webhook_secret"] = secret
+	}
+	return nil
+}
+
+// validateActivityWebhookURL accepts an empty URL, which removes the webhook, or an
+// absolute http or https URL.
+func validateActivityWebhookURL(webhookURL string) error {
+	if webhookURL == "" {
+		return nil
+	}
+	u, err := url.Parse(strings.TrimSpace(webhookURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid activity webhook url: must be an http or https URL")
+	}
+	return nil
+}
+
+// normalizeDomainTagRules lowercases domains, trims tag names and rejects empty or
+// duplicate domains.
+func normalizeDomainTagRules(rules []models.DomainTagRule) ([]models.DomainTagRule, error) {
+	if len(rules) > maxDomainTagRules {
+		return nil, fmt.Errorf("invalid domain tag rules: at most %d rules are allowed", maxDomainTagRules)
+	}
+	seen := make(map[string]bool, len(rules))
+	normalized := make([]models.DomainTagRule, 0, len(rules))
+	for _, rule := range rules {
+		domain := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(rule.Domain)), "www.")
+		if domain == "" || strings.ContainsAny(domain, "/ ") {
+			return nil, fmt.Errorf("invalid domain tag rules: invalid domain %q", rule.Domain)
+		}
+		if seen[domain] {
+			return nil, fmt.Errorf("invalid domain tag rules: duplicate domain %q", domain)
+		}
+		seen[domain] = true
+
+		tags := []string{}
+		for _, tag := range rule.Tags {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		normalized = append(normalized, models.DomainTagRule{Domain: domain, Tags: tags})
+	}
+	return normalized, nil
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+const APIKeyPrefix = "mk_"
+
+// GenerateAPIKey returns a new random API key and the SHA-256 hash that is stored in its place.
+func GenerateAPIKey() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", "", err
+	}
+	key := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, HashAPIKey(key), nil
+}
+
+// GenerateToken returns a random URL-safe token for one-off links such as approvals.
+// Store it hashed with HashAPIKey.
+func GenerateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashAPIKey hashes a raw API key for storage and lookup.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// NormalizeCIDRs validates a list of CIDR blocks. Bare IP addresses are converted to single-host blocks.
+func NormalizeCIDRs(cidrs []string) ([]string, error) {
+	var normalized []string
+	for _, c := range cidrs {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if !strings.Contains(c, "/") {
+			ip := net.ParseIP(c)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid CIDR: %s", c)
+			}
+			if ip.To4() != nil {
+				c += "/32"
+			} else {
+				c += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR: %s", c)
+		}
+		normalized = append(normalized, ipNet.String())
+	}
+	return normalized, nil
+}
+
+// IPInCIDRs reports whether ip falls inside any of the given CIDR blocks.
+func IPInCIDRs(ip net.IP, cidrs []string) bool {
+	for _, c := range cidrs {
+		_, ipNet, err := net.ParseCIDR(c)
+		if err != nil {
+			continue
+		}
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
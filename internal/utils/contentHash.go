@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"math/bits"
+	"strings"
+)
+
+// NearDuplicateDistance is the largest Hamming distance between the simhashes of
+// two pages that are treated as the same article.
+const NearDuplicateDistance = 3
+
+// ContentHash returns the SHA-256 of the words of text, so pages that differ only
+// in case, punctuation or whitespace get the same hash. It returns "" for text
+// without words.
+func ContentHash(text string) string {
+	words := SearchWords(text)
+	if len(words) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(strings.Join(words, " ")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Simhash returns a 64-bit fingerprint of text over its three-word shingles.
+// Texts that share most of their shingles get fingerprints a few bits apart, so a
+// page with a changed date line or sidebar stays close to the original. It
+// returns 0 for text without words.
+func Simhash(text string) uint64 {
+	words := SearchWords(text)
+	if len(words) == 0 {
+		return 0
+	}
+	var weights [64]int
+	add := func(shingle string) {
+		h := fnv.New64a()
+		h.Write([]byte(shingle))
+		sum := h.Sum64()
+		for i := 0; i < 64; i++ {
+			if sum&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+	if len(words) < 3 {
+		add(strings.Join(words, " "))
+	}
+	for i := 0; i+3 <= len(words); i++ {
+		add(strings.Join(words[i:i+3], " "))
+	}
+
+	var fingerprint uint64
+	for i, w := range weights {
+		if w > 0 {
+			fingerprint |= 1 << uint(i)
+		}
+	}
+	return fingerprint
+}
+
+// HammingDistance counts the bits in which a and b differ.
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestContentHash(t *testing.T) {
+	a := ContentHash("Hello, World!\n\nThis is   the article.")
+	b := ContentHash("hello world this is the ARTICLE")
+	if a == "" || a != b {
+		t.Errorf("ContentHash differs for the same words: %q, %q", a, b)
+	}
+	if ContentHash("hello world this is another article") == a {
+		t.Error("ContentHash is the same for different words")
+	}
+	if ContentHash(" ... ") != "" {
+		t.Error("ContentHash of text without words is not empty")
+	}
+}
+
+func TestSimhash(t *testing.T) {
+	article := strings.Repeat("the quick brown fox jumps over the lazy dog while the cat watches from the fence ", 20)
+	edited := "Updated March 3. " + article + " Share this article."
+	other := strings.Repeat("markets fell sharply on tuesday as investors weighed new inflation figures and rates ", 20)
+
+	if d := HammingDistance(Simhash(article), Simhash(edited)); d > NearDuplicateDistance {
+		t.Errorf("distance between an article and its edit = %d, want at most %d", d, NearDuplicateDistance)
+	}
+	if d := HammingDistance(Simhash(article), Simhash(other)); d <= NearDuplicateDistance {
+		t.Errorf("distance between different articles = %d, want more than %d", d, NearDuplicateDistance)
+	}
+	if Simhash("") != 0 {
+		t.Error("Simhash of empty text is not 0")
+	}
+}
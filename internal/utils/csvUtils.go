@@ -0,0 +1,13 @@
+package utils
+
+import "strings"
+
+// CSVSafe returns s for use as a CSV cell. Values starting with a character that
+// spreadsheets treat as a formula are prefixed with a quote, so user-controlled
+// text such as a user agent cannot run as a formula when the file is opened.
+func CSVSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,21 @@
+package utils
+
+import "testing"
+
+func TestCSVSafe(t *testing.T) {
+	cases := map[string]string{
+		"":                     "",
+		"Mozilla/5.0":          "Mozilla/5.0",
+		"=HYPERLINK(\"x\")":    "'=HYPERLINK(\"x\")",
+		"+1":                   "'+1",
+		"-2":                   "'-2",
+		"@SUM(A1)":             "'@SUM(A1)",
+		"203.0.113.7":          "203.0.113.7",
+		"api key ci (mk_ab12)": "api key ci (mk_ab12)",
+	}
+	for in, want := range cases {
+		if got := CSVSafe(in); got != want {
+			t.Errorf("CSVSafe(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
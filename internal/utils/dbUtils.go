@@ -82,6 +82,31 @@ func ValidateReferences(client *mongo.Client, userID primitive.ObjectID, tagIDs
 	return nil
 }
 
+// OwnedIDs returns which of ids exist in collection and belong to the user, so a
+// batch of items can be checked against a single query.
+func OwnedIDs(ctx context.Context, client *mongo.Client, collection string, userID primitive.ObjectID, ids []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	owned := make(map[primitive.ObjectID]bool)
+	if len(ids) == 0 {
+		return owned, nil
+	}
+	cursor, err := client.Database("markly").Collection(collection).Find(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "user_id": userID},
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var docs []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	for _, d := range docs {
+		owned[d.ID] = true
+	}
+	return owned, nil
+}
+
 // CreateUniqueIndex creates a unique index on the specified collection and keys.
 // It returns an error if the index creation fails, including a specific error for duplicate keys.
 func CreateUniqueIndex(collection *mongo.Collection, keys interface{}, fieldName string) error {
@@ -0,0 +1,87 @@
+package utils
+
+// IsSingleEmoji reports whether s is exactly one emoji grapheme cluster: an emoji
+// with an optional variation selector and skin tone, a keycap, a flag, a tag
+// sequence flag, or emojis joined by zero-width joiners. The emoji ranges are an
+// approximation of Unicode's Extended_Pictographic property.
+func IsSingleEmoji(s string) bool {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return false
+	}
+	i, ok := emojiElement(runes, 0)
+	for ok && i < len(runes) && runes[i] == zeroWidthJoiner {
+		i, ok = emojiElement(runes, i+1)
+	}
+	return ok && i == len(runes)
+}
+
+const (
+	zeroWidthJoiner   = 0x200D
+	variationSelector = 0xFE0F
+	combiningKeycap   = 0x20E3
+	tagCancel         = 0xE007F
+)
+
+// emojiElement consumes one emoji starting at runes[i] and returns the index after it.
+func emojiElement(runes []rune, i int) (int, bool) {
+	if i >= len(runes) {
+		return i, false
+	}
+	r := runes[i]
+	switch {
+	case isRegionalIndicator(r):
+		if i+1 < len(runes) && isRegionalIndicator(runes[i+1]) {
+			return i + 2, true
+		}
+		return i, false
+	case r == '#' || r == '*' || (r >= '0' && r <= '9'):
+		i++
+		if i < len(runes) && runes[i] == variationSelector {
+			i++
+		}
+		if i < len(runes) && runes[i] == combiningKeycap {
+			return i + 1, true
+		}
+		return i, false
+	case !isPictographic(r):
+		return i, false
+	}
+
+	i++
+	if i < len(runes) && runes[i] == variationSelector {
+		i++
+	}
+	if i < len(runes) && runes[i] >= 0x1F3FB && runes[i] <= 0x1F3FF {
+		i++
+	}
+	if i < len(runes) && runes[i] >= 0xE0020 && runes[i] <= 0xE007E {
+		for i < len(runes) && runes[i] >= 0xE0020 && runes[i] <= 0xE007E {
+			i++
+		}
+		if i >= len(runes) || runes[i] != tagCancel {
+			return i, false
+		}
+		i++
+	}
+	return i, true
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+func isPictographic(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF:
+		return !isRegionalIndicator(r) && !(r >= 0x1F3FB && r <= 0x1F3FF)
+	case r >= 0x2600 && r <= 0x27BF, r >= 0x2300 && r <= 0x23FF, r >= 0x2B00 && r <= 0x2BFF,
+		r >= 0x2190 && r <= 0x21FF, r >= 0x25A0 && r <= 0x25FF, r >= 0x2934 && r <= 0x2935:
+		return true
+	}
+	switch r {
+	case 0x00A9, 0x00AE, 0x203C, 0x2049, 0x2122, 0x2139, 0x24C2, 0x3030, 0x303D, 0x3297, 0x3299:
+		return true
+	}
+	return false
+}
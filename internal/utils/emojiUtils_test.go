@@ -0,0 +1,18 @@
+package utils
+
+import "testing"
+
+func TestIsSingleEmoji(t *testing.T) {
+	valid := []string{"💻", "✈️", "👍🏽", "👩‍💻", "👨‍👩‍👧‍👦", "🇯🇵", "1️⃣", "🏴\U000E0067\U000E0062\U000E0065\U000E006E\U000E0067\U000E007F", "❤️‍🔥"}
+	for _, s := range valid {
+		if !IsSingleEmoji(s) {
+			t.Errorf("IsSingleEmoji(%q) = false, want true", s)
+		}
+	}
+	invalid := []string{"", "a", "💻💻", "tech 💻", "🇯", "1", "👍 ", "‍💻", "💻‍"}
+	for _, s := range invalid {
+		if IsSingleEmoji(s) {
+			t.Errorf("IsSingleEmoji(%q) = true, want false", s)
+		}
+	}
+}
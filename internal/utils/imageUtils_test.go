@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, w, h int, c color.Color) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestResizeImage(t *testing.T) {
+	cases := []struct {
+		w, h, wantW, wantH int
+	}{
+		{400, 200, 128, 64},
+		{100, 300, 42, 128},
+		{64, 64, 64, 64},
+	}
+	for _, c := range cases {
+		out, err := ResizeImage(encodePNG(t, c.w, c.h, color.NRGBA{R: 200, G: 10, B: 10, A: 255}), 128)
+		if err != nil {
+			t.Fatalf("ResizeImage(%dx%d): %v", c.w, c.h, err)
+		}
+		img, err := png.Decode(bytes.NewReader(out))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := img.Bounds(); b.Dx() != c.wantW || b.Dy() != c.wantH {
+			t.Errorf("ResizeImage(%dx%d) = %dx%d, want %dx%d", c.w, c.h, b.Dx(), b.Dy(), c.wantW, c.wantH)
+		}
+		if got := color.NRGBAModel.Convert(img.At(0, 0)).(color.NRGBA); got.R != 200 || got.A != 255 {
+			t.Errorf("ResizeImage(%dx%d) changed the color to %v", c.w, c.h, got)
+		}
+	}
+
+	if _, err := ResizeImage([]byte("not an image"), 128); err == nil {
+		t.Error("ResizeImage accepted a non-image")
+	}
+	if _, err := ResizeImage(encodePNG(t, maxImageDimension+1, 1, color.White), 128); err == nil {
+		t.Error("ResizeImage accepted an oversized image")
+	}
+}
@@ -1,25 +1,92 @@
 package utils
 
 import (
+	"fmt"
 	"github.com/golang-jwt/jwt/v5"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"os"
+	"strings"
 	"time"
 )
 
+// Token audiences, one per kind of client. The audience decides which routes a
+// token may call.
+const (
+	AudienceWeb       = "web"
+	AudienceExtension = "extension"
+	AudienceMobile    = "mobile"
+)
+
 type Claims struct {
 	ID string `json:"id"`
+	// ImpersonatorID and ImpersonationID are set on tokens issued to an admin
+	// acting as the user.
+	ImpersonatorID  string `json:"imp_by,omitempty"`
+	ImpersonationID string `json:"imp_id,omitempty"`
+	// Scope restricts the token to the space-separated scopes it names. Tokens
+	// without one hold whatever their audience allows.
+	Scope string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Client returns the audience the token was issued for. Tokens issued before
+// audiences existed carry none and count as web tokens.
+func (c *Claims) Client() string {
+	if len(c.Audience) == 0 {
+		return AudienceWeb
+	}
+	return c.Audience[0]
+}
+
+// Scopes returns what the token may do, or nil for full access. A scope claim
+// is limited to what the audience allows.
+func (c *Claims) Scopes() []string {
+	allowed := AudienceScopes(c.Client())
+	if c.Scope == "" {
+		return allowed
+	}
+	var scopes []string
+	for _, s := range strings.Fields(c.Scope) {
+		if allowed == nil || containsScope(allowed, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return scopes
+}
+
+// ParseAudience validates the client type a login asks a token for. An empty
+// value means a web token.
+func ParseAudience(client string) (string, error) {
+	switch client = strings.ToLower(strings.TrimSpace(client)); client {
+	case "":
+		return AudienceWeb, nil
+	case AudienceWeb, AudienceExtension, AudienceMobile:
+		return client, nil
+	}
+	return "", fmt.Errorf("invalid client: must be %s, %s or %s", AudienceWeb, AudienceExtension, AudienceMobile)
+}
+
 // Generate JWT
-func GenerateJWT(id primitive.ObjectID) (string, error) {
+func GenerateJWT(id primitive.ObjectID, audience string) (string, error) {
+	return GenerateScopedJWT(id, audience, nil)
+}
+
+// GenerateScopedJWT issues a token restricted to scopes. With no scopes the
+// token holds everything its audience allows.
+func GenerateScopedJWT(id primitive.ObjectID, audience string, scopes []string) (string, error) {
 	jwtKey := []byte(os.Getenv("JWT_SECRET"))
 
-	expirationTime := time.Now().Add(24 * time.Hour)
+	now := time.Now()
+	expirationTime := now.Add(24 * time.Hour)
 	claims := &Claims{
-		ID: id.Hex(),
+		ID:    id.Hex(),
+		Scope: strings.Join(scopes, " "),
 		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
 	}
@@ -27,3 +94,52 @@ func GenerateJWT(id primitive.ObjectID) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(jwtKey)
 }
+
+// GenerateImpersonationJWT issues a token for userID that is marked as used by
+// adminID under the given impersonation and expires at expiresAt.
+func GenerateImpersonationJWT(userID, adminID, impersonationID primitive.ObjectID, expiresAt time.Time) (string, error) {
+	jwtKey := []byte(os.Getenv("JWT_SECRET"))
+
+	claims := &Claims{
+		ID:              userID.Hex(),
+		ImpersonatorID:  adminID.Hex(),
+		ImpersonationID: impersonationID.Hex(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{AudienceWeb},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtKey)
+}
+
+// ParseJWT verifies the signature and expiry of tokenString and returns its
+// claims. Tokens for an unknown audience are rejected.
+func ParseJWT(tokenString string) (*Claims, error) {
+	jwtKey := []byte(os.Getenv("JWT_SECRET"))
+	if len(jwtKey) == 0 {
+		return nil, fmt.Errorf("jwt secret missing")
+	}
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return jwtKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+	if len(claims.Audience) > 1 {
+		return nil, fmt.Errorf("invalid token")
+	}
+	if claims.Scope != "" {
+		if _, err := ParseScopes(strings.Fields(claims.Scope)); err != nil {
+			return nil, fmt.Errorf("invalid token")
+		}
+	}
+	switch claims.Client() {
+	case AudienceWeb, AudienceExtension, AudienceMobile:
+		return claims, nil
+	}
+	return nil, fmt.Errorf("invalid token")
+}
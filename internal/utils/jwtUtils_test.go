@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseJWTAudience(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	userID := primitive.NewObjectID()
+
+	token, err := GenerateJWT(userID, AudienceExtension)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ParseJWT(token)
+	if err != nil {
+		t.Fatalf("ParseJWT: %v", err)
+	}
+	if claims.ID != userID.Hex() || claims.Client() != AudienceExtension {
+		t.Errorf("claims = %s/%s, want %s/%s", claims.ID, claims.Client(), userID.Hex(), AudienceExtension)
+	}
+
+	sign := func(c *Claims) string {
+		s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte("test-secret"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	exp := jwt.NewNumericDate(time.Now().Add(time.Hour))
+	legacy := sign(&Claims{ID: userID.Hex(), RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: exp}})
+	if claims, err := ParseJWT(legacy); err != nil || claims.Client() != AudienceWeb {
+		t.Errorf("token without audience: %v, want a web token", err)
+	}
+	unknown := sign(&Claims{ID: userID.Hex(), RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: exp, Audience: jwt.ClaimStrings{"tv"}}})
+	if _, err := ParseJWT(unknown); err == nil {
+		t.Error("token for an unknown audience was accepted")
+	}
+
+	if _, err := ParseAudience("Mobile"); err != nil {
+		t.Errorf("ParseAudience(Mobile) = %v", err)
+	}
+	if _, err := ParseAudience("desktop"); err == nil {
+		t.Error("ParseAudience(desktop) accepted an unknown client")
+	}
+}
+
+func TestClaimsScopes(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	userID := primitive.NewObjectID()
+
+	token, err := GenerateScopedJWT(userID, AudienceExtension, []string{ScopeBookmarksRead, ScopeExportRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ParseJWT(token)
+	if err != nil {
+		t.Fatalf("ParseJWT: %v", err)
+	}
+	// The extension audience never holds export:read, even when asked for it.
+	if got := claims.Scopes(); len(got) != 1 || got[0] != ScopeBookmarksRead {
+		t.Errorf("extension scopes = %v, want [%s]", got, ScopeBookmarksRead)
+	}
+
+	token, _ = GenerateJWT(userID, AudienceWeb)
+	if claims, _ := ParseJWT(token); claims.Scopes() != nil {
+		t.Errorf("web scopes = %v, want full access", claims.Scopes())
+	}
+	token, _ = GenerateJWT(userID, AudienceExtension)
+	if claims, _ := ParseJWT(token); len(claims.Scopes()) != 4 {
+		t.Errorf("unscoped extension scopes = %v, want the extension defaults", claims.Scopes())
+	}
+
+	bad, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{ID: userID.Hex(), Scope: "admin",
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}}).SignedString([]byte("test-secret"))
+	if _, err := ParseJWT(bad); err == nil {
+		t.Error("token with an unknown scope was accepted")
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	got, err := ParseScopes([]string{"export:read", " Bookmarks:Read ", "export:read"})
+	if err != nil || len(got) != 2 || got[0] != ScopeBookmarksRead || got[1] != ScopeExportRead {
+		t.Errorf("ParseScopes = %v, %v", got, err)
+	}
+	if _, err := ParseScopes([]string{}); err == nil {
+		t.Error("ParseScopes accepted no scopes")
+	}
+	if _, err := ParseScopes([]string{"bookmarks:delete"}); err == nil {
+		t.Error("ParseScopes accepted an unknown scope")
+	}
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// NormalizeLanguage reduces a BCP 47 language tag, such as "en-US" or "pt_BR", to its
+// lowercase base language ("en", "pt"). It returns an empty string for anything that
+// is not a known language.
+func NormalizeLanguage(tag string) string {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	if tag == "" {
+		return ""
+	}
+	parsed, err := language.Parse(tag)
+	if err != nil {
+		return ""
+	}
+	base, confidence := parsed.Base()
+	if confidence != language.Exact {
+		return ""
+	}
+	return base.String()
+}
+
+// LanguageName returns the English name of a language code, or the code itself when
+// it has none.
+func LanguageName(code string) string {
+	tag, err := language.Parse(code)
+	if err != nil {
+		return code
+	}
+	if name := display.English.Languages().Name(tag); name != "" {
+		return name
+	}
+	return code
+}
@@ -0,0 +1,25 @@
+package utils
+
+import "testing"
+
+func TestNormalizeLanguage(t *testing.T) {
+	cases := map[string]string{
+		"en":        "en",
+		"en-US":     "en",
+		"pt_BR":     "pt",
+		" AR ":      "ar",
+		"zh-Hant":   "zh",
+		"":          "",
+		"und":       "",
+		"english":   "",
+		"x-klingon": "",
+	}
+	for in, want := range cases {
+		if got := NormalizeLanguage(in); got != want {
+			t.Errorf("NormalizeLanguage(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := LanguageName("ar"); got != "Arabic" {
+		t.Errorf("LanguageName(\"ar\") = %q, want \"Arabic\"", got)
+	}
+}
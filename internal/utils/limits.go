@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"markly/internal/models"
+)
+
+// ErrLimitExceeded is wrapped by errors about a request going over one of the
+// configured limits. Handlers answer them with 422 Unprocessable Entity.
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+var (
+	limitsOnce sync.Once
+	limits     models.Limits
+)
+
+// CurrentLimits returns the limits configured through LIMIT_DEFAULT_PAGE_SIZE,
+// LIMIT_MAX_PAGE_SIZE, LIMIT_BOOKMARK_PAGE_SIZE, LIMIT_MAX_BATCH_SIZE,
+// LIMIT_MAX_TAGS_PER_BOOKMARK, LIMIT_MAX_COLLECTIONS_PER_USER,
+// LIMIT_MAX_THUMBNAILS_PER_MONTH and LIMIT_MAX_SELECTION_LENGTH. They are read once.
+func CurrentLimits() models.Limits {
+	limitsOnce.Do(func() { limits = loadLimits(os.Getenv) })
+	return limits
+}
+
+func loadLimits(getenv func(string) string) models.Limits {
+	l := models.DefaultLimits()
+	positive := func(name string, def int64) int64 {
+		v := getenv(name)
+		if v == "" {
+			return def
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			log.Warn().Str(name, v).Msg("Invalid limit, using default")
+			return def
+		}
+		return n
+	}
+
+	l.MaxPageSize = positive("LIMIT_MAX_PAGE_SIZE", l.MaxPageSize)
+	l.DefaultPageSize = min(positive("LIMIT_DEFAULT_PAGE_SIZE", l.DefaultPageSize), l.MaxPageSize)
+	l.BookmarkPageSize = positive("LIMIT_BOOKMARK_PAGE_SIZE", l.BookmarkPageSize)
+	l.MaxBatchSize = int(positive("LIMIT_MAX_BATCH_SIZE", int64(l.MaxBatchSize)))
+	l.MaxTagsPerBookmark = int(positive("LIMIT_MAX_TAGS_PER_BOOKMARK", int64(l.MaxTagsPerBookmark)))
+	l.MaxCollectionsPerUser = int(positive("LIMIT_MAX_COLLECTIONS_PER_USER", int64(l.MaxCollectionsPerUser)))
+	l.MaxThumbnailsPerMonth = int(positive("LIMIT_MAX_THUMBNAILS_PER_MONTH", int64(l.MaxThumbnailsPerMonth)))
+	l.MaxSelectionLength = int(positive("LIMIT_MAX_SELECTION_LENGTH", int64(l.MaxSelectionLength)))
+	return l
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"testing"
+
+	"markly/internal/models"
+)
+
+func TestLoadLimits(t *testing.T) {
+	env := map[string]string{
+		"LIMIT_MAX_PAGE_SIZE":            "20",
+		"LIMIT_DEFAULT_PAGE_SIZE":        "50",
+		"LIMIT_MAX_TAGS_PER_BOOKMARK":    "0",
+		"LIMIT_MAX_BATCH_SIZE":           "abc",
+		"LIMIT_BOOKMARK_PAGE_SIZE":       "10",
+		"LIMIT_MAX_THUMBNAILS_PER_MONTH": "25",
+		"LIMIT_MAX_SELECTION_LENGTH":     "-5",
+	}
+	got := loadLimits(func(name string) string { return env[name] })
+
+	want := models.DefaultLimits()
+	want.MaxPageSize = 20
+	want.DefaultPageSize = 20 // capped at the max
+	want.BookmarkPageSize = 10
+	want.MaxThumbnailsPerMonth = 25
+	if got != want {
+		t.Errorf("loadLimits = %+v, want %+v", got, want)
+	}
+}
@@ -37,6 +37,26 @@ var DBConnectionsIdle = promauto.NewGaugeVec(prometheus.GaugeOpts{
 	Help: "Number of idle database connections.",
 }, []string{"db_name"})
 
+var DBActiveTarget = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "db_active_target",
+	Help: "1 for the MongoDB target (primary or fallback-N) queries are sent to, 0 for the others.",
+}, []string{"target"})
+
+var DBTargetUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "db_target_up",
+	Help: "Whether the last health check of a MongoDB target passed.",
+}, []string{"target"})
+
+var DBTargetPingSeconds = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "db_target_ping_seconds",
+	Help: "Latency of the last passed health check of a MongoDB target.",
+}, []string{"target"})
+
+var DBFailoversTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "db_failovers_total",
+	Help: "Total number of switches between MongoDB targets.",
+}, []string{"from", "to"})
+
 var DBQueryDurationSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
 	Name:    "db_query_duration_seconds",
 	Help:    "Duration of database queries in seconds.",
@@ -52,3 +72,121 @@ func RegisterMetrics() {
 	prometheus.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
 	prometheus.MustRegister(prometheus.NewGoCollector())
 }
+
+// LLM Metrics. Users are deliberately not a label; per-user usage is logged instead.
+var LLMRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "llm_requests_total",
+	Help: "Total number of LLM requests.",
+}, []string{"provider", "model", "operation", "status"})
+
+var LLMRequestDurationSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "llm_request_duration_seconds",
+	Help:    "Duration of LLM requests in seconds.",
+	Buckets: []float64{0.5, 1, 2, 5, 10, 20, 30, 60},
+}, []string{"provider", "model", "operation"})
+
+var LLMTokensTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "llm_tokens_total",
+	Help: "Total number of LLM tokens by type (prompt or completion).",
+}, []string{"provider", "model", "operation", "type"})
+
+var LLMTokensPerRequest = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "llm_tokens_per_request",
+	Help:    "Number of LLM tokens per request by type (prompt or completion).",
+	Buckets: prometheus.ExponentialBuckets(64, 2, 10),
+}, []string{"provider", "model", "operation", "type"})
+
+var LLMCostUSDTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "llm_cost_usd_total",
+	Help: "Estimated cost of LLM requests in US dollars.",
+}, []string{"provider", "model", "operation"})
+
+// Scheduler Metrics
+var ScheduledJobRunsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "scheduled_job_runs_total",
+	Help: "Total number of scheduled job runs.",
+}, []string{"job", "status"})
+
+var ScheduledJobDurationSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "scheduled_job_duration_seconds",
+	Help:    "Duration of scheduled job runs in seconds.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"job"})
+
+var ScheduledJobLastSuccessTimestamp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "scheduled_job_last_success_timestamp_seconds",
+	Help: "Unix time of the last successful run of a scheduled job.",
+}, []string{"job"})
+
+var AutoArchivedBookmarksTotal = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "auto_archived_bookmarks_total",
+	Help: "Total number of bookmarks archived by collection auto-archive policies.",
+})
+
+// Backup Metrics
+var BackupDocumentsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "backup_documents_total",
+	Help: "Total number of documents written to or read from backups.",
+}, []string{"operation", "collection"})
+
+var BackupSizeBytes = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "backup_last_size_bytes",
+	Help: "Compressed size of the most recent backup in bytes.",
+})
+
+var ShadowRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "shadow_requests_total",
+	Help: "Total number of mirrored requests by comparison result.",
+}, []string{"result"})
+
+var ContentExtractionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "content_extractions_total",
+	Help: "Total number of bookmark content extractions by status.",
+}, []string{"status"})
+
+// Usage Metrics, refreshed by the usage-metrics job.
+var BookmarksTotal = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "bookmarks_total",
+	Help: "Number of stored bookmarks.",
+})
+
+var UsersByPlan = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "users_by_plan",
+	Help: "Number of user accounts per plan. Accounts without a plan are counted as \"none\".",
+}, []string{"plan"})
+
+// Queue Metrics, read from the in-process queues at every scrape.
+var BackgroundQueueInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "background_queue_in_flight",
+	Help: "Number of tasks running or waiting in a background queue.",
+}, []string{"queue"})
+
+var BackgroundQueueCapacity = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "background_queue_capacity",
+	Help: "Number of tasks a background queue holds before it drops or refuses new ones.",
+}, []string{"queue"})
+
+var BookmarkFavoritesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "bookmark_favorites_total",
+	Help: "Total number of bookmarks marked or unmarked as favorite through the favorite endpoints, by action (favorited or unfavorited).",
+}, []string{"action"})
+
+var ActivityWebhookDeliveriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "activity_webhook_deliveries_total",
+	Help: "Total number of activity webhook deliveries by status (delivered, failed or dropped).",
+}, []string{"status"})
+
+var RetentionExpiredRecords = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "retention_expired_records",
+	Help: "Number of records per retention target found older than the retention policy by the last purge, dry runs included.",
+}, []string{"target"})
+
+var RetentionPurgedRecordsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "retention_purged_records_total",
+	Help: "Total number of records deleted by the retention policy, by target.",
+}, []string{"target"})
+
+var DigestEmailsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "digest_emails_total",
+	Help: "Total number of digest emails by kind (newsletter or tag_notification) and status (sent or failed).",
+}, []string{"kind", "status"})
@@ -1,15 +1,204 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 
-// HashPassword hashes the given password using bcrypt.
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashing algorithms selectable with PASSWORD_HASH_ALGORITHM.
+const (
+	PasswordHashBcrypt   = "bcrypt"
+	PasswordHashArgon2id = "argon2id"
+)
+
+const (
+	defaultBcryptCost = 12
+	// The argon2id defaults are the OWASP minimum: 19 MiB, 2 iterations, 1 lane.
+	defaultArgon2Memory      = 19 * 1024
+	defaultArgon2Iterations  = 2
+	defaultArgon2Parallelism = 1
+	argon2SaltLength         = 16
+	argon2KeyLength          = 32
+	// The most the argon2id settings can be configured to. Stored hashes asking
+	// for more are refused rather than computed, so that a tampered hash cannot
+	// make a login attempt exhaust the server.
+	maxArgon2Memory      = 1024 * 1024
+	maxArgon2Iterations  = 100
+	maxArgon2Parallelism = 255
+	maxArgon2SaltLength  = 64
+	maxArgon2KeyLength   = 64
+)
+
+// PasswordHasher hashes new passwords with the configured algorithm and verifies
+// hashes made by any supported algorithm, so the configuration can change without
+// locking anyone out.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Verify reports whether password matches hash. Unknown or malformed hashes
+	// never match.
+	Verify(password, hash string) bool
+	// NeedsRehash reports whether hash was made with another algorithm or other
+	// parameters than new hashes are, and should be replaced on the next login.
+	NeedsRehash(hash string) bool
+}
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	keyLength   uint32
+}
+
+type passwordHasher struct {
+	algorithm  string
+	bcryptCost int
+	argon2     argon2Params
+}
+
+var (
+	passwordsOnce sync.Once
+	passwords     PasswordHasher
+)
+
+// Passwords returns the hasher configured through PASSWORD_HASH_ALGORITHM
+// ("bcrypt", the default, or "argon2id"), BCRYPT_COST, ARGON2_MEMORY_KIB,
+// ARGON2_ITERATIONS and ARGON2_PARALLELISM. They are read once.
+func Passwords() PasswordHasher {
+	passwordsOnce.Do(func() { passwords = loadPasswordHasher(os.Getenv) })
+	return passwords
+}
+
+func loadPasswordHasher(getenv func(string) string) *passwordHasher {
+	h := &passwordHasher{
+		algorithm:  PasswordHashBcrypt,
+		bcryptCost: defaultBcryptCost,
+		argon2: argon2Params{
+			memory:      defaultArgon2Memory,
+			iterations:  defaultArgon2Iterations,
+			parallelism: defaultArgon2Parallelism,
+			keyLength:   argon2KeyLength,
+		},
+	}
+	intInRange := func(name string, def, lo, hi int) int {
+		v := getenv(name)
+		if v == "" {
+			return def
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < lo || n > hi {
+			log.Warn().Str(name, v).Int("default", def).Msg("Invalid password hashing parameter, using default")
+			return def
+		}
+		return n
+	}
+
+	switch v := strings.ToLower(getenv("PASSWORD_HASH_ALGORITHM")); v {
+	case "", PasswordHashBcrypt:
+	case PasswordHashArgon2id:
+		h.algorithm = PasswordHashArgon2id
+	default:
+		log.Warn().Str("PASSWORD_HASH_ALGORITHM", v).Msg("Unknown password hashing algorithm, using bcrypt")
+	}
+	h.bcryptCost = intInRange("BCRYPT_COST", defaultBcryptCost, bcrypt.MinCost, bcrypt.MaxCost)
+	h.argon2.memory = uint32(intInRange("ARGON2_MEMORY_KIB", defaultArgon2Memory, 8, maxArgon2Memory))
+	h.argon2.iterations = uint32(intInRange("ARGON2_ITERATIONS", defaultArgon2Iterations, 1, maxArgon2Iterations))
+	h.argon2.parallelism = uint8(intInRange("ARGON2_PARALLELISM", defaultArgon2Parallelism, 1, maxArgon2Parallelism))
+	return h
+}
+
+func (h *passwordHasher) Hash(password string) (string, error) {
+	if h.algorithm == PasswordHashArgon2id {
+		salt := make([]byte, argon2SaltLength)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return "", err
+		}
+		p := h.argon2
+		key := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, p.keyLength)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.memory, p.iterations, p.parallelism,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	}
+	b, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+	return string(b), err
+}
+
+func (h *passwordHasher) Verify(password, hash string) bool {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		p, salt, key, err := decodeArgon2Hash(hash)
+		if err != nil {
+			return false
+		}
+		got := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, p.keyLength)
+		return subtle.ConstantTimeCompare(got, key) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+func (h *passwordHasher) NeedsRehash(hash string) bool {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		if h.algorithm != PasswordHashArgon2id {
+			return true
+		}
+		p, _, _, err := decodeArgon2Hash(hash)
+		return err != nil || p != h.argon2
+	}
+	if h.algorithm != PasswordHashBcrypt {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.bcryptCost
+}
+
+// decodeArgon2Hash parses a hash in the PHC string format written by Hash. Its
+// parameters must be within the range of the configuration.
+func decodeArgon2Hash(hash string) (argon2Params, []byte, []byte, error) {
+	var p argon2Params
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, fmt.Errorf("invalid argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2 version")
+	}
+	// Parsed wider than the parameters so that out-of-range values are refused
+	// instead of wrapping around.
+	var memory, iterations, parallelism uint64
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+	if memory < 8 || memory > maxArgon2Memory || iterations < 1 || iterations > maxArgon2Iterations || parallelism < 1 || parallelism > maxArgon2Parallelism {
+		return p, nil, nil, fmt.Errorf("argon2id parameters out of range: m=%d,t=%d,p=%d", memory, iterations, parallelism)
+	}
+	p.memory, p.iterations, p.parallelism = uint32(memory), uint32(iterations), uint8(parallelism)
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(salt) > maxArgon2SaltLength {
+		return p, nil, nil, fmt.Errorf("invalid argon2id salt")
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 || len(key) > maxArgon2KeyLength {
+		return p, nil, nil, fmt.Errorf("invalid argon2id key")
+	}
+	p.keyLength = uint32(len(key))
+	return p, salt, key, nil
+}
+
+// HashPassword hashes the given password with the configured algorithm.
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(bytes), err
+	return Passwords().Hash(password)
 }
 
 // CheckPasswordHash compares a hashed password with its possible plaintext equivalent.
 func CheckPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+	return Passwords().Verify(password, hash)
 }
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func testHasher(env map[string]string) *passwordHasher {
+	return loadPasswordHasher(func(name string) string { return env[name] })
+}
+
+func TestLoadPasswordHasher(t *testing.T) {
+	h := testHasher(map[string]string{
+		"PASSWORD_HASH_ALGORITHM": "Argon2id",
+		"BCRYPT_COST":             "2",
+		"ARGON2_MEMORY_KIB":       "65536",
+		"ARGON2_ITERATIONS":       "abc",
+	})
+	if h.algorithm != PasswordHashArgon2id {
+		t.Errorf("algorithm = %q, want %q", h.algorithm, PasswordHashArgon2id)
+	}
+	if h.bcryptCost != defaultBcryptCost {
+		t.Errorf("bcryptCost = %d, want default %d", h.bcryptCost, defaultBcryptCost)
+	}
+	if h.argon2.memory != 65536 || h.argon2.iterations != defaultArgon2Iterations {
+		t.Errorf("argon2 = %+v, want memory 65536 and default iterations", h.argon2)
+	}
+	if got := testHasher(map[string]string{"PASSWORD_HASH_ALGORITHM": "md5"}).algorithm; got != PasswordHashBcrypt {
+		t.Errorf("unknown algorithm = %q, want %q", got, PasswordHashBcrypt)
+	}
+}
+
+func TestPasswordHasherRehash(t *testing.T) {
+	bcrypt4 := testHasher(map[string]string{"BCRYPT_COST": "4"})
+	bcrypt5 := testHasher(map[string]string{"BCRYPT_COST": "5"})
+	argon := testHasher(map[string]string{"PASSWORD_HASH_ALGORITHM": "argon2id", "ARGON2_MEMORY_KIB": "64", "ARGON2_ITERATIONS": "1"})
+	argonMore := testHasher(map[string]string{"PASSWORD_HASH_ALGORITHM": "argon2id", "ARGON2_MEMORY_KIB": "128", "ARGON2_ITERATIONS": "1"})
+
+	bcryptHash, err := bcrypt4.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	argonHash, err := argon.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(argonHash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("argon2id hash = %q", argonHash)
+	}
+
+	cases := []struct {
+		name   string
+		hasher *passwordHasher
+		hash   string
+		rehash bool
+	}{
+		{"same bcrypt cost", bcrypt4, bcryptHash, false},
+		{"higher bcrypt cost", bcrypt5, bcryptHash, true},
+		{"bcrypt to argon2id", argon, bcryptHash, true},
+		{"same argon2id params", argon, argonHash, false},
+		{"more argon2id memory", argonMore, argonHash, true},
+		{"argon2id to bcrypt", bcrypt4, argonHash, true},
+	}
+	for _, c := range cases {
+		// Every hasher verifies hashes of every algorithm.
+		if !c.hasher.Verify("secret", c.hash) {
+			t.Errorf("%s: Verify(correct password) = false", c.name)
+		}
+		if c.hasher.Verify("wrong", c.hash) {
+			t.Errorf("%s: Verify(wrong password) = true", c.name)
+		}
+		if got := c.hasher.NeedsRehash(c.hash); got != c.rehash {
+			t.Errorf("%s: NeedsRehash = %v, want %v", c.name, got, c.rehash)
+		}
+	}
+	if argon.Verify("", "") || argon.Verify("secret", "$argon2id$v=19$m=64,t=1,p=1$bad") {
+		t.Error("Verify accepted a malformed hash")
+	}
+}
+
+func TestPasswordHasherRefusesOutOfRangeArgon2Params(t *testing.T) {
+	argon := testHasher(map[string]string{"PASSWORD_HASH_ALGORITHM": "argon2id", "ARGON2_MEMORY_KIB": "64", "ARGON2_ITERATIONS": "1"})
+	hash, err := argon.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The same salt and key with parameters no configuration allows; verifying
+	// them would take gigabytes of memory, hours, or panic.
+	for _, params := range []string{"m=4194304,t=1,p=1", "m=64,t=1000000,p=1", "m=64,t=1,p=0", "m=64,t=1,p=256", "m=4294967360,t=1,p=1"} {
+		tampered := strings.Replace(hash, "m=64,t=1,p=1", params, 1)
+		if argon.Verify("secret", tampered) {
+			t.Errorf("Verify accepted %s", params)
+		}
+		if !argon.NeedsRehash(tampered) {
+			t.Errorf("NeedsRehash(%s) = false", params)
+		}
+	}
+}
@@ -2,10 +2,19 @@ package utils
 
 import (
 	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"markly/internal/models"
 )
 
 // GetUserIDFromContext extracts and parses the userID from the request context.
@@ -40,3 +49,86 @@ func GetObjectIDFromVars(w http.ResponseWriter, r *http.Request, paramName strin
 	}
 	return objID, nil
 }
+
+// GetPageRequest reads the limit and cursor query parameters of a list request.
+func GetPageRequest(r *http.Request) (models.PageRequest, error) {
+	query := r.URL.Query()
+	page := models.PageRequest{Cursor: query.Get("cursor")}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || limit < 1 {
+			return page, fmt.Errorf("invalid limit: must be a positive number")
+		}
+		if max := CurrentLimits().MaxPageSize; limit > max {
+			return page, fmt.Errorf("%w: limit must be at most %d", ErrLimitExceeded, max)
+		}
+		page.Limit = limit
+	}
+	return page, nil
+}
+
+// SetNextCursor sets the X-Next-Cursor header when there is a next page.
+func SetNextCursor(w http.ResponseWriter, next string) {
+	if next != "" {
+		w.Header().Set("X-Next-Cursor", next)
+	}
+}
+
+// defaultTrustedProxies are the proxies X-Forwarded-For hops are taken from when
+// TRUSTED_PROXIES is not set: loopback and private networks.
+var defaultTrustedProxies = []string{"127.0.0.0/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "::1/128", "fc00::/7"}
+
+var (
+	trustedProxiesOnce sync.Once
+	trustedProxies     []string
+)
+
+// ClientIP returns the caller's IP address. X-Forwarded-For is only honoured when
+// TRUST_PROXY is "true", and then only for requests from the TRUSTED_PROXIES, a
+// comma-separated list of CIDR blocks. The address is the rightmost hop that is
+// not a trusted proxy: hops to the left of it were sent by the client.
+func ClientIP(r *http.Request) string {
+	if os.Getenv("TRUST_PROXY") != "true" {
+		return clientIP(r, nil)
+	}
+	trustedProxiesOnce.Do(func() {
+		trustedProxies = defaultTrustedProxies
+		if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+			cidrs, err := NormalizeCIDRs(strings.Split(v, ","))
+			if err != nil {
+				log.Warn().Err(err).Msg("Invalid TRUSTED_PROXIES, trusting private networks")
+				return
+			}
+			trustedProxies = cidrs
+		}
+	})
+	return clientIP(r, trustedProxies)
+}
+
+// clientIP returns the rightmost address of the remote address and the
+// X-Forwarded-For hops in front of it that is not in proxies.
+func clientIP(r *http.Request, proxies []string) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if len(proxies) == 0 {
+		return ip
+	}
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops); ; i-- {
+		parsed := net.ParseIP(ip)
+		if parsed == nil || !IPInCIDRs(parsed, proxies) || i == 0 {
+			return ip
+		}
+		hop := strings.TrimSpace(hops[i-1])
+		if net.ParseIP(hop) == nil {
+			// A malformed hop ends the chain of proxies we can vouch for.
+			return ip
+		}
+		ip = hop
+	}
+}
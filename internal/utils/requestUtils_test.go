@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		proxies    []string
+		want       string
+	}{
+		{name: "proxies not trusted", remoteAddr: "10.0.0.2:1234", forwarded: []string{"203.0.113.7"}, want: "10.0.0.2"},
+		{name: "request not from a proxy", remoteAddr: "198.51.100.9:1234", forwarded: []string{"203.0.113.7"}, proxies: defaultTrustedProxies, want: "198.51.100.9"},
+		{name: "one proxy", remoteAddr: "10.0.0.2:1234", forwarded: []string{"203.0.113.7"}, proxies: defaultTrustedProxies, want: "203.0.113.7"},
+		{name: "forged hop", remoteAddr: "10.0.0.2:1234", forwarded: []string{"1.2.3.4, 203.0.113.7"}, proxies: defaultTrustedProxies, want: "203.0.113.7"},
+		{name: "proxy chain", remoteAddr: "10.0.0.2:1234", forwarded: []string{"1.2.3.4, 203.0.113.7, 10.0.0.3"}, proxies: defaultTrustedProxies, want: "203.0.113.7"},
+		{name: "repeated header", remoteAddr: "10.0.0.2:1234", forwarded: []string{"1.2.3.4", "203.0.113.7"}, proxies: defaultTrustedProxies, want: "203.0.113.7"},
+		{name: "malformed hop", remoteAddr: "10.0.0.2:1234", forwarded: []string{"203.0.113.7, bogus"}, proxies: defaultTrustedProxies, want: "10.0.0.2"},
+		{name: "only proxies", remoteAddr: "10.0.0.2:1234", forwarded: []string{"192.168.1.5, 10.0.0.3"}, proxies: defaultTrustedProxies, want: "192.168.1.5"},
+		{name: "no header", remoteAddr: "10.0.0.2:1234", proxies: defaultTrustedProxies, want: "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if got := clientIP(r, tt.proxies); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,13 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 )
 
 func RespondWithError(w http.ResponseWriter, code int, message string) {
@@ -22,3 +27,29 @@ func SendJSONError(w http.ResponseWriter, message string, statusCode int) {
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
+
+// RespondWithCachedJSON sends payload with an ETag and lets private caches keep it
+// for maxAge. A request whose If-None-Match holds the current ETag gets an empty
+// 304 Not Modified instead, so revalidating costs no body.
+func RespondWithCachedJSON(w http.ResponseWriter, r *http.Request, payload interface{}, maxAge time.Duration) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		SendJSONError(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	sum := sha256.Sum256(response)
+	etag := `W/"` + hex.EncodeToString(sum[:8]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(maxAge.Seconds())))
+	w.Header().Add("Vary", "Authorization, X-API-Key")
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		if strings.TrimSpace(candidate) == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRespondWithCachedJSON(t *testing.T) {
+	payload := []map[string]string{{"id": "1", "title": "Go", "url": "https://go.dev/"}}
+
+	first := httptest.NewRecorder()
+	RespondWithCachedJSON(first, httptest.NewRequest(http.MethodGet, "/", nil), payload, time.Minute)
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || first.Body.Len() == 0 {
+		t.Fatalf("first response = %d, etag %q, %d bytes", first.Code, etag, first.Body.Len())
+	}
+	if got := first.Header().Get("Cache-Control"); got != "private, max-age=60" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `W/"other", `+etag)
+	second := httptest.NewRecorder()
+	RespondWithCachedJSON(second, req, payload, time.Minute)
+	if second.Code != http.StatusNotModified || second.Body.Len() != 0 {
+		t.Errorf("revalidation = %d with %d bytes, want 304 and no body", second.Code, second.Body.Len())
+	}
+
+	payload[0]["title"] = "Go docs"
+	third := httptest.NewRecorder()
+	RespondWithCachedJSON(third, req, payload, time.Minute)
+	if third.Code != http.StatusOK || third.Header().Get("ETag") == etag {
+		t.Errorf("changed payload = %d with etag %q, want 200 and a new etag", third.Code, third.Header().Get("ETag"))
+	}
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Scopes limit what a credential may do. Web and mobile sessions hold every
+// scope and can also manage the account; API keys, extension tokens and tokens
+// issued to third-party integrations only reach the routes their scopes cover.
+const (
+	ScopeBookmarksRead  = "bookmarks:read"
+	ScopeBookmarksWrite = "bookmarks:write"
+	ScopeTagsWrite      = "tags:write"
+	ScopeAIInvoke       = "ai:invoke"
+	ScopeExportRead     = "export:read"
+)
+
+// AllScopes lists every scope in the order they are documented.
+var AllScopes = []string{ScopeBookmarksRead, ScopeBookmarksWrite, ScopeTagsWrite, ScopeAIInvoke, ScopeExportRead}
+
+// ParseScopes validates requested scopes and returns them without duplicates in
+// the order of AllScopes. At least one scope is required.
+func ParseScopes(scopes []string) ([]string, error) {
+	requested := make(map[string]bool, len(scopes))
+	for _, s := range scopes {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if !containsScope(AllScopes, s) {
+			return nil, fmt.Errorf("invalid scope %q: must be one of %s", s, strings.Join(AllScopes, ", "))
+		}
+		requested[s] = true
+	}
+	if len(requested) == 0 {
+		return nil, fmt.Errorf("invalid scopes: at least one scope is required")
+	}
+	parsed := make([]string, 0, len(requested))
+	for _, s := range AllScopes {
+		if requested[s] {
+			parsed = append(parsed, s)
+		}
+	}
+	return parsed, nil
+}
+
+// AudienceScopes returns the most a token issued for audience may hold, or nil
+// when the audience has full access. The extension saves and reads bookmarks
+// but does not export them.
+func AudienceScopes(audience string) []string {
+	if audience == AudienceExtension {
+		return []string{ScopeBookmarksRead, ScopeBookmarksWrite, ScopeTagsWrite, ScopeAIInvoke}
+	}
+	return nil
+}
+
+// HasScope reports whether the credential that authenticated the request holds
+// scope. Full-access sessions hold every scope.
+func HasScope(ctx context.Context, scope string) bool {
+	scopes, ok := ctx.Value("scopes").([]string)
+	if !ok {
+		return true
+	}
+	return containsScope(scopes, scope)
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxSearchGrams bounds the trigrams stored per document, so a long summary does
+// not bloat the index.
+const maxSearchGrams = 1000
+
+// SearchWords splits text into lowercase words of letters and digits, with accents
+// removed.
+func SearchWords(text string) []string {
+	var words []string
+	var b strings.Builder
+	flush := func() {
+		if b.Len() > 0 {
+			words = append(words, b.String())
+			b.Reset()
+		}
+	}
+	for _, r := range norm.NFKD.String(text) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			flush()
+		}
+	}
+	flush()
+	return words
+}
+
+// WordTrigrams returns the trigrams of word padded with "_" on both sides, so
+// "go" gives "_go" and "go_".
+func WordTrigrams(word string) []string {
+	runes := []rune("_" + word + "_")
+	if len(runes) < 3 {
+		return nil
+	}
+	grams := make([]string, 0, len(runes)-2)
+	for i := 0; i+3 <= len(runes); i++ {
+		grams = append(grams, string(runes[i:i+3]))
+	}
+	return grams
+}
+
+// SearchGrams returns the distinct trigrams of the words in texts in the order they
+// first appear, up to maxSearchGrams, so that a long text keeps the grams of its
+// beginning and of the texts before it. It never returns nil, so an empty result
+// can be stored to mark a document as indexed.
+func SearchGrams(texts ...string) []string {
+	seen := make(map[string]bool)
+	grams := []string{}
+	for _, text := range texts {
+		for _, word := range SearchWords(text) {
+			for _, g := range WordTrigrams(word) {
+				if seen[g] {
+					continue
+				}
+				if len(grams) == maxSearchGrams {
+					return grams
+				}
+				seen[g] = true
+				grams = append(grams, g)
+			}
+		}
+	}
+	return grams
+}
+
+// WordSimilarity scores how well a search term matches a word, from 0 to 1, by the
+// share of trigrams they have in common. A word starting with a term of three or
+// more letters is a full match, so partly typed words find their results.
+func WordSimilarity(term, word string) float64 {
+	if term == word || (len([]rune(term)) >= 3 && strings.HasPrefix(word, term)) {
+		return 1
+	}
+	a, b := WordTrigrams(term), WordTrigrams(word)
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	inB := make(map[string]int, len(b))
+	for _, g := range b {
+		inB[g]++
+	}
+	shared := 0
+	for _, g := range a {
+		if inB[g] > 0 {
+			inB[g]--
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(a)+len(b))
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSearchWords(t *testing.T) {
+	got := SearchWords("Kubernetes: Crème-brûlée 101!")
+	want := []string{"kubernetes", "creme", "brulee", "101"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SearchWords = %q, want %q", got, want)
+	}
+}
+
+func TestSearchGrams(t *testing.T) {
+	got := SearchGrams("Go", "go GO")
+	want := []string{"_go", "go_"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SearchGrams = %q, want %q", got, want)
+	}
+	if grams := SearchGrams("!!!"); grams == nil || len(grams) != 0 {
+		t.Errorf("SearchGrams of no words = %#v, want an empty slice", grams)
+	}
+
+	// A long summary is cut off without losing the title, whatever its letters.
+	var summary strings.Builder
+	for i := 0; i < 2*maxSearchGrams; i++ {
+		fmt.Fprintf(&summary, "w%04d ", i)
+	}
+	grams := SearchGrams("zebra", summary.String())
+	if want := []string{"_ze", "zeb", "ebr", "bra", "ra_"}; len(grams) != maxSearchGrams || !reflect.DeepEqual(grams[:len(want)], want) {
+		t.Errorf("SearchGrams of a long text = %d grams starting %q, want %d starting %q", len(grams), grams[:5], maxSearchGrams, want)
+	}
+}
+
+func TestWordSimilarity(t *testing.T) {
+	matches := [][2]string{
+		{"kuberntes", "kubernetes"},
+		{"postgress", "postgres"},
+		{"kube", "kubernetes"},
+		{"go", "go"},
+	}
+	for _, m := range matches {
+		if s := WordSimilarity(m[0], m[1]); s < 0.5 {
+			t.Errorf("WordSimilarity(%q, %q) = %.2f, want at least 0.5", m[0], m[1], s)
+		}
+	}
+	misses := [][2]string{
+		{"kubernetes", "docker"},
+		{"go", "rust"},
+		{"go", "google"},
+	}
+	for _, m := range misses {
+		if s := WordSimilarity(m[0], m[1]); s >= 0.5 {
+			t.Errorf("WordSimilarity(%q, %q) = %.2f, want below 0.5", m[0], m[1], s)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxSlugLength bounds generated slugs, before any numeric suffix is added.
+const maxSlugLength = 60
+
+// Slugify turns a name into a URL-safe slug: accents are removed, letters are
+// lowercased, and every run of other characters becomes a single "-". Names with no
+// ASCII letters or digits give an empty slug.
+func Slugify(name string) string {
+	var b strings.Builder
+	pendingDash := false
+	for _, r := range norm.NFKD.String(name) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		r = unicode.ToLower(r)
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			dash := pendingDash && b.Len() > 0
+			if dash && b.Len()+2 > maxSlugLength || b.Len()+1 > maxSlugLength {
+				break
+			}
+			if dash {
+				b.WriteByte('-')
+			}
+			pendingDash = false
+			b.WriteRune(r)
+			continue
+		}
+		pendingDash = true
+	}
+	return b.String()
+}
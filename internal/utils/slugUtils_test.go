@@ -0,0 +1,25 @@
+package utils
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	cases := map[string]string{
+		"Reading List":        "reading-list",
+		"  Café & Crème  ":    "cafe-creme",
+		"Go/Rust -- Systems!": "go-rust-systems",
+		"2024 Q1":             "2024-q1",
+		"日本語":                 "",
+		"🚀 Launch":            "launch",
+		"---":                 "",
+		"über_cool__stuff":    "uber-cool-stuff",
+	}
+	for in, want := range cases {
+		if got := Slugify(in); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", in, got, want)
+		}
+	}
+	long := Slugify("a very long collection name that keeps going and going well past the limit of sixty")
+	if len(long) > maxSlugLength {
+		t.Errorf("Slugify returned %d characters, want at most %d", len(long), maxSlugLength)
+	}
+}
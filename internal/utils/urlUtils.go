@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// urlScheme matches a URL that starts with a scheme. A "://" later on, as in the
+// query of "example.com/go?to=https://other.org", does not count.
+var urlScheme = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://`)
+
+// URLRule customizes canonicalization for a domain and its subdomains.
+type URLRule struct {
+	Domain string `json:"domain"`
+	// KeepParams, when set, is the complete list of query parameters to keep.
+	KeepParams []string `json:"keep_params,omitempty"`
+	// StripParams are removed in addition to the global tracking parameters.
+	// A trailing "*" matches by prefix.
+	StripParams []string `json:"strip_params,omitempty"`
+	// KeepFragment keeps the #fragment, for sites that route on it.
+	KeepFragment bool `json:"keep_fragment,omitempty"`
+	// StripWWW drops a leading "www." from the host.
+	StripWWW bool `json:"strip_www,omitempty"`
+}
+
+// trackingParams are stripped from every URL. A trailing "*" matches by prefix.
+var trackingParams = []string{"utm_*", "fbclid", "gclid", "dclid", "msclkid", "mc_cid", "mc_eid", "igshid", "yclid", "_hsenc", "_hsmi"}
+
+// DefaultURLRules are the built-in per-domain rules; configured rules take precedence.
+var DefaultURLRules = []URLRule{
+	{Domain: "youtube.com", KeepParams: []string{"v", "list", "t"}, StripWWW: true},
+	{Domain: "twitter.com", KeepParams: []string{}, StripWWW: true},
+	{Domain: "x.com", KeepParams: []string{}, StripWWW: true},
+	{Domain: "amazon.com", KeepParams: []string{}},
+}
+
+// CanonicalizeURL returns the canonical form of raw used to detect duplicates:
+// lowercase scheme and host, no default port, tracking parameters removed, remaining
+// parameters sorted, and the fragment dropped unless it is a hash route ("#/", "#!")
+// or the domain rule keeps it. The first rule matching the host wins.
+func CanonicalizeURL(raw string, rules []URLRule) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid url: scheme and host are required")
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+
+	rule := matchURLRule(host, rules)
+	if rule != nil && rule.StripWWW {
+		host = strings.TrimPrefix(host, "www.")
+	}
+	if port != "" {
+		host = host + ":" + port
+	}
+	u.Host = host
+	u.User = nil
+
+	if u.Path == "" {
+		u.Path = "/"
+	} else if cleaned := path.Clean(u.Path); cleaned != "." {
+		if strings.HasSuffix(u.Path, "/") && cleaned != "/" {
+			cleaned += "/"
+		}
+		u.Path = cleaned
+	}
+	u.RawPath = ""
+
+	query := u.Query()
+	for key := range query {
+		if !keepParam(key, rule) {
+			query.Del(key)
+		}
+	}
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+	u.RawQuery = strings.Join(parts, "&")
+	u.ForceQuery = false
+
+	keepFragment := strings.HasPrefix(u.Fragment, "/") || strings.HasPrefix(u.Fragment, "!")
+	if rule != nil && rule.KeepFragment {
+		keepFragment = true
+	}
+	if !keepFragment {
+		u.Fragment = ""
+		u.RawFragment = ""
+	}
+
+	return u.String(), nil
+}
+
+// NormalizeURL validates a URL entered by a user and returns the form that is
+// stored and linked to: https:// is prepended when the scheme is missing, and the
+// scheme and host are lowercased. Unlike CanonicalizeURL it keeps the query and
+// fragment as given. Errors start with "invalid url" and say what is wrong.
+func NormalizeURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", fmt.Errorf("invalid url: url is empty")
+	}
+	if strings.ContainsAny(raw, " \t\r\n") {
+		return "", fmt.Errorf("invalid url: must not contain spaces")
+	}
+	if !urlScheme.MatchString(raw) {
+		raw = "https://" + strings.TrimPrefix(raw, "//")
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %w", err)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid url: unsupported scheme %q, use http or https", u.Scheme)
+	}
+	host := u.Hostname()
+	if host == "" {
+		return "", fmt.Errorf("invalid url: host is required")
+	}
+	if !strings.Contains(host, ".") && host != "localhost" && net.ParseIP(host) == nil {
+		return "", fmt.Errorf("invalid url: host %q is not a domain name", host)
+	}
+	u.Host = strings.ToLower(u.Host)
+	return u.String(), nil
+}
+
+// URLHost returns the lowercase host of raw without port and "www." prefix.
+func URLHost(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+func matchURLRule(host string, rules []URLRule) *URLRule {
+	for i := range rules {
+		domain := strings.ToLower(rules[i].Domain)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+func keepParam(key string, rule *URLRule) bool {
+	if matchParam(key, trackingParams) {
+		return false
+	}
+	if rule == nil {
+		return true
+	}
+	if rule.KeepParams != nil {
+		return matchParam(key, rule.KeepParams)
+	}
+	return !matchParam(key, rule.StripParams)
+}
+
+func matchParam(key string, patterns []string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(key, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		} else if key == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package utils
+
+import "testing"
+
+func TestCanonicalizeURL(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"lowercases scheme and host", "HTTPS://Example.COM/Path", "https://example.com/Path"},
+		{"adds root path", "https://example.com", "https://example.com/"},
+		{"drops default port", "http://example.com:80/a", "http://example.com/a"},
+		{"keeps other ports", "http://example.com:8080/a", "http://example.com:8080/a"},
+		{"strips tracking params", "https://example.com/a?utm_source=x&utm_medium=y&fbclid=z&id=1", "https://example.com/a?id=1"},
+		{"sorts params", "https://example.com/a?b=2&a=1", "https://example.com/a?a=1&b=2"},
+		{"drops fragment", "https://example.com/a#section", "https://example.com/a"},
+		{"keeps hash routes", "https://example.com/#/inbox", "https://example.com/#/inbox"},
+		{"cleans path", "https://example.com/a/./b/../c/", "https://example.com/a/c/"},
+		{"applies keep params rule", "https://www.youtube.com/watch?v=abc&feature=share", "https://youtube.com/watch?v=abc"},
+		{"rule matches subdomains", "https://m.youtube.com/watch?v=abc&si=1", "https://m.youtube.com/watch?v=abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CanonicalizeURL(tt.in, DefaultURLRules)
+			if err != nil {
+				t.Fatalf("CanonicalizeURL(%q) returned error: %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("CanonicalizeURL(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeURLCustomRule(t *testing.T) {
+	rules := []URLRule{{Domain: "example.com", StripParams: []string{"ref*"}, KeepFragment: true}}
+	got, err := CanonicalizeURL("https://example.com/a?ref=feed&referrer=x&id=1#top", rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://example.com/a?id=1#top"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCanonicalizeURLInvalid(t *testing.T) {
+	for _, in := range []string{"", "example.com/a", "://bad"} {
+		if _, err := CanonicalizeURL(in, nil); err == nil {
+			t.Errorf("CanonicalizeURL(%q) succeeded, want error", in)
+		}
+	}
+}
+
+func TestNormalizeURL(t *testing.T) {
+	cases := map[string]string{
+		"example.com/a?b=1":                   "https://example.com/a?b=1",
+		"  HTTP://Example.COM/Path ":          "http://example.com/Path",
+		"//cdn.example.com/x":                 "https://cdn.example.com/x",
+		"localhost:8080/app":                  "https://localhost:8080/app",
+		"https://10.0.0.1/#frag":              "https://10.0.0.1/#frag",
+		"example.com/go?to=https://other.org": "https://example.com/go?to=https://other.org",
+	}
+	for in, want := range cases {
+		got, err := NormalizeURL(in)
+		if err != nil {
+			t.Errorf("NormalizeURL(%q) returned error: %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("NormalizeURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNormalizeURLInvalid(t *testing.T) {
+	for _, in := range []string{"", "   ", "https://exa mple.com", "ftp://example.com", "javascript:alert(1)", "https://", "notadomain"} {
+		if _, err := NormalizeURL(in); err == nil {
+			t.Errorf("NormalizeURL(%q) succeeded, want error", in)
+		}
+	}
+}